@@ -1,105 +1,192 @@
 package main
 
 import (
-	"compress/gzip"
+	"context"
 	"flag"
 	"fmt"
-	"io"
 	"os"
-	"path/filepath"
 	"time"
+
+	"ecommerce-backend/internal/backup"
 )
 
+const usage = `Usage:
+  backup [backup] -db database.db -output ./backups [-compress] [retention flags]
+  backup list     -output ./backups [-verify]
+  backup restore  -output ./backups -db database.db (-id ID | -at RFC3339) [-force]
+  backup prune    -output ./backups [retention flags]
+
+Retention flags:
+  -keep-last N      keep the N newest backups (default 7)
+  -keep-daily N     keep the newest backup of each of the last N days (default 30)
+  -keep-weekly N    keep the newest backup of each of the last N ISO weeks
+  -keep-monthly N   keep the newest backup of each of the last N months
+  -max-age D        remove anything older than D (e.g. 2160h), overrides keep-*
+`
+
 func main() {
-	// Flags
-	dbPath := flag.String("db", "database.db", "Path to SQLite database file")
-	outputDir := flag.String("output", "./backups", "Output directory for backups")
-	compress := flag.Bool("compress", true, "Compress backup with gzip")
-	flag.Parse()
-
-	// Create output directory if it doesn't exist
-	if err := os.MkdirAll(*outputDir, 0755); err != nil {
-		fmt.Fprintf(os.Stderr, "Error creating backup directory: %v\n", err)
-		os.Exit(1)
+	cmd := "backup"
+	args := os.Args[1:]
+	if len(args) > 0 && len(args[0]) > 0 && args[0][0] != '-' {
+		cmd, args = args[0], args[1:]
 	}
 
-	// Generate backup filename with timestamp
-	timestamp := time.Now().Format("2006-01-02_15-04-05")
-	backupFile := filepath.Join(*outputDir, fmt.Sprintf("database_%s.db", timestamp))
-	if *compress {
-		backupFile += ".gz"
+	var err error
+	switch cmd {
+	case "backup":
+		err = runBackup(args)
+	case "list":
+		err = runList(args)
+	case "restore":
+		err = runRestore(args)
+	case "prune":
+		err = runPrune(args)
+	case "help":
+		fmt.Print(usage)
+		return
+	default:
+		err = fmt.Errorf("unknown command %q\n\n%s", cmd, usage)
 	}
 
-	// Open source database
-	source, err := os.Open(*dbPath)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
-	defer source.Close()
+}
 
-	// Create backup file
-	destination, err := os.Create(backupFile)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error creating backup file: %v\n", err)
-		os.Exit(1)
-	}
-	defer destination.Close()
-
-	// Copy with optional compression
-	var writer io.Writer = destination
-	if *compress {
-		gzWriter := gzip.NewWriter(destination)
-		defer gzWriter.Close()
-		writer = gzWriter
+func runBackup(args []string) error {
+	fs := flag.NewFlagSet("backup", flag.ExitOnError)
+	dbPath := fs.String("db", "database.db", "Path to SQLite database file")
+	outputDir := fs.String("output", "./backups", "Output directory for backups")
+	compress := fs.Bool("compress", true, "Compress backup with gzip")
+	policy := retentionFlags(fs)
+	fs.Parse(args)
+
+	if err := policy.Validate(); err != nil {
+		return err
 	}
 
-	bytesWritten, err := io.Copy(writer, source)
+	m, err := backup.Create(context.Background(), *dbPath, *outputDir, backup.Options{Compress: *compress})
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error copying database: %v\n", err)
-		os.Exit(1)
+		return err
 	}
 
-	fmt.Printf("✅ Backup created: %s\n", backupFile)
-	fmt.Printf("   Size: %.2f MB\n", float64(bytesWritten)/1024/1024)
+	fmt.Printf("✅ Backup created: %s\n", m.File)
+	fmt.Printf("   Size: %.2f MB (database %.2f MB)\n", mb(m.Size), mb(m.DBSize))
+	fmt.Printf("   SHA-256: %s\n", m.SHA256)
+	fmt.Printf("   Integrity: %s\n", m.Integrity)
 
-	// Cleanup old backups (keep last 30 days)
-	if err := cleanupOldBackups(*outputDir, 30); err != nil {
+	// Retention runs after a successful backup only, so a failing backup never
+	// eats into the existing history
+	removed, err := backup.Prune(*outputDir, *policy, time.Now())
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "Warning: Failed to cleanup old backups: %v\n", err)
 	}
+	for _, r := range removed {
+		fmt.Printf("🗑️  Removed old backup: %s\n", r.File)
+	}
+	return nil
 }
 
-// cleanupOldBackups removes backups older than days
-func cleanupOldBackups(dir string, days int) error {
-	entries, err := os.ReadDir(dir)
+func runList(args []string) error {
+	fs := flag.NewFlagSet("list", flag.ExitOnError)
+	outputDir := fs.String("output", "./backups", "Backup directory")
+	verify := fs.Bool("verify", false, "Verify checksums of every backup")
+	fs.Parse(args)
+
+	manifests, err := backup.List(*outputDir)
 	if err != nil {
 		return err
 	}
+	if len(manifests) == 0 {
+		fmt.Println("No backups found")
+		return nil
+	}
 
-	cutoffTime := time.Now().AddDate(0, 0, -days)
-
-	for _, entry := range entries {
-		if entry.IsDir() {
-			continue
+	for _, m := range manifests {
+		status := ""
+		if *verify {
+			status = "  ✅ ok"
+			if err := backup.Verify(*outputDir, m); err != nil {
+				status = "  ❌ " + err.Error()
+			}
 		}
-
-		// Skip non-backup files
-		if !filepath.HasPrefix(entry.Name(), "database_") {
-			continue
+		sum := "legacy"
+		if !m.Legacy() {
+			sum = m.SHA256[:12]
 		}
+		fmt.Printf("%-32s  %s  %8.2f MB  %-12s%s\n",
+			m.ID, m.CreatedAt.Local().Format(time.RFC3339), mb(m.Size), sum, status)
+	}
+	return nil
+}
 
-		info, err := entry.Info()
-		if err != nil {
-			continue
+func runRestore(args []string) error {
+	fs := flag.NewFlagSet("restore", flag.ExitOnError)
+	outputDir := fs.String("output", "./backups", "Backup directory")
+	dbPath := fs.String("db", "database.db", "Database file to restore into")
+	id := fs.String("id", "", "Backup ID or file name to restore")
+	at := fs.String("at", "", "Restore the newest backup taken at or before this RFC3339 time")
+	force := fs.Bool("force", false, "Replace an existing database (kept as *.pre-restore-*)")
+	fs.Parse(args)
+
+	var (
+		m   *backup.Manifest
+		err error
+	)
+	switch {
+	case *id != "" && *at != "":
+		return fmt.Errorf("use either -id or -at, not both")
+	case *id != "":
+		m, err = backup.Find(*outputDir, *id)
+	case *at != "":
+		t, perr := time.Parse(time.RFC3339, *at)
+		if perr != nil {
+			return fmt.Errorf("invalid -at: %w", perr)
 		}
+		m, err = backup.SelectAt(*outputDir, t)
+	default:
+		m, err = backup.SelectAt(*outputDir, time.Now())
+	}
+	if err != nil {
+		return err
+	}
 
-		if info.ModTime().Before(cutoffTime) {
-			path := filepath.Join(dir, entry.Name())
-			if err := os.Remove(path); err == nil {
-				fmt.Printf("🗑️  Removed old backup: %s\n", entry.Name())
-			}
-		}
+	if err := backup.Restore(context.Background(), *outputDir, *m, *dbPath, *force); err != nil {
+		return err
 	}
+	fmt.Printf("✅ Restored %s (taken %s) into %s\n", m.ID, m.CreatedAt.Local().Format(time.RFC3339), *dbPath)
+	return nil
+}
+
+func runPrune(args []string) error {
+	fs := flag.NewFlagSet("prune", flag.ExitOnError)
+	outputDir := fs.String("output", "./backups", "Backup directory")
+	policy := retentionFlags(fs)
+	fs.Parse(args)
 
+	removed, err := backup.Prune(*outputDir, *policy, time.Now())
+	if err != nil {
+		return err
+	}
+	for _, r := range removed {
+		fmt.Printf("🗑️  Removed old backup: %s\n", r.File)
+	}
+	fmt.Printf("Removed %d backup(s)\n", len(removed))
 	return nil
 }
+
+func retentionFlags(fs *flag.FlagSet) *backup.RetentionPolicy {
+	p := backup.DefaultRetention
+	fs.IntVar(&p.KeepLast, "keep-last", p.KeepLast, "Keep the N newest backups")
+	fs.IntVar(&p.KeepDaily, "keep-daily", p.KeepDaily, "Keep one backup per day for N days")
+	fs.IntVar(&p.KeepWeekly, "keep-weekly", p.KeepWeekly, "Keep one backup per week for N weeks")
+	fs.IntVar(&p.KeepMonthly, "keep-monthly", p.KeepMonthly, "Keep one backup per month for N months")
+	fs.DurationVar(&p.MaxAge, "max-age", p.MaxAge, "Remove backups older than this, regardless of keep-*")
+	fs.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	return &p
+}
+
+func mb(n int64) float64 {
+	return float64(n) / 1024 / 1024
+}
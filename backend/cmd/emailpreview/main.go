@@ -0,0 +1,85 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"ecommerce-backend/internal/emails"
+)
+
+const usage = `Usage:
+  emailpreview [-output DIR] [-name NAME] [-locale vi|en]
+
+Renders every email template with fixture data, in every locale, to
+DIR/<name>.<locale>.html and DIR/<name>.<locale>.txt, and writes
+DIR/index.html linking them with their subjects. -name and -locale render
+just one template or one language.
+`
+
+func main() {
+	fs := flag.NewFlagSet("emailpreview", flag.ExitOnError)
+	fs.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	output := fs.String("output", "email-preview", "Directory the previews are written to")
+	name := fs.String("name", "", "Render only this template")
+	locale := fs.String("locale", "", "Render only this locale")
+	fs.Parse(os.Args[1:])
+
+	if err := run(*output, *name, *locale); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(output, only, onlyLocale string) error {
+	names := emails.Names()
+	if only != "" {
+		if emails.Fixture(only) == nil {
+			return fmt.Errorf("unknown template %q (have %s)", only, strings.Join(names, ", "))
+		}
+		names = []string{only}
+	}
+	locales := emails.Locales
+	if onlyLocale != "" {
+		l := emails.Locale(onlyLocale)
+		if emails.ParseLocale(onlyLocale) != l {
+			return fmt.Errorf("unknown locale %q", onlyLocale)
+		}
+		locales = []emails.Locale{l}
+	}
+
+	if err := os.MkdirAll(output, 0o755); err != nil {
+		return err
+	}
+
+	var index strings.Builder
+	index.WriteString("<!DOCTYPE html>\n<html>\n<head><meta charset=\"utf-8\"><title>Email previews</title></head>\n<body>\n<h1>Email previews</h1>\n<table>\n")
+	for _, name := range names {
+		for _, locale := range locales {
+			msg, err := emails.Render(name, locale, emails.Fixture(name))
+			if err != nil {
+				return err
+			}
+			base := fmt.Sprintf("%s.%s", name, locale)
+			if err := os.WriteFile(filepath.Join(output, base+".html"), []byte(msg.HTML), 0o644); err != nil {
+				return err
+			}
+			if err := os.WriteFile(filepath.Join(output, base+".txt"), []byte(msg.Text), 0o644); err != nil {
+				return err
+			}
+			fmt.Fprintf(&index, "<tr><td>%s</td><td>%s</td><td>%s</td><td><a href=\"%s.html\">html</a></td><td><a href=\"%s.txt\">text</a></td></tr>\n",
+				name, locale, html.EscapeString(msg.Subject), base, base)
+		}
+	}
+	index.WriteString("</table>\n</body>\n</html>\n")
+
+	path := filepath.Join(output, "index.html")
+	if err := os.WriteFile(path, []byte(index.String()), 0o644); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "✅ %d previews written, open %s\n", len(names)*len(locales), path)
+	return nil
+}
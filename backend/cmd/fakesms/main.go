@@ -0,0 +1,36 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+
+	"ecommerce-backend/internal/integrations/smstest"
+)
+
+const usage = `Usage:
+  fakesms [-addr ADDR]
+
+Runs a fake eSMS gateway for local development. Start the server with
+SMS_BASE_URL=http://ADDR and an SMS_API_KEY; every message is printed here
+and listed on GET /messages.
+`
+
+func main() {
+	fs := flag.NewFlagSet("fakesms", flag.ExitOnError)
+	fs.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	addr := fs.String("addr", "localhost:4590", "Address to listen on")
+	fs.Parse(os.Args[1:])
+
+	gateway := &smstest.Gateway{
+		OnMessage: func(m smstest.Message) {
+			log.Printf("sms %s to %s [%s]: %s", m.SMSID, m.Phone, m.BrandName, m.Content)
+		},
+	}
+	log.Printf("fake sms gateway listening on http://%s", *addr)
+	if err := http.ListenAndServe(*addr, gateway); err != nil {
+		log.Fatal(err)
+	}
+}
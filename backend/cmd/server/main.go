@@ -2,7 +2,11 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
+	"flag"
+	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
 	_ "net/http/pprof" // Register pprof handlers
 	"os"
@@ -11,18 +15,35 @@ import (
 	"time"
 
 	"ecommerce-backend/config"
+	"ecommerce-backend/internal/alerts"
+	"ecommerce-backend/internal/awsclient"
+	"ecommerce-backend/internal/botcheck"
 	"ecommerce-backend/internal/database"
+	"ecommerce-backend/internal/geo"
 	"ecommerce-backend/internal/handlers"
+	"ecommerce-backend/internal/health"
 	"ecommerce-backend/internal/integrations"
+	"ecommerce-backend/internal/jobs"
+	"ecommerce-backend/internal/logging"
+	"ecommerce-backend/internal/metrics"
+	"ecommerce-backend/internal/queue"
+	"ecommerce-backend/internal/ratelimit"
+	"ecommerce-backend/internal/replication"
 	"ecommerce-backend/internal/repository"
+	"ecommerce-backend/internal/scheduler"
+	"ecommerce-backend/internal/secrets"
 	"ecommerce-backend/internal/service"
+	"ecommerce-backend/internal/sheetsync"
+	"ecommerce-backend/internal/tracing"
+	"ecommerce-backend/internal/utils/base32"
+	"ecommerce-backend/internal/webhooks"
 
 	gojson "github.com/goccy/go-json"
 	"github.com/gofiber/fiber/v3"
 	"github.com/gofiber/fiber/v3/middleware/compress"
 	"github.com/gofiber/fiber/v3/middleware/cors"
 	"github.com/gofiber/fiber/v3/middleware/etag"
-	"github.com/gofiber/fiber/v3/middleware/logger"
+	"github.com/redis/go-redis/v9"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 
@@ -30,18 +51,67 @@ import (
 )
 
 func main() {
-	// Load configuration
-	cfg := config.Load()
+	configFile := flag.String("config", os.Getenv("CONFIG_FILE"), "Path to a YAML or TOML config file")
+	printConfig := flag.Bool("print-config", false, "Print the effective configuration (secrets redacted) and exit")
+	flag.Parse()
+
+	// Load configuration: defaults < config file < env < secrets
+	sources, err := configSources(*configFile)
+	if err != nil {
+		log.Fatalf("failed to load configuration: %v", err)
+	}
+	if *printConfig {
+		// Print even an invalid config: it is the quickest way to see why
+		cfg, err := config.Parse(sources)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := cfg.WriteYAML(os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		if err := cfg.Validate(); err != nil {
+			fmt.Fprintf(os.Stderr, "invalid configuration:\n%v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	cfg, err := config.LoadWith(sources)
+	if err != nil {
+		log.Fatalf("failed to load configuration: %v", err)
+	}
+
+	// Structured logging; the standard log package is routed through it too
+	logger, err := newLogger(cfg.Log)
+	if err != nil {
+		log.Fatalf("failed to configure logging: %v", err)
+	}
+	slog.SetDefault(logger)
+
+	// OpenTelemetry tracing (OTEL_TRACES_EXPORTER=stdout|otlp); spans are
+	// flushed on shutdown
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Options{
+		Exporter:    cfg.Tracing.Exporter,
+		Endpoint:    cfg.Tracing.Endpoint,
+		ServiceName: cfg.Tracing.ServiceName,
+		SampleRatio: cfg.Tracing.SampleRatio,
+	})
+	if err != nil {
+		log.Fatalf("failed to configure tracing: %v", err)
+	}
 
 	// Initialize optimized SQLite database with Split Architecture (Writer/Reader)
-	if err := database.Connect(cfg.DatabaseURL); err != nil {
+	if err := database.ConnectWithOptions(cfg.Database.Path, database.Options{
+		MaxWriteConns: cfg.Database.MaxWriteConns,
+		MaxReadConns:  cfg.Database.MaxReadConns,
+		BusyTimeout:   cfg.Database.BusyTimeout,
+	}); err != nil {
 		log.Fatalf("failed to connect database: %v", err)
 	}
 	defer database.Close()
 
 	// Initialize GORM for migrations only (AutoMigrate)
 	// Use Writer connection to avoid locking issues during migration
-	db, err := gorm.Open(sqlite.Open(cfg.DatabaseURL), &gorm.Config{})
+	db, err := gorm.Open(sqlite.Open(cfg.Database.Path), &gorm.Config{})
 	if err != nil {
 		log.Fatalf("failed to connect GORM for migration: %v", err)
 	}
@@ -53,30 +123,266 @@ func main() {
 		&models.Order{},
 		&models.LimitedDrop{},
 		&models.Symbicode{},
+		&models.SymbicodeScan{},
+		&models.SymbicodeTransfer{},
+		&models.SymbicodeTransferCode{},
+		&models.SymbicodeBatch{},
+		&models.OrderLookupToken{},
+		&models.Refund{},
+		&models.AuditEntry{},
+		&models.SchedulerJob{},
+		&models.EmailMessage{},
+		&models.EmailSuppression{},
+		&models.SheetRow{},
+		&models.WebhookSubscription{},
+		&models.WebhookDelivery{},
+		&models.WebhookAttempt{},
 	); err != nil {
 		log.Fatalf("auto-migrate failed: %v", err)
 	}
+	if err := database.SetSchemaVersion(database.DB.Writer, database.SchemaVersion); err != nil {
+		log.Fatalf("failed to record schema version: %v", err)
+	}
 
 	log.Println("database connected, migrated and configured with Split Architecture (WAL mode)")
 
+	// Continuous WAL shipping to S3 (LocalStack in development)
+	replCtx, stopReplication := context.WithCancel(context.Background())
+	replDone := make(chan struct{})
+	if cfg.AWS.UseS3 {
+		awsCfg, err := awsclient.Load(context.Background(), cfg.AWS)
+		if err != nil {
+			log.Fatalf("failed to load AWS config: %v", err)
+		}
+		replicator := replication.New(cfg.Database.Path, replication.NewS3Client(awsCfg, cfg.AWS.S3Bucket), replication.Options{
+			Prefix:           cfg.Replication.Prefix,
+			SyncInterval:     cfg.Replication.SyncInterval,
+			SnapshotInterval: cfg.Replication.SnapshotInterval,
+			Retention:        cfg.Replication.Retention,
+			Logger:           logger,
+		})
+		go func() {
+			defer close(replDone)
+			if err := replicator.Run(replCtx); err != nil {
+				log.Printf("[replication] stopped: %v", err)
+			}
+		}()
+		log.Printf("replicating database to s3://%s/%s", cfg.AWS.S3Bucket, cfg.Replication.Prefix)
+	} else {
+		close(replDone)
+	}
+
 	// Initialize layers using optimized database (raw SQL with Writer/Reader split)
 	// SmartExecutor automatically routes SELECT to Reader and writes to Writer
 	executor := database.NewSmartExecutor(database.DB.Writer, database.DB.Reader)
 	repo := repository.NewRepository(executor)
-	payment := integrations.NewPayOSGateway()
-	email := integrations.NewResendEmailer()
-	sheets := integrations.NewSheetsSubmitter()
-	svc := service.NewService(repo, payment, email, sheets)
-	hdlrs := handlers.NewHandlers(svc)
+
+	// Pool stats (writer wait time), per-drop sales and queue depth for /metrics
+	metrics.RegisterDB("writer", database.DB.Writer)
+	metrics.RegisterDB("reader", database.DB.Reader)
+	metrics.RegisterDrops(repo.GetActiveDrops)
+
+	// Credentials are resolved with the config and swapped in place on SIGHUP
+	// (key rotation); other settings need a restart
+	credStore := integrations.NewCredentialStore(credentials(cfg))
+	reloadCtx, stopReload := context.WithCancel(context.Background())
+	defer stopReload()
+	secrets.ReloadOnSIGHUP(reloadCtx, func(ctx context.Context) error {
+		reloaded, err := config.LoadWith(sources)
+		if err != nil {
+			return err
+		}
+		credStore.Set(credentials(reloaded))
+		return nil
+	})
+
+	payment := integrations.NewPayOSGatewayWithCredentials(credStore, integrations.WithLogger(logger))
+	email, err := integrations.NewEmailerWithCredentials(credStore, emailerConfig(cfg.Email, service.NewEmailLog(repo)), cfg.Email.Providers, integrations.WithLogger(logger))
+	if err != nil {
+		log.Fatalf("email: %v", err)
+	}
+	sheetColumns, err := sheetsync.ParseColumns(cfg.SheetSync.Columns)
+	if err != nil {
+		log.Fatalf("sheets: %v", err)
+	}
+	sheets := sheetsync.New(sheetsync.Options{
+		API:       integrations.NewGoogleSheetsWithCredentials(credStore, integrations.WithLogger(logger)),
+		Store:     sheetsync.NewSQLStore(database.DB.Writer),
+		Columns:   sheetColumns,
+		BatchSize: cfg.SheetSync.BatchSize,
+		Attempts:  cfg.SheetSync.Attempts,
+		Backoff:   cfg.SheetSync.Backoff,
+		Logger:    logger,
+	})
+	notifier, err := integrations.NewNotifier(notifyPreferences(cfg.Notify), []integrations.NotificationChannel{
+		integrations.NewEmailChannel(email),
+		integrations.NewSMSChannel(credStore, integrations.WithLogger(logger)),
+		integrations.NewZaloChannel(credStore, zaloTemplates(cfg.Notify), integrations.WithLogger(logger)),
+	}, integrations.WithLogger(logger))
+	if err != nil {
+		log.Fatalf("notify: %v", err)
+	}
+	// Admin alerts; held alerts go out when the server stops
+	alerter := newAlerter(cfg.Alerts, email, logger)
+	alertCtx, stopAlerts := context.WithCancel(context.Background())
+	alertsDone := make(chan struct{})
+	go func() {
+		defer close(alertsDone)
+		if alerter != nil {
+			alerter.Run(alertCtx)
+		}
+	}()
+	// Outbound webhooks; POSTs in flight finish when the server stops
+	hooks := webhooks.New(webhooks.Options{
+		Store:       webhooks.NewSQLStore(database.DB.Writer),
+		MaxAttempts: cfg.Webhooks.MaxAttempts,
+		Backoff:     cfg.Webhooks.Backoff,
+		MaxBackoff:  cfg.Webhooks.MaxBackoff,
+		Timeout:     cfg.Webhooks.Timeout,
+		Workers:     cfg.Webhooks.Workers,
+		Logger:      logger,
+	})
+	hooksCtx, stopHooks := context.WithCancel(context.Background())
+	hooksDone := make(chan struct{})
+	go func() {
+		defer close(hooksDone)
+		hooks.Run(hooksCtx)
+	}()
+	if cfg.Orders.NumberKey == "" {
+		log.Println("orders: no ORDER_NUMBER_KEY, order numbers use the development key")
+	}
+	base32.SetKey([]byte(cfg.Orders.NumberKey))
+	base32.SetLegacyMaxID(uint64(cfg.Orders.LegacyMaxID))
+	if cfg.Symbicode.SigningKey == "" {
+		log.Println("symbicode: no SYMBICODE_SIGNING_KEY, tokens issued now stop verifying after a restart")
+	}
+	svcOpts := []service.Option{
+		service.WithFrontendURL(cfg.Server.FrontendURL),
+		service.WithSymbicodeSigning([]byte(cfg.Symbicode.SigningKey), cfg.Symbicode.AcceptUnsigned),
+		service.WithPreviousSymbicodeKeys(cfg.Symbicode.PreviousKeys()...),
+		service.WithScanPolicy(service.ScanPolicy{MaxIPs: cfg.Symbicode.SuspiciousIPs, MaxLocations: cfg.Symbicode.SuspiciousLocations}),
+		service.WithTransferTTL(cfg.Symbicode.TransferTTL),
+		service.WithLookupLinkTTL(cfg.Orders.LookupLinkTTL),
+		service.WithRefundRetry(cfg.Orders.RefundAttempts, cfg.Orders.RefundBackoff),
+		service.WithNotifier(notifier),
+		service.WithAlerter(alerter),
+		service.WithWebhooks(hooks),
+		service.WithLogger(logger),
+	}
+	if cfg.Symbicode.GeoIPDatabase != "" {
+		geoDB, err := geo.Open(cfg.Symbicode.GeoIPDatabase)
+		if err != nil {
+			log.Fatalf("failed to open GeoIP database: %v", err)
+		}
+		defer geoDB.Close()
+		svcOpts = append(svcOpts, service.WithGeoLocator(geoDB))
+	}
+	svc := service.NewService(repo, payment, email, sheets, svcOpts...)
+
+	// Payment webhooks are validated and enqueued; workers finalize the orders.
+	// Without a queue the webhook handler finalizes them inline.
+	payments, deadLetter := newPaymentQueues(cfg)
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	workersDone := make(chan struct{})
+	go func() {
+		defer close(workersDone)
+		if payments == nil {
+			return
+		}
+		queue.NewWorker(payments, jobs.PaymentHandler(svc), queue.WorkerOptions{
+			Name:        "payments",
+			Concurrency: cfg.Queue.Workers,
+			Visibility:  cfg.Queue.Visibility,
+			MaxAttempts: cfg.Queue.MaxAttempts,
+			DeadLetter:  deadLetter,
+			Logger:      logger,
+		}).Run(workerCtx)
+	}()
+
+	for name, q := range map[string]queue.Queue{"payments": payments, "payments-dlq": deadLetter} {
+		if d, ok := q.(metrics.Depther); ok {
+			metrics.RegisterQueueDepth(name, d)
+		}
+	}
+
+	// Periodic jobs; leases in the shared database keep each run on one instance
+	sched := scheduler.New(scheduler.Options{
+		Owner:  cfg.Scheduler.InstanceID,
+		Store:  scheduler.NewSQLStore(database.DB.Writer),
+		Logger: logger,
+	})
+	if err := sched.Register(jobs.SymbicodeAutoActivation(svc, cfg.Symbicode.AutoActivateSchedule, cfg.Symbicode.AutoActivateAfter)); err != nil {
+		log.Fatalf("failed to register scheduled job: %v", err)
+	}
+	if err := sched.Register(jobs.SheetSync(sheets, cfg.SheetSync.Schedule)); err != nil {
+		log.Fatalf("failed to register scheduled job: %v", err)
+	}
+	if err := sched.Register(jobs.Refunds(svc, cfg.Orders.RefundSchedule)); err != nil {
+		log.Fatalf("failed to register scheduled job: %v", err)
+	}
+	if err := sched.Register(jobs.DropStartWebhooks(svc, cfg.Webhooks.DropStartSchedule, cfg.Webhooks.DropStartWindow)); err != nil {
+		log.Fatalf("failed to register scheduled job: %v", err)
+	}
+	schedCtx, stopScheduler := context.WithCancel(context.Background())
+	schedDone := make(chan struct{})
+	go func() {
+		defer close(schedDone)
+		if cfg.Scheduler.Enabled {
+			sched.Run(schedCtx)
+		}
+	}()
+
+	// Abuse protection; buckets and spent challenges are shared through Redis when configured
+	limitStore := newRateLimitStore(cfg.RateLimit)
+	hdlrOpts := []handlers.Option{
+		handlers.WithPaymentQueue(payments),
+		handlers.WithCredentials(credStore),
+		handlers.WithReadiness(newReadiness(cfg, credStore, email)),
+		handlers.WithScheduler(sched),
+		handlers.WithAdminToken(cfg.Server.AdminToken),
+		handlers.WithAlerter(alerter),
+		handlers.WithSheetSync(sheets),
+		handlers.WithWebhooks(hooks),
+		handlers.WithLogger(logger),
+	}
+	if cfg.RateLimit.Enabled {
+		rules, err := ratelimit.ParseRules(cfg.RateLimit.Rules)
+		if err != nil {
+			log.Fatalf("invalid rate limit rules: %v", err)
+		}
+		hdlrOpts = append(hdlrOpts, handlers.WithRateLimiter(ratelimit.New(ratelimit.Options{
+			Store:  limitStore,
+			Rules:  rules,
+			Logger: logger,
+		})))
+	}
+	if check := newBotCheck(cfg.BotCheck, limitStore); check != nil {
+		hdlrOpts = append(hdlrOpts, handlers.WithBotCheck(check))
+	}
+	hdlrs := handlers.NewHandlers(svc, hdlrOpts...)
 
 	// Initialize Fiber app
 	app := fiber.New(fiber.Config{
 		JSONEncoder: gojson.Marshal,
 		JSONDecoder: gojson.Unmarshal,
+		// c.IP() is the client, not the load balancer, when behind TRUSTED_PROXIES
+		TrustProxy:         len(cfg.Server.TrustedProxies) > 0,
+		TrustProxyConfig:   fiber.TrustProxyConfig{Proxies: cfg.Server.TrustedProxies},
+		ProxyHeader:        cfg.Server.ProxyHeader,
+		EnableIPValidation: true,
 	})
 
-	// Request logger
-	app.Use(logger.New())
+	// Request latency per route (outermost, so it includes the other middleware)
+	app.Use(metrics.Middleware())
+
+	// Server span per request, continuing an incoming traceparent
+	app.Use(tracing.Middleware())
+
+	// Request IDs (X-Request-ID) and structured access logs
+	app.Use(logging.Middleware(logging.Subsystem(logger, "http")))
+
+	// Read-your-writes for mutating requests
+	app.Use(database.SessionMiddleware())
 
 	// ETag: HTTP caching
 	app.Use(etag.New())
@@ -87,15 +393,11 @@ func main() {
 	}))
 
 	// CORS - Allow frontend origins
-	allowedOrigins := []string{"http://localhost:5173", "http://localhost:3000", "http://127.0.0.1:5173"}
-	if os.Getenv("CORS_ORIGINS") != "" {
-		allowedOrigins = []string{os.Getenv("CORS_ORIGINS")}
-	}
-
 	app.Use(cors.New(cors.Config{
-		AllowOrigins:     allowedOrigins,
+		AllowOrigins:     cfg.Server.CORSOrigins,
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "PATCH", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", ratelimit.FingerprintHeader, botcheck.Header},
+		ExposeHeaders:    []string{fiber.HeaderRetryAfter},
 		AllowCredentials: true,
 	}))
 
@@ -112,7 +414,7 @@ func main() {
 	})
 
 	// Start server
-	log.Printf("starting server on :%s", cfg.Port)
+	log.Printf("starting server on :%s", cfg.Server.Port)
 
 	// Channel to listen for interrupt signal
 	c := make(chan os.Signal, 1)
@@ -120,17 +422,19 @@ func main() {
 
 	// Start server in a goroutine
 	go func() {
-		if err := app.Listen(":" + cfg.Port); err != nil {
+		if err := app.Listen(":" + cfg.Server.Port); err != nil {
 			log.Printf("server failed: %v", err)
 		}
 	}()
 
 	// Start pprof server for profiling (Intel Engineering Requirement)
 	go func() {
-		// Listen on localhost:6060.
+		// Listen on localhost:6060 by default (PPROF_ADDR), away from the public port.
 		// Access profiles via: go tool pprof http://localhost:6060/debug/pprof/profile
-		log.Println("🔌 Pprof debugger running at http://localhost:6060/debug/pprof/")
-		if err := http.ListenAndServe("localhost:6060", nil); err != nil {
+		// Prometheus scrapes /metrics on the same listener.
+		http.Handle("/metrics", metrics.Handler())
+		log.Printf("🔌 Pprof debugger running at http://%s/debug/pprof/", cfg.Server.PprofAddr)
+		if err := http.ListenAndServe(cfg.Server.PprofAddr, nil); err != nil {
 			log.Printf("pprof failed: %v", err)
 		}
 	}()
@@ -148,5 +452,250 @@ func main() {
 		log.Printf("server shutdown failed: %v", err)
 	}
 
+	// Finish in-flight payment jobs; queued ones stay in SQS for the next start
+	stopWorkers()
+	<-workersDone
+
+	// Let a running scheduled job record its outcome and release its lease
+	stopScheduler()
+	<-schedDone
+
+	// Send the alerts held for a digest, including those raised by the last jobs
+	stopAlerts()
+	<-alertsDone
+
+	// Finish the webhook POSTs in flight; the rest go out after the restart
+	stopHooks()
+	<-hooksDone
+
+	// Ship the last WAL frames before the database is closed
+	stopReplication()
+	<-replDone
+
+	// Export the spans still buffered
+	if err := shutdownTracing(ctx); err != nil {
+		log.Printf("trace export failed: %v", err)
+	}
+
 	log.Println("server shutdown complete")
 }
+
+// newLogger builds the structured logger from the log config section
+func newLogger(cfg config.LogConfig) (*slog.Logger, error) {
+	levels, err := logging.ParseLevels(cfg.Levels)
+	if err != nil {
+		return nil, err
+	}
+	return logging.New(os.Stderr, logging.Options{
+		Level:  cfg.Level,
+		Format: cfg.Format,
+		Levels: levels,
+	})
+}
+
+// newReadiness builds the /readyz checks: both pools, WAL size, free disk,
+// schema version, email provider health and, when enabled, cached
+// reachability of configured APIs
+func newReadiness(cfg *config.Config, creds *integrations.CredentialStore, email *integrations.Emailer) *health.Checker {
+	checker := health.NewChecker(cfg.Health.Timeout,
+		health.Pool("writer", database.DB.Writer),
+		health.Pool("reader", database.DB.Reader),
+		health.WALSize(cfg.Database.Path, int64(cfg.Health.MaxWALMB)<<20),
+		health.DiskSpace(cfg.Database.Path, uint64(cfg.Health.MinFreeDiskMB)<<20),
+		health.SchemaVersion(database.DB.Reader, database.SchemaVersion),
+		health.Check{Name: "email", Run: email.Check},
+	)
+	if cfg.Health.CheckIntegrations {
+		client := &http.Client{Timeout: cfg.Health.Timeout}
+		for _, ep := range creds.Get().Endpoints() {
+			checker.Add(health.Cached(health.Reachable(ep.Name, ep.URL, client), cfg.Health.IntegrationsTTL))
+		}
+	}
+	return checker
+}
+
+// newRateLimitStore keeps token buckets in Redis when a URL is configured,
+// in memory otherwise
+func newRateLimitStore(cfg config.RateLimitConfig) ratelimit.Store {
+	if cfg.RedisURL == "" {
+		return ratelimit.NewMemoryStore()
+	}
+	opts, err := redis.ParseURL(cfg.RedisURL)
+	if err != nil {
+		log.Fatalf("invalid rate limit redis URL: %v", err)
+	}
+	log.Printf("rate limit: redis %s", opts.Addr)
+	return ratelimit.NewRedisStore(redis.NewClient(opts))
+}
+
+// newBotCheck returns the drop purchase check, or nil when disabled
+func newBotCheck(cfg config.BotCheckConfig, spent ratelimit.Store) botcheck.Checker {
+	switch cfg.Kind {
+	case "pow":
+		key := []byte(cfg.PoWKey)
+		if len(key) == 0 {
+			log.Println("bot check: no BOT_CHECK_POW_KEY, challenges are only valid on this instance until restart")
+			key = make([]byte, 32)
+			if _, err := rand.Read(key); err != nil {
+				log.Fatalf("failed to generate proof-of-work key: %v", err)
+			}
+		}
+		return botcheck.NewProofOfWork(botcheck.PoWOptions{
+			Key:        key,
+			Difficulty: cfg.PoWDifficulty,
+			TTL:        cfg.PoWTTL,
+			Spent:      spent,
+		})
+	case "captcha":
+		return botcheck.NewCaptcha(cfg.CaptchaVerifyURL, cfg.CaptchaSiteKey, cfg.CaptchaSecret, nil)
+	default:
+		return nil
+	}
+}
+
+// newPaymentQueues returns the payment queue and its dead-letter queue: SQS
+// when enabled (LocalStack in development), in-memory when asked for, and
+// none otherwise so payments are processed inline
+func newPaymentQueues(cfg *config.Config) (queue.Queue, queue.Queue) {
+	if !cfg.AWS.UseSQS {
+		if cfg.Queue.Memory {
+			log.Println("payment queue: in-memory (jobs are lost on exit; set USE_SQS=true for durable queueing)")
+			return queue.NewMemoryQueue(), queue.NewMemoryQueue()
+		}
+		log.Println("payment queue: none, processing PayOS webhooks inline (set USE_SQS=true for durable queueing)")
+		return nil, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	awsCfg, err := awsclient.Load(ctx, cfg.AWS)
+	if err != nil {
+		log.Fatalf("failed to load AWS config: %v", err)
+	}
+	payments, err := queue.NewSQSQueue(ctx, awsCfg, cfg.Queue.PaymentQueue)
+	if err != nil {
+		log.Fatalf("failed to open payment queue: %v", err)
+	}
+	deadLetter, err := queue.NewSQSQueue(ctx, awsCfg, cfg.Queue.PaymentDeadLetter)
+	if err != nil {
+		log.Fatalf("failed to open payment dead-letter queue: %v", err)
+	}
+	log.Printf("payment queue: sqs %s (dead-letter %s)", cfg.Queue.PaymentQueue, cfg.Queue.PaymentDeadLetter)
+	return payments, deadLetter
+}
+
+// configSources bootstraps the AWS/secrets settings from file and env, then
+// returns sources whose secret fields also resolve through the secrets provider
+func configSources(file string) (config.Sources, error) {
+	bootstrap, err := config.Parse(config.Sources{File: file})
+	if err != nil {
+		return config.Sources{}, err
+	}
+	provider := newSecretProvider(bootstrap)
+	return config.Sources{
+		File:   file,
+		Secret: secrets.Getter(context.Background(), provider),
+	}, nil
+}
+
+// emailerConfig maps the email settings onto the failover emailer, which
+// records deliveries and checks the suppression list in deliveries
+func emailerConfig(cfg config.EmailConfig, deliveries integrations.EmailLog) integrations.EmailerConfig {
+	return integrations.EmailerConfig{
+		DailyLimits: map[string]int{
+			integrations.ProviderBrevo:  cfg.BrevoDailyLimit,
+			integrations.ProviderResend: cfg.ResendDailyLimit,
+		},
+		FailureThreshold: cfg.FailureThreshold,
+		Cooldown:         cfg.Cooldown,
+		Deliveries:       deliveries,
+	}
+}
+
+// notifyPreferences maps the per-event channel lists onto notifier events
+func notifyPreferences(cfg config.NotifyConfig) map[string][]string {
+	return map[string][]string{
+		integrations.EventOrderConfirmation: cfg.OrderConfirmation,
+		integrations.EventDropWon:           cfg.DropWon,
+		integrations.EventDropLost:          cfg.DropLost,
+		integrations.EventTransferCode:      integrations.TransferCodePreferences(),
+	}
+}
+
+// zaloTemplates maps events to their ZNS template IDs
+func zaloTemplates(cfg config.NotifyConfig) map[string]string {
+	return map[string]string{
+		integrations.EventOrderConfirmation: cfg.ZaloOrderConfirmationTemplate,
+		integrations.EventDropWon:           cfg.ZaloDropWonTemplate,
+		integrations.EventDropLost:          cfg.ZaloDropLostTemplate,
+	}
+}
+
+// newAlerter builds the admin alerter from cfg; nil when alerts have
+// nowhere to go
+func newAlerter(cfg config.AlertsConfig, email *integrations.Emailer, logger *slog.Logger) *alerts.Alerter {
+	var sinks []alerts.Sink
+	if len(cfg.Emails) > 0 {
+		sinks = append(sinks, alerts.NewEmailSink(email, cfg.Emails))
+	}
+	if cfg.WebhookURL != "" {
+		sinks = append(sinks, alerts.NewWebhookSink(alerts.WebhookOptions{
+			URL:    cfg.WebhookURL,
+			Format: cfg.WebhookFormat,
+			ChatID: cfg.TelegramChatID,
+			Logger: logger,
+		}))
+	}
+	if len(sinks) == 0 {
+		log.Println("alerts: no ALERT_EMAILS or ALERT_WEBHOOK_URL, admin alerts are off")
+		return nil
+	}
+	kinds, err := alerts.ParseKinds(cfg.Events)
+	if err != nil {
+		log.Fatalf("alerts: %v", err)
+	}
+	return alerts.New(alerts.Options{
+		Sinks: sinks,
+		Kinds: kinds,
+		Rules: map[alerts.Kind]alerts.Rule{
+			alerts.WebhookSignature: {Threshold: cfg.SignatureFailures, Window: cfg.SignatureWindow, Throttle: cfg.SignatureWindow},
+		},
+		DigestAfter:    cfg.DigestAfter,
+		DigestInterval: cfg.DigestInterval,
+		Logger:         logger,
+	})
+}
+
+// credentials converts the integration sections of cfg
+func credentials(cfg *config.Config) integrations.Credentials {
+	return integrations.Credentials{
+		PayOS:      integrations.PayOSConfig(cfg.PayOS),
+		Brevo:      integrations.BrevoConfig(cfg.Brevo),
+		Resend:     integrations.ResendConfig(cfg.Resend),
+		Sheets:     integrations.SheetsConfig(cfg.Sheets),
+		SMS:        integrations.SMSConfig(cfg.SMS),
+		Zalo:       integrations.ZaloConfig(cfg.Zalo),
+		Cloudinary: integrations.CloudinaryConfig(cfg.Cloudinary),
+		Google:     integrations.GoogleConfig(cfg.Google),
+	}
+}
+
+// newSecretProvider resolves secrets from Secrets Manager (when enabled), then
+// SECRETS_DIR files, then the environment
+func newSecretProvider(cfg *config.Config) secrets.Provider {
+	var chain secrets.Chain
+	if cfg.AWS.UseSecrets {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		awsCfg, err := awsclient.Load(ctx, cfg.AWS)
+		if err != nil {
+			log.Fatalf("failed to load AWS config: %v", err)
+		}
+		chain = append(chain, secrets.NewSecretsManager(awsCfg, cfg.AWS.SecretsPrefix))
+		log.Printf("secrets: AWS Secrets Manager (prefix %q)", cfg.AWS.SecretsPrefix)
+	}
+	if cfg.Secrets.Dir != "" {
+		chain = append(chain, secrets.Dir{Path: cfg.Secrets.Dir})
+	}
+	return append(chain, secrets.Env{})
+}
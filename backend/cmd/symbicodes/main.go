@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"ecommerce-backend/config"
+	"ecommerce-backend/internal/database"
+	"ecommerce-backend/internal/labels"
+	"ecommerce-backend/internal/repository"
+	"ecommerce-backend/internal/service"
+)
+
+const usage = `Usage:
+  symbicodes generate -product ID -count N [-note TEXT] [-format csv|svg|pdf -output FILE]
+  symbicodes export   -batch ID [-format csv|svg|pdf] [-output FILE]
+  symbicodes bind     -code TOKEN -order ID
+
+Pre-generates codes for printing on tags before a drop, exports them as a CSV
+manifest or a printable A4 sheet of QR codes, and binds a tag to its order at
+fulfillment. Reads the same configuration as the server (-config or
+CONFIG_FILE, then the environment); SYMBICODE_SIGNING_KEY must be set.
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	cmd, args := os.Args[1], os.Args[2:]
+
+	var err error
+	switch cmd {
+	case "generate":
+		err = runGenerate(args)
+	case "export":
+		err = runExport(args)
+	case "bind":
+		err = runBind(args)
+	case "help", "-h", "-help":
+		fmt.Print(usage)
+		return
+	default:
+		err = fmt.Errorf("unknown command %q\n\n%s", cmd, usage)
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func runGenerate(args []string) error {
+	fs := flag.NewFlagSet("generate", flag.ExitOnError)
+	configFile := configFlag(fs)
+	productID := fs.Uint64("product", 0, "Product the codes are printed for")
+	count := fs.Int("count", 0, fmt.Sprintf("Number of codes (1-%d)", service.MaxSymbicodeBatch))
+	note := fs.String("note", "", "Free-form note, e.g. the factory order")
+	format := fs.String("format", "", "Also export the batch as csv, svg or pdf")
+	output := fs.String("output", "", "Export file (default stdout)")
+	fs.Parse(args)
+
+	if *productID == 0 {
+		return errors.New("-product is required")
+	}
+	svc, err := open(*configFile)
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+
+	batch, err := svc.GenerateSymbicodeBatch(context.Background(), *productID, *count, *note)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "✅ Batch %d: %d codes for product %d\n", batch.ID, batch.Count, batch.ProductID)
+
+	if *format == "" {
+		return nil
+	}
+	return export(svc, batch.ID, *format, *output)
+}
+
+func runExport(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	configFile := configFlag(fs)
+	batchID := fs.Uint64("batch", 0, "Batch to export")
+	format := fs.String("format", "csv", "csv, svg or pdf")
+	output := fs.String("output", "", "Output file (default stdout)")
+	fs.Parse(args)
+
+	if *batchID == 0 {
+		return errors.New("-batch is required")
+	}
+	svc, err := open(*configFile)
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+
+	return export(svc, *batchID, *format, *output)
+}
+
+func runBind(args []string) error {
+	fs := flag.NewFlagSet("bind", flag.ExitOnError)
+	configFile := configFlag(fs)
+	code := fs.String("code", "", "Token or scanned QR payload from the tag")
+	orderID := fs.Uint64("order", 0, "Paid order the tagged item ships with")
+	fs.Parse(args)
+
+	if *code == "" || *orderID == 0 {
+		return errors.New("-code and -order are required")
+	}
+	svc, err := open(*configFile)
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+
+	sym, err := svc.BindSymbicode(context.Background(), *code, *orderID)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("✅ Symbicode %d bound to order %d\n", sym.ID, sym.OrderID)
+	return nil
+}
+
+func configFlag(fs *flag.FlagSet) *string {
+	return fs.String("config", os.Getenv("CONFIG_FILE"), "Path to a YAML or TOML config file")
+}
+
+// open connects to the configured database and builds a service signing
+// with the configured key
+func open(configFile string) (service.Service, error) {
+	cfg, err := config.LoadWith(config.Sources{File: configFile})
+	if err != nil {
+		return nil, err
+	}
+	// A per-process key would print tags that never verify
+	if cfg.Symbicode.SigningKey == "" {
+		return nil, errors.New("SYMBICODE_SIGNING_KEY is required")
+	}
+
+	if err := database.ConnectWithOptions(cfg.Database.Path, database.Options{
+		MaxWriteConns: cfg.Database.MaxWriteConns,
+		MaxReadConns:  cfg.Database.MaxReadConns,
+		BusyTimeout:   cfg.Database.BusyTimeout,
+	}); err != nil {
+		return nil, err
+	}
+	repo := repository.NewRepository(database.NewSmartExecutor(database.DB.Writer, database.DB.Reader))
+	return service.NewService(repo, nil, nil, nil,
+		service.WithFrontendURL(cfg.Server.FrontendURL),
+		service.WithSymbicodeSigning([]byte(cfg.Symbicode.SigningKey), cfg.Symbicode.AcceptUnsigned),
+		service.WithPreviousSymbicodeKeys(cfg.Symbicode.PreviousKeys()...),
+	), nil
+}
+
+// export writes a batch's labels to output, or stdout when empty
+func export(svc service.Service, batchID uint64, format, output string) error {
+	if _, ok := labels.ContentTypes[format]; !ok {
+		return fmt.Errorf("unknown format %q (csv, svg or pdf)", format)
+	}
+	_, tags, err := svc.SymbicodeLabels(context.Background(), batchID)
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if output != "" {
+		f, err := os.Create(output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := labels.Write(w, format, tags); err != nil {
+		return err
+	}
+	if output != "" {
+		fmt.Fprintf(os.Stderr, "✅ %d labels written to %s\n", len(tags), output)
+	}
+	return nil
+}
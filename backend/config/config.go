@@ -1,78 +1,534 @@
 package config
 
 import (
-	"os"
-	"strconv"
+	"time"
 )
 
-// Config holds all configuration for the application
+// Config holds all configuration for the application.
+//
+// Every field is tagged with its config file key (`yaml`, also used for TOML)
+// and its environment variable(s) (`env`, first non-empty wins). Fields tagged
+// `secret:"true"` are also resolved through the secrets provider and are
+// redacted by --print-config. Precedence: defaults < file < env < secrets.
 type Config struct {
-	DatabaseURL string
-	Port        string
-	Environment string
-	// Database pool settings
-	MaxWriteConns int
-	MaxReadConns  int
-	BusyTimeout   int // milliseconds
+	Environment string `yaml:"environment" env:"ENV"`
+
+	Server   ServerConfig   `yaml:"server"`
+	Log      LogConfig      `yaml:"log"`
+	Tracing  TracingConfig  `yaml:"tracing"`
+	Database DatabaseConfig `yaml:"database"`
 
 	// AWS/LocalStack Configuration
-	AWS AWSConfig
+	AWS     AWSConfig     `yaml:"aws"`
+	Secrets SecretsConfig `yaml:"secrets"`
+
+	// Continuous WAL shipping to S3 (enabled by AWS.UseS3)
+	Replication ReplicationConfig `yaml:"replication"`
+
+	// Readiness checks served on /readyz
+	Health HealthConfig `yaml:"health"`
+
+	// Background payment processing (SQS when AWS.UseSQS, inline otherwise)
+	Queue QueueConfig `yaml:"queue"`
+
+	// Periodic jobs and their settings
+	Scheduler SchedulerConfig `yaml:"scheduler"`
+	Symbicode SymbicodeConfig `yaml:"symbicode"`
+	Orders    OrdersConfig    `yaml:"orders"`
+
+	// Abuse protection for purchase, verify and order lookup endpoints
+	RateLimit RateLimitConfig `yaml:"rate_limit"`
+	BotCheck  BotCheckConfig  `yaml:"bot_check"`
+
+	// Integrations; field layout matches the integrations package types
+	PayOS      PayOSConfig      `yaml:"payos"`
+	Brevo      BrevoConfig      `yaml:"brevo"`
+	Resend     ResendConfig     `yaml:"resend"`
+	Sheets     SheetsConfig     `yaml:"sheets"`
+	SMS        SMSConfig        `yaml:"sms"`
+	Zalo       ZaloConfig       `yaml:"zalo"`
+	Cloudinary CloudinaryConfig `yaml:"cloudinary"`
+	Google     GoogleConfig     `yaml:"google"`
+
+	// Failover between the email providers
+	Email EmailConfig `yaml:"email"`
+
+	// Which channels tell customers about each event
+	Notify NotifyConfig `yaml:"notify"`
+
+	// Admin alerts on paid orders, failed refunds, signature failures and sold out drops
+	Alerts AlertsConfig `yaml:"alerts"`
+
+	// Batched export of paid orders to the Google Sheets spreadsheet
+	SheetSync SheetSyncConfig `yaml:"sheet_sync"`
+
+	// Outbound webhooks for order, drop and symbicode events
+	Webhooks WebhooksConfig `yaml:"webhooks"`
+}
+
+// ServerConfig holds HTTP server settings
+type ServerConfig struct {
+	Port        string   `yaml:"port" env:"PORT"`
+	CORSOrigins []string `yaml:"cors_origins" env:"CORS_ORIGINS"`
+	// FrontendURL is where PayOS sends buyers back after checkout
+	FrontendURL string `yaml:"frontend_url" env:"FRONTEND_URL"`
+	PprofAddr   string `yaml:"pprof_addr" env:"PPROF_ADDR"`
+	// TrustedProxies (IPs or CIDRs) may set ProxyHeader; the client IP is read
+	// from it only for requests arriving from them
+	TrustedProxies []string `yaml:"trusted_proxies" env:"TRUSTED_PROXIES"`
+	ProxyHeader    string   `yaml:"proxy_header" env:"PROXY_HEADER"`
+	// AdminToken enables /api/admin/* for "Authorization: Bearer <token>"; empty disables them
+	AdminToken string `yaml:"admin_token" env:"ADMIN_API_TOKEN" secret:"true"`
+}
+
+// LogConfig holds structured logging settings
+type LogConfig struct {
+	Level  string `yaml:"level" env:"LOG_LEVEL"`
+	Format string `yaml:"format" env:"LOG_FORMAT"` // text or json
+	// Levels overrides Level per subsystem: payos=debug,queue=warn
+	Levels []string `yaml:"levels" env:"LOG_LEVELS"`
+}
+
+// TracingConfig holds OpenTelemetry trace export settings
+type TracingConfig struct {
+	// Exporter is none, stdout (pretty-printed spans, for local runs) or otlp
+	Exporter string `yaml:"exporter" env:"OTEL_TRACES_EXPORTER"`
+	// Endpoint is the OTLP/HTTP collector, e.g. http://localhost:4318
+	Endpoint    string  `yaml:"endpoint" env:"OTEL_EXPORTER_OTLP_TRACES_ENDPOINT,OTEL_EXPORTER_OTLP_ENDPOINT"`
+	ServiceName string  `yaml:"service_name" env:"OTEL_SERVICE_NAME"`
+	SampleRatio float64 `yaml:"sample_ratio" env:"OTEL_TRACES_SAMPLER_ARG"` // 0..1 of new traces
+}
+
+// DatabaseConfig holds SQLite settings
+type DatabaseConfig struct {
+	Path string `yaml:"path" env:"DATABASE_URL,DB_PATH"`
+	// Database pool settings
+	MaxWriteConns int `yaml:"max_write_conns" env:"MAX_WRITE_CONNS"`
+	MaxReadConns  int `yaml:"max_read_conns" env:"MAX_READ_CONNS"`
+	BusyTimeout   int `yaml:"busy_timeout_ms" env:"DB_BUSY_TIMEOUT"` // milliseconds
 }
 
 // AWSConfig holds AWS-specific settings
 type AWSConfig struct {
-	Endpoint   string
-	Region     string
-	AccessKey  string
-	SecretKey  string
-	UseSQS     bool
-	UseS3      bool
-	UseSecrets bool
+	Endpoint   string `yaml:"endpoint" env:"AWS_ENDPOINT_URL"`
+	Region     string `yaml:"region" env:"AWS_DEFAULT_REGION,AWS_REGION"`
+	AccessKey  string `yaml:"access_key_id" env:"AWS_ACCESS_KEY_ID" secret:"true"`
+	SecretKey  string `yaml:"secret_access_key" env:"AWS_SECRET_ACCESS_KEY" secret:"true"`
+	UseSQS     bool   `yaml:"use_sqs" env:"USE_SQS"`
+	UseS3      bool   `yaml:"use_s3" env:"USE_S3"`
+	UseSecrets bool   `yaml:"use_secrets_manager" env:"USE_SECRETS_MANAGER"`
+	S3Bucket   string `yaml:"s3_bucket" env:"S3_BUCKET"`
+	// SecretsPrefix namespaces Secrets Manager names (PAYOS_API_KEY -> donald/payos-api-key)
+	SecretsPrefix string `yaml:"secrets_prefix" env:"SECRETS_PREFIX"`
 }
 
-// Load loads configuration from environment variables
-func Load() *Config {
-	config := &Config{
-		DatabaseURL:   getEnv("DATABASE_URL", "./database.db"),
-		Port:          getEnv("PORT", "3030"),
-		Environment:   getEnv("ENV", "development"),
-		MaxWriteConns: getEnvAsInt("MAX_WRITE_CONNS", 1),    // SQLite writer uses 1 connection
-		MaxReadConns:  getEnvAsInt("MAX_READ_CONNS", 100),   // Reader supports 100 concurrent connections
-		BusyTimeout:   getEnvAsInt("DB_BUSY_TIMEOUT", 5000), // 5 seconds
+// SecretsConfig holds file-based secret settings
+type SecretsConfig struct {
+	// Dir holds one file per secret (Docker/Kubernetes secrets); optional
+	Dir string `yaml:"dir" env:"SECRETS_DIR"`
+}
 
-		AWS: AWSConfig{
-			Endpoint:   getEnv("AWS_ENDPOINT_URL", ""),
-			Region:     getEnv("AWS_DEFAULT_REGION", "us-east-1"),
-			AccessKey:  getEnv("AWS_ACCESS_KEY_ID", ""),
-			SecretKey:  getEnv("AWS_SECRET_ACCESS_KEY", ""),
-			UseSQS:     getEnv("USE_SQS", "false") == "true",
-			UseS3:      getEnv("USE_S3", "false") == "true",
-			UseSecrets: getEnv("USE_SECRETS_MANAGER", "false") == "true",
-		},
-	}
+// ReplicationConfig holds settings for continuous database replication
+type ReplicationConfig struct {
+	Prefix           string        `yaml:"prefix" env:"REPLICATION_PREFIX"`
+	SyncInterval     time.Duration `yaml:"sync_interval" env:"REPLICATION_SYNC_INTERVAL"`
+	SnapshotInterval time.Duration `yaml:"snapshot_interval" env:"REPLICATION_SNAPSHOT_INTERVAL"`
+	Retention        time.Duration `yaml:"retention" env:"REPLICATION_RETENTION"`
+}
 
-	// Handle legacy DB_PATH if DATABASE_URL not set
-	if config.DatabaseURL == "" {
-		config.DatabaseURL = getEnv("DB_PATH", "./database.db")
-	}
+// HealthConfig holds /readyz thresholds
+type HealthConfig struct {
+	Timeout       time.Duration `yaml:"timeout" env:"HEALTH_TIMEOUT"` // per check
+	MaxWALMB      int           `yaml:"max_wal_mb" env:"HEALTH_MAX_WAL_MB"`
+	MinFreeDiskMB int           `yaml:"min_free_disk_mb" env:"HEALTH_MIN_FREE_DISK_MB"`
+	// CheckIntegrations probes configured PayOS/Brevo/Sheets endpoints,
+	// at most once per IntegrationsTTL; failures only degrade readiness
+	CheckIntegrations bool          `yaml:"check_integrations" env:"HEALTH_CHECK_INTEGRATIONS"`
+	IntegrationsTTL   time.Duration `yaml:"integrations_ttl" env:"HEALTH_INTEGRATIONS_TTL"`
+}
 
-	return config
+// QueueConfig holds settings for the payment job queue and its workers
+type QueueConfig struct {
+	PaymentQueue      string        `yaml:"payment_queue" env:"SQS_PAYMENT_QUEUE"`
+	PaymentDeadLetter string        `yaml:"payment_dead_letter" env:"SQS_PAYMENT_DLQ"`
+	Workers           int           `yaml:"workers" env:"PAYMENT_WORKERS"`
+	Visibility        time.Duration `yaml:"visibility_timeout" env:"PAYMENT_VISIBILITY_TIMEOUT"`
+	MaxAttempts       int           `yaml:"max_attempts" env:"PAYMENT_MAX_ATTEMPTS"`
+	// Memory queues payments in process without SQS, for load tests; jobs
+	// still queued on exit are lost although PayOS was told they succeeded
+	Memory bool `yaml:"memory" env:"PAYMENT_QUEUE_MEMORY"`
+}
+
+// SchedulerConfig holds periodic job runner settings
+type SchedulerConfig struct {
+	Enabled bool `yaml:"enabled" env:"SCHEDULER_ENABLED"`
+	// InstanceID names this instance in job leases; empty means hostname-pid
+	InstanceID string `yaml:"instance_id" env:"SCHEDULER_INSTANCE_ID"`
 }
 
-// getEnv gets an environment variable with a fallback value
-func getEnv(key, fallback string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+// SymbicodeConfig holds authenticity code settings
+type SymbicodeConfig struct {
+	// SigningKey signs label tokens; labels printed under it stop verifying
+	// if it changes, unless it moves to PreviousSigningKeys. Empty uses a
+	// per-process key (dev only).
+	SigningKey string `yaml:"signing_key" env:"SYMBICODE_SIGNING_KEY" secret:"true"`
+	// PreviousSigningKeys keep verifying labels printed under keys rotated
+	// out; a token no key in the ring signed is rejected before any lookup
+	PreviousSigningKeys []string `yaml:"previous_signing_keys" env:"SYMBICODE_PREVIOUS_SIGNING_KEYS" secret:"true"`
+	// AcceptUnsigned keeps verifying bare UUIDs from labels printed before tokens
+	AcceptUnsigned bool `yaml:"accept_unsigned" env:"SYMBICODE_ACCEPT_UNSIGNED"`
+	// A code is suspicious once scanned from this many distinct IPs or
+	// locations (0 disables the rule)
+	SuspiciousIPs       int `yaml:"suspicious_ips" env:"SYMBICODE_SUSPICIOUS_IPS"`
+	SuspiciousLocations int `yaml:"suspicious_locations" env:"SYMBICODE_SUSPICIOUS_LOCATIONS"`
+	// GeoIPDatabase is a MaxMind GeoLite2 City/Country .mmdb; empty records no location
+	GeoIPDatabase string `yaml:"geoip_database" env:"GEOIP_DB_PATH"`
+	// TransferTTL is how long a resale transfer offer can be claimed
+	TransferTTL time.Duration `yaml:"transfer_ttl" env:"SYMBICODE_TRANSFER_TTL"`
+
+	// AutoActivateAfter is the grace period after which unscanned codes are activated
+	AutoActivateAfter    time.Duration `yaml:"auto_activate_after" env:"SYMBICODE_AUTO_ACTIVATE_AFTER"`
+	AutoActivateSchedule string        `yaml:"auto_activate_schedule" env:"SYMBICODE_AUTO_ACTIVATE_SCHEDULE"` // cron or @every
+}
+
+// PreviousKeys returns PreviousSigningKeys as keys for the service
+func (c SymbicodeConfig) PreviousKeys() [][]byte {
+	keys := make([][]byte, len(c.PreviousSigningKeys))
+	for i, k := range c.PreviousSigningKeys {
+		keys[i] = []byte(k)
 	}
-	return fallback
+	return keys
+}
+
+// OrdersConfig holds public order number and guest lookup settings
+type OrdersConfig struct {
+	// NumberKey permutes order IDs into public order numbers; numbers already
+	// emailed stop resolving if it changes. Empty uses a development key.
+	NumberKey string `yaml:"number_key" env:"ORDER_NUMBER_KEY" secret:"true"`
+	// LegacyMaxID is the last order ID emailed in the legacy unkeyed format
+	// (DV-{base32 of the decimal ID}); later IDs only resolve by the keyed
+	// number. 0 rejects every legacy number.
+	LegacyMaxID int `yaml:"legacy_max_id" env:"ORDER_LEGACY_MAX_ID"`
+	// LookupLinkTTL is how long an emailed one-time order link can be opened
+	LookupLinkTTL time.Duration `yaml:"lookup_link_ttl" env:"ORDER_LOOKUP_LINK_TTL"`
+	// RefundSchedule of the refunds job, which refunds the orders that lost
+	// their drop; RefundAttempts per refund, RefundBackoff doubling between
+	// them, before admins are alerted
+	RefundSchedule string        `yaml:"refund_schedule" env:"ORDER_REFUND_SCHEDULE"` // cron or @every
+	RefundAttempts int           `yaml:"refund_attempts" env:"ORDER_REFUND_ATTEMPTS"`
+	RefundBackoff  time.Duration `yaml:"refund_backoff" env:"ORDER_REFUND_BACKOFF"`
+}
+
+// RateLimitConfig holds the token bucket rules and their store
+type RateLimitConfig struct {
+	Enabled bool `yaml:"enabled" env:"RATE_LIMIT_ENABLED"`
+	// Rules are route:key=events/period, e.g. purchase:phone=3/10m. Routes are
+	// purchase, verify and orders; keys are ip, phone, fingerprint and order
+	// (the order number).
+	Rules []string `yaml:"rules" env:"RATE_LIMIT_RULES"`
+	// RedisURL (redis://...) shares buckets between instances; empty keeps them in memory
+	RedisURL string `yaml:"redis_url" env:"RATE_LIMIT_REDIS_URL" secret:"true"`
+}
+
+// BotCheckConfig holds the proof-of-work or CAPTCHA check on drop purchases
+type BotCheckConfig struct {
+	// Kind is none, pow or captcha
+	Kind string `yaml:"kind" env:"BOT_CHECK"`
+	// PoWDifficulty is the leading zero bits a solution needs (~2^n hashes)
+	PoWDifficulty int           `yaml:"pow_difficulty" env:"BOT_CHECK_POW_DIFFICULTY"`
+	PoWTTL        time.Duration `yaml:"pow_ttl" env:"BOT_CHECK_POW_TTL"`
+	// PoWKey signs challenges; empty uses a per-process key (single instance only)
+	PoWKey string `yaml:"pow_key" env:"BOT_CHECK_POW_KEY" secret:"true"`
+	// CAPTCHA provider (Turnstile by default; hCaptcha and reCAPTCHA use the same protocol)
+	CaptchaVerifyURL string `yaml:"captcha_verify_url" env:"CAPTCHA_VERIFY_URL"`
+	CaptchaSiteKey   string `yaml:"captcha_site_key" env:"CAPTCHA_SITE_KEY"`
+	CaptchaSecret    string `yaml:"captcha_secret" env:"CAPTCHA_SECRET" secret:"true"`
+}
+
+// PayOSConfig holds PayOS credentials and endpoint overrides
+type PayOSConfig struct {
+	ClientID    string `yaml:"client_id" env:"PAYOS_CLIENT_ID" secret:"true"`
+	APIKey      string `yaml:"api_key" env:"PAYOS_API_KEY" secret:"true"`
+	ChecksumKey string `yaml:"checksum_key" env:"PAYOS_CHECKSUM_KEY" secret:"true"`
+	CheckoutURL string `yaml:"checkout_url" env:"PAYOS_CHECKOUT_URL"`
+	BaseURL     string `yaml:"base_url" env:"PAYOS_BASE_URL"`
+	RefundURL   string `yaml:"refund_url" env:"PAYOS_REFUND_URL"`
+	ReturnURL   string `yaml:"return_url" env:"PAYOS_RETURN_URL"`
+	CancelURL   string `yaml:"cancel_url" env:"PAYOS_CANCEL_URL"`
+}
+
+// BrevoConfig holds Brevo credentials
+type BrevoConfig struct {
+	APIKey  string `yaml:"api_key" env:"BREVO_API_KEY" secret:"true"`
+	BaseURL string `yaml:"base_url" env:"BREVO_BASE_URL"`
+	// WebhookToken authenticates Brevo's delivery webhooks, sent as a
+	// bearer token or ?token=; empty disables the endpoint
+	WebhookToken string `yaml:"webhook_token" env:"BREVO_WEBHOOK_TOKEN" secret:"true"`
 }
 
-// getEnvAsInt gets an environment variable as integer with a fallback value
-func getEnvAsInt(key string, fallback int) int {
-	if value := os.Getenv(key); value != "" {
-		if intValue, err := strconv.Atoi(value); err == nil {
-			return intValue
-		}
+// ResendConfig holds Resend credentials
+type ResendConfig struct {
+	APIKey    string `yaml:"api_key" env:"RESEND_API_KEY" secret:"true"`
+	FromEmail string `yaml:"from_email" env:"RESEND_FROM_EMAIL"`
+	BaseURL   string `yaml:"base_url" env:"RESEND_BASE_URL"`
+	// WebhookSecret ("whsec_...") verifies Resend's signed delivery
+	// webhooks; empty disables the endpoint
+	WebhookSecret string `yaml:"webhook_secret" env:"RESEND_WEBHOOK_SECRET" secret:"true"`
+}
+
+// EmailConfig holds failover between Brevo and Resend
+type EmailConfig struct {
+	// Providers are tried in order: brevo, resend
+	Providers []string `yaml:"providers" env:"EMAIL_PROVIDERS"`
+	// Daily caps per provider (UTC days, counted per instance); 0 disables a cap
+	BrevoDailyLimit  int `yaml:"brevo_daily_limit" env:"BREVO_DAILY_LIMIT"`
+	ResendDailyLimit int `yaml:"resend_daily_limit" env:"RESEND_DAILY_LIMIT"`
+	// FailureThreshold consecutive errors take a provider out of rotation for Cooldown
+	FailureThreshold int           `yaml:"failure_threshold" env:"EMAIL_FAILURE_THRESHOLD"`
+	Cooldown         time.Duration `yaml:"cooldown" env:"EMAIL_FAILOVER_COOLDOWN"`
+}
+
+// SMSConfig holds the eSMS brand-name SMS credentials
+type SMSConfig struct {
+	APIKey    string `yaml:"api_key" env:"SMS_API_KEY" secret:"true"`
+	SecretKey string `yaml:"secret_key" env:"SMS_SECRET_KEY" secret:"true"`
+	// BrandName is the registered sender name shown on the phone
+	BrandName string `yaml:"brand_name" env:"SMS_BRAND_NAME"`
+	BaseURL   string `yaml:"base_url" env:"SMS_BASE_URL"`
+}
+
+// ZaloConfig holds the Zalo Official Account token for ZNS messages
+type ZaloConfig struct {
+	AccessToken string `yaml:"access_token" env:"ZALO_ACCESS_TOKEN" secret:"true"`
+	BaseURL     string `yaml:"base_url" env:"ZALO_BASE_URL"`
+}
+
+// CloudinaryConfig holds the image upload credentials
+type CloudinaryConfig struct {
+	CloudName string `yaml:"cloud_name" env:"CLOUDINARY_CLOUD_NAME"`
+	APIKey    string `yaml:"api_key" env:"CLOUDINARY_API_KEY" secret:"true"`
+	APISecret string `yaml:"api_secret" env:"CLOUDINARY_API_SECRET" secret:"true"`
+	// UploadPreset uploads unsigned when set
+	UploadPreset string `yaml:"upload_preset" env:"CLOUDINARY_UPLOAD_PRESET"`
+}
+
+// GoogleConfig holds the OAuth client that Google ID tokens are issued to
+type GoogleConfig struct {
+	ClientID string `yaml:"client_id" env:"GOOGLE_CLIENT_ID"`
+}
+
+// NotifyConfig holds the channels per customer event and the Zalo templates
+type NotifyConfig struct {
+	// Channels per event: email, sms or zalo. Every entry is sent; an entry
+	// like zalo|sms falls back to the next channel when one can't deliver.
+	OrderConfirmation []string `yaml:"order_confirmation" env:"NOTIFY_ORDER_CONFIRMATION"`
+	DropWon           []string `yaml:"drop_won" env:"NOTIFY_DROP_WON"`
+	DropLost          []string `yaml:"drop_lost" env:"NOTIFY_DROP_LOST"`
+	// Approved ZNS template IDs; zalo is skipped for events without one
+	ZaloOrderConfirmationTemplate string `yaml:"zalo_order_confirmation_template" env:"ZALO_TEMPLATE_ORDER_CONFIRMATION"`
+	ZaloDropWonTemplate           string `yaml:"zalo_drop_won_template" env:"ZALO_TEMPLATE_DROP_WON"`
+	ZaloDropLostTemplate          string `yaml:"zalo_drop_lost_template" env:"ZALO_TEMPLATE_DROP_LOST"`
+}
+
+// AlertsConfig holds the admin alerts and where they go
+type AlertsConfig struct {
+	// Events to alert on: order_paid, refund_failed, webhook_signature, drop_sold_out
+	Events []string `yaml:"events" env:"ALERT_EVENTS"`
+	// Emails receive alerts through the email providers
+	Emails []string `yaml:"emails" env:"ALERT_EMAILS,ADMIN_ORDER_EMAILS"`
+	// WebhookURL receives alerts as chat messages in WebhookFormat: slack,
+	// discord or telegram (https://api.telegram.org/bot<token>/sendMessage
+	// posting to TelegramChatID). No emails and no URL disables alerts.
+	WebhookURL     string `yaml:"webhook_url" env:"ALERT_WEBHOOK_URL" secret:"true"`
+	WebhookFormat  string `yaml:"webhook_format" env:"ALERT_WEBHOOK_FORMAT"`
+	TelegramChatID string `yaml:"telegram_chat_id" env:"ALERT_TELEGRAM_CHAT_ID"`
+	// SignatureFailures rejected webhooks from one provider within
+	// SignatureWindow raise an alert, at most once per window
+	SignatureFailures int           `yaml:"signature_failures" env:"ALERT_SIGNATURE_FAILURES"`
+	SignatureWindow   time.Duration `yaml:"signature_window" env:"ALERT_SIGNATURE_WINDOW"`
+	// DigestAfter alerts within a minute switch to one digest per
+	// DigestInterval until an interval passes quietly; 0 never switches
+	DigestAfter    int           `yaml:"digest_after" env:"ALERT_DIGEST_AFTER"`
+	DigestInterval time.Duration `yaml:"digest_interval" env:"ALERT_DIGEST_INTERVAL"`
+}
+
+// SheetSyncConfig holds how paid orders are exported to the spreadsheet
+type SheetSyncConfig struct {
+	// Schedule of the sheet-sync job, which exports the queued rows
+	Schedule string `yaml:"schedule" env:"SHEETS_SYNC_SCHEDULE"` // cron or @every
+	// Columns of the sheet, left to right: paid_at, created_at, order_number,
+	// order_id, name, phone, email, address, items, amount, status, note,
+	// or - for an empty column
+	Columns []string `yaml:"columns" env:"SHEETS_COLUMNS"`
+	// BatchSize rows go out per Sheets API call
+	BatchSize int `yaml:"batch_size" env:"SHEETS_BATCH_SIZE"`
+	// Attempts per batch within a run; Backoff doubles between them
+	Attempts int           `yaml:"attempts" env:"SHEETS_ATTEMPTS"`
+	Backoff  time.Duration `yaml:"backoff" env:"SHEETS_RETRY_BACKOFF"`
+}
+
+// WebhooksConfig holds how outbound webhooks are delivered; subscribers
+// are registered on /api/admin/webhooks
+type WebhooksConfig struct {
+	// Attempts per delivery; Backoff doubles between them up to MaxBackoff
+	MaxAttempts int           `yaml:"max_attempts" env:"WEBHOOK_MAX_ATTEMPTS"`
+	Backoff     time.Duration `yaml:"backoff" env:"WEBHOOK_RETRY_BACKOFF"`
+	MaxBackoff  time.Duration `yaml:"max_backoff" env:"WEBHOOK_MAX_BACKOFF"`
+	// Timeout per POST
+	Timeout time.Duration `yaml:"timeout" env:"WEBHOOK_TIMEOUT"`
+	// Workers POST at the same time
+	Workers int `yaml:"workers" env:"WEBHOOK_WORKERS"`
+	// DropStartSchedule of the drop-start-webhooks job, which sends
+	// drop.started for drops that started within DropStartWindow
+	DropStartSchedule string        `yaml:"drop_start_schedule" env:"WEBHOOK_DROP_START_SCHEDULE"` // cron or @every
+	DropStartWindow   time.Duration `yaml:"drop_start_window" env:"WEBHOOK_DROP_START_WINDOW"`
+}
+
+// SheetsConfig holds the Google Sheets target and service account
+type SheetsConfig struct {
+	SpreadsheetID      string `yaml:"spreadsheet_id" env:"GSSHEET_SPREADSHEET_ID"`
+	SheetName          string `yaml:"sheet_name" env:"GSSHEET_SHEET_NAME"`
+	ServiceAccountJSON string `yaml:"service_account_json" env:"GDRIVE_SERVICE_ACCOUNT_JSON" secret:"true"`
+	ServiceAccountPath string `yaml:"service_account_path" env:"GDRIVE_SERVICE_ACCOUNT"`
+}
+
+// Default returns the configuration used when nothing is overridden
+func Default() *Config {
+	return &Config{
+		Environment: "development",
+		Server: ServerConfig{
+			Port:           "3030",
+			CORSOrigins:    []string{"http://localhost:5173", "http://localhost:3000", "http://127.0.0.1:5173"},
+			FrontendURL:    "http://localhost:3000",
+			PprofAddr:      "localhost:6060",
+			TrustedProxies: []string{},
+			ProxyHeader:    "X-Forwarded-For",
+		},
+		Log: LogConfig{
+			Level:  "info",
+			Format: "text",
+		},
+		Tracing: TracingConfig{
+			Exporter:    "none",
+			Endpoint:    "http://localhost:4318",
+			ServiceName: "donald-backend",
+			SampleRatio: 1,
+		},
+		Database: DatabaseConfig{
+			Path:          "./database.db",
+			MaxWriteConns: 1,    // SQLite writer uses 1 connection
+			MaxReadConns:  100,  // Reader supports 100 concurrent connections
+			BusyTimeout:   5000, // 5 seconds
+		},
+		AWS: AWSConfig{
+			Region:        "us-east-1",
+			S3Bucket:      "donald-vibe",
+			SecretsPrefix: "donald/",
+		},
+		Replication: ReplicationConfig{
+			Prefix:           "replica/database",
+			SyncInterval:     time.Second,
+			SnapshotInterval: 24 * time.Hour,
+			Retention:        72 * time.Hour,
+		},
+		Health: HealthConfig{
+			Timeout:         2 * time.Second,
+			MaxWALMB:        256,
+			MinFreeDiskMB:   512,
+			IntegrationsTTL: time.Minute,
+		},
+		Queue: QueueConfig{
+			PaymentQueue:      "donald-orders",
+			PaymentDeadLetter: "donald-orders-dlq",
+			Workers:           4,
+			Visibility:        60 * time.Second,
+			MaxAttempts:       5,
+		},
+		Scheduler: SchedulerConfig{
+			Enabled: true,
+		},
+		Symbicode: SymbicodeConfig{
+			AutoActivateAfter:    72 * time.Hour,
+			AutoActivateSchedule: "@hourly",
+			SuspiciousIPs:        5,
+			SuspiciousLocations:  3,
+			TransferTTL:          7 * 24 * time.Hour,
+		},
+		Orders: OrdersConfig{
+			LookupLinkTTL:  30 * time.Minute,
+			RefundSchedule: "@every 1m",
+			RefundAttempts: 5,
+			RefundBackoff:  time.Minute,
+		},
+		RateLimit: RateLimitConfig{
+			Enabled: true,
+			Rules: []string{
+				"purchase:ip=10/1m",
+				"purchase:phone=3/10m",
+				"purchase:fingerprint=5/1m",
+				"verify:ip=30/1m",
+				"verify:fingerprint=30/1m",
+				"transfers:ip=10/1m",
+				"transfers:fingerprint=10/1m",
+				"orders:ip=10/1m",
+				"orders:fingerprint=10/1m",
+				"orders:order=5/10m",
+			},
+		},
+		BotCheck: BotCheckConfig{
+			Kind:             "none",
+			PoWDifficulty:    18,
+			PoWTTL:           2 * time.Minute,
+			CaptchaVerifyURL: "https://challenges.cloudflare.com/turnstile/v0/siteverify",
+		},
+		Sheets: SheetsConfig{
+			SheetName:          "Sheet1",
+			ServiceAccountPath: "./gdrive-service-account.json",
+		},
+		Email: EmailConfig{
+			Providers:        []string{"brevo", "resend"},
+			BrevoDailyLimit:  300,
+			ResendDailyLimit: 100,
+			FailureThreshold: 3,
+			Cooldown:         time.Minute,
+		},
+		Notify: NotifyConfig{
+			OrderConfirmation: []string{"email"},
+			DropWon:           []string{"email"},
+			DropLost:          []string{"email"},
+		},
+		SheetSync: SheetSyncConfig{
+			Schedule:  "@every 1m",
+			Columns:   []string{"paid_at", "name", "phone", "email", "address", "note", "amount"},
+			BatchSize: 200,
+			Attempts:  3,
+			Backoff:   2 * time.Second,
+		},
+		Webhooks: WebhooksConfig{
+			MaxAttempts:       8,
+			Backoff:           30 * time.Second,
+			MaxBackoff:        time.Hour,
+			Timeout:           10 * time.Second,
+			Workers:           4,
+			DropStartSchedule: "@every 1m",
+			DropStartWindow:   time.Hour,
+		},
+		Alerts: AlertsConfig{
+			Events:            []string{"order_paid", "refund_failed", "webhook_signature", "drop_sold_out"},
+			Emails:            []string{},
+			WebhookFormat:     "slack",
+			SignatureFailures: 5,
+			SignatureWindow:   5 * time.Minute,
+			DigestAfter:       10,
+			DigestInterval:    5 * time.Minute,
+		},
 	}
-	return fallback
+}
+
+// Load loads configuration from defaults and environment variables.
+// Malformed values keep their defaults; use LoadWith to get errors and validation.
+func Load() *Config {
+	config := Default()
+	applyEnv(config, Sources{}.env(), nil)
+	return config
 }
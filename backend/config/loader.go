@@ -0,0 +1,258 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Lookup returns the value of key, or "" when it is not set
+type Lookup func(key string) (string, error)
+
+// Sources says where LoadWith reads configuration from
+type Sources struct {
+	// File is an optional YAML (.yaml/.yml) or TOML (.toml) config file
+	File string
+	// Env overrides file values; nil reads the process environment
+	Env Lookup
+	// Secret resolves fields tagged secret (e.g. Secrets Manager); nil uses Env only
+	Secret Lookup
+}
+
+func (s Sources) env() Lookup {
+	if s.Env != nil {
+		return s.Env
+	}
+	return func(key string) (string, error) { return os.Getenv(key), nil }
+}
+
+// LoadWith loads defaults, then the config file, then environment and secret
+// overrides, and validates the result. All problems are reported together.
+func LoadWith(src Sources) (*Config, error) {
+	config, err := Parse(src)
+	if err != nil {
+		return nil, err
+	}
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration:\n%w", err)
+	}
+	return config, nil
+}
+
+// Parse is LoadWith without validation, for bootstrapping (e.g. reading the
+// AWS settings needed to build the secrets provider)
+func Parse(src Sources) (*Config, error) {
+	config := Default()
+	if src.File != "" {
+		if err := applyFile(config, src.File); err != nil {
+			return nil, err
+		}
+	}
+	if errs := applyEnv(config, src.env(), src.Secret); len(errs) > 0 {
+		return nil, fmt.Errorf("invalid configuration:\n%w", errors.Join(errs...))
+	}
+	return config, nil
+}
+
+// field is one leaf setting of the config tree
+type field struct {
+	key    string // dotted file key, e.g. server.port
+	env    []string
+	secret bool
+	value  reflect.Value
+}
+
+// name returns the key and its first env var for error messages
+func (f field) name() string {
+	if len(f.env) == 0 {
+		return f.key
+	}
+	return fmt.Sprintf("%s (%s)", f.key, f.env[0])
+}
+
+func fields(config *Config) []field {
+	var out []field
+	var walk func(v reflect.Value, prefix string)
+	walk = func(v reflect.Value, prefix string) {
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			key := sf.Tag.Get("yaml")
+			if prefix != "" {
+				key = prefix + "." + key
+			}
+			fv := v.Field(i)
+			if fv.Kind() == reflect.Struct && fv.Type() != reflect.TypeOf(time.Duration(0)) {
+				walk(fv, key)
+				continue
+			}
+			f := field{key: key, secret: sf.Tag.Get("secret") == "true", value: fv}
+			if env := sf.Tag.Get("env"); env != "" {
+				f.env = strings.Split(env, ",")
+			}
+			out = append(out, f)
+		}
+	}
+	walk(reflect.ValueOf(config).Elem(), "")
+	return out
+}
+
+// applyEnv overrides fields from env, then secret fields from secret
+func applyEnv(config *Config, env, secret Lookup) []error {
+	var errs []error
+	for _, f := range fields(config) {
+		lookups := []Lookup{env}
+		if f.secret && secret != nil {
+			lookups = append(lookups, secret)
+		}
+		for _, lookup := range lookups {
+			for _, key := range f.env {
+				raw, err := lookup(key)
+				if err != nil {
+					errs = append(errs, fmt.Errorf("  - %s: %w", f.name(), err))
+					break
+				}
+				if raw == "" {
+					continue
+				}
+				if err := setString(f.value, raw); err != nil {
+					errs = append(errs, fmt.Errorf("  - %s: %w", f.name(), err))
+				}
+				break
+			}
+		}
+	}
+	return errs
+}
+
+// applyFile reads a YAML or TOML file over config. Unknown keys are errors so
+// typos do not silently fall back to defaults.
+func applyFile(config *Config, path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read config file: %w", err)
+	}
+
+	raw := map[string]any{}
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(b, &raw)
+	case ".toml":
+		err = toml.Unmarshal(b, &raw)
+	default:
+		return fmt.Errorf("config file %s: unsupported extension %q (use .yaml, .yml or .toml)", path, ext)
+	}
+	if err != nil {
+		return fmt.Errorf("parse config file %s: %w", path, err)
+	}
+
+	values := map[string]any{}
+	flatten(raw, "", values)
+
+	byKey := map[string]field{}
+	for _, f := range fields(config) {
+		byKey[f.key] = f
+	}
+
+	var errs []error
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		f, ok := byKey[k]
+		if !ok {
+			errs = append(errs, fmt.Errorf("  - %s: unknown key", k))
+			continue
+		}
+		if err := setAny(f.value, values[k]); err != nil {
+			errs = append(errs, fmt.Errorf("  - %s: %w", f.name(), err))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("config file %s:\n%w", path, errors.Join(errs...))
+	}
+	return nil
+}
+
+func flatten(m map[string]any, prefix string, out map[string]any) {
+	for k, v := range m {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		if sub, ok := v.(map[string]any); ok {
+			flatten(sub, key, out)
+			continue
+		}
+		out[key] = v
+	}
+}
+
+func setAny(v reflect.Value, val any) error {
+	if list, ok := val.([]any); ok {
+		if v.Kind() != reflect.Slice {
+			return fmt.Errorf("expected a single value, got a list")
+		}
+		items := make([]string, len(list))
+		for i, item := range list {
+			items[i] = fmt.Sprint(item)
+		}
+		v.Set(reflect.ValueOf(items))
+		return nil
+	}
+	return setString(v, fmt.Sprint(val))
+}
+
+func setString(v reflect.Value, raw string) error {
+	raw = strings.TrimSpace(raw)
+	switch {
+	case v.Type() == reflect.TypeOf(time.Duration(0)):
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("expected a duration like 30s or 5m, got %q", raw)
+		}
+		v.SetInt(int64(d))
+	case v.Kind() == reflect.String:
+		v.SetString(raw)
+	case v.Kind() == reflect.Int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("expected an integer, got %q", raw)
+		}
+		v.SetInt(int64(n))
+	case v.Kind() == reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return fmt.Errorf("expected a number, got %q", raw)
+		}
+		v.SetFloat(f)
+	case v.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("expected true or false, got %q", raw)
+		}
+		v.SetBool(b)
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.String:
+		var items []string
+		for _, s := range strings.Split(raw, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				items = append(items, s)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
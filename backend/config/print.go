@@ -0,0 +1,74 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// redacted replaces secret values in printed configuration
+const redacted = "<redacted>"
+
+// WriteYAML prints the effective configuration as a YAML config file, in
+// declaration order, with secret fields redacted (empty secrets stay empty so
+// missing credentials are still visible).
+func (c *Config) WriteYAML(w io.Writer) error {
+	doc := &yaml.Node{Kind: yaml.DocumentNode}
+	root := &yaml.Node{Kind: yaml.MappingNode}
+	doc.Content = append(doc.Content, root)
+
+	sections := map[string]*yaml.Node{"": root}
+	for _, f := range fields(c) {
+		parent := root
+		parts := strings.Split(f.key, ".")
+		for i := range parts[:len(parts)-1] {
+			path := strings.Join(parts[:i+1], ".")
+			node, ok := sections[path]
+			if !ok {
+				node = &yaml.Node{Kind: yaml.MappingNode}
+				parent.Content = append(parent.Content,
+					&yaml.Node{Kind: yaml.ScalarNode, Value: parts[i]}, node)
+				sections[path] = node
+			}
+			parent = node
+		}
+
+		key := &yaml.Node{Kind: yaml.ScalarNode, Value: parts[len(parts)-1]}
+		if len(f.env) > 0 {
+			key.LineComment = strings.Join(f.env, ", ")
+		}
+		parent.Content = append(parent.Content, key, valueNode(f))
+	}
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+func valueNode(f field) *yaml.Node {
+	v := f.value
+	if f.secret && !v.IsZero() {
+		return &yaml.Node{Kind: yaml.ScalarNode, Value: redacted}
+	}
+	switch {
+	case v.Type() == reflect.TypeOf(time.Duration(0)):
+		return &yaml.Node{Kind: yaml.ScalarNode, Value: time.Duration(v.Int()).String()}
+	case v.Kind() == reflect.Slice:
+		seq := &yaml.Node{Kind: yaml.SequenceNode, Style: yaml.FlowStyle}
+		for i := 0; i < v.Len(); i++ {
+			seq.Content = append(seq.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: v.Index(i).String(), Style: yaml.DoubleQuotedStyle})
+		}
+		return seq
+	case v.Kind() == reflect.String:
+		return &yaml.Node{Kind: yaml.ScalarNode, Value: v.String(), Style: yaml.DoubleQuotedStyle}
+	default:
+		return &yaml.Node{Kind: yaml.ScalarNode, Value: fmt.Sprint(v.Interface())}
+	}
+}
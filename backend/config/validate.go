@@ -0,0 +1,445 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+var environments = map[string]bool{
+	"development": true,
+	"local":       true,
+	"test":        true,
+	"staging":     true,
+	"production":  true,
+}
+
+// IsProduction reports whether the server runs with production safeguards
+func (c *Config) IsProduction() bool {
+	return c.Environment == "production"
+}
+
+// Validate checks the whole tree and reports every problem at once
+func (c *Config) Validate() error {
+	var errs []error
+	fail := func(key, env, format string, args ...any) {
+		errs = append(errs, fmt.Errorf("  - %s (%s): %s", key, env, fmt.Sprintf(format, args...)))
+	}
+
+	if !environments[c.Environment] {
+		fail("environment", "ENV", "must be one of development, local, test, staging, production, got %q", c.Environment)
+	}
+
+	// Server
+	if p, err := strconv.Atoi(c.Server.Port); err != nil || p < 1 || p > 65535 {
+		fail("server.port", "PORT", "must be a port number, got %q", c.Server.Port)
+	}
+	if len(c.Server.CORSOrigins) == 0 {
+		fail("server.cors_origins", "CORS_ORIGINS", "at least one origin is required")
+	}
+	if !isHTTPURL(c.Server.FrontendURL) {
+		fail("server.frontend_url", "FRONTEND_URL", "must be an http(s) URL, got %q", c.Server.FrontendURL)
+	}
+	for _, p := range c.Server.TrustedProxies {
+		if net.ParseIP(p) == nil {
+			if _, _, err := net.ParseCIDR(p); err != nil {
+				fail("server.trusted_proxies", "TRUSTED_PROXIES", "entries must be IPs or CIDRs, got %q", p)
+			}
+		}
+	}
+	if len(c.Server.TrustedProxies) > 0 && c.Server.ProxyHeader == "" {
+		fail("server.proxy_header", "PROXY_HEADER", "is required with trusted proxies")
+	}
+	if c.Server.AdminToken != "" && len(c.Server.AdminToken) < 32 {
+		fail("server.admin_token", "ADMIN_API_TOKEN", "must be at least 32 characters")
+	}
+
+	// Log
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.Log.Level)); err != nil {
+		fail("log.level", "LOG_LEVEL", "must be debug, info, warn or error, got %q", c.Log.Level)
+	}
+	if c.Log.Format != "text" && c.Log.Format != "json" {
+		fail("log.format", "LOG_FORMAT", "must be text or json, got %q", c.Log.Format)
+	}
+	for _, pair := range c.Log.Levels {
+		name, lvl, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(name) == "" || level.UnmarshalText([]byte(strings.TrimSpace(lvl))) != nil {
+			fail("log.levels", "LOG_LEVELS", "entries must look like subsystem=level, got %q", pair)
+		}
+	}
+
+	// Tracing
+	switch c.Tracing.Exporter {
+	case "none", "stdout":
+	case "otlp":
+		if !isHTTPURL(c.Tracing.Endpoint) {
+			fail("tracing.endpoint", "OTEL_EXPORTER_OTLP_ENDPOINT", "must be an http(s) URL, got %q", c.Tracing.Endpoint)
+		}
+	default:
+		fail("tracing.exporter", "OTEL_TRACES_EXPORTER", "must be none, stdout or otlp, got %q", c.Tracing.Exporter)
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		fail("tracing.sample_ratio", "OTEL_TRACES_SAMPLER_ARG", "must be between 0 and 1, got %g", c.Tracing.SampleRatio)
+	}
+
+	// Database
+	if c.Database.Path == "" {
+		fail("database.path", "DATABASE_URL", "is required")
+	}
+	if c.Database.MaxWriteConns != 1 {
+		fail("database.max_write_conns", "MAX_WRITE_CONNS", "must be 1: SQLite allows a single writer, got %d", c.Database.MaxWriteConns)
+	}
+	if c.Database.MaxReadConns < 1 {
+		fail("database.max_read_conns", "MAX_READ_CONNS", "must be at least 1, got %d", c.Database.MaxReadConns)
+	}
+	if c.Database.BusyTimeout < 0 {
+		fail("database.busy_timeout_ms", "DB_BUSY_TIMEOUT", "must not be negative, got %d", c.Database.BusyTimeout)
+	}
+
+	// AWS
+	if c.AWS.UseS3 || c.AWS.UseSQS || c.AWS.UseSecrets {
+		if c.AWS.Region == "" {
+			fail("aws.region", "AWS_DEFAULT_REGION", "is required when an AWS service is enabled")
+		}
+		if c.AWS.Endpoint != "" && !isHTTPURL(c.AWS.Endpoint) {
+			fail("aws.endpoint", "AWS_ENDPOINT_URL", "must be an http(s) URL, got %q", c.AWS.Endpoint)
+		}
+		if (c.AWS.AccessKey == "") != (c.AWS.SecretKey == "") {
+			fail("aws.access_key_id", "AWS_ACCESS_KEY_ID", "access key and secret key must be set together")
+		}
+	}
+	if c.AWS.UseS3 && c.AWS.S3Bucket == "" {
+		fail("aws.s3_bucket", "S3_BUCKET", "is required when use_s3 is enabled")
+	}
+	if c.AWS.UseSecrets && c.AWS.SecretsPrefix == "" {
+		fail("aws.secrets_prefix", "SECRETS_PREFIX", "is required when use_secrets_manager is enabled")
+	}
+
+	// Replication
+	if c.Replication.SyncInterval <= 0 {
+		fail("replication.sync_interval", "REPLICATION_SYNC_INTERVAL", "must be positive")
+	}
+	if c.Replication.SnapshotInterval < c.Replication.SyncInterval {
+		fail("replication.snapshot_interval", "REPLICATION_SNAPSHOT_INTERVAL", "must not be shorter than sync_interval")
+	}
+	if c.Replication.Retention < c.Replication.SnapshotInterval {
+		fail("replication.retention", "REPLICATION_RETENTION", "must not be shorter than snapshot_interval, or no generation is ever kept")
+	}
+
+	// Health
+	if c.Health.Timeout <= 0 {
+		fail("health.timeout", "HEALTH_TIMEOUT", "must be positive, got %s", c.Health.Timeout)
+	}
+	if c.Health.MaxWALMB < 1 {
+		fail("health.max_wal_mb", "HEALTH_MAX_WAL_MB", "must be at least 1, got %d", c.Health.MaxWALMB)
+	}
+	if c.Health.MinFreeDiskMB < 0 {
+		fail("health.min_free_disk_mb", "HEALTH_MIN_FREE_DISK_MB", "must not be negative, got %d", c.Health.MinFreeDiskMB)
+	}
+	if c.Health.CheckIntegrations && c.Health.IntegrationsTTL < time.Second {
+		fail("health.integrations_ttl", "HEALTH_INTEGRATIONS_TTL", "must be at least 1s, got %s", c.Health.IntegrationsTTL)
+	}
+
+	// Queue
+	if c.Queue.Workers < 1 {
+		fail("queue.workers", "PAYMENT_WORKERS", "must be at least 1, got %d", c.Queue.Workers)
+	}
+	if c.Queue.Visibility < time.Second || c.Queue.Visibility > 12*time.Hour {
+		fail("queue.visibility_timeout", "PAYMENT_VISIBILITY_TIMEOUT", "must be between 1s and 12h (SQS limits), got %s", c.Queue.Visibility)
+	}
+	if c.Queue.MaxAttempts < 1 {
+		fail("queue.max_attempts", "PAYMENT_MAX_ATTEMPTS", "must be at least 1, got %d", c.Queue.MaxAttempts)
+	}
+	if c.AWS.UseSQS && (c.Queue.PaymentQueue == "" || c.Queue.PaymentDeadLetter == "") {
+		fail("queue.payment_queue", "SQS_PAYMENT_QUEUE", "queue and dead-letter names are required when use_sqs is enabled")
+	}
+	if c.IsProduction() && c.Queue.Memory {
+		fail("queue.memory", "PAYMENT_QUEUE_MEMORY", "must be false in production; the in-memory queue loses payments on restart")
+	}
+
+	// Symbicode tokens
+	if c.Symbicode.SigningKey != "" && len(c.Symbicode.SigningKey) < 32 {
+		fail("symbicode.signing_key", "SYMBICODE_SIGNING_KEY", "must be at least 32 characters")
+	}
+	if c.IsProduction() && c.Symbicode.SigningKey == "" {
+		fail("symbicode.signing_key", "SYMBICODE_SIGNING_KEY", "is required in production")
+	}
+	for _, key := range c.Symbicode.PreviousSigningKeys {
+		if len(key) < 32 {
+			fail("symbicode.previous_signing_keys", "SYMBICODE_PREVIOUS_SIGNING_KEYS", "must be at least 32 characters each")
+			break
+		}
+	}
+
+	if c.Symbicode.SuspiciousIPs < 0 {
+		fail("symbicode.suspicious_ips", "SYMBICODE_SUSPICIOUS_IPS", "must not be negative, got %d", c.Symbicode.SuspiciousIPs)
+	}
+	if c.Symbicode.SuspiciousLocations < 0 {
+		fail("symbicode.suspicious_locations", "SYMBICODE_SUSPICIOUS_LOCATIONS", "must not be negative, got %d", c.Symbicode.SuspiciousLocations)
+	}
+	if c.Symbicode.TransferTTL < time.Hour {
+		fail("symbicode.transfer_ttl", "SYMBICODE_TRANSFER_TTL", "must be at least 1h, got %s", c.Symbicode.TransferTTL)
+	}
+
+	// Orders
+	if c.Orders.NumberKey != "" && len(c.Orders.NumberKey) < 32 {
+		fail("orders.number_key", "ORDER_NUMBER_KEY", "must be at least 32 characters")
+	}
+	if c.Orders.LegacyMaxID < 0 {
+		fail("orders.legacy_max_id", "ORDER_LEGACY_MAX_ID", "must not be negative, got %d", c.Orders.LegacyMaxID)
+	}
+	if c.IsProduction() && c.Orders.NumberKey == "" {
+		fail("orders.number_key", "ORDER_NUMBER_KEY", "is required in production")
+	}
+	if c.Orders.LookupLinkTTL < time.Minute || c.Orders.LookupLinkTTL > 7*24*time.Hour {
+		fail("orders.lookup_link_ttl", "ORDER_LOOKUP_LINK_TTL", "must be between 1m and 168h, got %s", c.Orders.LookupLinkTTL)
+	}
+	if c.Orders.RefundAttempts < 1 {
+		fail("orders.refund_attempts", "ORDER_REFUND_ATTEMPTS", "must be at least 1, got %d", c.Orders.RefundAttempts)
+	}
+	if c.Orders.RefundBackoff < time.Second {
+		fail("orders.refund_backoff", "ORDER_REFUND_BACKOFF", "must be at least 1s, got %s", c.Orders.RefundBackoff)
+	}
+
+	// Email failover
+	if len(c.Email.Providers) == 0 {
+		fail("email.providers", "EMAIL_PROVIDERS", "must list at least one of brevo, resend")
+	}
+	seenProviders := map[string]bool{}
+	for _, p := range c.Email.Providers {
+		if p != "brevo" && p != "resend" {
+			fail("email.providers", "EMAIL_PROVIDERS", "unknown provider %q (want brevo or resend)", p)
+		} else if seenProviders[p] {
+			fail("email.providers", "EMAIL_PROVIDERS", "lists %s twice", p)
+		}
+		seenProviders[p] = true
+	}
+	if c.Email.BrevoDailyLimit < 0 {
+		fail("email.brevo_daily_limit", "BREVO_DAILY_LIMIT", "must not be negative, got %d", c.Email.BrevoDailyLimit)
+	}
+	if c.Email.ResendDailyLimit < 0 {
+		fail("email.resend_daily_limit", "RESEND_DAILY_LIMIT", "must not be negative, got %d", c.Email.ResendDailyLimit)
+	}
+	if c.Email.FailureThreshold < 1 {
+		fail("email.failure_threshold", "EMAIL_FAILURE_THRESHOLD", "must be at least 1, got %d", c.Email.FailureThreshold)
+	}
+	if c.Email.Cooldown < time.Second {
+		fail("email.cooldown", "EMAIL_FAILOVER_COOLDOWN", "must be at least 1s, got %s", c.Email.Cooldown)
+	}
+
+	// Customer notifications
+	for _, event := range []struct {
+		key, env, template string
+		channels           []string
+	}{
+		{"notify.order_confirmation", "NOTIFY_ORDER_CONFIRMATION", c.Notify.ZaloOrderConfirmationTemplate, c.Notify.OrderConfirmation},
+		{"notify.drop_won", "NOTIFY_DROP_WON", c.Notify.ZaloDropWonTemplate, c.Notify.DropWon},
+		{"notify.drop_lost", "NOTIFY_DROP_LOST", c.Notify.ZaloDropLostTemplate, c.Notify.DropLost},
+	} {
+		for _, entry := range event.channels {
+			for _, ch := range strings.Split(entry, "|") {
+				switch strings.TrimSpace(ch) {
+				case "email", "sms":
+				case "zalo":
+					if event.template == "" {
+						fail(event.key, event.env, "lists zalo but no ZNS template is set for the event")
+					}
+				default:
+					fail(event.key, event.env, "unknown channel %q (want email, sms or zalo, or a chain like zalo|sms)", ch)
+				}
+			}
+		}
+	}
+
+	// Admin alerts
+	for _, e := range c.Alerts.Events {
+		switch e {
+		case "order_paid", "refund_failed", "webhook_signature", "drop_sold_out":
+		default:
+			fail("alerts.events", "ALERT_EVENTS", "unknown event %q (want order_paid, refund_failed, webhook_signature or drop_sold_out)", e)
+		}
+	}
+	for _, addr := range c.Alerts.Emails {
+		if !strings.Contains(addr, "@") {
+			fail("alerts.emails", "ALERT_EMAILS", "must be email addresses, got %q", addr)
+		}
+	}
+	if c.Alerts.WebhookURL != "" {
+		if !isHTTPURL(c.Alerts.WebhookURL) {
+			fail("alerts.webhook_url", "ALERT_WEBHOOK_URL", "must be an http(s) URL")
+		}
+		switch c.Alerts.WebhookFormat {
+		case "slack", "discord":
+		case "telegram":
+			if c.Alerts.TelegramChatID == "" {
+				fail("alerts.telegram_chat_id", "ALERT_TELEGRAM_CHAT_ID", "is required for telegram")
+			}
+		default:
+			fail("alerts.webhook_format", "ALERT_WEBHOOK_FORMAT", "must be slack, discord or telegram, got %q", c.Alerts.WebhookFormat)
+		}
+	}
+	if c.Alerts.SignatureFailures < 1 {
+		fail("alerts.signature_failures", "ALERT_SIGNATURE_FAILURES", "must be at least 1, got %d", c.Alerts.SignatureFailures)
+	}
+	if c.Alerts.SignatureWindow < time.Minute {
+		fail("alerts.signature_window", "ALERT_SIGNATURE_WINDOW", "must be at least 1m, got %s", c.Alerts.SignatureWindow)
+	}
+	if c.Alerts.DigestAfter < 0 {
+		fail("alerts.digest_after", "ALERT_DIGEST_AFTER", "must not be negative, got %d", c.Alerts.DigestAfter)
+	}
+	if c.Alerts.DigestInterval < time.Minute {
+		fail("alerts.digest_interval", "ALERT_DIGEST_INTERVAL", "must be at least 1m, got %s", c.Alerts.DigestInterval)
+	}
+
+	// Scheduled jobs
+	if c.Symbicode.AutoActivateAfter < time.Hour {
+		fail("symbicode.auto_activate_after", "SYMBICODE_AUTO_ACTIVATE_AFTER", "must be at least 1h, got %s", c.Symbicode.AutoActivateAfter)
+	}
+	if _, err := cron.ParseStandard(c.Symbicode.AutoActivateSchedule); err != nil {
+		fail("symbicode.auto_activate_schedule", "SYMBICODE_AUTO_ACTIVATE_SCHEDULE", "must be a cron expression or @every <duration>: %v", err)
+	}
+	if _, err := cron.ParseStandard(c.SheetSync.Schedule); err != nil {
+		fail("sheet_sync.schedule", "SHEETS_SYNC_SCHEDULE", "must be a cron expression or @every <duration>: %v", err)
+	}
+	if _, err := cron.ParseStandard(c.Orders.RefundSchedule); err != nil {
+		fail("orders.refund_schedule", "ORDER_REFUND_SCHEDULE", "must be a cron expression or @every <duration>: %v", err)
+	}
+	if _, err := cron.ParseStandard(c.Webhooks.DropStartSchedule); err != nil {
+		fail("webhooks.drop_start_schedule", "WEBHOOK_DROP_START_SCHEDULE", "must be a cron expression or @every <duration>: %v", err)
+	}
+
+	// Sheet export
+	if len(c.SheetSync.Columns) == 0 {
+		fail("sheet_sync.columns", "SHEETS_COLUMNS", "must list at least one column")
+	}
+	for _, col := range c.SheetSync.Columns {
+		switch col {
+		case "paid_at", "created_at", "order_number", "order_id", "name", "phone", "email", "address", "items", "amount", "status", "note", "-":
+		default:
+			fail("sheet_sync.columns", "SHEETS_COLUMNS", "unknown column %q", col)
+		}
+	}
+	if c.SheetSync.BatchSize < 1 || c.SheetSync.BatchSize > 5000 {
+		fail("sheet_sync.batch_size", "SHEETS_BATCH_SIZE", "must be between 1 and 5000, got %d", c.SheetSync.BatchSize)
+	}
+	if c.SheetSync.Attempts < 1 {
+		fail("sheet_sync.attempts", "SHEETS_ATTEMPTS", "must be at least 1, got %d", c.SheetSync.Attempts)
+	}
+	if c.SheetSync.Backoff <= 0 {
+		fail("sheet_sync.backoff", "SHEETS_RETRY_BACKOFF", "must be positive, got %s", c.SheetSync.Backoff)
+	}
+
+	// Outbound webhooks
+	if c.Webhooks.MaxAttempts < 1 {
+		fail("webhooks.max_attempts", "WEBHOOK_MAX_ATTEMPTS", "must be at least 1, got %d", c.Webhooks.MaxAttempts)
+	}
+	if c.Webhooks.Backoff <= 0 {
+		fail("webhooks.backoff", "WEBHOOK_RETRY_BACKOFF", "must be positive, got %s", c.Webhooks.Backoff)
+	}
+	if c.Webhooks.MaxBackoff < c.Webhooks.Backoff {
+		fail("webhooks.max_backoff", "WEBHOOK_MAX_BACKOFF", "must be at least WEBHOOK_RETRY_BACKOFF (%s), got %s", c.Webhooks.Backoff, c.Webhooks.MaxBackoff)
+	}
+	if c.Webhooks.Timeout <= 0 {
+		fail("webhooks.timeout", "WEBHOOK_TIMEOUT", "must be positive, got %s", c.Webhooks.Timeout)
+	}
+	if c.Webhooks.Workers < 1 || c.Webhooks.Workers > 64 {
+		fail("webhooks.workers", "WEBHOOK_WORKERS", "must be between 1 and 64, got %d", c.Webhooks.Workers)
+	}
+	if c.Webhooks.DropStartWindow <= 0 {
+		fail("webhooks.drop_start_window", "WEBHOOK_DROP_START_WINDOW", "must be positive, got %s", c.Webhooks.DropStartWindow)
+	}
+
+	// Rate limiting
+	if c.RateLimit.Enabled {
+		for _, rule := range c.RateLimit.Rules {
+			if err := checkRateRule(rule); err != nil {
+				fail("rate_limit.rules", "RATE_LIMIT_RULES", "%v", err)
+			}
+		}
+	}
+	if c.RateLimit.RedisURL != "" {
+		if u, err := url.Parse(c.RateLimit.RedisURL); err != nil || (u.Scheme != "redis" && u.Scheme != "rediss") {
+			fail("rate_limit.redis_url", "RATE_LIMIT_REDIS_URL", "must be a redis:// or rediss:// URL")
+		}
+	}
+
+	// Bot check
+	switch c.BotCheck.Kind {
+	case "none":
+	case "pow":
+		if c.BotCheck.PoWDifficulty < 1 || c.BotCheck.PoWDifficulty > 32 {
+			fail("bot_check.pow_difficulty", "BOT_CHECK_POW_DIFFICULTY", "must be between 1 and 32, got %d", c.BotCheck.PoWDifficulty)
+		}
+		if c.BotCheck.PoWTTL < 10*time.Second {
+			fail("bot_check.pow_ttl", "BOT_CHECK_POW_TTL", "must be at least 10s, got %s", c.BotCheck.PoWTTL)
+		}
+		if c.BotCheck.PoWKey != "" && len(c.BotCheck.PoWKey) < 32 {
+			fail("bot_check.pow_key", "BOT_CHECK_POW_KEY", "must be at least 32 characters")
+		}
+	case "captcha":
+		if !isHTTPURL(c.BotCheck.CaptchaVerifyURL) {
+			fail("bot_check.captcha_verify_url", "CAPTCHA_VERIFY_URL", "must be an http(s) URL, got %q", c.BotCheck.CaptchaVerifyURL)
+		}
+		if c.BotCheck.CaptchaSiteKey == "" || c.BotCheck.CaptchaSecret == "" {
+			fail("bot_check.captcha_secret", "CAPTCHA_SECRET", "site key and secret are required for captcha")
+		}
+	default:
+		fail("bot_check.kind", "BOT_CHECK", "must be none, pow or captcha, got %q", c.BotCheck.Kind)
+	}
+
+	// PayOS: without credentials the gateway runs in mock mode, which must never happen in production
+	if c.IsProduction() {
+		if c.PayOS.ClientID == "" || c.PayOS.APIKey == "" || c.PayOS.ChecksumKey == "" {
+			fail("payos", "PAYOS_CLIENT_ID", "client_id, api_key and checksum_key are required in production")
+		}
+	}
+	for _, u := range []struct{ key, env, value string }{
+		{"payos.checkout_url", "PAYOS_CHECKOUT_URL", c.PayOS.CheckoutURL},
+		{"payos.base_url", "PAYOS_BASE_URL", c.PayOS.BaseURL},
+		{"payos.return_url", "PAYOS_RETURN_URL", c.PayOS.ReturnURL},
+		{"payos.cancel_url", "PAYOS_CANCEL_URL", c.PayOS.CancelURL},
+		{"brevo.base_url", "BREVO_BASE_URL", c.Brevo.BaseURL},
+		{"resend.base_url", "RESEND_BASE_URL", c.Resend.BaseURL},
+		{"sms.base_url", "SMS_BASE_URL", c.SMS.BaseURL},
+		{"zalo.base_url", "ZALO_BASE_URL", c.Zalo.BaseURL},
+	} {
+		if u.value != "" && !isHTTPURL(u.value) {
+			fail(u.key, u.env, "must be an http(s) URL, got %q", u.value)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// checkRateRule checks the shape of a route:key=events/period rule
+func checkRateRule(rule string) error {
+	target, limit, ok := strings.Cut(rule, "=")
+	_, key, ok2 := strings.Cut(target, ":")
+	events, period, ok3 := strings.Cut(limit, "/")
+	if !ok || !ok2 || !ok3 {
+		return fmt.Errorf("entries must look like route:key=events/period, got %q", rule)
+	}
+	if key != "ip" && key != "phone" && key != "fingerprint" && key != "order" {
+		return fmt.Errorf("key must be ip, phone, fingerprint or order, got %q", rule)
+	}
+	if n, err := strconv.Atoi(events); err != nil || n < 1 {
+		return fmt.Errorf("events must be a positive integer, got %q", rule)
+	}
+	if d, err := time.ParseDuration(period); err != nil || d < time.Second {
+		return fmt.Errorf("period must be a duration of at least 1s, got %q", rule)
+	}
+	return nil
+}
+
+func isHTTPURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
@@ -0,0 +1,369 @@
+// Package alerts tells the shop's admins about paid orders and operational
+// problems: failed refunds, spikes of webhook signature failures and sold
+// out drops. Each kind has a Rule that can require several occurrences
+// before alerting and keeps repeats quiet for a while. When alerts pile up,
+// as they do during a drop launch, the Alerter switches to digest mode and
+// sends one summary per interval instead of one message per alert.
+package alerts
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"ecommerce-backend/internal/logging"
+	"ecommerce-backend/internal/metrics"
+	"ecommerce-backend/internal/models"
+)
+
+// Kind is what an alert is about
+type Kind string
+
+const (
+	OrderPaid        Kind = "order_paid"
+	RefundFailed     Kind = "refund_failed"
+	WebhookSignature Kind = "webhook_signature"
+	DropSoldOut      Kind = "drop_sold_out"
+)
+
+// Kinds lists every kind of alert
+var Kinds = []Kind{OrderPaid, RefundFailed, WebhookSignature, DropSoldOut}
+
+// Alert is one thing to tell the admins
+type Alert struct {
+	At   time.Time
+	Kind Kind
+	// Key is the subject Rule thresholds and throttling apply to: the drop
+	// ID, the webhook's provider. Empty means one subject for the kind.
+	Key    string
+	Title  string
+	Detail string
+	// Order is the paid order, for sinks that can show all of it
+	Order *models.Order
+	// Count is how many occurrences the alert stands for, set by the Alerter
+	Count int
+}
+
+// Line is the alert on one line, as chat messages and digests show it
+func (a Alert) Line() string {
+	if d := a.detail(); d != "" {
+		return a.Title + ": " + d
+	}
+	return a.Title
+}
+
+// detail is Detail with the number of occurrences
+func (a Alert) detail() string {
+	if a.Count <= 1 {
+		return a.Detail
+	}
+	return strings.TrimSpace(fmt.Sprintf("%s (%d times)", a.Detail, a.Count))
+}
+
+// Rule decides when occurrences of a kind become an alert
+type Rule struct {
+	// Threshold occurrences of one key within Window raise the alert;
+	// 0 or 1 alerts on every occurrence
+	Threshold int
+	Window    time.Duration
+	// Throttle drops later alerts about the same key for this long
+	Throttle time.Duration
+}
+
+// DefaultRules alert on every paid order and failed refund, on 5 signature
+// failures within 5 minutes, and on a sold out drop once a day
+var DefaultRules = map[Kind]Rule{
+	OrderPaid:        {},
+	RefundFailed:     {},
+	WebhookSignature: {Threshold: 5, Window: 5 * time.Minute, Throttle: 5 * time.Minute},
+	DropSoldOut:      {Throttle: 24 * time.Hour},
+}
+
+// DefaultDigestInterval is how often digests are sent
+const DefaultDigestInterval = 5 * time.Minute
+
+// Sink delivers alerts to one destination
+type Sink interface {
+	// Name is the sink's label in logs and Status
+	Name() string
+	// Send delivers alerts, one alert unless digest is set
+	Send(ctx context.Context, alerts []Alert, digest bool) error
+}
+
+// Options configures an Alerter
+type Options struct {
+	Sinks []Sink
+	// Kinds are the alerts to send; nil sends every kind
+	Kinds []Kind
+	// Rules override DefaultRules per kind
+	Rules map[Kind]Rule
+	// DigestAfter alerts within a minute switch to digest mode until an
+	// interval passes quietly; 0 only digests when SetDigest asks to
+	DigestAfter    int
+	DigestInterval time.Duration
+	Logger         *slog.Logger
+	// Now returns the current time; nil means time.Now
+	Now func() time.Time
+}
+
+// Alerter applies the rules and delivers alerts in the background; see Run.
+// A nil Alerter drops every alert.
+type Alerter struct {
+	sinks          []Sink
+	kinds          map[Kind]bool
+	rules          map[Kind]Rule
+	digestAfter    int
+	digestInterval time.Duration
+	log            *slog.Logger
+	now            func() time.Time
+	wake           chan struct{}
+
+	mu          sync.Mutex
+	subjects    map[string]*subject
+	recent      []time.Time // alerts raised in the last minute
+	digestUntil time.Time
+	pending     []Alert
+}
+
+// subject tracks one kind and key for its Rule
+type subject struct {
+	hits       []time.Time
+	quietUntil time.Time
+}
+
+// New returns an Alerter delivering to opts.Sinks
+func New(opts Options) *Alerter {
+	a := &Alerter{
+		sinks:          opts.Sinks,
+		kinds:          make(map[Kind]bool),
+		rules:          make(map[Kind]Rule, len(DefaultRules)),
+		digestAfter:    opts.DigestAfter,
+		digestInterval: opts.DigestInterval,
+		log:            logging.Subsystem(opts.Logger, "alerts"),
+		now:            opts.Now,
+		wake:           make(chan struct{}, 1),
+		subjects:       make(map[string]*subject),
+	}
+	if a.digestInterval <= 0 {
+		a.digestInterval = DefaultDigestInterval
+	}
+	if a.now == nil {
+		a.now = time.Now
+	}
+	kinds := opts.Kinds
+	if kinds == nil {
+		kinds = Kinds
+	}
+	for _, k := range kinds {
+		a.kinds[k] = true
+	}
+	for k, r := range DefaultRules {
+		a.rules[k] = r
+	}
+	for k, r := range opts.Rules {
+		a.rules[k] = r
+	}
+	return a
+}
+
+// ParseKinds parses alert kind names
+func ParseKinds(names []string) ([]Kind, error) {
+	kinds := make([]Kind, 0, len(names))
+	for _, name := range names {
+		k := Kind(strings.TrimSpace(name))
+		if !known(k) {
+			return nil, fmt.Errorf("unknown alert kind %q", name)
+		}
+		kinds = append(kinds, k)
+	}
+	return kinds, nil
+}
+
+func known(k Kind) bool {
+	for _, kind := range Kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// Alert records an occurrence of al.Kind. It never blocks on delivery:
+// alerts that pass their Rule are queued for Run.
+func (a *Alerter) Alert(ctx context.Context, al Alert) {
+	if a == nil || !a.kinds[al.Kind] {
+		return
+	}
+	now := a.now()
+	if al.At.IsZero() {
+		al.At = now
+	}
+
+	a.mu.Lock()
+	raise, count := a.check(al, now)
+	if !raise {
+		a.mu.Unlock()
+		return
+	}
+	al.Count = count
+	a.pending = append(a.pending, al)
+	a.recent = append(trimBefore(a.recent, now.Add(-time.Minute)), now)
+	if a.digestAfter > 0 && len(a.recent) >= a.digestAfter && now.Add(a.digestInterval).After(a.digestUntil) {
+		if !now.Before(a.digestUntil) {
+			a.log.WarnContext(ctx, "alert storm, switching to digests", "alerts_last_minute", len(a.recent), "interval", a.digestInterval)
+		}
+		a.digestUntil = now.Add(a.digestInterval)
+	}
+	a.mu.Unlock()
+
+	select {
+	case a.wake <- struct{}{}:
+	default:
+	}
+}
+
+// check applies al's Rule under a.mu and reports whether to raise it and
+// how many occurrences it stands for
+func (a *Alerter) check(al Alert, now time.Time) (bool, int) {
+	rule := a.rules[al.Kind]
+	if rule.Threshold <= 1 && rule.Throttle <= 0 {
+		return true, 1
+	}
+
+	id := string(al.Kind) + "|" + al.Key
+	s := a.subjects[id]
+	if s == nil {
+		s = &subject{}
+		a.subjects[id] = s
+	}
+	s.hits = append(trimBefore(s.hits, now.Add(-rule.Window)), now)
+	if len(s.hits) < rule.Threshold {
+		return false, 0
+	}
+	if now.Before(s.quietUntil) {
+		metrics.AdminAlerts.WithLabelValues(string(al.Kind), "throttled").Inc()
+		return false, 0
+	}
+	count := len(s.hits)
+	s.hits = nil
+	s.quietUntil = now.Add(rule.Throttle)
+	return true, count
+}
+
+// trimBefore drops the times before cutoff from the sorted times
+func trimBefore(times []time.Time, cutoff time.Time) []time.Time {
+	i := 0
+	for i < len(times) && times[i].Before(cutoff) {
+		i++
+	}
+	return times[i:]
+}
+
+// SetDigest sends digests instead of single alerts for d; d <= 0 goes back
+// to single alerts and sends what is held right away
+func (a *Alerter) SetDigest(d time.Duration) {
+	a.mu.Lock()
+	if d > 0 {
+		a.digestUntil = a.now().Add(d)
+	} else {
+		a.digestUntil = time.Time{}
+	}
+	a.mu.Unlock()
+
+	select {
+	case a.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Status is the Alerter's state, as served to admins
+type Status struct {
+	Kinds       []Kind     `json:"kinds"`
+	Sinks       []string   `json:"sinks"`
+	Digest      bool       `json:"digest"`
+	DigestUntil *time.Time `json:"digest_until,omitempty"`
+	Pending     int        `json:"pending"`
+}
+
+// Status reports the enabled kinds and sinks and whether alerts are being
+// held for a digest
+func (a *Alerter) Status() Status {
+	st := Status{Kinds: []Kind{}, Sinks: []string{}}
+	for _, k := range Kinds {
+		if a.kinds[k] {
+			st.Kinds = append(st.Kinds, k)
+		}
+	}
+	for _, s := range a.sinks {
+		st.Sinks = append(st.Sinks, s.Name())
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.now().Before(a.digestUntil) {
+		until := a.digestUntil
+		st.Digest, st.DigestUntil = true, &until
+	}
+	st.Pending = len(a.pending)
+	return st
+}
+
+// Run delivers alerts until ctx is done, then sends what is still held.
+// Outside digest mode alerts go out as they come; in digest mode they go
+// out together every digest interval.
+func (a *Alerter) Run(ctx context.Context) {
+	ticker := time.NewTicker(a.digestInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			flushCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+			a.flush(flushCtx, true)
+			cancel()
+			return
+		case <-a.wake:
+			a.flush(ctx, false)
+		case <-ticker.C:
+			a.flush(ctx, true)
+		}
+	}
+}
+
+// flush sends the held alerts. Unless force is set nothing is sent in
+// digest mode. Several alerts sent together make a digest.
+func (a *Alerter) flush(ctx context.Context, force bool) {
+	a.mu.Lock()
+	if len(a.pending) == 0 || (!force && a.now().Before(a.digestUntil)) {
+		a.mu.Unlock()
+		return
+	}
+	batch := a.pending
+	a.pending = nil
+	a.mu.Unlock()
+
+	if force && len(batch) > 1 {
+		a.deliver(ctx, batch, true)
+		return
+	}
+	for _, al := range batch {
+		a.deliver(ctx, []Alert{al}, false)
+	}
+}
+
+// deliver sends batch to every sink; it counts as sent when one accepted it
+func (a *Alerter) deliver(ctx context.Context, batch []Alert, digest bool) {
+	result := "error"
+	for _, s := range a.sinks {
+		if err := s.Send(ctx, batch, digest); err != nil {
+			a.log.WarnContext(ctx, "alert delivery failed", "sink", s.Name(), "alerts", len(batch), "digest", digest, "error", err)
+			continue
+		}
+		result = "sent"
+	}
+	for _, al := range batch {
+		metrics.AdminAlerts.WithLabelValues(string(al.Kind), result).Inc()
+	}
+}
@@ -0,0 +1,150 @@
+package alerts
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"ecommerce-backend/internal/emails"
+	"ecommerce-backend/internal/integrations"
+	"ecommerce-backend/internal/logging"
+	"ecommerce-backend/internal/tracing"
+)
+
+// MessageSender delivers a rendered email; *integrations.Emailer is one
+type MessageSender interface {
+	Send(ctx context.Context, to []string, msg emails.Message) (provider, messageID string, err error)
+}
+
+// NewEmailSink emails alerts to recipients through sender. A single paid
+// order alert is the full admin order email; anything else is an
+// admin_alert email.
+func NewEmailSink(sender MessageSender, recipients []string) Sink {
+	return &emailSink{sender: sender, to: recipients}
+}
+
+type emailSink struct {
+	sender MessageSender
+	to     []string
+}
+
+func (s *emailSink) Name() string { return "email" }
+
+func (s *emailSink) Send(ctx context.Context, alerts []Alert, digest bool) error {
+	var msg emails.Message
+	var err error
+	if !digest && len(alerts) == 1 && alerts[0].Kind == OrderPaid && alerts[0].Order != nil {
+		msg, err = emails.Render(emails.AdminOrderCreated, emails.DefaultLocale, integrations.AdminOrderData(alerts[0].Order))
+	} else {
+		data := emails.AdminAlertData{Digest: digest}
+		for _, al := range alerts {
+			data.Alerts = append(data.Alerts, emails.AlertItem{At: al.At.Format("15:04:05"), Title: al.Title, Detail: al.detail()})
+		}
+		msg, err = emails.Render(emails.AdminAlert, emails.DefaultLocale, data)
+	}
+	if err != nil {
+		return err
+	}
+	_, _, err = s.sender.Send(ctx, s.to, msg)
+	return err
+}
+
+// Chat webhook formats
+const (
+	FormatSlack    = "slack"
+	FormatDiscord  = "discord"
+	FormatTelegram = "telegram"
+)
+
+// maxChatLines caps a digest's chat message; Discord takes 2000 characters
+const maxChatLines = 20
+
+// WebhookOptions configures a chat webhook sink
+type WebhookOptions struct {
+	// URL is a Slack or Discord incoming webhook, or Telegram's
+	// https://api.telegram.org/bot<token>/sendMessage
+	URL string
+	// Format is slack (also Mattermost and Google Chat), discord or telegram
+	Format string
+	// ChatID is the Telegram chat to post to
+	ChatID string
+	Logger *slog.Logger
+}
+
+// NewWebhookSink posts alerts as chat messages
+func NewWebhookSink(opts WebhookOptions) Sink {
+	log := logging.Subsystem(opts.Logger, "alerts")
+	return &webhookSink{
+		opts: opts,
+		client: &http.Client{
+			Timeout:   10 * time.Second,
+			Transport: tracing.Transport(logging.Transport(http.DefaultTransport, log), "alerts"),
+		},
+	}
+}
+
+type webhookSink struct {
+	opts   WebhookOptions
+	client *http.Client
+}
+
+func (s *webhookSink) Name() string { return "webhook" }
+
+func (s *webhookSink) Send(ctx context.Context, alerts []Alert, digest bool) error {
+	text := ChatText(alerts, digest)
+
+	var payload any
+	switch s.opts.Format {
+	case FormatDiscord:
+		payload = map[string]string{"content": text}
+	case FormatTelegram:
+		payload = map[string]string{"chat_id": s.opts.ChatID, "text": text}
+	default:
+		payload = map[string]string{"text": text}
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal alert: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", s.opts.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post alert: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("chat webhook returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// ChatText is the plain-text chat message for alerts
+func ChatText(alerts []Alert, digest bool) string {
+	if !digest && len(alerts) == 1 {
+		return "[DW] " + alerts[0].Line()
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "[DW] %d alerts", len(alerts))
+	for i, al := range alerts {
+		if i == maxChatLines {
+			fmt.Fprintf(&b, "\n… and %d more", len(alerts)-maxChatLines)
+			break
+		}
+		fmt.Fprintf(&b, "\n%s %s", al.At.Format("15:04:05"), al.Line())
+	}
+	return b.String()
+}
@@ -0,0 +1,36 @@
+// Package awsclient builds AWS SDK configuration from config.AWSConfig so
+// every AWS-backed subsystem (S3 replication, SQS, Secrets Manager) talks to
+// the same endpoint, which is LocalStack in development.
+package awsclient
+
+import (
+	"context"
+
+	"ecommerce-backend/config"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+)
+
+// Load returns an aws.Config honouring the endpoint override and static keys.
+// Without static keys the SDK default credential chain is used.
+func Load(ctx context.Context, cfg config.AWSConfig) (aws.Config, error) {
+	opts := []func(*awsconfig.LoadOptions) error{
+		awsconfig.WithRegion(cfg.Region),
+	}
+	if cfg.AccessKey != "" && cfg.SecretKey != "" {
+		opts = append(opts, awsconfig.WithCredentialsProvider(
+			credentials.NewStaticCredentialsProvider(cfg.AccessKey, cfg.SecretKey, ""),
+		))
+	}
+
+	awsCfg, err := awsconfig.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return aws.Config{}, err
+	}
+	if cfg.Endpoint != "" {
+		awsCfg.BaseEndpoint = aws.String(cfg.Endpoint)
+	}
+	return awsCfg, nil
+}
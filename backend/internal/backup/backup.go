@@ -0,0 +1,241 @@
+// Package backup takes consistent online snapshots of the SQLite database,
+// verifies them, and restores them.
+//
+// Snapshots are made with `VACUUM INTO`, which reads the database through a
+// normal read transaction. That makes them safe while the server is running in
+// WAL mode: committed frames still sitting in the -wal file are included, and
+// the writer is never blocked. Copying database.db byte-by-byte is not safe.
+package backup
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const (
+	// FilePrefix is shared by every backup file, including legacy raw copies
+	FilePrefix = "database_"
+	// TimeLayout is the timestamp layout used in backup IDs
+	TimeLayout = "2006-01-02_15-04-05"
+
+	manifestSuffix = ".manifest.json"
+	methodVacuum   = "vacuum_into"
+	// methodLegacy marks raw copies made before manifests existed
+	methodLegacy = "legacy_copy"
+)
+
+// ErrIntegrity is returned when PRAGMA integrity_check does not report "ok"
+var ErrIntegrity = errors.New("backup failed integrity check")
+
+// ErrChecksum is returned when a backup file does not match its manifest
+var ErrChecksum = errors.New("backup checksum mismatch")
+
+// ErrLegacy is returned when verifying or restoring a legacy raw copy, which
+// has no checksums and may have been taken mid-write
+var ErrLegacy = errors.New("legacy backup has no manifest")
+
+// Options controls how a snapshot is written
+type Options struct {
+	Compress bool
+	// Now overrides the clock (tests)
+	Now func() time.Time
+}
+
+// Create snapshots dbPath into outputDir, verifies the copy and writes its manifest.
+func Create(ctx context.Context, dbPath, outputDir string, opts Options) (*Manifest, error) {
+	now := time.Now
+	if opts.Now != nil {
+		now = opts.Now
+	}
+
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return nil, fmt.Errorf("create backup directory: %w", err)
+	}
+
+	createdAt := now()
+	id := uniqueID(outputDir, FilePrefix+createdAt.Format(TimeLayout))
+
+	// 1. Snapshot into a plain .db file next to the final destination
+	rawPath := filepath.Join(outputDir, id+".db.tmp")
+	defer os.Remove(rawPath)
+
+	version, err := vacuumInto(ctx, dbPath, rawPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. Verify before we trust it
+	result, err := IntegrityCheck(ctx, rawPath)
+	if err != nil {
+		return nil, err
+	}
+	if result != "ok" {
+		return nil, fmt.Errorf("%w: %s", ErrIntegrity, result)
+	}
+
+	dbSum, dbSize, err := fileChecksum(rawPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// 3. Move into place, optionally compressed
+	file := id + ".db"
+	if opts.Compress {
+		file += ".gz"
+	}
+	finalPath := filepath.Join(outputDir, file)
+	if opts.Compress {
+		if err := gzipFile(rawPath, finalPath); err != nil {
+			return nil, err
+		}
+	} else if err := os.Rename(rawPath, finalPath); err != nil {
+		return nil, fmt.Errorf("move backup into place: %w", err)
+	}
+
+	sum, size, err := fileChecksum(finalPath)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Manifest{
+		ID:            id,
+		CreatedAt:     createdAt.UTC(),
+		Source:        dbPath,
+		File:          file,
+		Compressed:    opts.Compress,
+		Size:          size,
+		SHA256:        sum,
+		DBSize:        dbSize,
+		DBSHA256:      dbSum,
+		Integrity:     result,
+		Method:        methodVacuum,
+		SQLiteVersion: version,
+	}
+	if err := writeManifest(outputDir, m); err != nil {
+		os.Remove(finalPath)
+		return nil, err
+	}
+	return m, nil
+}
+
+// IntegrityCheck runs PRAGMA integrity_check against the database at path
+func IntegrityCheck(ctx context.Context, path string) (string, error) {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return "", fmt.Errorf("open %s: %w", path, err)
+	}
+	defer db.Close()
+
+	rows, err := db.QueryContext(ctx, "PRAGMA integrity_check")
+	if err != nil {
+		return "", fmt.Errorf("integrity check: %w", err)
+	}
+	defer rows.Close()
+
+	var lines []string
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			return "", err
+		}
+		lines = append(lines, line)
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+	return strings.Join(lines, "; "), nil
+}
+
+// vacuumInto writes a compacted, transactionally consistent copy of src to dst
+func vacuumInto(ctx context.Context, src, dst string) (string, error) {
+	if _, err := os.Stat(src); err != nil {
+		return "", fmt.Errorf("open database: %w", err)
+	}
+
+	// Same pragmas as the server so we queue politely behind a busy writer
+	db, err := sql.Open("sqlite3", src+"?_busy_timeout=5000")
+	if err != nil {
+		return "", fmt.Errorf("open database: %w", err)
+	}
+	defer db.Close()
+
+	var version string
+	if err := db.QueryRowContext(ctx, "SELECT sqlite_version()").Scan(&version); err != nil {
+		return "", fmt.Errorf("read sqlite version: %w", err)
+	}
+
+	if _, err := db.ExecContext(ctx, "VACUUM INTO ?", dst); err != nil {
+		return "", fmt.Errorf("vacuum into %s: %w", dst, err)
+	}
+	return version, nil
+}
+
+// uniqueID appends a counter when two backups land in the same second
+func uniqueID(dir, base string) string {
+	id := base
+	for i := 1; ; i++ {
+		matches, _ := filepath.Glob(filepath.Join(dir, id+".*"))
+		if len(matches) == 0 {
+			return id
+		}
+		id = fmt.Sprintf("%s-%d", base, i)
+	}
+}
+
+func gzipFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return fmt.Errorf("create backup file: %w", err)
+	}
+
+	gz := gzip.NewWriter(out)
+	if _, err := io.Copy(gz, in); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return fmt.Errorf("compress backup: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return fmt.Errorf("compress backup: %w", err)
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// fileChecksum returns the hex SHA-256 and size of a file
+func fileChecksum(path string) (string, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), n, nil
+}
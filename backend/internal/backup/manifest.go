@@ -0,0 +1,180 @@
+package backup
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Manifest describes one backup. It is stored next to the backup file as
+// <id>.manifest.json so a backup directory can be listed and verified without
+// opening any database.
+type Manifest struct {
+	ID            string    `json:"id"`
+	CreatedAt     time.Time `json:"created_at"`
+	Source        string    `json:"source"`
+	File          string    `json:"file"`
+	Compressed    bool      `json:"compressed"`
+	Size          int64     `json:"size"`      // bytes on disk
+	SHA256        string    `json:"sha256"`    // checksum of File as stored
+	DBSize        int64     `json:"db_size"`   // bytes of the uncompressed database
+	DBSHA256      string    `json:"db_sha256"` // checksum of the uncompressed database
+	Integrity     string    `json:"integrity"` // PRAGMA integrity_check result at backup time
+	Method        string    `json:"method"`
+	SQLiteVersion string    `json:"sqlite_version"`
+}
+
+// List returns every manifest in dir, oldest first. Legacy raw copies
+// without a manifest are listed with one describing the file (see Legacy), so
+// retention prunes them too.
+func List(dir string) ([]Manifest, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, FilePrefix+"*"+manifestSuffix))
+	if err != nil {
+		return nil, err
+	}
+
+	manifests := make([]Manifest, 0, len(paths))
+	described := make(map[string]bool, len(paths))
+	for _, p := range paths {
+		b, err := os.ReadFile(p)
+		if err != nil {
+			return nil, err
+		}
+		var m Manifest
+		if err := json.Unmarshal(b, &m); err != nil {
+			return nil, fmt.Errorf("parse %s: %w", filepath.Base(p), err)
+		}
+		manifests = append(manifests, m)
+		described[m.File] = true
+	}
+
+	legacy, err := listLegacy(dir, described)
+	if err != nil {
+		return nil, err
+	}
+	manifests = append(manifests, legacy...)
+
+	sort.Slice(manifests, func(i, j int) bool {
+		return manifests[i].CreatedAt.Before(manifests[j].CreatedAt)
+	})
+	return manifests, nil
+}
+
+// Legacy reports whether m describes a raw copy made before manifests existed
+func (m Manifest) Legacy() bool {
+	return m.Method == methodLegacy
+}
+
+// listLegacy describes the database_<timestamp>.db[.gz] files in dir that no
+// manifest describes. They are dated by the timestamp in their name, in local
+// time as the old backup command wrote it, or by their modification time.
+func listLegacy(dir string, described map[string]bool) ([]Manifest, error) {
+	var out []Manifest
+	for _, ext := range []string{".db", ".db.gz"} {
+		paths, err := filepath.Glob(filepath.Join(dir, FilePrefix+"*"+ext))
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range paths {
+			file := filepath.Base(p)
+			if described[file] {
+				continue
+			}
+			info, err := os.Stat(p)
+			if err != nil {
+				return nil, err
+			}
+			id := strings.TrimSuffix(file, ext)
+			createdAt, err := time.ParseInLocation(TimeLayout, strings.TrimPrefix(id, FilePrefix), time.Local)
+			if err != nil {
+				createdAt = info.ModTime()
+			}
+			out = append(out, Manifest{
+				ID:         id,
+				CreatedAt:  createdAt.UTC(),
+				File:       file,
+				Compressed: ext == ".db.gz",
+				Size:       info.Size(),
+				Method:     methodLegacy,
+			})
+		}
+	}
+	return out, nil
+}
+
+// Find returns the manifest with the given ID (or backup file name)
+func Find(dir, id string) (*Manifest, error) {
+	manifests, err := List(dir)
+	if err != nil {
+		return nil, err
+	}
+	id = strings.TrimSuffix(filepath.Base(id), manifestSuffix)
+	for i := range manifests {
+		if manifests[i].ID == id || manifests[i].File == id {
+			return &manifests[i], nil
+		}
+	}
+	return nil, fmt.Errorf("backup %q not found in %s", id, dir)
+}
+
+// SelectAt returns the newest verifiable backup taken at or before t
+// (point-in-time restore)
+func SelectAt(dir string, t time.Time) (*Manifest, error) {
+	manifests, err := List(dir)
+	if err != nil {
+		return nil, err
+	}
+	for i := len(manifests) - 1; i >= 0; i-- {
+		if !manifests[i].Legacy() && !manifests[i].CreatedAt.After(t) {
+			return &manifests[i], nil
+		}
+	}
+	return nil, fmt.Errorf("no backup at or before %s in %s", t.Format(time.RFC3339), dir)
+}
+
+// Verify checks that the backup file still matches its manifest checksum
+func Verify(dir string, m Manifest) error {
+	if m.Legacy() {
+		return fmt.Errorf("%w: %s", ErrLegacy, m.File)
+	}
+	sum, size, err := fileChecksum(filepath.Join(dir, m.File))
+	if err != nil {
+		return err
+	}
+	if sum != m.SHA256 || size != m.Size {
+		return fmt.Errorf("%w: %s", ErrChecksum, m.File)
+	}
+	return nil
+}
+
+// Remove deletes a backup file and its manifest, if it has one
+func Remove(dir string, m Manifest) error {
+	if err := os.Remove(filepath.Join(dir, m.File)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.Remove(manifestPath(dir, m.ID)); err != nil && !(m.Legacy() && os.IsNotExist(err)) {
+		return err
+	}
+	return nil
+}
+
+func manifestPath(dir, id string) string {
+	return filepath.Join(dir, id+manifestSuffix)
+}
+
+// writeManifest writes atomically so a crash never leaves a half-written manifest
+func writeManifest(dir string, m *Manifest) error {
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := manifestPath(dir, m.ID) + ".tmp"
+	if err := os.WriteFile(tmp, b, 0644); err != nil {
+		return fmt.Errorf("write manifest: %w", err)
+	}
+	return os.Rename(tmp, manifestPath(dir, m.ID))
+}
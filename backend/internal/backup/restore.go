@@ -0,0 +1,96 @@
+package backup
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ErrTargetExists is returned by Restore when the target exists and force is not set
+var ErrTargetExists = errors.New("restore target already exists")
+
+// Restore verifies a backup and atomically replaces targetPath with it.
+//
+// The server must be stopped first: SQLite does not expect its files to be
+// swapped underneath an open connection. When force is set and the target
+// exists, it is kept as <target>.pre-restore-<timestamp>. Its -wal/-shm files
+// move with it, so the kept copy includes the commits still in the WAL and
+// they cannot be replayed on top of the restored database.
+func Restore(ctx context.Context, dir string, m Manifest, targetPath string, force bool) error {
+	if err := Verify(dir, m); err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(targetPath); err == nil && !force {
+		return fmt.Errorf("%w: %s (use -force)", ErrTargetExists, targetPath)
+	}
+
+	tmp := targetPath + ".restore-tmp"
+	defer os.Remove(tmp)
+
+	if err := extract(filepath.Join(dir, m.File), tmp, m.Compressed); err != nil {
+		return err
+	}
+
+	sum, _, err := fileChecksum(tmp)
+	if err != nil {
+		return err
+	}
+	if sum != m.DBSHA256 {
+		return fmt.Errorf("%w: restored database does not match manifest", ErrChecksum)
+	}
+
+	result, err := IntegrityCheck(ctx, tmp)
+	if err != nil {
+		return err
+	}
+	if result != "ok" {
+		return fmt.Errorf("%w: %s", ErrIntegrity, result)
+	}
+
+	aside := fmt.Sprintf("%s.pre-restore-%s", targetPath, time.Now().Format(TimeLayout))
+	for _, suffix := range []string{"", "-wal", "-shm"} {
+		if err := os.Rename(targetPath+suffix, aside+suffix); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("move existing database%s aside: %w", suffix, err)
+		}
+	}
+
+	return os.Rename(tmp, targetPath)
+}
+
+func extract(src, dst string, compressed bool) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	var r io.Reader = in
+	if compressed {
+		gz, err := gzip.NewReader(in)
+		if err != nil {
+			return fmt.Errorf("open gzip: %w", err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, r); err != nil {
+		out.Close()
+		return fmt.Errorf("extract backup: %w", err)
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,110 @@
+package backup
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// RetentionPolicy is a grandfather-father-son policy.
+//
+// A backup is kept when it is one of the KeepLast newest backups, or the newest
+// backup of one of the KeepDaily most recent days (likewise weeks and months).
+// MaxAge, when set, removes anything older regardless of the other rules.
+// The newest backup is never removed.
+type RetentionPolicy struct {
+	KeepLast    int
+	KeepDaily   int
+	KeepWeekly  int
+	KeepMonthly int
+	MaxAge      time.Duration
+}
+
+// DefaultRetention keeps roughly the same window as the old hardcoded 30 days
+var DefaultRetention = RetentionPolicy{
+	KeepLast:  7,
+	KeepDaily: 30,
+}
+
+// Validate rejects policies that would delete every backup
+func (p RetentionPolicy) Validate() error {
+	if p.KeepLast < 0 || p.KeepDaily < 0 || p.KeepWeekly < 0 || p.KeepMonthly < 0 || p.MaxAge < 0 {
+		return fmt.Errorf("retention values must not be negative")
+	}
+	if p.KeepLast+p.KeepDaily+p.KeepWeekly+p.KeepMonthly == 0 {
+		return fmt.Errorf("retention policy keeps nothing; set at least one keep-* value")
+	}
+	return nil
+}
+
+// Apply splits manifests into those to keep and those to remove
+func (p RetentionPolicy) Apply(manifests []Manifest, now time.Time) (keep, remove []Manifest) {
+	sorted := append([]Manifest(nil), manifests...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].CreatedAt.After(sorted[j].CreatedAt)
+	})
+
+	kept := make(map[string]bool, len(sorted))
+	for i := 0; i < len(sorted) && i < p.KeepLast; i++ {
+		kept[sorted[i].ID] = true
+	}
+
+	bucket := func(limit int, key func(time.Time) string) {
+		seen := make(map[string]bool)
+		for _, m := range sorted {
+			if len(seen) >= limit {
+				return
+			}
+			k := key(m.CreatedAt.Local())
+			if !seen[k] {
+				seen[k] = true
+				kept[m.ID] = true
+			}
+		}
+	}
+	bucket(p.KeepDaily, func(t time.Time) string { return t.Format("2006-01-02") })
+	bucket(p.KeepWeekly, func(t time.Time) string {
+		y, w := t.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", y, w)
+	})
+	bucket(p.KeepMonthly, func(t time.Time) string { return t.Format("2006-01") })
+
+	if p.MaxAge > 0 {
+		cutoff := now.Add(-p.MaxAge)
+		for _, m := range sorted {
+			if m.CreatedAt.Before(cutoff) {
+				delete(kept, m.ID)
+			}
+		}
+	}
+	if len(sorted) > 0 {
+		kept[sorted[0].ID] = true
+	}
+
+	for _, m := range sorted {
+		if kept[m.ID] {
+			keep = append(keep, m)
+		} else {
+			remove = append(remove, m)
+		}
+	}
+	return keep, remove
+}
+
+// Prune applies the policy to dir and deletes expired backups
+func Prune(dir string, p RetentionPolicy, now time.Time) ([]Manifest, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	manifests, err := List(dir)
+	if err != nil {
+		return nil, err
+	}
+	_, remove := p.Apply(manifests, now)
+	for _, m := range remove {
+		if err := Remove(dir, m); err != nil {
+			return nil, fmt.Errorf("remove %s: %w", m.ID, err)
+		}
+	}
+	return remove, nil
+}
@@ -0,0 +1,40 @@
+// Package botcheck makes drop purchases costly for bots: the client solves a
+// hashcash-style proof of work, or passes a CAPTCHA whose token is checked
+// with the provider, and sends the result with the purchase.
+package botcheck
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Header carries the solved token on protected requests
+const Header = "X-Bot-Token"
+
+// Token failures
+var (
+	ErrMissing = errors.New("bot check token missing")
+	ErrInvalid = errors.New("bot check token invalid")
+)
+
+// Puzzle tells the client how to produce a token
+type Puzzle struct {
+	Kind string `json:"kind"` // pow or captcha
+	// Proof of work: find a nonce such that sha256(challenge ":" nonce) starts
+	// with Difficulty zero bits, and send "challenge:nonce"
+	Challenge  string     `json:"challenge,omitempty"`
+	Difficulty int        `json:"difficulty,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	// CAPTCHA: render the provider widget with SiteKey and send its token
+	SiteKey string `json:"site_key,omitempty"`
+}
+
+// Checker issues puzzles and verifies the tokens that solve them
+type Checker interface {
+	Kind() string
+	Puzzle() (Puzzle, error)
+	// Verify returns ErrInvalid for a wrong, expired or reused token; other
+	// errors mean the token could not be checked
+	Verify(ctx context.Context, token, ip string) error
+}
@@ -0,0 +1,51 @@
+package botcheck
+
+import (
+	"errors"
+	"log/slog"
+
+	"ecommerce-backend/internal/logging"
+	"ecommerce-backend/internal/metrics"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// Middleware requires a valid token in the X-Bot-Token header; a nil
+// checker allows everything. Missing and invalid tokens get 403. When the
+// token cannot be checked (provider down) the request is allowed, like a
+// failing rate limit store.
+func Middleware(ch Checker, log *slog.Logger) fiber.Handler {
+	log = logging.Subsystem(log, "botcheck")
+	return func(c fiber.Ctx) error {
+		if ch == nil {
+			return c.Next()
+		}
+
+		token := c.Get(Header)
+		err := ErrMissing
+		if token != "" {
+			err = ch.Verify(c.Context(), token, c.IP())
+		}
+		switch {
+		case err == nil:
+			metrics.BotChecks.WithLabelValues(ch.Kind(), "passed").Inc()
+			return c.Next()
+		case errors.Is(err, ErrMissing):
+			metrics.BotChecks.WithLabelValues(ch.Kind(), "missing").Inc()
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error":  "Bot check required",
+				"reason": "bot_check_required",
+			})
+		case errors.Is(err, ErrInvalid):
+			metrics.BotChecks.WithLabelValues(ch.Kind(), "invalid").Inc()
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error":  "Bot check failed, please retry",
+				"reason": "bot_check_failed",
+			})
+		default:
+			metrics.BotChecks.WithLabelValues(ch.Kind(), "error").Inc()
+			log.ErrorContext(c.Context(), "bot check unavailable, allowing request", "kind", ch.Kind(), "error", err)
+			return c.Next()
+		}
+	}
+}
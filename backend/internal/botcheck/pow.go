@@ -0,0 +1,142 @@
+package botcheck
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+	"time"
+
+	"ecommerce-backend/internal/ratelimit"
+)
+
+// Challenge layout: expiry (unix seconds) ‖ random ‖ truncated HMAC of both
+const (
+	expiryLen = 8
+	randomLen = 16
+	macLen    = 16
+	maxNonce  = 64
+)
+
+// PoWOptions configures a ProofOfWork
+type PoWOptions struct {
+	// Key signs challenges; instances sharing a Spent store must share it
+	Key []byte
+	// Difficulty is the number of leading zero bits required
+	Difficulty int
+	// TTL is how long a challenge can be solved and used
+	TTL time.Duration
+	// Spent remembers used challenges; nil uses a MemoryStore
+	Spent ratelimit.Store
+	// Now returns the current time; nil means time.Now
+	Now func() time.Time
+}
+
+// ProofOfWork issues signed, expiring challenges, so no state is kept until
+// a solution is spent. Each challenge buys one request.
+type ProofOfWork struct {
+	opts PoWOptions
+}
+
+// NewProofOfWork returns a proof-of-work Checker
+func NewProofOfWork(opts PoWOptions) *ProofOfWork {
+	if opts.Spent == nil {
+		opts.Spent = ratelimit.NewMemoryStore()
+	}
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+	return &ProofOfWork{opts: opts}
+}
+
+// Kind implements Checker
+func (p *ProofOfWork) Kind() string { return "pow" }
+
+// Puzzle implements Checker
+func (p *ProofOfWork) Puzzle() (Puzzle, error) {
+	expires := p.opts.Now().Add(p.opts.TTL).Truncate(time.Second)
+	raw := make([]byte, expiryLen+randomLen, expiryLen+randomLen+macLen)
+	binary.BigEndian.PutUint64(raw, uint64(expires.Unix()))
+	if _, err := rand.Read(raw[expiryLen:]); err != nil {
+		return Puzzle{}, fmt.Errorf("failed to generate challenge: %w", err)
+	}
+	raw = append(raw, p.mac(raw)...)
+
+	return Puzzle{
+		Kind:       p.Kind(),
+		Challenge:  base64.RawURLEncoding.EncodeToString(raw),
+		Difficulty: p.opts.Difficulty,
+		ExpiresAt:  &expires,
+	}, nil
+}
+
+// Verify implements Checker. A solution is accepted once.
+func (p *ProofOfWork) Verify(ctx context.Context, token, _ string) error {
+	challenge, nonce, ok := strings.Cut(token, ":")
+	if !ok || nonce == "" || len(nonce) > maxNonce {
+		return ErrInvalid
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(challenge)
+	if err != nil || len(raw) != expiryLen+randomLen+macLen {
+		return ErrInvalid
+	}
+	payload := raw[:expiryLen+randomLen]
+	if !hmac.Equal(raw[expiryLen+randomLen:], p.mac(payload)) {
+		return ErrInvalid
+	}
+	expires := time.Unix(int64(binary.BigEndian.Uint64(payload)), 0)
+	if !p.opts.Now().Before(expires) {
+		return ErrInvalid
+	}
+	if !Solves(challenge, nonce, p.opts.Difficulty) {
+		return ErrInvalid
+	}
+
+	// The spent marker outlives the challenge, so replays within its
+	// lifetime find an empty bucket
+	d, err := p.opts.Spent.Take(ctx, "pow:"+challenge, ratelimit.Limit{Events: 1, Period: p.opts.TTL + time.Minute})
+	if err != nil {
+		return err
+	}
+	if !d.Allowed {
+		return ErrInvalid
+	}
+	return nil
+}
+
+func (p *ProofOfWork) mac(payload []byte) []byte {
+	m := hmac.New(sha256.New, p.opts.Key)
+	m.Write(payload)
+	return m.Sum(nil)[:macLen]
+}
+
+// Solves reports whether sha256(challenge ":" nonce) starts with difficulty zero bits
+func Solves(challenge, nonce string, difficulty int) bool {
+	sum := sha256.Sum256([]byte(challenge + ":" + nonce))
+	zeros := 0
+	for _, b := range sum {
+		if b != 0 {
+			zeros += bits.LeadingZeros8(b)
+			break
+		}
+		zeros += 8
+	}
+	return zeros >= difficulty
+}
+
+// Solve finds a nonce for challenge by brute force and returns the token to
+// send; about 2^difficulty hashes. For Go clients such as cmd/loadtest.
+func Solve(challenge string, difficulty int) string {
+	for n := uint64(0); ; n++ {
+		nonce := strconv.FormatUint(n, 36)
+		if Solves(challenge, nonce, difficulty) {
+			return challenge + ":" + nonce
+		}
+	}
+}
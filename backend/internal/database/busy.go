@@ -0,0 +1,27 @@
+package database
+
+import (
+	"errors"
+
+	"ecommerce-backend/internal/metrics"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// IsBusy reports whether err is SQLITE_BUSY or SQLITE_LOCKED, i.e. the
+// statement gave up waiting for another connection's lock (busy_timeout)
+func IsBusy(err error) bool {
+	var se sqlite3.Error
+	if !errors.As(err, &se) {
+		return false
+	}
+	return se.Code == sqlite3.ErrBusy || se.Code == sqlite3.ErrLocked
+}
+
+// ObserveBusy counts busy errors for op in donald_sqlite_busy_errors_total and returns err unchanged
+func ObserveBusy(op string, err error) error {
+	if err != nil && IsBusy(err) {
+		metrics.SQLiteBusyErrors.WithLabelValues(op).Inc()
+	}
+	return err
+}
@@ -2,6 +2,7 @@ package database
 
 import (
 	"database/sql"
+	"fmt"
 	"log"
 	"time"
 
@@ -17,8 +18,34 @@ type DBInstance struct {
 
 var DB DBInstance
 
+// Options cấu hình pool; giá trị 0 dùng mặc định (1 writer / 100 reader / 5000ms)
+type Options struct {
+	MaxWriteConns int
+	MaxReadConns  int
+	BusyTimeout   int // milliseconds
+}
+
+func (o *Options) setDefaults() {
+	if o.MaxWriteConns <= 0 {
+		o.MaxWriteConns = 1
+	}
+	if o.MaxReadConns <= 0 {
+		o.MaxReadConns = 100
+	}
+	if o.BusyTimeout <= 0 {
+		o.BusyTimeout = 5000
+	}
+}
+
 // Connect khởi tạo database với cấu hình Production Sweet Spot
 func Connect(dbPath string) error {
+	return ConnectWithOptions(dbPath, Options{})
+}
+
+// ConnectWithOptions giống Connect nhưng dùng pool settings từ config
+func ConnectWithOptions(dbPath string, opts Options) error {
+	opts.setDefaults()
+
 	// DSN chuẩn cho Production
 	// _journal_mode=WAL: Cho phép Đọc/Ghi song song
 	// _synchronous=NORMAL: An toàn + nhanh (chỉ mất uncommitted transaction nếu mất điện)
@@ -26,13 +53,13 @@ func Connect(dbPath string) error {
 	// _foreign_keys=on: Bật foreign key constraints
 	// _txlock=immediate: Nâng cấp mọi transaction (BEGIN) thành BEGIN IMMEDIATE ngay lập tức.
 	// Điều này ngăn chặn tình trạng Deadlock khi nhiều connection cùng muốn nâng cấp từ Read lên Write lock.
-	// Kết hợp với SetMaxOpenConns(1), nó đảm bảo tính deterministic tuyệt đối cho Writer.
-	dsn := dbPath + "?_journal_mode=WAL&_synchronous=NORMAL&_busy_timeout=5000&_foreign_keys=on&_txlock=immediate"
+	// Kết hợp với MaxWriteConns=1 (mặc định), nó đảm bảo tính deterministic tuyệt đối cho Writer.
+	dsn := fmt.Sprintf("%s?_journal_mode=WAL&_synchronous=NORMAL&_busy_timeout=%d&_foreign_keys=on&_txlock=immediate", dbPath, opts.BusyTimeout)
 
 	var err error
 
 	// 1. KHỞI TẠO WRITER (QUAN TRỌNG NHẤT)
-	// Writer chỉ được phép có 1 Connection duy nhất để tránh xung đột khóa
+	// Writer mặc định chỉ có 1 Connection (opts.MaxWriteConns) để tránh xung đột khóa
 	// Ép toàn bộ lệnh Ghi phải xếp hàng (Serialize) trong Go
 	// Điều này nhanh hơn để SQLite tự lock file
 	DB.Writer, err = sql.Open("sqlite3", dsn)
@@ -41,8 +68,8 @@ func Connect(dbPath string) error {
 	}
 
 	// Cấu hình Writer: "Cổ chai" chủ động
-	DB.Writer.SetMaxOpenConns(1) // Chỉ 1 connection duy nhất
-	DB.Writer.SetMaxIdleConns(1) // Giữ 1 connection idle
+	DB.Writer.SetMaxOpenConns(opts.MaxWriteConns) // opts.MaxWriteConns, mặc định 1
+	DB.Writer.SetMaxIdleConns(opts.MaxWriteConns) // Giữ toàn bộ connection ghi idle
 	DB.Writer.SetConnMaxLifetime(time.Hour)
 
 	// Test connection
@@ -59,9 +86,9 @@ func Connect(dbPath string) error {
 	}
 
 	// Cấu hình Reader: Mở rộng theo CPU
-	// Ví dụ: 100 kết nối đọc đồng thời
-	DB.Reader.SetMaxOpenConns(100) // Hỗ trợ 100 concurrent reads
-	DB.Reader.SetMaxIdleConns(100) // Keep 100 idle connections
+	// Số kết nối đọc đồng thời lấy từ opts.MaxReadConns (mặc định 100)
+	DB.Reader.SetMaxOpenConns(opts.MaxReadConns) // opts.MaxReadConns concurrent reads
+	DB.Reader.SetMaxIdleConns(opts.MaxReadConns) // Keep them all idle
 	DB.Reader.SetConnMaxLifetime(time.Hour)
 
 	// Test connection
@@ -0,0 +1,19 @@
+package database
+
+import (
+	"github.com/gofiber/fiber/v3"
+)
+
+// SessionMiddleware attaches a read-your-writes session (WithSession) to
+// the context of POST, PUT, PATCH and DELETE requests, so that once such a
+// request writes, its later reads see the write instead of a lagging
+// Reader. Reads by GET and HEAD requests stay on Reader.
+func SessionMiddleware() fiber.Handler {
+	return func(c fiber.Ctx) error {
+		switch c.Method() {
+		case fiber.MethodPost, fiber.MethodPut, fiber.MethodPatch, fiber.MethodDelete:
+			c.SetContext(WithSession(c.Context()))
+		}
+		return c.Next()
+	}
+}
@@ -0,0 +1,167 @@
+package database
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+)
+
+type routingKey int
+
+const (
+	primaryKey routingKey = iota
+	sessionKey
+)
+
+// WithPrimary marks ctx so that every statement issued with it reads from Writer
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey, true)
+}
+
+// WithSession attaches a read-your-writes session to ctx.
+// After the first write issued with ctx (or a derived ctx), reads are routed to Writer.
+func WithSession(ctx context.Context) context.Context {
+	if sessionFrom(ctx) != nil {
+		return ctx
+	}
+	return context.WithValue(ctx, sessionKey, new(atomic.Bool))
+}
+
+// SessionWrote reports whether the session attached to ctx has written
+func SessionWrote(ctx context.Context) bool {
+	s := sessionFrom(ctx)
+	return s != nil && s.Load()
+}
+
+func isPrimary(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	v, _ := ctx.Value(primaryKey).(bool)
+	return v
+}
+
+func sessionFrom(ctx context.Context) *atomic.Bool {
+	if ctx == nil {
+		return nil
+	}
+	s, _ := ctx.Value(sessionKey).(*atomic.Bool)
+	return s
+}
+
+// IsWriteQuery classifies a SQL statement as a write (true) or a pure read (false).
+//
+// Only SELECT, VALUES and EXPLAIN statements, and WITH statements whose main
+// statement is a SELECT, are reads. A top-level RETURNING clause always means
+// a write, so `INSERT ... RETURNING` issued through QueryRow lands on Writer.
+// `INSERT ... SELECT` starts with INSERT and is therefore a write. Anything
+// unknown (PRAGMA, DDL, BEGIN, ...) is treated as a write to stay safe.
+// Keywords inside string literals, quoted identifiers and comments are ignored.
+func IsWriteQuery(query string) bool {
+	words := topLevelKeywords(query)
+	if len(words) == 0 {
+		return false
+	}
+
+	for _, w := range words {
+		if w == "RETURNING" {
+			return true
+		}
+	}
+
+	switch words[0] {
+	case "SELECT", "VALUES", "EXPLAIN":
+		return false
+	case "WITH":
+		// WITH [RECURSIVE] name AS (...) [, name AS (...)] <main statement>
+		// CTE bodies are parenthesised, so the first top-level DML or SELECT
+		// keyword after WITH is the main statement.
+		for _, w := range words[1:] {
+			switch w {
+			case "INSERT", "UPDATE", "DELETE", "REPLACE":
+				return true
+			case "SELECT", "VALUES":
+				return false
+			}
+		}
+		return true
+	default:
+		return true
+	}
+}
+
+// topLevelKeywords returns the upper-cased bare words found outside of
+// parentheses, string literals, quoted identifiers and comments.
+func topLevelKeywords(query string) []string {
+	var words []string
+	depth := 0
+	n := len(query)
+
+	for i := 0; i < n; {
+		c := query[i]
+		switch {
+		case c == '-' && i+1 < n && query[i+1] == '-':
+			for i < n && query[i] != '\n' {
+				i++
+			}
+		case c == '/' && i+1 < n && query[i+1] == '*':
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				return words
+			}
+			i += end + 4
+		case c == '\'' || c == '"' || c == '`':
+			i = skipQuoted(query, i, c)
+		case c == '[':
+			end := strings.IndexByte(query[i:], ']')
+			if end < 0 {
+				return words
+			}
+			i += end + 1
+		case c == '(':
+			depth++
+			i++
+		case c == ')':
+			if depth > 0 {
+				depth--
+			}
+			i++
+		case isWordStart(c):
+			start := i
+			for i < n && isWordPart(query[i]) {
+				i++
+			}
+			if depth == 0 {
+				words = append(words, strings.ToUpper(query[start:i]))
+			}
+		default:
+			i++
+		}
+	}
+	return words
+}
+
+// skipQuoted returns the index just past the quoted token starting at i.
+// A doubled quote character is an escaped quote, as in SQL.
+func skipQuoted(query string, i int, quote byte) int {
+	i++
+	for i < len(query) {
+		if query[i] == quote {
+			if i+1 < len(query) && query[i+1] == quote {
+				i += 2
+				continue
+			}
+			return i + 1
+		}
+		i++
+	}
+	return i
+}
+
+func isWordStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isWordPart(c byte) bool {
+	return isWordStart(c) || (c >= '0' && c <= '9') || c == '$'
+}
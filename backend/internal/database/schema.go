@@ -0,0 +1,26 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// SchemaVersion is the schema this binary migrates to. Bump it whenever
+// models or tables change; /readyz fails while the database is older.
+const SchemaVersion = 9
+
+// SetSchemaVersion records v in the database header (PRAGMA user_version)
+// after migrations have run
+func SetSchemaVersion(db *sql.DB, v int) error {
+	// PRAGMA does not take bound parameters
+	_, err := db.Exec(fmt.Sprintf("PRAGMA user_version = %d", v))
+	return err
+}
+
+// GetSchemaVersion returns the version recorded by SetSchemaVersion (0 if never set)
+func GetSchemaVersion(ctx context.Context, db *sql.DB) (int, error) {
+	var v int
+	err := db.QueryRowContext(ctx, "PRAGMA user_version").Scan(&v)
+	return v, err
+}
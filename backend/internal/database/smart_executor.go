@@ -1,13 +1,40 @@
 package database
 
 import (
+	"context"
 	"database/sql"
+	"strings"
+	"sync/atomic"
+
+	"ecommerce-backend/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
-// SmartExecutor automatically routes SELECT queries to Reader and write queries to Writer
+var tracer = tracing.Tracer("database")
+
+// SmartExecutor automatically routes SELECT queries to Reader and write queries to Writer.
+//
+// Routing rules (first match wins):
+//  1. Statements classified as writes (see IsWriteQuery) always go to Writer.
+//  2. Executors created with Primary(), or contexts marked with WithPrimary, read from Writer.
+//  3. Sessions (Session(), or WithSession as SessionMiddleware attaches to
+//     mutating requests) that already wrote read from Writer (read-your-writes).
+//  4. Everything else reads from Reader.
+//
+// Every statement is recorded as a span with a db.sqlite.pool attribute
+// ("reader" or "writer"); use WithContext to parent them to a request.
 type SmartExecutor struct {
-	writer *sql.DB
-	reader *sql.DB
+	writer  *sql.DB
+	reader  *sql.DB
+	primary bool
+	// wrote is non-nil for sessions and flips to true after the first write
+	wrote *atomic.Bool
+	// ctx is used by the methods without a context parameter; nil means Background
+	ctx context.Context
 }
 
 // NewSmartExecutor creates a new smart executor that routes queries optimally
@@ -18,26 +45,197 @@ func NewSmartExecutor(writer *sql.DB, reader *sql.DB) *SmartExecutor {
 	}
 }
 
+// Primary returns an executor that sends every statement, including reads, to Writer.
+// Use it for logic that must never observe stale data.
+func (se *SmartExecutor) Primary() *SmartExecutor {
+	return &SmartExecutor{
+		writer:  se.writer,
+		reader:  se.reader,
+		primary: true,
+		wrote:   se.wrote,
+		ctx:     se.ctx,
+	}
+}
+
+// Session returns an executor that becomes sticky to Writer once it has written.
+// Reads before the first write still use Reader.
+func (se *SmartExecutor) Session() *SmartExecutor {
+	return &SmartExecutor{
+		writer:  se.writer,
+		reader:  se.reader,
+		primary: se.primary,
+		wrote:   new(atomic.Bool),
+		ctx:     se.ctx,
+	}
+}
+
+// WithContext returns an executor whose Query/QueryRow/Exec/Begin use ctx,
+// for callers (like the repository) that do not pass a context per statement
+func (se *SmartExecutor) WithContext(ctx context.Context) *SmartExecutor {
+	return &SmartExecutor{
+		writer:  se.writer,
+		reader:  se.reader,
+		primary: se.primary,
+		wrote:   se.wrote,
+		ctx:     ctx,
+	}
+}
+
+// Context returns the context set by WithContext, or context.Background()
+func (se *SmartExecutor) Context() context.Context {
+	if se.ctx == nil {
+		return context.Background()
+	}
+	return se.ctx
+}
+
 // Query routes SELECT queries to Reader
 func (se *SmartExecutor) Query(query string, args ...interface{}) (*sql.Rows, error) {
-	// Route read queries to Reader for parallelism
-	return se.reader.Query(query, args...)
+	return se.QueryContext(se.Context(), query, args...)
 }
 
 // QueryRow routes SELECT queries to Reader
 func (se *SmartExecutor) QueryRow(query string, args ...interface{}) *sql.Row {
-	// Route read queries to Reader for parallelism
-	return se.reader.QueryRow(query, args...)
+	return se.QueryRowContext(se.Context(), query, args...)
 }
 
 // Exec routes write operations to Writer
 func (se *SmartExecutor) Exec(query string, args ...interface{}) (sql.Result, error) {
-	// All writes go to Writer for serialization and safety
-	return se.writer.Exec(query, args...)
+	return se.ExecContext(se.Context(), query, args...)
 }
 
 // Begin creates a new transaction on Writer (for serialization)
 func (se *SmartExecutor) Begin() (*sql.Tx, error) {
-	// Transactions always use Writer for safety and serialization
-	return se.writer.Begin()
+	return se.BeginTx(se.Context(), nil)
+}
+
+// QueryContext is Query with routing hints taken from ctx
+func (se *SmartExecutor) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	db := se.route(ctx, query)
+	ctx, span := se.startSpan(ctx, query, db)
+	rows, err := db.QueryContext(ctx, query, args...)
+	err = ObserveBusy("query", err)
+	endSpan(span, err)
+	return rows, err
+}
+
+// QueryRowContext is QueryRow with routing hints taken from ctx
+func (se *SmartExecutor) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	db := se.route(ctx, query)
+	ctx, span := se.startSpan(ctx, query, db)
+	row := db.QueryRowContext(ctx, query, args...)
+	endSpan(span, row.Err())
+	return row
+}
+
+// ExecContext always runs on Writer and marks the session as dirty
+func (se *SmartExecutor) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	se.markWrote(ctx)
+	ctx, span := se.startSpan(ctx, query, se.writer)
+	res, err := se.writer.ExecContext(ctx, query, args...)
+	err = ObserveBusy("exec", err)
+	endSpan(span, err)
+	return res, err
+}
+
+// BeginTx always runs on Writer; a transaction may write, so the session becomes sticky
+func (se *SmartExecutor) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	se.markWrote(ctx)
+	tx, err := se.writer.BeginTx(ctx, opts)
+	return tx, ObserveBusy("begin", err)
+}
+
+// BeginWriteTx starts a transaction on Writer with the executor's context.
+// Its statements and the wait for the single writer connection are traced.
+func (se *SmartExecutor) BeginWriteTx() (*Tx, error) {
+	ctx := se.Context()
+	se.markWrote(ctx)
+	ctx, span := tracer.Start(ctx, "TRANSACTION",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.DBSystemNameSQLite, attribute.String("db.sqlite.pool", "writer")),
+	)
+	tx, err := se.writer.BeginTx(ctx, nil)
+	if err = ObserveBusy("begin", err); err != nil {
+		endSpan(span, err)
+		return nil, err
+	}
+	return &Tx{tx: tx, ctx: ctx, span: span}, nil
+}
+
+// Writer exposes the underlying write pool
+func (se *SmartExecutor) Writer() *sql.DB {
+	return se.writer
+}
+
+// Reader exposes the underlying read pool
+func (se *SmartExecutor) Reader() *sql.DB {
+	return se.reader
+}
+
+// route picks the pool for a statement issued through Query/QueryRow
+func (se *SmartExecutor) route(ctx context.Context, query string) *sql.DB {
+	if IsWriteQuery(query) {
+		se.markWrote(ctx)
+		return se.writer
+	}
+	if se.primary || isPrimary(ctx) {
+		return se.writer
+	}
+	if se.wrote != nil && se.wrote.Load() {
+		return se.writer
+	}
+	if s := sessionFrom(ctx); s != nil && s.Load() {
+		return se.writer
+	}
+	return se.reader
+}
+
+// startSpan opens a span for one statement on db
+func (se *SmartExecutor) startSpan(ctx context.Context, query string, db *sql.DB) (context.Context, trace.Span) {
+	pool := "reader"
+	if db == se.writer {
+		pool = "writer"
+	}
+	return startSpan(ctx, query, pool)
+}
+
+func startSpan(ctx context.Context, query, pool string) (context.Context, trace.Span) {
+	return tracer.Start(ctx, operation(query),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBQueryText(query),
+			attribute.String("db.sqlite.pool", pool),
+		),
+	)
+}
+
+func endSpan(span trace.Span, err error) {
+	if err != nil && err != sql.ErrNoRows {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// operation returns the statement's leading keyword (SELECT, UPDATE, ...) as the span name
+func operation(query string) string {
+	query = strings.TrimSpace(query)
+	end := 0
+	for end < len(query) && isWordPart(query[end]) {
+		end++
+	}
+	if end == 0 {
+		return "SQL"
+	}
+	return strings.ToUpper(query[:end])
+}
+
+func (se *SmartExecutor) markWrote(ctx context.Context) {
+	if se.wrote != nil {
+		se.wrote.Store(true)
+	}
+	if s := sessionFrom(ctx); s != nil {
+		s.Store(true)
+	}
 }
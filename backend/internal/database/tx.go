@@ -0,0 +1,57 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Tx is a writer transaction started by SmartExecutor.BeginWriteTx. Its
+// statements run with the executor's context and are traced as children of
+// the transaction span, which ends on Commit or Rollback.
+type Tx struct {
+	tx   *sql.Tx
+	ctx  context.Context
+	span trace.Span
+}
+
+// Query runs a read inside the transaction
+func (t *Tx) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	ctx, span := startSpan(t.ctx, query, "writer")
+	rows, err := t.tx.QueryContext(ctx, query, args...)
+	err = ObserveBusy("query", err)
+	endSpan(span, err)
+	return rows, err
+}
+
+// QueryRow runs a single-row read inside the transaction
+func (t *Tx) QueryRow(query string, args ...interface{}) *sql.Row {
+	ctx, span := startSpan(t.ctx, query, "writer")
+	row := t.tx.QueryRowContext(ctx, query, args...)
+	endSpan(span, row.Err())
+	return row
+}
+
+// Exec runs a write inside the transaction
+func (t *Tx) Exec(query string, args ...interface{}) (sql.Result, error) {
+	ctx, span := startSpan(t.ctx, query, "writer")
+	res, err := t.tx.ExecContext(ctx, query, args...)
+	err = ObserveBusy("exec", err)
+	endSpan(span, err)
+	return res, err
+}
+
+// Commit commits the transaction and ends its span
+func (t *Tx) Commit() error {
+	err := t.tx.Commit()
+	endSpan(t.span, err)
+	return err
+}
+
+// Rollback aborts the transaction and ends its span
+func (t *Tx) Rollback() error {
+	err := t.tx.Rollback()
+	t.span.End()
+	return err
+}
@@ -0,0 +1,81 @@
+package emails
+
+// OrderItem is one line of an order
+type OrderItem struct {
+	Name     string
+	Quantity int
+	Price    int64
+}
+
+// Subtotal is the line's price times its quantity
+func (i OrderItem) Subtotal() int64 { return i.Price * int64(i.Quantity) }
+
+// OrderConfirmationData fills OrderConfirmation, sent when a drop order is paid
+type OrderConfirmationData struct {
+	OrderNumber string
+	Total       int64
+}
+
+// OrderDetailsData fills OrderDetails, the full order sent for guest lookup
+type OrderDetailsData struct {
+	OrderNumber string
+	Total       int64
+	// Status is a models.OrderStatusName: pending, confirmed, paid, delivered or cancelled
+	Status  string
+	Address string
+	Items   []OrderItem
+	// Link is a one-time link to the order; without it the email points to LookupURL
+	Link      string
+	LookupURL string
+}
+
+// SymbioteReceiptData fills SymbioteReceipt, the drop result
+type SymbioteReceiptData struct {
+	UserID string // masked phone
+	// Result is WINNER or LOSER; anything else renders as a notice with that status
+	Result  string
+	Elapsed string
+}
+
+// AdminOrderCreatedData fills AdminOrderCreated, sent to the shop's admins
+type AdminOrderCreatedData struct {
+	OrderNumber string
+	Total       int64
+	Status      string // as in OrderDetailsData
+	Name        string
+	Email       string
+	Phone       string
+	Address     string
+	Items       []OrderItem
+}
+
+// AdminAlertData fills AdminAlert, operational alerts sent to the shop's
+// admins: one alert, or a digest of the alerts held during a busy period
+type AdminAlertData struct {
+	Digest bool
+	Alerts []AlertItem
+}
+
+// AlertItem is one alert
+type AlertItem struct {
+	At     string // HH:MM:SS
+	Title  string
+	Detail string
+}
+
+// PasswordResetData fills PasswordReset
+type PasswordResetData struct {
+	ResetURL string
+}
+
+// TransferCodeData fills TransferCode, the one-time code that confirms a
+// symbicode transfer
+type TransferCodeData struct {
+	Code      string
+	ExpiresIn int // minutes
+}
+
+// WelcomeData fills Welcome
+type WelcomeData struct {
+	Name string
+}
@@ -0,0 +1,142 @@
+// Package emails renders transactional emails from templates embedded in the
+// binary. Every email has an HTML body (html/template, escaped) and a
+// plain-text alternative (text/template), in Vietnamese and English.
+//
+// Templates live in templates/<locale>/: <name>.html defines "content" for
+// the shared layout, <name>.txt defines "subject" and holds the text body,
+// and common.tmpl holds strings shared by the locale's emails.
+package emails
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"sort"
+	"strings"
+	texttemplate "text/template"
+)
+
+// Template names
+const (
+	OrderConfirmation = "order_confirmation"
+	OrderDetails      = "order_details"
+	SymbioteReceipt   = "symbiote_receipt"
+	AdminOrderCreated = "admin_order_created"
+	AdminAlert        = "admin_alert"
+	PasswordReset     = "password_reset"
+	TransferCode      = "transfer_code"
+	Welcome           = "welcome"
+)
+
+//go:embed templates
+var templateFS embed.FS
+
+// Message is a rendered email
+type Message struct {
+	Template string // name of the template it was rendered from
+	Subject  string
+	HTML     string
+	Text     string
+}
+
+// email is one template in one locale
+type email struct {
+	html *htmltemplate.Template
+	text *texttemplate.Template
+}
+
+// registry holds every template by locale and name, parsed once at startup
+var registry = mustParse()
+
+// Names lists the templates, sorted
+func Names() []string {
+	names := make([]string, 0, len(registry[DefaultLocale]))
+	for name := range registry[DefaultLocale] {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Render renders the template name in locale with data, one of the *Data
+// types of this package
+func Render(name string, locale Locale, data any) (Message, error) {
+	e, ok := registry[locale][name]
+	if !ok {
+		return Message{}, fmt.Errorf("unknown email template %q", name)
+	}
+
+	var subject, text, html bytes.Buffer
+	if err := e.text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return Message{}, fmt.Errorf("render %s/%s subject: %w", locale, name, err)
+	}
+	if err := e.text.Execute(&text, data); err != nil {
+		return Message{}, fmt.Errorf("render %s/%s text: %w", locale, name, err)
+	}
+	if err := e.html.ExecuteTemplate(&html, "layout", data); err != nil {
+		return Message{}, fmt.Errorf("render %s/%s html: %w", locale, name, err)
+	}
+	return Message{
+		Template: name,
+		Subject:  strings.TrimSpace(subject.String()),
+		HTML:     html.String(),
+		Text:     strings.TrimSpace(text.String()) + "\n",
+	}, nil
+}
+
+func mustParse() map[Locale]map[string]email {
+	layout, err := fs.ReadFile(templateFS, "templates/layout.html")
+	if err != nil {
+		panic(err)
+	}
+
+	out := make(map[Locale]map[string]email, len(Locales))
+	for _, locale := range Locales {
+		dir := "templates/" + string(locale)
+		common, err := fs.ReadFile(templateFS, dir+"/common.tmpl")
+		if err != nil {
+			panic(err)
+		}
+		files, err := fs.Glob(templateFS, dir+"/*.txt")
+		if err != nil {
+			panic(err)
+		}
+
+		out[locale] = make(map[string]email, len(files))
+		for _, file := range files {
+			name := strings.TrimSuffix(file[len(dir)+1:], ".txt")
+			e, err := parse(locale, name, dir, layout, common)
+			if err != nil {
+				panic(err)
+			}
+			out[locale][name] = e
+		}
+	}
+	return out
+}
+
+func parse(locale Locale, name, dir string, layout, common []byte) (email, error) {
+	funcs := funcMap(locale)
+
+	text := texttemplate.New(name + ".txt").Funcs(texttemplate.FuncMap(funcs))
+	if _, err := text.New("common").Parse(string(common)); err != nil {
+		return email{}, fmt.Errorf("%s/common.tmpl: %w", dir, err)
+	}
+	if _, err := text.ParseFS(templateFS, dir+"/"+name+".txt"); err != nil {
+		return email{}, err
+	}
+
+	html := htmltemplate.New("layout.html").Funcs(htmltemplate.FuncMap(funcs))
+	if _, err := html.Parse(string(layout)); err != nil {
+		return email{}, fmt.Errorf("templates/layout.html: %w", err)
+	}
+	if _, err := html.New("common").Parse(string(common)); err != nil {
+		return email{}, fmt.Errorf("%s/common.tmpl: %w", dir, err)
+	}
+	if _, err := html.ParseFS(templateFS, dir+"/"+name+".html"); err != nil {
+		return email{}, err
+	}
+	return email{html: html, text: text}, nil
+}
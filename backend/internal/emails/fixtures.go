@@ -0,0 +1,55 @@
+package emails
+
+// Fixture returns sample data for the template name, for previews and tests
+func Fixture(name string) any {
+	items := []OrderItem{
+		{Name: "Donald Symbiote Watch", Quantity: 1, Price: 2490000},
+		{Name: "Leather strap <black>", Quantity: 2, Price: 350000},
+	}
+	address := "12 Lê Lợi, Phường Bến Nghé, Quận 1, TP. Hồ Chí Minh"
+
+	switch name {
+	case OrderConfirmation:
+		return OrderConfirmationData{OrderNumber: "DV-P0078DWZD", Total: 3190000}
+	case OrderDetails:
+		return OrderDetailsData{
+			OrderNumber: "DV-P0078DWZD",
+			Total:       3190000,
+			Status:      "paid",
+			Address:     address,
+			Items:       items,
+			Link:        "https://donaldwatch.vn/orders/view?t=2a7Kq9Xw",
+			LookupURL:   "https://donaldwatch.vn/orders",
+		}
+	case SymbioteReceipt:
+		return SymbioteReceiptData{UserID: "*******567", Result: "WINNER", Elapsed: "0.042s"}
+	case AdminOrderCreated:
+		return AdminOrderCreatedData{
+			OrderNumber: "DV-P0078DWZD",
+			Total:       3190000,
+			Status:      "pending",
+			Name:        "Nguyễn Văn An",
+			Email:       "an@example.com",
+			Phone:       "0901234567",
+			Address:     address,
+			Items:       items,
+		}
+	case AdminAlert:
+		return AdminAlertData{
+			Digest: true,
+			Alerts: []AlertItem{
+				{At: "20:00:03", Title: "New paid order DV-P0078DWZD", Detail: "3.190.000 ₫, Nguyễn Văn An"},
+				{At: "20:00:41", Title: "Drop sold out", Detail: "Symbiote Genesis <#1> (100/100)"},
+				{At: "20:02:17", Title: "Refund failed for DV-Q1R2S3T4U", Detail: "PayOS refund error: 502"},
+			},
+		}
+	case PasswordReset:
+		return PasswordResetData{ResetURL: "https://donaldwatch.vn/reset-password?token=example"}
+	case TransferCode:
+		return TransferCodeData{Code: "493021", ExpiresIn: 10}
+	case Welcome:
+		return WelcomeData{Name: "An"}
+	default:
+		return nil
+	}
+}
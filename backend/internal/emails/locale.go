@@ -0,0 +1,84 @@
+package emails
+
+import (
+	"context"
+	"strconv"
+	"strings"
+)
+
+// Locale selects the language of an email
+type Locale string
+
+// Supported locales
+const (
+	Vietnamese Locale = "vi"
+	English    Locale = "en"
+)
+
+// DefaultLocale is used for customers who never chose a language
+const DefaultLocale = Vietnamese
+
+// Locales lists the supported locales
+var Locales = []Locale{Vietnamese, English}
+
+// ParseLocale reads a locale from a stored preference, a language tag
+// ("en-US") or an Accept-Language header, taking the first supported
+// language. Anything else is DefaultLocale.
+func ParseLocale(s string) Locale {
+	for _, part := range strings.Split(s, ",") {
+		tag, _, _ := strings.Cut(part, ";")
+		lang, _, _ := strings.Cut(strings.TrimSpace(tag), "-")
+		for _, l := range Locales {
+			if strings.EqualFold(lang, string(l)) {
+				return l
+			}
+		}
+	}
+	return DefaultLocale
+}
+
+type localeKey struct{}
+
+// WithLocale returns ctx carrying the customer's locale, used by the email
+// senders that only receive an address
+func WithLocale(ctx context.Context, l Locale) context.Context {
+	return context.WithValue(ctx, localeKey{}, l)
+}
+
+// LocaleFrom returns the locale carried by ctx, or DefaultLocale
+func LocaleFrom(ctx context.Context) Locale {
+	if l, ok := ctx.Value(localeKey{}).(Locale); ok {
+		return l
+	}
+	return DefaultLocale
+}
+
+// funcMap holds the template functions formatting for locale
+func funcMap(locale Locale) map[string]any {
+	return map[string]any{
+		"money": func(amount int64) string { return FormatMoney(locale, amount) },
+	}
+}
+
+// FormatMoney writes VND amounts the way each locale reads them:
+// 1.250.000 ₫ and 1,250,000 VND
+func FormatMoney(locale Locale, amount int64) string {
+	sep, suffix := ",", " VND"
+	if locale == Vietnamese {
+		sep, suffix = ".", " ₫"
+	}
+
+	digits := strconv.FormatInt(amount, 10)
+	sign := ""
+	if amount < 0 {
+		sign, digits = "-", digits[1:]
+	}
+	var b strings.Builder
+	for i, d := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteString(sep)
+		}
+		b.WriteRune(d)
+	}
+	return sign + b.String() + suffix
+}
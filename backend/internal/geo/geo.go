@@ -0,0 +1,63 @@
+// Package geo resolves client IPs to a coarse location (country and city)
+// from a local MaxMind database, so no request leaves the server.
+package geo
+
+import (
+	"net"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// Location is a coarse client location; empty fields are unknown
+type Location struct {
+	Country string `json:"country,omitempty"` // ISO 3166-1 alpha-2
+	City    string `json:"city,omitempty"`    // English name
+}
+
+// Locator looks up the location of an IP address
+type Locator interface {
+	Locate(ip string) Location
+}
+
+// DB reads a GeoLite2/GeoIP2 City or Country database (.mmdb)
+type DB struct {
+	reader *maxminddb.Reader
+}
+
+// Open loads the database at path into memory
+func Open(path string) (*DB, error) {
+	reader, err := maxminddb.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return &DB{reader: reader}, nil
+}
+
+// record holds the fields read from both City and Country databases
+type record struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	City struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"city"`
+}
+
+// Locate returns the location of ip; private, invalid and unknown
+// addresses have an empty location
+func (d *DB) Locate(ip string) Location {
+	parsed := net.ParseIP(ip)
+	if parsed == nil || parsed.IsLoopback() || parsed.IsPrivate() || parsed.IsUnspecified() {
+		return Location{}
+	}
+	var rec record
+	if err := d.reader.Lookup(parsed, &rec); err != nil {
+		return Location{}
+	}
+	return Location{Country: rec.Country.ISOCode, City: rec.City.Names["en"]}
+}
+
+// Close releases the database
+func (d *DB) Close() error {
+	return d.reader.Close()
+}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// requireAdmin accepts requests carrying "Authorization: Bearer <ADMIN_API_TOKEN>"
+func (h *Handlers) requireAdmin(c fiber.Ctx) error {
+	token, ok := strings.CutPrefix(c.Get(fiber.HeaderAuthorization), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(h.adminToken)) != 1 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+	}
+	return c.Next()
+}
+
+// SchedulerJobs lists the periodic jobs with their next and last runs
+func (h *Handlers) SchedulerJobs(c fiber.Ctx) error {
+	jobs, err := h.scheduler.Status(c.Context())
+	if err != nil {
+		h.log.ErrorContext(c.Context(), "scheduler status failed", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to read job status"})
+	}
+	return c.JSON(fiber.Map{"jobs": jobs})
+}
+
+// registerAdminRoutes mounts /api/admin only when an admin token is configured
+func registerAdminRoutes(app *fiber.App, h *Handlers) {
+	if h.adminToken == "" {
+		return
+	}
+	admin := app.Group("/api/admin", h.requireAdmin)
+	if h.scheduler != nil {
+		admin.Get("/scheduler/jobs", h.SchedulerJobs)
+	}
+	if h.alerts != nil {
+		admin.Get("/alerts", h.AlertStatus)
+		admin.Put("/alerts/digest", h.SetAlertDigest)
+	}
+	if h.sheets != nil {
+		admin.Get("/sheets", h.SheetStatus)
+		admin.Post("/sheets/backfill", h.BackfillSheet)
+	}
+	if h.webhooks != nil {
+		admin.Get("/webhooks", h.WebhookSubscriptions)
+		admin.Post("/webhooks", h.CreateWebhookSubscription)
+		admin.Delete("/webhooks/:id", h.DeleteWebhookSubscription)
+		admin.Get("/webhooks/:id/deliveries", h.WebhookDeliveries)
+		admin.Get("/webhook-deliveries/:id", h.WebhookDelivery)
+		admin.Post("/webhook-deliveries/:id/replay", h.ReplayWebhookDelivery)
+	}
+	admin.Get("/orders", h.GetOrdersByPhone)
+	admin.Get("/orders/:id", h.GetOrderByID)
+	admin.Get("/orders/:id/emails", h.OrderEmails)
+	admin.Get("/email-suppressions", h.EmailSuppressions)
+	admin.Delete("/email-suppressions/:email", h.RemoveEmailSuppression)
+	admin.Get("/symbicodes/suspicious", h.SuspiciousSymbicodes)
+	admin.Get("/symbicodes/:id/scans", h.SymbicodeScans)
+	admin.Post("/symbicodes/batches", h.GenerateSymbicodeBatch)
+	admin.Get("/symbicodes/batches/:id/labels", h.SymbicodeBatchLabels)
+	admin.Post("/symbicodes/bind", h.BindSymbicode)
+}
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"encoding/json"
+	"time"
+
+	"ecommerce-backend/internal/alerts"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// alertSignature counts a rejected webhook from provider toward the
+// signature failure alert
+func (h *Handlers) alertSignature(c fiber.Ctx, provider, reason string) {
+	h.alerts.Alert(c.Context(), alerts.Alert{
+		Kind:   alerts.WebhookSignature,
+		Key:    provider,
+		Title:  "Webhook signature failures from " + provider,
+		Detail: "last from " + c.IP() + ": " + reason,
+	})
+}
+
+// AlertStatus reports the alert kinds, sinks and digest mode
+func (h *Handlers) AlertStatus(c fiber.Ctx) error {
+	return c.JSON(h.alerts.Status())
+}
+
+// SetAlertDigest holds alerts for digests for a while, e.g. ahead of a
+// drop launch. Body: {"duration": "2h"}; "0s" sends single alerts again.
+func (h *Handlers) SetAlertDigest(c fiber.Ctx) error {
+	var req struct {
+		Duration string `json:"duration"`
+	}
+	if err := json.Unmarshal(c.Body(), &req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request body"})
+	}
+	d, err := time.ParseDuration(req.Duration)
+	if err != nil || d < 0 || d > 24*time.Hour {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid duration", "reason": "duration must be between 0s and 24h"})
+	}
+	h.alerts.SetDigest(d)
+	h.log.InfoContext(c.Context(), "alert digest mode set", "duration", d)
+	return c.JSON(h.alerts.Status())
+}
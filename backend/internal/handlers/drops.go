@@ -1,11 +1,13 @@
 package handlers
 
 import (
-	"ecommerce-backend/internal/integrations"
+	"ecommerce-backend/internal/botcheck"
+	"ecommerce-backend/internal/jobs"
+	"ecommerce-backend/internal/metrics"
+	"ecommerce-backend/internal/ratelimit"
 	"ecommerce-backend/internal/service"
 	"encoding/json"
 	"fmt"
-	"os"
 	"strconv"
 
 	"github.com/gofiber/fiber/v3"
@@ -13,7 +15,7 @@ import (
 
 // GetActiveDrops returns active drops
 func (h *Handlers) GetActiveDrops(c fiber.Ctx) error {
-	drops, err := h.service.GetActiveDrops()
+	drops, err := h.service.GetActiveDrops(c.Context())
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{
 			"error": "Failed to fetch drops",
@@ -33,7 +35,7 @@ func (h *Handlers) GetDropStatus(c fiber.Ctx) error {
 		})
 	}
 
-	status, err := h.service.GetDropStatus(id)
+	status, err := h.service.GetDropStatus(c.Context(), id)
 	if err != nil {
 		return c.Status(404).JSON(fiber.Map{
 			"error": "Drop not found",
@@ -48,7 +50,7 @@ func (h *Handlers) PurchaseDrop(c fiber.Ctx) error {
 	idStr := c.Params("id")
 	dropID, err := strconv.ParseUint(idStr, 10, 64)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "[PURCHASE] Invalid drop ID: %s\n", idStr)
+		h.log.DebugContext(c.Context(), "invalid drop id", "drop_id", idStr)
 		return c.Status(400).JSON(fiber.Map{
 			"error": "Invalid drop ID",
 		})
@@ -63,11 +65,12 @@ func (h *Handlers) PurchaseDrop(c fiber.Ctx) error {
 		Province string `json:"province"`
 		District string `json:"district"`
 		Ward     string `json:"ward"`
+		Locale   string `json:"locale"`
 	}
 
 	body := c.Body()
 	if err := json.Unmarshal(body, &req); err != nil {
-		fmt.Fprintf(os.Stderr, "[PURCHASE] JSON unmarshal error: %v\n", err)
+		h.log.DebugContext(c.Context(), "invalid purchase body", "error", err)
 		return c.Status(400).JSON(fiber.Map{
 			"message": "Invalid request body: " + err.Error(),
 		})
@@ -90,9 +93,14 @@ func (h *Handlers) PurchaseDrop(c fiber.Ctx) error {
 		Province: req.Province,
 		District: req.District,
 		Ward:     req.Ward,
+		Locale:   req.Locale,
+	}
+	// Email in the customer's language: the form's choice, else the browser's
+	if purchaseReq.Locale == "" {
+		purchaseReq.Locale = c.Get("Accept-Language")
 	}
 
-	result, err := h.service.PurchaseDrop(dropID, purchaseReq)
+	result, err := h.service.PurchaseDrop(c.Context(), dropID, purchaseReq)
 	if err != nil {
 		// Do not log "Sold Out" errors as they are expected at high volume
 		return c.Status(400).JSON(fiber.Map{
@@ -103,15 +111,35 @@ func (h *Handlers) PurchaseDrop(c fiber.Ctx) error {
 	return c.JSON(result)
 }
 
+// DropChallenge issues the bot check puzzle to solve before purchasing
+func (h *Handlers) DropChallenge(c fiber.Ctx) error {
+	if h.botCheck == nil {
+		return c.JSON(botcheck.Puzzle{Kind: "none"})
+	}
+	puzzle, err := h.botCheck.Puzzle()
+	if err != nil {
+		h.log.ErrorContext(c.Context(), "bot check puzzle failed", "error", err)
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to issue challenge",
+		})
+	}
+	return c.JSON(puzzle)
+}
+
 // PayOSWebhook handles PayOS webhook for limited drop payments
 func (h *Handlers) PayOSWebhook(c fiber.Ctx) error {
+	payos := h.credentials().PayOS
+
 	// Get webhook signature from headers
 	signature := c.Get("x-payos-signature")
 	// Allow unsigned webhooks in local/dev mode when PAYOS_CLIENT_ID is not configured
 	if signature == "" {
-		if os.Getenv("PAYOS_CLIENT_ID") == "" {
+		if payos.ClientID == "" {
 			// dev mode: proceed without signature verification
 		} else {
+			metrics.WebhookResults.WithLabelValues("missing_signature").Inc()
+			h.log.WarnContext(c.Context(), "webhook rejected: missing signature", "ip", c.IP())
+			h.alertSignature(c, "payos", "missing signature")
 			return c.Status(400).JSON(fiber.Map{
 				"error": "Missing webhook signature",
 			})
@@ -123,8 +151,11 @@ func (h *Handlers) PayOSWebhook(c fiber.Ctx) error {
 
 	// Verify webhook signature when provided
 	if signature != "" {
-		expectedSignature := integrations.GeneratePayOSSignature(string(body))
+		expectedSignature := payos.Signature(string(body))
 		if signature != expectedSignature {
+			metrics.WebhookResults.WithLabelValues("invalid_signature").Inc()
+			h.log.WarnContext(c.Context(), "webhook rejected: invalid signature", "ip", c.IP())
+			h.alertSignature(c, "payos", "invalid signature")
 			return c.Status(401).JSON(fiber.Map{
 				"error": "Invalid webhook signature",
 			})
@@ -147,6 +178,7 @@ func (h *Handlers) PayOSWebhook(c fiber.Ctx) error {
 
 	body = c.Body()
 	if err := json.Unmarshal(body, &webhookData); err != nil {
+		metrics.WebhookResults.WithLabelValues("invalid_payload").Inc()
 		return c.Status(400).JSON(fiber.Map{
 			"error": "Invalid webhook payload",
 		})
@@ -154,21 +186,41 @@ func (h *Handlers) PayOSWebhook(c fiber.Ctx) error {
 
 	// Only process successful payments
 	if webhookData.Data.Status != "PAID" {
+		metrics.WebhookResults.WithLabelValues("not_paid").Inc()
 		return c.JSON(fiber.Map{
 			"message": "Payment not completed",
 		})
 	}
 
-	// Process the successful payment
-	err := h.service.ProcessSuccessfulDropPayment(webhookData.Data.OrderCode)
+	// Hand off to the payment workers; they retry and dead-letter on failure,
+	// so webhook latency does not depend on SQLite write contention
+	if h.payments != nil {
+		if err := jobs.EnqueuePayment(c.Context(), h.payments, webhookData.Data.OrderCode); err != nil {
+			// Not queued: return 500 so PayOS retries the webhook
+			h.log.ErrorContext(c.Context(), "payment enqueue failed", "order_code", webhookData.Data.OrderCode, "error", err)
+			metrics.WebhookResults.WithLabelValues("enqueue_failed").Inc()
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Internal Server Error, please retry",
+			})
+		}
+		metrics.WebhookResults.WithLabelValues("queued").Inc()
+		return c.JSON(fiber.Map{
+			"message": "Payment queued for processing",
+		})
+	}
+
+	// No queue configured: process the successful payment inline
+	err := h.service.ProcessSuccessfulDropPayment(c.Context(), webhookData.Data.OrderCode)
 	if err != nil {
 		// Log error and return 500 to PayOS to trigger retry
-		fmt.Fprintf(os.Stderr, "[WEBHOOK ERROR] Order %d: %v\n", webhookData.Data.OrderCode, err)
+		h.log.ErrorContext(c.Context(), "payment processing failed", "order_code", webhookData.Data.OrderCode, "error", err)
+		metrics.WebhookResults.WithLabelValues("error").Inc()
 		return c.Status(500).JSON(fiber.Map{
 			"error": "Internal Server Error, please retry",
 		})
 	}
 
+	metrics.WebhookResults.WithLabelValues("processed").Inc()
 	return c.JSON(fiber.Map{
 		"message": "Payment processed successfully",
 	})
@@ -177,7 +229,12 @@ func (h *Handlers) PayOSWebhook(c fiber.Ctx) error {
 func registerDropRoutes(app *fiber.App, h *Handlers) {
 	app.Get("/api/drops", h.GetActiveDrops)
 	app.Get("/api/drops/:id/status", h.GetDropStatus)
-	app.Post("/api/drops/:id/purchase", h.PurchaseDrop)
+	app.Get("/api/drops/challenge", h.DropChallenge)
+	app.Post("/api/drops/:id/purchase",
+		ratelimit.Middleware(h.limiter, "purchase"),
+		botcheck.Middleware(h.botCheck, h.log),
+		h.PurchaseDrop,
+	)
 	app.Post("/api/limited-drops/webhook/payos", h.PayOSWebhook)
 }
 
@@ -190,6 +247,7 @@ func validatePurchaseRequest(dropID uint64, req struct {
 	Province string `json:"province"`
 	District string `json:"district"`
 	Ward     string `json:"ward"`
+	Locale   string `json:"locale"`
 }) error {
 	if req.Name == "" {
 		return fmt.Errorf("Drop %d - Họ và tên là bắt buộc", dropID)
@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"ecommerce-backend/internal/integrations"
+	"ecommerce-backend/internal/metrics"
+	"ecommerce-backend/internal/service"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// credentials returns the loaded credentials, or the environment's
+func (h *Handlers) credentials() integrations.Credentials {
+	if h.creds != nil {
+		return h.creds.Get()
+	}
+	return integrations.CredentialsFromEnv()
+}
+
+// BrevoEmailWebhook records Brevo delivery, bounce and complaint events.
+// Brevo authenticates with the BREVO_WEBHOOK_TOKEN as a bearer token, or as
+// ?token= for webhooks created without custom headers.
+func (h *Handlers) BrevoEmailWebhook(c fiber.Ctx) error {
+	token := h.credentials().Brevo.WebhookToken
+	if token == "" {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"error":  "Email webhooks are not configured",
+			"reason": "not_configured",
+		})
+	}
+	got, ok := strings.CutPrefix(c.Get(fiber.HeaderAuthorization), "Bearer ")
+	if !ok {
+		got = c.Query("token")
+	}
+	if !integrations.CheckWebhookToken(token, got) {
+		metrics.EmailEvents.WithLabelValues(integrations.ProviderBrevo, "rejected").Inc()
+		h.log.WarnContext(c.Context(), "email webhook rejected: invalid token", "provider", integrations.ProviderBrevo, "ip", c.IP())
+		h.alertSignature(c, integrations.ProviderBrevo, "invalid token")
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+	}
+
+	events, err := integrations.ParseBrevoEvents(c.Body())
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid webhook payload"})
+	}
+	return h.handleEmailEvents(c, events)
+}
+
+// ResendEmailWebhook records Resend delivery, bounce and complaint events,
+// verified with the Svix signature headers and RESEND_WEBHOOK_SECRET
+func (h *Handlers) ResendEmailWebhook(c fiber.Ctx) error {
+	secret := h.credentials().Resend.WebhookSecret
+	if secret == "" {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"error":  "Email webhooks are not configured",
+			"reason": "not_configured",
+		})
+	}
+	body := c.Body()
+	err := integrations.VerifyResendWebhook(secret, c.Get("svix-id"), c.Get("svix-timestamp"), c.Get("svix-signature"), body, time.Now())
+	if err != nil {
+		metrics.EmailEvents.WithLabelValues(integrations.ProviderResend, "rejected").Inc()
+		h.log.WarnContext(c.Context(), "email webhook rejected", "provider", integrations.ProviderResend, "ip", c.IP(), "error", err)
+		h.alertSignature(c, integrations.ProviderResend, err.Error())
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid webhook signature"})
+	}
+
+	events, err := integrations.ParseResendEvents(body)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid webhook payload"})
+	}
+	return h.handleEmailEvents(c, events)
+}
+
+// handleEmailEvents applies verified events; a failure answers 500 so the
+// provider retries the whole delivery, which is safe to replay
+func (h *Handlers) handleEmailEvents(c fiber.Ctx, events []integrations.EmailEvent) error {
+	for _, ev := range events {
+		if err := h.service.HandleEmailEvent(c.Context(), ev); err != nil {
+			h.log.ErrorContext(c.Context(), "email event failed",
+				"provider", ev.Provider, "message_id", ev.MessageID, "status", ev.Status, "error", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to record event"})
+		}
+	}
+	return c.JSON(fiber.Map{"received": len(events)})
+}
+
+// OrderEmails shows support the emails sent about an order and whether they
+// were delivered, bounced or marked as spam
+func (h *Handlers) OrderEmails(c fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid order ID"})
+	}
+	emails, err := h.service.GetOrderEmails(c.Context(), id)
+	if errors.Is(err, service.ErrOrderNotFound) {
+		return c.Status(404).JSON(fiber.Map{"error": "Order not found"})
+	}
+	if err != nil {
+		h.log.ErrorContext(c.Context(), "order emails failed", "order_id", id, "error", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to load emails"})
+	}
+	return c.JSON(emails)
+}
+
+// EmailSuppressions lists the addresses that bounced or complained
+func (h *Handlers) EmailSuppressions(c fiber.Ctx) error {
+	list, err := h.service.ListEmailSuppressions(c.Context(), queryLimit(c, 100, 1000))
+	if err != nil {
+		h.log.ErrorContext(c.Context(), "email suppression list failed", "error", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to load suppressions"})
+	}
+	return c.JSON(fiber.Map{"suppressions": list})
+}
+
+// RemoveEmailSuppression lets an address receive email again
+func (h *Handlers) RemoveEmailSuppression(c fiber.Ctx) error {
+	email, err := url.PathUnescape(c.Params("email"))
+	if err != nil || !strings.Contains(email, "@") {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid email address"})
+	}
+	err = h.service.RemoveEmailSuppression(c.Context(), email)
+	if errors.Is(err, service.ErrSuppressionNotFound) {
+		return c.Status(404).JSON(fiber.Map{"error": "Email address is not suppressed"})
+	}
+	if err != nil {
+		h.log.ErrorContext(c.Context(), "removing email suppression failed", "error", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to remove suppression"})
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// registerEmailRoutes mounts the provider delivery webhooks
+func registerEmailRoutes(app *fiber.App, h *Handlers) {
+	app.Post("/api/email/webhook/brevo", h.BrevoEmailWebhook)
+	app.Post("/api/email/webhook/resend", h.ResendEmailWebhook)
+}
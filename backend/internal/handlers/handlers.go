@@ -1,7 +1,18 @@
 package handlers
 
 import (
+	"ecommerce-backend/internal/alerts"
+	"ecommerce-backend/internal/botcheck"
+	"ecommerce-backend/internal/health"
+	"ecommerce-backend/internal/integrations"
+	"ecommerce-backend/internal/logging"
+	"ecommerce-backend/internal/queue"
+	"ecommerce-backend/internal/ratelimit"
+	"ecommerce-backend/internal/scheduler"
 	"ecommerce-backend/internal/service"
+	"ecommerce-backend/internal/sheetsync"
+	"ecommerce-backend/internal/webhooks"
+	"log/slog"
 
 	"github.com/gofiber/fiber/v3"
 )
@@ -9,13 +20,119 @@ import (
 // Handlers contains all HTTP handlers
 type Handlers struct {
 	service service.Service
+	// payments receives PAID webhooks for background processing; nil processes them inline
+	payments queue.Queue
+	// creds verifies PayOS and email webhooks; nil reads the environment per request
+	creds *integrations.CredentialStore
+	// ready runs the /readyz checks; nil reports ready with no checks
+	ready *health.Checker
+	// scheduler reports periodic jobs on /api/admin/scheduler/jobs
+	scheduler *scheduler.Scheduler
+	// limiter throttles purchase, verify and order lookups; nil disables it
+	limiter *ratelimit.Limiter
+	// botCheck guards drop purchases with a proof of work or CAPTCHA; nil disables it
+	botCheck botcheck.Checker
+	// alerts counts webhook signature failures and serves /api/admin/alerts; nil disables both
+	alerts *alerts.Alerter
+	// sheets serves the spreadsheet export status and backfill to admins
+	sheets *sheetsync.Syncer
+	// webhooks serves the outbound webhook subscriptions, delivery log and replay to admins
+	webhooks *webhooks.Dispatcher
+	// adminToken guards /api/admin; empty leaves those routes unregistered
+	adminToken string
+	log        *slog.Logger
+}
+
+// Option configures optional handler dependencies
+type Option func(*Handlers)
+
+// WithPaymentQueue makes the PayOS webhook enqueue payments instead of processing them inline
+func WithPaymentQueue(q queue.Queue) Option {
+	return func(h *Handlers) {
+		h.payments = q
+	}
+}
+
+// WithCredentials verifies PayOS and email webhooks with the loaded credentials
+func WithCredentials(store *integrations.CredentialStore) Option {
+	return func(h *Handlers) {
+		h.creds = store
+	}
+}
+
+// WithReadiness serves /readyz from checker
+func WithReadiness(checker *health.Checker) Option {
+	return func(h *Handlers) {
+		h.ready = checker
+	}
+}
+
+// WithScheduler exposes the status of sched's jobs to admins
+func WithScheduler(sched *scheduler.Scheduler) Option {
+	return func(h *Handlers) {
+		h.scheduler = sched
+	}
+}
+
+// WithRateLimiter throttles the public endpoints bots target
+func WithRateLimiter(l *ratelimit.Limiter) Option {
+	return func(h *Handlers) {
+		h.limiter = l
+	}
+}
+
+// WithBotCheck requires a solved challenge on drop purchases
+func WithBotCheck(ch botcheck.Checker) Option {
+	return func(h *Handlers) {
+		h.botCheck = ch
+	}
+}
+
+// WithAlerter alerts admins to spikes of webhook signature failures
+func WithAlerter(a *alerts.Alerter) Option {
+	return func(h *Handlers) {
+		h.alerts = a
+	}
+}
+
+// WithSheetSync lets admins see and backfill the spreadsheet export
+func WithSheetSync(s *sheetsync.Syncer) Option {
+	return func(h *Handlers) {
+		h.sheets = s
+	}
+}
+
+// WithWebhooks lets admins manage webhook subscriptions and replay deliveries
+func WithWebhooks(d *webhooks.Dispatcher) Option {
+	return func(h *Handlers) {
+		h.webhooks = d
+	}
+}
+
+// WithAdminToken enables the /api/admin routes for bearer token
+func WithAdminToken(token string) Option {
+	return func(h *Handlers) {
+		h.adminToken = token
+	}
+}
+
+// WithLogger sets the handler logger (subsystem "http")
+func WithLogger(l *slog.Logger) Option {
+	return func(h *Handlers) {
+		h.log = l
+	}
 }
 
 // NewHandlers creates new handlers instance
-func NewHandlers(svc service.Service) *Handlers {
-	return &Handlers{
+func NewHandlers(svc service.Service, opts ...Option) *Handlers {
+	h := &Handlers{
 		service: svc,
 	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	h.log = logging.Subsystem(h.log, "http")
+	return h
 }
 
 // RegisterRoutes registers all routes by delegating to feature-specific registrars
@@ -25,4 +142,6 @@ func (h *Handlers) RegisterRoutes(app *fiber.App) {
 	registerDropRoutes(app, h)
 	registerOrderRoutes(app, h)
 	registerSymbicodeRoutes(app, h)
+	registerEmailRoutes(app, h)
+	registerAdminRoutes(app, h)
 }
@@ -1,6 +1,12 @@
 package handlers
 
-import "github.com/gofiber/fiber/v3"
+import (
+	"time"
+
+	"ecommerce-backend/internal/health"
+
+	"github.com/gofiber/fiber/v3"
+)
 
 // HealthCheck returns health status
 func (h *Handlers) HealthCheck(c fiber.Ctx) error {
@@ -10,6 +16,32 @@ func (h *Handlers) HealthCheck(c fiber.Ctx) error {
 	})
 }
 
+// Livez reports that the process is serving requests. It checks no
+// dependencies, so a database outage does not get the instance restarted.
+func (h *Handlers) Livez(c fiber.Ctx) error {
+	return c.JSON(fiber.Map{"status": health.StatusOK})
+}
+
+// Readyz runs the readiness checks: 200 when every critical check passes
+// (status ok or degraded), 503 otherwise. Checks have no side effects.
+func (h *Handlers) Readyz(c fiber.Ctx) error {
+	if h.ready == nil {
+		return c.JSON(health.Report{Status: health.StatusOK, Timestamp: time.Now().UTC(), Checks: map[string]health.Result{}})
+	}
+	report := h.ready.Run(c.Context())
+	if !report.Ready() {
+		for name, res := range report.Checks {
+			if res.Status == health.StatusFail {
+				h.log.WarnContext(c.Context(), "readiness check failed", "check", name, "error", res.Error)
+			}
+		}
+		return c.Status(fiber.StatusServiceUnavailable).JSON(report)
+	}
+	return c.JSON(report)
+}
+
 func registerHealthRoutes(app *fiber.App, h *Handlers) {
 	app.Get("/health", h.HealthCheck)
+	app.Get("/livez", h.Livez)
+	app.Get("/readyz", h.Readyz)
 }
@@ -1,12 +1,82 @@
 package handlers
 
 import (
+	"encoding/json"
+	"errors"
 	"strconv"
 
+	"ecommerce-backend/internal/ratelimit"
+	"ecommerce-backend/internal/service"
+
 	"github.com/gofiber/fiber/v3"
 )
 
-// GetOrderByID retrieves a specific order by ID
+// LookupOrder returns the redacted view of an order identified by its order
+// number and the order's email or last 4 phone digits, or by the token of an
+// emailed link
+func (h *Handlers) LookupOrder(c fiber.Ctx) error {
+	var req service.OrderLookup
+	if err := json.Unmarshal(c.Body(), &req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	if req.Token == "" && req.OrderNumber == "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Order number is required",
+		})
+	}
+
+	view, err := h.service.LookupOrder(c.Context(), req)
+	switch {
+	case err == nil:
+		return c.JSON(view)
+	case errors.Is(err, service.ErrLookupFactor):
+		return c.Status(400).JSON(fiber.Map{
+			"error":  "An email or the last 4 digits of the phone number is required",
+			"reason": "factor_required",
+		})
+	case errors.Is(err, service.ErrOrderLookup):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Order not found",
+		})
+	case errors.Is(err, service.ErrInvalidLookupLink):
+		return c.Status(fiber.StatusGone).JSON(fiber.Map{
+			"error":  "Order link is invalid, used or expired",
+			"reason": "invalid_link",
+		})
+	default:
+		h.log.ErrorContext(c.Context(), "order lookup failed", "error", err)
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to look up order",
+		})
+	}
+}
+
+// SendOrderLookupLink emails a one-time order link to the order's address.
+// The response is the same whether or not the order exists.
+func (h *Handlers) SendOrderLookupLink(c fiber.Ctx) error {
+	var req struct {
+		OrderNumber string `json:"order_number"`
+	}
+	if err := json.Unmarshal(c.Body(), &req); err != nil || req.OrderNumber == "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Order number is required",
+		})
+	}
+
+	if err := h.service.SendOrderLookupLink(c.Context(), req.OrderNumber); err != nil {
+		h.log.ErrorContext(c.Context(), "sending order link failed", "error", err)
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to send order link",
+		})
+	}
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"message": "If the order has an email address, a link has been sent to it",
+	})
+}
+
+// GetOrderByID retrieves a specific order by ID (admin)
 func (h *Handlers) GetOrderByID(c fiber.Ctx) error {
 	idStr := c.Params("id")
 	id, err := strconv.ParseUint(idStr, 10, 64)
@@ -16,7 +86,7 @@ func (h *Handlers) GetOrderByID(c fiber.Ctx) error {
 		})
 	}
 
-	order, err := h.service.GetOrderByID(id)
+	order, err := h.service.GetOrderByID(c.Context(), id)
 	if err != nil {
 		return c.Status(404).JSON(fiber.Map{
 			"error": "Order not found",
@@ -26,7 +96,7 @@ func (h *Handlers) GetOrderByID(c fiber.Ctx) error {
 	return c.JSON(order)
 }
 
-// GetOrdersByPhone retrieves all orders for a user (by phone from query param)
+// GetOrdersByPhone retrieves all orders for a user (by phone from query param, admin)
 func (h *Handlers) GetOrdersByPhone(c fiber.Ctx) error {
 	phone := c.Query("phone")
 	if phone == "" {
@@ -35,10 +105,9 @@ func (h *Handlers) GetOrdersByPhone(c fiber.Ctx) error {
 		})
 	}
 
-	orders, err := h.service.GetOrdersByUserPhone(phone)
+	orders, err := h.service.GetOrdersByUserPhone(c.Context(), phone)
 	if err != nil {
-		// Log the error
-		println("Error retrieving orders: " + err.Error())
+		h.log.ErrorContext(c.Context(), "retrieving orders failed", "customer_phone", phone, "error", err)
 		return c.Status(500).JSON(fiber.Map{
 			"error": "Failed to retrieve orders: " + err.Error(),
 		})
@@ -50,7 +119,10 @@ func (h *Handlers) GetOrdersByPhone(c fiber.Ctx) error {
 	})
 }
 
+// registerOrderRoutes mounts the guest lookup; listing by phone and reading by
+// sequential ID are admin routes
 func registerOrderRoutes(app *fiber.App, h *Handlers) {
-	app.Get("/api/orders/:id", h.GetOrderByID)
-	app.Get("/api/orders", h.GetOrdersByPhone)
+	limit := ratelimit.Middleware(h.limiter, "orders")
+	app.Post("/api/orders/lookup", limit, h.LookupOrder)
+	app.Post("/api/orders/lookup/link", limit, h.SendOrderLookupLink)
 }
@@ -8,7 +8,7 @@ import (
 
 // ListProducts returns all products
 func (h *Handlers) ListProducts(c fiber.Ctx) error {
-	products, err := h.service.ListProducts()
+	products, err := h.service.ListProducts(c.Context())
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{
 			"error": "Failed to fetch products",
@@ -28,7 +28,7 @@ func (h *Handlers) GetProduct(c fiber.Ctx) error {
 		})
 	}
 
-	product, err := h.service.GetProduct(id)
+	product, err := h.service.GetProduct(c.Context(), id)
 	if err != nil {
 		return c.Status(404).JSON(fiber.Map{
 			"error": "Product not found",
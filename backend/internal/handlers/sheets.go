@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// SheetStatus reports the spreadsheet export queue
+func (h *Handlers) SheetStatus(c fiber.Ctx) error {
+	st, err := h.sheets.Stats(c.Context())
+	if err != nil {
+		h.log.ErrorContext(c.Context(), "sheet export status failed", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to read sheet export status"})
+	}
+	return c.JSON(st)
+}
+
+// BackfillSheet queues the paid orders created in a time range that were
+// never exported. Body: {"from": RFC 3339, "to": RFC 3339 (default now)}.
+func (h *Handlers) BackfillSheet(c fiber.Ctx) error {
+	var req struct {
+		From time.Time  `json:"from"`
+		To   *time.Time `json:"to"`
+	}
+	if err := json.Unmarshal(c.Body(), &req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request body", "reason": "from and to must be RFC 3339 times"})
+	}
+	to := time.Now()
+	if req.To != nil {
+		to = *req.To
+	}
+	if req.From.IsZero() || !req.From.Before(to) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid range", "reason": "from is required and must be before to"})
+	}
+
+	n, err := h.sheets.Backfill(c.Context(), req.From, to)
+	if err != nil {
+		h.log.ErrorContext(c.Context(), "sheet backfill failed", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to queue orders"})
+	}
+	return c.JSON(fiber.Map{"queued": n})
+}
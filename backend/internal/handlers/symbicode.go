@@ -1,12 +1,21 @@
 package handlers
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"ecommerce-backend/internal/labels"
+	"ecommerce-backend/internal/ratelimit"
+	"ecommerce-backend/internal/service"
 
 	"github.com/gofiber/fiber/v3"
 )
 
-// VerifySymbicode verifies a symbicode
+// VerifySymbicode verifies a symbicode token (or scanned QR payload).
+// Failures are mapped by symbicodeError.
 func (h *Handlers) VerifySymbicode(c fiber.Ctx) error {
 	var req struct {
 		Code string `json:"code"`
@@ -19,19 +28,265 @@ func (h *Handlers) VerifySymbicode(c fiber.Ctx) error {
 		})
 	}
 
-	symbicode, isFirst, err := h.service.VerifySymbicode(req.Code)
+	res, err := h.service.VerifySymbicode(c.Context(), req.Code, service.ScanInfo{
+		IP:        c.IP(),
+		UserAgent: c.Get(fiber.HeaderUserAgent),
+	})
 	if err != nil {
+		return h.symbicodeError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"symbicode":           res.Symbicode,
+		"is_first_activation": res.IsFirstActivation,
+		"scans":               res.Scans,
+		"suspicious":          res.Scans.Suspicious,
+		"owners":              res.Owners,
+	})
+}
+
+// symbicodeError maps a symbicode lookup or transfer failure to a response.
+// Forged tokens (422) are told apart from unknown codes (404) so that the
+// page can warn about counterfeits.
+func (h *Handlers) symbicodeError(c fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, service.ErrForgedSymbicode):
+		h.log.WarnContext(c.Context(), "forged symbicode", "ip", c.IP())
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"error":  "Symbicode signature is invalid",
+			"reason": "forged",
+		})
+	case errors.Is(err, service.ErrUnknownSymbicode):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error":  "Symbicode not found",
+			"reason": "unknown",
+		})
+	case errors.Is(err, service.ErrUnsignedSymbicode):
+		return c.Status(400).JSON(fiber.Map{
+			"error":  "Scan the QR code on the label",
+			"reason": "unsigned",
+		})
+	case errors.Is(err, service.ErrTransferContact):
+		return c.Status(400).JSON(fiber.Map{
+			"error":  "A phone number or email is required",
+			"reason": "contact_required",
+		})
+	case errors.Is(err, service.ErrTransferChannel):
+		return c.Status(400).JSON(fiber.Map{
+			"error":  "Give either a phone number or an email, not both",
+			"reason": "one_contact",
+		})
+	case errors.Is(err, service.ErrTransferDenied):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error":  "This symbicode cannot be transferred with these details",
+			"reason": "transfer_denied",
+		})
+	case errors.Is(err, service.ErrInvalidTransferCode):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error":  "Transfer code is wrong or expired",
+			"reason": "invalid_code",
+		})
+	case errors.Is(err, service.ErrInvalidTransfer):
+		return c.Status(fiber.StatusGone).JSON(fiber.Map{
+			"error":  "Transfer link is invalid, used or expired",
+			"reason": "invalid_transfer",
+		})
+	case errors.Is(err, service.ErrMalformedSymbicode):
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid symbicode",
+		})
+	default:
+		h.log.ErrorContext(c.Context(), "symbicode request failed", "error", err)
 		return c.Status(400).JSON(fiber.Map{
 			"error": "Invalid symbicode",
 		})
 	}
+}
 
-	return c.JSON(fiber.Map{
-		"symbicode":           symbicode,
-		"is_first_activation": isFirst,
-	})
+// TransferSymbicode starts a resale: it sends a one-time code to the current
+// owner's phone or email, whichever the request gives
+func (h *Handlers) TransferSymbicode(c fiber.Ctx) error {
+	var req struct {
+		Code  string `json:"code"`
+		Phone string `json:"phone"`
+		Email string `json:"email"`
+	}
+	if err := json.Unmarshal(c.Body(), &req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	challenge, err := h.service.InitiateSymbicodeTransfer(c.Context(), req.Code, service.Contact{Phone: req.Phone, Email: req.Email})
+	if err != nil {
+		return h.symbicodeError(c, err)
+	}
+	return c.Status(fiber.StatusAccepted).JSON(challenge)
+}
+
+// ConfirmSymbicodeTransfer offers a symbicode for resale once the owner
+// enters the code sent to the same contact. The response token is shown
+// once; the buyer claims with it.
+func (h *Handlers) ConfirmSymbicodeTransfer(c fiber.Ctx) error {
+	var req struct {
+		Code  string `json:"code"`
+		Phone string `json:"phone"`
+		Email string `json:"email"`
+		OTP   string `json:"otp"`
+	}
+	if err := json.Unmarshal(c.Body(), &req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	offer, err := h.service.ConfirmSymbicodeTransfer(c.Context(), req.Code, service.Contact{Phone: req.Phone, Email: req.Email}, req.OTP)
+	if err != nil {
+		return h.symbicodeError(c, err)
+	}
+	return c.Status(fiber.StatusCreated).JSON(offer)
+}
+
+// ClaimSymbicode makes the caller the owner of a symbicode offered for resale
+func (h *Handlers) ClaimSymbicode(c fiber.Ctx) error {
+	var req struct {
+		Token string `json:"token"`
+		Phone string `json:"phone"`
+		Email string `json:"email"`
+	}
+	if err := json.Unmarshal(c.Body(), &req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	res, err := h.service.ClaimSymbicodeTransfer(c.Context(), req.Token, service.Contact{Phone: req.Phone, Email: req.Email})
+	if err != nil {
+		return h.symbicodeError(c, err)
+	}
+	return c.JSON(res)
+}
+
+// GenerateSymbicodeBatch pre-generates codes for a product to print on tags
+func (h *Handlers) GenerateSymbicodeBatch(c fiber.Ctx) error {
+	var req struct {
+		ProductID uint64 `json:"product_id"`
+		Count     int    `json:"count"`
+		Note      string `json:"note"`
+	}
+	if err := json.Unmarshal(c.Body(), &req); err != nil || req.ProductID == 0 {
+		return c.Status(400).JSON(fiber.Map{"error": "product_id and count are required"})
+	}
+
+	batch, err := h.service.GenerateSymbicodeBatch(c.Context(), req.ProductID, req.Count, req.Note)
+	if errors.Is(err, service.ErrBatchSize) {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err != nil {
+		h.log.ErrorContext(c.Context(), "symbicode batch failed", "product_id", req.ProductID, "count", req.Count, "error", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to generate symbicodes"})
+	}
+	return c.Status(fiber.StatusCreated).JSON(batch)
+}
+
+// SymbicodeBatchLabels exports a batch as ?format=csv (default), svg or pdf
+func (h *Handlers) SymbicodeBatchLabels(c fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid batch ID"})
+	}
+	format := c.Query("format", "csv")
+	contentType, ok := labels.ContentTypes[format]
+	if !ok {
+		return c.Status(400).JSON(fiber.Map{"error": "format must be csv, svg or pdf"})
+	}
+
+	_, tags, err := h.service.SymbicodeLabels(c.Context(), id)
+	if errors.Is(err, service.ErrBatchNotFound) {
+		return c.Status(404).JSON(fiber.Map{"error": "Batch not found"})
+	}
+	if err != nil {
+		h.log.ErrorContext(c.Context(), "symbicode labels failed", "batch_id", id, "error", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to export labels"})
+	}
+
+	var buf bytes.Buffer
+	if err := labels.Write(&buf, format, tags); err != nil {
+		h.log.ErrorContext(c.Context(), "symbicode labels failed", "batch_id", id, "format", format, "error", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to export labels"})
+	}
+	c.Set(fiber.HeaderContentType, contentType)
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="symbicodes-batch-%d.%s"`, id, format))
+	return c.Send(buf.Bytes())
+}
+
+// BindSymbicode ties a pre-generated code (scanned from its tag) to a paid order
+func (h *Handlers) BindSymbicode(c fiber.Ctx) error {
+	var req struct {
+		Code    string `json:"code"`
+		OrderID uint64 `json:"order_id"`
+	}
+	if err := json.Unmarshal(c.Body(), &req); err != nil || req.OrderID == 0 {
+		return c.Status(400).JSON(fiber.Map{"error": "code and order_id are required"})
+	}
+
+	sym, err := h.service.BindSymbicode(c.Context(), req.Code, req.OrderID)
+	switch {
+	case errors.Is(err, service.ErrSymbicodeBound):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Symbicode is already bound to an order"})
+	case errors.Is(err, service.ErrOrderNotFound):
+		return c.Status(404).JSON(fiber.Map{"error": "Order not found"})
+	case errors.Is(err, service.ErrOrderNotPaid):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Order is not paid"})
+	case errors.Is(err, service.ErrOrderFullyBound):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Order already has a symbicode for every unit"})
+	case errors.Is(err, service.ErrProductMismatch):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Symbicode is for a different product"})
+	case err != nil:
+		return h.symbicodeError(c, err)
+	}
+	return c.JSON(fiber.Map{"symbicode": sym})
+}
+
+// SuspiciousSymbicodes reports codes scanned from too many IPs or locations
+func (h *Handlers) SuspiciousSymbicodes(c fiber.Ctx) error {
+	codes, err := h.service.ListSuspiciousSymbicodes(c.Context(), queryLimit(c, 50, 500))
+	if err != nil {
+		h.log.ErrorContext(c.Context(), "suspicious symbicode report failed", "error", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to build report"})
+	}
+	return c.JSON(fiber.Map{"symbicodes": codes})
+}
+
+// SymbicodeScans lists the verification history of one symbicode
+func (h *Handlers) SymbicodeScans(c fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid symbicode ID"})
+	}
+	scans, err := h.service.ListSymbicodeScans(c.Context(), id, queryLimit(c, 100, 1000))
+	if err != nil {
+		h.log.ErrorContext(c.Context(), "symbicode scan history failed", "symbicode_id", id, "error", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to load scans"})
+	}
+	return c.JSON(fiber.Map{"scans": scans})
+}
+
+// queryLimit reads ?limit=, defaulting to def and capped at max
+func queryLimit(c fiber.Ctx, def, max int) int {
+	n, err := strconv.Atoi(c.Query("limit"))
+	if err != nil || n <= 0 {
+		return def
+	}
+	if n > max {
+		return max
+	}
+	return n
 }
 
 func registerSymbicodeRoutes(app *fiber.App, h *Handlers) {
-	app.Post("/api/symbicode/verify", h.VerifySymbicode)
+	app.Post("/api/symbicode/verify", ratelimit.Middleware(h.limiter, "verify"), h.VerifySymbicode)
+	app.Post("/api/symbicode/transfers", ratelimit.Middleware(h.limiter, "transfers"), h.TransferSymbicode)
+	app.Post("/api/symbicode/transfers/confirm", ratelimit.Middleware(h.limiter, "transfers"), h.ConfirmSymbicodeTransfer)
+	app.Post("/api/symbicode/transfers/claim", ratelimit.Middleware(h.limiter, "transfers"), h.ClaimSymbicode)
 }
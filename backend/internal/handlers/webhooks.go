@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+
+	"ecommerce-backend/internal/webhooks"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// WebhookSubscriptions lists the outbound webhook subscriptions
+func (h *Handlers) WebhookSubscriptions(c fiber.Ctx) error {
+	subs, err := h.webhooks.Subscriptions(c.Context())
+	if err != nil {
+		h.log.ErrorContext(c.Context(), "webhook subscriptions failed", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to read webhook subscriptions"})
+	}
+	return c.JSON(fiber.Map{"subscriptions": subs})
+}
+
+// CreateWebhookSubscription registers a URL for events. Body: {"url",
+// "events": ["order.paid", ...] or ["*"], "description"}. The response
+// carries the signing secret, which is not shown again.
+func (h *Handlers) CreateWebhookSubscription(c fiber.Ctx) error {
+	var req struct {
+		URL         string   `json:"url"`
+		Events      []string `json:"events"`
+		Description string   `json:"description"`
+	}
+	if err := json.Unmarshal(c.Body(), &req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request body"})
+	}
+	sub, err := h.webhooks.Subscribe(c.Context(), req.URL, req.Events, req.Description)
+	if errors.Is(err, webhooks.ErrInvalidSubscription) {
+		reason := strings.TrimPrefix(err.Error(), webhooks.ErrInvalidSubscription.Error()+": ")
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid subscription", "reason": reason})
+	}
+	if err != nil {
+		h.log.ErrorContext(c.Context(), "webhook subscribe failed", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to create webhook subscription"})
+	}
+	return c.Status(fiber.StatusCreated).JSON(sub)
+}
+
+// DeleteWebhookSubscription removes a subscription; its pending deliveries fail
+func (h *Handlers) DeleteWebhookSubscription(c fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid subscription ID"})
+	}
+	ok, err := h.webhooks.Unsubscribe(c.Context(), id)
+	if err != nil {
+		h.log.ErrorContext(c.Context(), "webhook unsubscribe failed", "subscription_id", id, "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to delete webhook subscription"})
+	}
+	if !ok {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "webhook subscription not found"})
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// WebhookDeliveries lists a subscription's deliveries, newest first (?limit=, default 100)
+func (h *Handlers) WebhookDeliveries(c fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid subscription ID"})
+	}
+	deliveries, err := h.webhooks.Deliveries(c.Context(), id, queryLimit(c, 100, 1000))
+	if err != nil {
+		h.log.ErrorContext(c.Context(), "webhook deliveries failed", "subscription_id", id, "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to read webhook deliveries"})
+	}
+	return c.JSON(fiber.Map{"deliveries": deliveries})
+}
+
+// WebhookDelivery returns a delivery with its payload and attempt log
+func (h *Handlers) WebhookDelivery(c fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid delivery ID"})
+	}
+	d, err := h.webhooks.Delivery(c.Context(), id)
+	if err != nil {
+		h.log.ErrorContext(c.Context(), "webhook delivery failed", "delivery_id", id, "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to read webhook delivery"})
+	}
+	if d == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "webhook delivery not found"})
+	}
+	return c.JSON(d)
+}
+
+// ReplayWebhookDelivery sends a delivery again, delivered or not
+func (h *Handlers) ReplayWebhookDelivery(c fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid delivery ID"})
+	}
+	ok, err := h.webhooks.Replay(c.Context(), id)
+	if err != nil {
+		h.log.ErrorContext(c.Context(), "webhook replay failed", "delivery_id", id, "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to replay webhook delivery"})
+	}
+	if !ok {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "webhook delivery not found", "reason": "no such delivery, or its subscription was deleted"})
+	}
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{"replayed": id})
+}
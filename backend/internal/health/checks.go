@@ -0,0 +1,100 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"ecommerce-backend/internal/database"
+)
+
+const mb = 1 << 20
+
+// Pool checks that db can run a query within the check timeout. The writer
+// pool has a single connection, so a failure there also means writes are
+// queued behind a long transaction.
+func Pool(name string, db *sql.DB) Check {
+	return Check{Name: name, Critical: true, Run: func(ctx context.Context) (string, error) {
+		var one int
+		if err := db.QueryRowContext(ctx, "SELECT 1").Scan(&one); err != nil {
+			return "", err
+		}
+		s := db.Stats()
+		return fmt.Sprintf("%d/%d connections in use, %d waits since start", s.InUse, s.MaxOpenConnections, s.WaitCount), nil
+	}}
+}
+
+// WALSize warns when dbPath's write-ahead log exceeds maxBytes, which means
+// checkpoints are not keeping up (usually a long-lived reader)
+func WALSize(dbPath string, maxBytes int64) Check {
+	return Check{Name: "wal_size", Run: func(ctx context.Context) (string, error) {
+		info, err := os.Stat(dbPath + "-wal")
+		if errors.Is(err, fs.ErrNotExist) {
+			return "no WAL file", nil
+		}
+		if err != nil {
+			return "", err
+		}
+		detail := fmt.Sprintf("%d MB", info.Size()/mb)
+		if info.Size() > maxBytes {
+			return detail, fmt.Errorf("WAL is %d MB, above %d MB", info.Size()/mb, maxBytes/mb)
+		}
+		return detail, nil
+	}}
+}
+
+// DiskSpace fails when the filesystem holding dbPath has less than minBytes
+// free; SQLite writes (and WAL growth) would start failing
+func DiskSpace(dbPath string, minBytes uint64) Check {
+	return Check{Name: "disk_space", Critical: true, Run: func(ctx context.Context) (string, error) {
+		free, err := freeBytes(filepath.Dir(dbPath))
+		if err != nil {
+			return "", err
+		}
+		detail := fmt.Sprintf("%d MB free", free/mb)
+		if free < minBytes {
+			return detail, fmt.Errorf("%d MB free, below %d MB", free/mb, minBytes/mb)
+		}
+		return detail, nil
+	}}
+}
+
+// SchemaVersion fails until the database has been migrated to want
+func SchemaVersion(db *sql.DB, want int) Check {
+	return Check{Name: "schema_version", Critical: true, Run: func(ctx context.Context) (string, error) {
+		got, err := database.GetSchemaVersion(ctx, db)
+		if err != nil {
+			return "", err
+		}
+		detail := fmt.Sprintf("version %d", got)
+		if got < want {
+			return detail, fmt.Errorf("schema version %d, want %d", got, want)
+		}
+		return detail, nil
+	}}
+}
+
+// Reachable checks that url answers HTTP at all; any status code counts,
+// since probes are unauthenticated. Wrap it in Cached.
+func Reachable(name, url string, client *http.Client) Check {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return Check{Name: name, Run: func(ctx context.Context) (string, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+		if err != nil {
+			return "", err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return "", err
+		}
+		resp.Body.Close()
+		return fmt.Sprintf("HTTP %d", resp.StatusCode), nil
+	}}
+}
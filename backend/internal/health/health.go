@@ -0,0 +1,140 @@
+// Package health runs the readiness checks behind /readyz. Checks only read:
+// they ping pools, stat files and probe endpoints, and never change data.
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Check statuses
+const (
+	StatusOK   = "ok"
+	StatusWarn = "warn" // a non-critical check failed; still ready
+	StatusFail = "fail"
+)
+
+// Check is one readiness probe
+type Check struct {
+	Name string
+	// Critical failures make the instance not ready; others report "warn"
+	Critical bool
+	// Run returns a short human-readable detail (sizes, versions) or an error
+	Run func(ctx context.Context) (detail string, err error)
+}
+
+// Result is the outcome of one check
+type Result struct {
+	Status     string  `json:"status"`
+	Detail     string  `json:"detail,omitempty"`
+	Error      string  `json:"error,omitempty"`
+	DurationMS float64 `json:"duration_ms"`
+}
+
+// Report is the /readyz response body
+type Report struct {
+	// Status is ok, degraded (only non-critical failures) or fail
+	Status    string            `json:"status"`
+	Timestamp time.Time         `json:"timestamp"`
+	Checks    map[string]Result `json:"checks"`
+}
+
+// Ready reports whether every critical check passed
+func (r Report) Ready() bool {
+	return r.Status != StatusFail
+}
+
+// Checker runs a fixed set of checks concurrently
+type Checker struct {
+	timeout time.Duration
+	checks  []Check
+}
+
+// NewChecker returns a checker giving each check at most timeout (0 means 2s)
+func NewChecker(timeout time.Duration, checks ...Check) *Checker {
+	if timeout <= 0 {
+		timeout = 2 * time.Second
+	}
+	return &Checker{timeout: timeout, checks: checks}
+}
+
+// Add registers more checks; call it before serving traffic
+func (c *Checker) Add(checks ...Check) {
+	c.checks = append(c.checks, checks...)
+}
+
+// Run executes every check and aggregates the results
+func (c *Checker) Run(ctx context.Context) Report {
+	report := Report{
+		Status:    StatusOK,
+		Timestamp: time.Now().UTC(),
+		Checks:    make(map[string]Result, len(c.checks)),
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, check := range c.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res := c.run(ctx, check)
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[check.Name] = res
+			switch {
+			case res.Status == StatusFail:
+				report.Status = StatusFail
+			case res.Status == StatusWarn && report.Status == StatusOK:
+				report.Status = "degraded"
+			}
+		}()
+	}
+	wg.Wait()
+	return report
+}
+
+func (c *Checker) run(ctx context.Context, check Check) Result {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	detail, err := check.Run(ctx)
+	res := Result{
+		Status:     StatusOK,
+		Detail:     detail,
+		DurationMS: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		res.Error = err.Error()
+		res.Status = StatusWarn
+		if check.Critical {
+			res.Status = StatusFail
+		}
+	}
+	return res
+}
+
+// Cached wraps check so it runs at most once per ttl; between runs the last
+// detail and error are returned. Use it for probes of third-party services.
+func Cached(check Check, ttl time.Duration) Check {
+	var (
+		mu      sync.Mutex
+		expires time.Time
+		detail  string
+		err     error
+	)
+	run := check.Run
+	check.Run = func(ctx context.Context) (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if time.Now().Before(expires) {
+			return detail, err
+		}
+		detail, err = run(ctx)
+		expires = time.Now().Add(ttl)
+		return detail, err
+	}
+	return check
+}
@@ -10,10 +10,12 @@ package integrations
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"os"
+
+	"ecommerce-backend/internal/emails"
 )
 
 type BrevoEmailRequest struct {
@@ -21,6 +23,7 @@ type BrevoEmailRequest struct {
 	To          []BrevoRecipient `json:"to"`
 	Subject     string           `json:"subject"`
 	HtmlContent string           `json:"htmlContent"`
+	TextContent string           `json:"textContent,omitempty"`
 }
 
 type BrevoSender struct {
@@ -33,15 +36,46 @@ type BrevoRecipient struct {
 }
 
 type BrevoEmailResponse struct {
-	MessageID int64 `json:"messageId"`
+	MessageID string `json:"messageId"`
+}
+
+// UnmarshalJSON accepts messageId as Brevo's "<...@smtp-relay.mailin.fr>"
+// string or as a number
+func (r *BrevoEmailResponse) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		MessageID json.RawMessage `json:"messageId"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	r.MessageID = ""
+	if len(raw.MessageID) == 0 || string(raw.MessageID) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(raw.MessageID, &r.MessageID); err != nil {
+		var n json.Number
+		if err := json.Unmarshal(raw.MessageID, &n); err != nil {
+			return fmt.Errorf("invalid messageId %s", raw.MessageID)
+		}
+		r.MessageID = n.String()
+	}
+	return nil
 }
 
 // SendEmailBrevo: Send email via Brevo
 func SendEmailBrevo(to []string, subject, htmlContent string) error {
-	apiKey := os.Getenv("BREVO_API_KEY")
+	msg := emails.Message{Subject: subject, HTML: htmlContent}
+	_, err := sendEmailBrevo(context.Background(), defaultHTTPClient, CredentialsFromEnv().Brevo, to, msg)
+	return err
+}
+
+// sendEmailBrevo sends msg and returns Brevo's message ID ("" if the
+// response had none). Running out of credits is ErrEmailQuota.
+func sendEmailBrevo(ctx context.Context, client *http.Client, cfg BrevoConfig, to []string, msg emails.Message) (string, error) {
+	apiKey := cfg.APIKey
 
 	if apiKey == "" {
-		return fmt.Errorf("brevo api key not configured")
+		return "", fmt.Errorf("brevo api key not configured")
 	}
 
 	// Build recipients
@@ -56,47 +90,45 @@ func SendEmailBrevo(to []string, subject, htmlContent string) error {
 			Email: "noreply@donaldwatch.xyz",
 		},
 		To:          recipients,
-		Subject:     subject,
-		HtmlContent: htmlContent,
+		Subject:     msg.Subject,
+		HtmlContent: msg.HTML,
+		TextContent: msg.Text,
 	}
 
 	body, err := json.Marshal(req)
 	if err != nil {
-		return fmt.Errorf("failed to marshal request: %w", err)
+		return "", fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	baseURL := os.Getenv("BREVO_BASE_URL")
+	baseURL := cfg.BaseURL
 	if baseURL == "" {
 		baseURL = "https://api.brevo.com/v3"
 	}
 
-	httpReq, err := http.NewRequest("POST", baseURL+"/smtp/email", bytes.NewBuffer(body))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", baseURL+"/smtp/email", bytes.NewBuffer(body))
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return "", fmt.Errorf("failed to create request: %w", err)
 	}
 
 	httpReq.Header.Set("api-key", apiKey)
 	httpReq.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{}
 	resp, err := client.Do(httpReq)
 	if err != nil {
-		return fmt.Errorf("failed to send email: %w", err)
+		return "", fmt.Errorf("failed to send email: %w", err)
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusPaymentRequired {
+		return "", fmt.Errorf("%w: brevo returned status %d", ErrEmailQuota, resp.StatusCode)
+	}
 	if resp.StatusCode != http.StatusCreated {
-		return fmt.Errorf("brevo returned status %d", resp.StatusCode)
+		return "", fmt.Errorf("brevo returned status %d", resp.StatusCode)
 	}
 
+	// The message is accepted: an unreadable ID must not fail the send, or
+	// failover would deliver it twice
 	var respBody BrevoEmailResponse
-	if err := json.NewDecoder(resp.Body).Decode(&respBody); err != nil {
-		return fmt.Errorf("failed to decode response: %w", err)
-	}
-
-	if respBody.MessageID == 0 {
-		return fmt.Errorf("invalid message id from brevo")
-	}
-
-	return nil
+	json.NewDecoder(resp.Body).Decode(&respBody)
+	return respBody.MessageID, nil
 }
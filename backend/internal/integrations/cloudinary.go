@@ -13,7 +13,6 @@ import (
 	"io"
 	"net/http"
 	"net/url"
-	"os"
 	"sort"
 	"strconv"
 	"strings"
@@ -35,11 +34,8 @@ type CloudinaryUploadResult struct {
 }
 
 // UploadToCloudinary: Upload image to Cloudinary
-func UploadToCloudinary(file io.Reader, filename string) (*CloudinaryUploadResult, error) {
-	cloudName := os.Getenv("CLOUDINARY_CLOUD_NAME")
-	apiKey := os.Getenv("CLOUDINARY_API_KEY")
-	apiSecret := os.Getenv("CLOUDINARY_API_SECRET")
-	uploadPreset := os.Getenv("CLOUDINARY_UPLOAD_PRESET")
+func UploadToCloudinary(cfg CloudinaryConfig, file io.Reader, filename string) (*CloudinaryUploadResult, error) {
+	cloudName, apiKey, apiSecret, uploadPreset := cfg.CloudName, cfg.APIKey, cfg.APISecret, cfg.UploadPreset
 
 	if cloudName == "" || apiKey == "" || apiSecret == "" {
 		return nil, fmt.Errorf("Cloudinary not configured")
@@ -122,9 +118,8 @@ func generateSignature(params map[string]string, apiSecret string) string {
 }
 
 // UploadBase64ToCloudinary: Upload base64 image
-func UploadBase64ToCloudinary(base64Data string) (*CloudinaryUploadResult, error) {
-	cloudName := os.Getenv("CLOUDINARY_CLOUD_NAME")
-	uploadPreset := os.Getenv("CLOUDINARY_UPLOAD_PRESET")
+func UploadBase64ToCloudinary(cfg CloudinaryConfig, base64Data string) (*CloudinaryUploadResult, error) {
+	cloudName, uploadPreset := cfg.CloudName, cfg.UploadPreset
 
 	if cloudName == "" {
 		return nil, fmt.Errorf("Cloudinary not configured")
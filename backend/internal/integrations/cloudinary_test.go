@@ -47,18 +47,16 @@ func TestUploadToCloudinary(t *testing.T) {
 	}))
 	defer server.Close()
 
-	// Override Base URL and Env
+	// Override Base URL
 	originalBaseURL := CloudinaryBaseURL
 	CloudinaryBaseURL = server.URL
 	defer func() { CloudinaryBaseURL = originalBaseURL }()
 
-	t.Setenv("CLOUDINARY_CLOUD_NAME", "test-cloud")
-	t.Setenv("CLOUDINARY_API_KEY", "test-key")
-	t.Setenv("CLOUDINARY_API_SECRET", "test-secret")
+	cfg := CloudinaryConfig{CloudName: "test-cloud", APIKey: "test-key", APISecret: "test-secret"}
 	
 	// Test Upload
 	reader := strings.NewReader("test-content")
-	result, err := UploadToCloudinary(reader, "test.jpg")
+	result, err := UploadToCloudinary(cfg, reader, "test.jpg")
 	
 	assert.NoError(t, err)
 	assert.NotNil(t, result)
@@ -83,10 +81,10 @@ func TestUploadBase64ToCloudinary(t *testing.T) {
 	CloudinaryBaseURL = server.URL
 	defer func() { CloudinaryBaseURL = originalBaseURL }()
 
-	t.Setenv("CLOUDINARY_CLOUD_NAME", "test-cloud")
+	cfg := CloudinaryConfig{CloudName: "test-cloud"}
 	
 	// Test
-	result, err := UploadBase64ToCloudinary("data:image/png;base64,xxxx")
+	result, err := UploadBase64ToCloudinary(cfg, "data:image/png;base64,xxxx")
 	assert.NoError(t, err)
 	assert.Equal(t, "b64_id", result.PublicID)
 }
@@ -103,12 +101,10 @@ func TestUploadToCloudinary_Error(t *testing.T) {
 	CloudinaryBaseURL = server.URL
 	defer func() { CloudinaryBaseURL = originalBaseURL }()
 
-	t.Setenv("CLOUDINARY_CLOUD_NAME", "test-cloud")
-	t.Setenv("CLOUDINARY_API_KEY", "test-key")
-	t.Setenv("CLOUDINARY_API_SECRET", "test-secret")
+	cfg := CloudinaryConfig{CloudName: "test-cloud", APIKey: "test-key", APISecret: "test-secret"}
 
 	reader := strings.NewReader("test")
-	_, err := UploadToCloudinary(reader, "test.jpg")
+	_, err := UploadToCloudinary(cfg, reader, "test.jpg")
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "upload failed")
 }
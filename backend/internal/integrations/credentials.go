@@ -0,0 +1,231 @@
+package integrations
+
+import (
+	"os"
+	"sync/atomic"
+)
+
+// =============================================================================
+// CREDENTIALS
+// =============================================================================
+
+// PayOSConfig holds PayOS credentials and endpoint overrides
+type PayOSConfig struct {
+	ClientID    string
+	APIKey      string
+	ChecksumKey string
+	CheckoutURL string
+	BaseURL     string
+	RefundURL   string
+	ReturnURL   string
+	CancelURL   string
+}
+
+// Configured reports whether real PayOS calls can be made
+func (c PayOSConfig) Configured() bool {
+	return c.ClientID != "" && c.APIKey != ""
+}
+
+// BrevoConfig holds Brevo credentials
+type BrevoConfig struct {
+	APIKey       string
+	BaseURL      string
+	WebhookToken string
+}
+
+// ResendConfig holds Resend credentials
+type ResendConfig struct {
+	APIKey        string
+	FromEmail     string
+	BaseURL       string
+	WebhookSecret string
+}
+
+// SMSConfig holds the SMS gateway credentials (eSMS brand-name API)
+type SMSConfig struct {
+	APIKey    string
+	SecretKey string
+	BrandName string
+	BaseURL   string
+}
+
+// ZaloConfig holds the Zalo Official Account token for ZNS messages
+type ZaloConfig struct {
+	AccessToken string
+	BaseURL     string
+}
+
+// CloudinaryConfig holds the image upload credentials
+type CloudinaryConfig struct {
+	CloudName    string
+	APIKey       string
+	APISecret    string
+	UploadPreset string
+}
+
+// GoogleConfig holds the OAuth client that Google ID tokens are issued to
+type GoogleConfig struct {
+	ClientID string
+}
+
+// SheetsConfig holds the Google Sheets target and service account.
+// ServiceAccountJSON takes precedence over reading ServiceAccountPath.
+type SheetsConfig struct {
+	SpreadsheetID      string
+	SheetName          string
+	ServiceAccountJSON string
+	ServiceAccountPath string
+}
+
+// Credentials holds everything the integrations read from secrets
+type Credentials struct {
+	PayOS      PayOSConfig
+	Brevo      BrevoConfig
+	Resend     ResendConfig
+	Sheets     SheetsConfig
+	SMS        SMSConfig
+	Zalo       ZaloConfig
+	Cloudinary CloudinaryConfig
+	Google     GoogleConfig
+}
+
+// LoadCredentials reads every credential through lookup, which returns "" for
+// missing keys. The first lookup error is returned.
+func LoadCredentials(lookup func(key string) (string, error)) (Credentials, error) {
+	var firstErr error
+	get := func(key string) string {
+		v, err := lookup(key)
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+		return v
+	}
+
+	c := Credentials{
+		PayOS: PayOSConfig{
+			ClientID:    get("PAYOS_CLIENT_ID"),
+			APIKey:      get("PAYOS_API_KEY"),
+			ChecksumKey: get("PAYOS_CHECKSUM_KEY"),
+			CheckoutURL: get("PAYOS_CHECKOUT_URL"),
+			BaseURL:     get("PAYOS_BASE_URL"),
+			RefundURL:   get("PAYOS_REFUND_URL"),
+			ReturnURL:   get("PAYOS_RETURN_URL"),
+			CancelURL:   get("PAYOS_CANCEL_URL"),
+		},
+		Brevo: BrevoConfig{
+			APIKey:       get("BREVO_API_KEY"),
+			BaseURL:      get("BREVO_BASE_URL"),
+			WebhookToken: get("BREVO_WEBHOOK_TOKEN"),
+		},
+		Resend: ResendConfig{
+			APIKey:        get("RESEND_API_KEY"),
+			FromEmail:     get("RESEND_FROM_EMAIL"),
+			BaseURL:       get("RESEND_BASE_URL"),
+			WebhookSecret: get("RESEND_WEBHOOK_SECRET"),
+		},
+		Sheets: SheetsConfig{
+			SpreadsheetID:      get("GSSHEET_SPREADSHEET_ID"),
+			SheetName:          get("GSSHEET_SHEET_NAME"),
+			ServiceAccountJSON: get("GDRIVE_SERVICE_ACCOUNT_JSON"),
+			ServiceAccountPath: get("GDRIVE_SERVICE_ACCOUNT"),
+		},
+		SMS: SMSConfig{
+			APIKey:    get("SMS_API_KEY"),
+			SecretKey: get("SMS_SECRET_KEY"),
+			BrandName: get("SMS_BRAND_NAME"),
+			BaseURL:   get("SMS_BASE_URL"),
+		},
+		Zalo: ZaloConfig{
+			AccessToken: get("ZALO_ACCESS_TOKEN"),
+			BaseURL:     get("ZALO_BASE_URL"),
+		},
+		Cloudinary: CloudinaryConfig{
+			CloudName:    get("CLOUDINARY_CLOUD_NAME"),
+			APIKey:       get("CLOUDINARY_API_KEY"),
+			APISecret:    get("CLOUDINARY_API_SECRET"),
+			UploadPreset: get("CLOUDINARY_UPLOAD_PRESET"),
+		},
+		Google: GoogleConfig{
+			ClientID: get("GOOGLE_CLIENT_ID"),
+		},
+	}
+	return c, firstErr
+}
+
+// CredentialsFromEnv reads credentials from the process environment
+func CredentialsFromEnv() Credentials {
+	c, _ := LoadCredentials(func(key string) (string, error) {
+		return os.Getenv(key), nil
+	})
+	return c
+}
+
+// CredentialStore holds the current credentials; Set swaps them atomically
+// so a secrets reload takes effect on the next call without a restart.
+type CredentialStore struct {
+	v atomic.Pointer[Credentials]
+}
+
+// NewCredentialStore creates a store holding c
+func NewCredentialStore(c Credentials) *CredentialStore {
+	s := &CredentialStore{}
+	s.Set(c)
+	return s
+}
+
+// Get returns the current credentials
+func (s *CredentialStore) Get() Credentials {
+	return *s.v.Load()
+}
+
+// Set replaces the credentials
+func (s *CredentialStore) Set(c Credentials) {
+	s.v.Store(&c)
+}
+
+// credentials returns the store's credentials, or the environment when s is
+// nil (gateways built with the no-argument constructors)
+func (s *CredentialStore) credentials() Credentials {
+	if s == nil {
+		return CredentialsFromEnv()
+	}
+	return s.Get()
+}
+
+// Endpoint is a third-party API the integrations call
+type Endpoint struct {
+	Name string
+	URL  string
+}
+
+// Endpoints lists the APIs enabled by c (those with credentials), for
+// reachability probes
+func (c Credentials) Endpoints() []Endpoint {
+	var out []Endpoint
+	if c.PayOS.ClientID != "" && c.PayOS.APIKey != "" {
+		out = append(out, Endpoint{"payos", orDefault(c.PayOS.BaseURL, "https://api-merchant.payos.vn/v2")})
+	}
+	if c.Brevo.APIKey != "" {
+		out = append(out, Endpoint{"brevo", orDefault(c.Brevo.BaseURL, "https://api.brevo.com/v3")})
+	}
+	if c.Resend.APIKey != "" {
+		out = append(out, Endpoint{"resend", orDefault(c.Resend.BaseURL, "https://api.resend.com")})
+	}
+	if c.Sheets.SpreadsheetID != "" {
+		out = append(out, Endpoint{"sheets", "https://sheets.googleapis.com"})
+	}
+	if c.SMS.APIKey != "" {
+		out = append(out, Endpoint{"sms", orDefault(c.SMS.BaseURL, defaultSMSBaseURL)})
+	}
+	if c.Zalo.AccessToken != "" {
+		out = append(out, Endpoint{"zalo", orDefault(c.Zalo.BaseURL, defaultZaloBaseURL)})
+	}
+	return out
+}
+
+func orDefault(v, def string) string {
+	if v == "" {
+		return def
+	}
+	return v
+}
@@ -0,0 +1,230 @@
+package integrations
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"ecommerce-backend/internal/models"
+)
+
+// ErrEmailSuppressed is returned when every recipient of a message is on the
+// suppression list, so nothing was sent
+var ErrEmailSuppressed = errors.New("all recipients are suppressed")
+
+// ErrWebhookSignature is returned for a provider webhook that fails
+// verification
+var ErrWebhookSignature = errors.New("invalid webhook signature")
+
+// resendWebhookTolerance bounds the age of a signed Resend webhook, against
+// replays
+const resendWebhookTolerance = 5 * time.Minute
+
+// EmailLog stores what the Emailer sent and which addresses it must skip
+type EmailLog interface {
+	// RecordEmail stores one recipient of a message a provider accepted
+	RecordEmail(ctx context.Context, msg *models.EmailMessage) error
+	// SuppressedEmails returns which of the addresses must not be mailed
+	SuppressedEmails(ctx context.Context, addresses []string) ([]string, error)
+}
+
+type emailOrderKey struct{}
+
+// WithEmailOrder ties the emails sent with ctx to an order, so support can
+// see their delivery state on the order
+func WithEmailOrder(ctx context.Context, orderID uint64) context.Context {
+	return context.WithValue(ctx, emailOrderKey{}, orderID)
+}
+
+func emailOrderFrom(ctx context.Context) uint64 {
+	id, _ := ctx.Value(emailOrderKey{}).(uint64)
+	return id
+}
+
+// EmailEvent is one delivery event reported by a provider's webhook
+type EmailEvent struct {
+	At        time.Time
+	Provider  string
+	MessageID string
+	Email     string
+	// Status is the models.Email* state the event moves the message to
+	Status string
+	// Suppress is set for hard bounces and complaints: the address must not
+	// be mailed again
+	Suppress bool
+	Detail   string
+}
+
+// brevoEvents maps Brevo's transactional webhook events; others (opens,
+// clicks, requests) are not tracked
+var brevoEvents = map[string]struct {
+	status   string
+	suppress bool
+}{
+	"delivered":     {models.EmailDelivered, false},
+	"soft_bounce":   {models.EmailDeferred, false},
+	"deferred":      {models.EmailDeferred, false},
+	"hard_bounce":   {models.EmailBounced, true},
+	"invalid_email": {models.EmailBounced, true},
+	"blocked":       {models.EmailBounced, true},
+	"spam":          {models.EmailComplained, true},
+}
+
+// brevoEvent is a Brevo transactional webhook payload
+type brevoEvent struct {
+	Event     string `json:"event"`
+	Email     string `json:"email"`
+	MessageID string `json:"message-id"`
+	Reason    string `json:"reason"`
+	TSEvent   int64  `json:"ts_event"`
+}
+
+// ParseBrevoEvents parses a Brevo transactional webhook, a single event or a
+// batch. Untracked events are dropped.
+func ParseBrevoEvents(body []byte) ([]EmailEvent, error) {
+	var batch []brevoEvent
+	if trimmed := strings.TrimSpace(string(body)); strings.HasPrefix(trimmed, "[") {
+		if err := json.Unmarshal(body, &batch); err != nil {
+			return nil, fmt.Errorf("invalid brevo webhook: %w", err)
+		}
+	} else {
+		var single brevoEvent
+		if err := json.Unmarshal(body, &single); err != nil {
+			return nil, fmt.Errorf("invalid brevo webhook: %w", err)
+		}
+		batch = []brevoEvent{single}
+	}
+
+	var events []EmailEvent
+	for _, b := range batch {
+		kind, ok := brevoEvents[b.Event]
+		if !ok || b.MessageID == "" {
+			continue
+		}
+		ev := EmailEvent{
+			Provider:  ProviderBrevo,
+			MessageID: brevoMessageID(b.MessageID),
+			Email:     b.Email,
+			Status:    kind.status,
+			Suppress:  kind.suppress,
+			Detail:    b.Reason,
+		}
+		if b.TSEvent > 0 {
+			ev.At = time.Unix(b.TSEvent, 0).UTC()
+		}
+		events = append(events, ev)
+	}
+	return events, nil
+}
+
+// brevoMessageID puts a message ID in the angle-bracketed form the send API
+// returns, which is what the delivery rows store
+func brevoMessageID(id string) string {
+	id = strings.TrimSpace(id)
+	if id == "" || strings.HasPrefix(id, "<") {
+		return id
+	}
+	return "<" + id + ">"
+}
+
+// CheckWebhookToken reports whether got matches the shared webhook token, in
+// constant time. An empty token never matches.
+func CheckWebhookToken(token, got string) bool {
+	return token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(got)) == 1
+}
+
+// VerifyResendWebhook checks the Svix signature Resend sends webhooks with:
+// an HMAC-SHA256 of "id.timestamp.body" keyed with the base64 part of the
+// "whsec_" secret. signatures is the space-separated svix-signature header.
+func VerifyResendWebhook(secret, id, timestamp, signatures string, body []byte, now time.Time) error {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(secret, "whsec_"))
+	if err != nil || len(key) == 0 {
+		return fmt.Errorf("invalid resend webhook secret")
+	}
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || id == "" {
+		return ErrWebhookSignature
+	}
+	if age := now.Sub(time.Unix(ts, 0)); age > resendWebhookTolerance || age < -resendWebhookTolerance {
+		return ErrWebhookSignature
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(id + "." + timestamp + "."))
+	mac.Write(body)
+	want := mac.Sum(nil)
+
+	for _, sig := range strings.Fields(signatures) {
+		version, encoded, ok := strings.Cut(sig, ",")
+		if !ok || version != "v1" {
+			continue
+		}
+		got, err := base64.StdEncoding.DecodeString(encoded)
+		if err == nil && hmac.Equal(got, want) {
+			return nil
+		}
+	}
+	return ErrWebhookSignature
+}
+
+// resendEvent is a Resend webhook payload
+type resendEvent struct {
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      struct {
+		EmailID string   `json:"email_id"`
+		To      []string `json:"to"`
+		Bounce  struct {
+			Type    string `json:"type"`
+			SubType string `json:"subType"`
+			Message string `json:"message"`
+		} `json:"bounce"`
+	} `json:"data"`
+}
+
+// ParseResendEvents parses a verified Resend webhook into one event per
+// recipient. Untracked events (sent, opened, clicked) yield none.
+func ParseResendEvents(body []byte) ([]EmailEvent, error) {
+	var r resendEvent
+	if err := json.Unmarshal(body, &r); err != nil {
+		return nil, fmt.Errorf("invalid resend webhook: %w", err)
+	}
+
+	ev := EmailEvent{Provider: ProviderResend, MessageID: r.Data.EmailID, At: r.CreatedAt}
+	switch r.Type {
+	case "email.delivered":
+		ev.Status = models.EmailDelivered
+	case "email.delivery_delayed":
+		ev.Status = models.EmailDeferred
+	case "email.bounced":
+		ev.Detail = r.Data.Bounce.Message
+		if r.Data.Bounce.Type == "Transient" {
+			ev.Status = models.EmailDeferred
+		} else {
+			ev.Status, ev.Suppress = models.EmailBounced, true
+		}
+	case "email.complained":
+		ev.Status, ev.Suppress = models.EmailComplained, true
+	default:
+		return nil, nil
+	}
+	if ev.MessageID == "" {
+		return nil, nil
+	}
+
+	events := make([]EmailEvent, 0, len(r.Data.To))
+	for _, to := range r.Data.To {
+		e := ev
+		e.Email = to
+		events = append(events, e)
+	}
+	return events, nil
+}
@@ -0,0 +1,441 @@
+package integrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"ecommerce-backend/internal/emails"
+	"ecommerce-backend/internal/logging"
+	"ecommerce-backend/internal/metrics"
+	"ecommerce-backend/internal/models"
+)
+
+// Email providers
+const (
+	ProviderBrevo  = "brevo"
+	ProviderResend = "resend"
+)
+
+// Defaults for the failover emailer
+const (
+	// DefaultBrevoDailyLimit is Brevo's free plan (300 emails/day)
+	DefaultBrevoDailyLimit = 300
+	// DefaultResendDailyLimit is Resend's free plan (100 emails/day)
+	DefaultResendDailyLimit = 100
+	// DefaultEmailFailureThreshold consecutive failures take a provider out of rotation
+	DefaultEmailFailureThreshold = 3
+	// DefaultEmailCooldown is how long a failing provider stays out of rotation
+	DefaultEmailCooldown = time.Minute
+)
+
+// ErrEmailQuota is returned by a provider that refused a message because the
+// account's sending quota is used up; the provider is skipped until the next
+// UTC day
+var ErrEmailQuota = errors.New("email quota exhausted")
+
+// ErrNoEmailProvider is returned when no provider has credentials
+var ErrNoEmailProvider = errors.New("no email provider configured")
+
+// EmailProvider delivers rendered emails through one email API
+type EmailProvider interface {
+	// Name is the provider's label in logs, metrics and Status
+	Name() string
+	// Configured reports whether the provider has credentials; unconfigured
+	// providers are never tried
+	Configured() bool
+	// Send delivers msg and returns the provider's message ID
+	Send(ctx context.Context, to []string, msg emails.Message) (messageID string, err error)
+}
+
+// NewBrevoProvider sends through Brevo with the credentials in store (nil
+// reads the environment per call)
+func NewBrevoProvider(store *CredentialStore, opts ...Option) EmailProvider {
+	o := newOptions(opts)
+	return &brevoProvider{creds: store, client: newHTTPClient(o.log, ProviderBrevo)}
+}
+
+type brevoProvider struct {
+	creds  *CredentialStore
+	client *http.Client
+}
+
+func (p *brevoProvider) Name() string { return ProviderBrevo }
+
+func (p *brevoProvider) Configured() bool { return p.creds.credentials().Brevo.APIKey != "" }
+
+func (p *brevoProvider) Send(ctx context.Context, to []string, msg emails.Message) (string, error) {
+	return sendEmailBrevo(ctx, p.client, p.creds.credentials().Brevo, to, msg)
+}
+
+// NewResendProvider sends through Resend with the credentials in store (nil
+// reads the environment per call)
+func NewResendProvider(store *CredentialStore, opts ...Option) EmailProvider {
+	o := newOptions(opts)
+	return &resendProvider{creds: store, client: newHTTPClient(o.log, ProviderResend)}
+}
+
+type resendProvider struct {
+	creds  *CredentialStore
+	client *http.Client
+}
+
+func (p *resendProvider) Name() string { return ProviderResend }
+
+func (p *resendProvider) Configured() bool { return p.creds.credentials().Resend.APIKey != "" }
+
+func (p *resendProvider) Send(ctx context.Context, to []string, msg emails.Message) (string, error) {
+	return sendEmailResend(ctx, p.client, p.creds.credentials().Resend, to, msg)
+}
+
+// EmailerConfig tunes failover between providers; zero values use the defaults
+type EmailerConfig struct {
+	// DailyLimits caps the messages sent through a provider per UTC day, by
+	// name; a provider at its cap is tried last. 0 means no cap.
+	DailyLimits map[string]int
+	// FailureThreshold consecutive failures take a provider out of rotation
+	// for Cooldown
+	FailureThreshold int
+	Cooldown         time.Duration
+	// Deliveries records accepted messages for delivery tracking and holds
+	// the suppression list; nil sends to every address and records nothing
+	Deliveries EmailLog
+}
+
+// DefaultEmailerConfig caps Brevo and Resend at their free plans
+func DefaultEmailerConfig() EmailerConfig {
+	return EmailerConfig{
+		DailyLimits: map[string]int{
+			ProviderBrevo:  DefaultBrevoDailyLimit,
+			ProviderResend: DefaultResendDailyLimit,
+		},
+		FailureThreshold: DefaultEmailFailureThreshold,
+		Cooldown:         DefaultEmailCooldown,
+	}
+}
+
+// Emailer sends through a list of providers in order, failing over to the
+// next one when a provider errors or runs out of quota. Providers that are
+// failing, over quota or at their daily cap are tried after the healthy ones
+// rather than skipped, so a message is only lost when every provider refuses
+// it. It implements EmailSender.
+type Emailer struct {
+	providers []*providerState
+	cfg       EmailerConfig
+	log       *slog.Logger
+	now       func() time.Time
+}
+
+// providerState is the health of one provider as seen by this process
+type providerState struct {
+	EmailProvider
+
+	mu          sync.Mutex
+	failures    int // consecutive
+	downUntil   time.Time
+	quota       bool // downUntil comes from a quota rejection
+	lastError   string
+	lastSuccess time.Time
+	day         string // UTC date sentToday counts
+	sentToday   int
+}
+
+// NewEmailer sends through Brevo, then Resend, with credentials from the
+// environment and the default limits
+func NewEmailer() *Emailer {
+	return NewFailoverEmailer(DefaultEmailerConfig(), nil, NewBrevoProvider(nil), NewResendProvider(nil))
+}
+
+// NewEmailerWithCredentials sends through the providers named in order
+// (brevo, resend; empty means both, Brevo first) using the credentials in
+// store
+func NewEmailerWithCredentials(store *CredentialStore, cfg EmailerConfig, order []string, opts ...Option) (*Emailer, error) {
+	if len(order) == 0 {
+		order = []string{ProviderBrevo, ProviderResend}
+	}
+	providers := make([]EmailProvider, 0, len(order))
+	for _, name := range order {
+		switch name {
+		case ProviderBrevo:
+			providers = append(providers, NewBrevoProvider(store, opts...))
+		case ProviderResend:
+			providers = append(providers, NewResendProvider(store, opts...))
+		default:
+			return nil, fmt.Errorf("unknown email provider %q", name)
+		}
+	}
+	return NewFailoverEmailer(cfg, newOptions(opts).log, providers...), nil
+}
+
+// NewFailoverEmailer sends through providers in order; log receives delivery
+// and failover records (nil uses slog.Default())
+func NewFailoverEmailer(cfg EmailerConfig, log *slog.Logger, providers ...EmailProvider) *Emailer {
+	if cfg.FailureThreshold <= 0 {
+		cfg.FailureThreshold = DefaultEmailFailureThreshold
+	}
+	if cfg.Cooldown <= 0 {
+		cfg.Cooldown = DefaultEmailCooldown
+	}
+	e := &Emailer{cfg: cfg, log: logging.Subsystem(log, "email"), now: time.Now}
+	for _, p := range providers {
+		e.providers = append(e.providers, &providerState{EmailProvider: p})
+	}
+	return e
+}
+
+// Send delivers msg through the first provider that accepts it and returns
+// that provider's name and message ID. Suppressed recipients are dropped,
+// and ErrEmailSuppressed is returned when none is left. The error joins every
+// provider's failure.
+func (e *Emailer) Send(ctx context.Context, to []string, msg emails.Message) (provider, messageID string, err error) {
+	to = e.unsuppressed(ctx, to, msg)
+	if len(to) == 0 {
+		metrics.EmailSends.WithLabelValues("none", "suppressed").Inc()
+		return "", "", ErrEmailSuppressed
+	}
+
+	now := e.now()
+	var healthy, degraded []*providerState
+	for _, p := range e.providers {
+		if !p.Configured() {
+			continue
+		}
+		if p.available(now, e.cfg.DailyLimits[p.Name()]) {
+			healthy = append(healthy, p)
+		} else {
+			degraded = append(degraded, p)
+		}
+	}
+	candidates := append(healthy, degraded...)
+	if len(candidates) == 0 {
+		return "", "", ErrNoEmailProvider
+	}
+
+	var errs []error
+	for i, p := range candidates {
+		if i > 0 && ctx.Err() != nil {
+			break
+		}
+		id, err := p.Send(ctx, to, msg)
+		if err == nil {
+			p.succeeded(e.now())
+			metrics.EmailSends.WithLabelValues(p.Name(), "ok").Inc()
+			e.log.InfoContext(ctx, "email delivered",
+				"provider", p.Name(), "message_id", id, "template", msg.Template, "attempts", i+1)
+			e.record(ctx, p.Name(), id, to, msg)
+			return p.Name(), id, nil
+		}
+
+		result := "error"
+		if errors.Is(err, ErrEmailQuota) {
+			result = "quota"
+		}
+		p.failed(e.now(), err, e.cfg)
+		metrics.EmailSends.WithLabelValues(p.Name(), result).Inc()
+		if i < len(candidates)-1 {
+			e.log.WarnContext(ctx, "email provider failed, failing over",
+				"provider", p.Name(), "next", candidates[i+1].Name(), "template", msg.Template, "error", err)
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", p.Name(), err))
+	}
+	return "", "", errors.Join(errs...)
+}
+
+// unsuppressed drops the recipients on the suppression list. A failed
+// lookup sends to everyone: a bounce costs less than a lost receipt.
+func (e *Emailer) unsuppressed(ctx context.Context, to []string, msg emails.Message) []string {
+	if e.cfg.Deliveries == nil {
+		return to
+	}
+	suppressed, err := e.cfg.Deliveries.SuppressedEmails(ctx, to)
+	if err != nil {
+		e.log.WarnContext(ctx, "email suppression lookup failed", "template", msg.Template, "error", err)
+		return to
+	}
+	if len(suppressed) == 0 {
+		return to
+	}
+	skip := make(map[string]bool, len(suppressed))
+	for _, a := range suppressed {
+		skip[a] = true
+	}
+	kept := make([]string, 0, len(to))
+	for _, a := range to {
+		if !skip[a] {
+			kept = append(kept, a)
+		}
+	}
+	e.log.InfoContext(ctx, "email recipients suppressed",
+		"template", msg.Template, "suppressed", len(to)-len(kept), "remaining", len(kept))
+	return kept
+}
+
+// record stores one delivery row per recipient of an accepted message;
+// failures are logged, the message has been sent either way
+func (e *Emailer) record(ctx context.Context, provider, messageID string, to []string, msg emails.Message) {
+	if e.cfg.Deliveries == nil || messageID == "" {
+		return
+	}
+	orderID := emailOrderFrom(ctx)
+	for _, addr := range to {
+		err := e.cfg.Deliveries.RecordEmail(ctx, &models.EmailMessage{
+			Provider:  provider,
+			MessageID: messageID,
+			Email:     addr,
+			Template:  msg.Template,
+			Status:    models.EmailSent,
+			OrderID:   orderID,
+		})
+		if err != nil {
+			e.log.WarnContext(ctx, "recording email delivery failed",
+				"provider", provider, "message_id", messageID, "error", err)
+		}
+	}
+}
+
+// send adapts Send to the email helpers
+func (e *Emailer) send(ctx context.Context, to []string, msg emails.Message) error {
+	_, _, err := e.Send(ctx, to, msg)
+	return err
+}
+
+// available reports whether p is in rotation: not cooling down after
+// failures or a quota rejection, and under its daily cap
+func (p *providerState) available(now time.Time, limit int) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rollDay(now)
+	if now.Before(p.downUntil) {
+		return false
+	}
+	return limit <= 0 || p.sentToday < limit
+}
+
+func (p *providerState) succeeded(now time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rollDay(now)
+	p.sentToday++
+	p.failures = 0
+	p.downUntil = time.Time{}
+	p.quota = false
+	p.lastSuccess = now
+}
+
+func (p *providerState) failed(now time.Time, err error, cfg EmailerConfig) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.failures++
+	p.lastError = err.Error()
+	switch {
+	case errors.Is(err, ErrEmailQuota):
+		p.downUntil = nextUTCDay(now)
+		p.quota = true
+	case p.failures >= cfg.FailureThreshold:
+		p.downUntil = now.Add(cfg.Cooldown)
+		p.quota = false
+	}
+}
+
+// rollDay resets the daily count on a new UTC day
+func (p *providerState) rollDay(now time.Time) {
+	if day := now.UTC().Format(time.DateOnly); day != p.day {
+		p.day, p.sentToday = day, 0
+	}
+}
+
+func nextUTCDay(now time.Time) time.Time {
+	y, m, d := now.UTC().Date()
+	return time.Date(y, m, d+1, 0, 0, 0, 0, time.UTC)
+}
+
+// ProviderStatus is the health of one provider as seen by this process
+type ProviderStatus struct {
+	Name       string `json:"name"`
+	Configured bool   `json:"configured"`
+	// Healthy providers are tried first, in the configured order
+	Healthy bool `json:"healthy"`
+	// DownUntil is when a failing or over-quota provider is tried first again
+	DownUntil   *time.Time `json:"down_until,omitempty"`
+	Quota       bool       `json:"quota_exhausted,omitempty"`
+	Failures    int        `json:"consecutive_failures"`
+	SentToday   int        `json:"sent_today"`
+	DailyLimit  int        `json:"daily_limit,omitempty"`
+	LastError   string     `json:"last_error,omitempty"`
+	LastSuccess *time.Time `json:"last_success,omitempty"`
+}
+
+// Status reports every provider's health, in the configured order
+func (e *Emailer) Status() []ProviderStatus {
+	now := e.now()
+	out := make([]ProviderStatus, 0, len(e.providers))
+	for _, p := range e.providers {
+		limit := e.cfg.DailyLimits[p.Name()]
+		configured := p.Configured()
+		healthy := configured && p.available(now, limit)
+
+		p.mu.Lock()
+		st := ProviderStatus{
+			Name:       p.Name(),
+			Configured: configured,
+			Healthy:    healthy,
+			Quota:      p.quota && now.Before(p.downUntil),
+			Failures:   p.failures,
+			SentToday:  p.sentToday,
+			DailyLimit: limit,
+			LastError:  p.lastError,
+		}
+		if now.Before(p.downUntil) {
+			t := p.downUntil
+			st.DownUntil = &t
+		}
+		if !p.lastSuccess.IsZero() {
+			t := p.lastSuccess
+			st.LastSuccess = &t
+		}
+		p.mu.Unlock()
+		out = append(out, st)
+	}
+	return out
+}
+
+// Check is a readiness probe (see health.Check): it fails when providers are
+// configured but none is healthy, and summarizes each provider otherwise
+func (e *Emailer) Check(ctx context.Context) (string, error) {
+	var parts []string
+	configured, healthy := 0, 0
+	for _, st := range e.Status() {
+		if !st.Configured {
+			continue
+		}
+		configured++
+		detail := fmt.Sprintf("%s %d", st.Name, st.SentToday)
+		if st.DailyLimit > 0 {
+			detail += fmt.Sprintf("/%d", st.DailyLimit)
+		}
+		switch {
+		case st.Healthy:
+			healthy++
+		case st.Quota:
+			detail += " quota exhausted"
+		case st.DownUntil != nil:
+			detail += " down until " + st.DownUntil.UTC().Format(time.RFC3339)
+		default:
+			detail += " at daily limit"
+		}
+		parts = append(parts, detail)
+	}
+	if configured == 0 {
+		return "not configured", nil
+	}
+	summary := strings.Join(parts, ", ")
+	if healthy == 0 {
+		return "", fmt.Errorf("no healthy email provider: %s", summary)
+	}
+	return summary, nil
+}
@@ -1,52 +1,32 @@
 package integrations
 
 import (
+	"context"
 	"ecommerce-backend/internal/models"
 	"time"
 )
 
 // =============================================================================
-// RESEND EMAIL SENDER IMPLEMENTATION
+// EMAIL SENDER IMPLEMENTATION
 // =============================================================================
 
-// resendEmailer implements EmailSender interface
-type resendEmailer struct{}
+// Emailer implements EmailSender; see email_failover.go
 
-// NewResendEmailer creates a new Resend email sender
-func NewResendEmailer() EmailSender {
-	return &resendEmailer{}
+func (e *Emailer) SendOrderConfirmation(ctx context.Context, email, orderNumber string, amount float64) error {
+	return sendOrderConfirmationEmail(ctx, e.send, email, orderNumber, amount)
 }
 
-func (r *resendEmailer) SendOrderConfirmation(email, orderNumber string, amount float64) error {
-	return SendOrderConfirmationEmail(email, orderNumber, amount)
+func (e *Emailer) SendSymbioteReceipt(ctx context.Context, email, phone, status, elapsed string) error {
+	return sendSymbioteReceipt(ctx, e.send, email, phone, status, elapsed)
 }
 
-func (r *resendEmailer) SendSymbioteReceipt(email, phone, status, elapsed string) error {
-	return SendSymbioteReceipt(email, phone, status, elapsed)
-}
-
-func (r *resendEmailer) SendOrderDetails(email string, order interface{}) error {
+func (e *Emailer) SendOrderDetails(ctx context.Context, email string, order interface{}, link string) error {
 	if o, ok := order.(*models.Order); ok {
-		return SendOrderDetailsEmail(email, o)
+		return sendOrderDetailsEmail(ctx, e.send, email, o, link)
 	}
 	return nil
 }
 
-// =============================================================================
-// GOOGLE SHEETS SUBMITTER IMPLEMENTATION
-// =============================================================================
-
-// sheetsSubmitter implements SheetSubmitter interface
-type sheetsSubmitter struct{}
-
-// NewSheetsSubmitter creates a new Google Sheets submitter
-func NewSheetsSubmitter() SheetSubmitter {
-	return &sheetsSubmitter{}
-}
-
-func (s *sheetsSubmitter) SubmitOrder(name, phone, email, address, notes string, amount float64, timestamp interface{}) error {
-	if t, ok := timestamp.(time.Time); ok {
-		return SubmitOrderToGoogleSheet(name, phone, email, address, notes, amount, t)
-	}
-	return nil
+func (e *Emailer) SendTransferCode(ctx context.Context, email, code string, ttl time.Duration) error {
+	return sendTransferCodeEmail(ctx, e.send, email, code, ttl)
 }
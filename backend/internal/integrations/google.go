@@ -12,7 +12,6 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"os"
 )
 
 type GoogleUserInfo struct {
@@ -24,8 +23,8 @@ type GoogleUserInfo struct {
 }
 
 // VerifyGoogleToken: Verify Google ID token and get user info
-func VerifyGoogleToken(idToken string) (*GoogleUserInfo, error) {
-	clientID := os.Getenv("GOOGLE_CLIENT_ID")
+func VerifyGoogleToken(cfg GoogleConfig, idToken string) (*GoogleUserInfo, error) {
+	clientID := cfg.ClientID
 	if clientID == "" {
 		return nil, fmt.Errorf("GOOGLE_CLIENT_ID not configured")
 	}
@@ -2,69 +2,119 @@ package integrations
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
-	"time"
+	"sync"
 
+	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
+	"google.golang.org/api/option"
 	sheets "google.golang.org/api/sheets/v4"
 )
 
-// SubmitOrderToGoogleSheet appends a row to a Google Spreadsheet using a
-// service account. If not configured, it is a silent no-op to avoid
-// impacting order processing.
-func SubmitOrderToGoogleSheet(name, phone, email, address, dropName string, amount float64, paidAt time.Time) error {
-	sheetID := os.Getenv("GSSHEET_SPREADSHEET_ID")
-	if sheetID == "" {
-		// Not configured; noop
-		return nil
-	}
+// ErrSheetsNotConfigured is returned while no spreadsheet ID is set
+var ErrSheetsNotConfigured = errors.New("google sheets: no spreadsheet configured")
 
-	credPath := os.Getenv("GDRIVE_SERVICE_ACCOUNT")
-	if credPath == "" {
-		credPath = "./gdrive-service-account.json"
-	}
+// GoogleSheets implements SheetsAPI with a service account. The API client
+// and its access token are built once and reused until the credentials
+// change.
+type GoogleSheets struct {
+	creds  *CredentialStore // nil reads the environment per call
+	client *http.Client
 
-	b, err := os.ReadFile(credPath)
-	if err != nil {
-		return fmt.Errorf("read service account: %w", err)
-	}
+	mu  sync.Mutex
+	cfg SheetsConfig // the credentials srv was built from
+	srv *sheets.Service
+}
 
-	conf, err := google.JWTConfigFromJSON(b, sheets.SpreadsheetsScope)
-	if err != nil {
-		return fmt.Errorf("jwt config: %w", err)
-	}
+// NewGoogleSheets returns a GoogleSheets reading the environment
+func NewGoogleSheets() *GoogleSheets {
+	return &GoogleSheets{client: defaultHTTPClient}
+}
+
+// NewGoogleSheetsWithCredentials returns a GoogleSheets using the credentials in store
+func NewGoogleSheetsWithCredentials(store *CredentialStore, opts ...Option) *GoogleSheets {
+	o := newOptions(opts)
+	return &GoogleSheets{creds: store, client: newHTTPClient(o.log, "sheets")}
+}
 
-	client := conf.Client(context.Background())
-	srv, err := sheets.New(client)
+// Configured reports whether a spreadsheet is set
+func (g *GoogleSheets) Configured() bool {
+	return g.creds.credentials().Sheets.SpreadsheetID != ""
+}
+
+// AppendRows appends rows to the configured sheet, letting Sheets parse
+// dates and numbers as if typed in
+func (g *GoogleSheets) AppendRows(ctx context.Context, rows [][]string) error {
+	cfg := g.creds.credentials().Sheets
+	if cfg.SpreadsheetID == "" {
+		return ErrSheetsNotConfigured
+	}
+	srv, err := g.service(cfg)
 	if err != nil {
-		return fmt.Errorf("sheets service: %w", err)
+		return err
 	}
 
-	sheetName := os.Getenv("GSSHEET_SHEET_NAME")
+	sheetName := cfg.SheetName
 	if sheetName == "" {
 		sheetName = "Sheet1"
 	}
-	rangeStr := fmt.Sprintf("%s!A:Z", sheetName)
-
-	vr := &sheets.ValueRange{Values: [][]interface{}{{
-		paidAt.Format(time.RFC3339),
-		name,
-		phone,
-		email,
-		address,
-		dropName,
-		fmt.Sprintf("%.0f", amount),
-	}}}
-
-	_, err = srv.Spreadsheets.Values.Append(sheetID, rangeStr, vr).
+	values := make([][]interface{}, len(rows))
+	for i, row := range rows {
+		values[i] = make([]interface{}, len(row))
+		for j, v := range row {
+			values[i][j] = v
+		}
+	}
+
+	_, err = srv.Spreadsheets.Values.Append(cfg.SpreadsheetID, sheetName+"!A:Z", &sheets.ValueRange{Values: values}).
 		ValueInputOption("USER_ENTERED").
 		InsertDataOption("INSERT_ROWS").
-		Context(context.Background()).
+		Context(ctx).
 		Do()
 	if err != nil {
 		return fmt.Errorf("append values: %w", err)
 	}
-
 	return nil
 }
+
+// service returns the API client for cfg, building it on first use and
+// after the credentials changed
+func (g *GoogleSheets) service(cfg SheetsConfig) (*sheets.Service, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.srv != nil && g.cfg == cfg {
+		return g.srv, nil
+	}
+
+	b := []byte(cfg.ServiceAccountJSON)
+	if len(b) == 0 {
+		credPath := cfg.ServiceAccountPath
+		if credPath == "" {
+			credPath = "./gdrive-service-account.json"
+		}
+
+		var err error
+		b, err = os.ReadFile(credPath)
+		if err != nil {
+			return nil, fmt.Errorf("read service account: %w", err)
+		}
+	}
+
+	conf, err := google.JWTConfigFromJSON(b, sheets.SpreadsheetsScope)
+	if err != nil {
+		return nil, fmt.Errorf("jwt config: %w", err)
+	}
+
+	// Token fetches outlive any one call, so they get a background context;
+	// they and the API calls build on g.client
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, g.client)
+	srv, err := sheets.NewService(ctx, option.WithHTTPClient(conf.Client(ctx)))
+	if err != nil {
+		return nil, fmt.Errorf("sheets service: %w", err)
+	}
+	g.srv, g.cfg = srv, cfg
+	return srv, nil
+}
@@ -3,11 +3,8 @@ package integrations
 import (
 	"context"
 	"fmt"
-	"log"
 	"time"
 
-	"ecommerce-backend/internal/models"
-
 	"gorm.io/gorm"
 )
 
@@ -18,7 +15,8 @@ type HealthStatus struct {
 	Checks    map[string]string `json:"checks"`
 }
 
-// CheckHealth performs comprehensive health checks
+// CheckHealth pings the database. It has no side effects; periodic
+// maintenance runs in the scheduler.
 func CheckHealth(db *gorm.DB) HealthStatus {
 	status := HealthStatus{
 		Status:    "ok",
@@ -47,39 +45,9 @@ func CheckHealth(db *gorm.DB) HealthStatus {
 		}
 	}
 
-	// Auto-activate expired symbicode (run during health checks)
-	if db != nil {
-		if err := AutoActivateExpiredSymbicode(db); err != nil {
-			// Log error but don't fail health check
-			status.Checks["symbicode_auto_activate"] = fmt.Sprintf("warning: %v", err)
-		} else {
-			status.Checks["symbicode_auto_activate"] = "ok"
-		}
-	}
-
 	return status
 }
 
-// AutoActivateExpiredSymbicode activates symbicode that are older than 3 days and not yet activated
-func AutoActivateExpiredSymbicode(db *gorm.DB) error {
-	threeDaysAgo := time.Now().AddDate(0, 0, -3)
-
-	result := db.Model(&models.Symbicode{}).
-		Where("is_activated = ? AND created_at < ?", 0, threeDaysAgo).
-		Updates(map[string]interface{}{
-			"is_activated": true,
-			"activated_at": time.Now(),
-			"activated_ip": "AUTO_ACTIVATED",
-		})
-
-	if result.Error != nil {
-		return fmt.Errorf("failed to auto-activate expired symbicode: %w", result.Error)
-	}
-
-	log.Printf("[symbicode] auto-activated %d expired symbicode", result.RowsAffected)
-	return nil
-}
-
 // IsHealthy returns true if all critical services are healthy
 func IsHealthy(db *gorm.DB) bool {
 	status := CheckHealth(db)
@@ -0,0 +1,48 @@
+package integrations
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"ecommerce-backend/internal/logging"
+	"ecommerce-backend/internal/tracing"
+)
+
+// Option configures a gateway created with the *WithCredentials constructors
+type Option func(*options)
+
+type options struct {
+	log *slog.Logger
+}
+
+// WithLogger logs outbound calls (at debug, with the caller's request ID) to l
+func WithLogger(l *slog.Logger) Option {
+	return func(o *options) {
+		o.log = l
+	}
+}
+
+func newOptions(opts []Option) options {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// newHTTPClient returns a client that forwards the context's request ID as
+// X-Request-ID and its trace as traceparent, recording a "<service> <METHOD>"
+// span per call. Logs go to subsystem service of l (nil uses slog.Default()).
+func newHTTPClient(l *slog.Logger, service string) *http.Client {
+	if service != "" {
+		l = logging.Subsystem(l, service)
+	}
+	return &http.Client{
+		Timeout:   30 * time.Second,
+		Transport: tracing.Transport(logging.Transport(http.DefaultTransport, l), service),
+	}
+}
+
+// defaultHTTPClient serves the package functions that read the environment
+var defaultHTTPClient = newHTTPClient(nil, "")
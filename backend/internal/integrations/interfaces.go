@@ -1,22 +1,28 @@
 package integrations
 
+import (
+	"context"
+	"time"
+)
+
 // =============================================================================
 // PAYMENT GATEWAY INTERFACE
 // =============================================================================
 
-// PaymentGateway handles payment operations
+// PaymentGateway handles payment operations. Outbound calls carry ctx
+// (cancellation and the request ID).
 type PaymentGateway interface {
 	// CreateCheckout creates a checkout session for payment
-	CreateCheckout(req PayOSCheckoutRequest) (*PayOSCheckoutResponse, error)
+	CreateCheckout(ctx context.Context, req PayOSCheckoutRequest) (*PayOSCheckoutResponse, error)
 
 	// VerifyPayment verifies a payment status
-	VerifyPayment(orderCode int64) (*PayOSVerifyResponse, error)
+	VerifyPayment(ctx context.Context, orderCode int64) (*PayOSVerifyResponse, error)
 
 	// RefundPayment refunds a completed payment
-	RefundPayment(orderCode int64, reason string) error
+	RefundPayment(ctx context.Context, orderCode int64, reason string) error
 
 	// CancelPayment cancels a pending payment
-	CancelPayment(orderCode int64) error
+	CancelPayment(ctx context.Context, orderCode int64) error
 
 	// GenerateSignature generates webhook signature for verification
 	GenerateSignature(data string) string
@@ -29,21 +35,33 @@ type PaymentGateway interface {
 // EmailSender handles email operations
 type EmailSender interface {
 	// SendOrderConfirmation sends order confirmation email
-	SendOrderConfirmation(email, orderNumber string, amount float64) error
+	SendOrderConfirmation(ctx context.Context, email, orderNumber string, amount float64) error
 
 	// SendSymbioteReceipt sends ACCESS GRANTED/DENIED receipt
-	SendSymbioteReceipt(email, phone, status, elapsed string) error
+	SendSymbioteReceipt(ctx context.Context, email, phone, status, elapsed string) error
+
+	// SendOrderDetails sends full order details (guest lookup) with link, a
+	// one-time URL to view the order; an empty link is left out
+	SendOrderDetails(ctx context.Context, email string, order interface{}, link string) error
 
-	// SendOrderDetails sends full order details (guest lookup)
-	SendOrderDetails(email string, order interface{}) error
+	// SendTransferCode sends the one-time code that confirms a symbicode
+	// transfer, valid for ttl
+	SendTransferCode(ctx context.Context, email, code string, ttl time.Duration) error
 }
 
 // =============================================================================
 // GOOGLE SHEETS INTERFACE
 // =============================================================================
 
-// SheetSubmitter handles Google Sheets operations
+// SheetSubmitter exports paid orders to the order spreadsheet
 type SheetSubmitter interface {
-	// SubmitOrder submits order data to Google Sheet
-	SubmitOrder(name, phone, email, address, notes string, amount float64, timestamp interface{}) error
+	// QueueOrder adds a paid order to the export; rows go out in batches
+	QueueOrder(ctx context.Context, orderID uint64, paidAt time.Time) error
+}
+
+// SheetsAPI appends rows to the order spreadsheet; GoogleSheets is the real
+// one and sheetstest has a fake
+type SheetsAPI interface {
+	// AppendRows appends rows after the last row of the sheet in one call
+	AppendRows(ctx context.Context, rows [][]string) error
 }
@@ -0,0 +1,186 @@
+package integrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"ecommerce-backend/internal/logging"
+	"ecommerce-backend/internal/metrics"
+)
+
+// Customer notification events
+const (
+	EventOrderConfirmation = "order_confirmation"
+	EventDropWon           = "drop_won"
+	EventDropLost          = "drop_lost"
+	// EventTransferCode carries a symbicode transfer code. It goes to the one
+	// contact it names, never by preference: see TransferCodePreferences.
+	EventTransferCode = "transfer_code"
+)
+
+// Notification channels
+const (
+	ChannelEmail = "email"
+	ChannelSMS   = "sms"
+	ChannelZalo  = "zalo"
+)
+
+// Events lists the notification events, in the order preferences are shown
+var Events = []string{EventOrderConfirmation, EventDropWon, EventDropLost}
+
+// Notification is one event to tell a customer about. Channels use the
+// contact details they need and ignore the rest.
+type Notification struct {
+	At          time.Time
+	Event       string
+	Name        string
+	Phone       string
+	Email       string
+	OrderNumber string
+	OrderID     uint64
+	Amount      uint64
+	// Code and CodeTTL are the one-time code of EventTransferCode
+	Code    string
+	CodeTTL time.Duration
+}
+
+// NotificationChannel delivers notifications through one medium
+type NotificationChannel interface {
+	// Name is the channel's label in preferences, logs and metrics
+	Name() string
+	// Accepts reports whether the channel is configured for n and n carries
+	// the contact it needs; refused notifications go to the next channel
+	Accepts(n Notification) bool
+	// Notify delivers n
+	Notify(ctx context.Context, n Notification) error
+}
+
+// Notifier routes customer notifications to channels by event
+type Notifier interface {
+	Notify(ctx context.Context, n Notification) error
+}
+
+// DefaultNotifyPreferences emails every event, transfer codes included
+func DefaultNotifyPreferences() map[string][]string {
+	prefs := make(map[string][]string, len(Events)+1)
+	for _, event := range Events {
+		prefs[event] = []string{ChannelEmail}
+	}
+	prefs[EventTransferCode] = []string{ChannelEmail}
+	return prefs
+}
+
+// TransferCodePreferences routes a transfer code to the email or phone it is
+// addressed to: only one is set, so only one channel accepts it
+func TransferCodePreferences() []string {
+	return []string{ChannelEmail + "|" + ChannelSMS}
+}
+
+// NewNotifier sends each event to the channels prefs lists for it. An entry
+// is a channel name or a fallback chain such as "zalo|sms": every entry gets
+// the notification, and within a chain the first channel that accepts and
+// delivers it wins. Events without preferences are dropped.
+func NewNotifier(prefs map[string][]string, channels []NotificationChannel, opts ...Option) (Notifier, error) {
+	byName := make(map[string]NotificationChannel, len(channels))
+	for _, ch := range channels {
+		byName[ch.Name()] = ch
+	}
+
+	routes := make(map[string][][]NotificationChannel, len(prefs))
+	for event, entries := range prefs {
+		for _, entry := range entries {
+			var chain []NotificationChannel
+			for _, name := range strings.Split(entry, "|") {
+				ch, ok := byName[strings.TrimSpace(name)]
+				if !ok {
+					return nil, fmt.Errorf("unknown notification channel %q for %s", name, event)
+				}
+				chain = append(chain, ch)
+			}
+			routes[event] = append(routes[event], chain)
+		}
+	}
+	return &notifier{routes: routes, log: logging.Subsystem(newOptions(opts).log, "notify")}, nil
+}
+
+// NewEmailNotifier emails every event through sender
+func NewEmailNotifier(sender EmailSender) Notifier {
+	n, _ := NewNotifier(DefaultNotifyPreferences(), []NotificationChannel{NewEmailChannel(sender)})
+	return n
+}
+
+type notifier struct {
+	routes map[string][][]NotificationChannel
+	log    *slog.Logger
+}
+
+// Notify delivers n on every chain for its event. The error joins the
+// chains no channel delivered.
+func (r *notifier) Notify(ctx context.Context, n Notification) error {
+	var errs []error
+	for _, chain := range r.routes[n.Event] {
+		if err := r.notifyChain(ctx, chain, n); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (r *notifier) notifyChain(ctx context.Context, chain []NotificationChannel, n Notification) error {
+	var errs []error
+	for i, ch := range chain {
+		if !ch.Accepts(n) {
+			metrics.Notifications.WithLabelValues(ch.Name(), n.Event, "skipped").Inc()
+			continue
+		}
+		err := ch.Notify(ctx, n)
+		if err == nil {
+			metrics.Notifications.WithLabelValues(ch.Name(), n.Event, "ok").Inc()
+			return nil
+		}
+		metrics.Notifications.WithLabelValues(ch.Name(), n.Event, "error").Inc()
+		if i < len(chain)-1 {
+			r.log.WarnContext(ctx, "notification channel failed, falling back",
+				"channel", ch.Name(), "next", chain[i+1].Name(), "event", n.Event, "order_id", n.OrderID, "error", err)
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", ch.Name(), err))
+	}
+	if len(errs) == 0 {
+		return fmt.Errorf("no channel accepted %s for order %d", n.Event, n.OrderID)
+	}
+	return errors.Join(errs...)
+}
+
+// NewEmailChannel delivers notifications as the existing transactional
+// emails through sender
+func NewEmailChannel(sender EmailSender) NotificationChannel {
+	return &emailChannel{sender: sender}
+}
+
+type emailChannel struct {
+	sender EmailSender
+}
+
+func (c *emailChannel) Name() string { return ChannelEmail }
+
+func (c *emailChannel) Accepts(n Notification) bool {
+	return c.sender != nil && n.Email != ""
+}
+
+func (c *emailChannel) Notify(ctx context.Context, n Notification) error {
+	switch n.Event {
+	case EventOrderConfirmation:
+		return c.sender.SendOrderConfirmation(ctx, n.Email, n.OrderNumber, float64(n.Amount))
+	case EventDropWon:
+		return c.sender.SendSymbioteReceipt(ctx, n.Email, n.Phone, "WINNER", n.At.Format("2006-01-02 15:04:05"))
+	case EventDropLost:
+		return c.sender.SendSymbioteReceipt(ctx, n.Email, n.Phone, "LOSER", "N/A")
+	case EventTransferCode:
+		return c.sender.SendTransferCode(ctx, n.Email, n.Code, n.CodeTTL)
+	}
+	return fmt.Errorf("no email for event %q", n.Event)
+}
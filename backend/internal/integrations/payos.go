@@ -8,6 +8,7 @@ package integrations
 
 import (
 	"bytes"
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
@@ -15,7 +16,6 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"os"
 	"time"
 )
 
@@ -76,9 +76,13 @@ type PayOSRefundRequest struct {
 
 // CreatePayOSCheckout: Create PayOS checkout session
 func CreatePayOSCheckout(req PayOSCheckoutRequest) (*PayOSCheckoutResponse, error) {
-	clientID := os.Getenv("PAYOS_CLIENT_ID")
-	apiKey := os.Getenv("PAYOS_API_KEY")
-	checkoutURL := os.Getenv("PAYOS_CHECKOUT_URL")
+	return createPayOSCheckout(context.Background(), defaultHTTPClient, CredentialsFromEnv().PayOS, req)
+}
+
+func createPayOSCheckout(ctx context.Context, client *http.Client, cfg PayOSConfig, req PayOSCheckoutRequest) (*PayOSCheckoutResponse, error) {
+	clientID := cfg.ClientID
+	apiKey := cfg.APIKey
+	checkoutURL := cfg.CheckoutURL
 
 	if clientID == "" || apiKey == "" {
 		// Mock Mode for System Testing
@@ -115,14 +119,14 @@ func CreatePayOSCheckout(req PayOSCheckoutRequest) (*PayOSCheckoutResponse, erro
 
 	// Set default URLs
 	if req.ReturnURL == "" {
-		returnURL := os.Getenv("PAYOS_RETURN_URL")
+		returnURL := cfg.ReturnURL
 		if returnURL == "" {
 			returnURL = "http://localhost:5173/orders?payment=success"
 		}
 		req.ReturnURL = returnURL
 	}
 	if req.CancelURL == "" {
-		cancelURL := os.Getenv("PAYOS_CANCEL_URL")
+		cancelURL := cfg.CancelURL
 		if cancelURL == "" {
 			cancelURL = "http://localhost:5173/checkout?payment=cancelled"
 		}
@@ -130,7 +134,7 @@ func CreatePayOSCheckout(req PayOSCheckoutRequest) (*PayOSCheckoutResponse, erro
 	}
 
 	// Generate signature using checksum key
-	checksumKey := os.Getenv("PAYOS_CHECKSUM_KEY")
+	checksumKey := cfg.ChecksumKey
 	if checksumKey == "" {
 		return nil, fmt.Errorf("PAYOS_CHECKSUM_KEY not configured")
 	}
@@ -151,7 +155,7 @@ func CreatePayOSCheckout(req PayOSCheckoutRequest) (*PayOSCheckoutResponse, erro
 	}
 
 	// Create request
-	httpReq, err := http.NewRequest("POST", checkoutURL, bytes.NewBuffer(body))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", checkoutURL, bytes.NewBuffer(body))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -162,7 +166,6 @@ func CreatePayOSCheckout(req PayOSCheckoutRequest) (*PayOSCheckoutResponse, erro
 	httpReq.Header.Set("x-api-key", apiKey)
 
 	// Make request
-	client := &http.Client{Timeout: 30 * time.Second}
 	resp, err := client.Do(httpReq)
 	if err != nil {
 		return nil, fmt.Errorf("failed to make request: %w", err)
@@ -194,14 +197,18 @@ func CreatePayOSCheckout(req PayOSCheckoutRequest) (*PayOSCheckoutResponse, erro
 // RefundPayOSPayment attempts to refund a completed PayOS payment.
 // This is used in limited-drop race-condition scenarios where multiple users paid but stock was already taken.
 func RefundPayOSPayment(orderCode int64, reason string) error {
-	clientID := os.Getenv("PAYOS_CLIENT_ID")
-	apiKey := os.Getenv("PAYOS_API_KEY")
+	return refundPayOSPayment(context.Background(), defaultHTTPClient, CredentialsFromEnv().PayOS, orderCode, reason)
+}
+
+func refundPayOSPayment(ctx context.Context, client *http.Client, cfg PayOSConfig, orderCode int64, reason string) error {
+	clientID := cfg.ClientID
+	apiKey := cfg.APIKey
 
 	if clientID == "" || apiKey == "" {
 		return fmt.Errorf("PayOS not configured")
 	}
 
-	refundURL := os.Getenv("PAYOS_REFUND_URL")
+	refundURL := cfg.RefundURL
 	if refundURL == "" {
 		// Default to generic refund endpoint; adjust via PAYOS_REFUND_URL if PayOS changes path.
 		refundURL = fmt.Sprintf("https://api-merchant.payos.vn/v2/payment-requests/%d/refunds", orderCode)
@@ -217,7 +224,7 @@ func RefundPayOSPayment(orderCode int64, reason string) error {
 		return fmt.Errorf("failed to marshal refund request: %w", err)
 	}
 
-	httpReq, err := http.NewRequest("POST", refundURL, bytes.NewBuffer(body))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", refundURL, bytes.NewBuffer(body))
 	if err != nil {
 		return fmt.Errorf("failed to create refund request: %w", err)
 	}
@@ -226,7 +233,6 @@ func RefundPayOSPayment(orderCode int64, reason string) error {
 	httpReq.Header.Set("x-client-id", clientID)
 	httpReq.Header.Set("x-api-key", apiKey)
 
-	client := &http.Client{Timeout: 30 * time.Second}
 	resp, err := client.Do(httpReq)
 	if err != nil {
 		return fmt.Errorf("failed to call refund API: %w", err)
@@ -247,21 +253,25 @@ func RefundPayOSPayment(orderCode int64, reason string) error {
 
 // VerifyPayOSPayment: Verify PayOS payment
 func VerifyPayOSPayment(orderCode int64) (*PayOSVerifyResponse, error) {
-	clientID := os.Getenv("PAYOS_CLIENT_ID")
-	apiKey := os.Getenv("PAYOS_API_KEY")
+	return verifyPayOSPayment(context.Background(), defaultHTTPClient, CredentialsFromEnv().PayOS, orderCode)
+}
+
+func verifyPayOSPayment(ctx context.Context, client *http.Client, cfg PayOSConfig, orderCode int64) (*PayOSVerifyResponse, error) {
+	clientID := cfg.ClientID
+	apiKey := cfg.APIKey
 
 	if clientID == "" || apiKey == "" {
 		return nil, fmt.Errorf("PayOS not configured")
 	}
 
 	// Allow override for testing
-	baseURL := os.Getenv("PAYOS_BASE_URL")
+	baseURL := cfg.BaseURL
 	if baseURL == "" {
 		baseURL = "https://api-merchant.payos.vn/v2"
 	}
 	verifyURL := fmt.Sprintf("%s/payment-requests/%d", baseURL, orderCode)
 
-	httpReq, err := http.NewRequest("GET", verifyURL, nil)
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", verifyURL, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -269,7 +279,6 @@ func VerifyPayOSPayment(orderCode int64) (*PayOSVerifyResponse, error) {
 	httpReq.Header.Set("x-client-id", clientID)
 	httpReq.Header.Set("x-api-key", apiKey)
 
-	client := &http.Client{Timeout: 30 * time.Second}
 	resp, err := client.Do(httpReq)
 	if err != nil {
 		return nil, fmt.Errorf("failed to make request: %w", err)
@@ -295,21 +304,25 @@ func VerifyPayOSPayment(orderCode int64) (*PayOSVerifyResponse, error) {
 
 // CancelPayOSPayment: Cancel pending PayOS payment
 func CancelPayOSPayment(orderCode int64) error {
-	clientID := os.Getenv("PAYOS_CLIENT_ID")
-	apiKey := os.Getenv("PAYOS_API_KEY")
+	return cancelPayOSPayment(context.Background(), defaultHTTPClient, CredentialsFromEnv().PayOS, orderCode)
+}
+
+func cancelPayOSPayment(ctx context.Context, client *http.Client, cfg PayOSConfig, orderCode int64) error {
+	clientID := cfg.ClientID
+	apiKey := cfg.APIKey
 
 	if clientID == "" || apiKey == "" {
 		return fmt.Errorf("PayOS not configured")
 	}
 
 	// Allow override for testing
-	baseURL := os.Getenv("PAYOS_BASE_URL")
+	baseURL := cfg.BaseURL
 	if baseURL == "" {
 		baseURL = "https://api-merchant.payos.vn/v2"
 	}
 	cancelURL := fmt.Sprintf("%s/payment-requests/%d/cancel", baseURL, orderCode)
 
-	httpReq, err := http.NewRequest("POST", cancelURL, nil)
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", cancelURL, nil)
 	if err != nil {
 		return fmt.Errorf("failed to create cancel request: %w", err)
 	}
@@ -317,7 +330,6 @@ func CancelPayOSPayment(orderCode int64) error {
 	httpReq.Header.Set("x-client-id", clientID)
 	httpReq.Header.Set("x-api-key", apiKey)
 
-	client := &http.Client{Timeout: 30 * time.Second}
 	resp, err := client.Do(httpReq)
 	if err != nil {
 		return fmt.Errorf("failed to call cancel API: %w", err)
@@ -338,7 +350,12 @@ func CancelPayOSPayment(orderCode int64) error {
 
 // GeneratePayOSSignature: Generate PayOS webhook signature
 func GeneratePayOSSignature(data string) string {
-	apiKey := os.Getenv("PAYOS_API_KEY")
+	return CredentialsFromEnv().PayOS.Signature(data)
+}
+
+// Signature returns the PayOS webhook signature of data ("" when no API key is set)
+func (cfg PayOSConfig) Signature(data string) string {
+	apiKey := cfg.APIKey
 	if apiKey == "" {
 		return ""
 	}
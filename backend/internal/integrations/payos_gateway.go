@@ -1,33 +1,52 @@
 package integrations
 
+import (
+	"context"
+	"net/http"
+
+)
+
 // =============================================================================
 // PAYOS GATEWAY IMPLEMENTATION
 // =============================================================================
 
 // payosGateway implements PaymentGateway interface
-type payosGateway struct{}
+type payosGateway struct {
+	creds  *CredentialStore // nil reads the environment per call
+	client *http.Client
+}
 
 // NewPayOSGateway creates a new PayOS payment gateway
 func NewPayOSGateway() PaymentGateway {
-	return &payosGateway{}
+	return &payosGateway{client: defaultHTTPClient}
+}
+
+// NewPayOSGatewayWithCredentials creates a PayOS gateway using the credentials in store
+func NewPayOSGatewayWithCredentials(store *CredentialStore, opts ...Option) PaymentGateway {
+	o := newOptions(opts)
+	return &payosGateway{creds: store, client: newHTTPClient(o.log, "payos")}
+}
+
+func (p *payosGateway) config() PayOSConfig {
+	return p.creds.credentials().PayOS
 }
 
-func (p *payosGateway) CreateCheckout(req PayOSCheckoutRequest) (*PayOSCheckoutResponse, error) {
-	return CreatePayOSCheckout(req)
+func (p *payosGateway) CreateCheckout(ctx context.Context, req PayOSCheckoutRequest) (*PayOSCheckoutResponse, error) {
+	return createPayOSCheckout(ctx, p.client, p.config(), req)
 }
 
-func (p *payosGateway) VerifyPayment(orderCode int64) (*PayOSVerifyResponse, error) {
-	return VerifyPayOSPayment(orderCode)
+func (p *payosGateway) VerifyPayment(ctx context.Context, orderCode int64) (*PayOSVerifyResponse, error) {
+	return verifyPayOSPayment(ctx, p.client, p.config(), orderCode)
 }
 
-func (p *payosGateway) RefundPayment(orderCode int64, reason string) error {
-	return RefundPayOSPayment(orderCode, reason)
+func (p *payosGateway) RefundPayment(ctx context.Context, orderCode int64, reason string) error {
+	return refundPayOSPayment(ctx, p.client, p.config(), orderCode, reason)
 }
 
-func (p *payosGateway) CancelPayment(orderCode int64) error {
-	return CancelPayOSPayment(orderCode)
+func (p *payosGateway) CancelPayment(ctx context.Context, orderCode int64) error {
+	return cancelPayOSPayment(ctx, p.client, p.config(), orderCode)
 }
 
 func (p *payosGateway) GenerateSignature(data string) string {
-	return GeneratePayOSSignature(data)
+	return p.config().Signature(data)
 }
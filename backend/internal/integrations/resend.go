@@ -8,16 +8,19 @@ package integrations
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
-	"os"
 	"strings"
 	"time"
 
+	"ecommerce-backend/internal/emails"
 	"ecommerce-backend/internal/models"
 	"ecommerce-backend/internal/utils/base32"
+
+	"gorm.io/datatypes"
 )
 
 type ResendEmailRequest struct {
@@ -34,11 +37,19 @@ type ResendEmailResponse struct {
 
 // SendEmail: Send email via Resend
 func SendEmail(to []string, subject, htmlContent string) error {
-	apiKey := os.Getenv("RESEND_API_KEY")
-	fromEmail := os.Getenv("RESEND_FROM_EMAIL")
+	msg := emails.Message{Subject: subject, HTML: htmlContent}
+	_, err := sendEmailResend(context.Background(), defaultHTTPClient, CredentialsFromEnv().Resend, to, msg)
+	return err
+}
+
+// sendEmailResend sends msg and returns Resend's email ID ("" if the
+// response had none). A daily or monthly quota rejection is ErrEmailQuota.
+func sendEmailResend(ctx context.Context, client *http.Client, cfg ResendConfig, to []string, msg emails.Message) (string, error) {
+	apiKey := cfg.APIKey
+	fromEmail := cfg.FromEmail
 
 	if apiKey == "" {
-		return fmt.Errorf("resend api key not configured")
+		return "", fmt.Errorf("resend api key not configured")
 	}
 
 	if fromEmail == "" {
@@ -48,108 +59,124 @@ func SendEmail(to []string, subject, htmlContent string) error {
 	req := ResendEmailRequest{
 		From:    fromEmail,
 		To:      to,
-		Subject: subject,
-		HTML:    htmlContent,
+		Subject: msg.Subject,
+		HTML:    msg.HTML,
+		Text:    msg.Text,
 	}
 
 	body, err := json.Marshal(req)
 	if err != nil {
-		return fmt.Errorf("failed to marshal request: %w", err)
+		return "", fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	baseURL := os.Getenv("RESEND_BASE_URL")
+	baseURL := cfg.BaseURL
 	if baseURL == "" {
 		baseURL = "https://api.resend.com"
 	}
 
-	httpReq, err := http.NewRequest("POST", baseURL+"/emails", bytes.NewBuffer(body))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", baseURL+"/emails", bytes.NewBuffer(body))
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return "", fmt.Errorf("failed to create request: %w", err)
 	}
 
 	httpReq.Header.Set("Content-Type", "application/json")
 	httpReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", apiKey))
 
-	client := &http.Client{Timeout: 30 * time.Second}
 	resp, err := client.Do(httpReq)
 	if err != nil {
-		return fmt.Errorf("failed to send email: %w", err)
+		return "", fmt.Errorf("failed to send email: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("resend error: %s", string(body))
+		if resp.StatusCode == http.StatusTooManyRequests && bytes.Contains(body, []byte("quota")) {
+			return "", fmt.Errorf("%w: resend error: %s", ErrEmailQuota, string(body))
+		}
+		return "", fmt.Errorf("resend error: %s", string(body))
 	}
 
-	return nil
+	// Accepted: an unreadable ID must not fail the send (see sendEmailBrevo)
+	var respBody ResendEmailResponse
+	json.NewDecoder(resp.Body).Decode(&respBody)
+
+	return respBody.ID, nil
+}
+
+// orderLookupURL is the storefront page where guests look up an order
+const orderLookupURL = "https://donaldwatch.vn/orders"
+
+// emailSendFunc delivers one email; the package functions use envEmailer
+type emailSendFunc func(ctx context.Context, to []string, msg emails.Message) error
+
+// envEmailer serves the package functions: Brevo, then Resend, with
+// credentials from the environment
+var envEmailer = NewEmailer()
+
+// envSend sends through envEmailer
+func envSend(ctx context.Context, to []string, msg emails.Message) error {
+	return envEmailer.send(ctx, to, msg)
+}
+
+// sendTemplate renders the email name in locale and delivers it
+func sendTemplate(ctx context.Context, send emailSendFunc, to []string, name string, locale emails.Locale, data any) error {
+	msg, err := emails.Render(name, locale, data)
+	if err != nil {
+		return err
+	}
+	return send(ctx, to, msg)
 }
 
 // SendWelcomeEmail: Send welcome email to new user
 func SendWelcomeEmail(email, name string) error {
-	html := fmt.Sprintf(`
-		<h1>Chào mừng %s!</h1>
-		<p>Cảm ơn bạn đã đăng ký tài khoản tại Donald Watch.</p>
-		<p>Chúc bạn mua sắm vui vẻ!</p>
-	`, name)
-
-	return SendEmailBrevo([]string{email}, "Chào mừng đến với Donald Watch", html)
+	return sendTemplate(context.Background(), envSend, []string{email}, emails.Welcome, emails.DefaultLocale, emails.WelcomeData{Name: name})
 }
 
 // SendOrderConfirmationEmail: Send order confirmation email
 func SendOrderConfirmationEmail(email, orderNumber string, totalAmount float64) error {
-	html := fmt.Sprintf(`
-		<h1>Đơn hàng của bạn đã được xác nhận</h1>
-		<p>Mã đơn hàng: <strong>%s</strong></p>
-		<p>Tổng tiền: <strong>%.0f VND</strong></p>
-		<p>Cảm ơn bạn đã mua sắm tại Donald Watch!</p>
-	`, orderNumber, totalAmount)
-
-	return SendEmailBrevo([]string{email}, fmt.Sprintf("Xác nhận đơn hàng #%s", orderNumber), html)
+	return sendOrderConfirmationEmail(context.Background(), envSend, email, orderNumber, totalAmount)
+}
+
+func sendOrderConfirmationEmail(ctx context.Context, send emailSendFunc, email, orderNumber string, totalAmount float64) error {
+	data := emails.OrderConfirmationData{OrderNumber: orderNumber, Total: int64(totalAmount)}
+	return sendTemplate(ctx, send, []string{email}, emails.OrderConfirmation, emails.LocaleFrom(ctx), data)
 }
 
 // SendOrderDetailsEmail: Send full order details (guest lookup)
 func SendOrderDetailsEmail(email string, order *models.Order) error {
+	return sendOrderDetailsEmail(context.Background(), envSend, email, order, "")
+}
+
+func sendOrderDetailsEmail(ctx context.Context, send emailSendFunc, email string, order *models.Order, link string) error {
 	if email == "" || order == nil {
 		return fmt.Errorf("missing email or order")
 	}
+	ctx = WithEmailOrder(ctx, order.ID)
 
-	var itemsBuilder strings.Builder
-	var orderItems []struct {
-		ProductName string  `json:"product_name"`
-		Quantity    int     `json:"quantity"`
-		Price       float64 `json:"price"`
-	}
-	if err := json.Unmarshal(order.Items, &orderItems); err == nil {
-		for _, item := range orderItems {
-			itemsBuilder.WriteString(fmt.Sprintf(
-				"<li>%s x %d - %.0f VND</li>",
-				item.ProductName, item.Quantity, item.Price*float64(item.Quantity),
-			))
-		}
+	shipping := parseShipping(order.ShippingAddress)
+	locale := emails.LocaleFrom(ctx)
+	if shipping.Locale != "" {
+		locale = emails.ParseLocale(shipping.Locale)
 	}
 
-	address := string(order.ShippingAddress)
-	if address == "" {
-		address = "Không có địa chỉ"
+	data := emails.OrderDetailsData{
+		OrderNumber: base32.GenerateOrderNumber(order.ID),
+		Total:       int64(order.TotalAmount),
+		Status:      models.OrderStatusName(order.Status),
+		Address:     shipping.line(),
+		Items:       parseOrderItems(order.Items),
+		Link:        link,
+		LookupURL:   orderLookupURL,
 	}
-
-	html := fmt.Sprintf(`
-		<h2>Thông tin đơn hàng #%s</h2>
-		<p>Cảm ơn bạn đã đặt hàng tại Donald Watch.</p>
-		<p><strong>Tổng tiền:</strong> %d VND</p>
-		<p><strong>Địa chỉ giao:</strong> %s</p>
-		<p><strong>Trạng thái:</strong> %d</p>
-		<p><strong>Sản phẩm:</strong></p>
-		<ul>%s</ul>
-		<p>Bạn có thể tra cứu đơn bằng mã đơn và email/số điện thoại tại trang: https://donaldwatch.vn/orders</p>
-	`, base32.GenerateOrderNumber(order.ID), order.TotalAmount, address, order.Status, itemsBuilder.String())
-
-	return SendEmailBrevo([]string{email}, fmt.Sprintf("Chi tiết đơn hàng #%s", base32.GenerateOrderNumber(order.ID)), html)
+	return sendTemplate(ctx, send, []string{email}, emails.OrderDetails, locale, data)
 }
 
 // SendSymbioteReceipt sends a high-touch "ACCESS GRANTED" receipt email
 func SendSymbioteReceipt(email, maskedPhone, status, elapsed string) error {
+	return sendSymbioteReceipt(context.Background(), envSend, email, maskedPhone, status, elapsed)
+}
+
+func sendSymbioteReceipt(ctx context.Context, send emailSendFunc, email, maskedPhone, status, elapsed string) error {
 	if email == "" {
 		return fmt.Errorf("email is required for receipt")
 	}
@@ -161,149 +188,106 @@ func SendSymbioteReceipt(email, maskedPhone, status, elapsed string) error {
 		elapsed = "0.042s"
 	}
 
-	var subject string
-	var bodyHTML string
-
-	switch status {
-	case "WINNER":
-		status = "SECURED"
-		subject = "[ACCESS GRANTED] PROJECT SYMBIOTE"
-		bodyHTML = fmt.Sprintf(
-			`<pre style="font-family: monospace; line-height: 1.5;">
-User ID: %s
-Status: %s
-Time: %s
-
-Chúc mừng. Bạn đã đánh bại hàng trăm kẻ khác.
-Hệ thống đã khoá slot lại và ghi nhận quyền sở hữu của bạn.
-Những kẻ chậm tay hơn chỉ còn quyền than vãn.
-
-Signed,
-DAEMON / System Architect.
-</pre>`, maskedPhone, status, elapsed)
-	case "LOSER":
-		status = "REJECTED"
-		subject = "[ACCESS DENIED] PROJECT SYMBIOTE"
-		bodyHTML = fmt.Sprintf(
-			`<pre style="font-family: monospace; line-height: 1.5;">
-User ID: %s
-Status: %s
-Time: %s
-
-Thanh toán của bạn đã đến SAU người khác.
-Slot đã bị cướp trước mặt bạn, hệ thống sẽ hoàn tiền (nếu đã trừ).
-Lần sau hãy quyết đoán hơn, Colosseum không chờ kẻ do dự.
-
-Signed,
-DAEMON / System Architect.
-</pre>`, maskedPhone, status, elapsed)
-	default:
-		if status == "" {
-			status = "INFO"
-		}
-		subject = "[NOTICE] PROJECT SYMBIOTE"
-		bodyHTML = fmt.Sprintf(
-			`<pre style="font-family: monospace; line-height: 1.5;">
-User ID: %s
-Status: %s
-Time: %s
-
-Thông báo trạng thái từ hệ thống.
-
-Signed,
-DAEMON / System Architect.
-</pre>`, maskedPhone, status, elapsed)
+	data := emails.SymbioteReceiptData{UserID: maskedPhone, Result: status, Elapsed: elapsed}
+	return sendTemplate(ctx, send, []string{email}, emails.SymbioteReceipt, emails.LocaleFrom(ctx), data)
+}
+
+// SendOrderCreatedAdminEmail notifies the admins at recipients (the alert
+// emails, ALERT_EMAILS or ADMIN_ORDER_EMAILS) of a new order
+func SendOrderCreatedAdminEmail(recipients []string, order *models.Order) error {
+	if order == nil {
+		return fmt.Errorf("order is nil")
 	}
 
-	return SendEmailBrevo([]string{email}, subject, bodyHTML)
+	if len(recipients) == 0 {
+		return fmt.Errorf("no admin recipients configured")
+	}
+	return sendTemplate(context.Background(), envSend, recipients, emails.AdminOrderCreated, emails.DefaultLocale, AdminOrderData(order))
 }
 
-// getAdminRecipients returns recipients from env or default list
-func getAdminRecipients() []string {
-	// Allow override via env (comma-separated)
-	env := os.Getenv("ADMIN_ORDER_EMAILS")
-	if env != "" {
-		parts := strings.Split(env, ",")
-		var recipients []string
-		for _, p := range parts {
-			if email := strings.TrimSpace(p); email != "" {
-				recipients = append(recipients, email)
-			}
-		}
-		if len(recipients) > 0 {
-			return recipients
-		}
+// AdminOrderData fills the admin new order email from order
+func AdminOrderData(order *models.Order) emails.AdminOrderCreatedData {
+	shipping := parseShipping(order.ShippingAddress)
+	return emails.AdminOrderCreatedData{
+		OrderNumber: base32.GenerateOrderNumber(order.ID),
+		Total:       int64(order.TotalAmount),
+		Status:      models.OrderStatusName(order.Status),
+		Name:        shipping.Name,
+		Email:       shipping.Email,
+		Phone:       shipping.Phone,
+		Address:     shipping.line(),
+		Items:       parseOrderItems(order.Items),
 	}
-
-	// Default admin emails
-	return []string{"n3r0.corp@gmail.com", "nam.dt161@gmail.com"}
 }
 
-// SendOrderCreatedAdminEmail notifies admins when a new order is placed
-func SendOrderCreatedAdminEmail(order *models.Order) error {
-	if order == nil {
-		return fmt.Errorf("order is nil")
+func sendTransferCodeEmail(ctx context.Context, send emailSendFunc, email, code string, ttl time.Duration) error {
+	if email == "" || code == "" {
+		return fmt.Errorf("missing email or code")
 	}
+	data := emails.TransferCodeData{Code: code, ExpiresIn: int(ttl.Minutes())}
+	return sendTemplate(ctx, send, []string{email}, emails.TransferCode, emails.LocaleFrom(ctx), data)
+}
 
-	recipients := getAdminRecipients()
-	if len(recipients) == 0 {
-		return fmt.Errorf("no admin recipients configured")
+// SendPasswordResetEmail: Send password reset email linking to the storefront at frontendURL
+func SendPasswordResetEmail(frontendURL, email, resetToken string) error {
+	resetURL := fmt.Sprintf("%s/reset-password?token=%s", frontendURL, resetToken)
+	return sendTemplate(context.Background(), envSend, []string{email}, emails.PasswordReset, emails.DefaultLocale, emails.PasswordResetData{ResetURL: resetURL})
+}
+
+// orderShipping is the shipping_address snapshot stored with an order
+type orderShipping struct {
+	Name     string `json:"name"`
+	Email    string `json:"email"`
+	Phone    string `json:"phone"`
+	Address  string `json:"address"`
+	Ward     string `json:"ward"`
+	District string `json:"district"`
+	Province string `json:"province"`
+	Locale   string `json:"locale"`
+
+	raw string // the snapshot as stored, when it is not a JSON object
+}
+
+func parseShipping(raw datatypes.JSON) orderShipping {
+	var s orderShipping
+	if err := json.Unmarshal(raw, &s); err != nil {
+		s.raw = strings.TrimSpace(string(raw))
 	}
+	return s
+}
 
-	// Parse shipping address snapshot
-	address := string(order.ShippingAddress)
-	var addressMap map[string]any
-	if err := json.Unmarshal(order.ShippingAddress, &addressMap); err == nil {
-		var parts []string
-		for _, key := range []string{"name", "email", "phone", "address"} {
-			if val, ok := addressMap[key]; ok && val != nil && strings.TrimSpace(fmt.Sprint(val)) != "" {
-				parts = append(parts, fmt.Sprintf("<strong>%s:</strong> %s", strings.Title(key), fmt.Sprint(val)))
-			}
-		}
-		if len(parts) > 0 {
-			address = strings.Join(parts, "<br/>")
+// line formats the delivery address on one line
+func (s orderShipping) line() string {
+	var parts []string
+	for _, p := range []string{s.Address, s.Ward, s.District, s.Province} {
+		if p = strings.TrimSpace(p); p != "" {
+			parts = append(parts, p)
 		}
 	}
+	if len(parts) == 0 {
+		return s.raw
+	}
+	return strings.Join(parts, ", ")
+}
 
-	var itemsBuilder strings.Builder
-	var orderItems []struct {
+// parseOrderItems reads an order's items snapshot; drop orders name items
+// "name", older orders "product_name"
+func parseOrderItems(raw datatypes.JSON) []emails.OrderItem {
+	var stored []struct {
+		Name        string  `json:"name"`
 		ProductName string  `json:"product_name"`
 		Quantity    int     `json:"quantity"`
 		Price       float64 `json:"price"`
 	}
-	if err := json.Unmarshal(order.Items, &orderItems); err == nil {
-		for _, item := range orderItems {
-			itemsBuilder.WriteString(fmt.Sprintf(
-				"<li>%s x %d - %.0f VND</li>",
-				item.ProductName, item.Quantity, item.Price*float64(item.Quantity),
-			))
+	json.Unmarshal(raw, &stored)
+
+	items := make([]emails.OrderItem, 0, len(stored))
+	for _, item := range stored {
+		name := item.Name
+		if name == "" {
+			name = item.ProductName
 		}
+		items = append(items, emails.OrderItem{Name: name, Quantity: item.Quantity, Price: int64(item.Price)})
 	}
-
-	html := fmt.Sprintf(`
-		<h2>Đơn hàng mới được tạo</h2>
-		<p><strong>Mã đơn:</strong> %s</p>
-		<p><strong>Tổng tiền:</strong> %d VND</p>
-		<p><strong>Trạng thái:</strong> %d</p>
-		<p><strong>Thông tin giao:</strong><br/>%s</p>
-		<p><strong>Sản phẩm:</strong></p>
-		<ul>%s</ul>
-	`, base32.GenerateOrderNumber(order.ID), order.TotalAmount, order.Status, address, itemsBuilder.String())
-
-	subject := fmt.Sprintf("[DW] Đơn hàng mới #%s", base32.GenerateOrderNumber(order.ID))
-	return SendEmailBrevo(recipients, subject, html)
-}
-
-// SendPasswordResetEmail: Send password reset email
-func SendPasswordResetEmail(email, resetToken string) error {
-	resetURL := fmt.Sprintf("%s/reset-password?token=%s", os.Getenv("FRONTEND_URL"), resetToken)
-
-	html := fmt.Sprintf(`
-		<h1>Đặt lại mật khẩu</h1>
-		<p>Bạn đã yêu cầu đặt lại mật khẩu.</p>
-		<p><a href="%s">Click vào đây để đặt lại mật khẩu</a></p>
-		<p>Link này sẽ hết hạn sau 1 giờ.</p>
-	`, resetURL)
-
-	return SendEmailBrevo([]string{email}, "Đặt lại mật khẩu", html)
+	return items
 }
@@ -0,0 +1,74 @@
+// Package sheetstest is an in-memory integrations.SheetsAPI for tests and
+// local development: it keeps the appended rows and can be made to fail.
+package sheetstest
+
+import (
+	"context"
+	"sync"
+
+	"ecommerce-backend/internal/integrations"
+)
+
+var _ integrations.SheetsAPI = (*Sheet)(nil)
+
+// Sheet is a fake spreadsheet
+type Sheet struct {
+	// OnAppend, when set, is called with every accepted batch
+	OnAppend func(rows [][]string)
+
+	mu    sync.Mutex
+	rows  [][]string
+	calls int
+	fails int
+	err   error
+}
+
+// Rows returns the appended rows, oldest first
+func (s *Sheet) Rows() [][]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([][]string, len(s.rows))
+	for i, row := range s.rows {
+		out[i] = append([]string(nil), row...)
+	}
+	return out
+}
+
+// Calls returns how many AppendRows calls were made, failed ones included
+func (s *Sheet) Calls() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls
+}
+
+// FailWith makes the next n calls return err; n < 0 fails until FailWith
+// is called again
+func (s *Sheet) FailWith(n int, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fails, s.err = n, err
+}
+
+// AppendRows implements integrations.SheetsAPI
+func (s *Sheet) AppendRows(ctx context.Context, rows [][]string) error {
+	s.mu.Lock()
+	s.calls++
+	if s.fails != 0 {
+		if s.fails > 0 {
+			s.fails--
+		}
+		err := s.err
+		s.mu.Unlock()
+		return err
+	}
+	for _, row := range rows {
+		s.rows = append(s.rows, append([]string(nil), row...))
+	}
+	onAppend := s.OnAppend
+	s.mu.Unlock()
+
+	if onAppend != nil {
+		onAppend(rows)
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+/**
+ * SMS SERVICE
+ *
+ * Brand-name SMS through eSMS.vn
+ */
+
+package integrations
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"ecommerce-backend/internal/emails"
+)
+
+const (
+	defaultSMSBaseURL = "https://rest.esms.vn"
+	// SMSSendPath is the eSMS endpoint for one message to one or more phones
+	SMSSendPath = "/MainService.svc/json/SendMultipleMessage_V4_post_json/"
+	// smsOK is the CodeResult of an accepted message
+	smsOK = "100"
+)
+
+// SMSRequest is an eSMS send request. SmsType 2 is brand-name customer care.
+type SMSRequest struct {
+	APIKey    string `json:"ApiKey"`
+	SecretKey string `json:"SecretKey"`
+	Phone     string `json:"Phone"`
+	Content   string `json:"Content"`
+	Brandname string `json:"Brandname"`
+	SmsType   string `json:"SmsType"`
+	IsUnicode string `json:"IsUnicode"`
+}
+
+// SMSResponse is the eSMS answer; CodeResult "100" means accepted
+type SMSResponse struct {
+	CodeResult   string `json:"CodeResult"`
+	ErrorMessage string `json:"ErrorMessage"`
+	SMSID        string `json:"SMSID"`
+}
+
+// sendSMS sends content to phone and returns the gateway's SMS ID
+func sendSMS(ctx context.Context, client *http.Client, cfg SMSConfig, phone, content string) (string, error) {
+	if cfg.APIKey == "" {
+		return "", fmt.Errorf("sms api key not configured")
+	}
+
+	body, err := json.Marshal(SMSRequest{
+		APIKey:    cfg.APIKey,
+		SecretKey: cfg.SecretKey,
+		Phone:     phone,
+		Content:   content,
+		Brandname: cfg.BrandName,
+		SmsType:   "2",
+		IsUnicode: "0",
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", orDefault(cfg.BaseURL, defaultSMSBaseURL)+SMSSendPath, bytes.NewBuffer(body))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return "", fmt.Errorf("failed to send sms: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("sms gateway returned status %d", resp.StatusCode)
+	}
+	var result SMSResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("invalid sms gateway response: %w", err)
+	}
+	if result.CodeResult != smsOK {
+		return "", fmt.Errorf("sms gateway refused the message: code %s %s", result.CodeResult, result.ErrorMessage)
+	}
+	return result.SMSID, nil
+}
+
+// smsText is the message for an event. Brand-name SMS is sent without
+// diacritics so each message fits one 160-character segment.
+func smsText(locale emails.Locale, n Notification) (string, error) {
+	amount := emails.FormatMoney(locale, int64(n.Amount))
+	if locale == emails.Vietnamese {
+		amount = strings.ReplaceAll(amount, " ₫", "d")
+	}
+	switch {
+	case n.Event == EventOrderConfirmation && locale == emails.English:
+		return fmt.Sprintf("Donald Watch: order %s is confirmed, total %s. Thank you for shopping with us.", n.OrderNumber, amount), nil
+	case n.Event == EventOrderConfirmation:
+		return fmt.Sprintf("Donald Watch: don hang %s da duoc xac nhan, tong %s. Cam on ban da mua sam.", n.OrderNumber, amount), nil
+	case n.Event == EventDropWon && locale == emails.English:
+		return fmt.Sprintf("Donald Watch: slot secured. Order %s is yours and your ownership is recorded.", n.OrderNumber), nil
+	case n.Event == EventDropWon:
+		return fmt.Sprintf("Donald Watch: ban da gianh suat. Don hang %s thuoc ve ban va quyen so huu da duoc ghi nhan.", n.OrderNumber), nil
+	case n.Event == EventDropLost && locale == emails.English:
+		return fmt.Sprintf("Donald Watch: the drop sold out before your payment for order %s arrived. You will be refunded if charged.", n.OrderNumber), nil
+	case n.Event == EventDropLost:
+		return fmt.Sprintf("Donald Watch: dot drop da het truoc khi thanh toan don %s den. Ban se duoc hoan tien neu da bi tru.", n.OrderNumber), nil
+	case n.Event == EventTransferCode && locale == emails.English:
+		return fmt.Sprintf("Donald Watch: your symbicode transfer code is %s, valid for %d minutes. Do not share it.", n.Code, int(n.CodeTTL.Minutes())), nil
+	case n.Event == EventTransferCode:
+		return fmt.Sprintf("Donald Watch: ma chuyen nhuong symbicode cua ban la %s, hieu luc %d phut. Khong chia se ma nay.", n.Code, int(n.CodeTTL.Minutes())), nil
+	}
+	return "", fmt.Errorf("no sms text for event %q", n.Event)
+}
+
+// NewSMSChannel texts customers through the SMS gateway with the
+// credentials in store (nil reads the environment per call)
+func NewSMSChannel(store *CredentialStore, opts ...Option) NotificationChannel {
+	o := newOptions(opts)
+	return &smsChannel{creds: store, client: newHTTPClient(o.log, ChannelSMS)}
+}
+
+type smsChannel struct {
+	creds  *CredentialStore
+	client *http.Client
+}
+
+func (c *smsChannel) Name() string { return ChannelSMS }
+
+func (c *smsChannel) Accepts(n Notification) bool {
+	return c.creds.credentials().SMS.APIKey != "" && n.Phone != ""
+}
+
+func (c *smsChannel) Notify(ctx context.Context, n Notification) error {
+	text, err := smsText(emails.LocaleFrom(ctx), n)
+	if err != nil {
+		return err
+	}
+	_, err = sendSMS(ctx, c.client, c.creds.credentials().SMS, n.Phone, text)
+	return err
+}
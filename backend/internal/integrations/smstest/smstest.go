@@ -0,0 +1,108 @@
+// Package smstest is a fake eSMS gateway for tests and local development:
+// it accepts sends on integrations.SMSSendPath, keeps the messages in memory
+// and lists them on GET /messages.
+package smstest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+
+	"ecommerce-backend/internal/integrations"
+)
+
+// Message is one SMS the gateway accepted
+type Message struct {
+	Phone     string `json:"phone"`
+	Content   string `json:"content"`
+	BrandName string `json:"brand_name"`
+	SMSID     string `json:"sms_id"`
+}
+
+// Gateway is an http.Handler speaking the eSMS send API
+type Gateway struct {
+	// OnMessage, when set, is called with every accepted message
+	OnMessage func(Message)
+
+	mu       sync.Mutex
+	messages []Message
+	failCode string
+}
+
+// Messages returns the accepted messages, oldest first
+func (g *Gateway) Messages() []Message {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]Message(nil), g.messages...)
+}
+
+// FailWith makes later sends answer CodeResult code ("" accepts again);
+// eSMS uses 99 for an unknown error and 103 for an empty balance
+func (g *Gateway) FailWith(code string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.failCode = code
+}
+
+func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == integrations.SMSSendPath:
+		g.send(w, r)
+	case r.Method == http.MethodGet && r.URL.Path == "/messages":
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(g.Messages())
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (g *Gateway) send(w http.ResponseWriter, r *http.Request) {
+	var req integrations.SMSRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	g.mu.Lock()
+	if g.failCode != "" {
+		code := g.failCode
+		g.mu.Unlock()
+		json.NewEncoder(w).Encode(integrations.SMSResponse{CodeResult: code, ErrorMessage: "fake gateway failure"})
+		return
+	}
+	if req.APIKey == "" || req.Phone == "" {
+		g.mu.Unlock()
+		json.NewEncoder(w).Encode(integrations.SMSResponse{CodeResult: "101", ErrorMessage: "missing ApiKey or Phone"})
+		return
+	}
+	msg := Message{
+		Phone:     req.Phone,
+		Content:   req.Content,
+		BrandName: req.Brandname,
+		SMSID:     "fake-" + strconv.Itoa(len(g.messages)+1),
+	}
+	g.messages = append(g.messages, msg)
+	onMessage := g.OnMessage
+	g.mu.Unlock()
+
+	if onMessage != nil {
+		onMessage(msg)
+	}
+	json.NewEncoder(w).Encode(integrations.SMSResponse{CodeResult: "100", SMSID: msg.SMSID})
+}
+
+// Server is a Gateway listening on a local port; point SMS_BASE_URL (or
+// SMSConfig.BaseURL) at URL
+type Server struct {
+	*httptest.Server
+	*Gateway
+}
+
+// NewServer starts a fake gateway; Close it when done
+func NewServer() *Server {
+	g := &Gateway{}
+	return &Server{Server: httptest.NewServer(g), Gateway: g}
+}
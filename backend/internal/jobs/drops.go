@@ -0,0 +1,28 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"ecommerce-backend/internal/scheduler"
+)
+
+// DropAnnouncer is the service method behind DropStartWebhooks
+type DropAnnouncer interface {
+	AnnounceStartedDrops(ctx context.Context, within time.Duration) (int, error)
+}
+
+// DropStartWebhooks sends drop.started for the drops that started within
+// the last window, on schedule. The window spans missed runs; each drop is
+// announced once.
+func DropStartWebhooks(svc DropAnnouncer, schedule string, window time.Duration) scheduler.Job {
+	return scheduler.Job{
+		Name:     "drop-start-webhooks",
+		Schedule: schedule,
+		Run: func(ctx context.Context) (string, error) {
+			n, err := svc.AnnounceStartedDrops(ctx, window)
+			return fmt.Sprintf("%d drops started within %s", n, window), err
+		},
+	}
+}
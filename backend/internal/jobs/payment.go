@@ -0,0 +1,72 @@
+// Package jobs defines the background jobs carried by the queue package and
+// the handlers that run them against the service layer, plus the periodic
+// jobs registered with the scheduler.
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"ecommerce-backend/internal/logging"
+	"ecommerce-backend/internal/queue"
+	"ecommerce-backend/internal/tracing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = tracing.Tracer("jobs")
+
+// PaymentJob asks a worker to finalize a paid limited-drop order
+type PaymentJob struct {
+	OrderCode int64 `json:"order_code"`
+	// RequestID of the webhook delivery, so worker logs join the request's
+	RequestID string `json:"request_id,omitempty"`
+	// Trace holds the webhook's W3C trace context (traceparent, ...)
+	Trace map[string]string `json:"trace,omitempty"`
+}
+
+// EnqueuePayment queues processing of a PAID webhook for orderCode
+func EnqueuePayment(ctx context.Context, q queue.Queue, orderCode int64) error {
+	job := PaymentJob{OrderCode: orderCode, RequestID: logging.RequestIDFrom(ctx), Trace: map[string]string{}}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.MapCarrier(job.Trace))
+	body, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	return q.Send(ctx, body)
+}
+
+// PaymentProcessor finalizes paid orders; implemented by service.Service
+type PaymentProcessor interface {
+	ProcessSuccessfulDropPayment(ctx context.Context, orderCode int64) error
+}
+
+// PaymentHandler runs ProcessSuccessfulDropPayment for queued PaymentJobs,
+// in a consumer span continuing the webhook's trace.
+// Delivery is at-least-once; the service is idempotent per order.
+func PaymentHandler(svc PaymentProcessor) queue.Handler {
+	return func(ctx context.Context, body []byte) (err error) {
+		var job PaymentJob
+		if err := json.Unmarshal(body, &job); err != nil {
+			return fmt.Errorf("decode payment job: %w", err)
+		}
+		if job.RequestID != "" {
+			ctx = logging.ContextWithRequestID(ctx, job.RequestID)
+		}
+		ctx = otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(job.Trace))
+		ctx, span := tracer.Start(ctx, "PaymentJob",
+			trace.WithSpanKind(trace.SpanKindConsumer),
+			trace.WithAttributes(attribute.Int64("payos.order_code", job.OrderCode)),
+		)
+		defer tracing.End(span, &err)
+
+		if err := svc.ProcessSuccessfulDropPayment(ctx, job.OrderCode); err != nil {
+			return fmt.Errorf("order %d: %w", job.OrderCode, err)
+		}
+		return nil
+	}
+}
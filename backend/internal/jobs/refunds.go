@@ -0,0 +1,26 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+
+	"ecommerce-backend/internal/scheduler"
+)
+
+// Refunder is the service method behind Refunds
+type Refunder interface {
+	ProcessRefunds(ctx context.Context) (int, error)
+}
+
+// Refunds issues the refunds of orders that lost their drop, on schedule.
+// A failed refund waits out its backoff for a later run.
+func Refunds(svc Refunder, schedule string) scheduler.Job {
+	return scheduler.Job{
+		Name:     "refunds",
+		Schedule: schedule,
+		Run: func(ctx context.Context) (string, error) {
+			n, err := svc.ProcessRefunds(ctx)
+			return fmt.Sprintf("issued %d refunds", n), err
+		},
+	}
+}
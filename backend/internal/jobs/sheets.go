@@ -0,0 +1,30 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"ecommerce-backend/internal/integrations"
+	"ecommerce-backend/internal/scheduler"
+)
+
+// SheetSyncer is the export behind SheetSync; implemented by sheetsync.Syncer
+type SheetSyncer interface {
+	Sync(ctx context.Context) (int, error)
+}
+
+// SheetSync exports the queued order rows to the spreadsheet on schedule
+func SheetSync(s SheetSyncer, schedule string) scheduler.Job {
+	return scheduler.Job{
+		Name:     "sheet-sync",
+		Schedule: schedule,
+		Run: func(ctx context.Context) (string, error) {
+			n, err := s.Sync(ctx)
+			if errors.Is(err, integrations.ErrSheetsNotConfigured) {
+				return "no spreadsheet configured", nil
+			}
+			return fmt.Sprintf("exported %d rows", n), err
+		},
+	}
+}
@@ -0,0 +1,27 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"ecommerce-backend/internal/scheduler"
+)
+
+// SymbicodeActivator is the service method behind SymbicodeAutoActivation
+type SymbicodeActivator interface {
+	AutoActivateExpiredSymbicodes(ctx context.Context, olderThan time.Duration) (int, error)
+}
+
+// SymbicodeAutoActivation activates symbicodes never scanned within after
+// of being issued, on schedule
+func SymbicodeAutoActivation(svc SymbicodeActivator, schedule string, after time.Duration) scheduler.Job {
+	return scheduler.Job{
+		Name:     "symbicode-auto-activate",
+		Schedule: schedule,
+		Run: func(ctx context.Context) (string, error) {
+			n, err := svc.AutoActivateExpiredSymbicodes(ctx, after)
+			return fmt.Sprintf("activated %d symbicodes", n), err
+		},
+	}
+}
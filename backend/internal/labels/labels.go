@@ -0,0 +1,189 @@
+// Package labels renders symbicode tags for the factory: a CSV manifest and
+// printable sheets of QR codes (SVG or PDF) laid out on A4 paper.
+package labels
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/go-pdf/fpdf"
+	"github.com/skip2/go-qrcode"
+)
+
+// ContentTypes lists the export formats accepted by Write
+var ContentTypes = map[string]string{
+	"csv": "text/csv; charset=utf-8",
+	"svg": "image/svg+xml",
+	"pdf": "application/pdf",
+}
+
+// Write renders labels as csv, or as an A4 svg or pdf sheet
+func Write(w io.Writer, format string, labels []Label) error {
+	switch format {
+	case "csv":
+		return WriteCSV(w, labels)
+	case "svg":
+		return WriteSVG(w, labels, A4)
+	case "pdf":
+		return WritePDF(w, labels, A4)
+	default:
+		return fmt.Errorf("unknown label format %q (csv, svg or pdf)", format)
+	}
+}
+
+// Label is one printed tag
+type Label struct {
+	ID    uint64
+	Code  string // UUID, for the manifest and the caption
+	Token string // signed token encoded in URL
+	URL   string // QR payload
+}
+
+// Caption is the short text printed under the QR code
+func (l Label) Caption() string {
+	code := l.Code
+	if len(code) > 8 {
+		code = code[:8]
+	}
+	return fmt.Sprintf("#%d %s", l.ID, code)
+}
+
+// Sheet is a page layout in millimetres: a grid of square cells inside the margin
+type Sheet struct {
+	PageWidth  float64
+	PageHeight float64
+	Margin     float64
+	Cell       float64
+}
+
+// A4 fits 5 x 7 tags of 38 mm
+var A4 = Sheet{PageWidth: 210, PageHeight: 297, Margin: 10, Cell: 38}
+
+func (s Sheet) columns() int { return int((s.PageWidth - 2*s.Margin) / s.Cell) }
+func (s Sheet) rows() int    { return int((s.PageHeight - 2*s.Margin) / s.Cell) }
+
+// PerPage is how many labels fit on one page
+func (s Sheet) PerPage() int { return s.columns() * s.rows() }
+
+// place returns the page and the top-left corner of the i-th cell
+func (s Sheet) place(i int) (page int, x, y float64) {
+	page, i = i/s.PerPage(), i%s.PerPage()
+	return page, s.Margin + float64(i%s.columns())*s.Cell, s.Margin + float64(i/s.columns())*s.Cell
+}
+
+// qrSize is the QR code width within a cell; the rest holds the caption
+func (s Sheet) qrSize() float64 { return s.Cell * 0.75 }
+
+// modules encodes url as a QR matrix without the quiet zone
+func modules(url string) ([][]bool, error) {
+	q, err := qrcode.New(url, qrcode.Medium)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %q: %w", url, err)
+	}
+	q.DisableBorder = true
+	return q.Bitmap(), nil
+}
+
+// runs calls fn for every horizontal run of dark modules
+func runs(bitmap [][]bool, fn func(row, col, length int)) {
+	for r, line := range bitmap {
+		for c := 0; c < len(line); {
+			if !line[c] {
+				c++
+				continue
+			}
+			start := c
+			for c < len(line) && line[c] {
+				c++
+			}
+			fn(r, start, c-start)
+		}
+	}
+}
+
+// WriteCSV writes the manifest: id, code, token and QR payload per label
+func WriteCSV(w io.Writer, labels []Label) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"id", "code", "token", "url"})
+	for _, l := range labels {
+		cw.Write([]string{strconv.FormatUint(l.ID, 10), l.Code, l.Token, l.URL})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteSVG writes all labels as one SVG document with the pages stacked
+// vertically, sized in millimetres so it prints at scale
+func WriteSVG(w io.Writer, labels []Label, sheet Sheet) error {
+	pages := (len(labels) + sheet.PerPage() - 1) / sheet.PerPage()
+	if pages == 0 {
+		pages = 1
+	}
+	height := float64(pages) * sheet.PageHeight
+
+	if _, err := fmt.Fprintf(w, `<svg xmlns="http://www.w3.org/2000/svg" width="%gmm" height="%gmm" viewBox="0 0 %g %g">`+"\n",
+		sheet.PageWidth, height, sheet.PageWidth, height); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, `<rect width="%g" height="%g" fill="#fff"/>`+"\n", sheet.PageWidth, height)
+
+	size := sheet.qrSize()
+	for i, l := range labels {
+		bitmap, err := modules(l.URL)
+		if err != nil {
+			return err
+		}
+		page, x, y := sheet.place(i)
+		y += float64(page) * sheet.PageHeight
+		x += (sheet.Cell - size) / 2
+		m := size / float64(len(bitmap))
+
+		fmt.Fprintf(w, `<g transform="translate(%.3f %.3f) scale(%.5f)"><path fill="#000" d="`, x, y, m)
+		runs(bitmap, func(r, c, n int) {
+			fmt.Fprintf(w, "M%d %dh%dv1h-%dz", c, r, n, n)
+		})
+		fmt.Fprint(w, `"/></g>`+"\n")
+		fmt.Fprintf(w, `<text x="%.3f" y="%.3f" font-family="monospace" font-size="3" text-anchor="middle">%s</text>`+"\n",
+			x+size/2, y+size+4, l.Caption())
+	}
+
+	_, err := fmt.Fprint(w, "</svg>\n")
+	return err
+}
+
+// WritePDF writes the labels as an A4 PDF, one page per sheet
+func WritePDF(w io.Writer, labels []Label, sheet Sheet) error {
+	pdf := fpdf.NewCustom(&fpdf.InitType{
+		UnitStr: "mm",
+		Size:    fpdf.SizeType{Wd: sheet.PageWidth, Ht: sheet.PageHeight},
+	})
+	pdf.SetAutoPageBreak(false, 0)
+	pdf.SetFont("Courier", "", 8)
+	pdf.SetFillColor(0, 0, 0)
+
+	size := sheet.qrSize()
+	for i, l := range labels {
+		bitmap, err := modules(l.URL)
+		if err != nil {
+			return err
+		}
+		page, x, y := sheet.place(i)
+		if page >= pdf.PageCount() {
+			pdf.AddPage()
+		}
+		x += (sheet.Cell - size) / 2
+		m := size / float64(len(bitmap))
+
+		runs(bitmap, func(r, c, n int) {
+			pdf.Rect(x+float64(c)*m, y+float64(r)*m, float64(n)*m, m, "F")
+		})
+		pdf.SetXY(x, y+size+1)
+		pdf.CellFormat(size, 4, l.Caption(), "", 0, "C", false, 0, "")
+	}
+	if pdf.PageCount() == 0 {
+		pdf.AddPage()
+	}
+	return pdf.Output(w)
+}
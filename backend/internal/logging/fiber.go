@@ -0,0 +1,52 @@
+package logging
+
+import (
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// Middleware assigns every request an ID (reusing a valid incoming
+// X-Request-ID), echoes it in the response, stores it in c.Context() for
+// service calls, and writes one access log line per request.
+func Middleware(l *slog.Logger) fiber.Handler {
+	if l == nil {
+		l = slog.Default()
+	}
+	return func(c fiber.Ctx) error {
+		start := time.Now()
+
+		id := c.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = NewRequestID()
+		}
+		c.Set(RequestIDHeader, id)
+		ctx := ContextWithRequestID(c.Context(), id)
+		c.SetContext(ctx)
+
+		err := c.Next()
+
+		status := c.Response().StatusCode()
+		if err != nil {
+			status = fiber.StatusInternalServerError
+			var fe *fiber.Error
+			if errors.As(err, &fe) {
+				status = fe.Code
+			}
+		}
+		level := slog.LevelInfo
+		if status >= 500 {
+			level = slog.LevelError
+		}
+		l.Log(ctx, level, "request",
+			"method", c.Method(),
+			"path", c.Path(),
+			"status", status,
+			"duration", time.Since(start),
+			"ip", c.IP(),
+		)
+		return err
+	}
+}
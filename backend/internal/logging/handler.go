@@ -0,0 +1,96 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// handler adds request and trace IDs, redacts PII and applies the subsystem level
+type handler struct {
+	inner  slog.Handler
+	levels *levels
+	level  slog.Level
+}
+
+func (h *handler) Enabled(ctx context.Context, l slog.Level) bool {
+	return l >= h.level
+}
+
+func (h *handler) Handle(ctx context.Context, r slog.Record) error {
+	out := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	if id := RequestIDFrom(ctx); id != "" {
+		out.AddAttrs(slog.String(RequestIDKey, id))
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsSampled() {
+		out.AddAttrs(slog.String("trace_id", sc.TraceID().String()), slog.String("span_id", sc.SpanID().String()))
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		out.AddAttrs(Redact(a))
+		return true
+	})
+	return h.inner.Handle(ctx, out)
+}
+
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	level := h.level
+	redacted := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		if a.Key == SubsystemKey {
+			level = h.levels.forSubsystem(a.Value.String())
+		}
+		redacted[i] = Redact(a)
+	}
+	return &handler{inner: h.inner.WithAttrs(redacted), levels: h.levels, level: level}
+}
+
+func (h *handler) WithGroup(name string) slog.Handler {
+	return &handler{inner: h.inner.WithGroup(name), levels: h.levels, level: h.level}
+}
+
+// Redact masks customer PII by attribute key, recursing into groups:
+// *phone keeps the last 3 digits, *email keeps the first letter and the
+// domain, *address and customer_name are replaced entirely.
+func Redact(a slog.Attr) slog.Attr {
+	if a.Value.Kind() == slog.KindGroup {
+		group := a.Value.Group()
+		out := make([]any, len(group))
+		for i, g := range group {
+			out[i] = Redact(g)
+		}
+		return slog.Group(a.Key, out...)
+	}
+
+	key := strings.ToLower(a.Key)
+	switch {
+	case strings.HasSuffix(key, "phone"):
+		return slog.String(a.Key, MaskPhone(a.Value.String()))
+	case strings.HasSuffix(key, "email"):
+		return slog.String(a.Key, MaskEmail(a.Value.String()))
+	case strings.HasSuffix(key, "address"), key == "customer_name":
+		if a.Value.String() == "" {
+			return a
+		}
+		return slog.String(a.Key, "[redacted]")
+	}
+	return a
+}
+
+// MaskPhone keeps the last 3 characters: 0901234567 -> *******567
+func MaskPhone(phone string) string {
+	if len(phone) <= 3 {
+		return strings.Repeat("*", len(phone))
+	}
+	return strings.Repeat("*", len(phone)-3) + phone[len(phone)-3:]
+}
+
+// MaskEmail keeps the first character and the domain: john@example.com -> j***@example.com
+func MaskEmail(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at < 1 {
+		return strings.Repeat("*", len(email))
+	}
+	return email[:1] + "***" + email[at:]
+}
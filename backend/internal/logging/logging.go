@@ -0,0 +1,103 @@
+// Package logging builds the structured (log/slog) logger shared by the
+// server: per-subsystem levels, request IDs taken from the context, and
+// redaction of customer PII (phone, email, address) in attributes.
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// SubsystemKey is the attribute that selects a per-subsystem level
+const SubsystemKey = "subsystem"
+
+// Options configures New
+type Options struct {
+	// Level is the default level: debug, info, warn or error
+	Level string
+	// Format is "text" (default) or "json"
+	Format string
+	// Levels overrides Level per subsystem, e.g. {"payos": "debug", "queue": "warn"}
+	Levels map[string]string
+}
+
+// New returns a logger writing to w. Loggers derived with Subsystem use the
+// level configured for that subsystem.
+func New(w io.Writer, opts Options) (*slog.Logger, error) {
+	lv := &levels{bySubsystem: map[string]slog.Level{}}
+	if err := lv.def.UnmarshalText([]byte(orDefault(opts.Level, "info"))); err != nil {
+		return nil, fmt.Errorf("log level %q: %w", opts.Level, err)
+	}
+	min := lv.def
+	for name, s := range opts.Levels {
+		var l slog.Level
+		if err := l.UnmarshalText([]byte(s)); err != nil {
+			return nil, fmt.Errorf("log level for %s %q: %w", name, s, err)
+		}
+		lv.bySubsystem[name] = l
+		if l < min {
+			min = l
+		}
+	}
+
+	// The inner handler only has to drop what no subsystem wants
+	hopts := &slog.HandlerOptions{Level: min}
+	var inner slog.Handler
+	switch strings.ToLower(orDefault(opts.Format, "text")) {
+	case "text":
+		inner = slog.NewTextHandler(w, hopts)
+	case "json":
+		inner = slog.NewJSONHandler(w, hopts)
+	default:
+		return nil, fmt.Errorf("log format %q: must be text or json", opts.Format)
+	}
+	return slog.New(&handler{inner: inner, levels: lv, level: lv.def}), nil
+}
+
+// ParseLevels parses "name=level" pairs (LOG_LEVELS=payos=debug,queue=warn)
+func ParseLevels(pairs []string) (map[string]string, error) {
+	out := make(map[string]string, len(pairs))
+	for _, p := range pairs {
+		name, level, ok := strings.Cut(p, "=")
+		name, level = strings.TrimSpace(name), strings.TrimSpace(level)
+		if !ok || name == "" || level == "" {
+			return nil, fmt.Errorf("%q: expected subsystem=level", p)
+		}
+		var l slog.Level
+		if err := l.UnmarshalText([]byte(level)); err != nil {
+			return nil, fmt.Errorf("%q: %w", p, err)
+		}
+		out[name] = level
+	}
+	return out, nil
+}
+
+// Subsystem returns l tagged with subsystem=name, logging at that subsystem's level
+func Subsystem(l *slog.Logger, name string) *slog.Logger {
+	if l == nil {
+		l = slog.Default()
+	}
+	return l.With(SubsystemKey, name)
+}
+
+// levels holds the default and per-subsystem levels of a logger tree
+type levels struct {
+	def         slog.Level
+	bySubsystem map[string]slog.Level
+}
+
+func (lv *levels) forSubsystem(name string) slog.Level {
+	if l, ok := lv.bySubsystem[name]; ok {
+		return l
+	}
+	return lv.def
+}
+
+func orDefault(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
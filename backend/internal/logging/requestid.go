@@ -0,0 +1,96 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"ecommerce-backend/internal/utils/uuid"
+)
+
+const (
+	// RequestIDHeader carries request IDs in and out of the server
+	RequestIDHeader = "X-Request-ID"
+	// RequestIDKey is the log attribute holding the request ID
+	RequestIDKey = "request_id"
+)
+
+type requestIDKey struct{}
+
+// ContextWithRequestID returns ctx carrying id; loggers and outbound
+// requests made with the context pick it up
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFrom returns the request ID carried by ctx, or ""
+func RequestIDFrom(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// NewRequestID returns a time-ordered unique ID (UUIDv7)
+func NewRequestID() string {
+	b, err := uuid.GenerateUUIDv7()
+	if err != nil {
+		return ""
+	}
+	return uuid.FormatUUIDToString(b)
+}
+
+// validRequestID accepts short printable IDs from clients and proxies so a
+// caller cannot inject log lines or huge values
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, r := range id {
+		if r < 0x21 || r > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// Transport propagates the context's request ID to outbound requests
+// (PayOS, Brevo, ...) and logs each call at debug level
+func Transport(base http.RoundTripper, l *slog.Logger) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{base: base, log: l}
+}
+
+type transport struct {
+	base http.RoundTripper
+	log  *slog.Logger
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	if id := RequestIDFrom(ctx); id != "" && req.Header.Get(RequestIDHeader) == "" {
+		req = req.Clone(ctx) // RoundTrippers must not modify the caller's request
+		req.Header.Set(RequestIDHeader, id)
+	}
+
+	l := t.log
+	if l == nil {
+		l = slog.Default()
+	}
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		l.WarnContext(ctx, "outbound request failed",
+			"method", req.Method, "host", req.URL.Host, "path", req.URL.Path,
+			"duration", time.Since(start), "error", err)
+		return nil, err
+	}
+	l.DebugContext(ctx, "outbound request",
+		"method", req.Method, "host", req.URL.Host, "path", req.URL.Path,
+		"status", resp.StatusCode, "duration", time.Since(start))
+	return resp, nil
+}
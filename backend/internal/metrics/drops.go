@@ -0,0 +1,51 @@
+package metrics
+
+import (
+	"log/slog"
+	"strconv"
+
+	"ecommerce-backend/internal/logging"
+	"ecommerce-backend/internal/models"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	dropSoldDesc = prometheus.NewDesc(namespace+"_drop_sold",
+		"Units sold per active drop.", []string{"drop_id"}, nil)
+	dropStockDesc = prometheus.NewDesc(namespace+"_drop_total_stock",
+		"Total stock per active drop.", []string{"drop_id"}, nil)
+	dropSizeDesc = prometheus.NewDesc(namespace+"_drop_size",
+		"Sell limit per active drop.", []string{"drop_id"}, nil)
+)
+
+// dropCollector reads active drops at scrape time so the gauges always match
+// the database, including sales made by other instances
+type dropCollector struct {
+	list func() ([]models.LimitedDrop, error)
+}
+
+// RegisterDrops exports sold/stock/size gauges for the drops returned by list
+func RegisterDrops(list func() ([]models.LimitedDrop, error)) {
+	Registry.MustRegister(dropCollector{list: list})
+}
+
+func (c dropCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- dropSoldDesc
+	ch <- dropStockDesc
+	ch <- dropSizeDesc
+}
+
+func (c dropCollector) Collect(ch chan<- prometheus.Metric) {
+	drops, err := c.list()
+	if err != nil {
+		slog.Error("list drops failed", logging.SubsystemKey, "metrics", "error", err)
+		return
+	}
+	for _, d := range drops {
+		id := strconv.FormatUint(d.ID, 10)
+		ch <- prometheus.MustNewConstMetric(dropSoldDesc, prometheus.GaugeValue, float64(d.Sold), id)
+		ch <- prometheus.MustNewConstMetric(dropStockDesc, prometheus.GaugeValue, float64(d.TotalStock), id)
+		ch <- prometheus.MustNewConstMetric(dropSizeDesc, prometheus.GaugeValue, float64(d.DropSize), id)
+	}
+}
@@ -0,0 +1,36 @@
+package metrics
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// Middleware records donald_http_request_duration_seconds. Routes are labelled
+// by their registered pattern (/api/drops/:id/purchase), never the raw path,
+// to keep label cardinality bounded.
+func Middleware() fiber.Handler {
+	return func(c fiber.Ctx) error {
+		start := time.Now()
+		err := c.Next()
+
+		status := c.Response().StatusCode()
+		if err != nil {
+			// The error handler has not run yet; report the status it will send
+			status = fiber.StatusInternalServerError
+			var fe *fiber.Error
+			if errors.As(err, &fe) {
+				status = fe.Code
+			}
+		}
+		route := "unmatched"
+		if c.Matched() {
+			route = c.FullPath()
+		}
+		HTTPRequestDuration.WithLabelValues(c.Method(), route, strconv.Itoa(status)).
+			Observe(time.Since(start).Seconds())
+		return err
+	}
+}
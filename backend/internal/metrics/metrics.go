@@ -0,0 +1,202 @@
+// Package metrics exposes Prometheus metrics for drops, payments and the
+// database. Collectors live on a dedicated registry served by Handler.
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "donald"
+
+// Registry holds every metric served on /metrics
+var Registry = prometheus.NewRegistry()
+
+var (
+	// HTTPRequestDuration observes request latency per route pattern
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by method, route pattern and status code.",
+		Buckets:   []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+	}, []string{"method", "route", "status"})
+
+	// PurchaseAttempts counts drop purchase attempts by outcome
+	PurchaseAttempts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "drop_purchase_attempts_total",
+		Help:      "Drop purchase attempts by drop and outcome (ok, sold_out, size_limit, not_started, ended, not_active, not_found, error).",
+	}, []string{"drop_id", "outcome"})
+
+	// WebhookResults counts PayOS webhook deliveries by result
+	WebhookResults = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "payos_webhooks_total",
+		Help:      "PayOS webhook deliveries by result (queued, processed, not_paid, missing_signature, invalid_signature, invalid_payload, enqueue_failed, error).",
+	}, []string{"result"})
+
+	// PaymentResults counts finalized payments by result
+	PaymentResults = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "payments_processed_total",
+		Help:      "Paid orders processed by result (winner, sold_out, duplicate, error).",
+	}, []string{"result"})
+
+	// PaymentDuration observes how long finalizing a paid order takes
+	PaymentDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "payment_processing_duration_seconds",
+		Help:      "Time to finalize a paid order, including the write transaction.",
+		Buckets:   prometheus.DefBuckets,
+	})
+
+	// SQLiteBusyErrors counts SQLITE_BUSY/SQLITE_LOCKED errors by operation
+	SQLiteBusyErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "sqlite_busy_errors_total",
+		Help:      "SQLite busy/locked errors by operation (exec, query, begin, tx, commit).",
+	}, []string{"op"})
+
+	// SymbicodeVerifications counts verification attempts by result
+	SymbicodeVerifications = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "symbicode_verifications_total",
+		Help:      "Symbicode verifications by result (valid, suspicious, forged, unknown, unsigned, malformed, error).",
+	}, []string{"result"})
+
+	// SymbicodeTransfers counts resale transfers by event
+	SymbicodeTransfers = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "symbicode_transfers_total",
+		Help:      "Symbicode ownership transfers by event (code_sent, code_rejected, offered, claimed).",
+	}, []string{"event"})
+
+	// RateLimited counts requests rejected with 429 by route and bucket key
+	RateLimited = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limited_total",
+		Help:      "Requests rejected by rate limiting, by route and the key of the exhausted bucket (ip, phone, fingerprint).",
+	}, []string{"route", "key"})
+
+	// BotChecks counts proof-of-work and CAPTCHA checks by result
+	BotChecks = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "bot_checks_total",
+		Help:      "Bot checks on drop purchases by kind (pow, captcha) and result (passed, missing, invalid, error).",
+	}, []string{"kind", "result"})
+
+	// SchedulerRuns counts periodic job runs by job and status
+	SchedulerRuns = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "scheduler_runs_total",
+		Help:      "Scheduled job slots by job and status (ok, error, skipped when another instance claimed the slot).",
+	}, []string{"job", "status"})
+
+	// EmailSends counts email send attempts by provider and result
+	EmailSends = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "email_sends_total",
+		Help:      "Email send attempts by provider (brevo, resend) and result (ok, error, quota); a failover shows as an error followed by an ok on the next provider. Messages with every recipient suppressed count as provider none, result suppressed.",
+	}, []string{"provider", "result"})
+
+	// EmailEvents counts delivery webhooks by provider and status
+	EmailEvents = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "email_events_total",
+		Help:      "Delivery webhook events by provider and status (delivered, deferred, bounced, complained, or rejected for a bad signature).",
+	}, []string{"provider", "status"})
+
+	// Notifications counts customer notifications by channel, event and result
+	Notifications = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "notifications_total",
+		Help:      "Customer notifications by channel (email, sms, zalo), event and result (ok, error, or skipped when the channel is unconfigured or lacks the contact); a fallback shows as an error or skip followed by the next channel.",
+	}, []string{"channel", "event", "result"})
+
+	// AdminAlerts counts admin alerts by kind and result
+	AdminAlerts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "admin_alerts_total",
+		Help:      "Admin alerts by kind (order_paid, refund_failed, webhook_signature, drop_sold_out) and result (sent, error when no sink accepted it, throttled).",
+	}, []string{"kind", "result"})
+
+	// SheetRows counts order rows sent to the order spreadsheet by result
+	SheetRows = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "sheet_rows_total",
+		Help:      "Order rows sent to the order spreadsheet by result (exported, or failed after every retry; failed rows stay queued).",
+	}, []string{"result"})
+
+	// WebhookDeliveries counts outbound webhook POSTs by event and result
+	WebhookDeliveries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "webhook_deliveries_total",
+		Help:      "Outbound webhook POSTs by event and result (delivered, retry when another attempt is scheduled, failed after the last attempt).",
+	}, []string{"event", "result"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequestDuration,
+		PurchaseAttempts,
+		WebhookResults,
+		PaymentResults,
+		PaymentDuration,
+		SQLiteBusyErrors,
+		SymbicodeVerifications,
+		SymbicodeTransfers,
+		RateLimited,
+		BotChecks,
+		SchedulerRuns,
+		EmailSends,
+		EmailEvents,
+		Notifications,
+		AdminAlerts,
+		SheetRows,
+		WebhookDeliveries,
+	)
+}
+
+// Handler serves the registry in the Prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// RegisterDB exports connection pool stats for db (go_sql_*{db_name=name}).
+// go_sql_wait_duration_seconds_total{db_name="writer"} is the time requests
+// spent queued for the single SQLite writer connection.
+func RegisterDB(name string, db *sql.DB) {
+	Registry.MustRegister(collectors.NewDBStatsCollector(db, name))
+}
+
+// Depther reports the number of messages waiting in a queue
+type Depther interface {
+	Depth(ctx context.Context) (int, error)
+}
+
+// RegisterQueueDepth exports the depth of q as donald_queue_depth{queue=name}.
+// Depth is read at scrape time; failures report -1.
+func RegisterQueueDepth(name string, q Depther) {
+	Registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace:   namespace,
+		Name:        "queue_depth",
+		Help:        "Messages waiting in a job queue (payment outbox and its dead-letter queue); -1 if unknown.",
+		ConstLabels: prometheus.Labels{"queue": name},
+	}, func() float64 {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		n, err := q.Depth(ctx)
+		if err != nil {
+			return -1
+		}
+		return float64(n)
+	}))
+}
@@ -15,6 +15,24 @@ const (
 	OrderCancelled uint8 = 8 // Đã hủy
 )
 
+// OrderStatusName names an order status for API responses and emails
+func OrderStatusName(status uint8) string {
+	switch status {
+	case OrderPending:
+		return "pending"
+	case OrderConfirmed:
+		return "confirmed"
+	case OrderPaid:
+		return "paid"
+	case OrderDelivered:
+		return "delivered"
+	case OrderCancelled:
+		return "cancelled"
+	default:
+		return "unknown"
+	}
+}
+
 const (
 	PaymentCod uint8 = iota // 0
 	PaymentQR               // 1
@@ -82,11 +100,223 @@ type LimitedDrop struct {
 type Symbicode struct {
 	CreatedAt   time.Time  `gorm:"index"`
 	ActivatedAt *time.Time `gorm:"index" db:"activated_at"`
-	SecretKey   string     `gorm:"not null" db:"secret_key"`
+	BoundAt     *time.Time `db:"bound_at"`                            // when a pre-generated code was bound to its order
+	SecretKey   string     `gorm:"not null" db:"secret_key" json:"-"` // signs Token; never served
 	ActivatedIP string     `gorm:"index" db:"activated_ip"`
+	Token       string     `gorm:"-" db:"-"` // signed label payload, derived from Code and SecretKey
 	Code        []byte     `gorm:"type:uuid;uniqueIndex;not null" db:"code"`
 	ID          uint64     `gorm:"primaryKey"`
 	OrderID     uint64     `gorm:"index" db:"order_id"`
 	ProductID   uint64     `gorm:"index" db:"product_id"`
+	BatchID     uint64     `gorm:"default:0;index" db:"batch_id"` // 0 unless pre-generated for printing
 	IsActivated uint8      `gorm:"default:0;index" db:"is_activated"`
 }
+
+// SYMBICODE BATCH - Codes pre-generated in one run for printing on tags
+type SymbicodeBatch struct {
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	Note      string    `db:"note" json:"note,omitempty"`
+	ID        uint64    `gorm:"primaryKey" json:"id"`
+	ProductID uint64    `gorm:"index" db:"product_id" json:"product_id"`
+	Count     int       `db:"count" json:"count"`
+}
+
+// SYMBICODE SCAN - One verification of a known symbicode (first and repeat scans)
+type SymbicodeScan struct {
+	ScannedAt   time.Time `gorm:"index" db:"scanned_at" json:"scanned_at"`
+	IP          string    `gorm:"index" db:"ip" json:"ip"`
+	UserAgent   string    `db:"user_agent" json:"user_agent"`
+	Country     string    `db:"country" json:"country,omitempty"` // ISO code from the local GeoIP database
+	City        string    `db:"city" json:"city,omitempty"`
+	ID          uint64    `gorm:"primaryKey" json:"id"`
+	SymbicodeID uint64    `gorm:"index" db:"symbicode_id" json:"symbicode_id"`
+}
+
+// SYMBICODE TRANSFER - Resale handover offered by the current owner. The
+// claimer's contact becomes the owner once ClaimedAt is set; the order's
+// contact owns a code that was never transferred.
+type SymbicodeTransfer struct {
+	CreatedAt   time.Time  `db:"created_at"`
+	ExpiresAt   time.Time  `db:"expires_at"`
+	ClaimedAt   *time.Time `gorm:"index" db:"claimed_at"`
+	CanceledAt  *time.Time `db:"canceled_at"`                            // superseded by a newer offer
+	TokenHash   string     `gorm:"uniqueIndex;not null" db:"token_hash"` // SHA-256 of the one-time claim token
+	OwnerPhone  string     `db:"owner_phone"`                            // set on claim
+	OwnerEmail  string     `db:"owner_email"`
+	ID          uint64     `gorm:"primaryKey"`
+	SymbicodeID uint64     `gorm:"index" db:"symbicode_id"`
+}
+
+// SYMBICODE TRANSFER CODE - One-time code sent to the current owner's phone
+// or email before a transfer offer is created. It is checked against the
+// channel and contact it was sent to, and dies after a few wrong guesses.
+type SymbicodeTransferCode struct {
+	CreatedAt   time.Time  `db:"created_at"`
+	ExpiresAt   time.Time  `db:"expires_at"`
+	UsedAt      *time.Time `db:"used_at"`                 // entered, or superseded by a newer code
+	Channel     string     `gorm:"not null" db:"channel"` // email or sms
+	Contact     string     `gorm:"not null" db:"contact"` // normalized address the code went to
+	CodeHash    string     `gorm:"not null" db:"code_hash"`
+	ID          uint64     `gorm:"primaryKey"`
+	SymbicodeID uint64     `gorm:"index" db:"symbicode_id"`
+	Attempts    int        `gorm:"not null;default:0" db:"attempts"` // wrong codes entered
+}
+
+// ORDER LOOKUP TOKEN - One-time link emailed to an order's address so the
+// buyer can view the order without the second factor
+type OrderLookupToken struct {
+	CreatedAt time.Time  `db:"created_at"`
+	ExpiresAt time.Time  `db:"expires_at"`
+	UsedAt    *time.Time `db:"used_at"`
+	TokenHash string     `gorm:"uniqueIndex;not null" db:"token_hash"` // SHA-256 of the emailed token
+	ID        uint64     `gorm:"primaryKey"`
+	OrderID   uint64     `gorm:"index" db:"order_id"`
+}
+
+// Refund states
+const (
+	RefundPending  = "pending"  // waiting for its next attempt
+	RefundRefunded = "refunded" // accepted by PayOS
+	RefundFailed   = "failed"   // attempts exhausted, an admin settles it by hand
+)
+
+// REFUND - A PayOS refund owed for a paid order that lost its drop. It is
+// written with the order's cancellation and retried until PayOS accepts it
+// or the attempts run out.
+type Refund struct {
+	CreatedAt     time.Time  `db:"created_at"`
+	NextAttemptAt time.Time  `gorm:"index:idx_refunds_due" db:"next_attempt_at"`
+	RefundedAt    *time.Time `db:"refunded_at"`
+	Status        string     `gorm:"not null;index:idx_refunds_due" db:"status"`
+	Reason        string     `gorm:"not null" db:"reason"`
+	LastError     string     `db:"last_error"` // of the last failed attempt
+	ID            uint64     `gorm:"primaryKey"`
+	OrderID       uint64     `gorm:"not null;uniqueIndex" db:"order_id"`
+	OrderCode     int64      `gorm:"not null" db:"order_code"` // PayOS order code
+	Attempts      int        `gorm:"not null;default:0" db:"attempts"`
+}
+
+// Email delivery states, from the send through the provider's webhooks
+const (
+	EmailSent       = "sent"       // accepted by the provider
+	EmailDeferred   = "deferred"   // soft bounce or delay, the provider retries
+	EmailDelivered  = "delivered"  // accepted by the recipient's mail server
+	EmailBounced    = "bounced"    // hard bounce, invalid or blocked address
+	EmailComplained = "complained" // marked as spam by the recipient
+)
+
+// EMAIL MESSAGE - One recipient of an email a provider accepted; Status is
+// advanced by the provider's delivery webhooks
+type EmailMessage struct {
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+	Provider  string    `gorm:"index:idx_email_messages_provider_message" db:"provider" json:"provider"`
+	MessageID string    `gorm:"index:idx_email_messages_provider_message" db:"message_id" json:"message_id"`
+	Email     string    `gorm:"index" db:"email" json:"email"`
+	Template  string    `db:"template" json:"template"`
+	Status    string    `db:"status" json:"status"`
+	Detail    string    `db:"detail" json:"detail,omitempty"` // bounce or deferral reason
+	ID        uint64    `gorm:"primaryKey" json:"id"`
+	OrderID   uint64    `gorm:"index" db:"order_id" json:"order_id,omitempty"` // 0 for emails not about an order
+}
+
+// EMAIL SUPPRESSION - Address that hard-bounced or complained; sends to it
+// are skipped until support removes it
+type EmailSuppression struct {
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	Email     string    `gorm:"primaryKey" db:"email" json:"email"` // lower-cased
+	Reason    string    `db:"reason" json:"reason"`                 // EmailBounced or EmailComplained
+	Provider  string    `db:"provider" json:"provider"`
+	MessageID string    `db:"message_id" json:"message_id,omitempty"`
+	Detail    string    `db:"detail" json:"detail,omitempty"`
+}
+
+// SymbicodeScanStats summarizes the scans of one symbicode; locations are
+// distinct country/city pairs of scans with a known location
+type SymbicodeScanStats struct {
+	FirstScanAt       time.Time `json:"first_scan_at"`
+	LastScanAt        time.Time `json:"last_scan_at"`
+	Code              []byte    `json:"-"`
+	SymbicodeID       uint64    `json:"symbicode_id"`
+	ProductID         uint64    `json:"product_id"`
+	OrderID           uint64    `json:"order_id"`
+	Scans             int       `json:"scans"`
+	DistinctIPs       int       `json:"distinct_ips"`
+	DistinctLocations int       `json:"distinct_locations"`
+}
+
+// AutoActivatedIP marks symbicodes activated by the scheduled job instead of a scan
+const AutoActivatedIP = "AUTO_ACTIVATED"
+
+// 6. AUDIT LOG - Changes made outside a customer request (scheduled jobs, admin actions)
+type AuditEntry struct {
+	CreatedAt time.Time      `gorm:"index" db:"created_at" json:"created_at"`
+	Details   datatypes.JSON `gorm:"type:jsonb" db:"details" json:"details"`
+	Actor     string         `gorm:"index" db:"actor" json:"actor"`   // scheduler, admin
+	Action    string         `gorm:"index" db:"action" json:"action"` // symbicode.auto_activate
+	ID        uint64         `gorm:"primaryKey" json:"id"`
+}
+
+// 7. SCHEDULER JOB - Lease and last run of a periodic job, shared by all instances.
+// Times are unix milliseconds; 0 means never.
+type SchedulerJob struct {
+	Name           string `gorm:"primaryKey" db:"name"`
+	Owner          string `db:"owner"`       // instance holding the lease
+	LeaseUntil     int64  `db:"lease_until"` // the lease is free after this
+	LastSlot       int64  `db:"last_slot"`   // scheduled time of the last claimed run
+	LastStartedAt  int64  `db:"last_started_at"`
+	LastFinishedAt int64  `db:"last_finished_at"`
+	LastStatus     string `db:"last_status"` // ok, error
+	LastResult     string `db:"last_result"`
+	LastError      string `db:"last_error"`
+}
+
+// SHEET ROW - A paid order queued for export to the order spreadsheet.
+// Times are unix milliseconds; ExportedAt is 0 while the row is pending.
+type SheetRow struct {
+	OrderID    uint64 `gorm:"primaryKey" db:"order_id"`
+	PaidAt     int64  `db:"paid_at"`
+	QueuedAt   int64  `db:"queued_at"`
+	ExportedAt int64  `gorm:"index" db:"exported_at"`
+	Attempts   int    `db:"attempts"`   // failed exports so far
+	LastError  string `db:"last_error"` // of the last failed export
+}
+
+// WEBHOOK SUBSCRIPTION - An outbound webhook endpoint and the events it
+// receives. Events is a comma-separated list of event types, "*" for all.
+type WebhookSubscription struct {
+	CreatedAt   time.Time `db:"created_at"`
+	URL         string    `gorm:"not null" db:"url"`
+	Events      string    `gorm:"not null" db:"events"`
+	Secret      string    `gorm:"not null" db:"secret"` // signs payloads; shown once, when created
+	Description string    `db:"description"`
+	ID          uint64    `gorm:"primaryKey"`
+}
+
+// WEBHOOK DELIVERY - One event queued for one subscription. Times are unix
+// milliseconds; DeliveredAt is 0 until a 2xx response.
+type WebhookDelivery struct {
+	EventID        string `gorm:"not null;uniqueIndex:idx_webhook_deliveries_subscription_event" db:"event_id"` // same for every subscription
+	Event          string `gorm:"not null;index" db:"event"`
+	Payload        string `gorm:"not null" db:"payload"`                                 // signed JSON body
+	Status         string `gorm:"not null;index:idx_webhook_deliveries_due" db:"status"` // pending, delivered, failed
+	LastError      string `db:"last_error"`
+	ID             uint64 `gorm:"primaryKey"`
+	SubscriptionID uint64 `gorm:"not null;uniqueIndex:idx_webhook_deliveries_subscription_event" db:"subscription_id"`
+	CreatedAt      int64  `db:"created_at"`
+	NextAttemptAt  int64  `gorm:"index:idx_webhook_deliveries_due" db:"next_attempt_at"`
+	DeliveredAt    int64  `db:"delivered_at"`
+	Attempts       int    `db:"attempts"`
+	LastStatusCode int    `db:"last_status_code"` // 0 when no response arrived
+}
+
+// WEBHOOK ATTEMPT - One POST of a delivery, kept for the delivery log
+type WebhookAttempt struct {
+	Error      string `db:"error"`
+	Response   string `db:"response"` // start of the response body
+	ID         uint64 `gorm:"primaryKey"`
+	DeliveryID uint64 `gorm:"not null;index" db:"delivery_id"`
+	At         int64  `db:"at"` // unix milliseconds
+	DurationMs int64  `db:"duration_ms"`
+	StatusCode int    `db:"status_code"`
+}
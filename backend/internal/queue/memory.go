@@ -0,0 +1,160 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrUnknownReceipt is returned when acknowledging a delivery that has expired
+var ErrUnknownReceipt = errors.New("queue: unknown or expired receipt")
+
+// MemoryQueue is an in-process Queue with SQS-like visibility semantics.
+// Messages are lost when the process exits, so it is meant for development and tests.
+type MemoryQueue struct {
+	mu       sync.Mutex
+	messages []*memMessage
+	seq      int64
+	notify   chan struct{}
+	wait     time.Duration
+	now      func() time.Time
+}
+
+type memMessage struct {
+	id        string
+	body      []byte
+	attempts  int
+	receipt   string
+	visibleAt time.Time
+}
+
+// NewMemoryQueue creates an empty in-memory queue
+func NewMemoryQueue() *MemoryQueue {
+	return &MemoryQueue{
+		notify: make(chan struct{}),
+		wait:   time.Second,
+		now:    time.Now,
+	}
+}
+
+func (q *MemoryQueue) Send(ctx context.Context, body []byte) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.seq++
+	q.messages = append(q.messages, &memMessage{
+		id:        strconv.FormatInt(q.seq, 10),
+		body:      append([]byte(nil), body...),
+		visibleAt: q.now(),
+	})
+	q.wake()
+	return nil
+}
+
+func (q *MemoryQueue) Receive(ctx context.Context, max int, visibility time.Duration) ([]Message, error) {
+	if max <= 0 {
+		max = 1
+	}
+	deadline := time.NewTimer(q.wait)
+	defer deadline.Stop()
+
+	for {
+		msgs, next, notify := q.take(max, visibility)
+		if len(msgs) > 0 {
+			return msgs, nil
+		}
+
+		// Wake on Send/Retry, when a hidden message becomes visible, or when the poll ends
+		var expire *time.Timer
+		var expireC <-chan time.Time
+		if !next.IsZero() {
+			expire = time.NewTimer(next.Sub(q.now()))
+			expireC = expire.C
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-deadline.C:
+			return nil, nil
+		case <-notify:
+		case <-expireC:
+		}
+		if expire != nil {
+			expire.Stop()
+		}
+	}
+}
+
+func (q *MemoryQueue) Delete(ctx context.Context, m Message) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for i, mm := range q.messages {
+		if mm.id == m.ID {
+			if mm.receipt != m.Receipt {
+				return ErrUnknownReceipt
+			}
+			q.messages = append(q.messages[:i], q.messages[i+1:]...)
+			return nil
+		}
+	}
+	return ErrUnknownReceipt
+}
+
+func (q *MemoryQueue) Retry(ctx context.Context, m Message, delay time.Duration) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, mm := range q.messages {
+		if mm.id == m.ID && mm.receipt == m.Receipt {
+			mm.visibleAt = q.now().Add(delay)
+			q.wake()
+			return nil
+		}
+	}
+	return ErrUnknownReceipt
+}
+
+// Len returns the number of messages in the queue, visible or not
+func (q *MemoryQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.messages)
+}
+
+// Depth reports Len for metrics
+func (q *MemoryQueue) Depth(ctx context.Context) (int, error) {
+	return q.Len(), nil
+}
+
+// take hides and returns up to max visible messages. When none are visible it
+// returns the earliest time one becomes visible and the channel closed on the next change.
+func (q *MemoryQueue) take(max int, visibility time.Duration) ([]Message, time.Time, <-chan struct{}) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := q.now()
+	var out []Message
+	var next time.Time
+	for _, mm := range q.messages {
+		if mm.visibleAt.After(now) {
+			if next.IsZero() || mm.visibleAt.Before(next) {
+				next = mm.visibleAt
+			}
+			continue
+		}
+		if len(out) == max {
+			break
+		}
+		mm.attempts++
+		mm.receipt = mm.id + "-" + strconv.Itoa(mm.attempts)
+		mm.visibleAt = now.Add(visibility)
+		out = append(out, Message{ID: mm.id, Body: mm.body, Attempts: mm.attempts, Receipt: mm.receipt})
+	}
+	return out, next, q.notify
+}
+
+// wake releases blocked receivers; callers hold q.mu
+func (q *MemoryQueue) wake() {
+	close(q.notify)
+	q.notify = make(chan struct{})
+}
@@ -0,0 +1,34 @@
+// Package queue provides an at-least-once job queue with an in-memory
+// implementation (development, tests) and an SQS implementation (LocalStack
+// in development, AWS in production), plus a Worker that processes messages
+// with visibility timeouts, retry backoff and a dead-letter queue.
+package queue
+
+import (
+	"context"
+	"time"
+)
+
+// Message is a received message. Attempts counts deliveries including this one.
+type Message struct {
+	ID       string
+	Body     []byte
+	Attempts int
+	// Receipt identifies this delivery for Delete and Retry
+	Receipt string
+}
+
+// Queue is an at-least-once message queue.
+// A received message is hidden from other consumers for the visibility
+// timeout; unless deleted within it, it is delivered again.
+type Queue interface {
+	// Send enqueues a message body
+	Send(ctx context.Context, body []byte) error
+	// Receive waits (long-polls) for up to max messages and hides them for visibility.
+	// It may return no messages when the poll times out.
+	Receive(ctx context.Context, max int, visibility time.Duration) ([]Message, error)
+	// Delete acknowledges a message so it is never delivered again
+	Delete(ctx context.Context, m Message) error
+	// Retry makes a message visible again after delay
+	Retry(ctx context.Context, m Message, delay time.Duration) error
+}
@@ -0,0 +1,124 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+// sqsMaxWait is the SQS long-poll limit
+const sqsMaxWait = 20 * time.Second
+
+// SQSQueue is a Queue backed by an SQS queue
+type SQSQueue struct {
+	api *sqs.Client
+	url string
+}
+
+// NewSQSQueue resolves the queue URL for name and returns a Queue for it
+func NewSQSQueue(ctx context.Context, cfg aws.Config, name string) (*SQSQueue, error) {
+	api := sqs.NewFromConfig(cfg)
+	out, err := api.GetQueueUrl(ctx, &sqs.GetQueueUrlInput{QueueName: aws.String(name)})
+	if err != nil {
+		return nil, fmt.Errorf("resolve sqs queue %s: %w", name, err)
+	}
+	return &SQSQueue{api: api, url: aws.ToString(out.QueueUrl)}, nil
+}
+
+func (q *SQSQueue) Send(ctx context.Context, body []byte) error {
+	_, err := q.api.SendMessage(ctx, &sqs.SendMessageInput{
+		QueueUrl:    aws.String(q.url),
+		MessageBody: aws.String(string(body)),
+	})
+	if err != nil {
+		return fmt.Errorf("sqs send: %w", err)
+	}
+	return nil
+}
+
+func (q *SQSQueue) Receive(ctx context.Context, max int, visibility time.Duration) ([]Message, error) {
+	if max <= 0 {
+		max = 1
+	}
+	if max > 10 {
+		max = 10 // SQS batch limit
+	}
+	out, err := q.api.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+		QueueUrl:            aws.String(q.url),
+		MaxNumberOfMessages: int32(max),
+		VisibilityTimeout:   int32(visibility / time.Second),
+		WaitTimeSeconds:     int32(sqsMaxWait / time.Second),
+		MessageSystemAttributeNames: []types.MessageSystemAttributeName{
+			types.MessageSystemAttributeNameApproximateReceiveCount,
+		},
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("sqs receive: %w", err)
+	}
+
+	msgs := make([]Message, 0, len(out.Messages))
+	for _, m := range out.Messages {
+		attempts, _ := strconv.Atoi(m.Attributes[string(types.MessageSystemAttributeNameApproximateReceiveCount)])
+		msgs = append(msgs, Message{
+			ID:       aws.ToString(m.MessageId),
+			Body:     []byte(aws.ToString(m.Body)),
+			Attempts: attempts,
+			Receipt:  aws.ToString(m.ReceiptHandle),
+		})
+	}
+	return msgs, nil
+}
+
+func (q *SQSQueue) Delete(ctx context.Context, m Message) error {
+	_, err := q.api.DeleteMessage(ctx, &sqs.DeleteMessageInput{
+		QueueUrl:      aws.String(q.url),
+		ReceiptHandle: aws.String(m.Receipt),
+	})
+	if err != nil {
+		return fmt.Errorf("sqs delete: %w", err)
+	}
+	return nil
+}
+
+func (q *SQSQueue) Retry(ctx context.Context, m Message, delay time.Duration) error {
+	_, err := q.api.ChangeMessageVisibility(ctx, &sqs.ChangeMessageVisibilityInput{
+		QueueUrl:          aws.String(q.url),
+		ReceiptHandle:     aws.String(m.Receipt),
+		VisibilityTimeout: int32(delay / time.Second),
+	})
+	if err != nil {
+		return fmt.Errorf("sqs change visibility: %w", err)
+	}
+	return nil
+}
+
+// Depth returns the approximate number of visible plus in-flight messages
+func (q *SQSQueue) Depth(ctx context.Context) (int, error) {
+	out, err := q.api.GetQueueAttributes(ctx, &sqs.GetQueueAttributesInput{
+		QueueUrl: aws.String(q.url),
+		AttributeNames: []types.QueueAttributeName{
+			types.QueueAttributeNameApproximateNumberOfMessages,
+			types.QueueAttributeNameApproximateNumberOfMessagesNotVisible,
+		},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("sqs queue attributes: %w", err)
+	}
+	depth := 0
+	for _, name := range []types.QueueAttributeName{
+		types.QueueAttributeNameApproximateNumberOfMessages,
+		types.QueueAttributeNameApproximateNumberOfMessagesNotVisible,
+	} {
+		n, _ := strconv.Atoi(out.Attributes[string(name)])
+		depth += n
+	}
+	return depth, nil
+}
@@ -0,0 +1,160 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"ecommerce-backend/internal/logging"
+)
+
+// Handler processes one message body. Returning an error schedules a retry.
+type Handler func(ctx context.Context, body []byte) error
+
+// WorkerOptions tunes a Worker; zero values fall back to defaults
+type WorkerOptions struct {
+	Name        string
+	Concurrency int
+	// Visibility hides a message while it is processed; a crashed worker's
+	// message is redelivered after it expires
+	Visibility time.Duration
+	// MaxAttempts deliveries before a message is moved to DeadLetter
+	MaxAttempts int
+	// Backoff returns the retry delay after the given failed attempt
+	Backoff    func(attempt int) time.Duration
+	DeadLetter Queue
+	// Logger defaults to slog.Default(); records carry subsystem=queue and the worker name
+	Logger *slog.Logger
+}
+
+func (o *WorkerOptions) setDefaults() {
+	if o.Name == "" {
+		o.Name = "worker"
+	}
+	if o.Concurrency <= 0 {
+		o.Concurrency = 1
+	}
+	if o.Visibility <= 0 {
+		o.Visibility = 30 * time.Second
+	}
+	if o.MaxAttempts <= 0 {
+		o.MaxAttempts = 5
+	}
+	if o.Backoff == nil {
+		o.Backoff = ExponentialBackoff(time.Second, 5*time.Minute)
+	}
+	o.Logger = logging.Subsystem(o.Logger, "queue").With("worker", o.Name)
+}
+
+// ExponentialBackoff doubles the delay from base on each attempt, up to max
+func ExponentialBackoff(base, max time.Duration) func(int) time.Duration {
+	return func(attempt int) time.Duration {
+		d := base
+		for i := 1; i < attempt && d < max; i++ {
+			d *= 2
+		}
+		if d > max {
+			d = max
+		}
+		return d
+	}
+}
+
+// Worker consumes a Queue with a Handler
+type Worker struct {
+	queue   Queue
+	handler Handler
+	opts    WorkerOptions
+}
+
+// NewWorker creates a worker for q
+func NewWorker(q Queue, h Handler, opts WorkerOptions) *Worker {
+	opts.setDefaults()
+	return &Worker{queue: q, handler: h, opts: opts}
+}
+
+// Run processes messages until ctx is cancelled, then waits for in-flight messages
+func (w *Worker) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i := 0; i < w.opts.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.loop(ctx)
+		}()
+	}
+	wg.Wait()
+}
+
+func (w *Worker) loop(ctx context.Context) {
+	for ctx.Err() == nil {
+		msgs, err := w.queue.Receive(ctx, 1, w.opts.Visibility)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			w.opts.Logger.Error("receive failed", "error", err)
+			select {
+			case <-ctx.Done():
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+		for _, m := range msgs {
+			// In-flight messages finish even when shutdown starts
+			w.process(context.WithoutCancel(ctx), m)
+		}
+	}
+}
+
+func (w *Worker) process(ctx context.Context, m Message) {
+	// Redelivered past the limit after crashes or expired visibility
+	if m.Attempts > w.opts.MaxAttempts {
+		w.deadLetter(ctx, m, nil)
+		return
+	}
+
+	err := w.handle(ctx, m)
+	if err == nil {
+		if err := w.queue.Delete(ctx, m); err != nil {
+			w.opts.Logger.Error("ack failed", "message_id", m.ID, "error", err)
+		}
+		return
+	}
+
+	if m.Attempts >= w.opts.MaxAttempts {
+		w.deadLetter(ctx, m, err)
+		return
+	}
+	delay := w.opts.Backoff(m.Attempts)
+	w.opts.Logger.Warn("message failed, retrying", "message_id", m.ID, "attempt", m.Attempts, "delay", delay, "error", err)
+	if err := w.queue.Retry(ctx, m, delay); err != nil {
+		w.opts.Logger.Error("retry failed", "message_id", m.ID, "error", err)
+	}
+}
+
+// handle runs the handler, converting a panic into an error so the message is retried
+func (w *Worker) handle(ctx context.Context, m Message) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return w.handler(ctx, m.Body)
+}
+
+func (w *Worker) deadLetter(ctx context.Context, m Message, cause error) {
+	w.opts.Logger.Error("message failed too often, moving to dead-letter queue", "message_id", m.ID, "attempts", m.Attempts, "error", cause)
+	if w.opts.DeadLetter != nil {
+		if err := w.opts.DeadLetter.Send(ctx, m.Body); err != nil {
+			// Keep the message; it will be redelivered and dead-lettered again
+			w.opts.Logger.Error("dead-letter failed", "message_id", m.ID, "error", err)
+			return
+		}
+	}
+	if err := w.queue.Delete(ctx, m); err != nil {
+		w.opts.Logger.Error("ack failed", "message_id", m.ID, "error", err)
+	}
+}
@@ -0,0 +1,108 @@
+package ratelimit
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+	"strings"
+
+	"ecommerce-backend/internal/metrics"
+	"ecommerce-backend/internal/utils/base32"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// FingerprintHeader carries the client's device fingerprint
+const FingerprintHeader = "X-Device-Fingerprint"
+
+// maxFingerprint bounds the header value hashed into a bucket key
+const maxFingerprint = 256
+
+// Middleware throttles route by client IP, phone number (?phone= or the
+// JSON body's "phone"), order number (?order_number= or the body's
+// "order_number", in any spelling) and the X-Device-Fingerprint header. A
+// nil limiter allows everything. Denied requests get 429 with Retry-After in seconds.
+func Middleware(l *Limiter, route string) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		if l == nil {
+			return c.Next()
+		}
+
+		phone, order := requestFields(c)
+		d, rule := l.Allow(c.Context(), route, map[Key]string{
+			KeyIP:          c.IP(),
+			KeyPhone:       normalizePhone(phone),
+			KeyFingerprint: fingerprint(c),
+			KeyOrder:       normalizeOrder(order),
+		})
+		if d.Allowed {
+			return c.Next()
+		}
+
+		metrics.RateLimited.WithLabelValues(route, string(rule.Key)).Inc()
+		retry := int(math.Ceil(d.RetryAfter.Seconds()))
+		if retry < 1 {
+			retry = 1
+		}
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(retry))
+		return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+			"error":       "Too many requests, please try again later",
+			"retry_after": retry,
+		})
+	}
+}
+
+// requestFields reads the phone and order numbers from the query, falling
+// back to the JSON body
+func requestFields(c fiber.Ctx) (phone, order string) {
+	phone, order = c.Query("phone"), c.Query("order_number")
+	if (phone == "" || order == "") && len(c.Body()) > 0 {
+		var body struct {
+			Phone       string `json:"phone"`
+			OrderNumber string `json:"order_number"`
+		}
+		// Malformed bodies are rejected by the handler; the IP bucket still applies
+		if json.Unmarshal(c.Body(), &body) == nil {
+			if phone == "" {
+				phone = body.Phone
+			}
+			if order == "" {
+				order = body.OrderNumber
+			}
+		}
+	}
+	return phone, order
+}
+
+// normalizePhone keeps the digits and writes +84 numbers in national form,
+// so that formatting variants of one number share a bucket
+func normalizePhone(phone string) string {
+	digits := strings.Map(func(r rune) rune {
+		if r < '0' || r > '9' {
+			return -1
+		}
+		return r
+	}, phone)
+	if strings.HasPrefix(digits, "84") && len(digits) == 11 {
+		digits = "0" + digits[2:]
+	}
+	return digits
+}
+
+// normalizeOrder names an order by ID, so that every spelling of its number
+// (case, hyphens, the legacy format) shares a bucket; undecodable numbers
+// are bucketed as written
+func normalizeOrder(number string) string {
+	if id, err := base32.DecodeOrderNumber(number); err == nil {
+		return strconv.FormatUint(id, 10)
+	}
+	return strings.ToUpper(strings.TrimSpace(number))
+}
+
+func fingerprint(c fiber.Ctx) string {
+	fp := strings.TrimSpace(c.Get(FingerprintHeader))
+	if len(fp) > maxFingerprint {
+		fp = fp[:maxFingerprint]
+	}
+	return fp
+}
@@ -0,0 +1,88 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often a MemoryStore drops refilled buckets
+const sweepInterval = time.Minute
+
+// bucket is a token bucket refilled continuously at its limit's rate
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	// full is when the bucket is back at capacity and can be forgotten
+	full time.Time
+}
+
+func (b *bucket) take(limit Limit, now time.Time) Decision {
+	capacity, rate := float64(limit.Events), limit.rate()
+	b.tokens = math.Min(capacity, b.tokens+now.Sub(b.updated).Seconds()*rate)
+	b.updated = now
+
+	d := Decision{Allowed: b.tokens >= 1}
+	if d.Allowed {
+		b.tokens--
+		d.Remaining = int(b.tokens)
+	} else {
+		d.RetryAfter = time.Duration((1 - b.tokens) / rate * float64(time.Second))
+	}
+	b.full = now.Add(time.Duration((capacity - b.tokens) / rate * float64(time.Second)))
+	return d
+}
+
+// MemoryStore is a Store for a single instance
+type MemoryStore struct {
+	// Now returns the current time; nil means time.Now
+	Now func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+// NewMemoryStore returns an empty MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]*bucket)}
+}
+
+// Take implements Store
+func (m *MemoryStore) Take(_ context.Context, key string, limit Limit) (Decision, error) {
+	now := time.Now()
+	if m.Now != nil {
+		now = m.Now()
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if now.Sub(m.lastSweep) >= sweepInterval {
+		m.sweep(now)
+	}
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Events), updated: now}
+		m.buckets[key] = b
+	}
+	return b.take(limit, now), nil
+}
+
+// Len returns the number of buckets held
+func (m *MemoryStore) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.buckets)
+}
+
+// sweep drops refilled buckets, which behave exactly like missing ones, so
+// memory is bounded by the clients active within one period
+func (m *MemoryStore) sweep(now time.Time) {
+	for key, b := range m.buckets {
+		if !b.full.After(now) {
+			delete(m.buckets, key)
+		}
+	}
+	m.lastSweep = now
+}
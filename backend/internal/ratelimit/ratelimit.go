@@ -0,0 +1,155 @@
+// Package ratelimit throttles abusive clients with token buckets keyed by
+// client IP, phone number, device fingerprint and order number. Buckets live in a Store:
+// in memory for a single instance, or in Redis so every instance shares them.
+package ratelimit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"ecommerce-backend/internal/logging"
+)
+
+// Limit allows Events per Period, in bursts of up to Events
+type Limit struct {
+	Events int
+	Period time.Duration
+}
+
+func (l Limit) String() string {
+	return fmt.Sprintf("%d/%s", l.Events, l.Period)
+}
+
+// rate is the refill rate in tokens per second
+func (l Limit) rate() float64 {
+	return float64(l.Events) / l.Period.Seconds()
+}
+
+// Key is the request attribute a bucket is keyed by
+type Key string
+
+const (
+	KeyIP          Key = "ip"
+	KeyPhone       Key = "phone"
+	KeyFingerprint Key = "fingerprint"
+	KeyOrder       Key = "order"
+)
+
+// Rule limits one key of one route
+type Rule struct {
+	Route string
+	Key   Key
+	Limit Limit
+}
+
+// ParseRules parses route:key=events/period entries, e.g. purchase:phone=3/10m
+func ParseRules(entries []string) ([]Rule, error) {
+	rules := make([]Rule, 0, len(entries))
+	for _, e := range entries {
+		target, limit, ok := strings.Cut(strings.TrimSpace(e), "=")
+		route, key, ok2 := strings.Cut(target, ":")
+		events, period, ok3 := strings.Cut(limit, "/")
+		if !ok || !ok2 || !ok3 || route == "" {
+			return nil, fmt.Errorf("%q: expected route:key=events/period", e)
+		}
+		switch Key(key) {
+		case KeyIP, KeyPhone, KeyFingerprint, KeyOrder:
+		default:
+			return nil, fmt.Errorf("%q: key must be ip, phone, fingerprint or order", e)
+		}
+		n, err := strconv.Atoi(events)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("%q: events must be a positive integer", e)
+		}
+		d, err := time.ParseDuration(period)
+		if err != nil || d < time.Second {
+			return nil, fmt.Errorf("%q: period must be a duration of at least 1s", e)
+		}
+		rules = append(rules, Rule{Route: route, Key: Key(key), Limit: Limit{Events: n, Period: d}})
+	}
+	return rules, nil
+}
+
+// Decision is the outcome of taking a token from a bucket
+type Decision struct {
+	Allowed   bool
+	Remaining int
+	// RetryAfter is how long until the next token when not allowed
+	RetryAfter time.Duration
+}
+
+// Store holds token buckets
+type Store interface {
+	// Take removes a token from the bucket at key, which starts full
+	Take(ctx context.Context, key string, limit Limit) (Decision, error)
+}
+
+// Options configures a Limiter
+type Options struct {
+	// Store holds the buckets; nil uses a MemoryStore
+	Store  Store
+	Rules  []Rule
+	Logger *slog.Logger
+}
+
+// Limiter applies per-route rules to request attributes
+type Limiter struct {
+	store Store
+	rules map[string][]Rule
+	log   *slog.Logger
+}
+
+// New returns a Limiter enforcing opts.Rules
+func New(opts Options) *Limiter {
+	l := &Limiter{
+		store: opts.Store,
+		rules: make(map[string][]Rule),
+		log:   logging.Subsystem(opts.Logger, "ratelimit"),
+	}
+	if l.store == nil {
+		l.store = NewMemoryStore()
+	}
+	for _, r := range opts.Rules {
+		l.rules[r.Route] = append(l.rules[r.Route], r)
+	}
+	return l
+}
+
+// Allow takes a token from every bucket of route whose key has a value;
+// the first exhausted bucket denies the request and is returned. Store
+// failures allow the request: throttling must not take the drop down
+// with Redis.
+func (l *Limiter) Allow(ctx context.Context, route string, values map[Key]string) (Decision, Rule) {
+	allowed := Decision{Allowed: true, Remaining: -1}
+	for _, r := range l.rules[route] {
+		v := values[r.Key]
+		if v == "" {
+			continue
+		}
+		d, err := l.store.Take(ctx, bucketKey(r, v), r.Limit)
+		if err != nil {
+			l.log.ErrorContext(ctx, "rate limit store failed, allowing request", "route", route, "key", r.Key, "error", err)
+			continue
+		}
+		if !d.Allowed {
+			return d, r
+		}
+		if allowed.Remaining < 0 || d.Remaining < allowed.Remaining {
+			allowed.Remaining = d.Remaining
+		}
+	}
+	return allowed, Rule{}
+}
+
+// bucketKey names a bucket without storing phone numbers or fingerprints
+// in the clear, and bounds the key length
+func bucketKey(r Rule, value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return "rl:" + r.Route + ":" + string(r.Key) + ":" + hex.EncodeToString(sum[:12])
+}
@@ -0,0 +1,63 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// takeScript refills and takes from the bucket at KEYS[1] atomically.
+// ARGV: capacity, period in ms. Time comes from the Redis server so that
+// instances with skewed clocks agree. Returns {allowed, remaining, retry ms}.
+var takeScript = redis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local period = tonumber(ARGV[2])
+local t = redis.call('TIME')
+local now = t[1] * 1000 + math.floor(t[2] / 1000)
+
+local b = redis.call('HMGET', KEYS[1], 'tokens', 'updated')
+local tokens = tonumber(b[1]) or capacity
+local updated = tonumber(b[2]) or now
+tokens = math.min(capacity, tokens + math.max(0, now - updated) * capacity / period)
+
+local allowed, retry = 0, 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+else
+  retry = math.ceil((1 - tokens) * period / capacity)
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'updated', now)
+redis.call('PEXPIRE', KEYS[1], math.ceil((capacity - tokens) * period / capacity) + 1000)
+return {allowed, math.floor(tokens), retry}
+`)
+
+// RedisStore is a Store shared by every instance using the same Redis.
+// Buckets expire once refilled.
+type RedisStore struct {
+	client redis.Scripter
+}
+
+// NewRedisStore returns a Store keeping buckets in client
+func NewRedisStore(client redis.Scripter) *RedisStore {
+	return &RedisStore{client: client}
+}
+
+// Take implements Store
+func (r *RedisStore) Take(ctx context.Context, key string, limit Limit) (Decision, error) {
+	res, err := takeScript.Run(ctx, r.client, []string{key}, limit.Events, limit.Period.Milliseconds()).Int64Slice()
+	if err != nil {
+		return Decision{}, fmt.Errorf("redis take %s: %w", key, err)
+	}
+	if len(res) != 3 {
+		return Decision{}, fmt.Errorf("redis take %s: unexpected reply %v", key, res)
+	}
+	return Decision{
+		Allowed:    res[0] == 1,
+		Remaining:  int(res[1]),
+		RetryAfter: time.Duration(res[2]) * time.Millisecond,
+	}, nil
+}
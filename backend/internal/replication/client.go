@@ -0,0 +1,103 @@
+package replication
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ErrNotFound is returned by Client.Get for missing keys
+var ErrNotFound = errors.New("replica object not found")
+
+// Client is the minimal object-store surface the replicator needs.
+// Keys are slash-separated; List returns full keys sorted lexically.
+type Client interface {
+	Put(ctx context.Context, key string, r io.Reader) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	List(ctx context.Context, prefix string) ([]string, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// FileClient stores objects in a local directory. Useful for tests and for
+// replicating to a mounted volume or NFS share.
+type FileClient struct {
+	root string
+}
+
+// NewFileClient creates a Client rooted at dir
+func NewFileClient(dir string) *FileClient {
+	return &FileClient{root: dir}
+}
+
+func (c *FileClient) path(key string) string {
+	return filepath.Join(c.root, filepath.FromSlash(key))
+}
+
+func (c *FileClient) Put(ctx context.Context, key string, r io.Reader) error {
+	p := c.path(key)
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return err
+	}
+	tmp := p + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, p)
+}
+
+func (c *FileClient) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	f, err := os.Open(c.path(key))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+	return f, err
+}
+
+func (c *FileClient) List(ctx context.Context, prefix string) ([]string, error) {
+	var keys []string
+	err := filepath.WalkDir(c.root, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() || strings.HasSuffix(p, ".tmp") {
+			return nil
+		}
+		rel, err := filepath.Rel(c.root, p)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+		return nil
+	})
+	sort.Strings(keys)
+	return keys, err
+}
+
+func (c *FileClient) Delete(ctx context.Context, key string) error {
+	err := os.Remove(c.path(key))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,227 @@
+package replication
+
+import (
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNoGeneration is returned when no generation covers the requested time
+var ErrNoGeneration = errors.New("no replica generation at or before the requested time")
+
+// Generation is one snapshot + WAL chain in the replica
+type Generation struct {
+	ID      string
+	Started time.Time
+}
+
+// Segment is one shipped chunk of WAL
+type Segment struct {
+	Key     string
+	Seq     int
+	Shipped time.Time
+}
+
+// RestoreResult describes what Restore applied
+type RestoreResult struct {
+	Generation Generation
+	Segments   int
+	// RestoredTo is the ship time of the last applied segment (or the snapshot)
+	RestoredTo time.Time
+}
+
+// Generations lists the generations in the replica, oldest first
+func Generations(ctx context.Context, client Client, prefix string) ([]Generation, error) {
+	keys, err := client.List(ctx, path.Join(prefix, "generations")+"/")
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	var gens []Generation
+	for _, k := range keys {
+		if !strings.HasSuffix(k, "/snapshot.db.gz") {
+			continue
+		}
+		id := path.Base(path.Dir(k))
+		nanos, err := strconv.ParseInt(id, 16, 64)
+		if err != nil || seen[id] {
+			continue
+		}
+		seen[id] = true
+		gens = append(gens, Generation{ID: id, Started: time.Unix(0, nanos)})
+	}
+	sort.Slice(gens, func(i, j int) bool { return gens[i].ID < gens[j].ID })
+	return gens, nil
+}
+
+// Segments lists the WAL segments of a generation in apply order
+func Segments(ctx context.Context, client Client, prefix, gen string) ([]Segment, error) {
+	keys, err := client.List(ctx, generationPrefix(prefix, gen)+"wal/")
+	if err != nil {
+		return nil, err
+	}
+
+	var segs []Segment
+	for _, k := range keys {
+		name := strings.TrimSuffix(path.Base(k), ".wal.gz")
+		seqStr, tsStr, ok := strings.Cut(name, "-")
+		if !ok {
+			continue
+		}
+		seq, err1 := strconv.Atoi(seqStr)
+		ts, err2 := strconv.ParseInt(tsStr, 16, 64)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		segs = append(segs, Segment{Key: k, Seq: seq, Shipped: time.Unix(0, ts)})
+	}
+	sort.Slice(segs, func(i, j int) bool { return segs[i].Seq < segs[j].Seq })
+	return segs, nil
+}
+
+// Restore rebuilds the database as of time at into target.
+// The newest generation started at or before at is used, and only WAL segments
+// shipped at or before at are applied. target must not exist unless force is set.
+func Restore(ctx context.Context, client Client, prefix, target string, at time.Time, force bool) (*RestoreResult, error) {
+	if _, err := os.Stat(target); err == nil && !force {
+		return nil, fmt.Errorf("restore target %s already exists", target)
+	}
+
+	gens, err := Generations(ctx, client, prefix)
+	if err != nil {
+		return nil, err
+	}
+	var gen *Generation
+	for i := len(gens) - 1; i >= 0; i-- {
+		if !gens[i].Started.After(at) {
+			gen = &gens[i]
+			break
+		}
+	}
+	if gen == nil {
+		return nil, ErrNoGeneration
+	}
+
+	tmp := target + ".replica-tmp"
+	cleanup := func() {
+		for _, s := range []string{"", "-wal", "-shm"} {
+			os.Remove(tmp + s)
+		}
+	}
+	cleanup()
+	defer cleanup()
+
+	if err := getGzip(ctx, client, snapshotKey(prefix, gen.ID), tmp, false); err != nil {
+		return nil, fmt.Errorf("download snapshot: %w", err)
+	}
+
+	segs, err := Segments(ctx, client, prefix, gen.ID)
+	if err != nil {
+		return nil, err
+	}
+	result := &RestoreResult{Generation: *gen, RestoredTo: gen.Started}
+	for i, s := range segs {
+		if s.Shipped.After(at) {
+			break
+		}
+		if s.Seq != i {
+			return nil, fmt.Errorf("wal segment %d missing in generation %s", i, gen.ID)
+		}
+		if err := getGzip(ctx, client, s.Key, tmp+"-wal", true); err != nil {
+			return nil, fmt.Errorf("download segment %d: %w", s.Seq, err)
+		}
+		result.Segments++
+		result.RestoredTo = s.Shipped
+	}
+
+	if err := applyWAL(ctx, tmp); err != nil {
+		return nil, err
+	}
+
+	if err := os.Rename(tmp, target); err != nil {
+		return nil, err
+	}
+	os.Remove(target + "-wal")
+	os.Remove(target + "-shm")
+	return result, nil
+}
+
+// applyWAL lets SQLite recover the downloaded WAL into the snapshot, then
+// checkpoints it into the main file and verifies the result
+func applyWAL(ctx context.Context, dbPath string) error {
+	if _, err := os.Stat(dbPath + "-wal"); err == nil {
+		// SQLite only reads a -wal file when the header says WAL mode
+		if err := markWALMode(dbPath); err != nil {
+			return err
+		}
+	}
+
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if _, err := db.ExecContext(ctx, "PRAGMA wal_checkpoint(TRUNCATE)"); err != nil {
+		return fmt.Errorf("apply wal: %w", err)
+	}
+	var result string
+	if err := db.QueryRowContext(ctx, "PRAGMA integrity_check").Scan(&result); err != nil {
+		return fmt.Errorf("integrity check: %w", err)
+	}
+	if result != "ok" {
+		return fmt.Errorf("restored database failed integrity check: %s", result)
+	}
+	return nil
+}
+
+// markWALMode sets the file format read/write versions (header bytes 18-19) to 2
+func markWALMode(dbPath string) error {
+	f, err := os.OpenFile(dbPath, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := f.WriteAt([]byte{2, 2}, 18); err != nil {
+		return err
+	}
+	return f.Sync()
+}
+
+func getGzip(ctx context.Context, client Client, key, dst string, appendTo bool) error {
+	rc, err := client.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	gz, err := gzip.NewReader(rc)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if appendTo {
+		flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+	f, err := os.OpenFile(dst, flags, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, gz); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,116 @@
+package replication
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// SQLite WAL file layout, see https://www.sqlite.org/fileformat2.html#walformat
+const (
+	walHeaderSize      = 32
+	walFrameHeaderSize = 24
+	walMagicLE         = 0x377f0682
+	walMagicBE         = 0x377f0683
+)
+
+var errNoWAL = errors.New("wal file is empty")
+
+// walHeader is the decoded 32-byte WAL header
+type walHeader struct {
+	pageSize  uint32
+	salt1     uint32
+	salt2     uint32
+	bigEndian bool // checksum byte order
+	s1, s2    uint32
+}
+
+func parseWALHeader(b []byte) (walHeader, error) {
+	if len(b) < walHeaderSize {
+		return walHeader{}, errNoWAL
+	}
+	var h walHeader
+	switch magic := binary.BigEndian.Uint32(b[0:4]); magic {
+	case walMagicLE:
+	case walMagicBE:
+		h.bigEndian = true
+	default:
+		return walHeader{}, fmt.Errorf("invalid wal magic %#x", magic)
+	}
+	h.pageSize = binary.BigEndian.Uint32(b[8:12])
+	if h.pageSize == 1 {
+		h.pageSize = 65536
+	}
+	h.salt1 = binary.BigEndian.Uint32(b[16:20])
+	h.salt2 = binary.BigEndian.Uint32(b[20:24])
+
+	s1, s2 := walChecksum(h.bigEndian, 0, 0, b[0:24])
+	if s1 != binary.BigEndian.Uint32(b[24:28]) || s2 != binary.BigEndian.Uint32(b[28:32]) {
+		return walHeader{}, errors.New("wal header checksum mismatch")
+	}
+	h.s1, h.s2 = s1, s2
+	return h, nil
+}
+
+func (h walHeader) frameSize() int64 {
+	return walFrameHeaderSize + int64(h.pageSize)
+}
+
+func (h walHeader) sameIncarnation(o walHeader) bool {
+	return h.salt1 == o.salt1 && h.salt2 == o.salt2 && h.pageSize == o.pageSize
+}
+
+// walCursor tracks how far into the current WAL incarnation we have shipped.
+// offset always points just past a commit frame (or the header), and s1/s2
+// hold the running checksum at that point, so the next scan can validate frames.
+type walCursor struct {
+	header walHeader
+	offset int64
+	s1, s2 uint32
+}
+
+func newWALCursor(h walHeader) walCursor {
+	return walCursor{header: h, offset: walHeaderSize, s1: h.s1, s2: h.s2}
+}
+
+// scan walks frames in wal starting at the cursor and returns the end offset of
+// the last valid commit frame together with the checksum state at that point.
+// Frames from an older incarnation, torn writes and uncommitted tails all fail
+// the salt/checksum test and end the scan, so they are picked up next time.
+func (c walCursor) scan(wal []byte) walCursor {
+	h := c.header
+	fs := h.frameSize()
+	pos, s1, s2 := c.offset, c.s1, c.s2
+	last := c
+
+	for pos+fs <= int64(len(wal)) {
+		frame := wal[pos : pos+fs]
+		if binary.BigEndian.Uint32(frame[8:12]) != h.salt1 || binary.BigEndian.Uint32(frame[12:16]) != h.salt2 {
+			break
+		}
+		s1, s2 = walChecksum(h.bigEndian, s1, s2, frame[0:8])
+		s1, s2 = walChecksum(h.bigEndian, s1, s2, frame[walFrameHeaderSize:])
+		if s1 != binary.BigEndian.Uint32(frame[16:20]) || s2 != binary.BigEndian.Uint32(frame[20:24]) {
+			break
+		}
+		pos += fs
+		// Non-zero "database size" marks a commit frame
+		if binary.BigEndian.Uint32(frame[4:8]) != 0 {
+			last = walCursor{header: h, offset: pos, s1: s1, s2: s2}
+		}
+	}
+	return last
+}
+
+// walChecksum is SQLite's WAL checksum over 8-byte chunks
+func walChecksum(bigEndian bool, s1, s2 uint32, b []byte) (uint32, uint32) {
+	order := binary.ByteOrder(binary.LittleEndian)
+	if bigEndian {
+		order = binary.BigEndian
+	}
+	for i := 0; i+8 <= len(b); i += 8 {
+		s1 += order.Uint32(b[i:]) + s2
+		s2 += order.Uint32(b[i+4:]) + s1
+	}
+	return s1, s2
+}
@@ -0,0 +1,26 @@
+package repository
+
+import (
+	"ecommerce-backend/internal/models"
+	"time"
+)
+
+// CreateAuditEntry records a change made by a scheduled job or an admin
+func (r *repository) CreateAuditEntry(entry *models.AuditEntry) error {
+	query := `INSERT INTO audit_entries (created_at, actor, action, details) VALUES (?, ?, ?, ?)`
+
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+	result, err := r.db.Exec(query, entry.CreatedAt, entry.Actor, entry.Action, string(entry.Details))
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	entry.ID = uint64(id)
+	return nil
+}
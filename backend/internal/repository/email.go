@@ -0,0 +1,189 @@
+package repository
+
+import (
+	"strings"
+	"time"
+
+	"ecommerce-backend/internal/models"
+)
+
+// emailStatusRank is an SQL expression ranking the delivery state in expr,
+// so a late or replayed webhook never moves a message back (e.g. "delivered"
+// after "bounced")
+func emailStatusRank(expr string) string {
+	return "CASE " + expr + " WHEN 'sent' THEN 0 WHEN 'deferred' THEN 1 WHEN 'delivered' THEN 2 ELSE 3 END"
+}
+
+// CreateEmailMessage records one recipient of an accepted email
+func (r *repository) CreateEmailMessage(msg *models.EmailMessage) error {
+	query := `
+		INSERT INTO email_messages (created_at, updated_at, provider, message_id, email, template, status, detail, order_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	if msg.CreatedAt.IsZero() {
+		msg.CreatedAt = time.Now()
+	}
+	msg.CreatedAt = msg.CreatedAt.UTC()
+	msg.UpdatedAt = msg.CreatedAt
+	msg.Email = strings.ToLower(strings.TrimSpace(msg.Email))
+	if msg.Status == "" {
+		msg.Status = models.EmailSent
+	}
+
+	result, err := r.db.Exec(query, msg.CreatedAt, msg.UpdatedAt, msg.Provider, msg.MessageID,
+		msg.Email, msg.Template, msg.Status, msg.Detail, msg.OrderID)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	msg.ID = uint64(id)
+	return nil
+}
+
+// UpdateEmailMessageStatus moves the messages with the provider's message ID
+// to status, for one recipient or for all of them when email is empty. A
+// status ranked below the current one is ignored. It returns how many
+// messages were updated.
+func (r *repository) UpdateEmailMessageStatus(provider, messageID, email, status, detail string) (int64, error) {
+	query := `
+		UPDATE email_messages SET status = ?, detail = ?, updated_at = ?
+		WHERE provider = ? AND message_id = ? AND (? = '' OR email = ?)
+		AND ` + emailStatusRank("?") + ` >= ` + emailStatusRank("status")
+
+	email = strings.ToLower(strings.TrimSpace(email))
+	result, err := r.db.Exec(query, status, detail, time.Now().UTC(),
+		provider, messageID, email, email, status)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// ListOrderEmailMessages returns the emails sent about an order, oldest first
+func (r *repository) ListOrderEmailMessages(orderID uint64) ([]models.EmailMessage, error) {
+	query := `
+		SELECT id, created_at, updated_at, provider, message_id, email, template, status, detail, order_id
+		FROM email_messages
+		WHERE order_id = ?
+		ORDER BY created_at, id`
+
+	rows, err := r.db.Query(query, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var messages []models.EmailMessage
+	for rows.Next() {
+		var m models.EmailMessage
+		if err := rows.Scan(&m.ID, &m.CreatedAt, &m.UpdatedAt, &m.Provider, &m.MessageID,
+			&m.Email, &m.Template, &m.Status, &m.Detail, &m.OrderID); err != nil {
+			return nil, err
+		}
+		messages = append(messages, m)
+	}
+	return messages, rows.Err()
+}
+
+// SuppressEmail adds an address to the suppression list. The first reason
+// recorded for an address is kept; it returns false if it was already listed.
+func (r *repository) SuppressEmail(s *models.EmailSuppression) (bool, error) {
+	query := `
+		INSERT INTO email_suppressions (email, created_at, reason, provider, message_id, detail)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(email) DO NOTHING`
+
+	if s.CreatedAt.IsZero() {
+		s.CreatedAt = time.Now()
+	}
+	s.CreatedAt = s.CreatedAt.UTC()
+	s.Email = strings.ToLower(strings.TrimSpace(s.Email))
+
+	result, err := r.db.Exec(query, s.Email, s.CreatedAt, s.Reason, s.Provider, s.MessageID, s.Detail)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// SuppressedEmails returns which of the addresses are on the suppression
+// list, as given
+func (r *repository) SuppressedEmails(addresses []string) ([]string, error) {
+	if len(addresses) == 0 {
+		return nil, nil
+	}
+	args := make([]interface{}, len(addresses))
+	for i, a := range addresses {
+		args[i] = strings.ToLower(strings.TrimSpace(a))
+	}
+	query := `
+		SELECT email FROM email_suppressions
+		WHERE email IN (?` + strings.Repeat(", ?", len(addresses)-1) + `)`
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	listed := make(map[string]bool)
+	for rows.Next() {
+		var email string
+		if err := rows.Scan(&email); err != nil {
+			return nil, err
+		}
+		listed[email] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var suppressed []string
+	for i, a := range addresses {
+		if listed[args[i].(string)] {
+			suppressed = append(suppressed, a)
+		}
+	}
+	return suppressed, nil
+}
+
+// ListEmailSuppressions returns the most recently suppressed addresses first
+func (r *repository) ListEmailSuppressions(limit int) ([]models.EmailSuppression, error) {
+	query := `
+		SELECT email, created_at, reason, provider, message_id, detail
+		FROM email_suppressions
+		ORDER BY created_at DESC, email
+		LIMIT ?`
+
+	rows, err := r.db.Query(query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []models.EmailSuppression
+	for rows.Next() {
+		var s models.EmailSuppression
+		if err := rows.Scan(&s.Email, &s.CreatedAt, &s.Reason, &s.Provider, &s.MessageID, &s.Detail); err != nil {
+			return nil, err
+		}
+		list = append(list, s)
+	}
+	return list, rows.Err()
+}
+
+// DeleteEmailSuppression removes an address from the suppression list and
+// reports whether it was listed
+func (r *repository) DeleteEmailSuppression(email string) (bool, error) {
+	result, err := r.db.Exec(`DELETE FROM email_suppressions WHERE email = ?`,
+		strings.ToLower(strings.TrimSpace(email)))
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
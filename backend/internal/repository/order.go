@@ -176,6 +176,16 @@ func (r *repository) GetOrderByPayOSOrderCode(orderCode int64) (*models.Order, e
 	return &order, nil
 }
 
+func (r *repository) CancelOrder(id uint64) (bool, error) {
+	query := `UPDATE orders SET status = ? WHERE id = ? AND status <> ?`
+	res, err := r.db.Exec(query, models.OrderCancelled, id, models.OrderCancelled)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
 func (r *repository) UpdateOrderStatus(id uint64, status uint8) error {
 	query := `UPDATE orders SET status = ? WHERE id = ?`
 	_, err := r.db.Exec(query, status, id)
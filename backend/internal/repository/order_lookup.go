@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"time"
+
+	"ecommerce-backend/internal/models"
+)
+
+// CreateOrderLookupToken stores an emailed one-time lookup link
+func (r *repository) CreateOrderLookupToken(token *models.OrderLookupToken) error {
+	query := `
+		INSERT INTO order_lookup_tokens (order_id, created_at, expires_at, token_hash)
+		VALUES (?, ?, ?, ?)`
+
+	if token.CreatedAt.IsZero() {
+		token.CreatedAt = time.Now()
+	}
+	// UTC keeps expires_at comparable as text in UseOrderLookupToken
+	token.CreatedAt = token.CreatedAt.UTC()
+	token.ExpiresAt = token.ExpiresAt.UTC()
+
+	result, err := r.db.Exec(query, token.OrderID, token.CreatedAt, token.ExpiresAt, token.TokenHash)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	token.ID = uint64(id)
+	return nil
+}
+
+// UseOrderLookupToken spends the unused, unexpired token with tokenHash and
+// returns its order ID. It returns sql.ErrNoRows when the token is unknown,
+// used or expired.
+func (r *repository) UseOrderLookupToken(tokenHash string) (uint64, error) {
+	query := `
+		UPDATE order_lookup_tokens SET used_at = ?
+		WHERE token_hash = ? AND used_at IS NULL AND expires_at > ?
+		RETURNING order_id`
+
+	now := time.Now().UTC()
+	var orderID uint64
+	if err := r.db.QueryRow(query, now, tokenHash, now).Scan(&orderID); err != nil {
+		return 0, err
+	}
+	return orderID, nil
+}
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"ecommerce-backend/internal/models"
+)
+
+// CreateRefund queues a pending refund, due at once; an order already
+// queued is left alone
+func (r *repository) CreateRefund(refund *models.Refund) error {
+	query := `
+		INSERT INTO refunds (order_id, order_code, reason, status, attempts, last_error, created_at, next_attempt_at)
+		VALUES (?, ?, ?, ?, 0, '', ?, ?)
+		ON CONFLICT(order_id) DO NOTHING`
+
+	if refund.CreatedAt.IsZero() {
+		refund.CreatedAt = time.Now()
+	}
+	// UTC keeps next_attempt_at comparable as text in DueRefunds
+	refund.CreatedAt = refund.CreatedAt.UTC()
+	refund.NextAttemptAt = refund.CreatedAt
+	refund.Status = models.RefundPending
+
+	result, err := r.db.Exec(query, refund.OrderID, refund.OrderCode, refund.Reason, refund.Status, refund.CreatedAt, refund.NextAttemptAt)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	refund.ID = uint64(id)
+	return nil
+}
+
+// DueRefunds returns up to limit pending refunds whose next attempt is due
+// at now, oldest first
+func (r *repository) DueRefunds(now time.Time, limit int) ([]models.Refund, error) {
+	query := `
+		SELECT id, order_id, order_code, reason, status, attempts, last_error, created_at, next_attempt_at
+		FROM refunds
+		WHERE status = ? AND next_attempt_at <= ?
+		ORDER BY next_attempt_at, id
+		LIMIT ?`
+
+	rows, err := r.db.Query(query, models.RefundPending, now.UTC(), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var refunds []models.Refund
+	for rows.Next() {
+		var f models.Refund
+		if err := rows.Scan(&f.ID, &f.OrderID, &f.OrderCode, &f.Reason, &f.Status, &f.Attempts,
+			&f.LastError, &f.CreatedAt, &f.NextAttemptAt); err != nil {
+			return nil, err
+		}
+		refunds = append(refunds, f)
+	}
+	return refunds, rows.Err()
+}
+
+// UpdateRefund records an attempt: the refund's status, attempts, next
+// attempt, refund time and last error
+func (r *repository) UpdateRefund(refund *models.Refund) error {
+	query := `
+		UPDATE refunds SET status = ?, attempts = ?, next_attempt_at = ?, refunded_at = ?, last_error = ?
+		WHERE id = ?`
+
+	refund.NextAttemptAt = refund.NextAttemptAt.UTC()
+	var refundedAt sql.NullTime
+	if refund.RefundedAt != nil {
+		refundedAt = sql.NullTime{Time: refund.RefundedAt.UTC(), Valid: true}
+	}
+	_, err := r.db.Exec(query, refund.Status, refund.Attempts, refund.NextAttemptAt, refundedAt, refund.LastError, refund.ID)
+	return err
+}
@@ -1,7 +1,9 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
+	"ecommerce-backend/internal/database"
 	"ecommerce-backend/internal/models"
 	"encoding/json"
 	"fmt"
@@ -23,6 +25,15 @@ type Repository interface {
 	GetOrdersByUserPhone(phone string) ([]models.Order, error)
 	GetOrderByPayOSOrderCode(orderCode int64) (*models.Order, error)
 	UpdateOrderStatus(id uint64, status uint8) error
+	// CancelOrder cancels an order unless it already is; false when another
+	// caller cancelled it first
+	CancelOrder(id uint64) (bool, error)
+	CreateOrderLookupToken(token *models.OrderLookupToken) error
+	UseOrderLookupToken(tokenHash string) (uint64, error)
+	// Refunds owed for orders that lost their drop
+	CreateRefund(refund *models.Refund) error
+	DueRefunds(now time.Time, limit int) ([]models.Refund, error)
+	UpdateRefund(refund *models.Refund) error
 
 	// Drop operations for drop flow
 	GetActiveDrops() ([]models.LimitedDrop, error)
@@ -33,10 +44,57 @@ type Repository interface {
 	// Transaction support
 	WithTransaction(fn func(Repository) error) error
 
+	// Read routing hints (no-ops unless backed by a SmartExecutor)
+	// Primary returns a repository whose reads are always served by the writer
+	Primary() Repository
+	// Session returns a repository that reads its own writes: once it writes,
+	// subsequent reads go to the writer as well
+	Session() Repository
+	// WithContext returns a repository whose statements run with ctx, so
+	// they are traced as children of the caller's span
+	WithContext(ctx context.Context) Repository
+
 	// Symbicode operations for verification flow
 	CreateSymbicode(symbicode *models.Symbicode) error
 	GetSymbicodeByCode(code []byte) (*models.Symbicode, error)
 	ActivateSymbicode(id uint64, ip string) error
+	AutoActivateSymbicodes(createdBefore time.Time, limit int) ([]uint64, error)
+
+	// Symbicode scan history for counterfeit detection
+	CreateSymbicodeScan(scan *models.SymbicodeScan) error
+	ListSymbicodeScans(symbicodeID uint64, limit int) ([]models.SymbicodeScan, error)
+	GetSymbicodeScanStats(symbicodeID uint64) (*models.SymbicodeScanStats, error)
+	ListSuspiciousSymbicodes(minIPs, minLocations, limit int) ([]models.SymbicodeScanStats, error)
+
+	// Symbicodes pre-generated for printing and bound at fulfillment
+	CreateSymbicodeBatch(batch *models.SymbicodeBatch) error
+	GetSymbicodeBatch(id uint64) (*models.SymbicodeBatch, error)
+	ListBatchSymbicodes(batchID uint64) ([]models.Symbicode, error)
+	BindSymbicode(id, orderID uint64) (bool, error)
+	// HasSymbicodeBatches reports whether a product's tags are pre-printed
+	HasSymbicodeBatches(productID uint64) (bool, error)
+	CountOrderSymbicodes(orderID uint64) (int, error)
+
+	// Symbicode ownership transfers for resale
+	GetSymbicodeByID(id uint64) (*models.Symbicode, error)
+	CreateSymbicodeTransfer(transfer *models.SymbicodeTransfer) error
+	CancelSymbicodeTransfers(symbicodeID uint64) (int64, error)
+	ClaimSymbicodeTransfer(tokenHash, phone, email string) (*models.SymbicodeTransfer, error)
+	ListSymbicodeOwners(symbicodeID uint64) ([]models.SymbicodeTransfer, error)
+	CreateSymbicodeTransferCode(code *models.SymbicodeTransferCode) error
+	UseSymbicodeTransferCode(symbicodeID uint64, channel, contact, codeHash string) (bool, error)
+
+	// Audit log for changes made outside customer requests
+	CreateAuditEntry(entry *models.AuditEntry) error
+
+	// Email delivery tracking and the bounce/complaint suppression list
+	CreateEmailMessage(msg *models.EmailMessage) error
+	UpdateEmailMessageStatus(provider, messageID, email, status, detail string) (int64, error)
+	ListOrderEmailMessages(orderID uint64) ([]models.EmailMessage, error)
+	SuppressEmail(s *models.EmailSuppression) (bool, error)
+	SuppressedEmails(addresses []string) ([]string, error)
+	ListEmailSuppressions(limit int) ([]models.EmailSuppression, error)
+	DeleteEmailSuppression(email string) (bool, error)
 }
 
 // DBExecutor interface that both *sql.DB and *sql.Tx implement
@@ -85,9 +143,9 @@ func nullTimeToPtr(nt sql.NullTime) *time.Time {
 
 // WithTransaction executes a function within a database transaction
 func (r *repository) WithTransaction(fn func(Repository) error) error {
-	// Try to use SmartExecutor's Begin method first
-	if executor, ok := r.db.(interface{ Begin() (*sql.Tx, error) }); ok {
-		tx, err := executor.Begin()
+	// Try to use SmartExecutor's traced writer transaction first
+	if executor, ok := r.db.(*database.SmartExecutor); ok {
+		tx, err := executor.BeginWriteTx()
 		if err != nil {
 			return err
 		}
@@ -99,10 +157,10 @@ func (r *repository) WithTransaction(fn func(Repository) error) error {
 		err = fn(txRepo)
 		if err != nil {
 			tx.Rollback()
-			return err
+			return database.ObserveBusy("tx", err)
 		}
 
-		return tx.Commit()
+		return database.ObserveBusy("commit", tx.Commit())
 	}
 
 	// Fallback: Type assert to *sql.DB to access Begin method
@@ -129,6 +187,53 @@ func (r *repository) WithTransaction(fn func(Repository) error) error {
 	return tx.Commit()
 }
 
+// Primary returns a repository that reads from the writer pool
+func (r *repository) Primary() Repository {
+	if se, ok := r.db.(*database.SmartExecutor); ok {
+		return &repository{db: se.Primary()}
+	}
+	// *sql.Tx and *sql.DB have a single pool, reads are already fresh
+	return r
+}
+
+// Session returns a repository with read-your-writes consistency
+func (r *repository) Session() Repository {
+	if se, ok := r.db.(*database.SmartExecutor); ok {
+		return &repository{db: se.Session()}
+	}
+	return r
+}
+
+// WithContext returns a repository that issues its statements with ctx
+func (r *repository) WithContext(ctx context.Context) Repository {
+	if se, ok := r.db.(*database.SmartExecutor); ok {
+		return &repository{db: se.WithContext(ctx)}
+	}
+	return r
+}
+
+// sqliteTimeLayouts are the text forms go-sqlite3 writes time.Time values in
+var sqliteTimeLayouts = []string{
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02T15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02 15:04:05",
+}
+
+// parseSQLiteTime parses a time read as text (e.g. from MIN/MAX, which lose
+// the column type); NULL and unknown formats give the zero time
+func parseSQLiteTime(s sql.NullString) time.Time {
+	if !s.Valid {
+		return time.Time{}
+	}
+	for _, layout := range sqliteTimeLayouts {
+		if t, err := time.Parse(layout, s.String); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
 // Helper to convert *time.Time to sql.NullTime
 func ptrToNullTime(t *time.Time) sql.NullTime {
 	if t != nil {
@@ -2,63 +2,242 @@ package repository
 
 import (
 	"database/sql"
-	"time"
 	"ecommerce-backend/internal/models"
+	"sort"
+	"time"
 )
 
 // Symbicode repository operations for verification flow only
 func (r *repository) CreateSymbicode(symbicode *models.Symbicode) error {
 	query := `
 		INSERT INTO symbicodes (
-			order_id, product_id, created_at, activated_at, code, secret_key, activated_ip, is_activated
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+			order_id, product_id, batch_id, created_at, activated_at, bound_at, code, secret_key, activated_ip, is_activated
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
 
-	_, err := r.db.Exec(query,
+	// Auto-activation is based on created_at, so it must never be the zero time
+	if symbicode.CreatedAt.IsZero() {
+		symbicode.CreatedAt = time.Now()
+	}
+
+	result, err := r.db.Exec(query,
 		symbicode.OrderID,
 		symbicode.ProductID,
+		symbicode.BatchID,
 		symbicode.CreatedAt,
 		ptrToNullTime(symbicode.ActivatedAt),
+		ptrToNullTime(symbicode.BoundAt),
 		symbicode.Code,
 		symbicode.SecretKey,
 		symbicode.ActivatedIP,
 		symbicode.IsActivated,
 	)
-	return err
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	symbicode.ID = uint64(id)
+	return nil
 }
 
-func (r *repository) GetSymbicodeByCode(code []byte) (*models.Symbicode, error) {
-	query := `
-		SELECT id, order_id, product_id, created_at, activated_at, code, secret_key, activated_ip, is_activated
-		FROM symbicodes WHERE code = ?`
+// symbicodeSelect lists the columns read by scanSymbicode
+const symbicodeSelect = `
+	SELECT id, order_id, product_id, batch_id, created_at, activated_at, bound_at, code, secret_key, activated_ip, is_activated
+	FROM symbicodes`
 
+// scanSymbicode reads one symbicodeSelect row from a *sql.Row or *sql.Rows
+func scanSymbicode(row interface{ Scan(...any) error }) (*models.Symbicode, error) {
 	var symbicode models.Symbicode
-	var activatedAt sql.NullTime
+	var activatedAt, boundAt sql.NullTime
 
-	err := r.db.QueryRow(query, code).Scan(
+	err := row.Scan(
 		&symbicode.ID,
 		&symbicode.OrderID,
 		&symbicode.ProductID,
+		&symbicode.BatchID,
 		&symbicode.CreatedAt,
 		&activatedAt,
+		&boundAt,
 		&symbicode.Code,
 		&symbicode.SecretKey,
 		&symbicode.ActivatedIP,
 		&symbicode.IsActivated,
 	)
-
-	if err == sql.ErrNoRows {
-		return nil, err
-	}
 	if err != nil {
 		return nil, err
 	}
 
 	symbicode.ActivatedAt = nullTimeToPtr(activatedAt)
+	symbicode.BoundAt = nullTimeToPtr(boundAt)
 	return &symbicode, nil
 }
 
+func (r *repository) GetSymbicodeByCode(code []byte) (*models.Symbicode, error) {
+	return scanSymbicode(r.db.QueryRow(symbicodeSelect+` WHERE code = ?`, code))
+}
+
+// GetSymbicodeByID loads a symbicode by primary key
+func (r *repository) GetSymbicodeByID(id uint64) (*models.Symbicode, error) {
+	return scanSymbicode(r.db.QueryRow(symbicodeSelect+` WHERE id = ?`, id))
+}
+
 func (r *repository) ActivateSymbicode(id uint64, ip string) error {
 	query := `UPDATE symbicodes SET is_activated = 1, activated_at = ?, activated_ip = ? WHERE id = ?`
 	_, err := r.db.Exec(query, time.Now(), ip, id)
 	return err
 }
+
+// AutoActivateSymbicodes activates up to limit codes created (or, when
+// pre-generated, bound to their order) before createdBefore that were never
+// scanned, marking them with models.AutoActivatedIP. Unsold pre-generated
+// codes are left alone. It returns the activated IDs in ascending order.
+func (r *repository) AutoActivateSymbicodes(createdBefore time.Time, limit int) ([]uint64, error) {
+	query := `
+		UPDATE symbicodes SET is_activated = 1, activated_at = ?, activated_ip = ?
+		WHERE id IN (
+			SELECT id FROM symbicodes
+			WHERE is_activated = 0 AND (batch_id = 0 OR order_id <> 0)
+				AND COALESCE(bound_at, created_at) < ?
+			ORDER BY id LIMIT ?
+		)
+		RETURNING id`
+
+	rows, err := r.db.Query(query, time.Now(), models.AutoActivatedIP, createdBefore, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []uint64
+	for rows.Next() {
+		var id uint64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	// RETURNING order is unspecified
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids, nil
+}
+
+// CreateSymbicodeScan records one verification of a symbicode
+func (r *repository) CreateSymbicodeScan(scan *models.SymbicodeScan) error {
+	query := `
+		INSERT INTO symbicode_scans (symbicode_id, scanned_at, ip, user_agent, country, city)
+		VALUES (?, ?, ?, ?, ?, ?)`
+
+	// UTC keeps scanned_at ordering (and MIN/MAX below) consistent as text
+	if scan.ScannedAt.IsZero() {
+		scan.ScannedAt = time.Now()
+	}
+	scan.ScannedAt = scan.ScannedAt.UTC()
+
+	result, err := r.db.Exec(query, scan.SymbicodeID, scan.ScannedAt, scan.IP, scan.UserAgent, scan.Country, scan.City)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	scan.ID = uint64(id)
+	return nil
+}
+
+// ListSymbicodeScans returns the latest scans of a symbicode, newest first
+func (r *repository) ListSymbicodeScans(symbicodeID uint64, limit int) ([]models.SymbicodeScan, error) {
+	query := `
+		SELECT id, symbicode_id, scanned_at, ip, user_agent, country, city
+		FROM symbicode_scans WHERE symbicode_id = ?
+		ORDER BY id DESC LIMIT ?`
+
+	rows, err := r.db.Query(query, symbicodeID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var scans []models.SymbicodeScan
+	for rows.Next() {
+		var scan models.SymbicodeScan
+		if err := rows.Scan(&scan.ID, &scan.SymbicodeID, &scan.ScannedAt, &scan.IP, &scan.UserAgent, &scan.Country, &scan.City); err != nil {
+			return nil, err
+		}
+		scans = append(scans, scan)
+	}
+	return scans, rows.Err()
+}
+
+// scanStatsSelect aggregates scans per symbicode; a location is a
+// country/city pair and scans without a known country are not counted
+const scanStatsSelect = `
+	SELECT s.id, s.code, s.product_id, s.order_id,
+		COUNT(sc.id),
+		COUNT(DISTINCT sc.ip),
+		COUNT(DISTINCT CASE WHEN sc.country <> '' THEN sc.country || '/' || sc.city END),
+		MIN(sc.scanned_at), MAX(sc.scanned_at)
+	FROM symbicodes s`
+
+// GetSymbicodeScanStats summarizes all scans of a symbicode
+func (r *repository) GetSymbicodeScanStats(symbicodeID uint64) (*models.SymbicodeScanStats, error) {
+	query := scanStatsSelect + `
+		LEFT JOIN symbicode_scans sc ON sc.symbicode_id = s.id
+		WHERE s.id = ?
+		GROUP BY s.id`
+
+	rows, err := r.db.Query(query, symbicodeID)
+	if err != nil {
+		return nil, err
+	}
+	stats, err := scanScanStats(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(stats) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return &stats[0], nil
+}
+
+// ListSuspiciousSymbicodes returns codes scanned from at least minIPs
+// distinct IPs or minLocations distinct locations, most recently scanned first
+func (r *repository) ListSuspiciousSymbicodes(minIPs, minLocations, limit int) ([]models.SymbicodeScanStats, error) {
+	query := scanStatsSelect + `
+		JOIN symbicode_scans sc ON sc.symbicode_id = s.id
+		GROUP BY s.id
+		HAVING COUNT(DISTINCT sc.ip) >= ?
+			OR COUNT(DISTINCT CASE WHEN sc.country <> '' THEN sc.country || '/' || sc.city END) >= ?
+		ORDER BY MAX(sc.scanned_at) DESC
+		LIMIT ?`
+
+	rows, err := r.db.Query(query, minIPs, minLocations, limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanScanStats(rows)
+}
+
+// scanScanStats reads scanStatsSelect rows and closes them
+func scanScanStats(rows *sql.Rows) ([]models.SymbicodeScanStats, error) {
+	defer rows.Close()
+
+	var out []models.SymbicodeScanStats
+	for rows.Next() {
+		var st models.SymbicodeScanStats
+		var first, last sql.NullString
+		if err := rows.Scan(&st.SymbicodeID, &st.Code, &st.ProductID, &st.OrderID,
+			&st.Scans, &st.DistinctIPs, &st.DistinctLocations, &first, &last); err != nil {
+			return nil, err
+		}
+		st.FirstScanAt = parseSQLiteTime(first)
+		st.LastScanAt = parseSQLiteTime(last)
+		out = append(out, st)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"time"
+
+	"ecommerce-backend/internal/models"
+)
+
+// CreateSymbicodeBatch records a pre-generation run; its codes reference the batch ID
+func (r *repository) CreateSymbicodeBatch(batch *models.SymbicodeBatch) error {
+	query := `INSERT INTO symbicode_batches (created_at, note, product_id, count) VALUES (?, ?, ?, ?)`
+
+	if batch.CreatedAt.IsZero() {
+		batch.CreatedAt = time.Now()
+	}
+	result, err := r.db.Exec(query, batch.CreatedAt, batch.Note, batch.ProductID, batch.Count)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	batch.ID = uint64(id)
+	return nil
+}
+
+// GetSymbicodeBatch loads a batch by ID
+func (r *repository) GetSymbicodeBatch(id uint64) (*models.SymbicodeBatch, error) {
+	query := `SELECT id, created_at, note, product_id, count FROM symbicode_batches WHERE id = ?`
+
+	var batch models.SymbicodeBatch
+	err := r.db.QueryRow(query, id).Scan(&batch.ID, &batch.CreatedAt, &batch.Note, &batch.ProductID, &batch.Count)
+	if err != nil {
+		return nil, err
+	}
+	return &batch, nil
+}
+
+// ListBatchSymbicodes returns the codes of a batch in generation order
+func (r *repository) ListBatchSymbicodes(batchID uint64) ([]models.Symbicode, error) {
+	rows, err := r.db.Query(symbicodeSelect+` WHERE batch_id = ? ORDER BY id`, batchID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var codes []models.Symbicode
+	for rows.Next() {
+		sym, err := scanSymbicode(rows)
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, *sym)
+	}
+	return codes, rows.Err()
+}
+
+// BindSymbicode ties an unsold pre-generated code to its order. It reports
+// false when the code is already bound (or does not exist).
+func (r *repository) BindSymbicode(id, orderID uint64) (bool, error) {
+	query := `UPDATE symbicodes SET order_id = ?, bound_at = ? WHERE id = ? AND order_id = 0`
+
+	result, err := r.db.Exec(query, orderID, time.Now(), id)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n == 1, err
+}
+
+// HasSymbicodeBatches reports whether any batch was generated for the product
+func (r *repository) HasSymbicodeBatches(productID uint64) (bool, error) {
+	var exists bool
+	err := r.db.QueryRow(`SELECT EXISTS (SELECT 1 FROM symbicode_batches WHERE product_id = ?)`, productID).Scan(&exists)
+	return exists, err
+}
+
+// CountOrderSymbicodes counts the codes tied to an order, generated or bound
+func (r *repository) CountOrderSymbicodes(orderID uint64) (int, error) {
+	var n int
+	err := r.db.QueryRow(`SELECT COUNT(*) FROM symbicodes WHERE order_id = ?`, orderID).Scan(&n)
+	return n, err
+}
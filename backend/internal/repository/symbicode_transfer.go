@@ -0,0 +1,165 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"ecommerce-backend/internal/models"
+)
+
+// CreateSymbicodeTransfer stores a pending transfer offer
+func (r *repository) CreateSymbicodeTransfer(transfer *models.SymbicodeTransfer) error {
+	query := `
+		INSERT INTO symbicode_transfers (symbicode_id, created_at, expires_at, token_hash, owner_phone, owner_email)
+		VALUES (?, ?, ?, ?, '', '')`
+
+	if transfer.CreatedAt.IsZero() {
+		transfer.CreatedAt = time.Now()
+	}
+	// UTC keeps expires_at comparable as text in ClaimSymbicodeTransfer
+	transfer.CreatedAt = transfer.CreatedAt.UTC()
+	transfer.ExpiresAt = transfer.ExpiresAt.UTC()
+
+	result, err := r.db.Exec(query, transfer.SymbicodeID, transfer.CreatedAt, transfer.ExpiresAt, transfer.TokenHash)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	transfer.ID = uint64(id)
+	return nil
+}
+
+// CancelSymbicodeTransfers cancels the pending offers of a symbicode and
+// returns how many were canceled
+func (r *repository) CancelSymbicodeTransfers(symbicodeID uint64) (int64, error) {
+	query := `
+		UPDATE symbicode_transfers SET canceled_at = ?
+		WHERE symbicode_id = ? AND claimed_at IS NULL AND canceled_at IS NULL`
+
+	result, err := r.db.Exec(query, time.Now().UTC(), symbicodeID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// ClaimSymbicodeTransfer redeems the pending, unexpired offer with tokenHash
+// for the given contact. It returns sql.ErrNoRows when the token is unknown,
+// already claimed, canceled or expired.
+func (r *repository) ClaimSymbicodeTransfer(tokenHash, phone, email string) (*models.SymbicodeTransfer, error) {
+	query := `
+		UPDATE symbicode_transfers SET claimed_at = ?, owner_phone = ?, owner_email = ?
+		WHERE token_hash = ? AND claimed_at IS NULL AND canceled_at IS NULL AND expires_at > ?
+		RETURNING id, symbicode_id, created_at, expires_at`
+
+	now := time.Now().UTC()
+	transfer := models.SymbicodeTransfer{
+		TokenHash:  tokenHash,
+		OwnerPhone: phone,
+		OwnerEmail: email,
+		ClaimedAt:  &now,
+	}
+	err := r.db.QueryRow(query, now, phone, email, tokenHash, now).Scan(
+		&transfer.ID,
+		&transfer.SymbicodeID,
+		&transfer.CreatedAt,
+		&transfer.ExpiresAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &transfer, nil
+}
+
+// ListSymbicodeOwners returns the claimed transfers of a symbicode in claim
+// order; the last one is the current owner
+func (r *repository) ListSymbicodeOwners(symbicodeID uint64) ([]models.SymbicodeTransfer, error) {
+	query := `
+		SELECT id, symbicode_id, created_at, expires_at, claimed_at, token_hash, owner_phone, owner_email
+		FROM symbicode_transfers
+		WHERE symbicode_id = ? AND claimed_at IS NOT NULL
+		ORDER BY claimed_at, id`
+
+	rows, err := r.db.Query(query, symbicodeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var owners []models.SymbicodeTransfer
+	for rows.Next() {
+		var t models.SymbicodeTransfer
+		var claimedAt sql.NullTime
+		if err := rows.Scan(&t.ID, &t.SymbicodeID, &t.CreatedAt, &t.ExpiresAt, &claimedAt,
+			&t.TokenHash, &t.OwnerPhone, &t.OwnerEmail); err != nil {
+			return nil, err
+		}
+		t.ClaimedAt = nullTimeToPtr(claimedAt)
+		owners = append(owners, t)
+	}
+	return owners, rows.Err()
+}
+
+// TransferCodeAttempts is how many wrong codes kill a transfer code
+const TransferCodeAttempts = 5
+
+// CreateSymbicodeTransferCode stores a transfer code sent to the owner,
+// superseding the symbicode's pending codes so that only the latest counts
+func (r *repository) CreateSymbicodeTransferCode(code *models.SymbicodeTransferCode) error {
+	if code.CreatedAt.IsZero() {
+		code.CreatedAt = time.Now()
+	}
+	// UTC keeps expires_at comparable as text in UseSymbicodeTransferCode
+	code.CreatedAt = code.CreatedAt.UTC()
+	code.ExpiresAt = code.ExpiresAt.UTC()
+
+	_, err := r.db.Exec(`
+		UPDATE symbicode_transfer_codes SET used_at = ?
+		WHERE symbicode_id = ? AND used_at IS NULL`, code.CreatedAt, code.SymbicodeID)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO symbicode_transfer_codes (symbicode_id, created_at, expires_at, channel, contact, code_hash, attempts)
+		VALUES (?, ?, ?, ?, ?, ?, 0)`
+
+	result, err := r.db.Exec(query, code.SymbicodeID, code.CreatedAt, code.ExpiresAt, code.Channel, code.Contact, code.CodeHash)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	code.ID = uint64(id)
+	return nil
+}
+
+// UseSymbicodeTransferCode spends the symbicode's pending code if it has
+// codeHash and was sent to contact on channel, is unexpired and has fewer
+// than TransferCodeAttempts wrong guesses. A miss counts against the pending
+// code, so it is not spent inside a transaction that may roll back.
+func (r *repository) UseSymbicodeTransferCode(symbicodeID uint64, channel, contact, codeHash string) (bool, error) {
+	query := `
+		UPDATE symbicode_transfer_codes SET used_at = ?
+		WHERE symbicode_id = ? AND channel = ? AND contact = ? AND code_hash = ?
+			AND used_at IS NULL AND expires_at > ? AND attempts < ?`
+
+	now := time.Now().UTC()
+	result, err := r.db.Exec(query, now, symbicodeID, channel, contact, codeHash, now, TransferCodeAttempts)
+	if err != nil {
+		return false, err
+	}
+	if n, err := result.RowsAffected(); err != nil || n > 0 {
+		return n > 0, err
+	}
+
+	_, err = r.db.Exec(`
+		UPDATE symbicode_transfer_codes SET attempts = attempts + 1
+		WHERE symbicode_id = ? AND used_at IS NULL`, symbicodeID)
+	return false, err
+}
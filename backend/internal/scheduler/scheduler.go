@@ -0,0 +1,250 @@
+// Package scheduler runs periodic jobs inside the server. Every scheduled
+// run is claimed through a Store lease, so when several instances share a
+// database exactly one of them runs each slot, and all of them can report
+// the last run.
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"ecommerce-backend/internal/logging"
+	"ecommerce-backend/internal/metrics"
+	"ecommerce-backend/internal/tracing"
+
+	"github.com/robfig/cron/v3"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+var tracer = tracing.Tracer("scheduler")
+
+// Job is a periodic task
+type Job struct {
+	Name string
+	// Schedule is a 5-field cron expression ("0 * * * *") or a descriptor
+	// (@hourly, @daily, @every 15m). @every slots are aligned to the Unix
+	// epoch so that all instances agree on them.
+	Schedule string
+	// Timeout bounds one run; 0 means 10 minutes. The lease lasts a minute longer.
+	Timeout time.Duration
+	// Run does the work and returns a short summary for the status page
+	Run func(ctx context.Context) (result string, err error)
+}
+
+// Status is a job's schedule and last run, as served to admins
+type Status struct {
+	Name           string     `json:"name"`
+	Schedule       string     `json:"schedule"`
+	NextRun        time.Time  `json:"next_run"`
+	Running        bool       `json:"running"`
+	LastRun        *time.Time `json:"last_run,omitempty"`
+	LastDurationMS int64      `json:"last_duration_ms,omitempty"`
+	LastStatus     string     `json:"last_status,omitempty"`
+	LastResult     string     `json:"last_result,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+	RanOn          string     `json:"ran_on,omitempty"`
+}
+
+// Options configures a Scheduler
+type Options struct {
+	// Owner identifies this instance in leases; "" means hostname-pid
+	Owner string
+	// Store coordinates instances; nil keeps state in memory (single instance)
+	Store  Store
+	Logger *slog.Logger
+}
+
+// Scheduler runs registered jobs on their schedules
+type Scheduler struct {
+	owner string
+	store Store
+	log   *slog.Logger
+
+	mu   sync.Mutex
+	jobs map[string]*entry
+	wg   sync.WaitGroup
+}
+
+type entry struct {
+	Job
+	schedule cron.Schedule
+}
+
+// ParseSchedule parses a Job.Schedule
+func ParseSchedule(spec string) (cron.Schedule, error) {
+	sched, err := cron.ParseStandard(spec)
+	if err != nil {
+		return nil, err
+	}
+	if every, ok := sched.(cron.ConstantDelaySchedule); ok {
+		return aligned{every.Delay}, nil
+	}
+	return sched, nil
+}
+
+// aligned fires at multiples of d since the Unix epoch
+type aligned struct{ d time.Duration }
+
+func (a aligned) Next(t time.Time) time.Time {
+	return t.Truncate(a.d).Add(a.d)
+}
+
+// New returns a scheduler; register jobs before calling Run
+func New(opts Options) *Scheduler {
+	if opts.Owner == "" {
+		host, _ := os.Hostname()
+		opts.Owner = fmt.Sprintf("%s-%d", host, os.Getpid())
+	}
+	if opts.Store == nil {
+		opts.Store = NewMemoryStore()
+	}
+	return &Scheduler{
+		owner: opts.Owner,
+		store: opts.Store,
+		log:   logging.Subsystem(opts.Logger, "scheduler"),
+		jobs:  make(map[string]*entry),
+	}
+}
+
+// Register adds job; names must be unique
+func (s *Scheduler) Register(job Job) error {
+	sched, err := ParseSchedule(job.Schedule)
+	if err != nil {
+		return fmt.Errorf("job %s: schedule %q: %w", job.Name, job.Schedule, err)
+	}
+	if job.Timeout <= 0 {
+		job.Timeout = 10 * time.Minute
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, dup := s.jobs[job.Name]; dup {
+		return fmt.Errorf("job %s is already registered", job.Name)
+	}
+	s.jobs[job.Name] = &entry{Job: job, schedule: sched}
+	return nil
+}
+
+// Run fires jobs on schedule until ctx is cancelled, then waits for the
+// runs in progress (which see the cancellation) to record their outcome
+func (s *Scheduler) Run(ctx context.Context) {
+	s.mu.Lock()
+	for _, e := range s.jobs {
+		s.wg.Add(1)
+		go s.loop(ctx, e)
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+}
+
+func (s *Scheduler) loop(ctx context.Context, e *entry) {
+	defer s.wg.Done()
+	for {
+		next := e.schedule.Next(time.Now())
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		s.execute(ctx, e, next)
+	}
+}
+
+// RunNow runs the named job immediately (still claiming it through the
+// store). It reports false when another instance is running it.
+func (s *Scheduler) RunNow(ctx context.Context, name string) (bool, error) {
+	s.mu.Lock()
+	e, ok := s.jobs[name]
+	s.mu.Unlock()
+	if !ok {
+		return false, fmt.Errorf("unknown job %q", name)
+	}
+	return s.execute(ctx, e, time.Now()), nil
+}
+
+// execute claims slot and runs the job, reporting whether it ran
+func (s *Scheduler) execute(ctx context.Context, e *entry, slot time.Time) bool {
+	start := time.Now()
+	claimed, err := s.store.Claim(ctx, e.Name, s.owner, slot, start.Add(e.Timeout+time.Minute))
+	if err != nil {
+		s.log.WarnContext(ctx, "job claim failed", "job", e.Name, "error", err)
+		metrics.SchedulerRuns.WithLabelValues(e.Name, "error").Inc()
+		return false
+	}
+	if !claimed {
+		s.log.DebugContext(ctx, "job claimed by another instance", "job", e.Name, "slot", slot)
+		metrics.SchedulerRuns.WithLabelValues(e.Name, "skipped").Inc()
+		return false
+	}
+
+	runCtx, cancel := context.WithTimeout(ctx, e.Timeout)
+	runCtx, span := tracer.Start(runCtx, "Job "+e.Name)
+	span.SetAttributes(attribute.String("scheduler.job", e.Name))
+	result, err := safeRun(runCtx, e.Run)
+	tracing.End(span, &err)
+	cancel()
+
+	run := Run{Started: start, Finished: time.Now(), Result: result, Status: "ok"}
+	if err != nil {
+		run.Status, run.Error = "error", err.Error()
+		s.log.ErrorContext(ctx, "job failed", "job", e.Name, "duration", run.Finished.Sub(start), "error", err)
+	} else {
+		s.log.InfoContext(ctx, "job finished", "job", e.Name, "duration", run.Finished.Sub(start), "result", result)
+	}
+	metrics.SchedulerRuns.WithLabelValues(e.Name, run.Status).Inc()
+
+	// Record even when shutting down, or the lease stays held until it expires
+	if err := s.store.Finish(context.WithoutCancel(ctx), e.Name, s.owner, run); err != nil {
+		s.log.WarnContext(ctx, "job status not recorded", "job", e.Name, "error", err)
+	}
+	return true
+}
+
+func safeRun(ctx context.Context, run func(context.Context) (string, error)) (result string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return run(ctx)
+}
+
+// Status reports every registered job, sorted by name
+func (s *Scheduler) Status(ctx context.Context) ([]Status, error) {
+	states, err := s.store.States(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	out := make([]Status, 0, len(s.jobs))
+	for name, e := range s.jobs {
+		st := Status{Name: name, Schedule: e.Schedule, NextRun: e.schedule.Next(now)}
+		if state, ok := states[name]; ok {
+			st.Running = state.LeaseUntil.After(now)
+			st.RanOn = state.Owner
+			if !state.LastRun.Started.IsZero() {
+				started := state.LastRun.Started
+				st.LastRun = &started
+				st.LastStatus = state.LastRun.Status
+				st.LastResult = state.LastRun.Result
+				st.LastError = state.LastRun.Error
+				if !state.LastRun.Finished.Before(started) {
+					st.LastDurationMS = state.LastRun.Finished.Sub(started).Milliseconds()
+				}
+			}
+		}
+		out = append(out, st)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out, nil
+}
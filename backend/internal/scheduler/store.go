@@ -0,0 +1,165 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+)
+
+// Run is the outcome of one job run
+type Run struct {
+	Started  time.Time
+	Finished time.Time
+	Status   string // ok, error
+	Result   string
+	Error    string
+}
+
+// State is a job's shared lease and last run
+type State struct {
+	Owner      string
+	LeaseUntil time.Time
+	LastSlot   time.Time
+	// LastRun is in progress while LeaseUntil is in the future
+	LastRun Run
+}
+
+// Store coordinates job runs between instances
+type Store interface {
+	// Claim leases the run of job scheduled at slot to owner until
+	// leaseUntil. It fails (false) while another lease is live or once any
+	// instance has claimed slot or a later one.
+	Claim(ctx context.Context, job, owner string, slot, leaseUntil time.Time) (bool, error)
+	// Finish records a claimed run and releases the lease
+	Finish(ctx context.Context, job, owner string, run Run) error
+	// States returns every job's state
+	States(ctx context.Context) (map[string]State, error)
+}
+
+// MemoryStore is a Store for a single instance
+type MemoryStore struct {
+	mu     sync.Mutex
+	states map[string]State
+}
+
+// NewMemoryStore returns an empty MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{states: make(map[string]State)}
+}
+
+func (m *MemoryStore) Claim(ctx context.Context, job, owner string, slot, leaseUntil time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	st := m.states[job]
+	if st.LeaseUntil.After(time.Now()) || !st.LastSlot.Before(slot) {
+		return false, nil
+	}
+	m.states[job] = State{
+		Owner:      owner,
+		LeaseUntil: leaseUntil,
+		LastSlot:   slot,
+		LastRun:    Run{Started: time.Now()},
+	}
+	return true, nil
+}
+
+func (m *MemoryStore) Finish(ctx context.Context, job, owner string, run Run) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	st, ok := m.states[job]
+	if !ok || st.Owner != owner {
+		return nil
+	}
+	st.LeaseUntil = time.Time{}
+	st.LastRun = run
+	m.states[job] = st
+	return nil
+}
+
+func (m *MemoryStore) States(ctx context.Context) (map[string]State, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make(map[string]State, len(m.states))
+	for k, v := range m.states {
+		out[k] = v
+	}
+	return out, nil
+}
+
+// SQLStore keeps job state in the scheduler_jobs table (models.SchedulerJob)
+// so instances sharing the database coordinate through it. db must be the
+// writer pool: claims are conditional upserts.
+type SQLStore struct {
+	db *sql.DB
+}
+
+// NewSQLStore returns a store backed by db
+func NewSQLStore(db *sql.DB) *SQLStore {
+	return &SQLStore{db: db}
+}
+
+func (s *SQLStore) Claim(ctx context.Context, job, owner string, slot, leaseUntil time.Time) (bool, error) {
+	now := time.Now()
+	res, err := s.db.ExecContext(ctx, `
+		INSERT INTO scheduler_jobs (name, owner, lease_until, last_slot, last_started_at, last_finished_at, last_status, last_result, last_error)
+		VALUES (?, ?, ?, ?, ?, 0, '', '', '')
+		ON CONFLICT(name) DO UPDATE SET
+			owner = excluded.owner,
+			lease_until = excluded.lease_until,
+			last_slot = excluded.last_slot,
+			last_started_at = excluded.last_started_at
+		WHERE scheduler_jobs.lease_until < ? AND scheduler_jobs.last_slot < excluded.last_slot`,
+		job, owner, leaseUntil.UnixMilli(), slot.UnixMilli(), now.UnixMilli(), now.UnixMilli())
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+func (s *SQLStore) Finish(ctx context.Context, job, owner string, run Run) error {
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE scheduler_jobs
+		SET lease_until = 0, last_started_at = ?, last_finished_at = ?, last_status = ?, last_result = ?, last_error = ?
+		WHERE name = ? AND owner = ?`,
+		run.Started.UnixMilli(), run.Finished.UnixMilli(), run.Status, run.Result, run.Error, job, owner)
+	return err
+}
+
+func (s *SQLStore) States(ctx context.Context) (map[string]State, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT name, owner, lease_until, last_slot, last_started_at, last_finished_at, last_status, last_result, last_error
+		FROM scheduler_jobs`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make(map[string]State)
+	for rows.Next() {
+		var (
+			name                           string
+			st                             State
+			lease, slot, started, finished int64
+		)
+		if err := rows.Scan(&name, &st.Owner, &lease, &slot, &started, &finished,
+			&st.LastRun.Status, &st.LastRun.Result, &st.LastRun.Error); err != nil {
+			return nil, err
+		}
+		st.LeaseUntil = fromMillis(lease)
+		st.LastSlot = fromMillis(slot)
+		st.LastRun.Started = fromMillis(started)
+		st.LastRun.Finished = fromMillis(finished)
+		out[name] = st
+	}
+	return out, rows.Err()
+}
+
+// fromMillis converts unix milliseconds, keeping 0 as the zero time
+func fromMillis(ms int64) time.Time {
+	if ms == 0 {
+		return time.Time{}
+	}
+	return time.UnixMilli(ms)
+}
@@ -0,0 +1,47 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
+)
+
+// SecretsManager reads secrets from AWS Secrets Manager (LocalStack in
+// development). Key PAYOS_API_KEY maps to secret "<Prefix>payos-api-key".
+type SecretsManager struct {
+	api    *secretsmanager.Client
+	prefix string
+}
+
+// NewSecretsManager creates a provider for secrets named prefix + key
+func NewSecretsManager(cfg aws.Config, prefix string) *SecretsManager {
+	return &SecretsManager{api: secretsmanager.NewFromConfig(cfg), prefix: prefix}
+}
+
+// SecretName returns the Secrets Manager name used for key
+func (s *SecretsManager) SecretName(key string) string {
+	return s.prefix + strings.ReplaceAll(strings.ToLower(key), "_", "-")
+}
+
+func (s *SecretsManager) Lookup(ctx context.Context, key string) (string, bool, error) {
+	name := s.SecretName(key)
+	out, err := s.api.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(name),
+	})
+	if err != nil {
+		var nf *types.ResourceNotFoundException
+		if errors.As(err, &nf) {
+			return "", false, nil
+		}
+		return "", false, fmt.Errorf("get secret %s: %w", name, err)
+	}
+	if out.SecretString == nil {
+		return "", false, nil
+	}
+	return *out.SecretString, true, nil
+}
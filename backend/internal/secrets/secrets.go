@@ -0,0 +1,96 @@
+// Package secrets resolves secret values by key (e.g. PAYOS_API_KEY) from
+// the environment, a directory of files (Docker/Kubernetes secrets) or AWS
+// Secrets Manager, so credentials are loaded once at startup and reloaded on
+// SIGHUP instead of being read ad hoc.
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"ecommerce-backend/internal/logging"
+)
+
+// Provider looks up one secret. ok is false when the provider does not have it.
+type Provider interface {
+	Lookup(ctx context.Context, key string) (value string, ok bool, err error)
+}
+
+// Env reads secrets from environment variables
+type Env struct{}
+
+func (Env) Lookup(ctx context.Context, key string) (string, bool, error) {
+	v := os.Getenv(key)
+	return v, v != "", nil
+}
+
+// Dir reads each secret from a file named after the key (or its lowercase
+// form), e.g. /run/secrets/payos_api_key. Trailing newlines are trimmed.
+type Dir struct {
+	Path string
+}
+
+func (d Dir) Lookup(ctx context.Context, key string) (string, bool, error) {
+	for _, name := range []string{key, strings.ToLower(key)} {
+		b, err := os.ReadFile(filepath.Join(d.Path, name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return "", false, fmt.Errorf("read secret %s: %w", name, err)
+		}
+		return strings.TrimRight(string(b), "\r\n"), true, nil
+	}
+	return "", false, nil
+}
+
+// Chain asks each provider in order; the first one that has the key wins
+type Chain []Provider
+
+func (c Chain) Lookup(ctx context.Context, key string) (string, bool, error) {
+	for _, p := range c {
+		v, ok, err := p.Lookup(ctx, key)
+		if err != nil {
+			return "", false, err
+		}
+		if ok {
+			return v, true, nil
+		}
+	}
+	return "", false, nil
+}
+
+// Getter adapts p for config loaders: missing keys resolve to ""
+func Getter(ctx context.Context, p Provider) func(key string) (string, error) {
+	return func(key string) (string, error) {
+		v, _, err := p.Lookup(ctx, key)
+		return v, err
+	}
+}
+
+// ReloadOnSIGHUP calls reload every time the process receives SIGHUP, until
+// ctx is cancelled. A failed reload is logged and the previous values stay in use.
+func ReloadOnSIGHUP(ctx context.Context, reload func(context.Context) error) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGHUP)
+	go func() {
+		defer signal.Stop(ch)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ch:
+				if err := reload(ctx); err != nil {
+					logging.Subsystem(nil, "secrets").Error("reload failed, keeping previous values", "error", err)
+					continue
+				}
+				logging.Subsystem(nil, "secrets").Info("reloaded")
+			}
+		}
+	}()
+}
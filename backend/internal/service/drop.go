@@ -1,7 +1,11 @@
 package service
 
 import (
+	"context"
 	"ecommerce-backend/internal/models"
+	"ecommerce-backend/internal/tracing"
+	"ecommerce-backend/internal/webhooks"
+	"strconv"
 	"time"
 )
 
@@ -23,18 +27,51 @@ type LimitedDropStatus struct {
 }
 
 // GetActiveDrops returns all active drops
-func (s *service) GetActiveDrops() ([]models.LimitedDrop, error) {
-	return s.repo.GetActiveDrops()
+func (s *service) GetActiveDrops(ctx context.Context) (_ []models.LimitedDrop, err error) {
+	ctx, span := tracer.Start(ctx, "Service.GetActiveDrops")
+	defer tracing.End(span, &err)
+
+	return s.repo.WithContext(ctx).GetActiveDrops()
+}
+
+// AnnounceStartedDrops sends drop.started for the active drops that
+// started within the last within and returns how many did. A drop is
+// announced once, however often this runs.
+func (s *service) AnnounceStartedDrops(ctx context.Context, within time.Duration) (n int, err error) {
+	ctx, span := tracer.Start(ctx, "Service.AnnounceStartedDrops")
+	defer tracing.End(span, &err)
+
+	if s.webhooks == nil {
+		return 0, nil
+	}
+	drops, err := s.repo.WithContext(ctx).GetActiveDrops()
+	if err != nil {
+		return 0, err
+	}
+	now := time.Now()
+	for i := range drops {
+		d := &drops[i]
+		if d.StartTime.After(now) || d.StartTime.Before(now.Add(-within)) {
+			continue
+		}
+		s.webhooks.Emit(ctx, webhooks.DropStarted, strconv.FormatUint(d.ID, 10), webhooks.Drop(d))
+		n++
+	}
+	return n, nil
 }
 
 // GetDropStatus returns the status of a specific drop
-func (s *service) GetDropStatus(id uint64) (*LimitedDropStatus, error) {
-	drop, err := s.repo.GetDropByID(id)
+func (s *service) GetDropStatus(ctx context.Context, id uint64) (_ *LimitedDropStatus, err error) {
+	ctx, span := tracer.Start(ctx, "Service.GetDropStatus")
+	defer tracing.End(span, &err)
+
+	repo := s.repo.WithContext(ctx)
+	drop, err := repo.GetDropByID(id)
 	if err != nil {
 		return nil, err
 	}
 
-	product, err := s.repo.GetProductByID(drop.ProductID)
+	product, err := repo.GetProductByID(drop.ProductID)
 	if err != nil {
 		return nil, err
 	}
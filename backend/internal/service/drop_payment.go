@@ -1,22 +1,68 @@
 package service
 
 import (
+	"context"
+	"ecommerce-backend/internal/alerts"
+	"ecommerce-backend/internal/emails"
+	"ecommerce-backend/internal/integrations"
+	"ecommerce-backend/internal/metrics"
 	"ecommerce-backend/internal/models"
 	"ecommerce-backend/internal/repository"
-	"ecommerce-backend/internal/utils/uuid"
+	"ecommerce-backend/internal/tracing"
+	"ecommerce-backend/internal/utils/base32"
+	"ecommerce-backend/internal/webhooks"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strconv"
 	"time"
+
+	"go.opentelemetry.io/otel/attribute"
 )
 
+// errAlreadyProcessed aborts the payment transaction when another delivery won
+var errAlreadyProcessed = errors.New("order already processed")
+
+// errLostDrop reports a paid order that was cancelled because stock ran out
+var errLostDrop = errors.New("order lost the drop")
+
 // ProcessSuccessfulDropPayment processes a successful PayOS payment for limited drops
-func (s *service) ProcessSuccessfulDropPayment(orderCode int64) error {
+func (s *service) ProcessSuccessfulDropPayment(ctx context.Context, orderCode int64) (err error) {
+	ctx, span := tracer.Start(ctx, "Service.ProcessSuccessfulDropPayment")
+	span.SetAttributes(attribute.Int64("payos.order_code", orderCode))
+	defer tracing.End(span, &err)
+
+	start := time.Now()
+	err = s.processDropPayment(ctx, orderCode)
+	metrics.PaymentDuration.Observe(time.Since(start).Seconds())
+
+	// Duplicates and losers are final outcomes, not failures to retry
+	switch {
+	case err == nil:
+		metrics.PaymentResults.WithLabelValues("winner").Inc()
+		s.log.InfoContext(ctx, "payment processed", "order_code", orderCode, "result", "winner")
+	case errors.Is(err, errAlreadyProcessed):
+		metrics.PaymentResults.WithLabelValues("duplicate").Inc()
+		s.log.InfoContext(ctx, "payment already processed", "order_code", orderCode)
+		err = nil
+	case errors.Is(err, errLostDrop):
+		metrics.PaymentResults.WithLabelValues("sold_out").Inc()
+		s.log.InfoContext(ctx, "payment processed", "order_code", orderCode, "result", "sold_out")
+		err = nil
+	default:
+		metrics.PaymentResults.WithLabelValues("error").Inc()
+		s.log.ErrorContext(ctx, "payment processing failed", "order_code", orderCode, "error", err)
+	}
+	return err
+}
+
+func (s *service) processDropPayment(ctx context.Context, orderCode int64) error {
 	// 1. Retrieve the existing order using PayOS Order Code
-	order, err := s.repo.GetOrderByPayOSOrderCode(orderCode)
+	// Read from the writer: the idempotency check below must not see a stale status
+	repo := s.repo.WithContext(ctx)
+	order, err := repo.Primary().GetOrderByPayOSOrderCode(orderCode)
 	if err != nil {
-		fmt.Printf("Order not found for code %d: %v\n", orderCode, err)
-		return err // Order must exist (created in PurchaseDrop)
+		return fmt.Errorf("order not found for code %d: %w", orderCode, err) // Order must exist (created in PurchaseDrop)
 	}
 	if order == nil {
 		return fmt.Errorf("order not found for code %d", orderCode)
@@ -24,7 +70,7 @@ func (s *service) ProcessSuccessfulDropPayment(orderCode int64) error {
 
 	// 2. Idempotency Check
 	if order.Status == models.OrderPaid || order.Status == models.OrderConfirmed {
-		return nil // Already processed
+		return errAlreadyProcessed
 	}
 
 	// 3. Extract Drop Info from Order Items (JSON)
@@ -51,12 +97,6 @@ func (s *service) ProcessSuccessfulDropPayment(orderCode int64) error {
 	}
 	quantity := int(quantityVal)
 
-	productIDVal, ok := item["product_id"].(float64)
-	if !ok {
-		return errors.New("invalid product_id in items")
-	}
-	productID := uint64(productIDVal)
-
 	// Extract info for notifications
 	var shippingAddress map[string]interface{}
 	json.Unmarshal([]byte(order.ShippingAddress), &shippingAddress)
@@ -68,33 +108,55 @@ func (s *service) ProcessSuccessfulDropPayment(orderCode int64) error {
 	if name, ok := shippingAddress["name"].(string); ok {
 		customerName = name
 	}
-	shippingAddrStr := string(order.ShippingAddress)
+	locale, _ := shippingAddress["locale"].(string)
 
 	// 4. Execute Atomic Transaction (Stock + Order Status + Symbicode)
-	err = s.repo.WithTransaction(func(tx repository.Repository) error {
+	var soldOut *models.LimitedDrop // set when this order took the last unit
+	err = repo.WithTransaction(func(tx repository.Repository) error {
+		// 4.0. Re-check under the write lock: queued jobs and PayOS retries are
+		// delivered at least once, possibly to two workers at the same time
+		current, err := tx.GetOrderByPayOSOrderCode(orderCode)
+		if err != nil {
+			return err
+		}
+		if current == nil {
+			return fmt.Errorf("order not found for code %d", orderCode)
+		}
+		if current.Status == models.OrderPaid || current.Status == models.OrderConfirmed {
+			return errAlreadyProcessed
+		}
+
 		// 4.1. Increment Stock (Atomic Check)
 		if err := tx.IncrementSoldCount(dropID, uint32(quantity)); err != nil {
 			return err // Will be handled below (ErrSoldOut or other)
 		}
+		// The drop names the product; orders placed before the fix carry
+		// the drop ID as their item product_id
+		drop, err := tx.GetDropByID(dropID)
+		if err != nil {
+			return fmt.Errorf("failed to load drop %d: %w", dropID, err)
+		}
+		if drop.Sold >= drop.TotalStock {
+			soldOut = drop
+		}
 
 		// 4.2. Update Order Status to PAID
 		if err := tx.UpdateOrderStatus(order.ID, models.OrderPaid); err != nil {
 			return err
 		}
 
-		// 4.3. Create Symbicode
-		code, err := uuid.GenerateUUIDv7()
+		// 4.3. Create Symbicode, unless the product's tags are pre-printed:
+		// those are bound to the order at fulfillment (BindSymbicode)
+		printed, err := tx.HasSymbicodeBatches(drop.ProductID)
 		if err != nil {
-			return fmt.Errorf("failed to generate uuid: %w", err)
+			return err
 		}
-		secret := generateSecretKey()
-
-		sym := &models.Symbicode{
-			Code:        code,
-			SecretKey:   secret,
-			ProductID:   productID,
-			IsActivated: 0,
-			OrderID:     order.ID,
+		if printed {
+			return nil
+		}
+		sym, err := s.newSymbicode(drop.ProductID, order.ID)
+		if err != nil {
+			return err
 		}
 
 		if err := tx.CreateSymbicode(sym); err != nil {
@@ -107,40 +169,112 @@ func (s *service) ProcessSuccessfulDropPayment(orderCode int64) error {
 	// 5. Handle Transaction Result
 	if err != nil {
 		if errors.Is(err, repository.ErrSoldOut) {
-			// LOSER: Stock ran out during transaction attempt
-			// Update status to Cancelled
-			s.repo.UpdateOrderStatus(order.ID, models.OrderCancelled)
+			// LOSER: Stock ran out during transaction attempt. Only the
+			// delivery that cancels the order queues its refund and
+			// notifies; a redelivered or concurrent webhook for a lost
+			// order stops here. The refund is written with the
+			// cancellation and issued by ProcessRefunds.
+			var cancelled bool
+			err := repo.WithTransaction(func(tx repository.Repository) error {
+				var err error
+				if cancelled, err = tx.CancelOrder(order.ID); err != nil || !cancelled {
+					return err
+				}
+				return tx.CreateRefund(&models.Refund{OrderID: order.ID, OrderCode: orderCode, Reason: "Limited drop sold out"})
+			})
+			if err != nil {
+				return fmt.Errorf("failed to cancel order: %w", err)
+			}
 
-			// Send Loser Notification
-			go s.email.SendSymbioteReceipt(customerEmail, order.CustomerPhone, "LOSER", "N/A")
-			return nil
+			// Send Loser Notification; keep the request ID but not the cancellation
+			notifyCtx := integrations.WithEmailOrder(emails.WithLocale(context.WithoutCancel(ctx), emails.ParseLocale(locale)), order.ID)
+			if d, err := repo.GetDropByID(dropID); err == nil && d != nil {
+				s.dropSoldOut(notifyCtx, d)
+			}
+			if !cancelled {
+				return errLostDrop
+			}
+			lost := *order
+			lost.Status = models.OrderCancelled
+			s.webhooks.Emit(notifyCtx, webhooks.OrderCancelled, strconv.FormatUint(order.ID, 10), webhooks.Order(&lost))
+			go func() {
+				if err := s.notifier.Notify(notifyCtx, integrations.Notification{
+					At:          time.Now(),
+					Event:       integrations.EventDropLost,
+					Name:        customerName,
+					Phone:       order.CustomerPhone,
+					Email:       customerEmail,
+					OrderNumber: base32.GenerateOrderNumber(order.ID),
+					OrderID:     order.ID,
+					Amount:      order.TotalAmount,
+				}); err != nil {
+					s.log.WarnContext(notifyCtx, "loser notification failed", "order_id", order.ID, "customer_email", customerEmail, "error", err)
+				}
+			}()
+			return errLostDrop
 		}
 		// Other errors: Return to retry (or log if fatal)
 		return err
 	}
 
-	// 6. WINNER: Send Notifications (Async)
+	// 6. WINNER: Send Notifications (Async); keep the request ID but not the cancellation
+	notifyCtx := integrations.WithEmailOrder(emails.WithLocale(context.WithoutCancel(ctx), emails.ParseLocale(locale)), order.ID)
+	paid := *order
+	paid.Status = models.OrderPaid
+	s.alerts.Alert(notifyCtx, alerts.Alert{
+		Kind:   alerts.OrderPaid,
+		Key:    base32.GenerateOrderNumber(order.ID),
+		Title:  "New paid order " + base32.GenerateOrderNumber(order.ID),
+		Detail: fmt.Sprintf("%s, %s %s", emails.FormatMoney(emails.DefaultLocale, int64(order.TotalAmount)), customerName, order.CustomerPhone),
+		Order:  &paid,
+	})
+	s.webhooks.Emit(notifyCtx, webhooks.OrderPaid, strconv.FormatUint(order.ID, 10), webhooks.Order(&paid))
+	if soldOut != nil {
+		s.dropSoldOut(notifyCtx, soldOut)
+	}
 	go func() {
 		defer func() {
 			if r := recover(); r != nil {
-				// Log
+				s.log.ErrorContext(notifyCtx, "winner notifications panicked", "order_id", order.ID, "panic", r)
 			}
 		}()
 
-		s.email.SendOrderConfirmation(customerEmail, fmt.Sprintf("DV-%d", order.ID), float64(order.TotalAmount))
+		orderNumber := base32.GenerateOrderNumber(order.ID)
+		n := integrations.Notification{
+			At:          time.Now(),
+			Event:       integrations.EventOrderConfirmation,
+			Name:        customerName,
+			Phone:       order.CustomerPhone,
+			Email:       customerEmail,
+			OrderNumber: orderNumber,
+			OrderID:     order.ID,
+			Amount:      order.TotalAmount,
+		}
+		if err := s.notifier.Notify(notifyCtx, n); err != nil {
+			s.log.WarnContext(notifyCtx, "order confirmation failed", "order_id", order.ID, "customer_email", customerEmail, "error", err)
+		}
 
-		s.sheets.SubmitOrder(
-			customerName,
-			order.CustomerPhone,
-			customerEmail,
-			shippingAddrStr,
-			"Winner - Limited Drop",
-			float64(order.TotalAmount),
-			time.Now(),
-		)
+		if err := s.sheets.QueueOrder(notifyCtx, order.ID, n.At); err != nil {
+			s.log.WarnContext(notifyCtx, "sheet export queue failed", "order_id", order.ID, "error", err)
+		}
 
-		s.email.SendSymbioteReceipt(customerEmail, order.CustomerPhone, "WINNER", time.Now().Format("2006-01-02 15:04:05"))
+		n.Event = integrations.EventDropWon
+		if err := s.notifier.Notify(notifyCtx, n); err != nil {
+			s.log.WarnContext(notifyCtx, "winner receipt failed", "order_id", order.ID, "customer_email", customerEmail, "error", err)
+		}
 	}()
 
 	return nil
 }
+
+// dropSoldOut tells admins and webhook subscribers the drop ran out; admin
+// alerts are throttled per drop and subscribers hear about it once
+func (s *service) dropSoldOut(ctx context.Context, d *models.LimitedDrop) {
+	s.webhooks.Emit(ctx, webhooks.DropSoldOut, strconv.FormatUint(d.ID, 10), webhooks.Drop(d))
+	s.alerts.Alert(ctx, alerts.Alert{
+		Kind:   alerts.DropSoldOut,
+		Key:    strconv.FormatUint(d.ID, 10),
+		Title:  "Drop sold out",
+		Detail: fmt.Sprintf("%s #%d (%d/%d)", d.Name, d.ID, d.Sold, d.TotalStock),
+	})
+}
@@ -1,48 +1,118 @@
 package service
 
 import (
+	"context"
+	"database/sql"
+	"ecommerce-backend/internal/emails"
 	"ecommerce-backend/internal/integrations"
+	"ecommerce-backend/internal/metrics"
+	"ecommerce-backend/internal/repository"
+	"ecommerce-backend/internal/tracing"
+	"ecommerce-backend/internal/utils/base32"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"os"
+	"strconv"
 	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Purchase rejections; the messages are shown to buyers as-is.
+// A sold out drop returns repository.ErrSoldOut.
+var (
+	ErrDropNotActive  = errors.New("limited drop is not active")
+	ErrDropNotStarted = errors.New("limited drop has not started yet")
+	ErrDropEnded      = errors.New("limited drop has ended")
+	ErrDropSizeLimit  = errors.New("limited drop size limit reached")
 )
 
 // PurchaseDrop handles the business logic for purchasing drop items
-func (s *service) PurchaseDrop(dropID uint64, req *PurchaseRequest) (*PurchaseResult, error) {
+func (s *service) PurchaseDrop(ctx context.Context, dropID uint64, req *PurchaseRequest) (*PurchaseResult, error) {
+	ctx, span := tracer.Start(ctx, "Service.PurchaseDrop")
+	result, err := s.purchaseDrop(ctx, dropID, req)
+
+	outcome := purchaseOutcome(err)
+	span.SetAttributes(attribute.Int64("drop.id", int64(dropID)), attribute.String("purchase.outcome", outcome))
+	// Rejections are answers, not failures; only unexpected errors mark the span
+	if outcome == "error" {
+		tracing.End(span, &err)
+	} else {
+		span.End()
+	}
+	drop := strconv.FormatUint(dropID, 10)
+	if outcome == "not_found" {
+		drop = "unknown" // keep label cardinality bounded for made-up IDs
+	}
+	metrics.PurchaseAttempts.WithLabelValues(drop, outcome).Inc()
+	switch outcome {
+	case "ok":
+		s.log.InfoContext(ctx, "purchase created", "drop_id", dropID, "order_code", result.OrderCode, "customer_phone", req.Phone)
+	case "error":
+		s.log.ErrorContext(ctx, "purchase failed", "drop_id", dropID, "customer_phone", req.Phone, "error", err)
+	default:
+		// Expected rejections (sold out, not started, ...) are high volume during a launch
+		s.log.DebugContext(ctx, "purchase rejected", "drop_id", dropID, "outcome", outcome)
+	}
+
+	return result, err
+}
+
+// purchaseOutcome maps a PurchaseDrop error to its metrics label
+func purchaseOutcome(err error) string {
+	switch {
+	case err == nil:
+		return "ok"
+	case errors.Is(err, repository.ErrSoldOut):
+		return "sold_out"
+	case errors.Is(err, ErrDropSizeLimit):
+		return "size_limit"
+	case errors.Is(err, ErrDropNotStarted):
+		return "not_started"
+	case errors.Is(err, ErrDropEnded):
+		return "ended"
+	case errors.Is(err, ErrDropNotActive):
+		return "not_active"
+	case errors.Is(err, sql.ErrNoRows):
+		return "not_found"
+	default:
+		return "error"
+	}
+}
+
+func (s *service) purchaseDrop(ctx context.Context, dropID uint64, req *PurchaseRequest) (*PurchaseResult, error) {
 	// Get the drop
-	drop, err := s.repo.GetDropByID(dropID)
+	drop, err := s.repo.WithContext(ctx).GetDropByID(dropID)
 	if err != nil {
 		return nil, err
 	}
 
 	// Check if drop is active
 	if drop.IsActive != 1 {
-		return nil, errors.New("limited drop is not active")
+		return nil, ErrDropNotActive
 	}
 
 	// Check if drop is still running
 	now := time.Now()
 	if now.Before(drop.StartTime) {
-		return nil, errors.New("limited drop has not started yet")
+		return nil, ErrDropNotStarted
 	}
 	if drop.EndTime != nil && now.After(*drop.EndTime) {
-		return nil, errors.New("limited drop has ended")
+		return nil, ErrDropEnded
 	}
 
 	// Check if stock is available
 	if drop.Sold >= drop.TotalStock {
-		return nil, errors.New("limited drop is sold out")
+		return nil, repository.ErrSoldOut
 	}
 
 	// Check drop size limit
 	if drop.Sold >= drop.DropSize {
-		return nil, errors.New("limited drop size limit reached")
+		return nil, ErrDropSizeLimit
 	}
 
 	// Get the product for pricing
-	product, err := s.GetProduct(drop.ProductID)
+	product, err := s.GetProduct(ctx, drop.ProductID)
 	if err != nil {
 		return nil, err
 	}
@@ -61,13 +131,14 @@ func (s *service) PurchaseDrop(dropID uint64, req *PurchaseRequest) (*PurchaseRe
 		"province": req.Province,
 		"district": req.District,
 		"ward":     req.Ward,
+		"locale":   string(emails.ParseLocale(req.Locale)),
 	}
 	shippingJSON, _ := json.Marshal(shippingAddress)
 
 	// Create items JSON
 	items := []map[string]interface{}{
 		{
-			"product_id": dropID,
+			"product_id": drop.ProductID,
 			"drop_id":    dropID,
 			"name":       product.Name,
 			"price":      product.Price,
@@ -79,21 +150,18 @@ func (s *service) PurchaseDrop(dropID uint64, req *PurchaseRequest) (*PurchaseRe
 	// Create order in database FIRST with PENDING payment status (status = 1)
 	// This ensures that if payment is successful, we definitely have the order record.
 	// Pass PayOSOrderCode to CreateOrder to link the transaction
-	_, err = s.CreateOrder(req.Phone, shippingJSON, itemsJSON, 1, &orderCode) // 1 = PayOS payment method
+	order, err := s.CreateOrder(ctx, req.Phone, shippingJSON, itemsJSON, 1, &orderCode) // 1 = PayOS payment method
 	if err != nil {
 		return nil, fmt.Errorf("failed to create local order: %w", err)
 	}
+	orderNumber := base32.GenerateOrderNumber(order.ID)
 
-	// Get frontend URL from environment, default to localhost:3000
-	frontendURL := os.Getenv("FRONTEND_URL")
-	if frontendURL == "" {
-		frontendURL = "http://localhost:3000"
-	}
+	frontendURL := s.storefrontURL()
 
 	payosReq := integrations.PayOSCheckoutRequest{
 		OrderCode:   orderCode,
 		Amount:      int64(amount),
-		Description: fmt.Sprintf("Drop %d", dropID),
+		Description: orderNumber, // shown on the buyer's bank transfer
 		ReturnURL:   frontendURL + "/#payment-success",
 		CancelURL:   frontendURL + "/#payment-cancel",
 		Items: []integrations.PayOSItem{
@@ -105,15 +173,24 @@ func (s *service) PurchaseDrop(dropID uint64, req *PurchaseRequest) (*PurchaseRe
 		},
 	}
 
-	checkout, err := s.payment.CreateCheckout(payosReq)
+	checkout, err := s.payment.CreateCheckout(ctx, payosReq)
 	if err != nil {
 		// If checkout creation fails, the order remains as PENDING (Abandoned Cart)
 		return nil, fmt.Errorf("failed to create PayOS checkout: %w", err)
 	}
 
 	return &PurchaseResult{
-		Message:    "Đơn hàng đã được tạo!",
-		PaymentURL: checkout.Data.CheckoutURL,
-		OrderCode:  orderCode,
+		Message:     "Đơn hàng đã được tạo!",
+		PaymentURL:  checkout.Data.CheckoutURL,
+		OrderCode:   orderCode,
+		OrderNumber: orderNumber,
 	}, nil
 }
+
+// storefrontURL is the frontend URL from config, default localhost:3000
+func (s *service) storefrontURL() string {
+	if s.frontendURL != "" {
+		return s.frontendURL
+	}
+	return "http://localhost:3000"
+}
@@ -0,0 +1,176 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"ecommerce-backend/internal/integrations"
+	"ecommerce-backend/internal/logging"
+	"ecommerce-backend/internal/metrics"
+	"ecommerce-backend/internal/models"
+	"ecommerce-backend/internal/repository"
+	"ecommerce-backend/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// ErrSuppressionNotFound is returned when removing an address that is not
+// on the suppression list
+var ErrSuppressionNotFound = errors.New("email address is not suppressed")
+
+// OrderEmails is the delivery state of the emails sent about an order
+type OrderEmails struct {
+	OrderID  uint64                `json:"order_id"`
+	Messages []models.EmailMessage `json:"messages"`
+	// Suppressed are the order's addresses that no longer receive email
+	Suppressed []string `json:"suppressed"`
+}
+
+// NewEmailLog records the emails the Emailer sends and serves it the
+// suppression list, from repo
+func NewEmailLog(repo repository.Repository) integrations.EmailLog {
+	return emailLog{repo: repo}
+}
+
+type emailLog struct {
+	repo repository.Repository
+}
+
+func (l emailLog) RecordEmail(ctx context.Context, msg *models.EmailMessage) error {
+	return l.repo.WithContext(ctx).CreateEmailMessage(msg)
+}
+
+func (l emailLog) SuppressedEmails(ctx context.Context, addresses []string) ([]string, error) {
+	return l.repo.WithContext(ctx).SuppressedEmails(addresses)
+}
+
+// HandleEmailEvent applies a provider's delivery webhook: it advances the
+// message's status and suppresses the address on a hard bounce or complaint.
+// Events for messages this process did not record still suppress.
+func (s *service) HandleEmailEvent(ctx context.Context, ev integrations.EmailEvent) (err error) {
+	ctx, span := tracer.Start(ctx, "Service.HandleEmailEvent")
+	span.SetAttributes(attribute.String("email.provider", ev.Provider), attribute.String("email.status", ev.Status))
+	defer tracing.End(span, &err)
+
+	repo := s.repo.WithContext(ctx)
+	email := strings.ToLower(strings.TrimSpace(ev.Email))
+	updated, err := repo.UpdateEmailMessageStatus(ev.Provider, ev.MessageID, email, ev.Status, ev.Detail)
+	if err != nil {
+		return fmt.Errorf("failed to update email status: %w", err)
+	}
+	metrics.EmailEvents.WithLabelValues(ev.Provider, ev.Status).Inc()
+
+	if ev.Suppress && email != "" {
+		added, err := repo.SuppressEmail(&models.EmailSuppression{
+			Email:     email,
+			Reason:    ev.Status,
+			Provider:  ev.Provider,
+			MessageID: ev.MessageID,
+			Detail:    ev.Detail,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to suppress email: %w", err)
+		}
+		if added {
+			s.log.WarnContext(ctx, "email address suppressed",
+				"email", logging.MaskEmail(email), "reason", ev.Status, "provider", ev.Provider, "detail", ev.Detail)
+		}
+	}
+	if updated == 0 {
+		s.log.DebugContext(ctx, "email event for an unknown or newer message",
+			"provider", ev.Provider, "message_id", ev.MessageID, "status", ev.Status)
+	}
+	return nil
+}
+
+// GetOrderEmails returns the emails sent about an order with their delivery
+// state, and which of the order's addresses are suppressed
+func (s *service) GetOrderEmails(ctx context.Context, orderID uint64) (_ *OrderEmails, err error) {
+	ctx, span := tracer.Start(ctx, "Service.GetOrderEmails")
+	defer tracing.End(span, &err)
+
+	repo := s.repo.WithContext(ctx)
+	order, err := repo.GetOrderByID(orderID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrOrderNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	messages, err := repo.ListOrderEmailMessages(orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	var shipping orderShipping
+	json.Unmarshal(order.ShippingAddress, &shipping)
+	seen := make(map[string]bool)
+	var addresses []string
+	for _, a := range append([]string{shipping.Email}, emailsOf(messages)...) {
+		a = strings.ToLower(strings.TrimSpace(a))
+		if a != "" && !seen[a] {
+			seen[a] = true
+			addresses = append(addresses, a)
+		}
+	}
+	suppressed, err := repo.SuppressedEmails(addresses)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &OrderEmails{OrderID: orderID, Messages: messages, Suppressed: suppressed}
+	if out.Messages == nil {
+		out.Messages = []models.EmailMessage{}
+	}
+	if out.Suppressed == nil {
+		out.Suppressed = []string{}
+	}
+	return out, nil
+}
+
+func emailsOf(messages []models.EmailMessage) []string {
+	out := make([]string, len(messages))
+	for i, m := range messages {
+		out[i] = m.Email
+	}
+	return out
+}
+
+// ListEmailSuppressions returns the suppressed addresses, newest first
+func (s *service) ListEmailSuppressions(ctx context.Context, limit int) (_ []models.EmailSuppression, err error) {
+	ctx, span := tracer.Start(ctx, "Service.ListEmailSuppressions")
+	defer tracing.End(span, &err)
+
+	return s.repo.WithContext(ctx).ListEmailSuppressions(limit)
+}
+
+// RemoveEmailSuppression lets an address receive email again, e.g. after
+// the customer fixed their mailbox, and records who did it in the audit log
+func (s *service) RemoveEmailSuppression(ctx context.Context, email string) (err error) {
+	ctx, span := tracer.Start(ctx, "Service.RemoveEmailSuppression")
+	defer tracing.End(span, &err)
+
+	email = strings.ToLower(strings.TrimSpace(email))
+	return s.repo.WithContext(ctx).WithTransaction(func(tx repository.Repository) error {
+		deleted, err := tx.DeleteEmailSuppression(email)
+		if err != nil {
+			return err
+		}
+		if !deleted {
+			return ErrSuppressionNotFound
+		}
+		details, err := json.Marshal(map[string]any{"email": logging.MaskEmail(email)})
+		if err != nil {
+			return err
+		}
+		return tx.CreateAuditEntry(&models.AuditEntry{
+			Actor:   "admin",
+			Action:  "email.unsuppress",
+			Details: details,
+		})
+	})
+}
@@ -1,7 +1,9 @@
 package service
 
 import (
+	"context"
 	"ecommerce-backend/internal/models"
+	"ecommerce-backend/internal/tracing"
 	"encoding/json"
 	"time"
 
@@ -9,7 +11,10 @@ import (
 )
 
 // CreateOrder creates a new order with business logic validation, optional payOSOrderCode
-func (s *service) CreateOrder(customerPhone string, shippingAddress []byte, items []byte, paymentMethod uint8, payOSOrderCode *int64) (*models.Order, error) {
+func (s *service) CreateOrder(ctx context.Context, customerPhone string, shippingAddress []byte, items []byte, paymentMethod uint8, payOSOrderCode *int64) (_ *models.Order, err error) {
+	ctx, span := tracer.Start(ctx, "Service.CreateOrder")
+	defer tracing.End(span, &err)
+
 	// Calculate total amount from items
 	var totalAmount uint64
 	var itemsData []map[string]interface{}
@@ -34,7 +39,7 @@ func (s *service) CreateOrder(customerPhone string, shippingAddress []byte, item
 		PayOSOrderCode:  payOSOrderCode,
 	}
 
-	err := s.repo.CreateOrder(order)
+	err = s.repo.WithContext(ctx).CreateOrder(order)
 	if err != nil {
 		return order, err
 	}
@@ -43,11 +48,17 @@ func (s *service) CreateOrder(customerPhone string, shippingAddress []byte, item
 }
 
 // GetOrderByID retrieves an order by ID for tracking purposes
-func (s *service) GetOrderByID(id uint64) (*models.Order, error) {
-	return s.repo.GetOrderByID(id)
+func (s *service) GetOrderByID(ctx context.Context, id uint64) (_ *models.Order, err error) {
+	ctx, span := tracer.Start(ctx, "Service.GetOrderByID")
+	defer tracing.End(span, &err)
+
+	return s.repo.WithContext(ctx).GetOrderByID(id)
 }
 
 // GetOrdersByUserPhone retrieves all orders for a user for order history/tracking
-func (s *service) GetOrdersByUserPhone(phone string) ([]models.Order, error) {
-	return s.repo.GetOrdersByUserPhone(phone)
+func (s *service) GetOrdersByUserPhone(ctx context.Context, phone string) (_ []models.Order, err error) {
+	ctx, span := tracer.Start(ctx, "Service.GetOrdersByUserPhone")
+	defer tracing.End(span, &err)
+
+	return s.repo.WithContext(ctx).GetOrdersByUserPhone(phone)
 }
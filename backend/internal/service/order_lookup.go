@@ -0,0 +1,213 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"ecommerce-backend/internal/integrations"
+	"ecommerce-backend/internal/logging"
+	"ecommerce-backend/internal/models"
+	"ecommerce-backend/internal/tracing"
+	"ecommerce-backend/internal/utils/base32"
+)
+
+// DefaultLookupLinkTTL is how long an emailed order link can be opened
+const DefaultLookupLinkTTL = 30 * time.Minute
+
+// Order lookup failures
+var (
+	// ErrLookupFactor: no email, phone digits or link token was given
+	ErrLookupFactor = errors.New("an email, the last 4 phone digits or a link token is required")
+	// ErrOrderLookup: the order number is unknown or the factor does not match;
+	// the two are not told apart so order numbers cannot be probed
+	ErrOrderLookup = errors.New("order not found")
+	// ErrInvalidLookupLink: the emailed link is unknown, used or expired
+	ErrInvalidLookupLink = errors.New("order link is invalid or expired")
+)
+
+// OrderLookup identifies an order to a guest: the order number with the
+// order's email or the last 4 digits of its phone, or the token of an
+// emailed link on its own
+type OrderLookup struct {
+	OrderNumber string `json:"order_number"`
+	Email       string `json:"email"`
+	PhoneLast4  string `json:"phone_last4"`
+	Token       string `json:"token"`
+}
+
+// OrderView is the redacted order shown to guests: no IDs, payment codes or
+// street address, and masked contact details
+type OrderView struct {
+	CreatedAt   time.Time       `json:"created_at"`
+	OrderNumber string          `json:"order_number"`
+	Status      string          `json:"status"`
+	Phone       string          `json:"phone"`
+	Email       string          `json:"email,omitempty"`
+	Province    string          `json:"province,omitempty"`
+	Items       []OrderViewItem `json:"items"`
+	TotalAmount uint64          `json:"total_amount"`
+}
+
+// OrderViewItem is one line of an OrderView
+type OrderViewItem struct {
+	Name     string  `json:"name"`
+	Price    float64 `json:"price"`
+	Quantity int     `json:"quantity"`
+}
+
+// orderShipping is the part of an order's shipping_address used here
+type orderShipping struct {
+	Email    string `json:"email"`
+	Province string `json:"province"`
+}
+
+// LookupOrder returns the redacted view of the order identified by q. A link
+// token is spent on first use.
+func (s *service) LookupOrder(ctx context.Context, q OrderLookup) (_ *OrderView, err error) {
+	ctx, span := tracer.Start(ctx, "Service.LookupOrder")
+	defer tracing.End(span, &err)
+
+	repo := s.repo.WithContext(ctx)
+	if token := strings.TrimSpace(q.Token); token != "" {
+		orderID, err := repo.UseOrderLookupToken(hashTransferToken(token))
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrInvalidLookupLink
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to use order link: %w", err)
+		}
+		order, err := repo.Primary().GetOrderByID(orderID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load order %d: %w", orderID, err)
+		}
+		return newOrderView(order), nil
+	}
+
+	email := strings.ToLower(strings.TrimSpace(q.Email))
+	last4 := strings.TrimSpace(q.PhoneLast4)
+	if email == "" && last4 == "" {
+		return nil, ErrLookupFactor
+	}
+	order, err := s.orderByNumber(ctx, q.OrderNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	var shipping orderShipping
+	json.Unmarshal(order.ShippingAddress, &shipping)
+	if !factorMatches(email, strings.ToLower(strings.TrimSpace(shipping.Email))) &&
+		!(len(last4) == 4 && factorMatches(last4, phoneLast4(order.CustomerPhone))) {
+		return nil, ErrOrderLookup
+	}
+	return newOrderView(order), nil
+}
+
+// SendOrderLookupLink emails a one-time link to the order's address. Unknown
+// orders and orders without an email succeed silently, so the response does
+// not reveal which order numbers exist.
+func (s *service) SendOrderLookupLink(ctx context.Context, orderNumber string) (err error) {
+	ctx, span := tracer.Start(ctx, "Service.SendOrderLookupLink")
+	defer tracing.End(span, &err)
+
+	order, err := s.orderByNumber(ctx, orderNumber)
+	if errors.Is(err, ErrOrderLookup) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var shipping orderShipping
+	json.Unmarshal(order.ShippingAddress, &shipping)
+	if shipping.Email == "" {
+		return nil
+	}
+
+	token, hash, err := newTransferToken()
+	if err != nil {
+		return err
+	}
+	err = s.repo.WithContext(ctx).CreateOrderLookupToken(&models.OrderLookupToken{
+		OrderID:   order.ID,
+		ExpiresAt: time.Now().Add(s.lookupLinkTTL),
+		TokenHash: hash,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create order link: %w", err)
+	}
+
+	link := strings.TrimRight(s.storefrontURL(), "/") + "/orders/view?t=" + url.QueryEscape(token)
+	if err := s.email.SendOrderDetails(ctx, shipping.Email, order, link); err != nil {
+		if errors.Is(err, integrations.ErrEmailSuppressed) {
+			// Same response as a sent link; support can see the bounce
+			s.log.InfoContext(ctx, "order link not sent, address suppressed", "order_id", order.ID, "email", logging.MaskEmail(shipping.Email))
+			return nil
+		}
+		return fmt.Errorf("failed to email order link: %w", err)
+	}
+	s.log.InfoContext(ctx, "order link sent", "order_id", order.ID, "email", logging.MaskEmail(shipping.Email))
+	return nil
+}
+
+// orderByNumber loads the order for a public order number. Sequential IDs
+// are never accepted; a malformed or unknown number is ErrOrderLookup.
+func (s *service) orderByNumber(ctx context.Context, orderNumber string) (*models.Order, error) {
+	id, err := base32.DecodeOrderNumber(orderNumber)
+	if err != nil || id == 0 {
+		return nil, ErrOrderLookup
+	}
+	order, err := s.repo.WithContext(ctx).GetOrderByID(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrOrderLookup
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load order %d: %w", id, err)
+	}
+	return order, nil
+}
+
+// factorMatches compares a non-empty given factor with the stored one in
+// constant time
+func factorMatches(given, stored string) bool {
+	return given != "" && stored != "" && subtle.ConstantTimeCompare([]byte(given), []byte(stored)) == 1
+}
+
+// phoneLast4 returns the last 4 digits of phone, ignoring separators
+func phoneLast4(phone string) string {
+	digits := strings.Map(func(r rune) rune {
+		if r < '0' || r > '9' {
+			return -1
+		}
+		return r
+	}, phone)
+	if len(digits) < 4 {
+		return ""
+	}
+	return digits[len(digits)-4:]
+}
+
+// newOrderView redacts order for a guest
+func newOrderView(order *models.Order) *OrderView {
+	var shipping orderShipping
+	json.Unmarshal(order.ShippingAddress, &shipping)
+	view := &OrderView{
+		CreatedAt:   order.CreatedAt,
+		OrderNumber: base32.GenerateOrderNumber(order.ID),
+		Status:      models.OrderStatusName(order.Status),
+		Phone:       logging.MaskPhone(order.CustomerPhone),
+		Province:    shipping.Province,
+		Items:       []OrderViewItem{},
+		TotalAmount: order.TotalAmount,
+	}
+	if shipping.Email != "" {
+		view.Email = logging.MaskEmail(shipping.Email)
+	}
+	json.Unmarshal(order.Items, &view.Items)
+	return view
+}
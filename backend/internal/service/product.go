@@ -1,17 +1,25 @@
 package service
 
 import (
+	"context"
 	"ecommerce-backend/internal/models"
+	"ecommerce-backend/internal/tracing"
 )
 
 // GetProduct retrieves a product by ID
-func (s *service) GetProduct(id uint64) (*models.Product, error) {
-	return s.repo.GetProductByID(id)
+func (s *service) GetProduct(ctx context.Context, id uint64) (_ *models.Product, err error) {
+	ctx, span := tracer.Start(ctx, "Service.GetProduct")
+	defer tracing.End(span, &err)
+
+	return s.repo.WithContext(ctx).GetProductByID(id)
 }
 
 // ListProducts retrieves all active products
-func (s *service) ListProducts() ([]models.Product, error) {
-	products, err := s.repo.GetAllProducts()
+func (s *service) ListProducts(ctx context.Context) (_ []models.Product, err error) {
+	ctx, span := tracer.Start(ctx, "Service.ListProducts")
+	defer tracing.End(span, &err)
+
+	products, err := s.repo.WithContext(ctx).GetAllProducts()
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"ecommerce-backend/internal/alerts"
+	"ecommerce-backend/internal/emails"
+	"ecommerce-backend/internal/models"
+	"ecommerce-backend/internal/tracing"
+	"ecommerce-backend/internal/utils/base32"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Loser refund retries: attempts at 0, 1, 3, 7 and 15 minutes after the
+// cancellation, plus the wait for the refunds job
+const (
+	DefaultRefundAttempts = 5
+	DefaultRefundBackoff  = time.Minute
+)
+
+// refundBatch is how many due refunds one ProcessRefunds run issues
+const refundBatch = 100
+
+// ProcessRefunds issues the due refunds of orders that lost their drop and
+// returns how many PayOS accepted. A failed refund waits out its backoff
+// for a later run; once its attempts are spent it is marked failed and
+// admins are alerted to settle it by hand.
+func (s *service) ProcessRefunds(ctx context.Context) (n int, err error) {
+	ctx, span := tracer.Start(ctx, "Service.ProcessRefunds")
+	defer func() {
+		span.SetAttributes(attribute.Int("refunds.issued", n))
+		tracing.End(span, &err)
+	}()
+
+	repo := s.repo.WithContext(ctx)
+	due, err := repo.DueRefunds(time.Now(), refundBatch)
+	if err != nil {
+		return 0, err
+	}
+	for i := range due {
+		if err := ctx.Err(); err != nil {
+			return n, err
+		}
+		f := &due[i]
+		refundErr := s.payment.RefundPayment(ctx, f.OrderCode, f.Reason)
+		now := time.Now()
+		f.Attempts++
+		switch {
+		case refundErr == nil:
+			f.Status = models.RefundRefunded
+			f.RefundedAt = &now
+			f.LastError = ""
+		case f.Attempts >= s.refundAttempts:
+			f.Status = models.RefundFailed
+			f.LastError = refundErr.Error()
+		default:
+			f.NextAttemptAt = now.Add(s.refundBackoff << (f.Attempts - 1))
+			f.LastError = refundErr.Error()
+		}
+		if err := repo.UpdateRefund(f); err != nil {
+			return n, fmt.Errorf("failed to record refund of order %d: %w", f.OrderID, err)
+		}
+
+		switch f.Status {
+		case models.RefundRefunded:
+			n++
+			s.log.InfoContext(ctx, "loser refunded", "order_id", f.OrderID, "order_code", f.OrderCode, "attempts", f.Attempts)
+		case models.RefundFailed:
+			s.log.ErrorContext(ctx, "loser refund failed", "order_id", f.OrderID, "order_code", f.OrderCode, "attempts", f.Attempts, "error", refundErr)
+			s.refundFailed(ctx, f)
+		default:
+			s.log.WarnContext(ctx, "loser refund will be retried", "order_id", f.OrderID, "order_code", f.OrderCode,
+				"attempts", f.Attempts, "next_attempt_at", f.NextAttemptAt, "error", refundErr)
+		}
+	}
+	return n, nil
+}
+
+// refundFailed alerts admins to a refund that ran out of attempts
+func (s *service) refundFailed(ctx context.Context, f *models.Refund) {
+	orderNumber := base32.GenerateOrderNumber(f.OrderID)
+	detail := fmt.Sprintf("PayOS order %d after %d attempts: %s", f.OrderCode, f.Attempts, f.LastError)
+	if order, err := s.repo.WithContext(ctx).GetOrderByID(f.OrderID); err == nil && order != nil {
+		detail = fmt.Sprintf("%s to %s, %s", emails.FormatMoney(emails.DefaultLocale, int64(order.TotalAmount)), order.CustomerPhone, detail)
+	}
+	s.alerts.Alert(ctx, alerts.Alert{
+		Kind:   alerts.RefundFailed,
+		Key:    orderNumber,
+		Title:  "Refund failed for " + orderNumber,
+		Detail: detail,
+	})
+}
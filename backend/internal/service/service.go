@@ -1,31 +1,64 @@
 package service
 
 import (
+	"context"
+	"crypto/rand"
+	"ecommerce-backend/internal/alerts"
+	"ecommerce-backend/internal/geo"
 	"ecommerce-backend/internal/integrations"
+	"ecommerce-backend/internal/labels"
+	"ecommerce-backend/internal/logging"
 	"ecommerce-backend/internal/models"
 	"ecommerce-backend/internal/repository"
+	"ecommerce-backend/internal/tracing"
+	"ecommerce-backend/internal/webhooks"
+	"log/slog"
+	"time"
 )
 
-// Service defines the interface for business logic operations
+// tracer records one span per Service method ("Service.PurchaseDrop")
+var tracer = tracing.Tracer("service")
+
+// Service defines the interface for business logic operations.
+// ctx carries the request ID and trace into logs, SQL and PayOS/Brevo calls.
 type Service interface {
 	// Product services
-	GetProduct(id uint64) (*models.Product, error)
-	ListProducts() ([]models.Product, error)
+	GetProduct(ctx context.Context, id uint64) (*models.Product, error)
+	ListProducts(ctx context.Context) ([]models.Product, error)
 
 	// Order services
-	CreateOrder(customerPhone string, shippingAddress []byte, items []byte, paymentMethod uint8, payOSOrderCode *int64) (*models.Order, error)
-	GetOrderByID(id uint64) (*models.Order, error)
-	GetOrdersByUserPhone(phone string) ([]models.Order, error)
+	CreateOrder(ctx context.Context, customerPhone string, shippingAddress []byte, items []byte, paymentMethod uint8, payOSOrderCode *int64) (*models.Order, error)
+	GetOrderByID(ctx context.Context, id uint64) (*models.Order, error)
+	GetOrdersByUserPhone(ctx context.Context, phone string) ([]models.Order, error)
+	LookupOrder(ctx context.Context, q OrderLookup) (*OrderView, error)
+	SendOrderLookupLink(ctx context.Context, orderNumber string) error
 
 	// Drop services
-	GetActiveDrops() ([]models.LimitedDrop, error)
-	GetDropStatus(id uint64) (*LimitedDropStatus, error)
-	PurchaseDrop(dropID uint64, req *PurchaseRequest) (*PurchaseResult, error)
-	ProcessSuccessfulDropPayment(orderCode int64) error
+	GetActiveDrops(ctx context.Context) ([]models.LimitedDrop, error)
+	GetDropStatus(ctx context.Context, id uint64) (*LimitedDropStatus, error)
+	PurchaseDrop(ctx context.Context, dropID uint64, req *PurchaseRequest) (*PurchaseResult, error)
+	ProcessSuccessfulDropPayment(ctx context.Context, orderCode int64) error
+	AnnounceStartedDrops(ctx context.Context, within time.Duration) (int, error)
+	ProcessRefunds(ctx context.Context) (int, error)
 
 	// Symbicode services
-	GenerateSymbicode(productID uint64, orderID *uint64) (*models.Symbicode, error)
-	VerifySymbicode(code string) (*models.Symbicode, bool, error)
+	GenerateSymbicode(ctx context.Context, productID uint64, orderID *uint64) (*models.Symbicode, error)
+	VerifySymbicode(ctx context.Context, code string, scan ScanInfo) (*VerifyResult, error)
+	AutoActivateExpiredSymbicodes(ctx context.Context, olderThan time.Duration) (int, error)
+	ListSymbicodeScans(ctx context.Context, symbicodeID uint64, limit int) ([]models.SymbicodeScan, error)
+	ListSuspiciousSymbicodes(ctx context.Context, limit int) ([]SuspiciousSymbicode, error)
+	InitiateSymbicodeTransfer(ctx context.Context, code string, owner Contact) (*TransferChallenge, error)
+	ConfirmSymbicodeTransfer(ctx context.Context, code string, owner Contact, otp string) (*TransferOffer, error)
+	ClaimSymbicodeTransfer(ctx context.Context, token string, recipient Contact) (*ClaimResult, error)
+	GenerateSymbicodeBatch(ctx context.Context, productID uint64, count int, note string) (*models.SymbicodeBatch, error)
+	SymbicodeLabels(ctx context.Context, batchID uint64) (*models.SymbicodeBatch, []labels.Label, error)
+	BindSymbicode(ctx context.Context, code string, orderID uint64) (*models.Symbicode, error)
+
+	// Email delivery tracking
+	HandleEmailEvent(ctx context.Context, ev integrations.EmailEvent) error
+	GetOrderEmails(ctx context.Context, orderID uint64) (*OrderEmails, error)
+	ListEmailSuppressions(ctx context.Context, limit int) ([]models.EmailSuppression, error)
+	RemoveEmailSuppression(ctx context.Context, email string) error
 }
 
 // PurchaseRequest represents a limited drop purchase request
@@ -38,13 +71,17 @@ type PurchaseRequest struct {
 	Province string `json:"province"`
 	District string `json:"district"`
 	Ward     string `json:"ward"`
+	// Locale is the language for the customer's emails, a locale or an
+	// Accept-Language value; see emails.ParseLocale
+	Locale string `json:"locale"`
 }
 
 // PurchaseResult represents the result of a purchase attempt
 type PurchaseResult struct {
-	Message    string `json:"message"`
-	PaymentURL string `json:"payment_url,omitempty"`
-	OrderCode  int64  `json:"order_code,omitempty"`
+	Message     string `json:"message"`
+	PaymentURL  string `json:"payment_url,omitempty"`
+	OrderCode   int64  `json:"order_code,omitempty"`
+	OrderNumber string `json:"order_number,omitempty"` // public number, see base32.GenerateOrderNumber
 }
 
 // service implements Service interface
@@ -53,15 +90,153 @@ type service struct {
 	payment integrations.PaymentGateway
 	email   integrations.EmailSender
 	sheets  integrations.SheetSubmitter
+
+	// notifier tells customers about their orders; defaults to email only
+	notifier integrations.Notifier
+	// alerts tells admins about paid orders, failed refunds and sold out drops; nil sends none
+	alerts *alerts.Alerter
+	// webhooks sends order, drop and symbicode events to subscribers; nil sends none
+	webhooks *webhooks.Dispatcher
+
+	// frontendURL is where PayOS returns buyers; "" falls back to FRONTEND_URL
+	frontendURL string
+	// symbicodeKey signs symbicode tokens; tokens verify under it or one of
+	// previousKeys; acceptUnsigned also verifies bare UUIDs
+	symbicodeKey   []byte
+	previousKeys   [][]byte
+	acceptUnsigned bool
+	// scanPolicy flags codes scanned from too many places; geo locates scans (nil: unknown)
+	scanPolicy ScanPolicy
+	geo        geo.Locator
+	// transferTTL is how long a resale transfer offer can be claimed
+	transferTTL time.Duration
+	// lookupLinkTTL is how long an emailed order link can be opened
+	lookupLinkTTL time.Duration
+	// refundAttempts per loser refund, the first included; refundBackoff
+	// doubles between them
+	refundAttempts int
+	refundBackoff  time.Duration
+	log            *slog.Logger
+}
+
+// Option configures optional service settings
+type Option func(*service)
+
+// WithFrontendURL sets the storefront URL used for PayOS return/cancel links
+func WithFrontendURL(url string) Option {
+	return func(s *service) {
+		s.frontendURL = url
+	}
+}
+
+// WithSymbicodeSigning signs symbicode tokens with key. acceptUnsigned keeps
+// verifying bare UUIDs printed before tokens existed. Without this option a
+// random per-process key is used and bare UUIDs are accepted.
+func WithSymbicodeSigning(key []byte, acceptUnsigned bool) Option {
+	return func(s *service) {
+		s.symbicodeKey = key
+		s.acceptUnsigned = acceptUnsigned
+	}
+}
+
+// WithPreviousSymbicodeKeys keeps verifying tokens signed with keys that
+// were rotated out; new tokens are signed with the current key only
+func WithPreviousSymbicodeKeys(keys ...[]byte) Option {
+	return func(s *service) {
+		s.previousKeys = keys
+	}
+}
+
+// WithScanPolicy sets when a symbicode's scan history is reported as suspicious
+func WithScanPolicy(p ScanPolicy) Option {
+	return func(s *service) {
+		s.scanPolicy = p
+	}
+}
+
+// WithGeoLocator records a coarse location with every symbicode scan
+func WithGeoLocator(l geo.Locator) Option {
+	return func(s *service) {
+		s.geo = l
+	}
+}
+
+// WithTransferTTL sets how long a symbicode transfer offer can be claimed
+func WithTransferTTL(d time.Duration) Option {
+	return func(s *service) {
+		s.transferTTL = d
+	}
+}
+
+// WithLookupLinkTTL sets how long an emailed order link can be opened
+func WithLookupLinkTTL(d time.Duration) Option {
+	return func(s *service) {
+		s.lookupLinkTTL = d
+	}
+}
+
+// WithRefundRetry sets how many times a loser refund is attempted and the
+// wait before the second attempt, doubling after
+func WithRefundRetry(attempts int, backoff time.Duration) Option {
+	return func(s *service) {
+		s.refundAttempts = attempts
+		s.refundBackoff = backoff
+	}
+}
+
+// WithNotifier routes customer notifications through n instead of email only
+func WithNotifier(n integrations.Notifier) Option {
+	return func(s *service) {
+		s.notifier = n
+	}
+}
+
+// WithAlerter raises admin alerts through a; nil sends none
+func WithAlerter(a *alerts.Alerter) Option {
+	return func(s *service) {
+		s.alerts = a
+	}
+}
+
+// WithWebhooks sends order, drop and symbicode events to the subscribers of d
+func WithWebhooks(d *webhooks.Dispatcher) Option {
+	return func(s *service) {
+		s.webhooks = d
+	}
+}
+
+// WithLogger sets the service logger (subsystem "service")
+func WithLogger(l *slog.Logger) Option {
+	return func(s *service) {
+		s.log = l
+	}
 }
 
 // NewService creates a new service instance
-func NewService(repo repository.Repository, payment integrations.PaymentGateway, email integrations.EmailSender, sheets integrations.SheetSubmitter) Service {
-	return &service{
-		repo:    repo,
-		payment: payment,
-		email:   email,
-		sheets:  sheets,
+func NewService(repo repository.Repository, payment integrations.PaymentGateway, email integrations.EmailSender, sheets integrations.SheetSubmitter, opts ...Option) Service {
+	s := &service{
+		repo:           repo,
+		payment:        payment,
+		email:          email,
+		sheets:         sheets,
+		acceptUnsigned: true,
+		scanPolicy:     DefaultScanPolicy,
+		transferTTL:    DefaultTransferTTL,
+		lookupLinkTTL:  DefaultLookupLinkTTL,
+		refundAttempts: DefaultRefundAttempts,
+		refundBackoff:  DefaultRefundBackoff,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.notifier == nil {
+		s.notifier = integrations.NewEmailNotifier(email)
+	}
+	if len(s.symbicodeKey) == 0 {
+		s.symbicodeKey = make([]byte, 32)
+		rand.Read(s.symbicodeKey)
 	}
+	s.log = logging.Subsystem(s.log, "service")
+	return s
 }
 
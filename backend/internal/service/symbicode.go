@@ -1,83 +1,299 @@
 package service
 
 import (
-	"crypto/rand"
-	"crypto/sha256"
-	"encoding/hex"
+	"context"
+	"crypto/hmac"
+	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"strings"
+	"net/url"
+	"strconv"
+	"time"
 
+	"ecommerce-backend/internal/geo"
+	"ecommerce-backend/internal/metrics"
 	"ecommerce-backend/internal/models"
+	"ecommerce-backend/internal/repository"
+	"ecommerce-backend/internal/tracing"
 	"ecommerce-backend/internal/utils/uuid"
+	"ecommerce-backend/internal/webhooks"
 
 	googleuuid "github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 const VerifyBaseURL = "/verify"
 
-// GenerateSymbicode creates and persists a new symbicode for a product/order
-func (s *service) GenerateSymbicode(productID uint64, orderID *uint64) (*models.Symbicode, error) {
+// autoActivateBatch bounds how many codes one write transaction activates
+const autoActivateBatch = 500
+
+// GenerateSymbicode creates and persists a new symbicode for a product/order.
+// The returned symbicode carries the Token to print on the label.
+func (s *service) GenerateSymbicode(ctx context.Context, productID uint64, orderID *uint64) (_ *models.Symbicode, err error) {
+	ctx, span := tracer.Start(ctx, "Service.GenerateSymbicode")
+	defer tracing.End(span, &err)
+
+	var order uint64
+	if orderID != nil {
+		order = *orderID
+	}
+	sym, err := s.newSymbicode(productID, order)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.WithContext(ctx).CreateSymbicode(sym); err != nil {
+		return nil, fmt.Errorf("failed to create symbicode: %w", err)
+	}
+	return sym, nil
+}
+
+// newSymbicode builds an unsaved symbicode whose secret derives from the signing key
+func (s *service) newSymbicode(productID, orderID uint64) (*models.Symbicode, error) {
 	code, err := uuid.GenerateUUIDv7()
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate uuid: %w", err)
 	}
-	secret := generateSecretKey()
-
-	sym := &models.Symbicode{
+	secret := symbicodeSecret(s.symbicodeKey, code)
+	return &models.Symbicode{
 		Code:        code,
 		SecretKey:   secret,
 		ProductID:   productID,
+		OrderID:     orderID,
 		IsActivated: 0,
-	}
-	if orderID != nil {
-		sym.OrderID = *orderID
-	}
-
-	if err := s.repo.CreateSymbicode(sym); err != nil {
-		return nil, fmt.Errorf("failed to create symbicode: %w", err)
-	}
-	return sym, nil
+		Token:       encodeSymbicodeToken(secret, code),
+	}, nil
 }
 
-// VerifySymbicode verifies and activates a symbicode. Returns (symbicode, isFirstActivation, error)
-func (s *service) VerifySymbicode(codeStr string) (*models.Symbicode, bool, error) {
-	codeStr = strings.TrimSpace(codeStr)
+// VerifySymbicode verifies and activates a symbicode, recording the scan.
+// code is a signed token, a scanned QR payload, or (when unsigned codes are
+// accepted) a bare UUID (see lookupSymbicode). The result carries the code's
+// scan history, flagged as suspicious under the scan policy, and the length
+// of its ownership history.
+func (s *service) VerifySymbicode(ctx context.Context, codeStr string, scan ScanInfo) (res *VerifyResult, err error) {
+	ctx, span := tracer.Start(ctx, "Service.VerifySymbicode")
+	defer func() {
+		metrics.SymbicodeVerifications.WithLabelValues(verifyResult(res, err)).Inc()
+		tracing.End(span, &err)
+	}()
 
-	parsed, err := parseUUID(codeStr)
+	repo := s.repo.WithContext(ctx)
+
+	symbicode, signed, err := s.lookupSymbicode(repo, codeStr)
 	if err != nil {
-		return nil, false, fmt.Errorf("invalid symbicode format: %w", err)
+		return nil, err
 	}
+	code := symbicode.Code
 
-	symbicode, err := s.repo.GetSymbicodeByCode(parsed)
+	var loc geo.Location
+	if s.geo != nil {
+		loc = s.geo.Locate(scan.IP)
+	}
+	// Tags printed ahead of the drop are checked in the factory and shop;
+	// only the buyer's first scan activates a code
+	isFirst := symbicode.IsActivated&1 == 0 && !unsold(symbicode)
+	err = repo.WithTransaction(func(tx repository.Repository) error {
+		if err := tx.CreateSymbicodeScan(&models.SymbicodeScan{
+			SymbicodeID: symbicode.ID,
+			IP:          scan.IP,
+			UserAgent:   scan.UserAgent,
+			Country:     loc.Country,
+			City:        loc.City,
+		}); err != nil {
+			return fmt.Errorf("failed to record scan: %w", err)
+		}
+		if isFirst {
+			if err := tx.ActivateSymbicode(symbicode.ID, scan.IP); err != nil {
+				return fmt.Errorf("failed to activate symbicode: %w", err)
+			}
+		}
+		return nil
+	})
 	if err != nil {
-		return nil, false, err
+		return nil, err
 	}
 
-	isFirst := symbicode.IsActivated&1 == 0
+	// Primary: the reads must see the transaction above
+	primary := repo.Primary()
 	if isFirst {
-		if err := s.repo.ActivateSymbicode(symbicode.ID, ""); err != nil {
-			return nil, false, fmt.Errorf("failed to activate symbicode: %w", err)
+		symbicode, err = primary.GetSymbicodeByCode(code)
+		if err != nil {
+			return nil, err
+		}
+		activatedAt := time.Now()
+		if symbicode.ActivatedAt != nil {
+			activatedAt = *symbicode.ActivatedAt
+		}
+		s.webhooks.Emit(ctx, webhooks.SymbicodeActivated, strconv.FormatUint(symbicode.ID, 10), webhooks.SymbicodeData{
+			SymbicodeID: symbicode.ID,
+			ProductID:   symbicode.ProductID,
+			OrderID:     symbicode.OrderID,
+			ActivatedAt: activatedAt,
+		})
+	}
+	if signed {
+		symbicode.Token = encodeSymbicodeToken(symbicode.SecretKey, code)
+	}
+
+	stats, err := primary.GetSymbicodeScanStats(symbicode.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read scan history: %w", err)
+	}
+	owners, err := primary.ListSymbicodeOwners(symbicode.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ownership history: %w", err)
+	}
+	reasons := s.scanPolicy.reasons(stats)
+	if len(reasons) > 0 {
+		s.log.WarnContext(ctx, "suspicious symbicode scan", "symbicode_id", symbicode.ID, "reasons", reasons,
+			"distinct_ips", stats.DistinctIPs, "distinct_locations", stats.DistinctLocations)
+	}
+
+	return &VerifyResult{
+		Symbicode:         symbicode,
+		IsFirstActivation: isFirst,
+		Owners:            ownerCount(symbicode, owners),
+		Scans: ScanReport{
+			Count:             stats.Scans,
+			DistinctIPs:       stats.DistinctIPs,
+			DistinctLocations: stats.DistinctLocations,
+			FirstScanAt:       stats.FirstScanAt,
+			Suspicious:        len(reasons) > 0,
+			Reasons:           reasons,
+		},
+	}, nil
+}
+
+// lookupSymbicode resolves a token, QR payload or (when accepted) bare UUID to
+// its symbicode; signed reports whether input was a token. A token is
+// checked against the signing keys before the database is read, so forged
+// tokens cost no query.
+func (s *service) lookupSymbicode(repo repository.Repository, input string) (_ *models.Symbicode, signed bool, err error) {
+	input = tokenFromInput(input)
+
+	var code, tag []byte
+	if parsed, err := parseUUID(input); err == nil {
+		if !s.acceptUnsigned {
+			return nil, false, ErrUnsignedSymbicode
 		}
-		// refresh the record
-		symbicode, err = s.repo.GetSymbicodeByCode(parsed)
+		code = parsed
+	} else {
+		code, tag, err = decodeSymbicodeToken(input)
 		if err != nil {
 			return nil, false, err
 		}
+		if !s.signedByKeyring(code, tag) {
+			return nil, false, ErrForgedSymbicode
+		}
 	}
 
-	return symbicode, isFirst, nil
+	symbicode, err := repo.GetSymbicodeByCode(code)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, false, ErrUnknownSymbicode
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return symbicode, tag != nil, nil
 }
 
-// GenerateQRCodeData formats verification URL with the UUID string
-func GenerateQRCodeData(code []byte) string {
-	return fmt.Sprintf("%s?code=%s", VerifyBaseURL, uuid.FormatUUIDToString(code))
+// signedByKeyring reports whether tag signs code under the current signing
+// key or one of the previous keys
+func (s *service) signedByKeyring(code, tag []byte) bool {
+	if hmac.Equal(tag, symbicodeTag(symbicodeSecret(s.symbicodeKey, code), code)) {
+		return true
+	}
+	for _, key := range s.previousKeys {
+		if hmac.Equal(tag, symbicodeTag(symbicodeSecret(key, code), code)) {
+			return true
+		}
+	}
+	return false
+}
+
+// verifyResult labels a VerifySymbicode outcome for metrics
+func verifyResult(res *VerifyResult, err error) string {
+	switch {
+	case err == nil && res.Scans.Suspicious:
+		return "suspicious"
+	case err == nil:
+		return "valid"
+	case errors.Is(err, ErrForgedSymbicode):
+		return "forged"
+	case errors.Is(err, ErrUnknownSymbicode):
+		return "unknown"
+	case errors.Is(err, ErrUnsignedSymbicode):
+		return "unsigned"
+	case errors.Is(err, ErrMalformedSymbicode):
+		return "malformed"
+	default:
+		return "error"
+	}
+}
+
+// AutoActivateExpiredSymbicodes activates codes that were never scanned
+// within olderThan of being issued, so the owner of a code does not depend on
+// a first scan. Each batch is one transaction with one audit entry.
+func (s *service) AutoActivateExpiredSymbicodes(ctx context.Context, olderThan time.Duration) (total int, err error) {
+	ctx, span := tracer.Start(ctx, "Service.AutoActivateExpiredSymbicodes")
+	defer func() {
+		span.SetAttributes(attribute.Int("symbicode.activated", total))
+		tracing.End(span, &err)
+	}()
+
+	createdBefore := time.Now().Add(-olderThan)
+	for {
+		if err := ctx.Err(); err != nil {
+			return total, err
+		}
+
+		var ids []uint64
+		err := s.repo.WithContext(ctx).WithTransaction(func(tx repository.Repository) error {
+			var err error
+			ids, err = tx.AutoActivateSymbicodes(createdBefore, autoActivateBatch)
+			if err != nil || len(ids) == 0 {
+				return err
+			}
+			details, err := json.Marshal(map[string]any{
+				"count":          len(ids),
+				"first_id":       ids[0],
+				"last_id":        ids[len(ids)-1],
+				"created_before": createdBefore.UTC(),
+			})
+			if err != nil {
+				return err
+			}
+			return tx.CreateAuditEntry(&models.AuditEntry{
+				Actor:   "scheduler",
+				Action:  "symbicode.auto_activate",
+				Details: details,
+			})
+		})
+		if err != nil {
+			return total, fmt.Errorf("failed to auto-activate symbicodes: %w", err)
+		}
+
+		activatedAt := time.Now()
+		for _, id := range ids {
+			s.webhooks.Emit(ctx, webhooks.SymbicodeActivated, strconv.FormatUint(id, 10), webhooks.SymbicodeData{
+				SymbicodeID: id,
+				ActivatedAt: activatedAt,
+				Auto:        true,
+			})
+		}
+
+		total += len(ids)
+		if len(ids) < autoActivateBatch {
+			return total, nil
+		}
+	}
 }
 
-func generateSecretKey() string {
-	randomBytes := make([]byte, 32)
-	rand.Read(randomBytes)
-	hash := sha256.Sum256(randomBytes)
-	return hex.EncodeToString(hash[:])
+// GenerateQRCodeData formats the verification URL printed as a QR code for
+// a symbicode token; VerifySymbicode accepts the whole payload
+func GenerateQRCodeData(token string) string {
+	return VerifyBaseURL + "?t=" + url.QueryEscape(token)
 }
 
 // parseUUID parses a UUID string to binary format (16 bytes)
@@ -0,0 +1,218 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"ecommerce-backend/internal/labels"
+	"ecommerce-backend/internal/models"
+	"ecommerce-backend/internal/repository"
+	"ecommerce-backend/internal/tracing"
+	"ecommerce-backend/internal/utils/uuid"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// MaxSymbicodeBatch bounds one pre-generation run, which is one write transaction
+const MaxSymbicodeBatch = 10000
+
+// Batch and binding failures
+var (
+	ErrBatchSize       = fmt.Errorf("batch size must be between 1 and %d", MaxSymbicodeBatch)
+	ErrBatchNotFound   = errors.New("symbicode batch not found")
+	ErrOrderNotFound   = errors.New("order not found")
+	ErrOrderNotPaid    = errors.New("order is not paid")
+	ErrSymbicodeBound  = errors.New("symbicode is already bound to an order")
+	ErrOrderFullyBound = errors.New("order already has a symbicode for every unit")
+	ErrProductMismatch = errors.New("symbicode is for a product the order does not contain")
+)
+
+// GenerateSymbicodeBatch pre-generates count unsold codes for a product in
+// one transaction, so tags can be printed before the drop. The codes verify
+// but are not activated until BindSymbicode ties them to an order.
+func (s *service) GenerateSymbicodeBatch(ctx context.Context, productID uint64, count int, note string) (_ *models.SymbicodeBatch, err error) {
+	ctx, span := tracer.Start(ctx, "Service.GenerateSymbicodeBatch")
+	span.SetAttributes(attribute.Int("symbicode.count", count))
+	defer tracing.End(span, &err)
+
+	if count < 1 || count > MaxSymbicodeBatch {
+		return nil, ErrBatchSize
+	}
+
+	batch := &models.SymbicodeBatch{ProductID: productID, Count: count, Note: note}
+	err = s.repo.WithContext(ctx).WithTransaction(func(tx repository.Repository) error {
+		if err := tx.CreateSymbicodeBatch(batch); err != nil {
+			return err
+		}
+		for i := 0; i < count; i++ {
+			sym, err := s.newSymbicode(productID, 0)
+			if err != nil {
+				return err
+			}
+			sym.BatchID = batch.ID
+			if err := tx.CreateSymbicode(sym); err != nil {
+				return err
+			}
+		}
+		details, err := json.Marshal(map[string]any{
+			"batch_id":   batch.ID,
+			"product_id": productID,
+			"count":      count,
+		})
+		if err != nil {
+			return err
+		}
+		return tx.CreateAuditEntry(&models.AuditEntry{
+			Actor:   "admin",
+			Action:  "symbicode.batch_generate",
+			Details: details,
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate symbicode batch: %w", err)
+	}
+	return batch, nil
+}
+
+// SymbicodeLabels returns a batch and the labels to print for its codes,
+// in generation order. Each label encodes the absolute verification URL.
+func (s *service) SymbicodeLabels(ctx context.Context, batchID uint64) (_ *models.SymbicodeBatch, _ []labels.Label, err error) {
+	ctx, span := tracer.Start(ctx, "Service.SymbicodeLabels")
+	defer tracing.End(span, &err)
+
+	repo := s.repo.WithContext(ctx)
+	batch, err := repo.GetSymbicodeBatch(batchID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil, ErrBatchNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	codes, err := repo.ListBatchSymbicodes(batchID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	out := make([]labels.Label, len(codes))
+	for i, sym := range codes {
+		token := encodeSymbicodeToken(sym.SecretKey, sym.Code)
+		out[i] = labels.Label{
+			ID:    sym.ID,
+			Code:  uuid.FormatUUIDToString(sym.Code),
+			Token: token,
+			URL:   s.storefrontURL() + GenerateQRCodeData(token),
+		}
+	}
+	return batch, out, nil
+}
+
+// BindSymbicode ties a code scanned from its tag at fulfillment to a paid
+// order. From then on the code activates on its first scan, is auto-activated
+// like any sold code, and belongs to the order's customer. The code must be
+// for a product in the order, which takes one code per unit, counting any
+// generated when it was paid.
+func (s *service) BindSymbicode(ctx context.Context, code string, orderID uint64) (_ *models.Symbicode, err error) {
+	ctx, span := tracer.Start(ctx, "Service.BindSymbicode")
+	defer tracing.End(span, &err)
+
+	repo := s.repo.WithContext(ctx)
+	sym, signed, err := s.lookupSymbicode(repo, code)
+	if err != nil {
+		return nil, err
+	}
+	if sym.OrderID != 0 {
+		return nil, ErrSymbicodeBound
+	}
+
+	order, err := repo.GetOrderByID(orderID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrOrderNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load order %d: %w", orderID, err)
+	}
+	if order.Status != models.OrderPaid && order.Status != models.OrderConfirmed {
+		return nil, ErrOrderNotPaid
+	}
+
+	quantity, products, err := orderItems(repo, order)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read order %d items: %w", orderID, err)
+	}
+	if !products[sym.ProductID] {
+		return nil, ErrProductMismatch
+	}
+	err = repo.WithTransaction(func(tx repository.Repository) error {
+		n, err := tx.CountOrderSymbicodes(orderID)
+		if err != nil {
+			return err
+		}
+		if n >= quantity {
+			return ErrOrderFullyBound
+		}
+		// Conditional update: a concurrent bind of the same tag loses here
+		bound, err := tx.BindSymbicode(sym.ID, orderID)
+		if err != nil {
+			return err
+		}
+		if !bound {
+			return ErrSymbicodeBound
+		}
+		details, err := json.Marshal(map[string]any{"symbicode_id": sym.ID, "order_id": orderID})
+		if err != nil {
+			return err
+		}
+		return tx.CreateAuditEntry(&models.AuditEntry{
+			Actor:   "admin",
+			Action:  "symbicode.bind",
+			Details: details,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sym, err = repo.Primary().GetSymbicodeByID(sym.ID)
+	if err != nil {
+		return nil, err
+	}
+	if signed {
+		sym.Token = encodeSymbicodeToken(sym.SecretKey, sym.Code)
+	}
+	return sym, nil
+}
+
+// orderItems returns the number of units in an order and the products they
+// are for. A drop item's product comes from its drop: orders placed before
+// purchases recorded it carry the drop ID as product_id.
+func orderItems(repo repository.Repository, order *models.Order) (int, map[uint64]bool, error) {
+	var items []struct {
+		DropID    uint64 `json:"drop_id"`
+		ProductID uint64 `json:"product_id"`
+		Quantity  int    `json:"quantity"`
+	}
+	json.Unmarshal(order.Items, &items)
+	n := 0
+	products := make(map[uint64]bool, len(items))
+	for _, item := range items {
+		n += item.Quantity
+		if item.DropID == 0 {
+			products[item.ProductID] = true
+			continue
+		}
+		drop, err := repo.GetDropByID(item.DropID)
+		if err != nil {
+			return 0, nil, err
+		}
+		products[drop.ProductID] = true
+	}
+	return n, products, nil
+}
+
+// unsold reports whether a pre-generated code is still waiting for its order
+func unsold(sym *models.Symbicode) bool {
+	return sym.BatchID != 0 && sym.OrderID == 0
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"ecommerce-backend/internal/models"
+	"ecommerce-backend/internal/tracing"
+	"ecommerce-backend/internal/utils/uuid"
+)
+
+// ScanInfo identifies the client verifying a symbicode
+type ScanInfo struct {
+	IP        string
+	UserAgent string
+}
+
+// ScanPolicy sets when a symbicode is reported as suspicious. A genuine
+// code is scanned by its owner from a handful of places; copies of a label
+// show up from many. A zero threshold disables that rule.
+type ScanPolicy struct {
+	MaxIPs       int // distinct client IPs
+	MaxLocations int // distinct country/city pairs
+}
+
+// DefaultScanPolicy applies when WithScanPolicy is not used
+var DefaultScanPolicy = ScanPolicy{MaxIPs: 5, MaxLocations: 3}
+
+// Suspicion reasons reported with a scan history
+const (
+	ReasonManyIPs       = "many_ips"
+	ReasonManyLocations = "many_locations"
+)
+
+// ScanReport summarizes a symbicode's scan history for the verify response
+type ScanReport struct {
+	Count             int       `json:"count"`
+	DistinctIPs       int       `json:"distinct_ips"`
+	DistinctLocations int       `json:"distinct_locations"`
+	FirstScanAt       time.Time `json:"first_scan_at"`
+	Suspicious        bool      `json:"suspicious"`
+	Reasons           []string  `json:"reasons,omitempty"`
+}
+
+// VerifyResult is the outcome of a successful verification
+type VerifyResult struct {
+	Symbicode         *models.Symbicode
+	IsFirstActivation bool
+	Scans             ScanReport
+	// Owners is the length of the ownership history: the buyer plus every
+	// claimed transfer (0 for a code not tied to an order)
+	Owners int
+}
+
+// SuspiciousSymbicode is an entry of the admin counterfeit report
+type SuspiciousSymbicode struct {
+	models.SymbicodeScanStats
+	Code    string   `json:"code"`
+	Reasons []string `json:"reasons"`
+}
+
+// reasons lists the rules st breaks
+func (p ScanPolicy) reasons(st *models.SymbicodeScanStats) []string {
+	var out []string
+	if p.MaxIPs > 0 && st.DistinctIPs >= p.MaxIPs {
+		out = append(out, ReasonManyIPs)
+	}
+	if p.MaxLocations > 0 && st.DistinctLocations >= p.MaxLocations {
+		out = append(out, ReasonManyLocations)
+	}
+	return out
+}
+
+// threshold turns a disabled (zero) rule into one no code can reach
+func threshold(n int) int {
+	if n <= 0 {
+		return int(^uint(0) >> 1)
+	}
+	return n
+}
+
+// ListSymbicodeScans returns the latest scans of a symbicode, newest first
+func (s *service) ListSymbicodeScans(ctx context.Context, symbicodeID uint64, limit int) (_ []models.SymbicodeScan, err error) {
+	ctx, span := tracer.Start(ctx, "Service.ListSymbicodeScans")
+	defer tracing.End(span, &err)
+
+	return s.repo.WithContext(ctx).ListSymbicodeScans(symbicodeID, limit)
+}
+
+// ListSuspiciousSymbicodes reports codes whose scan history breaks the scan policy
+func (s *service) ListSuspiciousSymbicodes(ctx context.Context, limit int) (_ []SuspiciousSymbicode, err error) {
+	ctx, span := tracer.Start(ctx, "Service.ListSuspiciousSymbicodes")
+	defer tracing.End(span, &err)
+
+	stats, err := s.repo.WithContext(ctx).ListSuspiciousSymbicodes(threshold(s.scanPolicy.MaxIPs), threshold(s.scanPolicy.MaxLocations), limit)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]SuspiciousSymbicode, len(stats))
+	for i := range stats {
+		out[i] = SuspiciousSymbicode{
+			SymbicodeScanStats: stats[i],
+			Code:               uuid.FormatUUIDToString(stats[i].Code),
+			Reasons:            s.scanPolicy.reasons(&stats[i]),
+		}
+	}
+	return out, nil
+}
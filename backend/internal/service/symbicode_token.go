@@ -0,0 +1,85 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"strings"
+)
+
+// Labels carry a signed token instead of the bare UUID:
+//
+//	token  = base64url(version || code || tag)
+//	tag    = HMAC-SHA256(secret, code)[:16]
+//	secret = hex(HMAC-SHA256(signing key, "symbicode:" || code)), stored as SecretKey
+//
+// A token is checked offline, against the secrets the current and previous
+// signing keys derive for its code, before the code is looked up; labels
+// printed under a key keep verifying while the key stays in the ring.
+const (
+	symbicodeTokenVersion = 1
+	symbicodeTagSize      = 16
+	symbicodeTokenSize    = 1 + 16 + symbicodeTagSize
+)
+
+// Verification failures; VerifySymbicode wraps one of these
+var (
+	// ErrMalformedSymbicode: neither a token, a QR payload nor a UUID
+	ErrMalformedSymbicode = errors.New("invalid symbicode format")
+	// ErrForgedSymbicode: a well-formed token no signing key signed
+	ErrForgedSymbicode = errors.New("symbicode signature is invalid")
+	// ErrUnknownSymbicode: a correctly signed code that is not in the database
+	ErrUnknownSymbicode = errors.New("symbicode not found")
+	// ErrUnsignedSymbicode: a bare UUID while unsigned codes are not accepted
+	ErrUnsignedSymbicode = errors.New("symbicode is not signed")
+)
+
+// symbicodeSecret derives the per-code secret from the signing key
+func symbicodeSecret(key, code []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("symbicode:"))
+	mac.Write(code)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func symbicodeTag(secret string, code []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(code)
+	return mac.Sum(nil)[:symbicodeTagSize]
+}
+
+// encodeSymbicodeToken signs code with secret
+func encodeSymbicodeToken(secret string, code []byte) string {
+	buf := make([]byte, 0, symbicodeTokenSize)
+	buf = append(buf, symbicodeTokenVersion)
+	buf = append(buf, code...)
+	buf = append(buf, symbicodeTag(secret, code)...)
+	return base64.RawURLEncoding.EncodeToString(buf)
+}
+
+// decodeSymbicodeToken splits a token into code and tag without checking the tag
+func decodeSymbicodeToken(token string) (code, tag []byte, err error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(raw) != symbicodeTokenSize || raw[0] != symbicodeTokenVersion {
+		return nil, nil, ErrMalformedSymbicode
+	}
+	return raw[1:17], raw[17:], nil
+}
+
+// tokenFromInput accepts a token, a scanned QR payload (any URL carrying
+// ?t=<token>) or a bare UUID, returned as-is
+func tokenFromInput(input string) string {
+	input = strings.TrimSpace(input)
+	if !strings.Contains(input, "t=") {
+		return input
+	}
+	if u, err := url.Parse(input); err == nil {
+		if t := u.Query().Get("t"); t != "" {
+			return t
+		}
+	}
+	return input
+}
@@ -0,0 +1,330 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"ecommerce-backend/internal/integrations"
+	"ecommerce-backend/internal/metrics"
+	"ecommerce-backend/internal/models"
+	"ecommerce-backend/internal/repository"
+	"ecommerce-backend/internal/tracing"
+)
+
+// DefaultTransferTTL is how long a transfer offer can be claimed
+const DefaultTransferTTL = 7 * 24 * time.Hour
+
+// TransferCodeTTL is how long the code sent to the owner can be entered
+const TransferCodeTTL = 10 * time.Minute
+
+// Transfer failures
+var (
+	// ErrTransferContact: neither a phone number nor an email was given
+	ErrTransferContact = errors.New("a phone number or email is required")
+	// ErrTransferChannel: an offer was started with both a phone number and
+	// an email; the confirmation code goes to exactly one of them
+	ErrTransferChannel = errors.New("give either a phone number or an email")
+	// ErrTransferDenied: the label is unknown, forged or unsigned, the code has
+	// no owner, or the contact is not the current owner's. These are one error
+	// so that the endpoint does not reveal which labels exist or who owns them.
+	ErrTransferDenied = errors.New("symbicode cannot be transferred with this contact")
+	// ErrInvalidTransferCode: the confirmation code is wrong, superseded,
+	// expired, guessed at too often or was sent on another channel
+	ErrInvalidTransferCode = errors.New("transfer code is invalid or expired")
+	// ErrInvalidTransfer: the claim token is unknown, used, superseded or expired
+	ErrInvalidTransfer = errors.New("transfer token is invalid or expired")
+)
+
+// Contact identifies a symbicode owner: the order's phone and email for the
+// buyer, the claimer's for later owners
+type Contact struct {
+	Phone string `json:"phone"`
+	Email string `json:"email"`
+}
+
+// normalized strips phone separators and lowercases the email
+func (c Contact) normalized() Contact {
+	phone := strings.Map(func(r rune) rune {
+		if strings.ContainsRune(" .-()", r) {
+			return -1
+		}
+		return r
+	}, c.Phone)
+	return Contact{Phone: phone, Email: strings.ToLower(strings.TrimSpace(c.Email))}
+}
+
+// channel returns the channel and normalized address of the one contact c
+// gives, where a transfer code is sent and checked
+func (c Contact) channel() (channel, address string, err error) {
+	c = c.normalized()
+	switch {
+	case c.Phone != "" && c.Email != "":
+		return "", "", ErrTransferChannel
+	case c.Phone != "":
+		return integrations.ChannelSMS, c.Phone, nil
+	case c.Email != "":
+		return integrations.ChannelEmail, c.Email, nil
+	}
+	return "", "", ErrTransferContact
+}
+
+// reaches reports whether address is c's contact on channel
+func (c Contact) reaches(channel, address string) bool {
+	c = c.normalized()
+	if channel == integrations.ChannelEmail {
+		return c.Email != "" && c.Email == address
+	}
+	return c.Phone != "" && c.Phone == address
+}
+
+// TransferChallenge tells the owner where the confirmation code went
+type TransferChallenge struct {
+	ExpiresAt time.Time `json:"expires_at"`
+	Channel   string    `json:"channel"` // email or sms
+}
+
+// TransferOffer is returned to the owner once; the buyer claims with Token
+type TransferOffer struct {
+	ExpiresAt   time.Time `json:"expires_at"`
+	Token       string    `json:"token"`
+	SymbicodeID uint64    `json:"symbicode_id"`
+}
+
+// ClaimResult describes a symbicode after its transfer was claimed
+type ClaimResult struct {
+	SymbicodeID uint64 `json:"symbicode_id"`
+	ProductID   uint64 `json:"product_id"`
+	Owners      int    `json:"owners"`
+}
+
+// InitiateSymbicodeTransfer starts a resale of a symbicode. code is the label
+// token and owner gives one contact, a phone number or an email, which must be
+// the current owner's. A one-time code is sent to that contact; the label and
+// the owner's details alone are not enough, since anyone holding the item may
+// know both. A new code supersedes the pending one.
+func (s *service) InitiateSymbicodeTransfer(ctx context.Context, code string, owner Contact) (_ *TransferChallenge, err error) {
+	ctx, span := tracer.Start(ctx, "Service.InitiateSymbicodeTransfer")
+	defer tracing.End(span, &err)
+
+	channel, address, err := owner.channel()
+	if err != nil {
+		return nil, err
+	}
+
+	repo := s.repo.WithContext(ctx)
+	symbicode, err := s.transferSymbicode(repo, code)
+	if err != nil {
+		return nil, err
+	}
+	current, ok, err := currentOwner(repo, symbicode)
+	if err != nil {
+		return nil, err
+	}
+	if !ok || !current.reaches(channel, address) {
+		return nil, ErrTransferDenied
+	}
+
+	otp, err := newTransferCode()
+	if err != nil {
+		return nil, err
+	}
+	record := &models.SymbicodeTransferCode{
+		SymbicodeID: symbicode.ID,
+		ExpiresAt:   time.Now().Add(TransferCodeTTL),
+		Channel:     channel,
+		Contact:     address,
+		CodeHash:    hashTransferToken(otp),
+	}
+	if err := repo.CreateSymbicodeTransferCode(record); err != nil {
+		return nil, fmt.Errorf("failed to create transfer code: %w", err)
+	}
+
+	n := integrations.Notification{At: time.Now(), Event: integrations.EventTransferCode, Code: otp, CodeTTL: TransferCodeTTL}
+	if channel == integrations.ChannelEmail {
+		n.Email = address
+	} else {
+		n.Phone = address
+	}
+	if err := s.notifier.Notify(ctx, n); err != nil {
+		return nil, fmt.Errorf("failed to send transfer code: %w", err)
+	}
+
+	metrics.SymbicodeTransfers.WithLabelValues("code_sent").Inc()
+	return &TransferChallenge{ExpiresAt: record.ExpiresAt, Channel: channel}, nil
+}
+
+// ConfirmSymbicodeTransfer creates the transfer offer once the owner enters
+// the code InitiateSymbicodeTransfer sent, on the same contact. Ownership is
+// checked again in the transaction that records the offer so a concurrent
+// claim cannot be overtaken. A new offer cancels any pending one.
+func (s *service) ConfirmSymbicodeTransfer(ctx context.Context, code string, owner Contact, otp string) (_ *TransferOffer, err error) {
+	ctx, span := tracer.Start(ctx, "Service.ConfirmSymbicodeTransfer")
+	defer tracing.End(span, &err)
+
+	channel, address, err := owner.channel()
+	if err != nil {
+		return nil, err
+	}
+
+	repo := s.repo.WithContext(ctx)
+	symbicode, err := s.transferSymbicode(repo, code)
+	if err != nil {
+		return nil, err
+	}
+	// Not in the transaction below: a wrong guess must count even though
+	// the offer is not created
+	used, err := repo.UseSymbicodeTransferCode(symbicode.ID, channel, address, hashTransferToken(strings.TrimSpace(otp)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to check transfer code: %w", err)
+	}
+	if !used {
+		metrics.SymbicodeTransfers.WithLabelValues("code_rejected").Inc()
+		return nil, ErrInvalidTransferCode
+	}
+
+	token, hash, err := newTransferToken()
+	if err != nil {
+		return nil, err
+	}
+	transfer := &models.SymbicodeTransfer{
+		SymbicodeID: symbicode.ID,
+		ExpiresAt:   time.Now().Add(s.transferTTL),
+		TokenHash:   hash,
+	}
+	err = repo.WithTransaction(func(tx repository.Repository) error {
+		current, ok, err := currentOwner(tx, symbicode)
+		if err != nil {
+			return err
+		}
+		if !ok || !current.reaches(channel, address) {
+			return ErrTransferDenied
+		}
+		if _, err := tx.CancelSymbicodeTransfers(symbicode.ID); err != nil {
+			return err
+		}
+		return tx.CreateSymbicodeTransfer(transfer)
+	})
+	if errors.Is(err, ErrTransferDenied) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create transfer: %w", err)
+	}
+
+	metrics.SymbicodeTransfers.WithLabelValues("offered").Inc()
+	return &TransferOffer{ExpiresAt: transfer.ExpiresAt, Token: token, SymbicodeID: symbicode.ID}, nil
+}
+
+// transferSymbicode looks up the symbicode on a label for a transfer; labels
+// that do not verify are all ErrTransferDenied
+func (s *service) transferSymbicode(repo repository.Repository, code string) (*models.Symbicode, error) {
+	symbicode, _, err := s.lookupSymbicode(repo, code)
+	switch {
+	case errors.Is(err, ErrForgedSymbicode), errors.Is(err, ErrUnknownSymbicode), errors.Is(err, ErrUnsignedSymbicode):
+		return nil, ErrTransferDenied
+	case err != nil:
+		return nil, err
+	}
+	return symbicode, nil
+}
+
+// ClaimSymbicodeTransfer makes recipient the owner of the symbicode offered
+// under token. Each token can be claimed once, before it expires.
+func (s *service) ClaimSymbicodeTransfer(ctx context.Context, token string, recipient Contact) (_ *ClaimResult, err error) {
+	ctx, span := tracer.Start(ctx, "Service.ClaimSymbicodeTransfer")
+	defer tracing.End(span, &err)
+
+	recipient = recipient.normalized()
+	if recipient.Phone == "" && recipient.Email == "" {
+		return nil, ErrTransferContact
+	}
+
+	repo := s.repo.WithContext(ctx)
+	transfer, err := repo.ClaimSymbicodeTransfer(hashTransferToken(strings.TrimSpace(token)), recipient.Phone, recipient.Email)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrInvalidTransfer
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim transfer: %w", err)
+	}
+	metrics.SymbicodeTransfers.WithLabelValues("claimed").Inc()
+
+	// Primary: the ownership history must include the claim above
+	primary := repo.Primary()
+	symbicode, err := primary.GetSymbicodeByID(transfer.SymbicodeID)
+	if err != nil {
+		return nil, err
+	}
+	owners, err := primary.ListSymbicodeOwners(symbicode.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &ClaimResult{SymbicodeID: symbicode.ID, ProductID: symbicode.ProductID, Owners: ownerCount(symbicode, owners)}, nil
+}
+
+// currentOwner returns the contact of the latest claimer, or of the order for
+// a code never transferred; ok is false for a code with no owner
+func currentOwner(repo repository.Repository, symbicode *models.Symbicode) (_ Contact, ok bool, err error) {
+	owners, err := repo.ListSymbicodeOwners(symbicode.ID)
+	if err != nil {
+		return Contact{}, false, err
+	}
+	if n := len(owners); n > 0 {
+		return Contact{Phone: owners[n-1].OwnerPhone, Email: owners[n-1].OwnerEmail}, true, nil
+	}
+	if symbicode.OrderID == 0 {
+		return Contact{}, false, nil
+	}
+
+	order, err := repo.GetOrderByID(symbicode.OrderID)
+	if err != nil {
+		return Contact{}, false, fmt.Errorf("failed to load order %d: %w", symbicode.OrderID, err)
+	}
+	var shipping struct {
+		Email string `json:"email"`
+	}
+	json.Unmarshal(order.ShippingAddress, &shipping)
+	return Contact{Phone: order.CustomerPhone, Email: shipping.Email}, true, nil
+}
+
+// ownerCount is the length of a symbicode's ownership history
+func ownerCount(symbicode *models.Symbicode, claimed []models.SymbicodeTransfer) int {
+	if symbicode.OrderID == 0 {
+		return len(claimed)
+	}
+	return len(claimed) + 1
+}
+
+// newTransferToken returns a random claim token and the hash that is stored
+func newTransferToken() (token, hash string, err error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", fmt.Errorf("failed to generate transfer token: %w", err)
+	}
+	token = base64.RawURLEncoding.EncodeToString(buf)
+	return token, hashTransferToken(token), nil
+}
+
+// newTransferCode returns a random 6-digit code to send to the owner
+func newTransferCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", fmt.Errorf("failed to generate transfer code: %w", err)
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
+
+func hashTransferToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
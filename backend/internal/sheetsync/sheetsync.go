@@ -0,0 +1,268 @@
+// Package sheetsync exports paid orders to the order spreadsheet. Paid
+// orders are queued as rows in the database; Sync appends the pending rows
+// in batches, one Sheets API call per batch, retrying each call with
+// backoff. It runs as a scheduled job, so one instance exports at a time
+// and a busy drop costs a few API calls a minute instead of one per order.
+// Rows that still fail stay pending for the next run. Backfill queues paid
+// orders straight from the orders table.
+package sheetsync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"ecommerce-backend/internal/emails"
+	"ecommerce-backend/internal/integrations"
+	"ecommerce-backend/internal/logging"
+	"ecommerce-backend/internal/metrics"
+	"ecommerce-backend/internal/models"
+	"ecommerce-backend/internal/utils/base32"
+)
+
+// Column is what one spreadsheet column holds
+type Column string
+
+const (
+	PaidAt      Column = "paid_at" // RFC 3339
+	CreatedAt   Column = "created_at"
+	OrderNumber Column = "order_number"
+	OrderID     Column = "order_id"
+	Name        Column = "name"
+	Phone       Column = "phone"
+	Email       Column = "email"
+	Address     Column = "address"
+	Items       Column = "items" // "Watch x1; Strap x2"
+	Amount      Column = "amount"
+	Status      Column = "status"
+	Note        Column = "note" // "Winner - Limited Drop <order number>"
+	// Blank leaves the column empty
+	Blank Column = "-"
+)
+
+// Columns lists every column
+var Columns = []Column{PaidAt, CreatedAt, OrderNumber, OrderID, Name, Phone, Email, Address, Items, Amount, Status, Note, Blank}
+
+// DefaultColumns is the layout of the per-order export sheets already have
+var DefaultColumns = []Column{PaidAt, Name, Phone, Email, Address, Note, Amount}
+
+// ParseColumns parses column names
+func ParseColumns(names []string) ([]Column, error) {
+	cols := make([]Column, 0, len(names))
+	for _, name := range names {
+		c := Column(strings.TrimSpace(name))
+		if !known(c) {
+			return nil, fmt.Errorf("unknown sheet column %q", name)
+		}
+		cols = append(cols, c)
+	}
+	return cols, nil
+}
+
+func known(c Column) bool {
+	for _, col := range Columns {
+		if c == col {
+			return true
+		}
+	}
+	return false
+}
+
+// Options configures a Syncer
+type Options struct {
+	API   integrations.SheetsAPI
+	Store Store
+	// Columns is the sheet layout; nil means DefaultColumns
+	Columns []Column
+	// BatchSize rows go out per API call; 0 means 200
+	BatchSize int
+	// Attempts per batch, the first included; 0 means 3
+	Attempts int
+	// Backoff before the second attempt, doubling after; 0 means 2s
+	Backoff time.Duration
+	Logger  *slog.Logger
+	// Now returns the current time; nil means time.Now
+	Now func() time.Time
+}
+
+// Syncer queues paid orders and exports them; it implements
+// integrations.SheetSubmitter
+type Syncer struct {
+	api       integrations.SheetsAPI
+	store     Store
+	columns   []Column
+	batchSize int
+	attempts  int
+	backoff   time.Duration
+	log       *slog.Logger
+	now       func() time.Time
+}
+
+// New returns a Syncer exporting through opts.API
+func New(opts Options) *Syncer {
+	s := &Syncer{
+		api:       opts.API,
+		store:     opts.Store,
+		columns:   opts.Columns,
+		batchSize: opts.BatchSize,
+		attempts:  opts.Attempts,
+		backoff:   opts.Backoff,
+		log:       logging.Subsystem(opts.Logger, "sheets"),
+		now:       opts.Now,
+	}
+	if s.columns == nil {
+		s.columns = DefaultColumns
+	}
+	if s.batchSize <= 0 {
+		s.batchSize = 200
+	}
+	if s.attempts <= 0 {
+		s.attempts = 3
+	}
+	if s.backoff <= 0 {
+		s.backoff = 2 * time.Second
+	}
+	if s.now == nil {
+		s.now = time.Now
+	}
+	return s
+}
+
+// QueueOrder queues a paid order for the next Sync
+func (s *Syncer) QueueOrder(ctx context.Context, orderID uint64, paidAt time.Time) error {
+	return s.store.Queue(ctx, orderID, paidAt, s.now())
+}
+
+// Backfill queues the paid orders created in [from, to) that were never
+// exported, for example those paid before the export was set up
+func (s *Syncer) Backfill(ctx context.Context, from, to time.Time) (int, error) {
+	n, err := s.store.QueuePaid(ctx, from, to, s.now())
+	if err != nil {
+		return 0, err
+	}
+	s.log.InfoContext(ctx, "sheet backfill queued", "from", from, "to", to, "orders", n)
+	return n, nil
+}
+
+// Stats reports the queue
+func (s *Syncer) Stats(ctx context.Context) (Stats, error) {
+	return s.store.Stats(ctx)
+}
+
+// Sync exports the pending rows and returns how many went out. A batch
+// that keeps failing stops the run and stays pending.
+// integrations.ErrSheetsNotConfigured is returned before any row is touched.
+func (s *Syncer) Sync(ctx context.Context) (int, error) {
+	exported := 0
+	for {
+		pending, err := s.store.Pending(ctx, s.batchSize)
+		if err != nil {
+			return exported, fmt.Errorf("read pending rows: %w", err)
+		}
+		if len(pending) == 0 {
+			return exported, nil
+		}
+
+		ids := make([]uint64, len(pending))
+		rows := make([][]string, len(pending))
+		for i, p := range pending {
+			ids[i] = p.Row.OrderID
+			rows[i] = s.row(p)
+		}
+
+		if err := s.append(ctx, rows); err != nil {
+			if errors.Is(err, integrations.ErrSheetsNotConfigured) {
+				return exported, err
+			}
+			metrics.SheetRows.WithLabelValues("failed").Add(float64(len(rows)))
+			if markErr := s.store.MarkFailed(context.WithoutCancel(ctx), ids, err.Error()); markErr != nil {
+				s.log.ErrorContext(ctx, "failed to record sheet export failure", "error", markErr)
+			}
+			return exported, err
+		}
+		// Appended rows must not go out again, even if the run was cancelled
+		if err := s.store.MarkExported(context.WithoutCancel(ctx), ids, s.now()); err != nil {
+			return exported, fmt.Errorf("mark %d rows exported: %w", len(ids), err)
+		}
+		metrics.SheetRows.WithLabelValues("exported").Add(float64(len(rows)))
+		exported += len(rows)
+
+		if len(pending) < s.batchSize {
+			return exported, nil
+		}
+	}
+}
+
+// append makes up to s.attempts AppendRows calls, backing off between them
+func (s *Syncer) append(ctx context.Context, rows [][]string) error {
+	wait := s.backoff
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = s.api.AppendRows(ctx, rows); err == nil || errors.Is(err, integrations.ErrSheetsNotConfigured) {
+			return err
+		}
+		if attempt == s.attempts {
+			return err
+		}
+		s.log.WarnContext(ctx, "sheet append failed, retrying", "rows", len(rows), "attempt", attempt, "retry_in", wait, "error", err)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(wait):
+		}
+		wait *= 2
+	}
+}
+
+// row renders p in the configured columns
+func (s *Syncer) row(p Pending) []string {
+	order := &p.Order
+	data := integrations.AdminOrderData(order)
+	if data.Phone == "" {
+		data.Phone = order.CustomerPhone
+	}
+
+	out := make([]string, len(s.columns))
+	for i, c := range s.columns {
+		switch c {
+		case PaidAt:
+			out[i] = time.UnixMilli(p.Row.PaidAt).Format(time.RFC3339)
+		case CreatedAt:
+			out[i] = order.CreatedAt.Format(time.RFC3339)
+		case OrderNumber:
+			out[i] = data.OrderNumber
+		case OrderID:
+			out[i] = strconv.FormatUint(order.ID, 10)
+		case Name:
+			out[i] = data.Name
+		case Phone:
+			out[i] = data.Phone
+		case Email:
+			out[i] = data.Email
+		case Address:
+			out[i] = data.Address
+		case Items:
+			out[i] = itemList(data.Items)
+		case Amount:
+			out[i] = strconv.FormatUint(order.TotalAmount, 10)
+		case Status:
+			out[i] = models.OrderStatusName(order.Status)
+		case Note:
+			out[i] = "Winner - Limited Drop " + base32.GenerateOrderNumber(order.ID)
+		}
+	}
+	return out
+}
+
+// itemList is the order's items on one line
+func itemList(items []emails.OrderItem) string {
+	parts := make([]string, 0, len(items))
+	for _, it := range items {
+		parts = append(parts, fmt.Sprintf("%s x%d", it.Name, it.Quantity))
+	}
+	return strings.Join(parts, "; ")
+}
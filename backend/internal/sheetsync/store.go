@@ -0,0 +1,190 @@
+package sheetsync
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"ecommerce-backend/internal/models"
+)
+
+// Pending is a row waiting for export, with its order
+type Pending struct {
+	Row   models.SheetRow
+	Order models.Order
+}
+
+// Stats summarizes the export, as served to admins
+type Stats struct {
+	Pending         int        `json:"pending"`
+	Exported        int        `json:"exported"`
+	OldestPendingAt *time.Time `json:"oldest_pending_at,omitempty"` // paid time of the oldest pending row
+	LastExportedAt  *time.Time `json:"last_exported_at,omitempty"`
+	LastError       string     `json:"last_error,omitempty"` // of the pending rows
+}
+
+// Store keeps the export queue
+type Store interface {
+	// Queue adds a paid order; an order already queued is left alone
+	Queue(ctx context.Context, orderID uint64, paidAt, now time.Time) error
+	// QueuePaid queues the paid orders created in [from, to) that were
+	// never queued, with their creation time as the paid time
+	QueuePaid(ctx context.Context, from, to, now time.Time) (int, error)
+	// Pending returns up to limit pending rows, oldest paid first
+	Pending(ctx context.Context, limit int) ([]Pending, error)
+	// MarkExported records that the rows of orderIDs were appended
+	MarkExported(ctx context.Context, orderIDs []uint64, at time.Time) error
+	// MarkFailed counts a failed export of the rows of orderIDs
+	MarkFailed(ctx context.Context, orderIDs []uint64, reason string) error
+	Stats(ctx context.Context) (Stats, error)
+}
+
+// SQLStore keeps the queue in the sheet_rows table (models.SheetRow) and
+// reads the orders from the orders table. db must be the writer pool.
+type SQLStore struct {
+	db *sql.DB
+}
+
+// NewSQLStore returns a store backed by db
+func NewSQLStore(db *sql.DB) *SQLStore {
+	return &SQLStore{db: db}
+}
+
+func (s *SQLStore) Queue(ctx context.Context, orderID uint64, paidAt, now time.Time) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO sheet_rows (order_id, paid_at, queued_at, exported_at, attempts, last_error)
+		VALUES (?, ?, ?, 0, 0, '')
+		ON CONFLICT(order_id) DO NOTHING`,
+		orderID, paidAt.UnixMilli(), now.UnixMilli())
+	return err
+}
+
+func (s *SQLStore) QueuePaid(ctx context.Context, from, to, now time.Time) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// created_at is stored in local time; compare in the same zone
+	rows, err := tx.QueryContext(ctx, `
+		SELECT o.id, o.created_at FROM orders o
+		LEFT JOIN sheet_rows r ON r.order_id = o.id
+		WHERE o.status IN (?, ?) AND o.created_at >= ? AND o.created_at < ? AND r.order_id IS NULL
+		ORDER BY o.id`,
+		models.OrderPaid, models.OrderDelivered, from.Local(), to.Local())
+	if err != nil {
+		return 0, err
+	}
+	type paid struct {
+		id        uint64
+		createdAt time.Time
+	}
+	var orders []paid
+	for rows.Next() {
+		var p paid
+		if err := rows.Scan(&p.id, &p.createdAt); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		orders = append(orders, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, p := range orders {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO sheet_rows (order_id, paid_at, queued_at, exported_at, attempts, last_error)
+			VALUES (?, ?, ?, 0, 0, '')`,
+			p.id, p.createdAt.UnixMilli(), now.UnixMilli()); err != nil {
+			return 0, err
+		}
+	}
+	return len(orders), tx.Commit()
+}
+
+func (s *SQLStore) Pending(ctx context.Context, limit int) ([]Pending, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT r.order_id, r.paid_at, r.queued_at, r.attempts, r.last_error,
+			o.created_at, COALESCE(o.customer_phone, ''), COALESCE(o.shipping_address, '{}'), COALESCE(o.items, '[]'), o.total_amount, o.status
+		FROM sheet_rows r
+		JOIN orders o ON o.id = r.order_id
+		WHERE r.exported_at = 0
+		ORDER BY r.paid_at, r.order_id
+		LIMIT ?`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Pending
+	for rows.Next() {
+		var (
+			p               Pending
+			shipping, items string
+		)
+		if err := rows.Scan(&p.Row.OrderID, &p.Row.PaidAt, &p.Row.QueuedAt, &p.Row.Attempts, &p.Row.LastError,
+			&p.Order.CreatedAt, &p.Order.CustomerPhone, &shipping, &items, &p.Order.TotalAmount, &p.Order.Status); err != nil {
+			return nil, err
+		}
+		p.Order.ID = p.Row.OrderID
+		p.Order.ShippingAddress = []byte(shipping)
+		p.Order.Items = []byte(items)
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
+
+func (s *SQLStore) MarkExported(ctx context.Context, orderIDs []uint64, at time.Time) error {
+	query, args := inOrders(`UPDATE sheet_rows SET exported_at = ?, last_error = '' WHERE order_id IN `, orderIDs, at.UnixMilli())
+	_, err := s.db.ExecContext(ctx, query, args...)
+	return err
+}
+
+func (s *SQLStore) MarkFailed(ctx context.Context, orderIDs []uint64, reason string) error {
+	query, args := inOrders(`UPDATE sheet_rows SET attempts = attempts + 1, last_error = ? WHERE order_id IN `, orderIDs, reason)
+	_, err := s.db.ExecContext(ctx, query, args...)
+	return err
+}
+
+// inOrders appends the (?, ...) list for orderIDs to query, after args
+func inOrders(query string, orderIDs []uint64, args ...any) (string, []any) {
+	marks := strings.TrimSuffix(strings.Repeat("?, ", len(orderIDs)), ", ")
+	for _, id := range orderIDs {
+		args = append(args, id)
+	}
+	return query + "(" + marks + ")", args
+}
+
+func (s *SQLStore) Stats(ctx context.Context) (Stats, error) {
+	var (
+		st           Stats
+		oldest, last int64
+	)
+	err := s.db.QueryRowContext(ctx, `
+		SELECT
+			COALESCE(SUM(exported_at = 0), 0),
+			COALESCE(SUM(exported_at <> 0), 0),
+			COALESCE(MIN(CASE WHEN exported_at = 0 THEN paid_at END), 0),
+			COALESCE(MAX(exported_at), 0),
+			COALESCE((SELECT last_error FROM sheet_rows WHERE exported_at = 0 AND last_error <> '' ORDER BY paid_at DESC LIMIT 1), '')
+		FROM sheet_rows`).Scan(&st.Pending, &st.Exported, &oldest, &last, &st.LastError)
+	if err != nil {
+		return Stats{}, err
+	}
+	st.OldestPendingAt = fromMillis(oldest)
+	st.LastExportedAt = fromMillis(last)
+	return st, nil
+}
+
+// fromMillis converts unix milliseconds, keeping 0 as nil
+func fromMillis(ms int64) *time.Time {
+	if ms == 0 {
+		return nil
+	}
+	t := time.UnixMilli(ms)
+	return &t
+}
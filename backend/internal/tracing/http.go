@@ -0,0 +1,24 @@
+package tracing
+
+import (
+	"net/http"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+// Transport records a client span named "<service> <METHOD>" for every
+// outbound request and sends the traceparent header; base nil means
+// http.DefaultTransport and an empty service names spans after the host.
+func Transport(base http.RoundTripper, service string) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return otelhttp.NewTransport(base,
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+			if service == "" {
+				return r.URL.Host + " " + r.Method
+			}
+			return service + " " + r.Method
+		}),
+	)
+}
@@ -0,0 +1,47 @@
+package base32
+
+import (
+	"fmt"
+	"strings"
+)
+
+// crockfordAlphabet is Crockford's base32: no I, L, O or U, so numbers read
+// aloud or copied by hand are not mistaken for each other
+const crockfordAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// checkSymbols are the Crockford check symbols for values mod 37
+const checkSymbols = crockfordAlphabet + "*~$=U"
+
+// encodeCrockford writes the low width bits of v as fixed-length Crockford base32
+func encodeCrockford(v uint64, width int) string {
+	n := (width + 4) / 5
+	out := make([]byte, n)
+	for i := n - 1; i >= 0; i-- {
+		out[i] = crockfordAlphabet[v&31]
+		v >>= 5
+	}
+	return string(out)
+}
+
+// decodeCrockford parses upper-case Crockford base32, reading O as 0 and I, L as 1
+func decodeCrockford(s string) (uint64, error) {
+	var v uint64
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch c {
+		case 'O':
+			c = '0'
+		case 'I', 'L':
+			c = '1'
+		}
+		d := strings.IndexByte(crockfordAlphabet, c)
+		if d < 0 {
+			return 0, fmt.Errorf("%w: unexpected %q", ErrInvalidOrderNumber, s[i])
+		}
+		if v>>59 != 0 {
+			return 0, fmt.Errorf("%w: out of range", ErrInvalidOrderNumber)
+		}
+		v = v<<5 | uint64(d)
+	}
+	return v, nil
+}
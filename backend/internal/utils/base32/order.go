@@ -1,36 +1,166 @@
 package base32
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
 	"encoding/base32"
+	"encoding/binary"
+	"errors"
 	"fmt"
 	"strconv"
+	"strings"
+	"sync"
 )
 
-// GenerateOrderNumber generates order number from ID using Base32 encoding
-// Format: DV-{Base32(ID)} (e.g., DV-7X9 for ID=12345)
+// Prefix starts every public order number
+const Prefix = "DV-"
+
+// ErrInvalidOrderNumber is returned for malformed numbers and failed checksums
+var ErrInvalidOrderNumber = errors.New("invalid order number")
+
+// defaultKey keeps numbers stable in development; production sets its own
+var defaultKey = []byte("donald-order-numbers-development")
+
+var (
+	keyMu sync.RWMutex
+	key   = defaultKey
+	// legacyMaxID is the last ID handed out in the legacy format
+	legacyMaxID uint64
+)
+
+// SetKey sets the key of the ID permutation. Numbers already handed out stop
+// decoding when it changes, so set it once at startup. An empty key restores
+// the development default.
+func SetKey(k []byte) {
+	keyMu.Lock()
+	defer keyMu.Unlock()
+	if len(k) == 0 {
+		k = defaultKey
+	}
+	key = append([]byte(nil), k...)
+}
+
+// SetLegacyMaxID sets the last order ID emailed in the legacy format; legacy
+// numbers of later IDs are rejected, and 0 rejects every legacy number. The
+// legacy format is unkeyed, so without a cutover any ID could be guessed.
+func SetLegacyMaxID(id uint64) {
+	keyMu.Lock()
+	defer keyMu.Unlock()
+	legacyMaxID = id
+}
+
+func currentKey() []byte {
+	keyMu.RLock()
+	defer keyMu.RUnlock()
+	return key
+}
+
+// GenerateOrderNumber generates the public order number for an ID: the ID
+// permuted under the key, in Crockford base32, followed by a check symbol.
+// Format: DV-{8 symbols}{check} (e.g. DV-P0078DWZD), or 13 symbols for IDs
+// of 2^40 and above
 func GenerateOrderNumber(id uint64) string {
-	idStr := strconv.FormatUint(id, 10)
-	encoded := base32.StdEncoding.EncodeToString([]byte(idStr))
-	return "DV-" + encoded
+	w := shortWidth
+	if id >= 1<<shortWidth {
+		w = longWidth
+	}
+	v := permute(currentKey(), w, id)
+	return Prefix + encodeCrockford(v, w) + string(checkSymbols[v%37])
 }
 
-// DecodeOrderNumber decodes order number back to ID
-// Input: "DV-7X9" -> Output: 12345
+// DecodeOrderNumber decodes an order number back to its ID. Input is
+// case-insensitive, hyphens after the prefix are ignored and O, I and L read
+// as 0, 1 and 1. Numbers from the earlier format (DV-GEZDGNBV, the base32
+// of the decimal ID) are still accepted up to the ID set by SetLegacyMaxID.
+// Input: "DV-P0078DWZD" -> Output: 12345 (development key)
 func DecodeOrderNumber(orderNumber string) (uint64, error) {
-	if len(orderNumber) < 3 || orderNumber[:3] != "DV-" {
-		return 0, fmt.Errorf("invalid order number format")
+	s := strings.ToUpper(strings.TrimSpace(orderNumber))
+	if !strings.HasPrefix(s, Prefix) {
+		return 0, fmt.Errorf("%w: missing %s prefix", ErrInvalidOrderNumber, Prefix)
 	}
+	s = strings.ReplaceAll(s[len(Prefix):], "-", "")
 
-	encoded := orderNumber[3:] // Remove "DV-" prefix
-	decoded, err := base32.StdEncoding.DecodeString(encoded)
+	var w int
+	switch len(s) {
+	case shortWidth/5 + 1:
+		w = shortWidth
+	case longWidth/5 + 2: // 13 symbols hold 65 bits
+		w = longWidth
+	default:
+		return decodeLegacy(s)
+	}
+
+	v, err := decodeCrockford(s[:len(s)-1])
 	if err != nil {
 		return 0, err
 	}
+	if check := strings.IndexByte(checkSymbols, s[len(s)-1]); check < 0 || uint64(check) != v%37 {
+		return 0, fmt.Errorf("%w: checksum mismatch", ErrInvalidOrderNumber)
+	}
+	id := unpermute(currentKey(), w, v)
+	// Each ID has one number: the long form is only for IDs the short one can't hold
+	if w == longWidth && id < 1<<shortWidth {
+		return 0, fmt.Errorf("%w: non-canonical number", ErrInvalidOrderNumber)
+	}
+	return id, nil
+}
+
+// decodeLegacy decodes DV-{base32(decimal ID)} numbers emailed before the
+// Crockford format
+func decodeLegacy(s string) (uint64, error) {
+	keyMu.RLock()
+	maxID := legacyMaxID
+	keyMu.RUnlock()
 
+	decoded, err := base32.StdEncoding.DecodeString(s)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrInvalidOrderNumber, err)
+	}
 	id, err := strconv.ParseUint(string(decoded), 10, 64)
 	if err != nil {
-		return 0, err
+		return 0, fmt.Errorf("%w: %v", ErrInvalidOrderNumber, err)
+	}
+	if id == 0 || id > maxID {
+		return 0, fmt.Errorf("%w: legacy number after the cutover", ErrInvalidOrderNumber)
 	}
-
 	return id, nil
 }
+
+// Permutation widths in bits: 40 bits (8 symbols) covers a trillion orders
+const (
+	shortWidth = 40
+	longWidth  = 64
+	rounds     = 4
+)
+
+// permute maps id onto [0, 2^width) with a keyed Feistel network, so
+// consecutive IDs give unrelated numbers
+func permute(key []byte, width int, id uint64) uint64 {
+	half := width / 2
+	mask := uint64(1)<<half - 1
+	l, r := id>>half&mask, id&mask
+	for i := 0; i < rounds; i++ {
+		l, r = r, l^feistel(key, width, i, r)&mask
+	}
+	return l<<half | r
+}
+
+// unpermute inverts permute
+func unpermute(key []byte, width int, v uint64) uint64 {
+	half := width / 2
+	mask := uint64(1)<<half - 1
+	l, r := v>>half&mask, v&mask
+	for i := rounds - 1; i >= 0; i-- {
+		l, r = r^feistel(key, width, i, l)&mask, l
+	}
+	return l<<half | r
+}
+
+func feistel(key []byte, width, round int, half uint64) uint64 {
+	var msg [10]byte
+	msg[0], msg[1] = byte(width), byte(round)
+	binary.BigEndian.PutUint64(msg[2:], half)
+	m := hmac.New(sha256.New, key)
+	m.Write(msg[:])
+	return binary.BigEndian.Uint64(m.Sum(nil))
+}
@@ -0,0 +1,142 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"ecommerce-backend/internal/models"
+	"ecommerce-backend/internal/utils/base32"
+)
+
+// Event is the type of an outbound event
+type Event string
+
+const (
+	OrderPaid          Event = "order.paid"
+	OrderCancelled     Event = "order.cancelled" // lost the drop; the payment is refunded
+	DropStarted        Event = "drop.started"
+	DropSoldOut        Event = "drop.sold_out"
+	SymbicodeActivated Event = "symbicode.activated"
+)
+
+// Events lists every event
+var Events = []Event{OrderPaid, OrderCancelled, DropStarted, DropSoldOut, SymbicodeActivated}
+
+// AllEvents subscribes to every event, including those added later
+const AllEvents = "*"
+
+// ParseEvents parses event names; AllEvents stands alone
+func ParseEvents(names []string) ([]Event, error) {
+	events := make([]Event, 0, len(names))
+	for _, name := range names {
+		ev := Event(strings.TrimSpace(name))
+		if ev == AllEvents {
+			if len(names) > 1 {
+				return nil, fmt.Errorf("%q cannot be combined with other events", AllEvents)
+			}
+			return []Event{ev}, nil
+		}
+		if !known(ev) {
+			return nil, fmt.Errorf("unknown webhook event %q", name)
+		}
+		events = append(events, ev)
+	}
+	if len(events) == 0 {
+		return nil, fmt.Errorf("no webhook events")
+	}
+	return events, nil
+}
+
+func known(ev Event) bool {
+	for _, e := range Events {
+		if ev == e {
+			return true
+		}
+	}
+	return false
+}
+
+// Payload is the JSON body POSTed to subscribers. ID is the same for every
+// subscription and every retry or replay, so receivers can drop duplicates.
+type Payload struct {
+	ID        string    `json:"id"`
+	Event     Event     `json:"event"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      any       `json:"data"`
+}
+
+// eventID identifies the event about key, for example "order.paid:42"
+func eventID(ev Event, key string) string {
+	return string(ev) + ":" + key
+}
+
+// OrderData is the data of the order events
+type OrderData struct {
+	OrderID         uint64          `json:"order_id"`
+	OrderNumber     string          `json:"order_number"`
+	Status          string          `json:"status"`
+	Amount          uint64          `json:"amount"`
+	Currency        string          `json:"currency"`
+	Phone           string          `json:"phone"`
+	Items           json.RawMessage `json:"items"`
+	ShippingAddress json.RawMessage `json:"shipping_address"`
+	CreatedAt       time.Time       `json:"created_at"`
+}
+
+// Order is the data of an order event about o
+func Order(o *models.Order) OrderData {
+	return OrderData{
+		OrderID:         o.ID,
+		OrderNumber:     base32.GenerateOrderNumber(o.ID),
+		Status:          models.OrderStatusName(o.Status),
+		Amount:          o.TotalAmount,
+		Currency:        "VND",
+		Phone:           o.CustomerPhone,
+		Items:           rawJSON(o.Items, "[]"),
+		ShippingAddress: rawJSON(o.ShippingAddress, "{}"),
+		CreatedAt:       o.CreatedAt,
+	}
+}
+
+// DropData is the data of the drop events
+type DropData struct {
+	DropID     uint64     `json:"drop_id"`
+	ProductID  uint64     `json:"product_id"`
+	Name       string     `json:"name"`
+	TotalStock uint32     `json:"total_stock"`
+	Sold       uint32     `json:"sold"`
+	StartsAt   time.Time  `json:"starts_at"`
+	EndsAt     *time.Time `json:"ends_at,omitempty"`
+}
+
+// Drop is the data of a drop event about d
+func Drop(d *models.LimitedDrop) DropData {
+	return DropData{
+		DropID:     d.ID,
+		ProductID:  d.ProductID,
+		Name:       d.Name,
+		TotalStock: d.TotalStock,
+		Sold:       d.Sold,
+		StartsAt:   d.StartTime,
+		EndsAt:     d.EndTime,
+	}
+}
+
+// SymbicodeData is the data of symbicode.activated
+type SymbicodeData struct {
+	SymbicodeID uint64    `json:"symbicode_id"`
+	ProductID   uint64    `json:"product_id,omitempty"`
+	OrderID     uint64    `json:"order_id,omitempty"`
+	ActivatedAt time.Time `json:"activated_at"`
+	// Auto is set when the code was activated for never being scanned
+	Auto bool `json:"auto"`
+}
+
+func rawJSON(b []byte, empty string) json.RawMessage {
+	if len(b) == 0 || !json.Valid(b) {
+		return json.RawMessage(empty)
+	}
+	return json.RawMessage(b)
+}
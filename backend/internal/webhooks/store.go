@@ -0,0 +1,279 @@
+package webhooks
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"ecommerce-backend/internal/models"
+)
+
+// Delivery statuses
+const (
+	StatusPending   = "pending"
+	StatusDelivered = "delivered"
+	StatusFailed    = "failed"
+)
+
+// Due is a pending delivery with where it goes
+type Due struct {
+	Delivery models.WebhookDelivery
+	URL      string
+	Secret   string
+}
+
+// Store keeps subscriptions, the delivery queue and the delivery log
+type Store interface {
+	CreateSubscription(ctx context.Context, sub *models.WebhookSubscription) error
+	Subscriptions(ctx context.Context) ([]models.WebhookSubscription, error)
+	// DeleteSubscription removes a subscription and fails its pending
+	// deliveries; false when there is no such subscription
+	DeleteSubscription(ctx context.Context, id uint64) (bool, error)
+	// Enqueue queues the event for every subscription to it and returns how
+	// many were queued. A subscription that already has eventID is skipped.
+	Enqueue(ctx context.Context, eventID string, ev Event, payload []byte, now time.Time) (int, error)
+	// Due returns up to limit pending deliveries whose next attempt is at
+	// or before now, oldest first
+	Due(ctx context.Context, now time.Time, limit int) ([]Due, error)
+	// Claim moves a due delivery's next attempt to leaseUntil so no other
+	// instance sends it; false when another instance claimed it first
+	Claim(ctx context.Context, d models.WebhookDelivery, leaseUntil time.Time) (bool, error)
+	// Record logs an attempt and sets the delivery's status and next attempt
+	Record(ctx context.Context, attempt models.WebhookAttempt, status string, next time.Time) error
+	// Deliveries returns up to limit deliveries to a subscription, newest first
+	Deliveries(ctx context.Context, subscriptionID uint64, limit int) ([]models.WebhookDelivery, error)
+	// Delivery returns a delivery and its attempts, oldest first; nil when
+	// there is no such delivery
+	Delivery(ctx context.Context, id uint64) (*models.WebhookDelivery, []models.WebhookAttempt, error)
+	// Replay queues a delivery of a live subscription again with a fresh
+	// set of attempts; false when there is no such delivery
+	Replay(ctx context.Context, id uint64, now time.Time) (bool, error)
+}
+
+// SQLStore keeps subscriptions and deliveries in the webhook_subscriptions,
+// webhook_deliveries and webhook_attempts tables. db must be the writer pool.
+type SQLStore struct {
+	db *sql.DB
+}
+
+// NewSQLStore returns a store backed by db
+func NewSQLStore(db *sql.DB) *SQLStore {
+	return &SQLStore{db: db}
+}
+
+func (s *SQLStore) CreateSubscription(ctx context.Context, sub *models.WebhookSubscription) error {
+	res, err := s.db.ExecContext(ctx, `
+		INSERT INTO webhook_subscriptions (url, events, secret, description, created_at)
+		VALUES (?, ?, ?, ?, ?)`,
+		sub.URL, sub.Events, sub.Secret, sub.Description, sub.CreatedAt)
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	sub.ID = uint64(id)
+	return nil
+}
+
+func (s *SQLStore) Subscriptions(ctx context.Context) ([]models.WebhookSubscription, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, url, events, secret, description, created_at
+		FROM webhook_subscriptions ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []models.WebhookSubscription
+	for rows.Next() {
+		var sub models.WebhookSubscription
+		if err := rows.Scan(&sub.ID, &sub.URL, &sub.Events, &sub.Secret, &sub.Description, &sub.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, sub)
+	}
+	return out, rows.Err()
+}
+
+func (s *SQLStore) DeleteSubscription(ctx context.Context, id uint64) (bool, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `DELETE FROM webhook_subscriptions WHERE id = ?`, id)
+	if err != nil {
+		return false, err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return false, err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE webhook_deliveries SET status = ?, last_error = 'subscription deleted'
+		WHERE subscription_id = ? AND status = ?`,
+		StatusFailed, id, StatusPending); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+func (s *SQLStore) Enqueue(ctx context.Context, eventID string, ev Event, payload []byte, now time.Time) (int, error) {
+	// events is a comma-separated list without spaces, or "*"
+	res, err := s.db.ExecContext(ctx, `
+		INSERT INTO webhook_deliveries (subscription_id, event_id, event, payload, status, last_error,
+			created_at, next_attempt_at, delivered_at, attempts, last_status_code)
+		SELECT id, ?, ?, ?, ?, '', ?, ?, 0, 0, 0
+		FROM webhook_subscriptions
+		WHERE events = ? OR (',' || events || ',') LIKE ('%,' || ? || ',%')
+		ON CONFLICT(subscription_id, event_id) DO NOTHING`,
+		eventID, string(ev), string(payload), StatusPending, now.UnixMilli(), now.UnixMilli(), AllEvents, string(ev))
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+func (s *SQLStore) Due(ctx context.Context, now time.Time, limit int) ([]Due, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+deliveryColumns+`, s.url, s.secret
+		FROM webhook_deliveries d
+		JOIN webhook_subscriptions s ON s.id = d.subscription_id
+		WHERE d.status = ? AND d.next_attempt_at <= ?
+		ORDER BY d.next_attempt_at, d.id
+		LIMIT ?`,
+		StatusPending, now.UnixMilli(), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Due
+	for rows.Next() {
+		var due Due
+		if err := rows.Scan(append(deliveryFields(&due.Delivery), &due.URL, &due.Secret)...); err != nil {
+			return nil, err
+		}
+		out = append(out, due)
+	}
+	return out, rows.Err()
+}
+
+func (s *SQLStore) Claim(ctx context.Context, d models.WebhookDelivery, leaseUntil time.Time) (bool, error) {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE webhook_deliveries SET next_attempt_at = ?
+		WHERE id = ? AND status = ? AND next_attempt_at = ?`,
+		leaseUntil.UnixMilli(), d.ID, StatusPending, d.NextAttemptAt)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+func (s *SQLStore) Record(ctx context.Context, a models.WebhookAttempt, status string, next time.Time) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO webhook_attempts (delivery_id, at, duration_ms, status_code, error, response)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		a.DeliveryID, a.At, a.DurationMs, a.StatusCode, a.Error, a.Response); err != nil {
+		return err
+	}
+	deliveredAt := int64(0)
+	if status == StatusDelivered {
+		deliveredAt = a.At
+	}
+	// A delivery failed by DeleteSubscription meanwhile stays failed
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE webhook_deliveries
+		SET status = ?, attempts = attempts + 1, next_attempt_at = ?, delivered_at = ?, last_status_code = ?, last_error = ?
+		WHERE id = ? AND status = ?`,
+		status, next.UnixMilli(), deliveredAt, a.StatusCode, a.Error, a.DeliveryID, StatusPending); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *SQLStore) Deliveries(ctx context.Context, subscriptionID uint64, limit int) ([]models.WebhookDelivery, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+deliveryColumns+`
+		FROM webhook_deliveries d
+		WHERE d.subscription_id = ?
+		ORDER BY d.id DESC
+		LIMIT ?`,
+		subscriptionID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []models.WebhookDelivery
+	for rows.Next() {
+		var d models.WebhookDelivery
+		if err := rows.Scan(deliveryFields(&d)...); err != nil {
+			return nil, err
+		}
+		out = append(out, d)
+	}
+	return out, rows.Err()
+}
+
+func (s *SQLStore) Delivery(ctx context.Context, id uint64) (*models.WebhookDelivery, []models.WebhookAttempt, error) {
+	var d models.WebhookDelivery
+	err := s.db.QueryRowContext(ctx, `SELECT `+deliveryColumns+` FROM webhook_deliveries d WHERE d.id = ?`, id).
+		Scan(deliveryFields(&d)...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, delivery_id, at, duration_ms, status_code, error, response
+		FROM webhook_attempts WHERE delivery_id = ? ORDER BY id`, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var attempts []models.WebhookAttempt
+	for rows.Next() {
+		var a models.WebhookAttempt
+		if err := rows.Scan(&a.ID, &a.DeliveryID, &a.At, &a.DurationMs, &a.StatusCode, &a.Error, &a.Response); err != nil {
+			return nil, nil, err
+		}
+		attempts = append(attempts, a)
+	}
+	return &d, attempts, rows.Err()
+}
+
+func (s *SQLStore) Replay(ctx context.Context, id uint64, now time.Time) (bool, error) {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE webhook_deliveries SET status = ?, attempts = 0, next_attempt_at = ?, delivered_at = 0
+		WHERE id = ? AND subscription_id IN (SELECT id FROM webhook_subscriptions)`,
+		StatusPending, now.UnixMilli(), id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+const deliveryColumns = `d.id, d.subscription_id, d.event_id, d.event, d.payload, d.status, d.attempts,
+	d.next_attempt_at, d.created_at, d.delivered_at, d.last_status_code, d.last_error`
+
+// deliveryFields are the scan targets for deliveryColumns
+func deliveryFields(d *models.WebhookDelivery) []any {
+	return []any{&d.ID, &d.SubscriptionID, &d.EventID, &d.Event, &d.Payload, &d.Status, &d.Attempts,
+		&d.NextAttemptAt, &d.CreatedAt, &d.DeliveredAt, &d.LastStatusCode, &d.LastError}
+}
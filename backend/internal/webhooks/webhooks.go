@@ -0,0 +1,536 @@
+// Package webhooks sends order, drop and symbicode events to the URLs
+// subscribers register, such as a warehouse tool or a chat bot. Emit
+// queues one delivery per subscription in the database; Run POSTs the due
+// deliveries, signed with the subscription's secret (see Sign), and retries
+// failures with exponential backoff until a 2xx response or the last
+// attempt. Every attempt is logged, and any delivery can be replayed.
+// Deliveries are claimed in the database, so several instances can run.
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"ecommerce-backend/internal/logging"
+	"ecommerce-backend/internal/metrics"
+	"ecommerce-backend/internal/models"
+)
+
+// Request headers
+const (
+	// SignatureHeader carries "t=<unix seconds>,v1=<hex HMAC-SHA256>"; see Sign
+	SignatureHeader = "X-Webhook-Signature"
+	EventHeader     = "X-Webhook-Event"
+	// IDHeader is the event ID, the same on every retry and replay
+	IDHeader = "X-Webhook-ID"
+	// DeliveryHeader is the delivery ID, as the delivery log shows it
+	DeliveryHeader = "X-Webhook-Delivery"
+)
+
+// ErrInvalidSubscription reports a subscription that cannot be created
+var ErrInvalidSubscription = errors.New("invalid webhook subscription")
+
+// maxResponse is how much of a response body the delivery log keeps
+const maxResponse = 512
+
+// Options configures a Dispatcher
+type Options struct {
+	Store Store
+	// MaxAttempts per delivery, the first included; 0 means 8
+	MaxAttempts int
+	// Backoff before the second attempt, doubling after up to MaxBackoff;
+	// 0 means 30s and 1h
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// Timeout per POST; 0 means 10s
+	Timeout time.Duration
+	// Workers POST at the same time; 0 means 4
+	Workers int
+	// PollInterval is how often due retries are looked for; 0 means 5s
+	PollInterval time.Duration
+	Client       *http.Client
+	Logger       *slog.Logger
+	// Now returns the current time; nil means time.Now
+	Now func() time.Time
+}
+
+// Dispatcher queues events and delivers them in the background; see Run.
+// A nil Dispatcher drops every event.
+type Dispatcher struct {
+	store       Store
+	maxAttempts int
+	backoff     time.Duration
+	maxBackoff  time.Duration
+	timeout     time.Duration
+	workers     int
+	poll        time.Duration
+	client      *http.Client
+	log         *slog.Logger
+	now         func() time.Time
+	wake        chan struct{}
+}
+
+// New returns a Dispatcher keeping its queue in opts.Store
+func New(opts Options) *Dispatcher {
+	d := &Dispatcher{
+		store:       opts.Store,
+		maxAttempts: opts.MaxAttempts,
+		backoff:     opts.Backoff,
+		maxBackoff:  opts.MaxBackoff,
+		timeout:     opts.Timeout,
+		workers:     opts.Workers,
+		poll:        opts.PollInterval,
+		client:      opts.Client,
+		log:         logging.Subsystem(opts.Logger, "webhooks"),
+		now:         opts.Now,
+		wake:        make(chan struct{}, 1),
+	}
+	if d.maxAttempts <= 0 {
+		d.maxAttempts = 8
+	}
+	if d.backoff <= 0 {
+		d.backoff = 30 * time.Second
+	}
+	if d.maxBackoff <= 0 {
+		d.maxBackoff = time.Hour
+	}
+	if d.timeout <= 0 {
+		d.timeout = 10 * time.Second
+	}
+	if d.workers <= 0 {
+		d.workers = 4
+	}
+	if d.poll <= 0 {
+		d.poll = 5 * time.Second
+	}
+	if d.client == nil {
+		// Subscribers must answer themselves, not through a redirect
+		d.client = &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		}}
+	}
+	if d.now == nil {
+		d.now = time.Now
+	}
+	return d
+}
+
+// Emit queues ev about key (an order or drop ID) for its subscribers.
+// Emitting the same event about the same key again queues nothing. Failures
+// are logged: an event never fails the operation that raised it.
+func (d *Dispatcher) Emit(ctx context.Context, ev Event, key string, data any) {
+	if d == nil {
+		return
+	}
+	id := eventID(ev, key)
+	now := d.now()
+	body, err := json.Marshal(Payload{ID: id, Event: ev, CreatedAt: now.UTC(), Data: data})
+	if err != nil {
+		d.log.ErrorContext(ctx, "webhook payload failed", "event_id", id, "error", err)
+		return
+	}
+	n, err := d.store.Enqueue(context.WithoutCancel(ctx), id, ev, body, now)
+	if err != nil {
+		d.log.ErrorContext(ctx, "webhook enqueue failed", "event_id", id, "error", err)
+		return
+	}
+	if n > 0 {
+		d.log.DebugContext(ctx, "webhook queued", "event_id", id, "subscriptions", n)
+		d.notify()
+	}
+}
+
+func (d *Dispatcher) notify() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Subscription is a subscription as served to admins. Secret is only set
+// when the subscription is created.
+type Subscription struct {
+	ID          uint64    `json:"id"`
+	URL         string    `json:"url"`
+	Events      []string  `json:"events"`
+	Description string    `json:"description,omitempty"`
+	Secret      string    `json:"secret,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func subscription(sub models.WebhookSubscription) Subscription {
+	return Subscription{
+		ID:          sub.ID,
+		URL:         sub.URL,
+		Events:      strings.Split(sub.Events, ","),
+		Description: sub.Description,
+		CreatedAt:   sub.CreatedAt,
+	}
+}
+
+// Subscribe registers rawURL for events and returns the subscription with
+// its new secret. Errors wrapping ErrInvalidSubscription explain what is
+// wrong with the request.
+func (d *Dispatcher) Subscribe(ctx context.Context, rawURL string, events []string, description string) (Subscription, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return Subscription{}, fmt.Errorf("%w: url must be an http or https URL", ErrInvalidSubscription)
+	}
+	evs, err := ParseEvents(events)
+	if err != nil {
+		return Subscription{}, fmt.Errorf("%w: %v", ErrInvalidSubscription, err)
+	}
+	names := make([]string, len(evs))
+	for i, ev := range evs {
+		names[i] = string(ev)
+	}
+	secret, err := newSecret()
+	if err != nil {
+		return Subscription{}, err
+	}
+
+	sub := models.WebhookSubscription{
+		URL:         rawURL,
+		Events:      strings.Join(names, ","),
+		Secret:      secret,
+		Description: description,
+		CreatedAt:   d.now().UTC(),
+	}
+	if err := d.store.CreateSubscription(ctx, &sub); err != nil {
+		return Subscription{}, err
+	}
+	d.log.InfoContext(ctx, "webhook subscribed", "subscription_id", sub.ID, "url", sub.URL, "events", sub.Events)
+	out := subscription(sub)
+	out.Secret = secret
+	return out, nil
+}
+
+// newSecret returns a random signing secret
+func newSecret() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+// Subscriptions lists the subscriptions, without their secrets
+func (d *Dispatcher) Subscriptions(ctx context.Context) ([]Subscription, error) {
+	subs, err := d.store.Subscriptions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Subscription, len(subs))
+	for i, sub := range subs {
+		out[i] = subscription(sub)
+	}
+	return out, nil
+}
+
+// Unsubscribe removes a subscription; its pending deliveries fail. False
+// when there is no such subscription.
+func (d *Dispatcher) Unsubscribe(ctx context.Context, id uint64) (bool, error) {
+	ok, err := d.store.DeleteSubscription(ctx, id)
+	if ok {
+		d.log.InfoContext(ctx, "webhook unsubscribed", "subscription_id", id)
+	}
+	return ok, err
+}
+
+// Delivery is a delivery as served to admins
+type Delivery struct {
+	ID             uint64          `json:"id"`
+	SubscriptionID uint64          `json:"subscription_id"`
+	EventID        string          `json:"event_id"`
+	Event          string          `json:"event"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	LastStatusCode int             `json:"last_status_code,omitempty"`
+	LastError      string          `json:"last_error,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at,omitempty"` // while pending
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty"`
+	Payload        json.RawMessage `json:"payload,omitempty"`
+	// Log is every attempt, oldest first, when one delivery is asked for
+	Log []Attempt `json:"log,omitempty"`
+}
+
+// Attempt is one POST of a delivery
+type Attempt struct {
+	At         time.Time `json:"at"`
+	DurationMs int64     `json:"duration_ms"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	Response   string    `json:"response,omitempty"`
+}
+
+func delivery(d models.WebhookDelivery) Delivery {
+	out := Delivery{
+		ID:             d.ID,
+		SubscriptionID: d.SubscriptionID,
+		EventID:        d.EventID,
+		Event:          d.Event,
+		Status:         d.Status,
+		Attempts:       d.Attempts,
+		LastStatusCode: d.LastStatusCode,
+		LastError:      d.LastError,
+		CreatedAt:      time.UnixMilli(d.CreatedAt).UTC(),
+		DeliveredAt:    fromMillis(d.DeliveredAt),
+	}
+	if d.Status == StatusPending {
+		out.NextAttemptAt = fromMillis(d.NextAttemptAt)
+	}
+	return out
+}
+
+// fromMillis converts unix milliseconds, keeping 0 as nil
+func fromMillis(ms int64) *time.Time {
+	if ms == 0 {
+		return nil
+	}
+	t := time.UnixMilli(ms).UTC()
+	return &t
+}
+
+// Deliveries lists up to limit deliveries to a subscription, newest first
+func (d *Dispatcher) Deliveries(ctx context.Context, subscriptionID uint64, limit int) ([]Delivery, error) {
+	rows, err := d.store.Deliveries(ctx, subscriptionID, limit)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Delivery, len(rows))
+	for i, row := range rows {
+		out[i] = delivery(row)
+	}
+	return out, nil
+}
+
+// Delivery returns a delivery with its payload and attempt log; nil when
+// there is no such delivery
+func (d *Dispatcher) Delivery(ctx context.Context, id uint64) (*Delivery, error) {
+	row, attempts, err := d.store.Delivery(ctx, id)
+	if err != nil || row == nil {
+		return nil, err
+	}
+	out := delivery(*row)
+	out.Payload = json.RawMessage(row.Payload)
+	for _, a := range attempts {
+		out.Log = append(out.Log, Attempt{
+			At:         time.UnixMilli(a.At).UTC(),
+			DurationMs: a.DurationMs,
+			StatusCode: a.StatusCode,
+			Error:      a.Error,
+			Response:   a.Response,
+		})
+	}
+	return &out, nil
+}
+
+// Replay sends a delivery again, delivered or not, with a fresh set of
+// attempts. False when there is no such delivery or its subscription was
+// removed.
+func (d *Dispatcher) Replay(ctx context.Context, id uint64) (bool, error) {
+	ok, err := d.store.Replay(ctx, id, d.now())
+	if ok {
+		d.log.InfoContext(ctx, "webhook replay queued", "delivery_id", id)
+		d.notify()
+	}
+	return ok, err
+}
+
+// Run delivers due deliveries until ctx is cancelled, when the POSTs in
+// flight are finished
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.poll)
+	defer ticker.Stop()
+	for {
+		d.dispatch(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-d.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// dispatch delivers everything due now, d.workers at a time
+func (d *Dispatcher) dispatch(ctx context.Context) {
+	batch := d.workers * 8
+	for ctx.Err() == nil {
+		due, err := d.store.Due(ctx, d.now(), batch)
+		if err != nil {
+			d.log.ErrorContext(ctx, "webhook queue read failed", "error", err)
+			return
+		}
+
+		sem := make(chan struct{}, d.workers)
+		var wg sync.WaitGroup
+		claimed := 0
+		for _, item := range due {
+			// Long enough to outlast the POST; an instance that dies
+			// mid-delivery leaves it for a retry after this
+			ok, err := d.store.Claim(ctx, item.Delivery, d.now().Add(d.timeout+time.Minute))
+			if err != nil {
+				d.log.ErrorContext(ctx, "webhook claim failed", "delivery_id", item.Delivery.ID, "error", err)
+				continue
+			}
+			if !ok {
+				continue
+			}
+			claimed++
+			sem <- struct{}{}
+			wg.Add(1)
+			go func(item Due) {
+				defer func() {
+					<-sem
+					wg.Done()
+				}()
+				d.deliver(context.WithoutCancel(ctx), item)
+			}(item)
+		}
+		wg.Wait()
+
+		if len(due) < batch || claimed == 0 {
+			return
+		}
+	}
+}
+
+// deliver makes one attempt and records it
+func (d *Dispatcher) deliver(ctx context.Context, item Due) {
+	del := item.Delivery
+	start := d.now()
+	attempt := models.WebhookAttempt{DeliveryID: del.ID, At: start.UnixMilli()}
+
+	code, resp, err := d.post(ctx, item, start)
+	attempt.DurationMs = d.now().Sub(start).Milliseconds()
+	attempt.StatusCode = code
+	attempt.Response = resp
+	if err == nil && (code < 200 || code > 299) {
+		err = fmt.Errorf("status %d", code)
+	}
+
+	status, next, result := StatusDelivered, start, "delivered"
+	if err != nil {
+		attempt.Error = err.Error()
+		if del.Attempts+1 >= d.maxAttempts {
+			status, result = StatusFailed, "failed"
+		} else {
+			status, result = StatusPending, "retry"
+			next = start.Add(d.retryAfter(del.Attempts + 1))
+		}
+	}
+	metrics.WebhookDeliveries.WithLabelValues(del.Event, result).Inc()
+
+	switch result {
+	case "delivered":
+		d.log.DebugContext(ctx, "webhook delivered", "delivery_id", del.ID, "event_id", del.EventID, "status", code)
+	case "retry":
+		d.log.WarnContext(ctx, "webhook delivery failed, retrying", "delivery_id", del.ID, "event_id", del.EventID,
+			"url", item.URL, "attempt", del.Attempts+1, "retry_at", next, "error", err)
+	default:
+		d.log.ErrorContext(ctx, "webhook delivery failed", "delivery_id", del.ID, "event_id", del.EventID,
+			"url", item.URL, "attempts", del.Attempts+1, "error", err)
+	}
+
+	if err := d.store.Record(ctx, attempt, status, next); err != nil {
+		d.log.ErrorContext(ctx, "failed to record webhook attempt", "delivery_id", del.ID, "error", err)
+	}
+}
+
+// retryAfter is the wait after the nth failed attempt
+func (d *Dispatcher) retryAfter(n int) time.Duration {
+	wait := d.backoff
+	for i := 1; i < n && wait < d.maxBackoff; i++ {
+		wait *= 2
+	}
+	return min(wait, d.maxBackoff)
+}
+
+// post sends the delivery and returns the response status and the start of
+// its body
+func (d *Dispatcher) post(ctx context.Context, item Due, at time.Time) (int, string, error) {
+	ctx, cancel := context.WithTimeout(ctx, d.timeout)
+	defer cancel()
+
+	body := []byte(item.Delivery.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, item.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "donald-webhooks/1")
+	req.Header.Set(EventHeader, item.Delivery.Event)
+	req.Header.Set(IDHeader, item.Delivery.EventID)
+	req.Header.Set(DeliveryHeader, strconv.FormatUint(item.Delivery.ID, 10))
+	req.Header.Set(SignatureHeader, Sign(item.Secret, at, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+	snippet, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponse))
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10)) // lets the connection be reused
+	return resp.StatusCode, string(snippet), nil
+}
+
+// Sign returns the SignatureHeader value for body sent at t:
+// "t=<unix seconds>,v1=<hex HMAC-SHA256 of "<unix seconds>.<body>">"
+func Sign(secret string, t time.Time, body []byte) string {
+	ts := strconv.FormatInt(t.Unix(), 10)
+	return "t=" + ts + ",v1=" + signature(secret, ts, body)
+}
+
+func signature(secret, ts string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// ErrBadSignature reports a SignatureHeader that does not match the body
+var ErrBadSignature = errors.New("webhook signature mismatch")
+
+// Verify checks a SignatureHeader value as a subscriber would: the
+// signature must match and be at most tolerance old at now
+func Verify(secret, header string, body []byte, tolerance time.Duration, now time.Time) error {
+	var ts, sig string
+	for _, part := range strings.Split(header, ",") {
+		k, v, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch k {
+		case "t":
+			ts = v
+		case "v1":
+			sig = v
+		}
+	}
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil || sig == "" {
+		return ErrBadSignature
+	}
+	if !hmac.Equal([]byte(sig), []byte(signature(secret, ts, body))) {
+		return ErrBadSignature
+	}
+	if age := now.Sub(time.Unix(sec, 0)); age > tolerance || age < -tolerance {
+		return fmt.Errorf("%w: timestamp outside tolerance", ErrBadSignature)
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package drop_test
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"sync"
@@ -96,7 +97,7 @@ func TestPurchaseDrop_Concurrency(t *testing.T) {
 						District: "Test District",
 						Ward:     "Test Ward",
 					}
-					_, err := svc.PurchaseDrop(1, req)
+					_, err := svc.PurchaseDrop(context.Background(), 1, req)
 					if err == nil {
 						mu.Lock()
 						success++
@@ -131,21 +132,21 @@ func TestPurchaseDrop_Concurrency(t *testing.T) {
 
 type MockPaymentGateway struct{}
 
-func (m *MockPaymentGateway) CreateCheckout(req integrations.PayOSCheckoutRequest) (*integrations.PayOSCheckoutResponse, error) {
+func (m *MockPaymentGateway) CreateCheckout(ctx context.Context, req integrations.PayOSCheckoutRequest) (*integrations.PayOSCheckoutResponse, error) {
 	resp := &integrations.PayOSCheckoutResponse{}
 	resp.Data.CheckoutURL = "http://mock-checkout-url"
 	return resp, nil
 }
 
-func (m *MockPaymentGateway) VerifyPayment(orderCode int64) (*integrations.PayOSVerifyResponse, error) {
+func (m *MockPaymentGateway) VerifyPayment(ctx context.Context, orderCode int64) (*integrations.PayOSVerifyResponse, error) {
 	return nil, nil
 }
 
-func (m *MockPaymentGateway) RefundPayment(orderCode int64, reason string) error {
+func (m *MockPaymentGateway) RefundPayment(ctx context.Context, orderCode int64, reason string) error {
 	return nil
 }
 
-func (m *MockPaymentGateway) CancelPayment(orderCode int64) error {
+func (m *MockPaymentGateway) CancelPayment(ctx context.Context, orderCode int64) error {
 	return nil
 }
 
@@ -155,20 +156,24 @@ func (m *MockPaymentGateway) GenerateSignature(data string) string {
 
 type MockEmailSender struct{}
 
-func (m *MockEmailSender) SendOrderConfirmation(email, orderNumber string, amount float64) error {
+func (m *MockEmailSender) SendOrderConfirmation(ctx context.Context, email, orderNumber string, amount float64) error {
 	return nil
 }
 
-func (m *MockEmailSender) SendSymbioteReceipt(email, phone, status, elapsed string) error {
+func (m *MockEmailSender) SendSymbioteReceipt(ctx context.Context, email, phone, status, elapsed string) error {
 	return nil
 }
 
-func (m *MockEmailSender) SendOrderDetails(email string, order interface{}) error {
+func (m *MockEmailSender) SendOrderDetails(ctx context.Context, email string, order interface{}, link string) error {
+	return nil
+}
+
+func (m *MockEmailSender) SendTransferCode(ctx context.Context, email, code string, ttl time.Duration) error {
 	return nil
 }
 
 type MockSheetSubmitter struct{}
 
-func (m *MockSheetSubmitter) SubmitOrder(name, phone, email, address, notes string, amount float64, timestamp interface{}) error {
+func (m *MockSheetSubmitter) QueueOrder(ctx context.Context, orderID uint64, paidAt time.Time) error {
 	return nil
 }
@@ -0,0 +1,325 @@
+package alerts_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"ecommerce-backend/internal/alerts"
+	"ecommerce-backend/internal/emails"
+	"ecommerce-backend/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// clock is a settable Now
+type clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newClock() *clock { return &clock{now: time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)} }
+
+func (c *clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	c.mu.Unlock()
+}
+
+// delivery is one Send call
+type delivery struct {
+	alerts []alerts.Alert
+	digest bool
+}
+
+// recordingSink passes deliveries to a channel and fails while err is set
+type recordingSink struct {
+	sent chan delivery
+	err  error
+}
+
+func newRecordingSink() *recordingSink { return &recordingSink{sent: make(chan delivery, 16)} }
+
+func (s *recordingSink) Name() string { return "recording" }
+
+func (s *recordingSink) Send(ctx context.Context, batch []alerts.Alert, digest bool) error {
+	s.sent <- delivery{alerts: batch, digest: digest}
+	return s.err
+}
+
+func (s *recordingSink) next(t *testing.T) delivery {
+	t.Helper()
+	select {
+	case d := <-s.sent:
+		return d
+	case <-time.After(2 * time.Second):
+		t.Fatal("no alert delivered")
+		return delivery{}
+	}
+}
+
+func (s *recordingSink) none(t *testing.T) {
+	t.Helper()
+	select {
+	case d := <-s.sent:
+		t.Fatalf("unexpected delivery of %d alerts", len(d.alerts))
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func signatureFailure() alerts.Alert {
+	return alerts.Alert{Kind: alerts.WebhookSignature, Key: "payos", Title: "payos webhook signature failures", Detail: "invalid signature"}
+}
+
+func TestAlerter_ThresholdAndThrottle(t *testing.T) {
+	clk := newClock()
+	a := alerts.New(alerts.Options{
+		Sinks: []alerts.Sink{newRecordingSink()},
+		Rules: map[alerts.Kind]alerts.Rule{
+			alerts.WebhookSignature: {Threshold: 3, Window: time.Minute, Throttle: 10 * time.Minute},
+		},
+		Now: clk.Now,
+	})
+	ctx := context.Background()
+
+	a.Alert(ctx, signatureFailure())
+	a.Alert(ctx, signatureFailure())
+	assert.Equal(t, 0, a.Status().Pending, "below the threshold")
+
+	// Failures older than the window do not count
+	clk.Advance(2 * time.Minute)
+	a.Alert(ctx, signatureFailure())
+	a.Alert(ctx, signatureFailure())
+	assert.Equal(t, 0, a.Status().Pending)
+	a.Alert(ctx, signatureFailure())
+	assert.Equal(t, 1, a.Status().Pending)
+
+	// Another provider is another subject
+	for i := 0; i < 3; i++ {
+		a.Alert(ctx, alerts.Alert{Kind: alerts.WebhookSignature, Key: "brevo", Title: "brevo webhook signature failures"})
+	}
+	assert.Equal(t, 2, a.Status().Pending)
+
+	// Throttled for the rest of the 10 minutes
+	for i := 0; i < 6; i++ {
+		a.Alert(ctx, signatureFailure())
+	}
+	assert.Equal(t, 2, a.Status().Pending)
+
+	clk.Advance(10 * time.Minute)
+	for i := 0; i < 3; i++ {
+		a.Alert(ctx, signatureFailure())
+	}
+	assert.Equal(t, 3, a.Status().Pending)
+}
+
+func TestAlerter_KindsAndNil(t *testing.T) {
+	a := alerts.New(alerts.Options{Sinks: []alerts.Sink{newRecordingSink()}, Kinds: []alerts.Kind{alerts.OrderPaid}})
+	a.Alert(context.Background(), alerts.Alert{Kind: alerts.RefundFailed, Title: "refund failed"})
+	a.Alert(context.Background(), alerts.Alert{Kind: alerts.OrderPaid, Title: "order paid"})
+	st := a.Status()
+	assert.Equal(t, []alerts.Kind{alerts.OrderPaid}, st.Kinds)
+	assert.Equal(t, []string{"recording"}, st.Sinks)
+	assert.Equal(t, 1, st.Pending)
+
+	var none *alerts.Alerter
+	assert.NotPanics(t, func() { none.Alert(context.Background(), alerts.Alert{Kind: alerts.OrderPaid}) })
+}
+
+func TestParseKinds(t *testing.T) {
+	kinds, err := alerts.ParseKinds([]string{"order_paid", " drop_sold_out"})
+	require.NoError(t, err)
+	assert.Equal(t, []alerts.Kind{alerts.OrderPaid, alerts.DropSoldOut}, kinds)
+
+	_, err = alerts.ParseKinds([]string{"order_shipped"})
+	assert.Error(t, err)
+}
+
+func TestAlerter_RunSendsSingleAlerts(t *testing.T) {
+	sink := newRecordingSink()
+	a := alerts.New(alerts.Options{Sinks: []alerts.Sink{sink}, DigestInterval: time.Hour})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() { defer close(done); a.Run(ctx) }()
+
+	a.Alert(ctx, alerts.Alert{Kind: alerts.RefundFailed, Title: "Refund failed for order #ABC", Detail: "payos: 500"})
+	d := sink.next(t)
+	assert.False(t, d.digest)
+	require.Len(t, d.alerts, 1)
+	assert.Equal(t, "Refund failed for order #ABC", d.alerts[0].Title)
+	assert.Equal(t, 1, d.alerts[0].Count)
+	assert.False(t, d.alerts[0].At.IsZero())
+
+	cancel()
+	<-done
+	sink.none(t)
+}
+
+func TestAlerter_DigestMode(t *testing.T) {
+	sink := newRecordingSink()
+	clk := newClock()
+	a := alerts.New(alerts.Options{Sinks: []alerts.Sink{sink}, DigestAfter: 3, DigestInterval: time.Hour, Now: clk.Now})
+	ctx := context.Background()
+
+	// A burst of paid orders switches to digests
+	for i := 0; i < 3; i++ {
+		a.Alert(ctx, alerts.Alert{Kind: alerts.OrderPaid, Title: fmt.Sprintf("New paid order #%d", i)})
+	}
+	st := a.Status()
+	assert.True(t, st.Digest)
+	require.NotNil(t, st.DigestUntil)
+	assert.Equal(t, clk.Now().Add(time.Hour), *st.DigestUntil)
+
+	runCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() { defer close(done); a.Run(runCtx) }()
+
+	// Held while in digest mode
+	a.Alert(ctx, alerts.Alert{Kind: alerts.OrderPaid, Title: "New paid order #3"})
+	sink.none(t)
+	assert.Equal(t, 4, a.Status().Pending)
+
+	// Stopping sends the held alerts as one digest
+	cancel()
+	<-done
+	d := sink.next(t)
+	assert.True(t, d.digest)
+	assert.Len(t, d.alerts, 4)
+}
+
+func TestAlerter_SetDigest(t *testing.T) {
+	sink := newRecordingSink()
+	a := alerts.New(alerts.Options{Sinks: []alerts.Sink{sink}, DigestInterval: time.Hour})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go a.Run(ctx)
+
+	a.SetDigest(2 * time.Hour)
+	assert.True(t, a.Status().Digest)
+	a.Alert(ctx, alerts.Alert{Kind: alerts.OrderPaid, Title: "New paid order"})
+	sink.none(t)
+
+	// Leaving digest mode sends what was held
+	a.SetDigest(0)
+	assert.False(t, a.Status().Digest)
+	d := sink.next(t)
+	require.Len(t, d.alerts, 1)
+	assert.Equal(t, "New paid order", d.alerts[0].Title)
+}
+
+func TestAlerter_DeliversToEverySink(t *testing.T) {
+	failing, working := newRecordingSink(), newRecordingSink()
+	failing.err = errors.New("down")
+	a := alerts.New(alerts.Options{Sinks: []alerts.Sink{failing, working}})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go a.Run(ctx)
+
+	a.Alert(ctx, alerts.Alert{Kind: alerts.DropSoldOut, Key: "1", Title: "Drop sold out"})
+	failing.next(t)
+	working.next(t)
+}
+
+func TestChatText(t *testing.T) {
+	at := time.Date(2026, 3, 1, 10, 4, 5, 0, time.UTC)
+	single := alerts.Alert{At: at, Kind: alerts.WebhookSignature, Title: "payos webhook signature failures", Detail: "invalid signature", Count: 5}
+	assert.Equal(t, "[DW] payos webhook signature failures: invalid signature (5 times)", alerts.ChatText([]alerts.Alert{single}, false))
+
+	var many []alerts.Alert
+	for i := 0; i < 25; i++ {
+		many = append(many, alerts.Alert{At: at, Title: fmt.Sprintf("New paid order #%d", i)})
+	}
+	text := alerts.ChatText(many, true)
+	lines := strings.Split(text, "\n")
+	assert.Equal(t, "[DW] 25 alerts", lines[0])
+	assert.Equal(t, "10:04:05 New paid order #0", lines[1])
+	assert.Len(t, lines, 22)
+	assert.Equal(t, "… and 5 more", lines[21])
+}
+
+func TestWebhookSink_Formats(t *testing.T) {
+	al := alerts.Alert{At: time.Now(), Kind: alerts.DropSoldOut, Title: "Drop sold out", Detail: "Summer drop"}
+	tests := []struct {
+		format string
+		chatID string
+		want   map[string]string
+	}{
+		{alerts.FormatSlack, "", map[string]string{"text": "[DW] Drop sold out: Summer drop"}},
+		{alerts.FormatDiscord, "", map[string]string{"content": "[DW] Drop sold out: Summer drop"}},
+		{alerts.FormatTelegram, "-100123", map[string]string{"chat_id": "-100123", "text": "[DW] Drop sold out: Summer drop"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var got map[string]string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+				body, _ := io.ReadAll(r.Body)
+				assert.NoError(t, json.Unmarshal(body, &got))
+			}))
+			defer srv.Close()
+
+			sink := alerts.NewWebhookSink(alerts.WebhookOptions{URL: srv.URL, Format: tt.format, ChatID: tt.chatID})
+			require.NoError(t, sink.Send(context.Background(), []alerts.Alert{al}, false))
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestWebhookSink_ErrorStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "invalid_token", http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	sink := alerts.NewWebhookSink(alerts.WebhookOptions{URL: srv.URL, Format: alerts.FormatSlack})
+	err := sink.Send(context.Background(), []alerts.Alert{{Title: "x"}}, false)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "403")
+	assert.Contains(t, err.Error(), "invalid_token")
+}
+
+// fakeSender records the messages it is asked to send
+type fakeSender struct {
+	to  []string
+	msg emails.Message
+}
+
+func (s *fakeSender) Send(ctx context.Context, to []string, msg emails.Message) (string, string, error) {
+	s.to, s.msg = to, msg
+	return "fake", "msg-1", nil
+}
+
+func TestEmailSink(t *testing.T) {
+	sender := &fakeSender{}
+	sink := alerts.NewEmailSink(sender, []string{"ops@example.com"})
+	assert.Equal(t, "email", sink.Name())
+
+	order := &models.Order{ID: 42, TotalAmount: 350000, Status: models.OrderPaid}
+	require.NoError(t, sink.Send(context.Background(), []alerts.Alert{{Kind: alerts.OrderPaid, Title: "New paid order", Order: order}}, false))
+	assert.Equal(t, []string{"ops@example.com"}, sender.to)
+	assert.Equal(t, emails.AdminOrderCreated, sender.msg.Template)
+
+	at := time.Date(2026, 3, 1, 10, 4, 5, 0, time.UTC)
+	batch := []alerts.Alert{
+		{At: at, Kind: alerts.OrderPaid, Title: "New paid order", Order: order},
+		{At: at, Kind: alerts.RefundFailed, Title: "Refund failed", Detail: "payos: 500"},
+	}
+	require.NoError(t, sink.Send(context.Background(), batch, true))
+	assert.Equal(t, emails.AdminAlert, sender.msg.Template)
+	assert.Contains(t, sender.msg.Text, "Refund failed: payos: 500")
+}
@@ -0,0 +1,242 @@
+package backup_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"ecommerce-backend/internal/backup"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// openLiveDB opens a WAL database the way the server does and keeps the
+// connection open, so committed rows stay in the -wal file (no checkpoint).
+func openLiveDB(t *testing.T, path string) *sql.DB {
+	db, err := sql.Open("sqlite3", path+"?_journal_mode=WAL&_synchronous=NORMAL&_busy_timeout=5000")
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	_, err = db.Exec(`PRAGMA wal_autocheckpoint=0`)
+	require.NoError(t, err)
+	_, err = db.Exec(`CREATE TABLE orders (id INTEGER PRIMARY KEY, customer_phone TEXT)`)
+	require.NoError(t, err)
+	return db
+}
+
+func countOrders(t *testing.T, path string) int {
+	db, err := sql.Open("sqlite3", path)
+	require.NoError(t, err)
+	defer db.Close()
+	var n int
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM orders`).Scan(&n))
+	return n
+}
+
+func TestCreate_IncludesUncheckpointedWAL(t *testing.T) {
+	for _, compress := range []bool{true, false} {
+		t.Run(map[bool]string{true: "gzip", false: "raw"}[compress], func(t *testing.T) {
+			dir := t.TempDir()
+			dbPath := filepath.Join(dir, "database.db")
+			live := openLiveDB(t, dbPath)
+			for i := 0; i < 50; i++ {
+				_, err := live.Exec(`INSERT INTO orders (customer_phone) VALUES (?)`, "0909")
+				require.NoError(t, err)
+			}
+
+			wal, err := os.Stat(dbPath + "-wal")
+			require.NoError(t, err)
+			require.Greater(t, wal.Size(), int64(0), "rows should still be in the WAL")
+
+			out := filepath.Join(dir, "backups")
+			m, err := backup.Create(context.Background(), dbPath, out, backup.Options{Compress: compress})
+			require.NoError(t, err)
+
+			assert.Equal(t, "ok", m.Integrity)
+			assert.Equal(t, compress, m.Compressed)
+			assert.NotEmpty(t, m.SHA256)
+			assert.NotEmpty(t, m.DBSHA256)
+			assert.NoError(t, backup.Verify(out, *m))
+
+			target := filepath.Join(dir, "restored.db")
+			require.NoError(t, backup.Restore(context.Background(), out, *m, target, false))
+			assert.Equal(t, 50, countOrders(t, target))
+		})
+	}
+}
+
+func TestVerify_DetectsTampering(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "database.db")
+	openLiveDB(t, dbPath)
+
+	out := filepath.Join(dir, "backups")
+	m, err := backup.Create(context.Background(), dbPath, out, backup.Options{Compress: false})
+	require.NoError(t, err)
+
+	f, err := os.OpenFile(filepath.Join(out, m.File), os.O_WRONLY|os.O_APPEND, 0)
+	require.NoError(t, err)
+	_, err = f.Write([]byte("garbage"))
+	require.NoError(t, err)
+	f.Close()
+
+	assert.True(t, errors.Is(backup.Verify(out, *m), backup.ErrChecksum))
+
+	target := filepath.Join(dir, "restored.db")
+	err = backup.Restore(context.Background(), out, *m, target, false)
+	assert.True(t, errors.Is(err, backup.ErrChecksum))
+	_, statErr := os.Stat(target)
+	assert.True(t, os.IsNotExist(statErr), "a failed restore must not create the target")
+}
+
+func TestRestore_RequiresForceAndKeepsPreviousCopy(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "database.db")
+	live := openLiveDB(t, dbPath)
+	_, err := live.Exec(`INSERT INTO orders (customer_phone) VALUES ('1')`)
+	require.NoError(t, err)
+
+	out := filepath.Join(dir, "backups")
+	m, err := backup.Create(context.Background(), dbPath, out, backup.Options{Compress: true})
+	require.NoError(t, err)
+
+	target := filepath.Join(dir, "target.db")
+	require.NoError(t, os.WriteFile(target, []byte("old"), 0644))
+	require.NoError(t, os.WriteFile(target+"-wal", []byte("stale wal"), 0644))
+
+	err = backup.Restore(context.Background(), out, *m, target, false)
+	assert.True(t, errors.Is(err, backup.ErrTargetExists))
+
+	require.NoError(t, backup.Restore(context.Background(), out, *m, target, true))
+	assert.Equal(t, 1, countOrders(t, target))
+
+	aside, _ := filepath.Glob(target + ".pre-restore-*[0-9]")
+	require.Len(t, aside, 1)
+	assert.True(t, fileContains(t, aside[0], "old"))
+	assert.True(t, fileContains(t, aside[0]+"-wal", "stale wal"), "the WAL is kept with the previous copy")
+	_, statErr := os.Stat(target + "-wal")
+	assert.True(t, os.IsNotExist(statErr), "stale WAL must not survive a restore")
+}
+
+func fileContains(t *testing.T, path, s string) bool {
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return string(b) == s
+}
+
+func TestListAndSelectAt(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "database.db")
+	openLiveDB(t, dbPath)
+	out := filepath.Join(dir, "backups")
+
+	base := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		at := base.Add(time.Duration(i) * time.Hour)
+		_, err := backup.Create(context.Background(), dbPath, out, backup.Options{Now: func() time.Time { return at }})
+		require.NoError(t, err)
+	}
+
+	list, err := backup.List(out)
+	require.NoError(t, err)
+	require.Len(t, list, 3)
+	assert.True(t, list[0].CreatedAt.Before(list[2].CreatedAt))
+
+	m, err := backup.SelectAt(out, base.Add(90*time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, base.Add(time.Hour), m.CreatedAt)
+
+	_, err = backup.SelectAt(out, base.Add(-time.Minute))
+	assert.Error(t, err)
+
+	found, err := backup.Find(out, list[1].File)
+	require.NoError(t, err)
+	assert.Equal(t, list[1].ID, found.ID)
+}
+
+// =============================================================================
+// RETENTION
+// =============================================================================
+
+func TestList_LegacyCopies(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "database.db")
+	openLiveDB(t, dbPath)
+	out := filepath.Join(dir, "backups")
+	m, err := backup.Create(context.Background(), dbPath, out, backup.Options{Now: func() time.Time { return time.Now().Add(-time.Hour) }})
+	require.NoError(t, err)
+
+	// Raw copies written by the old backup command, without manifests
+	old := time.Now().AddDate(0, 0, -90)
+	for _, name := range []string{"database_" + old.Format(backup.TimeLayout) + ".db.gz", "database_" + old.Add(time.Hour).Format(backup.TimeLayout) + ".db"} {
+		require.NoError(t, os.WriteFile(filepath.Join(out, name), []byte("raw"), 0644))
+	}
+
+	list, err := backup.List(out)
+	require.NoError(t, err)
+	require.Len(t, list, 3)
+	assert.True(t, list[0].Legacy() && list[0].Compressed)
+	assert.WithinDuration(t, old, list[0].CreatedAt, time.Second)
+	assert.Equal(t, m.ID, list[2].ID)
+	assert.ErrorIs(t, backup.Verify(out, list[0]), backup.ErrLegacy)
+
+	// Point-in-time restore never picks a legacy copy
+	_, err = backup.SelectAt(out, old.Add(2*time.Hour))
+	assert.Error(t, err)
+
+	removed, err := backup.Prune(out, backup.RetentionPolicy{KeepDaily: 30, MaxAge: 30 * 24 * time.Hour}, time.Now())
+	require.NoError(t, err)
+	assert.Len(t, removed, 2)
+	list, err = backup.List(out)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, m.ID, list[0].ID)
+}
+
+func TestRetentionPolicy_Apply_TableDriven(t *testing.T) {
+	now := time.Date(2026, 3, 31, 23, 0, 0, 0, time.Local)
+
+	// Two backups per day for 90 days
+	var manifests []backup.Manifest
+	for d := 0; d < 90; d++ {
+		for _, h := range []int{15, 3} {
+			at := time.Date(2026, 3, 31, h, 0, 0, 0, time.Local).AddDate(0, 0, -d)
+			manifests = append(manifests, backup.Manifest{ID: at.Format(backup.TimeLayout), CreatedAt: at})
+		}
+	}
+
+	tests := []struct {
+		name     string
+		policy   backup.RetentionPolicy
+		wantKeep int
+	}{
+		{"keep last only", backup.RetentionPolicy{KeepLast: 5}, 5},
+		{"daily keeps one per day", backup.RetentionPolicy{KeepDaily: 10}, 10},
+		{"last overlaps daily", backup.RetentionPolicy{KeepLast: 2, KeepDaily: 3}, 4},
+		{"monthly", backup.RetentionPolicy{KeepMonthly: 2}, 2},
+		{"max age overrides keep", backup.RetentionPolicy{KeepDaily: 60, MaxAge: 7 * 24 * time.Hour}, 7},
+		{"newest always kept", backup.RetentionPolicy{KeepDaily: 30, MaxAge: time.Nanosecond}, 1},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			keep, remove := tc.policy.Apply(manifests, now)
+			assert.Len(t, keep, tc.wantKeep)
+			assert.Len(t, remove, len(manifests)-tc.wantKeep)
+			assert.Equal(t, manifests[0].ID, keep[0].ID, "newest backup must be kept")
+		})
+	}
+}
+
+func TestRetentionPolicy_Validate(t *testing.T) {
+	assert.NoError(t, backup.DefaultRetention.Validate())
+	assert.Error(t, backup.RetentionPolicy{}.Validate())
+	assert.Error(t, backup.RetentionPolicy{KeepLast: -1, KeepDaily: 1}.Validate())
+}
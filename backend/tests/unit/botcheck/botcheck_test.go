@@ -0,0 +1,179 @@
+package botcheck_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"ecommerce-backend/internal/botcheck"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testKey = []byte("0123456789abcdef0123456789abcdef")
+
+func TestProofOfWork_TableDriven(t *testing.T) {
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	pow := botcheck.NewProofOfWork(botcheck.PoWOptions{
+		Key:        testKey,
+		Difficulty: 8,
+		TTL:        time.Minute,
+		Now:        func() time.Time { return now },
+	})
+
+	puzzle, err := pow.Puzzle()
+	require.NoError(t, err)
+	assert.Equal(t, "pow", puzzle.Kind)
+	assert.Equal(t, 8, puzzle.Difficulty)
+	require.NotNil(t, puzzle.ExpiresAt)
+	assert.Equal(t, now.Add(time.Minute), *puzzle.ExpiresAt)
+
+	solved := botcheck.Solve(puzzle.Challenge, puzzle.Difficulty)
+	other, err := pow.Puzzle()
+	require.NoError(t, err)
+	forged := botcheck.NewProofOfWork(botcheck.PoWOptions{Key: []byte("another key"), Difficulty: 8, TTL: time.Minute})
+	forgedPuzzle, err := forged.Puzzle()
+	require.NoError(t, err)
+
+	// A nonce that does not solve the other challenge at this difficulty
+	n := 0
+	for botcheck.Solves(other.Challenge, strconv.Itoa(n), 8) {
+		n++
+	}
+	unsolved := other.Challenge + ":" + strconv.Itoa(n)
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr error
+	}{
+		{name: "solved", token: solved},
+		{name: "replayed", token: solved, wantErr: botcheck.ErrInvalid},
+		{name: "wrong nonce", token: unsolved, wantErr: botcheck.ErrInvalid},
+		{name: "signed with another key", token: botcheck.Solve(forgedPuzzle.Challenge, 8), wantErr: botcheck.ErrInvalid},
+		{name: "no nonce", token: other.Challenge, wantErr: botcheck.ErrInvalid},
+		{name: "garbage", token: "not-base64!:1", wantErr: botcheck.ErrInvalid},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := pow.Verify(context.Background(), tc.token, "")
+			if tc.wantErr == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, tc.wantErr)
+		})
+	}
+
+	t.Run("expired", func(t *testing.T) {
+		token := botcheck.Solve(other.Challenge, 8)
+		now = now.Add(time.Minute)
+		assert.ErrorIs(t, pow.Verify(context.Background(), token, ""), botcheck.ErrInvalid)
+	})
+}
+
+func TestSolves(t *testing.T) {
+	token := botcheck.Solve("challenge", 12)
+	challenge, nonce, ok := strings.Cut(token, ":")
+	require.True(t, ok)
+	assert.Equal(t, "challenge", challenge)
+	assert.True(t, botcheck.Solves(challenge, nonce, 12))
+	assert.True(t, botcheck.Solves(challenge, nonce, 0))
+}
+
+func TestCaptcha_TableDriven(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		response string
+		wantErr  error
+		wantFail bool // an error other than ErrInvalid
+	}{
+		{name: "accepted", status: 200, response: `{"success":true}`},
+		{name: "rejected", status: 200, response: `{"success":false,"error-codes":["timeout-or-duplicate"]}`, wantErr: botcheck.ErrInvalid},
+		{name: "provider error", status: 503, response: ``, wantFail: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var form map[string]string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				require.NoError(t, r.ParseForm())
+				form = map[string]string{"secret": r.PostForm.Get("secret"), "response": r.PostForm.Get("response"), "remoteip": r.PostForm.Get("remoteip")}
+				w.WriteHeader(tc.status)
+				w.Write([]byte(tc.response))
+			}))
+			defer srv.Close()
+
+			captcha := botcheck.NewCaptcha(srv.URL, "site-key", "secret", srv.Client())
+			puzzle, err := captcha.Puzzle()
+			require.NoError(t, err)
+			assert.Equal(t, botcheck.Puzzle{Kind: "captcha", SiteKey: "site-key"}, puzzle)
+
+			err = captcha.Verify(context.Background(), "widget-token", "203.0.113.7")
+			assert.Equal(t, map[string]string{"secret": "secret", "response": "widget-token", "remoteip": "203.0.113.7"}, form)
+			switch {
+			case tc.wantErr != nil:
+				assert.ErrorIs(t, err, tc.wantErr)
+			case tc.wantFail:
+				require.Error(t, err)
+				assert.False(t, errors.Is(err, botcheck.ErrInvalid))
+			default:
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+// stubChecker verifies tokens with a fixed result
+type stubChecker struct{ err error }
+
+func (s stubChecker) Kind() string                                 { return "stub" }
+func (s stubChecker) Puzzle() (botcheck.Puzzle, error)             { return botcheck.Puzzle{Kind: "stub"}, nil }
+func (s stubChecker) Verify(context.Context, string, string) error { return s.err }
+
+func TestMiddleware_TableDriven(t *testing.T) {
+	tests := []struct {
+		name       string
+		checker    botcheck.Checker
+		token      string
+		wantStatus int
+		wantReason string
+	}{
+		{name: "disabled", checker: nil, wantStatus: 200},
+		{name: "passed", checker: stubChecker{}, token: "t", wantStatus: 200},
+		{name: "missing", checker: stubChecker{}, wantStatus: 403, wantReason: "bot_check_required"},
+		{name: "invalid", checker: stubChecker{err: botcheck.ErrInvalid}, token: "t", wantStatus: 403, wantReason: "bot_check_failed"},
+		{name: "provider down allows", checker: stubChecker{err: errors.New("timeout")}, token: "t", wantStatus: 200},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			app := fiber.New()
+			app.Post("/", botcheck.Middleware(tc.checker, nil), func(c fiber.Ctx) error { return c.SendString("ok") })
+
+			req := httptest.NewRequest("POST", "/", nil)
+			if tc.token != "" {
+				req.Header.Set(botcheck.Header, tc.token)
+			}
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			assert.Equal(t, tc.wantStatus, resp.StatusCode)
+			if tc.wantReason != "" {
+				body, err := io.ReadAll(resp.Body)
+				require.NoError(t, err)
+				assert.Contains(t, string(body), `"reason":"`+tc.wantReason+`"`)
+			}
+		})
+	}
+}
@@ -0,0 +1,496 @@
+package config_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"ecommerce-backend/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func envMap(m map[string]string) config.Lookup {
+	return func(key string) (string, error) { return m[key], nil }
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestDefault_Validates(t *testing.T) {
+	assert.NoError(t, config.Default().Validate())
+}
+
+func TestLoadWith_TableDriven(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string // file name; content below
+		content string
+		env     map[string]string
+		secret  map[string]string
+		wantErr string
+		check   func(t *testing.T, cfg *config.Config)
+	}{
+		{
+			name: "env overrides defaults",
+			env: map[string]string{
+				"PORT":                       "8080",
+				"CORS_ORIGINS":               "https://a.example, https://b.example",
+				"MAX_READ_CONNS":             "20",
+				"PAYMENT_VISIBILITY_TIMEOUT": "2m",
+				"USE_S3":                     "true",
+			},
+			check: func(t *testing.T, cfg *config.Config) {
+				assert.Equal(t, "8080", cfg.Server.Port)
+				assert.Equal(t, []string{"https://a.example", "https://b.example"}, cfg.Server.CORSOrigins)
+				assert.Equal(t, 20, cfg.Database.MaxReadConns)
+				assert.Equal(t, 2*time.Minute, cfg.Queue.Visibility)
+				assert.True(t, cfg.AWS.UseS3)
+			},
+		},
+		{
+			name: "DB_PATH is a fallback for DATABASE_URL",
+			env:  map[string]string{"DB_PATH": "/data/b.db"},
+			check: func(t *testing.T, cfg *config.Config) {
+				assert.Equal(t, "/data/b.db", cfg.Database.Path)
+			},
+		},
+		{
+			name: "DATABASE_URL wins over DB_PATH",
+			env:  map[string]string{"DATABASE_URL": "/data/a.db", "DB_PATH": "/data/b.db"},
+			check: func(t *testing.T, cfg *config.Config) {
+				assert.Equal(t, "/data/a.db", cfg.Database.Path)
+			},
+		},
+		{
+			name: "yaml file",
+			file: "config.yaml",
+			content: `
+server:
+  port: "9000"
+  cors_origins: ["https://shop.example"]
+database:
+  max_read_conns: 8
+replication:
+  sync_interval: 5s
+`,
+			check: func(t *testing.T, cfg *config.Config) {
+				assert.Equal(t, "9000", cfg.Server.Port)
+				assert.Equal(t, []string{"https://shop.example"}, cfg.Server.CORSOrigins)
+				assert.Equal(t, 8, cfg.Database.MaxReadConns)
+				assert.Equal(t, 5*time.Second, cfg.Replication.SyncInterval)
+			},
+		},
+		{
+			name: "toml file",
+			file: "config.toml",
+			content: `
+environment = "staging"
+
+[queue]
+workers = 2
+max_attempts = 3
+`,
+			check: func(t *testing.T, cfg *config.Config) {
+				assert.Equal(t, "staging", cfg.Environment)
+				assert.Equal(t, 2, cfg.Queue.Workers)
+				assert.Equal(t, 3, cfg.Queue.MaxAttempts)
+			},
+		},
+		{
+			name:    "precedence: file < env < secret",
+			file:    "config.yaml",
+			content: "server:\n  port: \"9000\"\npayos:\n  api_key: from-file\n  client_id: from-file\n",
+			env:     map[string]string{"PORT": "9100", "PAYOS_API_KEY": "from-env", "PAYOS_CLIENT_ID": "from-env"},
+			secret:  map[string]string{"PAYOS_API_KEY": "from-secret", "PORT": "9200"},
+			check: func(t *testing.T, cfg *config.Config) {
+				assert.Equal(t, "9100", cfg.Server.Port, "secret lookups only apply to secret fields")
+				assert.Equal(t, "from-secret", cfg.PayOS.APIKey)
+				assert.Equal(t, "from-env", cfg.PayOS.ClientID)
+			},
+		},
+		{
+			name:    "unknown file key",
+			file:    "config.yaml",
+			content: "server:\n  prot: \"9000\"\n",
+			wantErr: "server.prot: unknown key",
+		},
+		{
+			name:    "unsupported file extension",
+			file:    "config.json",
+			content: "{}",
+			wantErr: "unsupported extension",
+		},
+		{
+			name:    "malformed env value",
+			env:     map[string]string{"PAYMENT_VISIBILITY_TIMEOUT": "soon"},
+			wantErr: "queue.visibility_timeout (PAYMENT_VISIBILITY_TIMEOUT): expected a duration",
+		},
+		{
+			name:    "sqlite allows a single writer",
+			env:     map[string]string{"MAX_WRITE_CONNS": "4"},
+			wantErr: "database.max_write_conns (MAX_WRITE_CONNS): must be 1",
+		},
+		{
+			name: "otlp tracing",
+			env:  map[string]string{"OTEL_TRACES_EXPORTER": "otlp", "OTEL_EXPORTER_OTLP_ENDPOINT": "http://collector:4318", "OTEL_TRACES_SAMPLER_ARG": "0.25"},
+			check: func(t *testing.T, cfg *config.Config) {
+				assert.Equal(t, "otlp", cfg.Tracing.Exporter)
+				assert.Equal(t, "http://collector:4318", cfg.Tracing.Endpoint)
+				assert.Equal(t, 0.25, cfg.Tracing.SampleRatio)
+			},
+		},
+		{
+			name:    "unknown trace exporter",
+			env:     map[string]string{"OTEL_TRACES_EXPORTER": "jaeger"},
+			wantErr: "tracing.exporter (OTEL_TRACES_EXPORTER): must be none, stdout or otlp",
+		},
+		{
+			name:    "sample ratio out of range",
+			env:     map[string]string{"OTEL_TRACES_SAMPLER_ARG": "2"},
+			wantErr: "tracing.sample_ratio (OTEL_TRACES_SAMPLER_ARG): must be between 0 and 1",
+		},
+		{
+			name: "symbicode auto-activation schedule",
+			env:  map[string]string{"SYMBICODE_AUTO_ACTIVATE_AFTER": "48h", "SYMBICODE_AUTO_ACTIVATE_SCHEDULE": "*/30 * * * *", "SCHEDULER_ENABLED": "false"},
+			check: func(t *testing.T, cfg *config.Config) {
+				assert.Equal(t, 48*time.Hour, cfg.Symbicode.AutoActivateAfter)
+				assert.Equal(t, "*/30 * * * *", cfg.Symbicode.AutoActivateSchedule)
+				assert.False(t, cfg.Scheduler.Enabled)
+			},
+		},
+		{
+			name:    "invalid auto-activation schedule",
+			env:     map[string]string{"SYMBICODE_AUTO_ACTIVATE_SCHEDULE": "hourly"},
+			wantErr: "symbicode.auto_activate_schedule (SYMBICODE_AUTO_ACTIVATE_SCHEDULE)",
+		},
+		{
+			name: "trusted proxies",
+			env:  map[string]string{"TRUSTED_PROXIES": "10.0.0.0/8, 127.0.0.1", "SYMBICODE_SUSPICIOUS_IPS": "8"},
+			check: func(t *testing.T, cfg *config.Config) {
+				assert.Equal(t, []string{"10.0.0.0/8", "127.0.0.1"}, cfg.Server.TrustedProxies)
+				assert.Equal(t, "X-Forwarded-For", cfg.Server.ProxyHeader)
+				assert.Equal(t, 8, cfg.Symbicode.SuspiciousIPs)
+			},
+		},
+		{
+			name:    "invalid trusted proxy",
+			env:     map[string]string{"TRUSTED_PROXIES": "loadbalancer"},
+			wantErr: "server.trusted_proxies (TRUSTED_PROXIES): entries must be IPs or CIDRs",
+		},
+		{
+			name:    "negative suspicion threshold",
+			env:     map[string]string{"SYMBICODE_SUSPICIOUS_LOCATIONS": "-1"},
+			wantErr: "symbicode.suspicious_locations (SYMBICODE_SUSPICIOUS_LOCATIONS): must not be negative",
+		},
+		{
+			name:    "transfer offers must last at least an hour",
+			env:     map[string]string{"SYMBICODE_TRANSFER_TTL": "10m"},
+			wantErr: "symbicode.transfer_ttl (SYMBICODE_TRANSFER_TTL): must be at least 1h",
+		},
+		{
+			name:    "short previous signing key",
+			env:     map[string]string{"SYMBICODE_PREVIOUS_SIGNING_KEYS": "0123456789abcdef0123456789abcdef,short"},
+			wantErr: "symbicode.previous_signing_keys (SYMBICODE_PREVIOUS_SIGNING_KEYS): must be at least 32 characters each",
+		},
+		{
+			name:    "order links must not outlive a week",
+			env:     map[string]string{"ORDER_LOOKUP_LINK_TTL": "720h"},
+			wantErr: "orders.lookup_link_ttl (ORDER_LOOKUP_LINK_TTL): must be between 1m and 168h",
+		},
+		{
+			name:    "refunds need an attempt",
+			env:     map[string]string{"ORDER_REFUND_ATTEMPTS": "0"},
+			wantErr: "orders.refund_attempts (ORDER_REFUND_ATTEMPTS): must be at least 1",
+		},
+		{
+			name: "rate limit rules and proof of work",
+			env:  map[string]string{"RATE_LIMIT_RULES": "purchase:ip=2/30s,verify:fingerprint=10/1m", "BOT_CHECK": "pow", "BOT_CHECK_POW_DIFFICULTY": "12"},
+			check: func(t *testing.T, cfg *config.Config) {
+				assert.True(t, cfg.RateLimit.Enabled)
+				assert.Equal(t, []string{"purchase:ip=2/30s", "verify:fingerprint=10/1m"}, cfg.RateLimit.Rules)
+				assert.Equal(t, "pow", cfg.BotCheck.Kind)
+				assert.Equal(t, 12, cfg.BotCheck.PoWDifficulty)
+			},
+		},
+		{
+			name:    "rate limit rule with an unknown key",
+			env:     map[string]string{"RATE_LIMIT_RULES": "purchase:email=3/10m"},
+			wantErr: "rate_limit.rules (RATE_LIMIT_RULES): key must be ip, phone, fingerprint or order",
+		},
+		{
+			name:    "rate limit rule without a period",
+			env:     map[string]string{"RATE_LIMIT_RULES": "purchase:ip=3"},
+			wantErr: "rate_limit.rules (RATE_LIMIT_RULES): entries must look like route:key=events/period",
+		},
+		{
+			name:    "rate limit store must be redis",
+			env:     map[string]string{"RATE_LIMIT_REDIS_URL": "http://cache:6379"},
+			wantErr: "rate_limit.redis_url (RATE_LIMIT_REDIS_URL): must be a redis:// or rediss:// URL",
+		},
+		{
+			name:    "unknown bot check",
+			env:     map[string]string{"BOT_CHECK": "recaptcha"},
+			wantErr: "bot_check.kind (BOT_CHECK): must be none, pow or captcha",
+		},
+		{
+			name:    "captcha requires its secret",
+			env:     map[string]string{"BOT_CHECK": "captcha", "CAPTCHA_SITE_KEY": "site"},
+			wantErr: "bot_check.captcha_secret (CAPTCHA_SECRET): site key and secret are required for captcha",
+		},
+		{
+			name:    "production requires PayOS credentials",
+			env:     map[string]string{"ENV": "production"},
+			wantErr: "payos (PAYOS_CLIENT_ID)",
+		},
+		{
+			name: "production with PayOS credentials",
+			env: map[string]string{
+				"ENV":                   "production",
+				"PAYOS_CLIENT_ID":       "id",
+				"PAYOS_API_KEY":         "key",
+				"PAYOS_CHECKSUM_KEY":    "checksum",
+				"SYMBICODE_SIGNING_KEY": "0123456789abcdef0123456789abcdef",
+				"ORDER_NUMBER_KEY":      "fedcba9876543210fedcba9876543210",
+			},
+			check: func(t *testing.T, cfg *config.Config) {
+				assert.True(t, cfg.IsProduction())
+			},
+		},
+		{
+			name:    "production requires a symbicode signing key",
+			env:     map[string]string{"ENV": "production", "PAYOS_CLIENT_ID": "id", "PAYOS_API_KEY": "key", "PAYOS_CHECKSUM_KEY": "checksum"},
+			wantErr: "symbicode.signing_key (SYMBICODE_SIGNING_KEY): is required in production",
+		},
+		{
+			name: "production requires an order number key",
+			env: map[string]string{
+				"ENV":                   "production",
+				"PAYOS_CLIENT_ID":       "id",
+				"PAYOS_API_KEY":         "key",
+				"PAYOS_CHECKSUM_KEY":    "checksum",
+				"SYMBICODE_SIGNING_KEY": "0123456789abcdef0123456789abcdef",
+			},
+			wantErr: "orders.number_key (ORDER_NUMBER_KEY): is required in production",
+		},
+		{
+			name: "production rejects the in-memory payment queue",
+			env: map[string]string{
+				"ENV":                   "production",
+				"PAYOS_CLIENT_ID":       "id",
+				"PAYOS_API_KEY":         "key",
+				"PAYOS_CHECKSUM_KEY":    "checksum",
+				"SYMBICODE_SIGNING_KEY": "0123456789abcdef0123456789abcdef",
+				"ORDER_NUMBER_KEY":      "fedcba9876543210fedcba9876543210",
+				"PAYMENT_QUEUE_MEMORY":  "true",
+			},
+			wantErr: "queue.memory (PAYMENT_QUEUE_MEMORY): must be false in production",
+		},
+		{
+			name:    "short order number key",
+			env:     map[string]string{"ORDER_NUMBER_KEY": "short"},
+			wantErr: "orders.number_key (ORDER_NUMBER_KEY): must be at least 32 characters",
+		},
+		{
+			name: "email failover",
+			env:  map[string]string{"EMAIL_PROVIDERS": "resend,brevo", "BREVO_DAILY_LIMIT": "0", "EMAIL_FAILOVER_COOLDOWN": "5m"},
+			check: func(t *testing.T, cfg *config.Config) {
+				assert.Equal(t, []string{"resend", "brevo"}, cfg.Email.Providers)
+				assert.Equal(t, 0, cfg.Email.BrevoDailyLimit)
+				assert.Equal(t, 100, cfg.Email.ResendDailyLimit)
+				assert.Equal(t, 5*time.Minute, cfg.Email.Cooldown)
+			},
+		},
+		{
+			name:    "unknown email provider",
+			env:     map[string]string{"EMAIL_PROVIDERS": "brevo,smtp"},
+			wantErr: `email.providers (EMAIL_PROVIDERS): unknown provider "smtp"`,
+		},
+		{
+			name:    "email provider listed twice",
+			env:     map[string]string{"EMAIL_PROVIDERS": "brevo,brevo"},
+			wantErr: "email.providers (EMAIL_PROVIDERS): lists brevo twice",
+		},
+		{
+			name:    "email failure threshold",
+			env:     map[string]string{"EMAIL_FAILURE_THRESHOLD": "0"},
+			wantErr: "email.failure_threshold (EMAIL_FAILURE_THRESHOLD): must be at least 1",
+		},
+		{
+			name: "notification channels",
+			env: map[string]string{
+				"NOTIFY_DROP_WON":        "email,zalo|sms",
+				"ZALO_TEMPLATE_DROP_WON": "231456",
+				"SMS_API_KEY":            "sms-key",
+				"SMS_BRAND_NAME":         "DONALD",
+			},
+			check: func(t *testing.T, cfg *config.Config) {
+				assert.Equal(t, []string{"email", "zalo|sms"}, cfg.Notify.DropWon)
+				assert.Equal(t, []string{"email"}, cfg.Notify.OrderConfirmation)
+				assert.Equal(t, "231456", cfg.Notify.ZaloDropWonTemplate)
+				assert.Equal(t, "sms-key", cfg.SMS.APIKey)
+				assert.Equal(t, "DONALD", cfg.SMS.BrandName)
+			},
+		},
+		{
+			name:    "unknown notification channel",
+			env:     map[string]string{"NOTIFY_DROP_LOST": "email|fax"},
+			wantErr: `notify.drop_lost (NOTIFY_DROP_LOST): unknown channel "fax"`,
+		},
+		{
+			name:    "zalo needs a template",
+			env:     map[string]string{"NOTIFY_ORDER_CONFIRMATION": "zalo|email"},
+			wantErr: "notify.order_confirmation (NOTIFY_ORDER_CONFIRMATION): lists zalo but no ZNS template",
+		},
+		{
+			name: "admin alerts",
+			env: map[string]string{
+				"ALERT_EVENTS":           "refund_failed,webhook_signature",
+				"ADMIN_ORDER_EMAILS":     "ops@example.com",
+				"ALERT_WEBHOOK_URL":      "https://api.telegram.org/bot123:abc/sendMessage",
+				"ALERT_WEBHOOK_FORMAT":   "telegram",
+				"ALERT_TELEGRAM_CHAT_ID": "-100123",
+				"ALERT_SIGNATURE_WINDOW": "10m",
+				"ALERT_DIGEST_AFTER":     "0",
+			},
+			check: func(t *testing.T, cfg *config.Config) {
+				assert.Equal(t, []string{"refund_failed", "webhook_signature"}, cfg.Alerts.Events)
+				assert.Equal(t, []string{"ops@example.com"}, cfg.Alerts.Emails)
+				assert.Equal(t, "telegram", cfg.Alerts.WebhookFormat)
+				assert.Equal(t, "-100123", cfg.Alerts.TelegramChatID)
+				assert.Equal(t, 10*time.Minute, cfg.Alerts.SignatureWindow)
+				assert.Equal(t, 5, cfg.Alerts.SignatureFailures)
+				assert.Equal(t, 0, cfg.Alerts.DigestAfter)
+			},
+		},
+		{
+			name:    "unknown alert event",
+			env:     map[string]string{"ALERT_EVENTS": "order_paid,order_shipped"},
+			wantErr: `alerts.events (ALERT_EVENTS): unknown event "order_shipped"`,
+		},
+		{
+			name:    "telegram alerts need a chat",
+			env:     map[string]string{"ALERT_WEBHOOK_URL": "https://api.telegram.org/bot123:abc/sendMessage", "ALERT_WEBHOOK_FORMAT": "telegram"},
+			wantErr: "alerts.telegram_chat_id (ALERT_TELEGRAM_CHAT_ID): is required for telegram",
+		},
+		{
+			name:    "unknown chat format",
+			env:     map[string]string{"ALERT_WEBHOOK_URL": "https://chat.example.com/hook", "ALERT_WEBHOOK_FORMAT": "teams"},
+			wantErr: `alerts.webhook_format (ALERT_WEBHOOK_FORMAT): must be slack, discord or telegram, got "teams"`,
+		},
+		{
+			name:    "alert digests at most once a minute",
+			env:     map[string]string{"ALERT_DIGEST_INTERVAL": "30s"},
+			wantErr: "alerts.digest_interval (ALERT_DIGEST_INTERVAL): must be at least 1m",
+		},
+		{
+			name: "sheet export",
+			env: map[string]string{
+				"SHEETS_SYNC_SCHEDULE": "@every 30s",
+				"SHEETS_COLUMNS":       "order_number,paid_at,-,amount",
+				"SHEETS_BATCH_SIZE":    "500",
+			},
+			check: func(t *testing.T, cfg *config.Config) {
+				assert.Equal(t, "@every 30s", cfg.SheetSync.Schedule)
+				assert.Equal(t, []string{"order_number", "paid_at", "-", "amount"}, cfg.SheetSync.Columns)
+				assert.Equal(t, 500, cfg.SheetSync.BatchSize)
+				assert.Equal(t, 3, cfg.SheetSync.Attempts)
+			},
+		},
+		{
+			name:    "unknown sheet column",
+			env:     map[string]string{"SHEETS_COLUMNS": "name,shoe_size"},
+			wantErr: `sheet_sync.columns (SHEETS_COLUMNS): unknown column "shoe_size"`,
+		},
+		{
+			name:    "bad sheet schedule",
+			env:     map[string]string{"SHEETS_SYNC_SCHEDULE": "every minute"},
+			wantErr: "sheet_sync.schedule (SHEETS_SYNC_SCHEDULE): must be a cron expression",
+		},
+		{
+			name: "webhook delivery",
+			env: map[string]string{
+				"WEBHOOK_MAX_ATTEMPTS":      "5",
+				"WEBHOOK_RETRY_BACKOFF":     "10s",
+				"WEBHOOK_DROP_START_WINDOW": "15m",
+			},
+			check: func(t *testing.T, cfg *config.Config) {
+				assert.Equal(t, 5, cfg.Webhooks.MaxAttempts)
+				assert.Equal(t, 10*time.Second, cfg.Webhooks.Backoff)
+				assert.Equal(t, time.Hour, cfg.Webhooks.MaxBackoff)
+				assert.Equal(t, "@every 1m", cfg.Webhooks.DropStartSchedule)
+				assert.Equal(t, 15*time.Minute, cfg.Webhooks.DropStartWindow)
+			},
+		},
+		{
+			name:    "webhook backoff above its cap",
+			env:     map[string]string{"WEBHOOK_RETRY_BACKOFF": "2h"},
+			wantErr: "webhooks.max_backoff (WEBHOOK_MAX_BACKOFF): must be at least WEBHOOK_RETRY_BACKOFF (2h0m0s), got 1h0m0s",
+		},
+		{
+			name:    "no webhook workers",
+			env:     map[string]string{"WEBHOOK_WORKERS": "0"},
+			wantErr: "webhooks.workers (WEBHOOK_WORKERS): must be between 1 and 64, got 0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := config.Sources{Env: envMap(tt.env)}
+			if tt.file != "" {
+				src.File = writeFile(t, tt.file, tt.content)
+			}
+			if tt.secret != nil {
+				src.Secret = envMap(tt.secret)
+			}
+
+			cfg, err := config.LoadWith(src)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			tt.check(t, cfg)
+		})
+	}
+}
+
+func TestLoadWith_ReportsEveryError(t *testing.T) {
+	_, err := config.LoadWith(config.Sources{Env: envMap(map[string]string{
+		"PORT":            "0",
+		"PAYMENT_WORKERS": "0",
+	})})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "server.port (PORT)")
+	assert.Contains(t, err.Error(), "queue.workers (PAYMENT_WORKERS)")
+}
+
+func TestWriteYAML_RedactsSecrets(t *testing.T) {
+	cfg := config.Default()
+	cfg.PayOS.APIKey = "super-secret"
+	cfg.AWS.AccessKey = "AKIAEXAMPLE"
+
+	var buf bytes.Buffer
+	require.NoError(t, cfg.WriteYAML(&buf))
+	out := buf.String()
+
+	assert.NotContains(t, out, "super-secret")
+	assert.NotContains(t, out, "AKIAEXAMPLE")
+	assert.Contains(t, out, `api_key: <redacted>`)
+	assert.Contains(t, out, `checksum_key: ""`, "unset secrets stay visible as empty")
+
+	// The printed config is a valid config file
+	path := writeFile(t, "printed.yaml", out)
+	reloaded, err := config.LoadWith(config.Sources{File: path, Env: envMap(nil)})
+	require.NoError(t, err)
+	assert.Equal(t, cfg.Server, reloaded.Server)
+	assert.Equal(t, cfg.Queue, reloaded.Queue)
+}
@@ -0,0 +1,53 @@
+package database_test
+
+import (
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"ecommerce-backend/internal/database"
+	"ecommerce-backend/internal/metrics"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestObserveBusy_CountsLockContention(t *testing.T) {
+	dsn := "file:" + filepath.Join(t.TempDir(), "busy.db") + "?_journal_mode=WAL&_busy_timeout=0"
+
+	holder, err := sql.Open("sqlite3", dsn)
+	require.NoError(t, err)
+	defer holder.Close()
+	_, err = holder.Exec(`CREATE TABLE t (id INTEGER)`)
+	require.NoError(t, err)
+
+	// Hold the write lock from another connection
+	lock, err := holder.Begin()
+	require.NoError(t, err)
+	defer lock.Rollback()
+	_, err = lock.Exec(`INSERT INTO t VALUES (1)`)
+	require.NoError(t, err)
+
+	writer, err := sql.Open("sqlite3", dsn)
+	require.NoError(t, err)
+	defer writer.Close()
+	exec := database.NewSmartExecutor(writer, writer)
+
+	counter := metrics.SQLiteBusyErrors.WithLabelValues("exec")
+	before := testutil.ToFloat64(counter)
+
+	_, err = exec.Exec(`INSERT INTO t VALUES (2)`)
+	require.Error(t, err)
+	assert.True(t, database.IsBusy(err))
+	assert.Equal(t, before+1, testutil.ToFloat64(counter))
+
+	// Other errors are not counted
+	_, err = exec.Exec(`INSERT INTO missing VALUES (1)`)
+	require.Error(t, err)
+	assert.False(t, database.IsBusy(err))
+	assert.Equal(t, before+1, testutil.ToFloat64(counter))
+
+	assert.False(t, database.IsBusy(errors.New("database is locked")), "only driver errors are classified")
+}
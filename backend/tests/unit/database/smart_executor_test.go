@@ -0,0 +1,239 @@
+package database_test
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"ecommerce-backend/internal/database"
+	"ecommerce-backend/internal/repository"
+
+	"github.com/gofiber/fiber/v3"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// =============================================================================
+// SQL CLASSIFICATION
+// =============================================================================
+
+func TestIsWriteQuery_TableDriven(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		write bool
+	}{
+		{"select", "SELECT * FROM orders WHERE id = ?", false},
+		{"select lowercase with leading whitespace", "\n\t  select id from orders", false},
+		{"values", "VALUES (1), (2)", false},
+		{"explain", "EXPLAIN QUERY PLAN SELECT 1", false},
+		{"insert", "INSERT INTO orders (id) VALUES (?)", true},
+		{"update", "UPDATE symbicodes SET is_activated = 1 WHERE id = ?", true},
+		{"delete", "DELETE FROM orders WHERE id = ?", true},
+		{"replace", "REPLACE INTO orders (id) VALUES (1)", true},
+		{"insert select", "INSERT INTO archive SELECT * FROM orders", true},
+		{"insert returning", "INSERT INTO orders (id) VALUES (?) RETURNING id", true},
+		{"update returning", "UPDATE limited_drops SET sold = sold + 1 WHERE id = ? RETURNING sold", true},
+		{"with select", "WITH recent AS (SELECT * FROM orders) SELECT * FROM recent", false},
+		{"with recursive select", "WITH RECURSIVE n(x) AS (VALUES(1) UNION ALL SELECT x+1 FROM n WHERE x < 5) SELECT x FROM n", false},
+		{"with insert", "WITH src AS (SELECT id FROM orders) INSERT INTO archive SELECT id FROM src", true},
+		{"with delete", "WITH old AS (SELECT id FROM orders WHERE status = 8) DELETE FROM orders WHERE id IN (SELECT id FROM old)", true},
+		{"with update returning", "WITH x AS (SELECT 1) UPDATE orders SET status = 2 RETURNING id", true},
+		{"keyword inside string literal", "SELECT 'DELETE FROM orders' AS note", false},
+		{"keyword inside quoted identifier", `SELECT "update", [delete], ` + "`insert`" + ` FROM t`, false},
+		{"escaped quote", "SELECT 'it''s RETURNING' FROM t", false},
+		{"keyword in line comment", "-- UPDATE orders\nSELECT 1", false},
+		{"keyword in block comment", "/* DELETE */ SELECT 1", false},
+		{"comment before write", "/* audit */ INSERT INTO t VALUES (1)", true},
+		{"identifier containing keyword", "SELECT updated_at, returning_customer FROM users", false},
+		{"pragma is treated as write", "PRAGMA wal_checkpoint(TRUNCATE)", true},
+		{"ddl", "CREATE TABLE t (id INTEGER)", true},
+		{"empty", "   ", false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.write, database.IsWriteQuery(tc.query))
+		})
+	}
+}
+
+// =============================================================================
+// ROUTING / FRESHNESS
+// =============================================================================
+
+// setupPools opens two independent SQLite files. The reader file plays the role
+// of a lagging replica: it never sees writes made through the writer, so any
+// read that returns fresh data provably went to the writer.
+func setupPools(t *testing.T) (*sql.DB, *sql.DB) {
+	dir := t.TempDir()
+	open := func(name string) *sql.DB {
+		db, err := sql.Open("sqlite3", filepath.Join(dir, name))
+		require.NoError(t, err)
+		db.SetMaxOpenConns(1)
+		_, err = db.Exec(`CREATE TABLE kv (k TEXT PRIMARY KEY, v TEXT)`)
+		require.NoError(t, err)
+		_, err = db.Exec(`INSERT INTO kv (k, v) VALUES ('a', 'stale')`)
+		require.NoError(t, err)
+		t.Cleanup(func() { db.Close() })
+		return db
+	}
+	return open("writer.db"), open("reader.db")
+}
+
+func readValue(t *testing.T, row *sql.Row) string {
+	var v string
+	require.NoError(t, row.Scan(&v))
+	return v
+}
+
+func TestSmartExecutor_DefaultReadsGoToReader(t *testing.T) {
+	writer, reader := setupPools(t)
+	se := database.NewSmartExecutor(writer, reader)
+
+	_, err := se.Exec(`UPDATE kv SET v = 'fresh' WHERE k = 'a'`)
+	require.NoError(t, err)
+
+	// The shared executor has no session, so it keeps reading from Reader
+	assert.Equal(t, "stale", readValue(t, se.QueryRow(`SELECT v FROM kv WHERE k = 'a'`)))
+}
+
+func TestSmartExecutor_SessionReadsItsOwnWrites(t *testing.T) {
+	writer, reader := setupPools(t)
+	se := database.NewSmartExecutor(writer, reader)
+	session := se.Session()
+
+	// Before any write, the session still uses Reader
+	assert.Equal(t, "stale", readValue(t, session.QueryRow(`SELECT v FROM kv WHERE k = 'a'`)))
+
+	_, err := session.Exec(`UPDATE kv SET v = 'fresh' WHERE k = 'a'`)
+	require.NoError(t, err)
+
+	assert.Equal(t, "fresh", readValue(t, session.QueryRow(`SELECT v FROM kv WHERE k = 'a'`)))
+
+	// Stickiness is scoped to the session, other callers are unaffected
+	assert.Equal(t, "stale", readValue(t, se.QueryRow(`SELECT v FROM kv WHERE k = 'a'`)))
+	assert.Equal(t, "stale", readValue(t, se.Session().QueryRow(`SELECT v FROM kv WHERE k = 'a'`)))
+}
+
+func TestSmartExecutor_PrimaryAlwaysReadsWriter(t *testing.T) {
+	writer, reader := setupPools(t)
+	se := database.NewSmartExecutor(writer, reader)
+
+	_, err := writer.Exec(`UPDATE kv SET v = 'fresh' WHERE k = 'a'`)
+	require.NoError(t, err)
+
+	assert.Equal(t, "fresh", readValue(t, se.Primary().QueryRow(`SELECT v FROM kv WHERE k = 'a'`)))
+	assert.Equal(t, "stale", readValue(t, se.QueryRow(`SELECT v FROM kv WHERE k = 'a'`)))
+}
+
+func TestSmartExecutor_ContextHints(t *testing.T) {
+	writer, reader := setupPools(t)
+	se := database.NewSmartExecutor(writer, reader)
+	q := `SELECT v FROM kv WHERE k = 'a'`
+
+	_, err := writer.Exec(`UPDATE kv SET v = 'fresh' WHERE k = 'a'`)
+	require.NoError(t, err)
+
+	t.Run("WithPrimary forces writer", func(t *testing.T) {
+		ctx := database.WithPrimary(context.Background())
+		assert.Equal(t, "fresh", readValue(t, se.QueryRowContext(ctx, q)))
+	})
+
+	t.Run("WithSession becomes sticky after a write", func(t *testing.T) {
+		ctx := database.WithSession(context.Background())
+		assert.Equal(t, "stale", readValue(t, se.QueryRowContext(ctx, q)))
+		assert.False(t, database.SessionWrote(ctx))
+
+		_, err := se.ExecContext(ctx, `UPDATE kv SET v = 'fresher' WHERE k = 'a'`)
+		require.NoError(t, err)
+		assert.True(t, database.SessionWrote(ctx))
+
+		assert.Equal(t, "fresher", readValue(t, se.QueryRowContext(ctx, q)))
+		assert.Equal(t, "stale", readValue(t, se.QueryRowContext(context.Background(), q)))
+	})
+}
+
+func TestSessionMiddleware(t *testing.T) {
+	writer, reader := setupPools(t)
+	se := database.NewSmartExecutor(writer, reader)
+
+	app := fiber.New()
+	app.Use(database.SessionMiddleware())
+	// Writes when asked to, then reports what a read sees
+	handler := func(c fiber.Ctx) error {
+		if c.Query("write") != "" {
+			if _, err := se.ExecContext(c.Context(), `UPDATE kv SET v = 'fresh' WHERE k = 'a'`); err != nil {
+				return err
+			}
+		}
+		var v string
+		if err := se.QueryRowContext(c.Context(), `SELECT v FROM kv WHERE k = 'a'`).Scan(&v); err != nil {
+			return err
+		}
+		return c.SendString(v)
+	}
+	app.Get("/kv", handler)
+	app.Post("/kv", handler)
+
+	tests := []struct {
+		name, method, target, want string
+	}{
+		{"post reads its own write", fiber.MethodPost, "/kv?write=1", "fresh"},
+		{"post without a write reads the reader", fiber.MethodPost, "/kv", "stale"},
+		{"get reads the reader", fiber.MethodGet, "/kv?write=1", "stale"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := app.Test(httptest.NewRequest(tc.method, tc.target, nil))
+			require.NoError(t, err)
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, string(body))
+		})
+	}
+}
+
+func TestSmartExecutor_ReturningGoesToWriter(t *testing.T) {
+	writer, reader := setupPools(t)
+	se := database.NewSmartExecutor(writer, reader)
+	session := se.Session()
+
+	// A write issued through QueryRow must not be sent to the read pool
+	v := readValue(t, session.QueryRow(`UPDATE kv SET v = 'returned' WHERE k = 'a' RETURNING v`))
+	assert.Equal(t, "returned", v)
+
+	// ...and it counts as a write for the session
+	assert.Equal(t, "returned", readValue(t, session.QueryRow(`SELECT v FROM kv WHERE k = 'a'`)))
+	assert.Equal(t, "returned", readValue(t, writer.QueryRow(`SELECT v FROM kv WHERE k = 'a'`)))
+	assert.Equal(t, "stale", readValue(t, reader.QueryRow(`SELECT v FROM kv WHERE k = 'a'`)))
+}
+
+func TestRepository_SessionSeesActivation(t *testing.T) {
+	writer, reader := setupPools(t)
+	for _, db := range []*sql.DB{writer, reader} {
+		_, err := db.Exec(`
+			CREATE TABLE symbicodes (
+				id INTEGER PRIMARY KEY, order_id INTEGER, product_id INTEGER,
+				created_at DATETIME, activated_at DATETIME, code BLOB UNIQUE,
+				secret_key TEXT, activated_ip TEXT, is_activated INTEGER DEFAULT 0,
+				bound_at DATETIME, batch_id INTEGER DEFAULT 0
+			);
+			INSERT INTO symbicodes (id, order_id, product_id, created_at, code, secret_key, activated_ip, is_activated)
+			VALUES (1, 1, 1, CURRENT_TIMESTAMP, x'01', 's', '', 0);`)
+		require.NoError(t, err)
+	}
+
+	repo := repository.NewRepository(database.NewSmartExecutor(writer, reader)).Session()
+	require.NoError(t, repo.ActivateSymbicode(1, "127.0.0.1"))
+
+	sym, err := repo.GetSymbicodeByCode([]byte{0x01})
+	require.NoError(t, err)
+	assert.Equal(t, uint8(1), sym.IsActivated)
+	assert.Equal(t, "127.0.0.1", sym.ActivatedIP)
+}
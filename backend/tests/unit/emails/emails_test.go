@@ -0,0 +1,144 @@
+package emails_test
+
+import (
+	"context"
+	"testing"
+
+	"ecommerce-backend/internal/emails"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRender_AllTemplatesAllLocales(t *testing.T) {
+	names := emails.Names()
+	require.Len(t, names, 8)
+
+	for _, locale := range emails.Locales {
+		for _, name := range names {
+			t.Run(string(locale)+"/"+name, func(t *testing.T) {
+				data := emails.Fixture(name)
+				require.NotNil(t, data)
+
+				msg, err := emails.Render(name, locale, data)
+				require.NoError(t, err)
+				assert.NotEmpty(t, msg.Subject)
+				assert.NotContains(t, msg.Subject, "\n")
+				assert.Contains(t, msg.HTML, "<!DOCTYPE html>")
+				assert.NotEmpty(t, msg.Text)
+				assert.NotContains(t, msg.Text, "<p>")
+			})
+		}
+	}
+}
+
+func TestRender_Localized(t *testing.T) {
+	data := emails.Fixture(emails.OrderDetails)
+
+	vi, err := emails.Render(emails.OrderDetails, emails.Vietnamese, data)
+	require.NoError(t, err)
+	en, err := emails.Render(emails.OrderDetails, emails.English, data)
+	require.NoError(t, err)
+
+	assert.Equal(t, "Chi tiết đơn hàng #DV-P0078DWZD", vi.Subject)
+	assert.Equal(t, "Order details #DV-P0078DWZD", en.Subject)
+	assert.Contains(t, vi.HTML, "Đã thanh toán")
+	assert.Contains(t, en.HTML, "Paid")
+	assert.Contains(t, vi.Text, "3.190.000 ₫")
+	assert.Contains(t, en.Text, "3,190,000 VND")
+}
+
+func TestRender_EscapesHTMLOnly(t *testing.T) {
+	msg, err := emails.Render(emails.OrderDetails, emails.English, emails.Fixture(emails.OrderDetails))
+	require.NoError(t, err)
+
+	assert.Contains(t, msg.HTML, "Leather strap &lt;black&gt;")
+	assert.NotContains(t, msg.HTML, "<black>")
+	assert.Contains(t, msg.Text, "Leather strap <black>")
+}
+
+func TestRender_SymbioteResults(t *testing.T) {
+	tests := []struct {
+		result      string
+		wantSubject string
+		wantStatus  string
+	}{
+		{"WINNER", "[ACCESS GRANTED] PROJECT SYMBIOTE", "Status: SECURED"},
+		{"LOSER", "[ACCESS DENIED] PROJECT SYMBIOTE", "Status: REJECTED"},
+		{"ACTIVE", "[NOTICE] PROJECT SYMBIOTE", "Status: ACTIVE"},
+		{"", "[NOTICE] PROJECT SYMBIOTE", "Status: INFO"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.wantStatus, func(t *testing.T) {
+			data := emails.SymbioteReceiptData{UserID: "09**99", Result: tc.result, Elapsed: "1s"}
+			msg, err := emails.Render(emails.SymbioteReceipt, emails.Vietnamese, data)
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantSubject, msg.Subject)
+			assert.Contains(t, msg.Text, tc.wantStatus)
+			assert.Contains(t, msg.HTML, tc.wantStatus)
+		})
+	}
+}
+
+func TestRender_OrderDetailsWithoutLink(t *testing.T) {
+	data := emails.Fixture(emails.OrderDetails).(emails.OrderDetailsData)
+	data.Link = ""
+
+	msg, err := emails.Render(emails.OrderDetails, emails.English, data)
+	require.NoError(t, err)
+	assert.NotContains(t, msg.Text, "orders/view")
+	assert.Contains(t, msg.Text, "https://donaldwatch.vn/orders")
+}
+
+func TestRender_AdminAlert(t *testing.T) {
+	digest, err := emails.Render(emails.AdminAlert, emails.English, emails.Fixture(emails.AdminAlert))
+	require.NoError(t, err)
+	assert.Equal(t, "[DW] 3 alerts", digest.Subject)
+	assert.Contains(t, digest.Text, "20:00:41 Drop sold out: Symbiote Genesis <#1> (100/100)")
+	assert.Contains(t, digest.HTML, "Symbiote Genesis &lt;#1&gt;")
+
+	single, err := emails.Render(emails.AdminAlert, emails.Vietnamese, emails.AdminAlertData{
+		Alerts: []emails.AlertItem{{At: "20:02:17", Title: "Refund failed for DV-Q1R2S3T4U"}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "[DW] Refund failed for DV-Q1R2S3T4U", single.Subject)
+	assert.Contains(t, single.Text, "Cảnh báo")
+}
+
+func TestRender_UnknownTemplate(t *testing.T) {
+	_, err := emails.Render("missing", emails.English, nil)
+	require.Error(t, err)
+
+	_, err = emails.Render(emails.Welcome, emails.Locale("fr"), emails.Fixture(emails.Welcome))
+	require.Error(t, err)
+}
+
+func TestParseLocale_TableDriven(t *testing.T) {
+	tests := []struct {
+		in   string
+		want emails.Locale
+	}{
+		{"", emails.Vietnamese},
+		{"vi", emails.Vietnamese},
+		{"en", emails.English},
+		{"EN", emails.English},
+		{"en-US", emails.English},
+		{"en-US,en;q=0.9,vi;q=0.8", emails.English},
+		{"fr-FR,fr;q=0.9,en;q=0.8", emails.English},
+		{"vi-VN,vi;q=0.9", emails.Vietnamese},
+		{"fr", emails.Vietnamese},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.in, func(t *testing.T) {
+			assert.Equal(t, tc.want, emails.ParseLocale(tc.in))
+		})
+	}
+}
+
+func TestLocaleContext(t *testing.T) {
+	ctx := context.Background()
+	assert.Equal(t, emails.DefaultLocale, emails.LocaleFrom(ctx))
+	assert.Equal(t, emails.English, emails.LocaleFrom(emails.WithLocale(ctx, emails.English)))
+}
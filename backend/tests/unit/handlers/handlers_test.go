@@ -1,22 +1,41 @@
 package handlers_test
 
 import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"io"
 	"net/http/httptest"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
 
+	"ecommerce-backend/internal/alerts"
+	"ecommerce-backend/internal/botcheck"
 	"ecommerce-backend/internal/handlers"
+	"ecommerce-backend/internal/health"
 	"ecommerce-backend/internal/integrations"
+	"ecommerce-backend/internal/integrations/sheetstest"
+	"ecommerce-backend/internal/labels"
 	"ecommerce-backend/internal/models"
+	"ecommerce-backend/internal/queue"
+	"ecommerce-backend/internal/ratelimit"
+	"ecommerce-backend/internal/scheduler"
 	"ecommerce-backend/internal/service"
+	"ecommerce-backend/internal/sheetsync"
+	"ecommerce-backend/internal/webhooks"
+	"ecommerce-backend/internal/utils/base32"
 
 	"github.com/gofiber/fiber/v3"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	gormsqlite "gorm.io/driver/sqlite"
+	"gorm.io/gorm"
 )
 
 // =============================================================================
@@ -34,17 +53,36 @@ type mockService struct {
 	dropErr     error
 	purchaseRes *service.PurchaseResult // Configurable result
 	purchaseErr error               // Configurable error
+	lastPurchase *service.PurchaseRequest
 	processPaymentErr error         // Configurable error
 
 	// Order
 	orders        map[uint64]*models.Order
 	ordersByPhone map[string][]models.Order
 	orderErr      error
+	orderView     *service.OrderView
+	lookupErr     error
+	lastLookup    service.OrderLookup
+	linkOrders    []string
 
 	// Symbicode
 	symbicode      *models.Symbicode
 	symbicodeValid bool
 	symbicodeErr   error
+	scanReport     service.ScanReport
+	lastScan       service.ScanInfo
+	suspicious     []service.SuspiciousSymbicode
+	transferErr    error
+	lastContact    service.Contact
+	lastOTP        string
+	batchErr       error
+	labels         []labels.Label
+
+	// Email delivery
+	emailEvents    []integrations.EmailEvent
+	emailEventErr  error
+	orderEmails    *service.OrderEmails
+	suppressionErr error
 }
 
 func newMockService() *mockService {
@@ -57,7 +95,7 @@ func newMockService() *mockService {
 }
 
 // Product methods
-func (m *mockService) GetProduct(id uint64) (*models.Product, error) {
+func (m *mockService) GetProduct(ctx context.Context, id uint64) (*models.Product, error) {
 	if m.productErr != nil {
 		return nil, m.productErr
 	}
@@ -68,7 +106,7 @@ func (m *mockService) GetProduct(id uint64) (*models.Product, error) {
 	return p, nil
 }
 
-func (m *mockService) ListProducts() ([]models.Product, error) {
+func (m *mockService) ListProducts(ctx context.Context) ([]models.Product, error) {
 	if m.productsErr != nil {
 		return nil, m.productsErr
 	}
@@ -80,11 +118,11 @@ func (m *mockService) ListProducts() ([]models.Product, error) {
 }
 
 // Order methods
-func (m *mockService) CreateOrder(customerPhone string, shippingAddress []byte, items []byte, paymentMethod uint8, payOSOrderCode *int64) (*models.Order, error) {
+func (m *mockService) CreateOrder(ctx context.Context, customerPhone string, shippingAddress []byte, items []byte, paymentMethod uint8, payOSOrderCode *int64) (*models.Order, error) {
 	return nil, nil
 }
 
-func (m *mockService) GetOrderByID(id uint64) (*models.Order, error) {
+func (m *mockService) GetOrderByID(ctx context.Context, id uint64) (*models.Order, error) {
 	if m.orderErr != nil {
 		return nil, m.orderErr
 	}
@@ -95,13 +133,26 @@ func (m *mockService) GetOrderByID(id uint64) (*models.Order, error) {
 	return o, nil
 }
 
-func (m *mockService) GetOrdersByUserPhone(phone string) ([]models.Order, error) {
+func (m *mockService) GetOrdersByUserPhone(ctx context.Context, phone string) ([]models.Order, error) {
 	return m.ordersByPhone[phone], nil
 }
 
+func (m *mockService) LookupOrder(ctx context.Context, q service.OrderLookup) (*service.OrderView, error) {
+	m.lastLookup = q
+	if m.lookupErr != nil {
+		return nil, m.lookupErr
+	}
+	return m.orderView, nil
+}
+
+func (m *mockService) SendOrderLookupLink(ctx context.Context, orderNumber string) error {
+	m.linkOrders = append(m.linkOrders, orderNumber)
+	return nil
+}
+
 
 // Drop methods
-func (m *mockService) GetActiveDrops() ([]models.LimitedDrop, error) {
+func (m *mockService) GetActiveDrops(ctx context.Context) ([]models.LimitedDrop, error) {
 	if m.dropErr != nil {
 		return nil, m.dropErr
 	}
@@ -112,7 +163,7 @@ func (m *mockService) GetActiveDrops() ([]models.LimitedDrop, error) {
 	return result, nil
 }
 
-func (m *mockService) GetDropStatus(id uint64) (*service.LimitedDropStatus, error) {
+func (m *mockService) GetDropStatus(ctx context.Context, id uint64) (*service.LimitedDropStatus, error) {
 	if m.dropErr != nil {
 		return nil, m.dropErr
 	}
@@ -131,7 +182,8 @@ func (m *mockService) GetDropStatus(id uint64) (*service.LimitedDropStatus, erro
 	}, nil
 }
 
-func (m *mockService) PurchaseDrop(dropID uint64, req *service.PurchaseRequest) (*service.PurchaseResult, error) {
+func (m *mockService) PurchaseDrop(ctx context.Context, dropID uint64, req *service.PurchaseRequest) (*service.PurchaseResult, error) {
+	m.lastPurchase = req
 	if m.purchaseErr != nil {
 		return nil, m.purchaseErr
 	}
@@ -144,20 +196,116 @@ func (m *mockService) PurchaseDrop(dropID uint64, req *service.PurchaseRequest)
 	}, nil
 }
 
-func (m *mockService) ProcessSuccessfulDropPayment(orderCode int64) error {
+func (m *mockService) ProcessSuccessfulDropPayment(ctx context.Context, orderCode int64) error {
 	return m.processPaymentErr
 }
 
+func (m *mockService) AnnounceStartedDrops(ctx context.Context, within time.Duration) (int, error) {
+	return 0, nil
+}
+
+func (m *mockService) ProcessRefunds(ctx context.Context) (int, error) {
+	return 0, nil
+}
+
 // Symbicode methods
-func (m *mockService) GenerateSymbicode(productID uint64, orderID *uint64) (*models.Symbicode, error) {
+func (m *mockService) GenerateSymbicode(ctx context.Context, productID uint64, orderID *uint64) (*models.Symbicode, error) {
 	return nil, nil
 }
 
-func (m *mockService) VerifySymbicode(code string) (*models.Symbicode, bool, error) {
+func (m *mockService) AutoActivateExpiredSymbicodes(ctx context.Context, olderThan time.Duration) (int, error) {
+	return 0, nil
+}
+
+func (m *mockService) VerifySymbicode(ctx context.Context, code string, scan service.ScanInfo) (*service.VerifyResult, error) {
 	if m.symbicodeErr != nil {
-		return nil, false, m.symbicodeErr
+		return nil, m.symbicodeErr
+	}
+	m.lastScan = scan
+	return &service.VerifyResult{Symbicode: m.symbicode, IsFirstActivation: m.symbicodeValid, Scans: m.scanReport}, nil
+}
+
+func (m *mockService) ListSymbicodeScans(ctx context.Context, symbicodeID uint64, limit int) ([]models.SymbicodeScan, error) {
+	return nil, nil
+}
+
+func (m *mockService) ListSuspiciousSymbicodes(ctx context.Context, limit int) ([]service.SuspiciousSymbicode, error) {
+	return m.suspicious, nil
+}
+
+func (m *mockService) InitiateSymbicodeTransfer(ctx context.Context, code string, owner service.Contact) (*service.TransferChallenge, error) {
+	if m.transferErr != nil {
+		return nil, m.transferErr
+	}
+	m.lastContact = owner
+	return &service.TransferChallenge{Channel: "sms", ExpiresAt: time.Now().Add(10 * time.Minute)}, nil
+}
+
+func (m *mockService) ConfirmSymbicodeTransfer(ctx context.Context, code string, owner service.Contact, otp string) (*service.TransferOffer, error) {
+	if m.transferErr != nil {
+		return nil, m.transferErr
+	}
+	m.lastContact = owner
+	m.lastOTP = otp
+	return &service.TransferOffer{Token: "claim-token", SymbicodeID: 1, ExpiresAt: time.Now().Add(time.Hour)}, nil
+}
+
+func (m *mockService) GenerateSymbicodeBatch(ctx context.Context, productID uint64, count int, note string) (*models.SymbicodeBatch, error) {
+	if m.batchErr != nil {
+		return nil, m.batchErr
+	}
+	return &models.SymbicodeBatch{ID: 1, ProductID: productID, Count: count, Note: note}, nil
+}
+
+func (m *mockService) SymbicodeLabels(ctx context.Context, batchID uint64) (*models.SymbicodeBatch, []labels.Label, error) {
+	if m.batchErr != nil {
+		return nil, nil, m.batchErr
+	}
+	return &models.SymbicodeBatch{ID: batchID}, m.labels, nil
+}
+
+func (m *mockService) BindSymbicode(ctx context.Context, code string, orderID uint64) (*models.Symbicode, error) {
+	if m.batchErr != nil {
+		return nil, m.batchErr
 	}
-	return m.symbicode, m.symbicodeValid, nil
+	return &models.Symbicode{ID: 1, OrderID: orderID, BatchID: 1}, nil
+}
+
+func (m *mockService) ClaimSymbicodeTransfer(ctx context.Context, token string, recipient service.Contact) (*service.ClaimResult, error) {
+	if m.transferErr != nil {
+		return nil, m.transferErr
+	}
+	m.lastContact = recipient
+	return &service.ClaimResult{SymbicodeID: 1, ProductID: 10, Owners: 2}, nil
+}
+
+func (m *mockService) HandleEmailEvent(ctx context.Context, ev integrations.EmailEvent) error {
+	if m.emailEventErr != nil {
+		return m.emailEventErr
+	}
+	m.emailEvents = append(m.emailEvents, ev)
+	return nil
+}
+
+func (m *mockService) GetOrderEmails(ctx context.Context, orderID uint64) (*service.OrderEmails, error) {
+	if m.orderEmails == nil || m.orderEmails.OrderID != orderID {
+		return nil, service.ErrOrderNotFound
+	}
+	return m.orderEmails, nil
+}
+
+func (m *mockService) ListEmailSuppressions(ctx context.Context, limit int) ([]models.EmailSuppression, error) {
+	return []models.EmailSuppression{{Email: "bounce@example.com", Reason: models.EmailBounced}}, nil
+}
+
+func (m *mockService) RemoveEmailSuppression(ctx context.Context, email string) error {
+	if m.suppressionErr != nil {
+		return m.suppressionErr
+	}
+	if email != "bounce@example.com" {
+		return service.ErrSuppressionNotFound
+	}
+	return nil
 }
 
 
@@ -302,6 +450,284 @@ func TestHealthEndpoint(t *testing.T) {
 	assert.Equal(t, "ok", result["status"])
 }
 
+func TestReadinessEndpoints_TableDriven(t *testing.T) {
+	ok := func(context.Context) (string, error) { return "fine", nil }
+	broken := func(context.Context) (string, error) { return "", errors.New("broken") }
+
+	tests := []struct {
+		name       string
+		path       string
+		checks     []health.Check
+		wantStatus int
+		wantBody   string
+	}{
+		{"livez ignores dependencies", "/livez", []health.Check{{Name: "writer", Critical: true, Run: broken}}, 200, "ok"},
+		{"ready", "/readyz", []health.Check{{Name: "writer", Critical: true, Run: ok}}, 200, "ok"},
+		{"non-critical failure degrades", "/readyz", []health.Check{{Name: "writer", Critical: true, Run: ok}, {Name: "payos", Run: broken}}, 200, "degraded"},
+		{"critical failure is not ready", "/readyz", []health.Check{{Name: "writer", Critical: true, Run: broken}}, 503, "fail"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			app := fiber.New()
+			handlers.NewHandlers(newMockService(), handlers.WithReadiness(health.NewChecker(time.Second, tc.checks...))).RegisterRoutes(app)
+
+			resp, err := app.Test(httptest.NewRequest("GET", tc.path, nil))
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			assert.Equal(t, tc.wantStatus, resp.StatusCode)
+
+			var body map[string]interface{}
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+			assert.Equal(t, tc.wantBody, body["status"])
+		})
+	}
+}
+
+func TestAdminSchedulerJobs_TableDriven(t *testing.T) {
+	const token = "0123456789abcdef0123456789abcdef"
+
+	tests := []struct {
+		name       string
+		adminToken string
+		auth       string
+		wantStatus int
+	}{
+		{"valid token", token, "Bearer " + token, 200},
+		{"wrong token", token, "Bearer wrong", 401},
+		{"missing header", token, "", 401},
+		{"routes disabled without a token", "", "Bearer ", 404},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			sched := scheduler.New(scheduler.Options{Owner: "test"})
+			require.NoError(t, sched.Register(scheduler.Job{Name: "cleanup", Schedule: "@hourly", Run: func(context.Context) (string, error) { return "", nil }}))
+
+			app := fiber.New()
+			handlers.NewHandlers(newMockService(), handlers.WithScheduler(sched), handlers.WithAdminToken(tc.adminToken)).RegisterRoutes(app)
+
+			req := httptest.NewRequest("GET", "/api/admin/scheduler/jobs", nil)
+			if tc.auth != "" {
+				req.Header.Set("Authorization", tc.auth)
+			}
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			assert.Equal(t, tc.wantStatus, resp.StatusCode)
+
+			if tc.wantStatus == 200 {
+				var body struct {
+					Jobs []scheduler.Status `json:"jobs"`
+				}
+				require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+				require.Len(t, body.Jobs, 1)
+				assert.Equal(t, "cleanup", body.Jobs[0].Name)
+				assert.Equal(t, "@hourly", body.Jobs[0].Schedule)
+			}
+		})
+	}
+}
+
+func TestAdminAlerts_TableDriven(t *testing.T) {
+	const token = "0123456789abcdef0123456789abcdef"
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		noAlerter  bool
+		wantStatus int
+		wantDigest bool
+	}{
+		{name: "status", method: "GET", path: "/api/admin/alerts", wantStatus: 200},
+		{name: "digest for a launch", method: "PUT", path: "/api/admin/alerts/digest", body: `{"duration":"2h"}`, wantStatus: 200, wantDigest: true},
+		{name: "back to single alerts", method: "PUT", path: "/api/admin/alerts/digest", body: `{"duration":"0s"}`, wantStatus: 200},
+		{name: "duration over a day", method: "PUT", path: "/api/admin/alerts/digest", body: `{"duration":"48h"}`, wantStatus: 400},
+		{name: "bad duration", method: "PUT", path: "/api/admin/alerts/digest", body: `{"duration":"soon"}`, wantStatus: 400},
+		{name: "bad body", method: "PUT", path: "/api/admin/alerts/digest", body: `{`, wantStatus: 400},
+		{name: "routes disabled without alerts", method: "GET", path: "/api/admin/alerts", noAlerter: true, wantStatus: 404},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := []handlers.Option{handlers.WithAdminToken(token)}
+			if !tc.noAlerter {
+				opts = append(opts, handlers.WithAlerter(alerts.New(alerts.Options{Kinds: []alerts.Kind{alerts.OrderPaid}})))
+			}
+			app := fiber.New()
+			handlers.NewHandlers(newMockService(), opts...).RegisterRoutes(app)
+
+			req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", "Bearer "+token)
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			assert.Equal(t, tc.wantStatus, resp.StatusCode)
+
+			if tc.wantStatus == 200 {
+				var st alerts.Status
+				require.NoError(t, json.NewDecoder(resp.Body).Decode(&st))
+				assert.Equal(t, []alerts.Kind{alerts.OrderPaid}, st.Kinds)
+				assert.Equal(t, tc.wantDigest, st.Digest)
+				assert.Equal(t, tc.wantDigest, st.DigestUntil != nil)
+			}
+		})
+	}
+}
+
+func TestAdminSheets_TableDriven(t *testing.T) {
+	const token = "0123456789abcdef0123456789abcdef"
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		{name: "status", method: "GET", path: "/api/admin/sheets", wantStatus: 200, wantBody: `{"pending":0,"exported":0}`},
+		{name: "backfill", method: "POST", path: "/api/admin/sheets/backfill", body: `{"from":"2026-03-01T00:00:00+07:00","to":"2026-03-02T00:00:00+07:00"}`, wantStatus: 200, wantBody: `{"queued":1}`},
+		{name: "backfill until now", method: "POST", path: "/api/admin/sheets/backfill", body: `{"from":"2026-01-01T00:00:00Z"}`, wantStatus: 200, wantBody: `{"queued":1}`},
+		{name: "backfill needs from", method: "POST", path: "/api/admin/sheets/backfill", body: `{}`, wantStatus: 400},
+		{name: "backfill range reversed", method: "POST", path: "/api/admin/sheets/backfill", body: `{"from":"2026-03-02T00:00:00Z","to":"2026-03-01T00:00:00Z"}`, wantStatus: 400},
+		{name: "backfill bad time", method: "POST", path: "/api/admin/sheets/backfill", body: `{"from":"yesterday"}`, wantStatus: 400},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			gdb, err := gorm.Open(gormsqlite.Open(filepath.Join(t.TempDir(), "sheets.db")), &gorm.Config{})
+			require.NoError(t, err)
+			require.NoError(t, gdb.AutoMigrate(&models.Order{}, &models.SheetRow{}))
+			db, err := gdb.DB()
+			require.NoError(t, err)
+			defer db.Close()
+			require.NoError(t, gdb.Create(&models.Order{ID: 1, CreatedAt: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC), Status: models.OrderPaid}).Error)
+
+			syncer := sheetsync.New(sheetsync.Options{API: &sheetstest.Sheet{}, Store: sheetsync.NewSQLStore(db)})
+			app := fiber.New()
+			handlers.NewHandlers(newMockService(), handlers.WithAdminToken(token), handlers.WithSheetSync(syncer)).RegisterRoutes(app)
+
+			req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", "Bearer "+token)
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			assert.Equal(t, tc.wantStatus, resp.StatusCode)
+			if tc.wantBody != "" {
+				body, _ := io.ReadAll(resp.Body)
+				assert.JSONEq(t, tc.wantBody, string(body))
+			}
+		})
+	}
+}
+
+func TestAdminWebhooks_TableDriven(t *testing.T) {
+	const token = "0123456789abcdef0123456789abcdef"
+
+	tests := []struct {
+		name         string
+		method       string
+		path         string
+		body         string
+		wantStatus   int
+		wantContains []string
+		wantMissing  []string
+	}{
+		{name: "list", method: "GET", path: "/api/admin/webhooks", wantStatus: 200, wantContains: []string{`"url":"https://hooks.example.com/in"`, `"events":["order.paid"]`}, wantMissing: []string{"whsec_"}},
+		{name: "create", method: "POST", path: "/api/admin/webhooks", body: `{"url":"https://bot.example.com/hook","events":["drop.started","drop.sold_out"],"description":"discord bot"}`, wantStatus: 201, wantContains: []string{`"secret":"whsec_`, `"events":["drop.started","drop.sold_out"]`}},
+		{name: "create unknown event", method: "POST", path: "/api/admin/webhooks", body: `{"url":"https://bot.example.com/hook","events":["order.shipped"]}`, wantStatus: 400, wantContains: []string{`unknown webhook event`}},
+		{name: "create bad url", method: "POST", path: "/api/admin/webhooks", body: `{"url":"bot.example.com","events":["*"]}`, wantStatus: 400},
+		{name: "create bad body", method: "POST", path: "/api/admin/webhooks", body: `{`, wantStatus: 400},
+		{name: "delete", method: "DELETE", path: "/api/admin/webhooks/1", wantStatus: 204},
+		{name: "delete unknown", method: "DELETE", path: "/api/admin/webhooks/9", wantStatus: 404},
+		{name: "delete bad id", method: "DELETE", path: "/api/admin/webhooks/first", wantStatus: 400},
+		{name: "deliveries", method: "GET", path: "/api/admin/webhooks/1/deliveries", wantStatus: 200, wantContains: []string{`"event_id":"order.paid:1"`, `"status":"pending"`}},
+		{name: "delivery", method: "GET", path: "/api/admin/webhook-deliveries/1", wantStatus: 200, wantContains: []string{`"payload":{"id":"order.paid:1"`}},
+		{name: "delivery unknown", method: "GET", path: "/api/admin/webhook-deliveries/9", wantStatus: 404},
+		{name: "replay", method: "POST", path: "/api/admin/webhook-deliveries/1/replay", wantStatus: 202, wantContains: []string{`{"replayed":1}`}},
+		{name: "replay unknown", method: "POST", path: "/api/admin/webhook-deliveries/9/replay", wantStatus: 404},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			gdb, err := gorm.Open(gormsqlite.Open(filepath.Join(t.TempDir(), "webhooks.db")), &gorm.Config{})
+			require.NoError(t, err)
+			require.NoError(t, gdb.AutoMigrate(&models.WebhookSubscription{}, &models.WebhookDelivery{}, &models.WebhookAttempt{}))
+			db, err := gdb.DB()
+			require.NoError(t, err)
+			defer db.Close()
+
+			// Not running: deliveries stay pending
+			hooks := webhooks.New(webhooks.Options{Store: webhooks.NewSQLStore(db)})
+			_, err = hooks.Subscribe(context.Background(), "https://hooks.example.com/in", []string{"order.paid"}, "warehouse")
+			require.NoError(t, err)
+			hooks.Emit(context.Background(), webhooks.OrderPaid, "1", map[string]any{"order_id": 1})
+
+			app := fiber.New()
+			handlers.NewHandlers(newMockService(), handlers.WithAdminToken(token), handlers.WithWebhooks(hooks)).RegisterRoutes(app)
+
+			req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", "Bearer "+token)
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			assert.Equal(t, tc.wantStatus, resp.StatusCode)
+			body, _ := io.ReadAll(resp.Body)
+			for _, want := range tc.wantContains {
+				assert.Contains(t, string(body), want)
+			}
+			for _, missing := range tc.wantMissing {
+				assert.NotContains(t, string(body), missing)
+			}
+		})
+	}
+}
+
+func TestWebhookSignatureAlerts(t *testing.T) {
+	alerter := alerts.New(alerts.Options{
+		Rules: map[alerts.Kind]alerts.Rule{
+			alerts.WebhookSignature: {Threshold: 3, Window: time.Minute, Throttle: time.Hour},
+		},
+	})
+	creds := integrations.Credentials{Brevo: integrations.BrevoConfig{WebhookToken: "brevo-webhook-token"}}
+	app := fiber.New()
+	handlers.NewHandlers(newMockService(), handlers.WithCredentials(integrations.NewCredentialStore(creds)), handlers.WithAlerter(alerter)).RegisterRoutes(app)
+
+	post := func(token string) int {
+		req := httptest.NewRequest("POST", "/api/email/webhook/brevo?token="+token, strings.NewReader(`{"event":"delivered","email":"a@example.com","message-id":"<m1@smtp-relay.mailin.fr>"}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	// Accepted webhooks do not count
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, 200, post("brevo-webhook-token"))
+	}
+	assert.Equal(t, 0, alerter.Status().Pending)
+
+	for i := 0; i < 2; i++ {
+		assert.Equal(t, 401, post("guess"))
+	}
+	assert.Equal(t, 0, alerter.Status().Pending)
+	assert.Equal(t, 401, post("guess"))
+	assert.Equal(t, 1, alerter.Status().Pending)
+
+	// Counting starts over, and the next alert is throttled for an hour
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, 401, post("guess"))
+	}
+	assert.Equal(t, 1, alerter.Status().Pending)
+}
+
 // =============================================================================
 // DROP HANDLER TESTS
 // =============================================================================
@@ -397,6 +823,66 @@ func TestGetDropStatus_TableDriven(t *testing.T) {
 	}
 }
 
+// failingQueue rejects every Send
+type failingQueue struct{ queue.Queue }
+
+func (failingQueue) Send(ctx context.Context, body []byte) error { return errors.New("sqs unavailable") }
+
+func TestPayOSWebhook_Queued(t *testing.T) {
+	t.Setenv("PAYOS_API_KEY", "test-api-key")
+	t.Setenv("PAYOS_CLIENT_ID", "client-id")
+	body := `{"code":"00","desc":"success","data":{"orderCode":123,"amount":100000,"status":"PAID"}}`
+
+	tests := []struct {
+		name       string
+		queue      func() queue.Queue
+		wantStatus int
+		wantBody   string
+		wantQueued int
+	}{
+		{
+			name:       "success - enqueued, not processed inline",
+			queue:      func() queue.Queue { return queue.NewMemoryQueue() },
+			wantStatus: 200,
+			wantBody:   `{"message":"Payment queued for processing"}`,
+			wantQueued: 1,
+		},
+		{
+			name:       "error - enqueue fails so PayOS retries",
+			queue:      func() queue.Queue { return failingQueue{} },
+			wantStatus: 500,
+			wantBody:   `{"error":"Internal Server Error, please retry"}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockSvc := newMockService()
+			// Inline processing would surface this error as a 500
+			mockSvc.processPaymentErr = errors.New("database is locked")
+			q := tc.queue()
+
+			app := fiber.New()
+			handlers.NewHandlers(mockSvc, handlers.WithPaymentQueue(q)).RegisterRoutes(app)
+
+			req := httptest.NewRequest("POST", "/api/limited-drops/webhook/payos", strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header["x-payos-signature"] = []string{integrations.GeneratePayOSSignature(body)}
+
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			assert.Equal(t, tc.wantStatus, resp.StatusCode)
+			got, _ := io.ReadAll(resp.Body)
+			assert.JSONEq(t, tc.wantBody, string(got))
+			if mq, ok := q.(*queue.MemoryQueue); ok {
+				assert.Equal(t, tc.wantQueued, mq.Len())
+			}
+		})
+	}
+}
+
 func TestPayOSWebhook_TableDriven(t *testing.T) {
 	// Set API Key for signature generation (logic in integrations.GeneratePayOSSignature uses PAYOS_API_KEY)
 	t.Setenv("PAYOS_API_KEY", "test-api-key")
@@ -582,11 +1068,127 @@ func TestPurchaseDrop_TableDriven(t *testing.T) {
 	}
 }
 
+func TestPurchaseDrop_Locale(t *testing.T) {
+	const form = `"quantity":1,"name":"Test","phone":"0909","email":"test@test.com","address":"123","province":"HCM","district":"D1","ward":"W1"`
+	tests := []struct {
+		name           string
+		body           string
+		acceptLanguage string
+		wantLocale     string
+	}{
+		{"form choice wins", `{` + form + `,"locale":"vi"}`, "en-US,en;q=0.9", "vi"},
+		{"browser language", `{` + form + `}`, "en-US,en;q=0.9", "en-US,en;q=0.9"},
+		{"neither", `{` + form + `}`, "", ""},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockSvc := newMockService()
+			app := fiber.New()
+			handlers.NewHandlers(mockSvc).RegisterRoutes(app)
+
+			req := httptest.NewRequest("POST", "/api/drops/1/purchase", strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+			if tc.acceptLanguage != "" {
+				req.Header.Set("Accept-Language", tc.acceptLanguage)
+			}
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			require.Equal(t, 200, resp.StatusCode)
+			require.NotNil(t, mockSvc.lastPurchase)
+			assert.Equal(t, tc.wantLocale, mockSvc.lastPurchase.Locale)
+		})
+	}
+}
+
+func TestPurchaseDrop_AbuseProtection(t *testing.T) {
+	body := `{"quantity":1,"name":"Test","phone":"0909","email":"test@test.com","address":"123","province":"HCM","district":"D1","ward":"W1"}`
+	rules, err := ratelimit.ParseRules([]string{"purchase:phone=3/10m", "orders:order=1/10m"})
+	require.NoError(t, err)
+	pow := botcheck.NewProofOfWork(botcheck.PoWOptions{Key: []byte("0123456789abcdef0123456789abcdef"), Difficulty: 4, TTL: time.Minute})
+
+	mockSvc := newMockService()
+	mockSvc.purchaseRes = &service.PurchaseResult{PaymentURL: "http://pay", OrderCode: 123}
+	app := fiber.New()
+	handlers.NewHandlers(mockSvc,
+		handlers.WithRateLimiter(ratelimit.New(ratelimit.Options{Rules: rules})),
+		handlers.WithBotCheck(pow),
+	).RegisterRoutes(app)
+
+	purchase := func(token string) int {
+		req := httptest.NewRequest("POST", "/api/drops/1/purchase", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set(botcheck.Header, token)
+		}
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	solve := func() string {
+		resp, err := app.Test(httptest.NewRequest("GET", "/api/drops/challenge", nil))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		var puzzle botcheck.Puzzle
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&puzzle))
+		require.Equal(t, "pow", puzzle.Kind)
+		return botcheck.Solve(puzzle.Challenge, puzzle.Difficulty)
+	}
+
+	assert.Equal(t, 403, purchase(""), "no proof of work")
+	token := solve()
+	assert.Equal(t, 200, purchase(token))
+	assert.Equal(t, 403, purchase(token), "a solution buys one request")
+	// Rate limiting runs first: the phone bucket counted all three attempts
+	assert.Equal(t, fiber.StatusTooManyRequests, purchase(solve()))
+
+	// Order lookups are limited per order, however its number is written
+	base32.SetLegacyMaxID(1)
+	defer base32.SetLegacyMaxID(0)
+	number := base32.GenerateOrderNumber(1)
+	mockSvc.orderView = &service.OrderView{OrderNumber: number}
+	for i, tc := range []struct {
+		number string
+		want   int
+	}{
+		{number, 200},
+		{" " + strings.ToLower(number[:7]) + "-" + number[7:] + " ", fiber.StatusTooManyRequests},
+		{"DV-GE======", fiber.StatusTooManyRequests}, // legacy number of order 1
+		{base32.GenerateOrderNumber(2), 200},
+	} {
+		req := httptest.NewRequest("POST", "/api/orders/lookup", strings.NewReader(`{"order_number":"`+tc.number+`","phone_last4":"0909"}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, tc.want, resp.StatusCode, "lookup %d", i)
+		if tc.want == fiber.StatusTooManyRequests {
+			assert.Equal(t, "600", resp.Header.Get(fiber.HeaderRetryAfter))
+		}
+	}
+}
+
+func TestDropChallenge_Disabled(t *testing.T) {
+	app := fiber.New()
+	handlers.NewHandlers(newMockService()).RegisterRoutes(app)
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/api/drops/challenge", nil))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	var puzzle botcheck.Puzzle
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&puzzle))
+	assert.Equal(t, botcheck.Puzzle{Kind: "none"}, puzzle)
+}
+
 // =============================================================================
 // ORDER HANDLER TESTS
 // =============================================================================
 
 func TestGetOrderByID_TableDriven(t *testing.T) {
+const token = "0123456789abcdef0123456789abcdef"
 tests := []struct {
 name       string
 orderID    string
@@ -621,10 +1223,11 @@ mockSvc := newMockService()
 tc.setup(mockSvc)
 
 app := fiber.New()
-h := handlers.NewHandlers(mockSvc)
+h := handlers.NewHandlers(mockSvc, handlers.WithAdminToken(token))
 h.RegisterRoutes(app)
 
-req := httptest.NewRequest("GET", "/api/orders/"+tc.orderID, nil)
+req := httptest.NewRequest("GET", "/api/admin/orders/"+tc.orderID, nil)
+req.Header.Set("Authorization", "Bearer "+token)
 resp, err := app.Test(req)
 require.NoError(t, err)
 defer resp.Body.Close()
@@ -635,6 +1238,7 @@ assert.Equal(t, tc.wantStatus, resp.StatusCode)
 }
 
 func TestGetOrdersByPhone_TableDriven(t *testing.T) {
+const token = "0123456789abcdef0123456789abcdef"
 tests := []struct {
 name       string
 phone      string
@@ -672,14 +1276,15 @@ mockSvc := newMockService()
 tc.setup(mockSvc)
 
 app := fiber.New()
-h := handlers.NewHandlers(mockSvc)
+h := handlers.NewHandlers(mockSvc, handlers.WithAdminToken(token))
 h.RegisterRoutes(app)
 
-url := "/api/orders"
+url := "/api/admin/orders"
 if tc.phone != "" {
 url += "?phone=" + tc.phone
 }
 req := httptest.NewRequest("GET", url, nil)
+req.Header.Set("Authorization", "Bearer "+token)
 resp, err := app.Test(req)
 require.NoError(t, err)
 defer resp.Body.Close()
@@ -689,6 +1294,105 @@ assert.Equal(t, tc.wantStatus, resp.StatusCode)
 }
 }
 
+func TestOrderLookup_PublicRoutes(t *testing.T) {
+	app := fiber.New()
+	handlers.NewHandlers(newMockService()).RegisterRoutes(app)
+
+	// Sequential IDs and phone listings are not public
+	for _, url := range []string{"/api/orders/1", "/api/orders?phone=0909", "/api/admin/orders/1"} {
+		resp, err := app.Test(httptest.NewRequest("GET", url, nil))
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, 404, resp.StatusCode, url)
+	}
+}
+
+func TestLookupOrder_TableDriven(t *testing.T) {
+	tests := []struct {
+		name       string
+		path       string
+		body       string
+		setup      func(*mockService)
+		wantStatus int
+	}{
+		{
+			name: "order number and phone digits",
+			path: "/api/orders/lookup",
+			body: `{"order_number":"DV-GE======","phone_last4":"6789"}`,
+			setup: func(m *mockService) {
+				m.orderView = &service.OrderView{OrderNumber: "DV-GE======", Status: "paid", Phone: "*******789"}
+			},
+			wantStatus: 200,
+		},
+		{
+			name:       "wrong factor or unknown order",
+			path:       "/api/orders/lookup",
+			body:       `{"order_number":"DV-GE======","email":"someone@else.com"}`,
+			setup:      func(m *mockService) { m.lookupErr = service.ErrOrderLookup },
+			wantStatus: 404,
+		},
+		{
+			name:       "no factor",
+			path:       "/api/orders/lookup",
+			body:       `{"order_number":"DV-GE======"}`,
+			setup:      func(m *mockService) { m.lookupErr = service.ErrLookupFactor },
+			wantStatus: 400,
+		},
+		{
+			name:       "no order number",
+			path:       "/api/orders/lookup",
+			body:       `{"email":"a@b.com"}`,
+			setup:      func(m *mockService) {},
+			wantStatus: 400,
+		},
+		{
+			name:       "used link",
+			path:       "/api/orders/lookup",
+			body:       `{"token":"abc"}`,
+			setup:      func(m *mockService) { m.lookupErr = service.ErrInvalidLookupLink },
+			wantStatus: 410,
+		},
+		{
+			name:       "link request",
+			path:       "/api/orders/lookup/link",
+			body:       `{"order_number":"DV-GE======"}`,
+			setup:      func(m *mockService) {},
+			wantStatus: 202,
+		},
+		{
+			name:       "link request without an order number",
+			path:       "/api/orders/lookup/link",
+			body:       `{}`,
+			setup:      func(m *mockService) {},
+			wantStatus: 400,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockSvc := newMockService()
+			tc.setup(mockSvc)
+
+			app := fiber.New()
+			handlers.NewHandlers(mockSvc).RegisterRoutes(app)
+
+			req := httptest.NewRequest("POST", tc.path, strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			assert.Equal(t, tc.wantStatus, resp.StatusCode)
+
+			if tc.wantStatus == 200 {
+				var view service.OrderView
+				require.NoError(t, json.NewDecoder(resp.Body).Decode(&view))
+				assert.Equal(t, *mockSvc.orderView, view)
+				assert.Equal(t, "6789", mockSvc.lastLookup.PhoneLast4)
+			}
+		})
+	}
+}
+
 // =============================================================================
 // SYMBICODE HANDLER TESTS
 // =============================================================================
@@ -718,6 +1422,22 @@ m.symbicodeErr = errors.New("invalid symbicode")
 wantStatus: 400,
 },
 {
+name: "error - forged token",
+body: `{"code":"ARI-RWfomxLTpFZCZhQXQABkR0Zb3VGBQ5mV4wPq2N0x"}`,
+setup: func(m *mockService) {
+m.symbicodeErr = service.ErrForgedSymbicode
+},
+wantStatus: 422,
+},
+{
+name: "error - unknown symbicode",
+body: `{"code":"ARI-RWfomxLTpFZCZhQXQABkR0Zb3VGBQ5mV4wPq2N0x"}`,
+setup: func(m *mockService) {
+m.symbicodeErr = service.ErrUnknownSymbicode
+},
+wantStatus: 404,
+},
+{
 name:       "error - invalid request body",
 body:       "not json",
 setup:      func(m *mockService) {},
@@ -744,3 +1464,407 @@ assert.Equal(t, tc.wantStatus, resp.StatusCode)
 })
 }
 }
+
+func TestVerifySymbicode_ReportsScans(t *testing.T) {
+	mockSvc := newMockService()
+	mockSvc.symbicode = &models.Symbicode{ID: 1, ProductID: 10, IsActivated: 1}
+	mockSvc.scanReport = service.ScanReport{Count: 7, DistinctIPs: 6, DistinctLocations: 2, Suspicious: true, Reasons: []string{service.ReasonManyIPs}}
+
+	app := fiber.New()
+	handlers.NewHandlers(mockSvc).RegisterRoutes(app)
+
+	req := httptest.NewRequest("POST", "/api/symbicode/verify", strings.NewReader(`{"code":"abc123"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "scanner/1.0")
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, 200, resp.StatusCode)
+
+	var body struct {
+		Suspicious bool               `json:"suspicious"`
+		Scans      service.ScanReport `json:"scans"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.True(t, body.Suspicious)
+	assert.Equal(t, 7, body.Scans.Count)
+	assert.Equal(t, []string{service.ReasonManyIPs}, body.Scans.Reasons)
+	assert.Equal(t, "scanner/1.0", mockSvc.lastScan.UserAgent)
+	assert.NotEmpty(t, mockSvc.lastScan.IP)
+}
+
+func TestAdminSymbicodes_TableDriven(t *testing.T) {
+	const token = "0123456789abcdef0123456789abcdef"
+
+	tests := []struct {
+		name       string
+		path       string
+		auth       string
+		wantStatus int
+	}{
+		{"suspicious report", "/api/admin/symbicodes/suspicious?limit=10", "Bearer " + token, 200},
+		{"suspicious report requires auth", "/api/admin/symbicodes/suspicious", "", 401},
+		{"scan history", "/api/admin/symbicodes/42/scans", "Bearer " + token, 200},
+		{"scan history with bad id", "/api/admin/symbicodes/abc/scans", "Bearer " + token, 400},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockSvc := newMockService()
+			mockSvc.suspicious = []service.SuspiciousSymbicode{{Code: "abc", Reasons: []string{service.ReasonManyLocations}}}
+
+			app := fiber.New()
+			handlers.NewHandlers(mockSvc, handlers.WithAdminToken(token)).RegisterRoutes(app)
+
+			req := httptest.NewRequest("GET", tc.path, nil)
+			if tc.auth != "" {
+				req.Header.Set("Authorization", tc.auth)
+			}
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			assert.Equal(t, tc.wantStatus, resp.StatusCode)
+		})
+	}
+}
+
+func TestSymbicodeTransfer_TableDriven(t *testing.T) {
+	tests := []struct {
+		name       string
+		path       string
+		body       string
+		err        error
+		wantStatus int
+		wantReason string
+	}{
+		{"send a code", "/api/symbicode/transfers", `{"code":"abc","phone":"0901234567"}`, nil, 202, ""},
+		{"send a code to someone else", "/api/symbicode/transfers", `{"code":"abc","phone":"0900000000"}`, service.ErrTransferDenied, 403, "transfer_denied"},
+		{"send a code without contact", "/api/symbicode/transfers", `{"code":"abc"}`, service.ErrTransferContact, 400, "contact_required"},
+		{"send a code to both contacts", "/api/symbicode/transfers", `{"code":"abc","phone":"0901234567","email":"a@example.com"}`, service.ErrTransferChannel, 400, "one_contact"},
+		{"offer", "/api/symbicode/transfers/confirm", `{"code":"abc","phone":"0901234567","otp":"493021"}`, nil, 201, ""},
+		{"offer with a wrong code", "/api/symbicode/transfers/confirm", `{"code":"abc","phone":"0901234567","otp":"000000"}`, service.ErrInvalidTransferCode, 403, "invalid_code"},
+		{"offer with a bad body", "/api/symbicode/transfers/confirm", `not json`, nil, 400, ""},
+		{"claim", "/api/symbicode/transfers/claim", `{"token":"claim-token","email":"new@example.com"}`, nil, 200, ""},
+		{"claim with a used token", "/api/symbicode/transfers/claim", `{"token":"claim-token","email":"new@example.com"}`, service.ErrInvalidTransfer, 410, "invalid_transfer"},
+		{"claim with a bad body", "/api/symbicode/transfers/claim", `not json`, nil, 400, ""},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockSvc := newMockService()
+			mockSvc.transferErr = tc.err
+
+			app := fiber.New()
+			handlers.NewHandlers(mockSvc).RegisterRoutes(app)
+
+			req := httptest.NewRequest("POST", tc.path, strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			assert.Equal(t, tc.wantStatus, resp.StatusCode)
+
+			var body map[string]any
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+			if tc.wantReason != "" {
+				assert.Equal(t, tc.wantReason, body["reason"])
+			}
+			switch tc.wantStatus {
+			case 202:
+				assert.Equal(t, "sms", body["channel"])
+				assert.NotContains(t, body, "token", "the offer waits for the code")
+				assert.Equal(t, "0901234567", mockSvc.lastContact.Phone)
+			case 201:
+				assert.Equal(t, "claim-token", body["token"])
+				assert.Equal(t, "0901234567", mockSvc.lastContact.Phone)
+				assert.Equal(t, "493021", mockSvc.lastOTP)
+			case 200:
+				assert.EqualValues(t, 2, body["owners"])
+				assert.NotContains(t, body, "owner_email", "ownership details stay private")
+			}
+		})
+	}
+}
+
+func TestSymbicodeTransfer_RateLimited(t *testing.T) {
+	rules, err := ratelimit.ParseRules([]string{"transfers:ip=3/10m"})
+	require.NoError(t, err)
+	mockSvc := newMockService()
+	mockSvc.transferErr = service.ErrTransferDenied
+	app := fiber.New()
+	handlers.NewHandlers(mockSvc, handlers.WithRateLimiter(ratelimit.New(ratelimit.Options{Rules: rules}))).RegisterRoutes(app)
+
+	// Guessing owners, codes and claim tokens share one bucket
+	for i, tc := range []struct {
+		path string
+		want int
+	}{
+		{"/api/symbicode/transfers", 403},
+		{"/api/symbicode/transfers/confirm", 403},
+		{"/api/symbicode/transfers/claim", 403},
+		{"/api/symbicode/transfers", fiber.StatusTooManyRequests},
+		{"/api/symbicode/transfers/confirm", fiber.StatusTooManyRequests},
+		{"/api/symbicode/transfers/claim", fiber.StatusTooManyRequests},
+	} {
+		req := httptest.NewRequest("POST", tc.path, strings.NewReader(`{"code":"abc","token":"t","phone":"0900000000"}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, tc.want, resp.StatusCode, "request %d", i)
+	}
+}
+
+func TestAdminSymbicodeBatches_TableDriven(t *testing.T) {
+	const token = "0123456789abcdef0123456789abcdef"
+	tags := []labels.Label{{ID: 1, Code: "01a1526e-489c-789c-a145-68e331f317f7", Token: "tok", URL: "https://shop.example/verify?t=tok"}}
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		body        string
+		err         error
+		wantStatus  int
+		wantType    string
+		wantContent string
+	}{
+		{"generate", "POST", "/api/admin/symbicodes/batches", `{"product_id":10,"count":50}`, nil, 201, "application/json", `"count":50`},
+		{"generate without product", "POST", "/api/admin/symbicodes/batches", `{"count":50}`, nil, 400, "", ""},
+		{"generate too many", "POST", "/api/admin/symbicodes/batches", `{"product_id":10,"count":50000}`, service.ErrBatchSize, 400, "", ""},
+		{"export csv", "GET", "/api/admin/symbicodes/batches/1/labels", "", nil, 200, "text/csv", "id,code,token,url"},
+		{"export svg", "GET", "/api/admin/symbicodes/batches/1/labels?format=svg", "", nil, 200, "image/svg+xml", "<svg"},
+		{"export pdf", "GET", "/api/admin/symbicodes/batches/1/labels?format=pdf", "", nil, 200, "application/pdf", "%PDF"},
+		{"export unknown format", "GET", "/api/admin/symbicodes/batches/1/labels?format=png", "", nil, 400, "", ""},
+		{"export unknown batch", "GET", "/api/admin/symbicodes/batches/9/labels", "", service.ErrBatchNotFound, 404, "", ""},
+		{"bind", "POST", "/api/admin/symbicodes/bind", `{"code":"tok","order_id":7}`, nil, 200, "application/json", `"OrderID":7`},
+		{"bind twice", "POST", "/api/admin/symbicodes/bind", `{"code":"tok","order_id":7}`, service.ErrSymbicodeBound, 409, "", ""},
+		{"bind to an unpaid order", "POST", "/api/admin/symbicodes/bind", `{"code":"tok","order_id":7}`, service.ErrOrderNotPaid, 409, "", ""},
+		{"bind past the order quantity", "POST", "/api/admin/symbicodes/bind", `{"code":"tok","order_id":7}`, service.ErrOrderFullyBound, 409, "", ""},
+		{"bind another product's tag", "POST", "/api/admin/symbicodes/bind", `{"code":"tok","order_id":7}`, service.ErrProductMismatch, 409, "", ""},
+		{"bind a forged tag", "POST", "/api/admin/symbicodes/bind", `{"code":"tok","order_id":7}`, service.ErrForgedSymbicode, 422, "", ""},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockSvc := newMockService()
+			mockSvc.batchErr = tc.err
+			mockSvc.labels = tags
+
+			app := fiber.New()
+			handlers.NewHandlers(mockSvc, handlers.WithAdminToken(token)).RegisterRoutes(app)
+
+			req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", "Bearer "+token)
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			assert.Equal(t, tc.wantStatus, resp.StatusCode)
+
+			if tc.wantType != "" {
+				body, _ := io.ReadAll(resp.Body)
+				assert.Contains(t, resp.Header.Get("Content-Type"), tc.wantType)
+				assert.Contains(t, string(body), tc.wantContent)
+			}
+		})
+	}
+}
+
+// =============================================================================
+// EMAIL DELIVERY HANDLER TESTS
+// =============================================================================
+
+const testResendSecret = "whsec_c2VjcmV0LXNpZ25pbmcta2V5LWZvci10ZXN0cw=="
+
+// signResend signs body the way Svix does for Resend webhooks
+func signResend(t *testing.T, id, timestamp, body string) string {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(testResendSecret, "whsec_"))
+	require.NoError(t, err)
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(id + "." + timestamp + "." + body))
+	return "v1," + base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestEmailWebhooks_TableDriven(t *testing.T) {
+	const brevoToken = "brevo-webhook-token"
+	brevoBounce := `{"event":"hard_bounce","email":"a@example.com","message-id":"<m1@smtp-relay.mailin.fr>","reason":"mailbox does not exist"}`
+	resendDelivered := `{"type":"email.delivered","created_at":"2026-01-02T03:04:05Z","data":{"email_id":"re_1","to":["a@example.com"]}}`
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	stale := strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+
+	tests := []struct {
+		name       string
+		creds      integrations.Credentials
+		path       string
+		body       string
+		headers    map[string]string
+		serviceErr error
+		wantStatus int
+		wantEvents int
+	}{
+		{
+			name:       "brevo - bearer token",
+			creds:      integrations.Credentials{Brevo: integrations.BrevoConfig{WebhookToken: brevoToken}},
+			path:       "/api/email/webhook/brevo",
+			body:       brevoBounce,
+			headers:    map[string]string{"Authorization": "Bearer " + brevoToken},
+			wantStatus: 200,
+			wantEvents: 1,
+		},
+		{
+			name:       "brevo - query token",
+			creds:      integrations.Credentials{Brevo: integrations.BrevoConfig{WebhookToken: brevoToken}},
+			path:       "/api/email/webhook/brevo?token=" + brevoToken,
+			body:       brevoBounce,
+			wantStatus: 200,
+			wantEvents: 1,
+		},
+		{
+			name:       "brevo - wrong token",
+			creds:      integrations.Credentials{Brevo: integrations.BrevoConfig{WebhookToken: brevoToken}},
+			path:       "/api/email/webhook/brevo?token=guess",
+			body:       brevoBounce,
+			wantStatus: 401,
+		},
+		{
+			name:       "brevo - not configured",
+			path:       "/api/email/webhook/brevo?token=",
+			body:       brevoBounce,
+			wantStatus: 503,
+		},
+		{
+			name:       "brevo - untracked event",
+			creds:      integrations.Credentials{Brevo: integrations.BrevoConfig{WebhookToken: brevoToken}},
+			path:       "/api/email/webhook/brevo?token=" + brevoToken,
+			body:       `{"event":"opened","email":"a@example.com","message-id":"<m1@smtp-relay.mailin.fr>"}`,
+			wantStatus: 200,
+		},
+		{
+			name:       "brevo - service error asks for a retry",
+			creds:      integrations.Credentials{Brevo: integrations.BrevoConfig{WebhookToken: brevoToken}},
+			path:       "/api/email/webhook/brevo?token=" + brevoToken,
+			body:       brevoBounce,
+			serviceErr: errors.New("database locked"),
+			wantStatus: 500,
+		},
+		{
+			name:  "resend - signed",
+			creds: integrations.Credentials{Resend: integrations.ResendConfig{WebhookSecret: testResendSecret}},
+			path:  "/api/email/webhook/resend",
+			body:  resendDelivered,
+			headers: map[string]string{
+				"svix-id":        "msg_1",
+				"svix-timestamp": now,
+				"svix-signature": "v1,bm90LXRoaXMtb25l " + signResend(t, "msg_1", now, resendDelivered),
+			},
+			wantStatus: 200,
+			wantEvents: 1,
+		},
+		{
+			name:  "resend - body changed after signing",
+			creds: integrations.Credentials{Resend: integrations.ResendConfig{WebhookSecret: testResendSecret}},
+			path:  "/api/email/webhook/resend",
+			body:  strings.Replace(resendDelivered, "delivered", "bounced", 1),
+			headers: map[string]string{
+				"svix-id":        "msg_1",
+				"svix-timestamp": now,
+				"svix-signature": signResend(t, "msg_1", now, resendDelivered),
+			},
+			wantStatus: 401,
+		},
+		{
+			name:  "resend - stale timestamp",
+			creds: integrations.Credentials{Resend: integrations.ResendConfig{WebhookSecret: testResendSecret}},
+			path:  "/api/email/webhook/resend",
+			body:  resendDelivered,
+			headers: map[string]string{
+				"svix-id":        "msg_1",
+				"svix-timestamp": stale,
+				"svix-signature": signResend(t, "msg_1", stale, resendDelivered),
+			},
+			wantStatus: 401,
+		},
+		{
+			name:       "resend - not configured",
+			path:       "/api/email/webhook/resend",
+			body:       resendDelivered,
+			wantStatus: 503,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockSvc := newMockService()
+			mockSvc.emailEventErr = tc.serviceErr
+
+			app := fiber.New()
+			handlers.NewHandlers(mockSvc, handlers.WithCredentials(integrations.NewCredentialStore(tc.creds))).RegisterRoutes(app)
+
+			req := httptest.NewRequest("POST", tc.path, strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+			for k, v := range tc.headers {
+				req.Header.Set(k, v)
+			}
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			assert.Equal(t, tc.wantStatus, resp.StatusCode)
+			assert.Len(t, mockSvc.emailEvents, tc.wantEvents)
+		})
+	}
+}
+
+func TestAdminEmails_TableDriven(t *testing.T) {
+	const token = "0123456789abcdef0123456789abcdef"
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		auth           string
+		suppressionErr error
+		wantStatus     int
+		wantBody       string
+	}{
+		{name: "order emails", method: "GET", path: "/api/admin/orders/7/emails", auth: "Bearer " + token, wantStatus: 200, wantBody: `"status":"bounced"`},
+		{name: "order emails require auth", method: "GET", path: "/api/admin/orders/7/emails", wantStatus: 401},
+		{name: "order emails - unknown order", method: "GET", path: "/api/admin/orders/8/emails", auth: "Bearer " + token, wantStatus: 404},
+		{name: "order emails - bad id", method: "GET", path: "/api/admin/orders/abc/emails", auth: "Bearer " + token, wantStatus: 400},
+		{name: "suppression list", method: "GET", path: "/api/admin/email-suppressions?limit=5", auth: "Bearer " + token, wantStatus: 200, wantBody: `"email":"bounce@example.com"`},
+		{name: "remove suppression", method: "DELETE", path: "/api/admin/email-suppressions/bounce%40example.com", auth: "Bearer " + token, wantStatus: 204},
+		{name: "remove unknown suppression", method: "DELETE", path: "/api/admin/email-suppressions/ok@example.com", auth: "Bearer " + token, wantStatus: 404},
+		{name: "remove suppression - not an address", method: "DELETE", path: "/api/admin/email-suppressions/nobody", auth: "Bearer " + token, wantStatus: 400},
+		{name: "remove suppression - service error", method: "DELETE", path: "/api/admin/email-suppressions/bounce@example.com", auth: "Bearer " + token, suppressionErr: errors.New("database locked"), wantStatus: 500},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockSvc := newMockService()
+			mockSvc.suppressionErr = tc.suppressionErr
+			mockSvc.orderEmails = &service.OrderEmails{
+				OrderID:    7,
+				Messages:   []models.EmailMessage{{Provider: "brevo", MessageID: "<m1@smtp-relay.mailin.fr>", Email: "bounce@example.com", Status: models.EmailBounced, OrderID: 7}},
+				Suppressed: []string{"bounce@example.com"},
+			}
+
+			app := fiber.New()
+			handlers.NewHandlers(mockSvc, handlers.WithAdminToken(token)).RegisterRoutes(app)
+
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			if tc.auth != "" {
+				req.Header.Set("Authorization", tc.auth)
+			}
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			assert.Equal(t, tc.wantStatus, resp.StatusCode)
+			if tc.wantBody != "" {
+				body, _ := io.ReadAll(resp.Body)
+				assert.Contains(t, string(body), tc.wantBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package health_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"ecommerce-backend/internal/database"
+	"ecommerce-backend/internal/health"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func run(t *testing.T, check health.Check) health.Result {
+	t.Helper()
+	return health.NewChecker(time.Second, check).Run(context.Background()).Checks[check.Name]
+}
+
+func TestChecker_Aggregation_TableDriven(t *testing.T) {
+	ok := func(context.Context) (string, error) { return "", nil }
+	broken := func(context.Context) (string, error) { return "", errors.New("broken") }
+	slow := func(ctx context.Context) (string, error) {
+		<-ctx.Done()
+		return "", ctx.Err()
+	}
+
+	tests := []struct {
+		name   string
+		checks []health.Check
+		want   string
+		ready  bool
+	}{
+		{"no checks", nil, "ok", true},
+		{"all pass", []health.Check{{Name: "a", Critical: true, Run: ok}, {Name: "b", Run: ok}}, "ok", true},
+		{"non-critical failure", []health.Check{{Name: "a", Critical: true, Run: ok}, {Name: "b", Run: broken}}, "degraded", true},
+		{"critical failure", []health.Check{{Name: "a", Critical: true, Run: broken}, {Name: "b", Run: broken}}, "fail", false},
+		{"critical timeout", []health.Check{{Name: "a", Critical: true, Run: slow}}, "fail", false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			report := health.NewChecker(20*time.Millisecond, tc.checks...).Run(context.Background())
+			assert.Equal(t, tc.want, report.Status)
+			assert.Equal(t, tc.ready, report.Ready())
+			assert.Len(t, report.Checks, len(tc.checks))
+		})
+	}
+}
+
+func TestCached_RunsOncePerTTL(t *testing.T) {
+	var calls atomic.Int32
+	check := health.Cached(health.Check{Name: "payos", Run: func(context.Context) (string, error) {
+		calls.Add(1)
+		return "", errors.New("down")
+	}}, time.Hour)
+
+	for i := 0; i < 3; i++ {
+		res := run(t, check)
+		assert.Equal(t, "warn", res.Status)
+		assert.Equal(t, "down", res.Error)
+	}
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestWALSize_TableDriven(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "app.db")
+
+	tests := []struct {
+		name    string
+		walSize int
+		max     int64
+		want    string
+	}{
+		{"no wal file", -1, 1 << 20, "ok"},
+		{"under limit", 1024, 1 << 20, "ok"},
+		{"over limit warns", 2 << 20, 1 << 20, "warn"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			os.Remove(dbPath + "-wal")
+			if tc.walSize >= 0 {
+				require.NoError(t, os.WriteFile(dbPath+"-wal", make([]byte, tc.walSize), 0o600))
+			}
+			assert.Equal(t, tc.want, run(t, health.WALSize(dbPath, tc.max)).Status)
+		})
+	}
+}
+
+func TestDiskSpace(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "app.db")
+
+	res := run(t, health.DiskSpace(dbPath, 0))
+	assert.Equal(t, "ok", res.Status)
+	assert.Contains(t, res.Detail, "MB free")
+
+	assert.Equal(t, "fail", run(t, health.DiskSpace(dbPath, 1<<62)).Status)
+}
+
+func openDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "app.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestPoolAndSchemaVersion(t *testing.T) {
+	db := openDB(t)
+
+	assert.Equal(t, "ok", run(t, health.Pool("writer", db)).Status)
+
+	check := health.SchemaVersion(db, database.SchemaVersion)
+	res := run(t, check)
+	assert.Equal(t, "fail", res.Status, "a database that was never migrated is not ready")
+
+	require.NoError(t, database.SetSchemaVersion(db, database.SchemaVersion))
+	res = run(t, check)
+	assert.Equal(t, "ok", res.Status)
+
+	closed := openDB(t)
+	closed.Close()
+	assert.Equal(t, "fail", run(t, health.Pool("reader", closed)).Status)
+}
+
+func TestReachable(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodHead, r.Method)
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	url := srv.URL
+
+	res := run(t, health.Reachable("brevo", url, nil))
+	assert.Equal(t, "ok", res.Status, "any HTTP answer counts")
+	assert.Equal(t, "HTTP 401", res.Detail)
+
+	srv.Close()
+	assert.Equal(t, "warn", run(t, health.Reachable("brevo", url, nil)).Status)
+}
@@ -0,0 +1,226 @@
+package integrations_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"ecommerce-backend/internal/integrations"
+	"ecommerce-backend/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryEmailLog is an in-memory integrations.EmailLog
+type memoryEmailLog struct {
+	mu          sync.Mutex
+	recorded    []models.EmailMessage
+	suppressed  map[string]bool
+	lookupError error
+}
+
+func (l *memoryEmailLog) RecordEmail(ctx context.Context, msg *models.EmailMessage) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.recorded = append(l.recorded, *msg)
+	return nil
+}
+
+func (l *memoryEmailLog) SuppressedEmails(ctx context.Context, addresses []string) ([]string, error) {
+	if l.lookupError != nil {
+		return nil, l.lookupError
+	}
+	var out []string
+	for _, a := range addresses {
+		if l.suppressed[a] {
+			out = append(out, a)
+		}
+	}
+	return out, nil
+}
+
+func TestEmailer_RecordsDeliveries(t *testing.T) {
+	brevo := fakeBrevo(t)
+	deliveries := &memoryEmailLog{}
+	cfg := integrations.DefaultEmailerConfig()
+	cfg.Deliveries = deliveries
+	e := newTestEmailer(brevo, nil, cfg)
+
+	ctx := integrations.WithEmailOrder(context.Background(), 42)
+	_, _, err := e.Send(ctx, []string{"a@example.com", "b@example.com"}, testMessage("one"))
+	require.NoError(t, err)
+
+	require.Len(t, deliveries.recorded, 2)
+	for i, want := range []string{"a@example.com", "b@example.com"} {
+		msg := deliveries.recorded[i]
+		assert.Equal(t, want, msg.Email)
+		assert.Equal(t, integrations.ProviderBrevo, msg.Provider)
+		assert.Equal(t, "<202601.1@smtp-relay.mailin.fr>", msg.MessageID)
+		assert.Equal(t, "test", msg.Template)
+		assert.Equal(t, models.EmailSent, msg.Status)
+		assert.Equal(t, uint64(42), msg.OrderID)
+	}
+}
+
+func TestEmailer_SkipsSuppressedRecipients(t *testing.T) {
+	brevo := fakeBrevo(t)
+	deliveries := &memoryEmailLog{suppressed: map[string]bool{"bounce@example.com": true}}
+	cfg := integrations.DefaultEmailerConfig()
+	cfg.Deliveries = deliveries
+	e := newTestEmailer(brevo, nil, cfg)
+
+	_, _, err := e.Send(context.Background(), []string{"bounce@example.com", "ok@example.com"}, testMessage("partial"))
+	require.NoError(t, err)
+	require.Len(t, deliveries.recorded, 1)
+	assert.Equal(t, "ok@example.com", deliveries.recorded[0].Email)
+
+	_, _, err = e.Send(context.Background(), []string{"bounce@example.com"}, testMessage("none"))
+	assert.ErrorIs(t, err, integrations.ErrEmailSuppressed)
+	assert.Equal(t, 1, brevo.calls(), "nothing is sent when every recipient is suppressed")
+	assert.True(t, statusOf(t, e, integrations.ProviderBrevo).Healthy, "a suppressed send is not a provider failure")
+
+	deliveries.lookupError = errors.New("database locked")
+	_, _, err = e.Send(context.Background(), []string{"bounce@example.com"}, testMessage("lookup failed"))
+	require.NoError(t, err, "a failed lookup sends anyway")
+	assert.Equal(t, 2, brevo.calls())
+}
+
+func TestParseBrevoEvents_TableDriven(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		wantStatus   []string
+		wantSuppress bool
+		wantErr      bool
+	}{
+		{name: "delivered", body: `{"event":"delivered","email":"a@example.com","message-id":"<m1@relay>"}`, wantStatus: []string{models.EmailDelivered}},
+		{name: "hard bounce", body: `{"event":"hard_bounce","email":"a@example.com","message-id":"<m1@relay>","reason":"unknown user"}`, wantStatus: []string{models.EmailBounced}, wantSuppress: true},
+		{name: "invalid email", body: `{"event":"invalid_email","email":"a@example","message-id":"<m1@relay>"}`, wantStatus: []string{models.EmailBounced}, wantSuppress: true},
+		{name: "blocked", body: `{"event":"blocked","email":"a@example.com","message-id":"<m1@relay>"}`, wantStatus: []string{models.EmailBounced}, wantSuppress: true},
+		{name: "soft bounce", body: `{"event":"soft_bounce","email":"a@example.com","message-id":"<m1@relay>"}`, wantStatus: []string{models.EmailDeferred}},
+		{name: "spam complaint", body: `{"event":"spam","email":"a@example.com","message-id":"<m1@relay>"}`, wantStatus: []string{models.EmailComplained}, wantSuppress: true},
+		{name: "opens are not tracked", body: `{"event":"opened","email":"a@example.com","message-id":"<m1@relay>"}`},
+		{name: "batch", body: `[{"event":"request","message-id":"<m1@relay>"},{"event":"delivered","email":"a@example.com","message-id":"<m1@relay>"}]`, wantStatus: []string{models.EmailDelivered}},
+		{name: "invalid json", body: `{`, wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			events, err := integrations.ParseBrevoEvents([]byte(tc.body))
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, events, len(tc.wantStatus))
+			for i, ev := range events {
+				assert.Equal(t, tc.wantStatus[i], ev.Status)
+				assert.Equal(t, tc.wantSuppress, ev.Suppress)
+				assert.Equal(t, integrations.ProviderBrevo, ev.Provider)
+				assert.Equal(t, "<m1@relay>", ev.MessageID)
+			}
+		})
+	}
+}
+
+func TestParseBrevoEvents_BracketsMessageID(t *testing.T) {
+	events, err := integrations.ParseBrevoEvents([]byte(`{"event":"hard_bounce","email":"a@example.com","message-id":"m1@relay","reason":"unknown user","ts_event":1767323045}`))
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, "<m1@relay>", events[0].MessageID, "rows store the send API's bracketed form")
+	assert.Equal(t, "unknown user", events[0].Detail)
+	assert.Equal(t, time.Unix(1767323045, 0).UTC(), events[0].At)
+}
+
+func TestParseResendEvents_TableDriven(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		wantStatus   string
+		wantSuppress bool
+		wantEvents   int
+	}{
+		{name: "delivered", body: `{"type":"email.delivered","data":{"email_id":"re_1","to":["a@example.com"]}}`, wantStatus: models.EmailDelivered, wantEvents: 1},
+		{name: "delayed", body: `{"type":"email.delivery_delayed","data":{"email_id":"re_1","to":["a@example.com"]}}`, wantStatus: models.EmailDeferred, wantEvents: 1},
+		{name: "permanent bounce", body: `{"type":"email.bounced","data":{"email_id":"re_1","to":["a@example.com"],"bounce":{"type":"Permanent","message":"unknown user"}}}`, wantStatus: models.EmailBounced, wantSuppress: true, wantEvents: 1},
+		{name: "transient bounce", body: `{"type":"email.bounced","data":{"email_id":"re_1","to":["a@example.com"],"bounce":{"type":"Transient","message":"mailbox full"}}}`, wantStatus: models.EmailDeferred, wantEvents: 1},
+		{name: "complaint to two recipients", body: `{"type":"email.complained","data":{"email_id":"re_1","to":["a@example.com","b@example.com"]}}`, wantStatus: models.EmailComplained, wantSuppress: true, wantEvents: 2},
+		{name: "sent is not tracked", body: `{"type":"email.sent","data":{"email_id":"re_1","to":["a@example.com"]}}`},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			events, err := integrations.ParseResendEvents([]byte(tc.body))
+			require.NoError(t, err)
+			require.Len(t, events, tc.wantEvents)
+			for _, ev := range events {
+				assert.Equal(t, tc.wantStatus, ev.Status)
+				assert.Equal(t, tc.wantSuppress, ev.Suppress)
+				assert.Equal(t, integrations.ProviderResend, ev.Provider)
+				assert.Equal(t, "re_1", ev.MessageID)
+			}
+		})
+	}
+
+	_, err := integrations.ParseResendEvents([]byte(`not json`))
+	assert.Error(t, err)
+}
+
+func TestVerifyResendWebhook_TableDriven(t *testing.T) {
+	key := []byte("resend-test-signing-key")
+	secret := "whsec_" + base64.StdEncoding.EncodeToString(key)
+	body := []byte(`{"type":"email.delivered"}`)
+	now := time.Unix(1767323045, 0)
+	ts := strconv.FormatInt(now.Unix(), 10)
+
+	sign := func(id, timestamp string, body []byte) string {
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(id + "." + timestamp + "."))
+		mac.Write(body)
+		return "v1," + base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	}
+
+	tests := []struct {
+		name       string
+		secret     string
+		id         string
+		timestamp  string
+		signatures string
+		body       []byte
+		wantErr    bool
+	}{
+		{name: "valid", secret: secret, id: "msg_1", timestamp: ts, signatures: sign("msg_1", ts, body), body: body},
+		{name: "one of several signatures", secret: secret, id: "msg_1", timestamp: ts, signatures: "v1,Zm9v " + sign("msg_1", ts, body), body: body},
+		{name: "tampered body", secret: secret, id: "msg_1", timestamp: ts, signatures: sign("msg_1", ts, body), body: []byte(`{"type":"email.bounced"}`), wantErr: true},
+		{name: "other message id", secret: secret, id: "msg_2", timestamp: ts, signatures: sign("msg_1", ts, body), body: body, wantErr: true},
+		{name: "unknown signature version", secret: secret, id: "msg_1", timestamp: ts, signatures: strings.Replace(sign("msg_1", ts, body), "v1,", "v2,", 1), body: body, wantErr: true},
+		{name: "replayed too late", secret: secret, id: "msg_1", timestamp: "1767322000", signatures: sign("msg_1", "1767322000", body), body: body, wantErr: true},
+		{name: "missing headers", secret: secret, body: body, wantErr: true},
+		{name: "malformed secret", secret: "whsec_%%%", id: "msg_1", timestamp: ts, signatures: sign("msg_1", ts, body), body: body, wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := integrations.VerifyResendWebhook(tc.secret, tc.id, tc.timestamp, tc.signatures, tc.body, now)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestCheckWebhookToken(t *testing.T) {
+	assert.True(t, integrations.CheckWebhookToken("secret", "secret"))
+	assert.False(t, integrations.CheckWebhookToken("secret", "Secret"))
+	assert.False(t, integrations.CheckWebhookToken("", ""), "an unset token never matches")
+}
@@ -0,0 +1,324 @@
+package integrations_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"ecommerce-backend/internal/emails"
+	"ecommerce-backend/internal/integrations"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeEmailAPI is a local stand-in for the Brevo or Resend send endpoint.
+// It answers with status and body, and records the subjects it received.
+type fakeEmailAPI struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	status   int
+	body     string
+	subjects []string
+}
+
+func newFakeEmailAPI(t *testing.T, path string, status int, body string) *fakeEmailAPI {
+	f := &fakeEmailAPI{status: status, body: body}
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, path, r.URL.Path)
+		var req struct {
+			Subject string `json:"subject"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.subjects = append(f.subjects, req.Subject)
+		w.WriteHeader(f.status)
+		w.Write([]byte(f.body))
+	}))
+	t.Cleanup(f.Close)
+	return f
+}
+
+// fakeBrevo and fakeResend accept every message
+func fakeBrevo(t *testing.T) *fakeEmailAPI {
+	return newFakeEmailAPI(t, "/smtp/email", http.StatusCreated, `{"messageId":"<202601.1@smtp-relay.mailin.fr>"}`)
+}
+
+func fakeResend(t *testing.T) *fakeEmailAPI {
+	return newFakeEmailAPI(t, "/emails", http.StatusOK, `{"id":"re_123"}`)
+}
+
+func (f *fakeEmailAPI) respond(status int, body string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.status, f.body = status, body
+}
+
+func (f *fakeEmailAPI) calls() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.subjects)
+}
+
+// newTestEmailer sends through brevo, then resend; a nil fake leaves that
+// provider unconfigured
+func newTestEmailer(brevo, resend *fakeEmailAPI, cfg integrations.EmailerConfig) *integrations.Emailer {
+	var creds integrations.Credentials
+	if brevo != nil {
+		creds.Brevo = integrations.BrevoConfig{APIKey: "brevo-key", BaseURL: brevo.URL}
+	}
+	if resend != nil {
+		creds.Resend = integrations.ResendConfig{APIKey: "resend-key", BaseURL: resend.URL}
+	}
+	e, err := integrations.NewEmailerWithCredentials(integrations.NewCredentialStore(creds), cfg, nil)
+	if err != nil {
+		panic(err)
+	}
+	return e
+}
+
+func testMessage(subject string) emails.Message {
+	return emails.Message{Template: "test", Subject: subject, HTML: "<p>hi</p>", Text: "hi\n"}
+}
+
+func statusOf(t *testing.T, e *integrations.Emailer, name string) integrations.ProviderStatus {
+	for _, st := range e.Status() {
+		if st.Name == name {
+			return st
+		}
+	}
+	t.Fatalf("no status for %s", name)
+	return integrations.ProviderStatus{}
+}
+
+func TestEmailer_PrimaryDelivers(t *testing.T) {
+	brevo, resend := fakeBrevo(t), fakeResend(t)
+	e := newTestEmailer(brevo, resend, integrations.DefaultEmailerConfig())
+
+	provider, id, err := e.Send(context.Background(), []string{"a@b.c"}, testMessage("one"))
+	require.NoError(t, err)
+	assert.Equal(t, integrations.ProviderBrevo, provider)
+	assert.Equal(t, "<202601.1@smtp-relay.mailin.fr>", id)
+	assert.Equal(t, 1, brevo.calls())
+	assert.Equal(t, 0, resend.calls())
+
+	st := statusOf(t, e, integrations.ProviderBrevo)
+	assert.True(t, st.Healthy)
+	assert.Equal(t, 1, st.SentToday)
+	assert.Equal(t, integrations.DefaultBrevoDailyLimit, st.DailyLimit)
+	assert.NotNil(t, st.LastSuccess)
+}
+
+func TestEmailer_FailsOverOnError(t *testing.T) {
+	brevo, resend := fakeBrevo(t), fakeResend(t)
+	brevo.respond(http.StatusInternalServerError, `{}`)
+	e := newTestEmailer(brevo, resend, integrations.DefaultEmailerConfig())
+
+	provider, id, err := e.Send(context.Background(), []string{"a@b.c"}, testMessage("one"))
+	require.NoError(t, err)
+	assert.Equal(t, integrations.ProviderResend, provider)
+	assert.Equal(t, "re_123", id)
+	assert.Equal(t, []string{"one"}, resend.subjects)
+
+	// Below the threshold Brevo stays first in line
+	st := statusOf(t, e, integrations.ProviderBrevo)
+	assert.True(t, st.Healthy)
+	assert.Equal(t, 1, st.Failures)
+	assert.Contains(t, st.LastError, "500")
+}
+
+func TestEmailer_CooldownAfterThreshold(t *testing.T) {
+	brevo, resend := fakeBrevo(t), fakeResend(t)
+	brevo.respond(http.StatusBadGateway, `{}`)
+	cfg := integrations.DefaultEmailerConfig()
+	cfg.FailureThreshold = 2
+	cfg.Cooldown = time.Hour
+	e := newTestEmailer(brevo, resend, cfg)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		_, _, err := e.Send(ctx, []string{"a@b.c"}, testMessage("fail"))
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 2, brevo.calls())
+
+	st := statusOf(t, e, integrations.ProviderBrevo)
+	assert.False(t, st.Healthy)
+	require.NotNil(t, st.DownUntil)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), *st.DownUntil, time.Minute)
+
+	// Brevo is out of rotation: Resend goes first and Brevo is not called
+	brevo.respond(http.StatusCreated, `{"messageId":1}`)
+	provider, _, err := e.Send(ctx, []string{"a@b.c"}, testMessage("skip"))
+	require.NoError(t, err)
+	assert.Equal(t, integrations.ProviderResend, provider)
+	assert.Equal(t, 2, brevo.calls())
+}
+
+func TestEmailer_DegradedProviderIsLastResort(t *testing.T) {
+	brevo, resend := fakeBrevo(t), fakeResend(t)
+	brevo.respond(http.StatusInternalServerError, `{}`)
+	cfg := integrations.DefaultEmailerConfig()
+	cfg.FailureThreshold = 1
+	e := newTestEmailer(brevo, resend, cfg)
+	ctx := context.Background()
+
+	_, _, err := e.Send(ctx, []string{"a@b.c"}, testMessage("one"))
+	require.NoError(t, err)
+	require.False(t, statusOf(t, e, integrations.ProviderBrevo).Healthy)
+
+	// Resend now fails too; Brevo, cooling down but recovered, still delivers
+	resend.respond(http.StatusInternalServerError, `{"message":"down"}`)
+	brevo.respond(http.StatusCreated, `{"messageId":42}`)
+	provider, id, err := e.Send(ctx, []string{"a@b.c"}, testMessage("two"))
+	require.NoError(t, err)
+	assert.Equal(t, integrations.ProviderBrevo, provider)
+	assert.Equal(t, "42", id)
+	assert.True(t, statusOf(t, e, integrations.ProviderBrevo).Healthy)
+}
+
+func TestEmailer_QuotaExhaustion(t *testing.T) {
+	brevo, resend := fakeBrevo(t), fakeResend(t)
+	brevo.respond(http.StatusPaymentRequired, `{"code":"not_enough_credits"}`)
+	e := newTestEmailer(brevo, resend, integrations.DefaultEmailerConfig())
+
+	provider, _, err := e.Send(context.Background(), []string{"a@b.c"}, testMessage("one"))
+	require.NoError(t, err)
+	assert.Equal(t, integrations.ProviderResend, provider)
+
+	st := statusOf(t, e, integrations.ProviderBrevo)
+	assert.False(t, st.Healthy)
+	assert.True(t, st.Quota)
+	require.NotNil(t, st.DownUntil)
+	y, m, d := time.Now().UTC().Date()
+	assert.Equal(t, time.Date(y, m, d+1, 0, 0, 0, 0, time.UTC), *st.DownUntil)
+
+	detail, err := e.Check(context.Background())
+	require.NoError(t, err)
+	assert.Contains(t, detail, "brevo 0/300 quota exhausted")
+	assert.Contains(t, detail, "resend 1/100")
+}
+
+func TestEmailer_ResendQuotaError(t *testing.T) {
+	resend := fakeResend(t)
+	resend.respond(http.StatusTooManyRequests, `{"name":"daily_quota_exceeded","message":"You have reached your daily email sending quota."}`)
+	e := newTestEmailer(nil, resend, integrations.DefaultEmailerConfig())
+
+	_, _, err := e.Send(context.Background(), []string{"a@b.c"}, testMessage("one"))
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, integrations.ErrEmailQuota))
+
+	// A plain rate limit is an ordinary failure
+	resend.respond(http.StatusTooManyRequests, `{"name":"rate_limit_exceeded"}`)
+	e = newTestEmailer(nil, resend, integrations.DefaultEmailerConfig())
+	_, _, err = e.Send(context.Background(), []string{"a@b.c"}, testMessage("two"))
+	require.Error(t, err)
+	assert.False(t, errors.Is(err, integrations.ErrEmailQuota))
+}
+
+func TestEmailer_DailyLimit(t *testing.T) {
+	brevo, resend := fakeBrevo(t), fakeResend(t)
+	cfg := integrations.DefaultEmailerConfig()
+	cfg.DailyLimits[integrations.ProviderBrevo] = 2
+	e := newTestEmailer(brevo, resend, cfg)
+	ctx := context.Background()
+
+	var providers []string
+	for i := 0; i < 3; i++ {
+		provider, _, err := e.Send(ctx, []string{"a@b.c"}, testMessage("n"))
+		require.NoError(t, err)
+		providers = append(providers, provider)
+	}
+	assert.Equal(t, []string{"brevo", "brevo", "resend"}, providers)
+
+	detail, err := e.Check(ctx)
+	require.NoError(t, err)
+	assert.Contains(t, detail, "brevo 2/2 at daily limit")
+}
+
+func TestEmailer_AllProvidersFail(t *testing.T) {
+	brevo, resend := fakeBrevo(t), fakeResend(t)
+	brevo.respond(http.StatusPaymentRequired, `{}`)
+	resend.respond(http.StatusInternalServerError, `{"message":"boom"}`)
+	e := newTestEmailer(brevo, resend, integrations.DefaultEmailerConfig())
+
+	_, _, err := e.Send(context.Background(), []string{"a@b.c"}, testMessage("one"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "brevo:")
+	assert.Contains(t, err.Error(), "resend:")
+	assert.True(t, errors.Is(err, integrations.ErrEmailQuota))
+
+	// One failure leaves Resend below the threshold, so it is still in rotation
+	detail, err := e.Check(context.Background())
+	require.NoError(t, err)
+	assert.Contains(t, detail, "brevo 0/300 quota exhausted")
+}
+
+func TestEmailer_CheckUnhealthy(t *testing.T) {
+	brevo := fakeBrevo(t)
+	brevo.respond(http.StatusPaymentRequired, `{}`)
+	e := newTestEmailer(brevo, nil, integrations.DefaultEmailerConfig())
+
+	e.Send(context.Background(), []string{"a@b.c"}, testMessage("one"))
+	_, err := e.Check(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no healthy email provider")
+}
+
+func TestEmailer_NotConfigured(t *testing.T) {
+	e := newTestEmailer(nil, nil, integrations.DefaultEmailerConfig())
+
+	_, _, err := e.Send(context.Background(), []string{"a@b.c"}, testMessage("one"))
+	assert.ErrorIs(t, err, integrations.ErrNoEmailProvider)
+
+	detail, err := e.Check(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "not configured", detail)
+}
+
+func TestEmailer_AcceptedWithoutIDIsDelivered(t *testing.T) {
+	brevo, resend := fakeBrevo(t), fakeResend(t)
+	brevo.respond(http.StatusCreated, `not json`)
+	e := newTestEmailer(brevo, resend, integrations.DefaultEmailerConfig())
+
+	provider, id, err := e.Send(context.Background(), []string{"a@b.c"}, testMessage("one"))
+	require.NoError(t, err)
+	assert.Equal(t, integrations.ProviderBrevo, provider)
+	assert.Empty(t, id)
+	assert.Equal(t, 0, resend.calls(), "an accepted message must not be sent twice")
+}
+
+func TestEmailer_ProviderOrder(t *testing.T) {
+	brevo, resend := fakeBrevo(t), fakeResend(t)
+	creds := integrations.Credentials{
+		Brevo:  integrations.BrevoConfig{APIKey: "k", BaseURL: brevo.URL},
+		Resend: integrations.ResendConfig{APIKey: "k", BaseURL: resend.URL},
+	}
+	store := integrations.NewCredentialStore(creds)
+
+	e, err := integrations.NewEmailerWithCredentials(store, integrations.DefaultEmailerConfig(), []string{"resend", "brevo"})
+	require.NoError(t, err)
+	provider, _, err := e.Send(context.Background(), []string{"a@b.c"}, testMessage("one"))
+	require.NoError(t, err)
+	assert.Equal(t, integrations.ProviderResend, provider)
+
+	_, err = integrations.NewEmailerWithCredentials(store, integrations.DefaultEmailerConfig(), []string{"smtp"})
+	assert.Error(t, err)
+}
+
+func TestEmailer_SendsTemplates(t *testing.T) {
+	brevo := fakeBrevo(t)
+	e := newTestEmailer(brevo, nil, integrations.DefaultEmailerConfig())
+
+	err := e.SendOrderConfirmation(emails.WithLocale(context.Background(), emails.English), "a@b.c", "DV-P0078DWZD", 100000)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Order confirmation #DV-P0078DWZD"}, brevo.subjects)
+}
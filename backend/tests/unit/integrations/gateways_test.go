@@ -1,6 +1,7 @@
 package integrations_test
 
 import (
+	"context"
 	"ecommerce-backend/internal/integrations"
 	"ecommerce-backend/internal/models"
 	"testing"
@@ -20,33 +21,35 @@ func TestPayOSGateway_Methods(t *testing.T) {
 	t.Setenv("PAYOS_CHECKSUM_KEY", "test-checksum")
 
 	gw := integrations.NewPayOSGateway() // No args
+	ctx := context.Background()
 	
 	// req type is PayOSCheckoutRequest
 	req := integrations.PayOSCheckoutRequest{
 		OrderCode: 123,
 		Amount:    1000,
 	}
-	_, _ = gw.CreateCheckout(req)
+	_, _ = gw.CreateCheckout(ctx, req)
 	
-	_, _ = gw.VerifyPayment(123)
+	_, _ = gw.VerifyPayment(ctx, 123)
 
-	_ = gw.RefundPayment(123, "reason")
+	_ = gw.RefundPayment(ctx, 123, "reason")
 	
-	_ = gw.CancelPayment(123)
+	_ = gw.CancelPayment(ctx, 123)
 
 	sig := gw.GenerateSignature("message")
 	assert.NotEmpty(t, sig)
 }
 
-func TestResendEmailer_Methods(t *testing.T) {
-	em := integrations.NewResendEmailer() // No args
+func TestEmailer_Methods(t *testing.T) {
+	em := integrations.NewEmailer() // No args
+	ctx := context.Background()
 
-	err := em.SendOrderConfirmation("test@example.com", "ORD-123", 1000.0)
+	err := em.SendOrderConfirmation(ctx, "test@example.com", "ORD-123", 1000.0)
 	assert.Error(t, err)
 
-	err = em.SendSymbioteReceipt("test@example.com", "0909090909", "ACTIVE", "1s")
+	err = em.SendSymbioteReceipt(ctx, "test@example.com", "0909090909", "ACTIVE", "1s")
 	assert.Error(t, err)
 
-	err = em.SendOrderDetails("test@example.com", &models.Order{})
+	err = em.SendOrderDetails(ctx, "test@example.com", &models.Order{}, "")
 	assert.Error(t, err)
 }
@@ -1,6 +1,7 @@
 package integrations_test
 
 import (
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -9,6 +10,7 @@ import (
 	"ecommerce-backend/internal/integrations"
 	"ecommerce-backend/internal/models"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"gorm.io/datatypes"
 )
@@ -41,7 +43,7 @@ func TestHelperFunctions(t *testing.T) {
 	})
 
 	t.Run("SendPasswordResetEmail", func(t *testing.T) {
-		err := integrations.SendPasswordResetEmail("test@example.com", "token123")
+		err := integrations.SendPasswordResetEmail("http://localhost:3000", "test@example.com", "token123")
 		require.NoError(t, err)
 	})
 
@@ -54,9 +56,43 @@ func TestHelperFunctions(t *testing.T) {
 			ShippingAddress: datatypes.JSON(`{"name":"Admin Test","email":"test@admin.com"}`),
 			Items:           datatypes.JSON(`[{"product_name":"Test Product","quantity":1,"price":500000}]`),
 		}
-		// Admin email recipients are fetched from env or default
-		// Default is hardcoded, so it should work.
-		err := integrations.SendOrderCreatedAdminEmail(order)
+		err := integrations.SendOrderCreatedAdminEmail(nil, order)
+		require.Error(t, err, "no recipients without admin emails")
+
+		err = integrations.SendOrderCreatedAdminEmail([]string{"ops@example.com"}, order)
 		require.NoError(t, err)
 	})
 }
+
+func TestOrderDetailsEmail_LocaleAndTextPart(t *testing.T) {
+	var got struct {
+		Subject     string `json:"subject"`
+		HtmlContent string `json:"htmlContent"`
+		TextContent string `json:"textContent"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&got)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"messageId":12345}`))
+	}))
+	defer server.Close()
+
+	os.Setenv("BREVO_API_KEY", "test-key")
+	os.Setenv("BREVO_BASE_URL", server.URL)
+	defer os.Unsetenv("BREVO_BASE_URL")
+
+	order := &models.Order{
+		ID:              12345,
+		TotalAmount:     2490000,
+		Status:          models.OrderPaid,
+		ShippingAddress: datatypes.JSON(`{"email":"a@b.c","address":"12 Le Loi","province":"HCM","locale":"en"}`),
+		Items:           datatypes.JSON(`[{"name":"Watch <gold>","quantity":1,"price":2490000}]`),
+	}
+	require.NoError(t, integrations.SendOrderDetailsEmail("a@b.c", order))
+
+	assert.Equal(t, "Order details #DV-P0078DWZD", got.Subject)
+	assert.Contains(t, got.HtmlContent, "Watch &lt;gold&gt;")
+	assert.Contains(t, got.HtmlContent, "12 Le Loi, HCM")
+	assert.Contains(t, got.HtmlContent, "Paid")
+	assert.Contains(t, got.TextContent, "Watch <gold> x 1: 2,490,000 VND")
+}
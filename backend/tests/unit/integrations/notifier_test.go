@@ -0,0 +1,180 @@
+package integrations_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"ecommerce-backend/internal/emails"
+	"ecommerce-backend/internal/integrations"
+	"ecommerce-backend/internal/integrations/smstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeChannel records notifications and fails while err is set
+type fakeChannel struct {
+	name     string
+	accepts  bool
+	err      error
+	notified []integrations.Notification
+}
+
+func (c *fakeChannel) Name() string                             { return c.name }
+func (c *fakeChannel) Accepts(n integrations.Notification) bool { return c.accepts }
+
+func (c *fakeChannel) Notify(ctx context.Context, n integrations.Notification) error {
+	if c.err != nil {
+		return c.err
+	}
+	c.notified = append(c.notified, n)
+	return nil
+}
+
+func testNotification(event string) integrations.Notification {
+	return integrations.Notification{
+		At:          time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		Event:       event,
+		Name:        "Lan",
+		Phone:       "0912 345 678",
+		Email:       "lan@example.com",
+		OrderNumber: "DW7K2M9QX",
+		OrderID:     42,
+		Amount:      1250000,
+	}
+}
+
+func TestNotifier_Routing_TableDriven(t *testing.T) {
+	tests := []struct {
+		name      string
+		prefs     []string
+		emailErr  error
+		smsSkips  bool
+		wantEmail int
+		wantSMS   int
+		wantZalo  int
+		wantErr   bool
+	}{
+		{name: "single channel", prefs: []string{"email"}, wantEmail: 1},
+		{name: "every entry is sent", prefs: []string{"email", "sms"}, wantEmail: 1, wantSMS: 1},
+		{name: "chain stops at the first delivery", prefs: []string{"zalo|sms"}, wantZalo: 1},
+		{name: "chain falls back on error", prefs: []string{"email|sms"}, emailErr: errors.New("bounced"), wantSMS: 1},
+		{name: "chain skips a channel that can't take it", prefs: []string{"sms|email"}, smsSkips: true, wantEmail: 1},
+		{name: "failed entry does not stop the others", prefs: []string{"email", "zalo"}, emailErr: errors.New("bounced"), wantZalo: 1, wantErr: true},
+		{name: "nothing accepts", prefs: []string{"sms"}, smsSkips: true, wantErr: true},
+		{name: "event without preferences is dropped"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			email := &fakeChannel{name: integrations.ChannelEmail, accepts: true, err: tc.emailErr}
+			sms := &fakeChannel{name: integrations.ChannelSMS, accepts: !tc.smsSkips}
+			zalo := &fakeChannel{name: integrations.ChannelZalo, accepts: true}
+			n, err := integrations.NewNotifier(
+				map[string][]string{integrations.EventDropWon: tc.prefs},
+				[]integrations.NotificationChannel{email, sms, zalo},
+			)
+			require.NoError(t, err)
+
+			err = n.Notify(context.Background(), testNotification(integrations.EventDropWon))
+			if tc.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Len(t, email.notified, tc.wantEmail, "email")
+			assert.Len(t, sms.notified, tc.wantSMS, "sms")
+			assert.Len(t, zalo.notified, tc.wantZalo, "zalo")
+		})
+	}
+}
+
+func TestNewNotifier_UnknownChannel(t *testing.T) {
+	_, err := integrations.NewNotifier(
+		map[string][]string{integrations.EventDropWon: {"email|fax"}},
+		[]integrations.NotificationChannel{integrations.NewEmailChannel(nil)},
+	)
+	assert.ErrorContains(t, err, "fax")
+}
+
+func TestSMSChannel(t *testing.T) {
+	gateway := smstest.NewServer()
+	defer gateway.Close()
+	store := integrations.NewCredentialStore(integrations.Credentials{
+		SMS: integrations.SMSConfig{APIKey: "key", SecretKey: "secret", BrandName: "DONALD", BaseURL: gateway.URL},
+	})
+	ch := integrations.NewSMSChannel(store)
+
+	n := testNotification(integrations.EventOrderConfirmation)
+	require.True(t, ch.Accepts(n))
+	require.NoError(t, ch.Notify(context.Background(), n))
+	require.NoError(t, ch.Notify(emails.WithLocale(context.Background(), emails.English), n))
+
+	msgs := gateway.Messages()
+	require.Len(t, msgs, 2)
+	assert.Equal(t, "0912 345 678", msgs[0].Phone)
+	assert.Equal(t, "DONALD", msgs[0].BrandName)
+	assert.Contains(t, msgs[0].Content, "DW7K2M9QX")
+	assert.Contains(t, msgs[0].Content, "1.250.000d")
+	assert.Contains(t, msgs[1].Content, "1,250,000 VND")
+	for _, m := range msgs {
+		assert.LessOrEqual(t, len(m.Content), 160, "one SMS segment")
+	}
+
+	gateway.FailWith("103")
+	assert.ErrorContains(t, ch.Notify(context.Background(), n), "103")
+
+	n.Phone = ""
+	assert.False(t, ch.Accepts(n), "no phone")
+	assert.False(t, integrations.NewSMSChannel(integrations.NewCredentialStore(integrations.Credentials{})).Accepts(testNotification(integrations.EventDropWon)), "no api key")
+}
+
+func TestZaloChannel(t *testing.T) {
+	var got integrations.ZaloRequest
+	var token string
+	refuse := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, integrations.ZaloTemplatePath, r.URL.Path)
+		token = r.Header.Get("access_token")
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		if refuse {
+			w.Write([]byte(`{"error":-124,"message":"Access token is invalid"}`))
+			return
+		}
+		w.Write([]byte(`{"error":0,"message":"Success","data":{"msg_id":"zns-1"}}`))
+	}))
+	defer server.Close()
+
+	store := integrations.NewCredentialStore(integrations.Credentials{
+		Zalo: integrations.ZaloConfig{AccessToken: "oa-token", BaseURL: server.URL},
+	})
+	ch := integrations.NewZaloChannel(store, map[string]string{integrations.EventDropWon: "231456"})
+
+	n := testNotification(integrations.EventDropWon)
+	require.True(t, ch.Accepts(n))
+	require.NoError(t, ch.Notify(context.Background(), n))
+	assert.Equal(t, "oa-token", token)
+	assert.Equal(t, "84912345678", got.Phone)
+	assert.Equal(t, "231456", got.TemplateID)
+	assert.Equal(t, "Lan", got.TemplateData["customer_name"])
+	assert.Equal(t, "DW7K2M9QX", got.TemplateData["order_code"])
+	assert.Equal(t, "1.250.000 ₫", got.TemplateData["amount"])
+	assert.Equal(t, "drop_won-42", got.TrackingID)
+
+	refuse = true
+	assert.ErrorContains(t, ch.Notify(context.Background(), n), "-124")
+
+	assert.False(t, ch.Accepts(testNotification(integrations.EventDropLost)), "no template for the event")
+}
+
+func TestInternationalPhone(t *testing.T) {
+	assert.Equal(t, "84912345678", integrations.InternationalPhone("0912345678"))
+	assert.Equal(t, "84912345678", integrations.InternationalPhone("0912 345 678"))
+	assert.Equal(t, "84912345678", integrations.InternationalPhone("+84 912-345-678"))
+	assert.Equal(t, "84912345678", integrations.InternationalPhone("84912345678"))
+}
@@ -1,11 +1,12 @@
 package integrations_test
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"testing"
-	"time"
 
 	"ecommerce-backend/internal/integrations"
 
@@ -34,19 +35,19 @@ func TestPayOSGatewayWrapper(t *testing.T) {
 	gw := integrations.NewPayOSGateway()
 
 	t.Run("CreateCheckout", func(t *testing.T) {
-		resp, err := gw.CreateCheckout(integrations.PayOSCheckoutRequest{OrderCode: 123})
+		resp, err := gw.CreateCheckout(context.Background(), integrations.PayOSCheckoutRequest{OrderCode: 123})
 		require.NoError(t, err)
 		assert.Equal(t, "http://mock", resp.Data.CheckoutURL)
 	})
 
 	t.Run("VerifyPayment", func(t *testing.T) {
-		resp, err := gw.VerifyPayment(123)
+		resp, err := gw.VerifyPayment(context.Background(), 123)
 		require.NoError(t, err)
 		assert.Equal(t, "PAID", resp.Data.Status)
 	})
 
 	t.Run("RefundPayment", func(t *testing.T) {
-		err := gw.RefundPayment(123, "reason")
+		err := gw.RefundPayment(context.Background(), 123, "reason")
 		require.NoError(t, err)
 	})
 
@@ -57,8 +58,8 @@ func TestPayOSGatewayWrapper(t *testing.T) {
 	})
 }
 
-// Since ResendEmailer wrapper calls helper functions that call Brevo,
-// we need to mock Brevo for it.
+// The emailer tries Brevo first, so a Brevo mock serves every send;
+// Resend is not configured.
 func TestEmailWrapper_WithBrevoMock(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusCreated)
@@ -70,25 +71,34 @@ func TestEmailWrapper_WithBrevoMock(t *testing.T) {
 	os.Setenv("BREVO_BASE_URL", server.URL)
 	defer os.Unsetenv("BREVO_BASE_URL")
 
-	emailer := integrations.NewResendEmailer()
+	emailer := integrations.NewEmailer()
 
 	t.Run("SendOrderConfirmation", func(t *testing.T) {
-		err := emailer.SendOrderConfirmation("foo@bar.com", "123", 100)
+		err := emailer.SendOrderConfirmation(context.Background(), "foo@bar.com", "123", 100)
 		require.NoError(t, err)
 	})
 
 	t.Run("SendSymbioteReceipt", func(t *testing.T) {
-		err := emailer.SendSymbioteReceipt("foo@bar.com", "123", "WINNER", "1s")
+		err := emailer.SendSymbioteReceipt(context.Background(), "foo@bar.com", "123", "WINNER", "1s")
 		require.NoError(t, err)
 	})
 }
 
 func TestSheetsWrapper(t *testing.T) {
-	// Sheets wrapper is hard to mock (Google SDK), so we just test the No-Op case
-	// ensuring it doesn't crash when env vars are missing.
-	os.Unsetenv("GSSHEET_SPREADSHEET_ID")
-	
-	sheets := integrations.NewSheetsSubmitter()
-	err := sheets.SubmitOrder("Name", "Phone", "Email", "Addr", "Drop", 100, time.Now())
-	require.NoError(t, err)
+	// The Sheets API is hard to mock (Google SDK), so we only test the
+	// setup errors; sheetstest fakes the API for the export itself.
+	t.Setenv("GSSHEET_SPREADSHEET_ID", "")
+
+	sheets := integrations.NewGoogleSheets()
+	assert.False(t, sheets.Configured())
+	err := sheets.AppendRows(context.Background(), [][]string{{"Name", "Phone"}})
+	assert.ErrorIs(t, err, integrations.ErrSheetsNotConfigured)
+
+	t.Setenv("GSSHEET_SPREADSHEET_ID", "sheet-id")
+	t.Setenv("GDRIVE_SERVICE_ACCOUNT_JSON", "")
+	t.Setenv("GDRIVE_SERVICE_ACCOUNT", filepath.Join(t.TempDir(), "missing.json"))
+	assert.True(t, sheets.Configured())
+	err = sheets.AppendRows(context.Background(), [][]string{{"Name", "Phone"}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "read service account")
 }
@@ -0,0 +1,77 @@
+package labels_test
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"strings"
+	"testing"
+
+	"ecommerce-backend/internal/labels"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sample(n int) []labels.Label {
+	out := make([]labels.Label, n)
+	for i := range out {
+		token := fmt.Sprintf("AQ-token-%03d", i)
+		out[i] = labels.Label{
+			ID:    uint64(i + 1),
+			Code:  fmt.Sprintf("01a1526e-489c-789c-a145-%012d", i),
+			Token: token,
+			URL:   "https://shop.example/verify?t=" + token,
+		}
+	}
+	return out
+}
+
+func TestA4Layout(t *testing.T) {
+	assert.Equal(t, 35, labels.A4.PerPage())
+	assert.Equal(t, "#12 01a1526e", sample(12)[11].Caption())
+}
+
+func TestWriteCSV(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, labels.WriteCSV(&buf, sample(3)))
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 4)
+	assert.Equal(t, []string{"id", "code", "token", "url"}, records[0])
+	assert.Equal(t, []string{"3", "01a1526e-489c-789c-a145-000000000002", "AQ-token-002", "https://shop.example/verify?t=AQ-token-002"}, records[3])
+}
+
+func TestWriteSheets_TableDriven(t *testing.T) {
+	tests := []struct {
+		name      string
+		count     int
+		wantPages int
+	}{
+		{"empty batch", 0, 1},
+		{"one page", 35, 1},
+		{"spills onto a second page", 36, 2},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var svg bytes.Buffer
+			require.NoError(t, labels.Write(&svg, "svg", sample(tc.count)))
+			out := svg.String()
+			assert.True(t, strings.HasPrefix(out, "<svg"))
+			assert.Contains(t, out, fmt.Sprintf(`height="%gmm"`, float64(tc.wantPages)*labels.A4.PageHeight))
+			assert.Equal(t, tc.count, strings.Count(out, "<path"))
+			assert.Equal(t, tc.count, strings.Count(out, "<text"))
+
+			var pdf bytes.Buffer
+			require.NoError(t, labels.Write(&pdf, "pdf", sample(tc.count)))
+			assert.True(t, bytes.HasPrefix(pdf.Bytes(), []byte("%PDF")))
+			assert.Equal(t, tc.wantPages, bytes.Count(pdf.Bytes(), []byte("/Type /Page\n")))
+		})
+	}
+}
+
+func TestWrite_UnknownFormat(t *testing.T) {
+	assert.Error(t, labels.Write(&bytes.Buffer{}, "png", sample(1)))
+}
@@ -0,0 +1,205 @@
+package logging_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"ecommerce-backend/internal/logging"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// records parses JSON log lines
+func records(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var out []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var m map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &m))
+		out = append(out, m)
+	}
+	return out
+}
+
+func TestNew_SubsystemLevels(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := logging.New(&buf, logging.Options{
+		Level:  "info",
+		Format: "json",
+		Levels: map[string]string{"payos": "debug", "queue": "error"},
+	})
+	require.NoError(t, err)
+
+	l.Debug("root debug")
+	l.Info("root info")
+	logging.Subsystem(l, "payos").Debug("payos debug")
+	logging.Subsystem(l, "queue").Warn("queue warn")
+	logging.Subsystem(l, "queue").Error("queue error")
+	logging.Subsystem(l, "service").Debug("service debug")
+
+	var msgs []string
+	for _, r := range records(t, &buf) {
+		msgs = append(msgs, r["msg"].(string))
+	}
+	assert.Equal(t, []string{"root info", "payos debug", "queue error"}, msgs)
+}
+
+func TestNew_InvalidOptions(t *testing.T) {
+	tests := []struct {
+		name string
+		opts logging.Options
+	}{
+		{"bad level", logging.Options{Level: "loud"}},
+		{"bad subsystem level", logging.Options{Levels: map[string]string{"payos": "verbose"}}},
+		{"bad format", logging.Options{Format: "xml"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := logging.New(&bytes.Buffer{}, tt.opts)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestParseLevels(t *testing.T) {
+	levels, err := logging.ParseLevels([]string{"payos=debug", " queue = warn "})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"payos": "debug", "queue": "warn"}, levels)
+
+	for _, bad := range []string{"payos", "=debug", "payos=", "payos=loud"} {
+		_, err := logging.ParseLevels([]string{bad})
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestRedact_TableDriven(t *testing.T) {
+	tests := []struct {
+		attr slog.Attr
+		want string
+	}{
+		{slog.String("phone", "0901234567"), "*******567"},
+		{slog.String("customer_phone", "12"), "**"},
+		{slog.String("email", "john@example.com"), "j***@example.com"},
+		{slog.String("customer_email", "not-an-email"), "************"},
+		{slog.String("shipping_address", "1 Le Loi, Q1"), "[redacted]"},
+		{slog.String("customer_name", "Nguyen Van A"), "[redacted]"},
+		{slog.String("address", ""), ""},
+		{slog.String("name", "payments"), "payments"},
+		{slog.Int64("order_code", 42), "42"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.attr.Key, func(t *testing.T) {
+			assert.Equal(t, tt.want, logging.Redact(tt.attr).Value.String())
+		})
+	}
+}
+
+func TestHandler_RedactsAndAddsRequestID(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := logging.New(&buf, logging.Options{Format: "json"})
+	require.NoError(t, err)
+
+	ctx := logging.ContextWithRequestID(context.Background(), "req-1")
+	l.With("customer_email", "john@example.com").InfoContext(ctx, "order",
+		"phone", "0901234567",
+		slog.Group("shipping", "address", "1 Le Loi"),
+	)
+
+	out := buf.String()
+	assert.NotContains(t, out, "0901234567")
+	assert.NotContains(t, out, "john@example.com")
+	assert.NotContains(t, out, "1 Le Loi")
+
+	r := records(t, &buf)[0]
+	assert.Equal(t, "req-1", r["request_id"])
+	assert.Equal(t, "*******567", r["phone"])
+	assert.Equal(t, "j***@example.com", r["customer_email"])
+	assert.Equal(t, map[string]any{"address": "[redacted]"}, r["shipping"])
+}
+
+func TestMiddleware_RequestID(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := logging.New(&buf, logging.Options{Format: "json"})
+	require.NoError(t, err)
+
+	var seen string
+	app := fiber.New()
+	app.Use(logging.Middleware(l))
+	app.Get("/", func(c fiber.Ctx) error {
+		seen = logging.RequestIDFrom(c.Context())
+		return c.SendString("ok")
+	})
+
+	tests := []struct {
+		name     string
+		incoming string
+		reuse    bool
+	}{
+		{"reuses a valid incoming id", "abc-123", true},
+		{"generates when missing", "", false},
+		{"replaces ids with control characters", "bad\nid", false},
+		{"replaces oversized ids", strings.Repeat("x", 200), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf.Reset()
+			req := httptest.NewRequest("GET", "/", nil)
+			if tt.incoming != "" {
+				req.Header[logging.RequestIDHeader] = []string{tt.incoming}
+			}
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+
+			id := resp.Header.Get(logging.RequestIDHeader)
+			require.NotEmpty(t, id)
+			assert.Equal(t, id, seen, "handlers see the response's request ID")
+			if tt.reuse {
+				assert.Equal(t, tt.incoming, id)
+			} else {
+				assert.NotEqual(t, tt.incoming, id)
+			}
+
+			access := records(t, &buf)[0]
+			assert.Equal(t, "request", access["msg"])
+			assert.Equal(t, id, access["request_id"])
+			assert.Equal(t, float64(200), access["status"])
+		})
+	}
+}
+
+func TestTransport_PropagatesRequestID(t *testing.T) {
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get(logging.RequestIDHeader)
+	}))
+	defer srv.Close()
+
+	client := &http.Client{Transport: logging.Transport(nil, slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil)))}
+
+	ctx := logging.ContextWithRequestID(context.Background(), "req-42")
+	req, err := http.NewRequestWithContext(ctx, "GET", srv.URL, nil)
+	require.NoError(t, err)
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, "req-42", got)
+	assert.Empty(t, req.Header.Get(logging.RequestIDHeader), "the caller's request is not modified")
+
+	req, err = http.NewRequest("GET", srv.URL, nil)
+	require.NoError(t, err)
+	resp, err = client.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Empty(t, got)
+}
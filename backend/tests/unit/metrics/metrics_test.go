@@ -0,0 +1,86 @@
+package metrics_test
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"ecommerce-backend/internal/metrics"
+	"ecommerce-backend/internal/models"
+	"ecommerce-backend/internal/queue"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMiddleware_LabelsByRoutePattern(t *testing.T) {
+	app := fiber.New()
+	app.Use(metrics.Middleware())
+	app.Get("/api/widgets/:id", func(c fiber.Ctx) error { return c.SendString("ok") })
+	app.Get("/api/broken", func(c fiber.Ctx) error { return fiber.ErrServiceUnavailable })
+
+	tests := []struct {
+		path   string
+		route  string
+		status string
+	}{
+		{"/api/widgets/1", "/api/widgets/:id", "200"},
+		{"/api/widgets/2", "/api/widgets/:id", "200"},
+		{"/api/broken", "/api/broken", "503"},
+		{"/no/such/path", "unmatched", "404"},
+	}
+	for _, tt := range tests {
+		_, err := app.Test(httptest.NewRequest("GET", tt.path, nil))
+		require.NoError(t, err)
+	}
+
+	out, err := collect(t)
+	require.NoError(t, err)
+	for _, tt := range tests {
+		assert.Contains(t, out, `donald_http_request_duration_seconds_count{method="GET",route="`+tt.route+`",status="`+tt.status+`"}`)
+	}
+	assert.NotContains(t, out, `route="/api/widgets/1"`, "raw paths must not become labels")
+}
+
+func TestRegisterDrops(t *testing.T) {
+	metrics.RegisterDrops(func() ([]models.LimitedDrop, error) {
+		return []models.LimitedDrop{{ID: 7, Sold: 3, TotalStock: 10, DropSize: 5}}, nil
+	})
+
+	out, err := collect(t)
+	require.NoError(t, err)
+	assert.Contains(t, out, `donald_drop_sold{drop_id="7"} 3`)
+	assert.Contains(t, out, `donald_drop_total_stock{drop_id="7"} 10`)
+	assert.Contains(t, out, `donald_drop_size{drop_id="7"} 5`)
+}
+
+type failingDepth struct{}
+
+func (failingDepth) Depth(ctx context.Context) (int, error) { return 0, errors.New("unreachable") }
+
+func TestRegisterQueueDepth(t *testing.T) {
+	q := queue.NewMemoryQueue()
+	require.NoError(t, q.Send(context.Background(), []byte("a")))
+	require.NoError(t, q.Send(context.Background(), []byte("b")))
+	metrics.RegisterQueueDepth("test", q)
+	metrics.RegisterQueueDepth("test-down", failingDepth{})
+
+	out, err := collect(t)
+	require.NoError(t, err)
+	assert.Contains(t, out, `donald_queue_depth{queue="test"} 2`)
+	assert.Contains(t, out, `donald_queue_depth{queue="test-down"} -1`)
+}
+
+// collect scrapes the registry through the HTTP handler
+func collect(t *testing.T) (string, error) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if rec.Code != 200 {
+		return "", errors.New(rec.Body.String())
+	}
+	return strings.TrimSpace(rec.Body.String()), nil
+}
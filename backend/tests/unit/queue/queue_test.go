@@ -0,0 +1,138 @@
+package queue_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"ecommerce-backend/internal/jobs"
+	"ecommerce-backend/internal/queue"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryQueue_VisibilityTimeout(t *testing.T) {
+	q := queue.NewMemoryQueue()
+	ctx := context.Background()
+	require.NoError(t, q.Send(ctx, []byte("job")))
+
+	msgs, err := q.Receive(ctx, 10, 50*time.Millisecond)
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, 1, msgs[0].Attempts)
+
+	// Hidden while in flight
+	ctxShort, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	_, err = q.Receive(ctxShort, 10, time.Second)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// Redelivered once the visibility timeout expires
+	again, err := q.Receive(ctx, 10, time.Second)
+	require.NoError(t, err)
+	require.Len(t, again, 1)
+	assert.Equal(t, 2, again[0].Attempts)
+
+	// The expired receipt can no longer acknowledge
+	assert.ErrorIs(t, q.Delete(ctx, msgs[0]), queue.ErrUnknownReceipt)
+	require.NoError(t, q.Delete(ctx, again[0]))
+	assert.Equal(t, 0, q.Len())
+}
+
+func TestMemoryQueue_ReceiveWakesOnSend(t *testing.T) {
+	q := queue.NewMemoryQueue()
+	ctx := context.Background()
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		q.Send(ctx, []byte("late"))
+	}()
+	msgs, err := q.Receive(ctx, 1, time.Second)
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, "late", string(msgs[0].Body))
+}
+
+func TestWorker_TableDriven(t *testing.T) {
+	tests := []struct {
+		name         string
+		failures     int // handler fails this many times before succeeding
+		panics       bool
+		wantCalls    int
+		wantDeadLtrs int
+	}{
+		{"success first try", 0, false, 1, 0},
+		{"retried then succeeds", 2, false, 3, 0},
+		{"dead-lettered after max attempts", 10, false, 3, 1},
+		{"panic is retried and dead-lettered", 10, true, 3, 1},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			q := queue.NewMemoryQueue()
+			dlq := queue.NewMemoryQueue()
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			var calls atomic.Int32
+			handler := func(ctx context.Context, body []byte) error {
+				n := int(calls.Add(1))
+				if n <= tc.failures {
+					if tc.panics {
+						panic("boom")
+					}
+					return errors.New("transient")
+				}
+				return nil
+			}
+
+			w := queue.NewWorker(q, handler, queue.WorkerOptions{
+				Concurrency: 2,
+				MaxAttempts: 3,
+				Backoff:     func(int) time.Duration { return time.Millisecond },
+				DeadLetter:  dlq,
+			})
+			stopped := make(chan struct{})
+			go func() { w.Run(ctx); close(stopped) }()
+
+			require.NoError(t, q.Send(ctx, []byte("job")))
+			require.Eventually(t, func() bool { return q.Len() == 0 }, 2*time.Second, 5*time.Millisecond)
+
+			cancel()
+			<-stopped
+			assert.Equal(t, tc.wantCalls, int(calls.Load()))
+			assert.Equal(t, tc.wantDeadLtrs, dlq.Len())
+		})
+	}
+}
+
+type mockPaymentService struct {
+	got []int64
+	err error
+}
+
+func (m *mockPaymentService) ProcessSuccessfulDropPayment(ctx context.Context, orderCode int64) error {
+	m.got = append(m.got, orderCode)
+	return m.err
+}
+
+func TestPaymentJob_RoundTrip(t *testing.T) {
+	q := queue.NewMemoryQueue()
+	ctx := context.Background()
+	require.NoError(t, jobs.EnqueuePayment(ctx, q, 4242))
+
+	msgs, err := q.Receive(ctx, 1, time.Second)
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+
+	svc := &mockPaymentService{}
+	require.NoError(t, jobs.PaymentHandler(svc)(ctx, msgs[0].Body))
+	assert.Equal(t, []int64{4242}, svc.got)
+
+	svc.err = errors.New("db locked")
+	assert.Error(t, jobs.PaymentHandler(svc)(ctx, msgs[0].Body))
+	assert.Error(t, jobs.PaymentHandler(svc)(ctx, []byte("not json")))
+}
@@ -0,0 +1,235 @@
+package ratelimit_test
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"ecommerce-backend/internal/ratelimit"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// clock is a settable time source
+type clock struct{ now time.Time }
+
+func (c *clock) Now() time.Time          { return c.now }
+func (c *clock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+func newClock() *clock                   { return &clock{now: time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)} }
+
+// memoryStore returns a MemoryStore reading time from c
+func memoryStore(c *clock) *ratelimit.MemoryStore {
+	s := ratelimit.NewMemoryStore()
+	s.Now = c.Now
+	return s
+}
+
+func TestParseRules_TableDriven(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []string
+		want    []ratelimit.Rule
+		wantErr string
+	}{
+		{
+			name:    "valid rules",
+			entries: []string{"purchase:phone=3/10m", " verify:ip=30/1m"},
+			want: []ratelimit.Rule{
+				{Route: "purchase", Key: ratelimit.KeyPhone, Limit: ratelimit.Limit{Events: 3, Period: 10 * time.Minute}},
+				{Route: "verify", Key: ratelimit.KeyIP, Limit: ratelimit.Limit{Events: 30, Period: time.Minute}},
+			},
+		},
+		{name: "missing key", entries: []string{"purchase=3/10m"}, wantErr: "expected route:key=events/period"},
+		{name: "unknown key", entries: []string{"purchase:email=3/10m"}, wantErr: "key must be ip, phone, fingerprint or order"},
+		{name: "zero events", entries: []string{"purchase:ip=0/1m"}, wantErr: "events must be a positive integer"},
+		{name: "sub-second period", entries: []string{"purchase:ip=5/100ms"}, wantErr: "period must be a duration of at least 1s"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rules, err := ratelimit.ParseRules(tc.entries)
+			if tc.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, rules)
+		})
+	}
+}
+
+func TestMemoryStore_TokenBucket(t *testing.T) {
+	c := newClock()
+	store := memoryStore(c)
+	limit := ratelimit.Limit{Events: 3, Period: time.Minute}
+	ctx := context.Background()
+
+	// A new bucket allows a burst of Events
+	for i := 2; i >= 0; i-- {
+		d, err := store.Take(ctx, "k", limit)
+		require.NoError(t, err)
+		assert.True(t, d.Allowed)
+		assert.Equal(t, i, d.Remaining)
+	}
+	d, err := store.Take(ctx, "k", limit)
+	require.NoError(t, err)
+	assert.False(t, d.Allowed)
+	assert.Equal(t, 20*time.Second, d.RetryAfter)
+
+	// One token refills every Period/Events
+	c.Advance(20 * time.Second)
+	d, _ = store.Take(ctx, "k", limit)
+	assert.True(t, d.Allowed)
+	d, _ = store.Take(ctx, "k", limit)
+	assert.False(t, d.Allowed)
+
+	// Buckets are independent
+	d, _ = store.Take(ctx, "other", limit)
+	assert.True(t, d.Allowed)
+}
+
+func TestMemoryStore_SweepsRefilledBuckets(t *testing.T) {
+	c := newClock()
+	store := memoryStore(c)
+	ctx := context.Background()
+
+	store.Take(ctx, "short", ratelimit.Limit{Events: 1, Period: time.Second})
+	store.Take(ctx, "long", ratelimit.Limit{Events: 1, Period: time.Hour})
+	assert.Equal(t, 2, store.Len())
+
+	// The next take after a minute drops the refilled bucket only
+	c.Advance(2 * time.Minute)
+	store.Take(ctx, "new", ratelimit.Limit{Events: 1, Period: time.Second})
+	assert.Equal(t, 2, store.Len())
+	d, _ := store.Take(ctx, "long", ratelimit.Limit{Events: 1, Period: time.Hour})
+	assert.False(t, d.Allowed, "a bucket still refilling must be kept")
+}
+
+// failingStore always errors, like an unreachable Redis
+type failingStore struct{}
+
+func (failingStore) Take(context.Context, string, ratelimit.Limit) (ratelimit.Decision, error) {
+	return ratelimit.Decision{}, errors.New("connection refused")
+}
+
+func TestLimiter_Allow(t *testing.T) {
+	c := newClock()
+	rules, err := ratelimit.ParseRules([]string{"purchase:ip=5/1m", "purchase:phone=1/10m"})
+	require.NoError(t, err)
+	l := ratelimit.New(ratelimit.Options{Store: memoryStore(c), Rules: rules})
+	ctx := context.Background()
+
+	d, _ := l.Allow(ctx, "purchase", map[ratelimit.Key]string{ratelimit.KeyIP: "1.2.3.4", ratelimit.KeyPhone: "0901"})
+	assert.True(t, d.Allowed)
+	assert.Equal(t, 0, d.Remaining, "the tightest bucket is reported")
+
+	// Same phone from another IP: the phone bucket denies
+	d, rule := l.Allow(ctx, "purchase", map[ratelimit.Key]string{ratelimit.KeyIP: "5.6.7.8", ratelimit.KeyPhone: "0901"})
+	assert.False(t, d.Allowed)
+	assert.Equal(t, ratelimit.KeyPhone, rule.Key)
+	assert.Equal(t, 10*time.Minute, d.RetryAfter)
+
+	// Keys without a value are not limited
+	d, _ = l.Allow(ctx, "purchase", map[ratelimit.Key]string{ratelimit.KeyIP: "5.6.7.8"})
+	assert.True(t, d.Allowed)
+
+	// Routes without rules are not limited
+	d, _ = l.Allow(ctx, "orders", map[ratelimit.Key]string{ratelimit.KeyIP: "1.2.3.4"})
+	assert.True(t, d.Allowed)
+
+	// A failing store allows the request
+	l = ratelimit.New(ratelimit.Options{Store: failingStore{}, Rules: rules})
+	d, _ = l.Allow(ctx, "purchase", map[ratelimit.Key]string{ratelimit.KeyIP: "1.2.3.4"})
+	assert.True(t, d.Allowed)
+}
+
+func TestMiddleware_TableDriven(t *testing.T) {
+	tests := []struct {
+		name    string
+		rules   []string
+		build   func(i int) (method, target, body string, headers map[string]string)
+		allowed int
+	}{
+		{
+			name:  "ip",
+			rules: []string{"orders:ip=2/1m"},
+			build: func(int) (string, string, string, map[string]string) {
+				return "GET", "/orders", "", nil
+			},
+			allowed: 2,
+		},
+		{
+			name:  "phone from the query, formatting ignored",
+			rules: []string{"orders:phone=2/1m"},
+			build: func(i int) (string, string, string, map[string]string) {
+				return "GET", []string{"/orders?phone=0901234567", "/orders?phone=%2B84%20901%20234%20567", "/orders?phone=090-123-4567"}[i%3], "", nil
+			},
+			allowed: 2,
+		},
+		{
+			name:  "phone from the JSON body",
+			rules: []string{"purchase:phone=1/1m"},
+			build: func(int) (string, string, string, map[string]string) {
+				return "POST", "/purchase", `{"phone":"0901234567"}`, map[string]string{"Content-Type": "application/json"}
+			},
+			allowed: 1,
+		},
+		{
+			name:  "device fingerprint",
+			rules: []string{"purchase:fingerprint=3/1m"},
+			build: func(int) (string, string, string, map[string]string) {
+				return "POST", "/purchase", "", map[string]string{ratelimit.FingerprintHeader: "device-a"}
+			},
+			allowed: 3,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rules, err := ratelimit.ParseRules(tc.rules)
+			require.NoError(t, err)
+			l := ratelimit.New(ratelimit.Options{Rules: rules})
+
+			app := fiber.New()
+			ok := func(c fiber.Ctx) error { return c.SendString("ok") }
+			app.Get("/orders", ratelimit.Middleware(l, "orders"), ok)
+			app.Post("/purchase", ratelimit.Middleware(l, "purchase"), ok)
+
+			for i := 0; i <= tc.allowed; i++ {
+				method, target, body, headers := tc.build(i)
+				req := httptest.NewRequest(method, target, strings.NewReader(body))
+				for k, v := range headers {
+					req.Header.Set(k, v)
+				}
+				resp, err := app.Test(req)
+				require.NoError(t, err)
+				resp.Body.Close()
+
+				if i < tc.allowed {
+					assert.Equal(t, 200, resp.StatusCode, "request %d", i)
+					continue
+				}
+				assert.Equal(t, fiber.StatusTooManyRequests, resp.StatusCode)
+				assert.NotEmpty(t, resp.Header.Get(fiber.HeaderRetryAfter))
+			}
+		})
+	}
+}
+
+func TestMiddleware_NilLimiter(t *testing.T) {
+	app := fiber.New()
+	app.Get("/", ratelimit.Middleware(nil, "orders"), func(c fiber.Ctx) error { return c.SendString("ok") })
+
+	for i := 0; i < 5; i++ {
+		resp, err := app.Test(httptest.NewRequest("GET", "/", nil))
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, 200, resp.StatusCode)
+	}
+}
@@ -0,0 +1,222 @@
+package replication_test
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"ecommerce-backend/internal/replication"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *clock) Advance(d time.Duration) time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	return c.now
+}
+
+// openApp opens the database the way the server's writer does
+func openApp(t *testing.T, path string) *sql.DB {
+	db, err := sql.Open("sqlite3", path+"?_journal_mode=WAL&_synchronous=NORMAL&_busy_timeout=5000&_txlock=immediate")
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	_, err = db.Exec(`CREATE TABLE orders (id INTEGER PRIMARY KEY, payload TEXT)`)
+	require.NoError(t, err)
+	return db
+}
+
+func insertOrders(t *testing.T, db *sql.DB, n int) {
+	for i := 0; i < n; i++ {
+		_, err := db.Exec(`INSERT INTO orders (payload) VALUES (randomblob(512))`)
+		require.NoError(t, err)
+	}
+}
+
+func countRestored(t *testing.T, path string) int {
+	db, err := sql.Open("sqlite3", path)
+	require.NoError(t, err)
+	defer db.Close()
+	var n int
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM orders`).Scan(&n))
+	return n
+}
+
+func setup(t *testing.T, opts replication.Options) (*sql.DB, *replication.Replicator, replication.Client, *clock, string) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "database.db")
+	app := openApp(t, dbPath)
+
+	clk := &clock{now: time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC)}
+	opts.Now = clk.Now
+	client := replication.NewFileClient(filepath.Join(dir, "replica"))
+	r := replication.New(dbPath, client, opts)
+	t.Cleanup(func() { r.Close() })
+	return app, r, client, clk, dir
+}
+
+func TestReplicator_PointInTimeRestore(t *testing.T) {
+	app, r, client, clk, dir := setup(t, replication.Options{Prefix: "db"})
+	ctx := context.Background()
+
+	insertOrders(t, app, 5) // before replication starts: captured by the snapshot
+	require.NoError(t, r.Sync(ctx))
+	require.NotEmpty(t, r.Generation())
+
+	insertOrders(t, app, 10)
+	t1 := clk.Advance(time.Minute)
+	require.NoError(t, r.Sync(ctx))
+
+	insertOrders(t, app, 10)
+	t2 := clk.Advance(time.Minute)
+	require.NoError(t, r.Sync(ctx))
+
+	tests := []struct {
+		name string
+		at   time.Time
+		want int
+	}{
+		{"snapshot only", t1.Add(-time.Second), 5},
+		{"after first segment", t1, 15},
+		{"between segments", t1.Add(30 * time.Second), 15},
+		{"latest", t2, 25},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			target := filepath.Join(dir, tc.name+".db")
+			res, err := replication.Restore(ctx, client, "db", target, tc.at, false)
+			require.NoError(t, err)
+			assert.Equal(t, r.Generation(), res.Generation.ID)
+			assert.Equal(t, tc.want, countRestored(t, target))
+		})
+	}
+
+	_, err := replication.Restore(ctx, client, "db", filepath.Join(dir, "early.db"), t1.Add(-time.Hour), false)
+	assert.ErrorIs(t, err, replication.ErrNoGeneration)
+}
+
+func TestReplicator_RotatesGenerationWhenWALIsLarge(t *testing.T) {
+	app, r, client, clk, dir := setup(t, replication.Options{MaxWALSize: 64 << 10})
+	ctx := context.Background()
+
+	require.NoError(t, r.Sync(ctx))
+	first := r.Generation()
+
+	insertOrders(t, app, 200) // ~100KB of frames
+	clk.Advance(time.Second)
+	require.NoError(t, r.Sync(ctx))
+	assert.NotEqual(t, first, r.Generation(), "a WAL over MaxWALSize should start a new generation")
+
+	insertOrders(t, app, 3)
+	at := clk.Advance(time.Second)
+	require.NoError(t, r.Sync(ctx))
+
+	target := filepath.Join(dir, "restored.db")
+	_, err := replication.Restore(ctx, client, "", target, at, false)
+	require.NoError(t, err)
+	assert.Equal(t, 203, countRestored(t, target))
+
+	gens, err := replication.Generations(ctx, client, "")
+	require.NoError(t, err)
+	assert.Len(t, gens, 2)
+}
+
+func TestReplicator_DetectsExternalWALRestart(t *testing.T) {
+	app, r, client, clk, dir := setup(t, replication.Options{})
+	ctx := context.Background()
+
+	require.NoError(t, r.Sync(ctx))
+	insertOrders(t, app, 4)
+	clk.Advance(time.Second)
+	require.NoError(t, r.Sync(ctx))
+	first := r.Generation()
+
+	// Simulate the replicator losing its read lock (e.g. restart), then an
+	// external checkpoint resetting the WAL before new writes arrive
+	require.NoError(t, r.Close())
+	_, err := app.Exec(`PRAGMA wal_checkpoint(TRUNCATE)`)
+	require.NoError(t, err)
+	insertOrders(t, app, 6)
+
+	at := clk.Advance(time.Second)
+	require.NoError(t, r.Sync(ctx))
+	assert.NotEqual(t, first, r.Generation())
+
+	target := filepath.Join(dir, "restored.db")
+	_, err = replication.Restore(ctx, client, "", target, at, false)
+	require.NoError(t, err)
+	assert.Equal(t, 10, countRestored(t, target))
+}
+
+func TestRestore_RefusesExistingTarget(t *testing.T) {
+	_, r, client, clk, dir := setup(t, replication.Options{})
+	ctx := context.Background()
+	require.NoError(t, r.Sync(ctx))
+
+	target := filepath.Join(dir, "database.db") // the live file
+	_, err := replication.Restore(ctx, client, "", target, clk.Now(), false)
+	assert.Error(t, err)
+}
+
+// writingClient writes to the database from inside Put, as the app would
+// while an upload is in flight
+type writingClient struct {
+	replication.Client
+	db     *sql.DB
+	writes []error
+}
+
+func (c *writingClient) Put(ctx context.Context, key string, r io.Reader) error {
+	if strings.HasSuffix(key, "snapshot.db.gz") {
+		_, err := c.db.Exec(`INSERT INTO orders (payload) VALUES (randomblob(512))`)
+		c.writes = append(c.writes, err)
+	}
+	return c.Client.Put(ctx, key, r)
+}
+
+func TestReplicator_UploadsWithoutTheWriteLock(t *testing.T) {
+	app, _, files, clk, dir := setup(t, replication.Options{})
+	ctx := context.Background()
+	insertOrders(t, app, 2)
+
+	// A second writer that gives up at once instead of waiting out the lock
+	writer, err := sql.Open("sqlite3", filepath.Join(dir, "database.db")+"?_busy_timeout=50&_txlock=immediate")
+	require.NoError(t, err)
+	defer writer.Close()
+
+	client := &writingClient{Client: files, db: writer}
+	r := replication.New(filepath.Join(dir, "database.db"), client, replication.Options{Now: clk.Now})
+	defer r.Close()
+
+	require.NoError(t, r.Sync(ctx))
+	require.Len(t, client.writes, 1)
+	require.NoError(t, client.writes[0], "writers must not wait for the snapshot upload")
+
+	// The write made during the upload follows in the next segment
+	at := clk.Advance(time.Second)
+	require.NoError(t, r.Sync(ctx))
+	target := filepath.Join(dir, "restored.db")
+	_, err = replication.Restore(ctx, client, "", target, at, false)
+	require.NoError(t, err)
+	assert.Equal(t, 3, countRestored(t, target))
+}
@@ -69,7 +69,40 @@ func setupTestDB(t *testing.T) *sql.DB {
 			code BLOB NOT NULL UNIQUE,
 			secret_key TEXT NOT NULL,
 			activated_ip TEXT,
-			is_activated INTEGER NOT NULL DEFAULT 0
+			is_activated INTEGER NOT NULL DEFAULT 0,
+			bound_at DATETIME,
+			batch_id INTEGER NOT NULL DEFAULT 0
+		);
+
+		CREATE TABLE order_lookup_tokens (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			created_at DATETIME,
+			expires_at DATETIME,
+			used_at DATETIME,
+			token_hash TEXT NOT NULL UNIQUE,
+			order_id INTEGER
+		);
+
+		CREATE TABLE email_messages (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			created_at DATETIME,
+			updated_at DATETIME,
+			provider TEXT,
+			message_id TEXT,
+			email TEXT,
+			template TEXT,
+			status TEXT,
+			detail TEXT,
+			order_id INTEGER
+		);
+
+		CREATE TABLE email_suppressions (
+			email TEXT PRIMARY KEY,
+			created_at DATETIME,
+			reason TEXT,
+			provider TEXT,
+			message_id TEXT,
+			detail TEXT
 		);
 	`)
 	require.NoError(t, err)
@@ -600,6 +633,122 @@ func TestActivateSymbicode_TableDriven(t *testing.T) {
 	}
 }
 
+func TestUseOrderLookupToken_TableDriven(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	repo := repository.NewRepository(db)
+
+	require.NoError(t, repo.CreateOrderLookupToken(&models.OrderLookupToken{OrderID: 7, TokenHash: "live", ExpiresAt: time.Now().Add(time.Hour)}))
+	require.NoError(t, repo.CreateOrderLookupToken(&models.OrderLookupToken{OrderID: 8, TokenHash: "expired", ExpiresAt: time.Now().Add(-time.Minute)}))
+
+	tests := []struct {
+		name      string
+		tokenHash string
+		wantOrder uint64
+		wantErr   error
+	}{
+		{name: "success - first use", tokenHash: "live", wantOrder: 7},
+		{name: "error - already used", tokenHash: "live", wantErr: sql.ErrNoRows},
+		{name: "error - expired", tokenHash: "expired", wantErr: sql.ErrNoRows},
+		{name: "error - unknown", tokenHash: "unknown", wantErr: sql.ErrNoRows},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			orderID, err := repo.UseOrderLookupToken(tc.tokenHash)
+			if tc.wantErr != nil {
+				assert.ErrorIs(t, err, tc.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.wantOrder, orderID)
+		})
+	}
+}
+
+// =============================================================================
+// EMAIL DELIVERY TRACKING TESTS
+// =============================================================================
+
+func TestUpdateEmailMessageStatus_TableDriven(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	repo := repository.NewRepository(db)
+
+	require.NoError(t, repo.CreateEmailMessage(&models.EmailMessage{Provider: "brevo", MessageID: "<m1@brevo>", Email: "A@Example.com", Template: "order_confirmation", OrderID: 7}))
+	require.NoError(t, repo.CreateEmailMessage(&models.EmailMessage{Provider: "brevo", MessageID: "<m1@brevo>", Email: "b@example.com", Template: "order_confirmation", OrderID: 7}))
+
+	// Applied in order: each step sees the state the previous one left
+	tests := []struct {
+		name       string
+		email      string
+		status     string
+		wantN      int64
+		wantStatus []string // a@, b@
+	}{
+		{name: "deferred", email: "a@example.com", status: models.EmailDeferred, wantN: 1, wantStatus: []string{"deferred", "sent"}},
+		{name: "delivered - address compared case-insensitively", email: "A@EXAMPLE.COM", status: models.EmailDelivered, wantN: 1, wantStatus: []string{"delivered", "sent"}},
+		{name: "late deferred ignored", email: "a@example.com", status: models.EmailDeferred, wantN: 0, wantStatus: []string{"delivered", "sent"}},
+		{name: "complaint after delivery", email: "a@example.com", status: models.EmailComplained, wantN: 1, wantStatus: []string{"complained", "sent"}},
+		{name: "replayed delivery ignored", email: "a@example.com", status: models.EmailDelivered, wantN: 0, wantStatus: []string{"complained", "sent"}},
+		{name: "no email - every recipient", status: models.EmailBounced, wantN: 2, wantStatus: []string{"bounced", "bounced"}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			n, err := repo.UpdateEmailMessageStatus("brevo", "<m1@brevo>", tc.email, tc.status, "")
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantN, n)
+
+			messages, err := repo.ListOrderEmailMessages(7)
+			require.NoError(t, err)
+			require.Len(t, messages, 2)
+			assert.Equal(t, "a@example.com", messages[0].Email)
+			assert.Equal(t, tc.wantStatus, []string{messages[0].Status, messages[1].Status})
+		})
+	}
+
+	n, err := repo.UpdateEmailMessageStatus("resend", "<m1@brevo>", "", models.EmailDelivered, "")
+	require.NoError(t, err)
+	assert.Zero(t, n, "message IDs are per provider")
+}
+
+func TestEmailSuppressions(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	repo := repository.NewRepository(db)
+
+	added, err := repo.SuppressEmail(&models.EmailSuppression{Email: " Bounce@Example.com ", Reason: models.EmailBounced, Provider: "brevo", Detail: "mailbox does not exist"})
+	require.NoError(t, err)
+	assert.True(t, added)
+
+	added, err = repo.SuppressEmail(&models.EmailSuppression{Email: "bounce@example.com", Reason: models.EmailComplained, Provider: "resend"})
+	require.NoError(t, err)
+	assert.False(t, added, "the first reason is kept")
+
+	suppressed, err := repo.SuppressedEmails([]string{"ok@example.com", "BOUNCE@example.com"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"BOUNCE@example.com"}, suppressed)
+
+	list, err := repo.ListEmailSuppressions(10)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, "bounce@example.com", list[0].Email)
+	assert.Equal(t, models.EmailBounced, list[0].Reason)
+	assert.Equal(t, "mailbox does not exist", list[0].Detail)
+
+	deleted, err := repo.DeleteEmailSuppression("Bounce@example.com")
+	require.NoError(t, err)
+	assert.True(t, deleted)
+	deleted, err = repo.DeleteEmailSuppression("bounce@example.com")
+	require.NoError(t, err)
+	assert.False(t, deleted)
+
+	suppressed, err = repo.SuppressedEmails([]string{"bounce@example.com"})
+	require.NoError(t, err)
+	assert.Empty(t, suppressed)
+}
+
 // =============================================================================
 // HELPER FUNCTIONS
 // =============================================================================
@@ -192,6 +192,32 @@ func TestUpdateOrderStatus(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestCancelOrder(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := repository.NewRepository(db)
+
+	query := regexp.QuoteMeta("UPDATE orders SET status = ? WHERE id = ? AND status <> ?")
+	mock.ExpectExec(query).
+		WithArgs(models.OrderCancelled, uint64(1), models.OrderCancelled).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(query).
+		WithArgs(models.OrderCancelled, uint64(1), models.OrderCancelled).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	cancelled, err := repo.CancelOrder(1)
+	assert.NoError(t, err)
+	assert.True(t, cancelled)
+
+	// Already cancelled by another delivery
+	cancelled, err = repo.CancelOrder(1)
+	assert.NoError(t, err)
+	assert.False(t, cancelled)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestDecrementSoldCount(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	assert.NoError(t, err)
@@ -206,3 +232,105 @@ func TestDecrementSoldCount(t *testing.T) {
 	err = repo.DecrementSoldCount(1, 1)
 	assert.NoError(t, err)
 }
+
+func TestOrderSymbicodeCounts(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := repository.NewRepository(db)
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT EXISTS (SELECT 1 FROM symbicode_batches WHERE product_id = ?)")).
+		WithArgs(uint64(10)).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM symbicodes WHERE order_id = ?")).
+		WithArgs(uint64(7)).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+
+	printed, err := repo.HasSymbicodeBatches(10)
+	assert.NoError(t, err)
+	assert.True(t, printed)
+
+	n, err := repo.CountOrderSymbicodes(7)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUseSymbicodeTransferCode(t *testing.T) {
+	tests := []struct {
+		name    string
+		matched int64
+		want    bool
+	}{
+		{"right code", 1, true},
+		{"wrong code counts an attempt", 0, false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer db.Close()
+
+			repo := repository.NewRepository(db)
+
+			mock.ExpectExec(regexp.QuoteMeta("UPDATE symbicode_transfer_codes SET used_at = ?")).
+				WithArgs(sqlmock.AnyArg(), uint64(3), "sms", "0901234567", "hash", sqlmock.AnyArg(), repository.TransferCodeAttempts).
+				WillReturnResult(sqlmock.NewResult(0, tc.matched))
+			if !tc.want {
+				mock.ExpectExec(regexp.QuoteMeta("UPDATE symbicode_transfer_codes SET attempts = attempts + 1")).
+					WithArgs(uint64(3)).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			}
+
+			used, err := repo.UseSymbicodeTransferCode(3, "sms", "0901234567", "hash")
+			assert.NoError(t, err)
+			assert.Equal(t, tc.want, used)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestRefunds(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := repository.NewRepository(db)
+
+	insert := regexp.QuoteMeta("INSERT INTO refunds")
+	mock.ExpectExec(insert).
+		WithArgs(uint64(1), int64(12345), "Limited drop sold out", models.RefundPending, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(7, 1))
+	// Already queued by another delivery
+	mock.ExpectExec(insert).
+		WithArgs(uint64(1), int64(12345), "Limited drop sold out", models.RefundPending, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(7, 0))
+	now := time.Now().UTC()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, order_id, order_code")).
+		WithArgs(models.RefundPending, now, 100).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "order_id", "order_code", "reason", "status", "attempts", "last_error", "created_at", "next_attempt_at"}).
+			AddRow(7, 1, 12345, "Limited drop sold out", models.RefundPending, 0, "", now, now))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE refunds SET status = ?")).
+		WithArgs(models.RefundRefunded, 1, sqlmock.AnyArg(), sqlmock.AnyArg(), "", uint64(7)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	refund := &models.Refund{OrderID: 1, OrderCode: 12345, Reason: "Limited drop sold out"}
+	assert.NoError(t, repo.CreateRefund(refund))
+	assert.Equal(t, uint64(7), refund.ID)
+
+	again := &models.Refund{OrderID: 1, OrderCode: 12345, Reason: "Limited drop sold out"}
+	assert.NoError(t, repo.CreateRefund(again))
+	assert.Zero(t, again.ID)
+
+	due, err := repo.DueRefunds(now, 100)
+	assert.NoError(t, err)
+	if assert.Len(t, due, 1) {
+		due[0].Status = models.RefundRefunded
+		due[0].Attempts = 1
+		due[0].RefundedAt = &now
+		assert.NoError(t, repo.UpdateRefund(&due[0]))
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
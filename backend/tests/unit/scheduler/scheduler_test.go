@@ -0,0 +1,220 @@
+package scheduler_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"ecommerce-backend/internal/models"
+	"ecommerce-backend/internal/scheduler"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// sqlStore returns a SQLStore on a fresh database migrated like the server's
+func sqlStore(t *testing.T) *scheduler.SQLStore {
+	t.Helper()
+	gdb, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "sched.db")), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, gdb.AutoMigrate(&models.SchedulerJob{}))
+	db, err := gdb.DB()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return scheduler.NewSQLStore(db)
+}
+
+func TestParseSchedule_TableDriven(t *testing.T) {
+	from := time.Date(2026, 3, 1, 10, 7, 30, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		spec    string
+		want    time.Time
+		wantErr bool
+	}{
+		{"every is aligned to the epoch", "@every 15m", time.Date(2026, 3, 1, 10, 15, 0, 0, time.UTC), false},
+		{"hourly", "@hourly", time.Date(2026, 3, 1, 11, 0, 0, 0, time.UTC), false},
+		{"cron expression", "30 2 * * *", time.Date(2026, 3, 2, 2, 30, 0, 0, time.UTC), false},
+		{"invalid", "every hour", time.Time{}, true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			sched, err := scheduler.ParseSchedule(tc.spec)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.True(t, tc.want.Equal(sched.Next(from)), "next run %s", sched.Next(from))
+		})
+	}
+}
+
+func TestStore_Claim_TableDriven(t *testing.T) {
+	stores := map[string]func(*testing.T) scheduler.Store{
+		"memory": func(*testing.T) scheduler.Store { return scheduler.NewMemoryStore() },
+		"sql":    func(t *testing.T) scheduler.Store { return sqlStore(t) },
+	}
+
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			store := newStore(t)
+			now := time.Now()
+			slot1, slot2, slot3 := now.Add(-2*time.Minute), now.Add(-time.Minute), now
+			lease := now.Add(time.Hour)
+
+			ok, err := store.Claim(ctx, "job", "a", slot1, lease)
+			require.NoError(t, err)
+			assert.True(t, ok, "first claim of a slot succeeds")
+
+			ok, _ = store.Claim(ctx, "job", "b", slot1, lease)
+			assert.False(t, ok, "a slot is claimed once")
+			ok, _ = store.Claim(ctx, "job", "b", slot2, lease)
+			assert.False(t, ok, "no claim while the lease is held")
+
+			require.NoError(t, store.Finish(ctx, "job", "a", scheduler.Run{Started: now, Finished: now, Status: "ok", Result: "done"}))
+			ok, _ = store.Claim(ctx, "job", "b", slot1, lease)
+			assert.False(t, ok, "released slots are not run again")
+
+			// b crashes holding an expired lease
+			ok, _ = store.Claim(ctx, "job", "b", slot2, now.Add(-time.Second))
+			assert.True(t, ok)
+			ok, _ = store.Claim(ctx, "job", "a", slot3, lease)
+			assert.True(t, ok, "expired leases can be taken over")
+
+			states, err := store.States(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, "a", states["job"].Owner)
+			assert.WithinDuration(t, slot3, states["job"].LastSlot, time.Millisecond)
+		})
+	}
+}
+
+func TestScheduler_RunNow_SingleInstanceAndStatus(t *testing.T) {
+	ctx := context.Background()
+	store := sqlStore(t)
+
+	started, release := make(chan struct{}), make(chan struct{})
+	var runs atomic.Int32
+	job := scheduler.Job{
+		Name:     "sync",
+		Schedule: "@hourly",
+		Run: func(ctx context.Context) (string, error) {
+			runs.Add(1)
+			close(started)
+			<-release
+			return "synced 3 rows", nil
+		},
+	}
+
+	a := scheduler.New(scheduler.Options{Owner: "a", Store: store})
+	b := scheduler.New(scheduler.Options{Owner: "b", Store: store})
+	require.NoError(t, a.Register(job))
+	require.NoError(t, b.Register(job))
+	assert.Error(t, a.Register(job), "duplicate names are rejected")
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ran, err := a.RunNow(ctx, "sync")
+		assert.NoError(t, err)
+		assert.True(t, ran)
+	}()
+	<-started
+
+	ran, err := b.RunNow(ctx, "sync")
+	require.NoError(t, err)
+	assert.False(t, ran, "another instance holds the lease")
+
+	status, err := b.Status(ctx)
+	require.NoError(t, err)
+	require.Len(t, status, 1)
+	assert.True(t, status[0].Running)
+
+	close(release)
+	wg.Wait()
+	assert.Equal(t, int32(1), runs.Load())
+
+	status, err = b.Status(ctx)
+	require.NoError(t, err)
+	assert.False(t, status[0].Running)
+	assert.Equal(t, "a", status[0].RanOn)
+	assert.Equal(t, "ok", status[0].LastStatus)
+	assert.Equal(t, "synced 3 rows", status[0].LastResult)
+	assert.NotNil(t, status[0].LastRun)
+	assert.True(t, status[0].NextRun.After(time.Now()))
+
+	_, err = b.RunNow(ctx, "missing")
+	assert.Error(t, err)
+}
+
+func TestScheduler_FailedRuns_TableDriven(t *testing.T) {
+	tests := []struct {
+		name    string
+		run     func(context.Context) (string, error)
+		wantErr string
+	}{
+		{"error", func(context.Context) (string, error) { return "", errors.New("upstream down") }, "upstream down"},
+		{"panic", func(context.Context) (string, error) { panic("boom") }, "panic: boom"},
+		{"timeout", func(ctx context.Context) (string, error) { <-ctx.Done(); return "", ctx.Err() }, context.DeadlineExceeded.Error()},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			s := scheduler.New(scheduler.Options{Owner: "a"})
+			require.NoError(t, s.Register(scheduler.Job{Name: "job", Schedule: "@daily", Timeout: 50 * time.Millisecond, Run: tc.run}))
+
+			ran, err := s.RunNow(ctx, "job")
+			require.NoError(t, err)
+			assert.True(t, ran)
+
+			status, err := s.Status(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, "error", status[0].LastStatus)
+			assert.Equal(t, tc.wantErr, status[0].LastError)
+			assert.False(t, status[0].Running, "the lease is released after a failure")
+		})
+	}
+}
+
+func TestScheduler_Run_OneInstancePerSlot(t *testing.T) {
+	if testing.Short() {
+		t.Skip("waits for scheduled slots")
+	}
+	store := sqlStore(t)
+	var runs atomic.Int32
+	job := scheduler.Job{Name: "tick", Schedule: "@every 1s", Run: func(context.Context) (string, error) {
+		runs.Add(1)
+		return "", nil
+	}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2500*time.Millisecond)
+	defer cancel()
+	var wg sync.WaitGroup
+	for _, owner := range []string{"a", "b", "c"} {
+		s := scheduler.New(scheduler.Options{Owner: owner, Store: store})
+		require.NoError(t, s.Register(job))
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.Run(ctx)
+		}()
+	}
+	wg.Wait()
+
+	// 2 or 3 one-second slots fit in 2.5s; each runs on exactly one instance
+	n := runs.Load()
+	assert.GreaterOrEqual(t, n, int32(2))
+	assert.LessOrEqual(t, n, int32(3))
+}
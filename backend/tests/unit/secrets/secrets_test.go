@@ -0,0 +1,100 @@
+package secrets_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"ecommerce-backend/internal/integrations"
+	"ecommerce-backend/internal/secrets"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mapProvider map[string]string
+
+func (m mapProvider) Lookup(ctx context.Context, key string) (string, bool, error) {
+	v, ok := m[key]
+	return v, ok, nil
+}
+
+type failingProvider struct{}
+
+func (failingProvider) Lookup(ctx context.Context, key string) (string, bool, error) {
+	return "", false, errors.New("access denied")
+}
+
+func TestProviders_TableDriven(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "payos_api_key"), []byte("from-file\n"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "BREVO_API_KEY"), []byte("brevo-file"), 0600))
+	t.Setenv("PAYOS_API_KEY", "from-env")
+	t.Setenv("PAYOS_CLIENT_ID", "client-env")
+
+	tests := []struct {
+		name     string
+		provider secrets.Provider
+		key      string
+		want     string
+		wantOK   bool
+	}{
+		{"env", secrets.Env{}, "PAYOS_API_KEY", "from-env", true},
+		{"env missing", secrets.Env{}, "NOPE_NOT_SET", "", false},
+		{"dir lowercase file, newline trimmed", secrets.Dir{Path: dir}, "PAYOS_API_KEY", "from-file", true},
+		{"dir exact file", secrets.Dir{Path: dir}, "BREVO_API_KEY", "brevo-file", true},
+		{"dir missing", secrets.Dir{Path: dir}, "PAYOS_CLIENT_ID", "", false},
+		{"chain prefers first", secrets.Chain{secrets.Dir{Path: dir}, secrets.Env{}}, "PAYOS_API_KEY", "from-file", true},
+		{"chain falls through", secrets.Chain{secrets.Dir{Path: dir}, secrets.Env{}}, "PAYOS_CLIENT_ID", "client-env", true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok, err := tc.provider.Lookup(context.Background(), tc.key)
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantOK, ok)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestChain_StopsOnError(t *testing.T) {
+	_, _, err := secrets.Chain{failingProvider{}, secrets.Env{}}.Lookup(context.Background(), "PATH")
+	assert.Error(t, err)
+}
+
+func TestLoadCredentials(t *testing.T) {
+	p := mapProvider{
+		"PAYOS_CLIENT_ID":             "cid",
+		"PAYOS_API_KEY":               "key-v1",
+		"BREVO_API_KEY":               "brevo",
+		"GDRIVE_SERVICE_ACCOUNT_JSON": `{"type":"service_account"}`,
+	}
+	creds, err := integrations.LoadCredentials(secrets.Getter(context.Background(), p))
+	require.NoError(t, err)
+	assert.Equal(t, "cid", creds.PayOS.ClientID)
+	assert.True(t, creds.PayOS.Configured())
+	assert.Equal(t, "brevo", creds.Brevo.APIKey)
+	assert.Equal(t, `{"type":"service_account"}`, creds.Sheets.ServiceAccountJSON)
+	assert.Empty(t, creds.Resend.APIKey)
+
+	_, err = integrations.LoadCredentials(secrets.Getter(context.Background(), failingProvider{}))
+	assert.Error(t, err)
+}
+
+func TestCredentialStore_RotationIsPickedUpByGateway(t *testing.T) {
+	// The environment must not leak into a gateway built from a store
+	t.Setenv("PAYOS_API_KEY", "env-key")
+
+	store := integrations.NewCredentialStore(integrations.Credentials{PayOS: integrations.PayOSConfig{APIKey: "key-v1"}})
+	gw := integrations.NewPayOSGatewayWithCredentials(store)
+
+	v1 := gw.GenerateSignature("payload")
+	assert.Equal(t, integrations.PayOSConfig{APIKey: "key-v1"}.Signature("payload"), v1)
+	assert.NotEqual(t, integrations.GeneratePayOSSignature("payload"), v1)
+
+	store.Set(integrations.Credentials{PayOS: integrations.PayOSConfig{APIKey: "key-v2"}})
+	assert.Equal(t, integrations.PayOSConfig{APIKey: "key-v2"}.Signature("payload"), gw.GenerateSignature("payload"))
+}
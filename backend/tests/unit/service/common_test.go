@@ -1,7 +1,12 @@
 package service_test
 
 import (
+	"context"
+	"database/sql"
 	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"ecommerce-backend/internal/integrations"
@@ -54,6 +59,26 @@ type mockRepository struct {
 	createSymErr   error
 	getSymErr      error
 	activateSymErr error
+	autoActErr     error
+	scans          []models.SymbicodeScan
+	scanErr        error
+	transfers      []*models.SymbicodeTransfer
+	transferCodes  []*models.SymbicodeTransferCode
+	batches        []*models.SymbicodeBatch
+
+	// Order lookup links
+	lookupTokens []*models.OrderLookupToken
+
+	// Loser refunds
+	refunds []*models.Refund
+
+	// Audit log
+	audit    []models.AuditEntry
+	auditErr error
+
+	// Email delivery tracking
+	emailMessages []*models.EmailMessage
+	suppressions  []models.EmailSuppression
 
 	// Transaction
 	txErr error
@@ -189,6 +214,13 @@ func (m *mockRepository) WithTransaction(fn func(repository.Repository) error) e
 	return fn(m)
 }
 
+// Read routing hints
+func (m *mockRepository) Primary() repository.Repository { return m }
+
+func (m *mockRepository) Session() repository.Repository { return m }
+
+func (m *mockRepository) WithContext(ctx context.Context) repository.Repository { return m }
+
 // Symbicode operations
 func (m *mockRepository) CreateSymbicode(symbicode *models.Symbicode) error {
 	if m.createSymErr != nil {
@@ -208,7 +240,7 @@ func (m *mockRepository) GetSymbicodeByCode(code []byte) (*models.Symbicode, err
 	if s, ok := m.symbicodes[key]; ok {
 		return s, nil
 	}
-	return nil, errors.New("symbicode not found")
+	return nil, sql.ErrNoRows
 }
 
 func (m *mockRepository) ActivateSymbicode(id uint64, ip string) error {
@@ -227,6 +259,228 @@ func (m *mockRepository) ActivateSymbicode(id uint64, ip string) error {
 	return errors.New("symbicode not found")
 }
 
+func (m *mockRepository) AutoActivateSymbicodes(createdBefore time.Time, limit int) ([]uint64, error) {
+	if m.autoActErr != nil {
+		return nil, m.autoActErr
+	}
+	var ids []uint64
+	for _, s := range m.symbicodes {
+		issued := s.CreatedAt
+		if s.BoundAt != nil {
+			issued = *s.BoundAt
+		}
+		if s.IsActivated == 0 && (s.BatchID == 0 || s.OrderID != 0) && issued.Before(createdBefore) {
+			ids = append(ids, s.ID)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	if len(ids) > limit {
+		ids = ids[:limit]
+	}
+	for _, id := range ids {
+		if err := m.ActivateSymbicode(id, models.AutoActivatedIP); err != nil {
+			return nil, err
+		}
+	}
+	return ids, nil
+}
+
+func (m *mockRepository) CreateSymbicodeScan(scan *models.SymbicodeScan) error {
+	if m.scanErr != nil {
+		return m.scanErr
+	}
+	scan.ID = uint64(len(m.scans) + 1)
+	if scan.ScannedAt.IsZero() {
+		scan.ScannedAt = time.Now()
+	}
+	m.scans = append(m.scans, *scan)
+	return nil
+}
+
+func (m *mockRepository) ListSymbicodeScans(symbicodeID uint64, limit int) ([]models.SymbicodeScan, error) {
+	var out []models.SymbicodeScan
+	for i := len(m.scans) - 1; i >= 0 && len(out) < limit; i-- {
+		if m.scans[i].SymbicodeID == symbicodeID {
+			out = append(out, m.scans[i])
+		}
+	}
+	return out, nil
+}
+
+func (m *mockRepository) GetSymbicodeScanStats(symbicodeID uint64) (*models.SymbicodeScanStats, error) {
+	st := models.SymbicodeScanStats{SymbicodeID: symbicodeID}
+	ips, locations := map[string]bool{}, map[string]bool{}
+	for _, sc := range m.scans {
+		if sc.SymbicodeID != symbicodeID {
+			continue
+		}
+		if st.Scans == 0 {
+			st.FirstScanAt = sc.ScannedAt
+		}
+		st.Scans++
+		st.LastScanAt = sc.ScannedAt
+		ips[sc.IP] = true
+		if sc.Country != "" {
+			locations[sc.Country+"/"+sc.City] = true
+		}
+	}
+	st.DistinctIPs, st.DistinctLocations = len(ips), len(locations)
+	return &st, nil
+}
+
+func (m *mockRepository) ListSuspiciousSymbicodes(minIPs, minLocations, limit int) ([]models.SymbicodeScanStats, error) {
+	var out []models.SymbicodeScanStats
+	for _, s := range m.symbicodes {
+		st, _ := m.GetSymbicodeScanStats(s.ID)
+		if st.Scans > 0 && (st.DistinctIPs >= minIPs || st.DistinctLocations >= minLocations) {
+			st.Code, st.ProductID, st.OrderID = s.Code, s.ProductID, s.OrderID
+			out = append(out, *st)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].SymbicodeID < out[j].SymbicodeID })
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+func (m *mockRepository) GetSymbicodeByID(id uint64) (*models.Symbicode, error) {
+	for _, s := range m.symbicodes {
+		if s.ID == id {
+			return s, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (m *mockRepository) CreateSymbicodeBatch(batch *models.SymbicodeBatch) error {
+	batch.ID = uint64(len(m.batches) + 1)
+	m.batches = append(m.batches, batch)
+	return nil
+}
+
+func (m *mockRepository) GetSymbicodeBatch(id uint64) (*models.SymbicodeBatch, error) {
+	if id == 0 || id > uint64(len(m.batches)) {
+		return nil, sql.ErrNoRows
+	}
+	return m.batches[id-1], nil
+}
+
+func (m *mockRepository) ListBatchSymbicodes(batchID uint64) ([]models.Symbicode, error) {
+	var out []models.Symbicode
+	for _, s := range m.symbicodes {
+		if s.BatchID == batchID {
+			out = append(out, *s)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out, nil
+}
+
+func (m *mockRepository) BindSymbicode(id, orderID uint64) (bool, error) {
+	s, err := m.GetSymbicodeByID(id)
+	if err != nil || s.OrderID != 0 {
+		return false, nil
+	}
+	s.OrderID, s.BoundAt = orderID, ptrTime(time.Now())
+	return true, nil
+}
+
+func (m *mockRepository) HasSymbicodeBatches(productID uint64) (bool, error) {
+	for _, b := range m.batches {
+		if b.ProductID == productID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *mockRepository) CountOrderSymbicodes(orderID uint64) (int, error) {
+	n := 0
+	for _, s := range m.symbicodes {
+		if s.OrderID == orderID {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (m *mockRepository) CreateSymbicodeTransfer(transfer *models.SymbicodeTransfer) error {
+	transfer.ID = uint64(len(m.transfers) + 1)
+	if transfer.CreatedAt.IsZero() {
+		transfer.CreatedAt = time.Now()
+	}
+	m.transfers = append(m.transfers, transfer)
+	return nil
+}
+
+func (m *mockRepository) CancelSymbicodeTransfers(symbicodeID uint64) (int64, error) {
+	var n int64
+	for _, t := range m.transfers {
+		if t.SymbicodeID == symbicodeID && t.ClaimedAt == nil && t.CanceledAt == nil {
+			t.CanceledAt = ptrTime(time.Now())
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (m *mockRepository) ClaimSymbicodeTransfer(tokenHash, phone, email string) (*models.SymbicodeTransfer, error) {
+	for _, t := range m.transfers {
+		if t.TokenHash == tokenHash && t.ClaimedAt == nil && t.CanceledAt == nil && t.ExpiresAt.After(time.Now()) {
+			t.ClaimedAt = ptrTime(time.Now())
+			t.OwnerPhone, t.OwnerEmail = phone, email
+			return t, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (m *mockRepository) ListSymbicodeOwners(symbicodeID uint64) ([]models.SymbicodeTransfer, error) {
+	var out []models.SymbicodeTransfer
+	for _, t := range m.transfers {
+		if t.SymbicodeID == symbicodeID && t.ClaimedAt != nil {
+			out = append(out, *t)
+		}
+	}
+	return out, nil
+}
+
+func (m *mockRepository) CreateSymbicodeTransferCode(code *models.SymbicodeTransferCode) error {
+	for _, c := range m.transferCodes {
+		if c.SymbicodeID == code.SymbicodeID && c.UsedAt == nil {
+			c.UsedAt = ptrTime(time.Now())
+		}
+	}
+	code.ID = uint64(len(m.transferCodes) + 1)
+	m.transferCodes = append(m.transferCodes, code)
+	return nil
+}
+
+func (m *mockRepository) UseSymbicodeTransferCode(symbicodeID uint64, channel, contact, codeHash string) (bool, error) {
+	for _, c := range m.transferCodes {
+		if c.SymbicodeID != symbicodeID || c.UsedAt != nil {
+			continue
+		}
+		if c.Channel == channel && c.Contact == contact && c.CodeHash == codeHash &&
+			c.ExpiresAt.After(time.Now()) && c.Attempts < repository.TransferCodeAttempts {
+			c.UsedAt = ptrTime(time.Now())
+			return true, nil
+		}
+		c.Attempts++
+	}
+	return false, nil
+}
+
+func (m *mockRepository) CreateAuditEntry(entry *models.AuditEntry) error {
+	if m.auditErr != nil {
+		return m.auditErr
+	}
+	entry.ID = uint64(len(m.audit) + 1)
+	m.audit = append(m.audit, *entry)
+	return nil
+}
+
 func (m *mockRepository) UpdateOrderStatus(id uint64, status uint8) error {
 	if order, ok := m.orders[id]; ok {
 		order.Status = status
@@ -235,6 +489,154 @@ func (m *mockRepository) UpdateOrderStatus(id uint64, status uint8) error {
 	return errors.New("order not found")
 }
 
+func (m *mockRepository) CancelOrder(id uint64) (bool, error) {
+	order, ok := m.orders[id]
+	if !ok {
+		for _, o := range m.orderByPayOS {
+			if o.ID == id {
+				order, ok = o, true
+			}
+		}
+	}
+	if !ok || order.Status == models.OrderCancelled {
+		return false, nil
+	}
+	order.Status = models.OrderCancelled
+	return true, nil
+}
+
+func (m *mockRepository) CreateRefund(refund *models.Refund) error {
+	for _, f := range m.refunds {
+		if f.OrderID == refund.OrderID {
+			return nil
+		}
+	}
+	refund.ID = uint64(len(m.refunds) + 1)
+	refund.CreatedAt = time.Now()
+	refund.NextAttemptAt = refund.CreatedAt
+	refund.Status = models.RefundPending
+	stored := *refund
+	m.refunds = append(m.refunds, &stored)
+	return nil
+}
+
+func (m *mockRepository) DueRefunds(now time.Time, limit int) ([]models.Refund, error) {
+	var due []models.Refund
+	for _, f := range m.refunds {
+		if f.Status == models.RefundPending && !f.NextAttemptAt.After(now) && len(due) < limit {
+			due = append(due, *f)
+		}
+	}
+	return due, nil
+}
+
+func (m *mockRepository) UpdateRefund(refund *models.Refund) error {
+	for _, f := range m.refunds {
+		if f.ID == refund.ID {
+			*f = *refund
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
+
+func (m *mockRepository) CreateOrderLookupToken(token *models.OrderLookupToken) error {
+	token.ID = uint64(len(m.lookupTokens) + 1)
+	m.lookupTokens = append(m.lookupTokens, token)
+	return nil
+}
+
+func (m *mockRepository) UseOrderLookupToken(tokenHash string) (uint64, error) {
+	for _, t := range m.lookupTokens {
+		if t.TokenHash == tokenHash && t.UsedAt == nil && t.ExpiresAt.After(time.Now()) {
+			t.UsedAt = ptrTime(time.Now())
+			return t.OrderID, nil
+		}
+	}
+	return 0, sql.ErrNoRows
+}
+
+var mockEmailRank = map[string]int{models.EmailSent: 0, models.EmailDeferred: 1, models.EmailDelivered: 2}
+
+func mockEmailStatusRank(status string) int {
+	if r, ok := mockEmailRank[status]; ok {
+		return r
+	}
+	return 3
+}
+
+func (m *mockRepository) CreateEmailMessage(msg *models.EmailMessage) error {
+	msg.ID = uint64(len(m.emailMessages) + 1)
+	if msg.Status == "" {
+		msg.Status = models.EmailSent
+	}
+	m.emailMessages = append(m.emailMessages, msg)
+	return nil
+}
+
+func (m *mockRepository) UpdateEmailMessageStatus(provider, messageID, email, status, detail string) (int64, error) {
+	var n int64
+	for _, msg := range m.emailMessages {
+		if msg.Provider != provider || msg.MessageID != messageID || (email != "" && msg.Email != email) {
+			continue
+		}
+		if mockEmailStatusRank(status) >= mockEmailStatusRank(msg.Status) {
+			msg.Status, msg.Detail = status, detail
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (m *mockRepository) ListOrderEmailMessages(orderID uint64) ([]models.EmailMessage, error) {
+	var out []models.EmailMessage
+	for _, msg := range m.emailMessages {
+		if msg.OrderID == orderID {
+			out = append(out, *msg)
+		}
+	}
+	return out, nil
+}
+
+func (m *mockRepository) SuppressEmail(s *models.EmailSuppression) (bool, error) {
+	for _, existing := range m.suppressions {
+		if existing.Email == s.Email {
+			return false, nil
+		}
+	}
+	m.suppressions = append(m.suppressions, *s)
+	return true, nil
+}
+
+func (m *mockRepository) SuppressedEmails(addresses []string) ([]string, error) {
+	var out []string
+	for _, a := range addresses {
+		for _, s := range m.suppressions {
+			if s.Email == a {
+				out = append(out, a)
+			}
+		}
+	}
+	return out, nil
+}
+
+func (m *mockRepository) ListEmailSuppressions(limit int) ([]models.EmailSuppression, error) {
+	if len(m.suppressions) > limit {
+		return m.suppressions[:limit], nil
+	}
+	return m.suppressions, nil
+}
+
+func (m *mockRepository) DeleteEmailSuppression(email string) (bool, error) {
+	for i, s := range m.suppressions {
+		if s.Email == email {
+			m.suppressions = append(m.suppressions[:i], m.suppressions[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 // =============================================================================
 // HELPER FUNCTIONS
 // =============================================================================
@@ -251,7 +653,9 @@ type mockPaymentGateway struct {
 	verifyResponse   *integrations.PayOSVerifyResponse
 	verifyErr        error
 	refundErr        error
+	refunds          atomic.Int32 // RefundPayment calls
 	cancelErr        error
+	lastCheckout     integrations.PayOSCheckoutRequest
 }
 
 func newMockPaymentGateway() *mockPaymentGateway {
@@ -263,7 +667,8 @@ func newMockPaymentGateway() *mockPaymentGateway {
 	}
 }
 
-func (m *mockPaymentGateway) CreateCheckout(req integrations.PayOSCheckoutRequest) (*integrations.PayOSCheckoutResponse, error) {
+func (m *mockPaymentGateway) CreateCheckout(ctx context.Context, req integrations.PayOSCheckoutRequest) (*integrations.PayOSCheckoutResponse, error) {
+	m.lastCheckout = req
 	if m.checkoutErr != nil {
 		return nil, m.checkoutErr
 	}
@@ -274,18 +679,19 @@ func (m *mockPaymentGateway) CreateCheckout(req integrations.PayOSCheckoutReques
 	return m.checkoutResponse, nil
 }
 
-func (m *mockPaymentGateway) VerifyPayment(orderCode int64) (*integrations.PayOSVerifyResponse, error) {
+func (m *mockPaymentGateway) VerifyPayment(ctx context.Context, orderCode int64) (*integrations.PayOSVerifyResponse, error) {
 	if m.verifyErr != nil {
 		return nil, m.verifyErr
 	}
 	return m.verifyResponse, nil
 }
 
-func (m *mockPaymentGateway) RefundPayment(orderCode int64, reason string) error {
+func (m *mockPaymentGateway) RefundPayment(ctx context.Context, orderCode int64, reason string) error {
+	m.refunds.Add(1)
 	return m.refundErr
 }
 
-func (m *mockPaymentGateway) CancelPayment(orderCode int64) error {
+func (m *mockPaymentGateway) CancelPayment(ctx context.Context, orderCode int64) error {
 	return m.cancelErr
 }
 
@@ -302,6 +708,7 @@ type mockEmailSender struct {
 	sendSymbioteReceiptErr   error
 	sendOrderDetailsErr      error
 	sentEmails               []string
+	lastLink                 string
 }
 
 func newMockEmailSender() *mockEmailSender {
@@ -310,7 +717,7 @@ func newMockEmailSender() *mockEmailSender {
 	}
 }
 
-func (m *mockEmailSender) SendOrderConfirmation(email, orderNumber string, amount float64) error {
+func (m *mockEmailSender) SendOrderConfirmation(ctx context.Context, email, orderNumber string, amount float64) error {
 	if m.sendOrderConfirmationErr != nil {
 		return m.sendOrderConfirmationErr
 	}
@@ -318,7 +725,7 @@ func (m *mockEmailSender) SendOrderConfirmation(email, orderNumber string, amoun
 	return nil
 }
 
-func (m *mockEmailSender) SendSymbioteReceipt(email, phone, status, elapsed string) error {
+func (m *mockEmailSender) SendSymbioteReceipt(ctx context.Context, email, phone, status, elapsed string) error {
 	if m.sendSymbioteReceiptErr != nil {
 		return m.sendSymbioteReceiptErr
 	}
@@ -326,10 +733,16 @@ func (m *mockEmailSender) SendSymbioteReceipt(email, phone, status, elapsed stri
 	return nil
 }
 
-func (m *mockEmailSender) SendOrderDetails(email string, order interface{}) error {
+func (m *mockEmailSender) SendOrderDetails(ctx context.Context, email string, order interface{}, link string) error {
 	if m.sendOrderDetailsErr != nil {
 		return m.sendOrderDetailsErr
 	}
+	m.sentEmails = append(m.sentEmails, email)
+	m.lastLink = link
+	return nil
+}
+
+func (m *mockEmailSender) SendTransferCode(ctx context.Context, email, code string, ttl time.Duration) error {
 	m.sentEmails = append(m.sentEmails, email)
 	return nil
 }
@@ -339,13 +752,27 @@ func (m *mockEmailSender) SendOrderDetails(email string, order interface{}) erro
 // =============================================================================
 
 type mockSheetSubmitter struct {
-	submitErr error
+	mu       sync.Mutex
+	queued   []uint64
+	queueErr error
 }
 
 func newMockSheetSubmitter() *mockSheetSubmitter {
 	return &mockSheetSubmitter{}
 }
 
-func (m *mockSheetSubmitter) SubmitOrder(name, phone, email, address, notes string, amount float64, timestamp interface{}) error {
-	return m.submitErr
+func (m *mockSheetSubmitter) QueueOrder(ctx context.Context, orderID uint64, paidAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.queueErr != nil {
+		return m.queueErr
+	}
+	m.queued = append(m.queued, orderID)
+	return nil
+}
+
+func (m *mockSheetSubmitter) Queued() []uint64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]uint64(nil), m.queued...)
 }
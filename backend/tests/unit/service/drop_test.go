@@ -1,6 +1,8 @@
 package service_test
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"testing"
 	"time"
@@ -8,6 +10,7 @@ import (
 	"ecommerce-backend/internal/integrations"
 	"ecommerce-backend/internal/models"
 	"ecommerce-backend/internal/service"
+	"ecommerce-backend/internal/utils/base32"
 
 	"gorm.io/datatypes"
 )
@@ -55,7 +58,7 @@ func TestGetActiveDrops_TableDriven(t *testing.T) {
 			tc.setup(repo)
 			srv := service.NewService(repo, nil, nil, nil)
 
-			drops, err := srv.GetActiveDrops()
+			drops, err := srv.GetActiveDrops(context.Background())
 
 			if tc.wantErr {
 				if err == nil {
@@ -138,7 +141,7 @@ func TestGetDropStatus_TableDriven(t *testing.T) {
 			tc.setup(repo)
 			srv := service.NewService(repo, nil, nil, nil)
 
-			status, err := srv.GetDropStatus(tc.dropID)
+			status, err := srv.GetDropStatus(context.Background(), tc.dropID)
 
 			if tc.wantErr {
 				if err == nil {
@@ -166,6 +169,7 @@ func TestPurchaseDrop_TableDriven(t *testing.T) {
 		setup          func(*mockRepository, *mockPaymentGateway)
 		wantErr        string
 		wantPaymentURL string
+		wantLocale     string
 	}{
 		{
 			name:   "success - valid purchase with PayOS",
@@ -174,7 +178,9 @@ func TestPurchaseDrop_TableDriven(t *testing.T) {
 				Quantity: 1, Name: "John", Phone: "0123",
 				Email:    "john@test.com", Address: "123 St",
 				Province: "HCM", District: "D1", Ward: "W1",
+				Locale: "en-US,en;q=0.9",
 			},
+			wantLocale: "en",
 			setup: func(m *mockRepository, pg *mockPaymentGateway) {
 				m.drops[1] = &models.LimitedDrop{
 					ID:         1,
@@ -327,7 +333,7 @@ func TestPurchaseDrop_TableDriven(t *testing.T) {
 			
 			srv := service.NewService(repo, pg, nil, nil)
 
-			result, err := srv.PurchaseDrop(tc.dropID, tc.request)
+			result, err := srv.PurchaseDrop(context.Background(), tc.dropID, tc.request)
 
 			if tc.wantErr != "" {
 				if err == nil {
@@ -348,6 +354,24 @@ func TestPurchaseDrop_TableDriven(t *testing.T) {
 			if tc.wantPaymentURL != "" && result.PaymentURL != tc.wantPaymentURL {
 				t.Errorf("expected payment URL '%s', got '%s'", tc.wantPaymentURL, result.PaymentURL)
 			}
+			id, err := base32.DecodeOrderNumber(result.OrderNumber)
+			if err != nil || repo.orders[id] == nil {
+				t.Fatalf("order number %q does not name the new order: %v", result.OrderNumber, err)
+			}
+			var shipping struct {
+				Locale string `json:"locale"`
+			}
+			json.Unmarshal(repo.orders[id].ShippingAddress, &shipping)
+			wantLocale := tc.wantLocale
+			if wantLocale == "" {
+				wantLocale = "vi"
+			}
+			if shipping.Locale != wantLocale {
+				t.Errorf("expected stored locale %q, got %q", wantLocale, shipping.Locale)
+			}
+			if pg.lastCheckout.Description != result.OrderNumber {
+				t.Errorf("expected PayOS description %q, got %q", result.OrderNumber, pg.lastCheckout.Description)
+			}
 		})
 	}
 }
@@ -456,9 +480,9 @@ func TestProcessSuccessfulDropPayment_TableDriven(t *testing.T) {
 			email := newMockEmailSender()
 			sheets := newMockSheetSubmitter()
 			tc.setup(repo, email, sheets)
-			srv := service.NewService(repo, nil, email, sheets)
+			srv := service.NewService(repo, newMockPaymentGateway(), email, sheets)
 
-			err := srv.ProcessSuccessfulDropPayment(tc.orderCode)
+			err := srv.ProcessSuccessfulDropPayment(context.Background(), tc.orderCode)
 
 			if tc.wantErr {
 				if err == nil {
@@ -569,7 +593,7 @@ func TestPurchaseDrop_EdgeCases(t *testing.T) {
 			tc.setup(repo, pg)
 			srv := service.NewService(repo, pg, nil, nil)
 
-			result, err := srv.PurchaseDrop(tc.dropID, tc.request)
+			result, err := srv.PurchaseDrop(context.Background(), tc.dropID, tc.request)
 
 			if tc.wantErr != "" {
 				if err == nil {
@@ -0,0 +1,112 @@
+package service_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"ecommerce-backend/internal/integrations"
+	"ecommerce-backend/internal/models"
+	"ecommerce-backend/internal/service"
+	"ecommerce-backend/internal/utils/base32"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleEmailEvent_TableDriven(t *testing.T) {
+	tests := []struct {
+		name           string
+		event          integrations.EmailEvent
+		wantStatus     string
+		wantSuppressed []string
+	}{
+		{
+			name:       "delivered",
+			event:      integrations.EmailEvent{Provider: "brevo", MessageID: "<m1@relay>", Email: "a@example.com", Status: models.EmailDelivered},
+			wantStatus: models.EmailDelivered,
+		},
+		{
+			name:           "hard bounce suppresses the address",
+			event:          integrations.EmailEvent{Provider: "brevo", MessageID: "<m1@relay>", Email: " A@Example.com", Status: models.EmailBounced, Suppress: true, Detail: "unknown user"},
+			wantStatus:     models.EmailBounced,
+			wantSuppressed: []string{"a@example.com"},
+		},
+		{
+			name:           "complaint about an unrecorded message still suppresses",
+			event:          integrations.EmailEvent{Provider: "resend", MessageID: "re_unknown", Email: "b@example.com", Status: models.EmailComplained, Suppress: true},
+			wantStatus:     models.EmailSent,
+			wantSuppressed: []string{"b@example.com"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			svc, repo := setup()
+			require.NoError(t, repo.CreateEmailMessage(&models.EmailMessage{Provider: "brevo", MessageID: "<m1@relay>", Email: "a@example.com", OrderID: 7}))
+
+			require.NoError(t, svc.HandleEmailEvent(context.Background(), tc.event))
+			assert.Equal(t, tc.wantStatus, repo.emailMessages[0].Status)
+
+			var suppressed []string
+			for _, s := range repo.suppressions {
+				suppressed = append(suppressed, s.Email)
+				assert.Equal(t, tc.event.Status, s.Reason)
+				assert.Equal(t, tc.event.Provider, s.Provider)
+			}
+			assert.Equal(t, tc.wantSuppressed, suppressed)
+		})
+	}
+}
+
+func TestGetOrderEmails(t *testing.T) {
+	svc, repo := setup()
+	repo.orders[7] = &models.Order{ID: 7, ShippingAddress: []byte(`{"email":"Buyer@example.com"}`)}
+	require.NoError(t, repo.CreateEmailMessage(&models.EmailMessage{Provider: "brevo", MessageID: "<m1@relay>", Email: "buyer@example.com", Template: "order_confirmation", OrderID: 7}))
+	require.NoError(t, repo.CreateEmailMessage(&models.EmailMessage{Provider: "brevo", MessageID: "<m2@relay>", Email: "other@example.com", OrderID: 8}))
+	ctx := context.Background()
+
+	got, err := svc.GetOrderEmails(ctx, 7)
+	require.NoError(t, err)
+	require.Len(t, got.Messages, 1)
+	assert.Equal(t, "order_confirmation", got.Messages[0].Template)
+	assert.Empty(t, got.Suppressed)
+
+	require.NoError(t, svc.HandleEmailEvent(ctx, integrations.EmailEvent{Provider: "brevo", MessageID: "<m1@relay>", Email: "buyer@example.com", Status: models.EmailBounced, Suppress: true}))
+	got, err = svc.GetOrderEmails(ctx, 7)
+	require.NoError(t, err)
+	assert.Equal(t, models.EmailBounced, got.Messages[0].Status)
+	assert.Equal(t, []string{"buyer@example.com"}, got.Suppressed)
+
+	repo.getOrderErr = sql.ErrNoRows
+	_, err = svc.GetOrderEmails(ctx, 9)
+	assert.ErrorIs(t, err, service.ErrOrderNotFound)
+}
+
+func TestRemoveEmailSuppression(t *testing.T) {
+	svc, repo := setup()
+	repo.suppressions = []models.EmailSuppression{{Email: "bounce@example.com", Reason: models.EmailBounced}}
+	ctx := context.Background()
+
+	list, err := svc.ListEmailSuppressions(ctx, 10)
+	require.NoError(t, err)
+	assert.Len(t, list, 1)
+
+	require.NoError(t, svc.RemoveEmailSuppression(ctx, " Bounce@Example.com "))
+	assert.Empty(t, repo.suppressions)
+	require.Len(t, repo.audit, 1)
+	assert.Equal(t, "email.unsuppress", repo.audit[0].Action)
+
+	assert.ErrorIs(t, svc.RemoveEmailSuppression(ctx, "bounce@example.com"), service.ErrSuppressionNotFound)
+}
+
+func TestSendOrderLookupLink_Suppressed(t *testing.T) {
+	repo := newMockRepository()
+	email := newMockEmailSender()
+	email.sendOrderDetailsErr = integrations.ErrEmailSuppressed
+	s := service.NewService(repo, nil, email, nil)
+	repo.orders[7] = &models.Order{ID: 7, ShippingAddress: []byte(`{"email":"bounce@example.com"}`)}
+
+	// Same answer as a sent link, so the endpoint does not reveal the bounce
+	assert.NoError(t, s.SendOrderLookupLink(context.Background(), base32.GenerateOrderNumber(7)))
+}
@@ -0,0 +1,77 @@
+package service_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"ecommerce-backend/internal/metrics"
+	"ecommerce-backend/internal/models"
+	"ecommerce-backend/internal/service"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPurchaseDrop_RecordsOutcome(t *testing.T) {
+	now := time.Now()
+	req := &service.PurchaseRequest{Quantity: 1, Name: "John", Phone: "0123"}
+
+	tests := []struct {
+		name    string
+		drop    *models.LimitedDrop
+		dropErr error
+		dropID  string
+		outcome string
+	}{
+		{
+			name:    "sold out",
+			drop:    &models.LimitedDrop{ID: 1, TotalStock: 10, Sold: 10, DropSize: 50, StartTime: now.Add(-time.Minute), IsActive: 1},
+			dropID:  "1",
+			outcome: "sold_out",
+		},
+		{
+			name:    "size limit",
+			drop:    &models.LimitedDrop{ID: 1, TotalStock: 100, Sold: 50, DropSize: 50, StartTime: now.Add(-time.Minute), IsActive: 1},
+			dropID:  "1",
+			outcome: "size_limit",
+		},
+		{
+			name:    "not started",
+			drop:    &models.LimitedDrop{ID: 1, StartTime: now.Add(time.Hour), IsActive: 1},
+			dropID:  "1",
+			outcome: "not_started",
+		},
+		{
+			name:    "not active",
+			drop:    &models.LimitedDrop{ID: 1, IsActive: 0},
+			dropID:  "1",
+			outcome: "not_active",
+		},
+		{
+			name:    "unknown drop keeps the label bounded",
+			dropErr: sql.ErrNoRows,
+			dropID:  "unknown",
+			outcome: "not_found",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := newMockRepository()
+			repo.getDropErr = tc.dropErr
+			if tc.drop != nil {
+				repo.drops[1] = tc.drop
+			}
+			srv := service.NewService(repo, newMockPaymentGateway(), nil, nil)
+
+			counter := metrics.PurchaseAttempts.WithLabelValues(tc.dropID, tc.outcome)
+			before := testutil.ToFloat64(counter)
+
+			_, err := srv.PurchaseDrop(context.Background(), 1, req)
+			assert.Error(t, err)
+			assert.Equal(t, before+1, testutil.ToFloat64(counter))
+		})
+	}
+}
@@ -0,0 +1,316 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"ecommerce-backend/internal/alerts"
+	"ecommerce-backend/internal/integrations"
+	"ecommerce-backend/internal/models"
+	"ecommerce-backend/internal/service"
+	"ecommerce-backend/internal/utils/base32"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/datatypes"
+)
+
+// recordingNotifier hands every notification to the test
+type recordingNotifier chan integrations.Notification
+
+func (r recordingNotifier) Notify(ctx context.Context, n integrations.Notification) error {
+	r <- n
+	return nil
+}
+
+func (r recordingNotifier) next(t *testing.T) integrations.Notification {
+	t.Helper()
+	select {
+	case n := <-r:
+		return n
+	case <-time.After(2 * time.Second):
+		t.Fatal("no notification sent")
+		return integrations.Notification{}
+	}
+}
+
+func TestProcessSuccessfulDropPayment_Notifications(t *testing.T) {
+	tests := []struct {
+		name       string
+		soldOut    bool
+		wantEvents []string
+	}{
+		{name: "winner", wantEvents: []string{integrations.EventOrderConfirmation, integrations.EventDropWon}},
+		{name: "loser", soldOut: true, wantEvents: []string{integrations.EventDropLost}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := newMockRepository()
+			repo.drops[1] = &models.LimitedDrop{ID: 1, ProductID: 10, TotalStock: 100}
+			repo.allowIncrement = !tc.soldOut
+			order := &models.Order{
+				ID:              100,
+				Status:          models.OrderPending,
+				Items:           datatypes.JSON(`[{"drop_id":1,"quantity":1,"product_id":10}]`),
+				ShippingAddress: datatypes.JSON(`{"name":"Lan","email":"lan@test.com","phone":"0912345678"}`),
+				CustomerPhone:   "0912345678",
+				TotalAmount:     1250000,
+			}
+			repo.orderByPayOS[12345] = order
+			repo.orders[100] = order
+
+			notifier := make(recordingNotifier, 4)
+			sheets := newMockSheetSubmitter()
+			srv := service.NewService(repo, newMockPaymentGateway(), newMockEmailSender(), sheets, service.WithNotifier(notifier))
+			require.NoError(t, srv.ProcessSuccessfulDropPayment(context.Background(), 12345))
+
+			for _, event := range tc.wantEvents {
+				n := notifier.next(t)
+				assert.Equal(t, event, n.Event)
+				assert.Equal(t, "Lan", n.Name)
+				assert.Equal(t, "0912345678", n.Phone)
+				assert.Equal(t, "lan@test.com", n.Email)
+				assert.Equal(t, base32.GenerateOrderNumber(100), n.OrderNumber)
+				assert.Equal(t, uint64(100), n.OrderID)
+				assert.Equal(t, uint64(1250000), n.Amount)
+				assert.False(t, n.At.IsZero())
+			}
+
+			// Winners are queued for the spreadsheet before their receipt
+			if tc.soldOut {
+				assert.Empty(t, sheets.Queued())
+			} else {
+				assert.Equal(t, []uint64{100}, sheets.Queued())
+			}
+		})
+	}
+}
+
+// alertSink hands every admin alert to the test
+type alertSink chan alerts.Alert
+
+func (s alertSink) Name() string { return "test" }
+
+func (s alertSink) Send(ctx context.Context, batch []alerts.Alert, digest bool) error {
+	for _, al := range batch {
+		s <- al
+	}
+	return nil
+}
+
+func (s alertSink) next(t *testing.T) alerts.Alert {
+	t.Helper()
+	select {
+	case al := <-s:
+		return al
+	case <-time.After(2 * time.Second):
+		t.Fatal("no alert sent")
+		return alerts.Alert{}
+	}
+}
+
+func TestProcessSuccessfulDropPayment_LoserRefundedOnce(t *testing.T) {
+	repo := newMockRepository()
+	repo.drops[1] = &models.LimitedDrop{ID: 1, Name: "Summer drop", ProductID: 10, TotalStock: 1, Sold: 1}
+	order := &models.Order{
+		ID:              100,
+		Status:          models.OrderPending,
+		Items:           datatypes.JSON(`[{"drop_id":1,"quantity":1,"product_id":10}]`),
+		ShippingAddress: datatypes.JSON(`{"name":"Lan","email":"lan@test.com","phone":"0912345678"}`),
+		CustomerPhone:   "0912345678",
+		TotalAmount:     1250000,
+	}
+	repo.orderByPayOS[12345] = order
+	repo.orders[100] = order
+	payment := newMockPaymentGateway()
+	notifier := make(recordingNotifier, 4)
+	srv := service.NewService(repo, payment, newMockEmailSender(), newMockSheetSubmitter(), service.WithNotifier(notifier))
+
+	// PayOS delivers the webhook three times
+	for i := 0; i < 3; i++ {
+		require.NoError(t, srv.ProcessSuccessfulDropPayment(context.Background(), 12345))
+	}
+
+	assert.Equal(t, integrations.EventDropLost, notifier.next(t).Event)
+	select {
+	case n := <-notifier:
+		t.Fatalf("unexpected %s notification", n.Event)
+	case <-time.After(50 * time.Millisecond):
+	}
+	assert.Equal(t, models.OrderCancelled, order.Status)
+
+	// The refund is queued with the cancellation and issued by the job
+	require.Len(t, repo.refunds, 1)
+	assert.Equal(t, uint64(100), repo.refunds[0].OrderID)
+	assert.Equal(t, int64(12345), repo.refunds[0].OrderCode)
+	assert.Equal(t, int32(0), payment.refunds.Load())
+	for i := 0; i < 2; i++ {
+		_, err := srv.ProcessRefunds(context.Background())
+		require.NoError(t, err)
+	}
+	assert.Equal(t, int32(1), payment.refunds.Load())
+	assert.Equal(t, models.RefundRefunded, repo.refunds[0].Status)
+	assert.NotNil(t, repo.refunds[0].RefundedAt)
+}
+
+func TestProcessRefunds_RetriesBeforeAlerting(t *testing.T) {
+	tests := []struct {
+		name       string
+		failures   int // refund attempts PayOS rejects
+		wantStatus string
+		wantAlert  bool
+	}{
+		{name: "first attempt", wantStatus: models.RefundRefunded},
+		{name: "second attempt", failures: 1, wantStatus: models.RefundRefunded},
+		{name: "attempts exhausted", failures: 3, wantStatus: models.RefundFailed, wantAlert: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := newMockRepository()
+			repo.orders[100] = &models.Order{ID: 100, Status: models.OrderCancelled, CustomerPhone: "0912345678", TotalAmount: 1250000}
+			require.NoError(t, repo.CreateRefund(&models.Refund{OrderID: 100, OrderCode: 12345, Reason: "Limited drop sold out"}))
+			payment := newMockPaymentGateway()
+
+			sink := make(alertSink, 4)
+			alerter := alerts.New(alerts.Options{Sinks: []alerts.Sink{sink}})
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			go alerter.Run(ctx)
+
+			srv := service.NewService(repo, payment, newMockEmailSender(), newMockSheetSubmitter(),
+				service.WithAlerter(alerter), service.WithRefundRetry(3, time.Nanosecond))
+			for i := 0; i < 4; i++ {
+				if i < tc.failures {
+					payment.refundErr = errors.New("payos: 500")
+				} else {
+					payment.refundErr = nil
+				}
+				_, err := srv.ProcessRefunds(context.Background())
+				require.NoError(t, err)
+				time.Sleep(time.Millisecond) // past the backoff
+			}
+
+			refund := repo.refunds[0]
+			assert.Equal(t, tc.wantStatus, refund.Status)
+			assert.Equal(t, int32(min(tc.failures+1, 3)), payment.refunds.Load())
+			if !tc.wantAlert {
+				assert.Empty(t, refund.LastError)
+				select {
+				case al := <-sink:
+					t.Fatalf("unexpected %s alert", al.Kind)
+				case <-time.After(50 * time.Millisecond):
+				}
+				return
+			}
+			assert.Equal(t, "payos: 500", refund.LastError)
+			al := sink.next(t)
+			assert.Equal(t, alerts.RefundFailed, al.Kind)
+			assert.Contains(t, al.Title, base32.GenerateOrderNumber(100))
+			assert.Contains(t, al.Detail, "0912345678")
+			assert.Contains(t, al.Detail, "payos: 500")
+		})
+	}
+}
+
+func TestProcessRefunds_Backoff(t *testing.T) {
+	repo := newMockRepository()
+	require.NoError(t, repo.CreateRefund(&models.Refund{OrderID: 100, OrderCode: 12345, Reason: "Limited drop sold out"}))
+	payment := newMockPaymentGateway()
+	payment.refundErr = errors.New("payos: 500")
+	srv := service.NewService(repo, payment, newMockEmailSender(), newMockSheetSubmitter(),
+		service.WithRefundRetry(5, time.Minute))
+
+	before := time.Now()
+	for i := 0; i < 2; i++ {
+		_, err := srv.ProcessRefunds(context.Background())
+		require.NoError(t, err)
+	}
+
+	// The second run is within the backoff
+	assert.Equal(t, int32(1), payment.refunds.Load())
+	refund := repo.refunds[0]
+	assert.Equal(t, models.RefundPending, refund.Status)
+	assert.Equal(t, 1, refund.Attempts)
+	assert.WithinDuration(t, before.Add(time.Minute), refund.NextAttemptAt, 5*time.Second)
+}
+
+func TestProcessSuccessfulDropPayment_AdminAlerts(t *testing.T) {
+	tests := []struct {
+		name      string
+		soldOut   bool
+		refundErr error
+		wantKinds []alerts.Kind
+	}{
+		{name: "winner takes the last unit", wantKinds: []alerts.Kind{alerts.OrderPaid, alerts.DropSoldOut}},
+		{name: "loser refunded", soldOut: true, wantKinds: []alerts.Kind{alerts.DropSoldOut}},
+		{name: "loser refund fails", soldOut: true, refundErr: errors.New("payos: 500"), wantKinds: []alerts.Kind{alerts.DropSoldOut, alerts.RefundFailed}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := newMockRepository()
+			repo.drops[1] = &models.LimitedDrop{ID: 1, Name: "Summer drop", ProductID: 10, TotalStock: 1}
+			if tc.soldOut {
+				repo.drops[1].Sold = 1
+			}
+			order := &models.Order{
+				ID:              100,
+				Status:          models.OrderPending,
+				Items:           datatypes.JSON(`[{"drop_id":1,"quantity":1,"product_id":10}]`),
+				ShippingAddress: datatypes.JSON(`{"name":"Lan","email":"lan@test.com","phone":"0912345678"}`),
+				CustomerPhone:   "0912345678",
+				TotalAmount:     1250000,
+			}
+			repo.orderByPayOS[12345] = order
+			repo.orders[100] = order
+			payment := newMockPaymentGateway()
+			payment.refundErr = tc.refundErr
+
+			sink := make(alertSink, 4)
+			alerter := alerts.New(alerts.Options{Sinks: []alerts.Sink{sink}})
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			go alerter.Run(ctx)
+
+			srv := service.NewService(repo, payment, newMockEmailSender(), newMockSheetSubmitter(),
+				service.WithNotifier(make(recordingNotifier, 4)), service.WithAlerter(alerter), service.WithRefundRetry(1, time.Minute))
+			require.NoError(t, srv.ProcessSuccessfulDropPayment(context.Background(), 12345))
+			_, err := srv.ProcessRefunds(context.Background())
+			require.NoError(t, err)
+
+			// Alerts are sent in the background, so they may arrive in any order
+			got := map[alerts.Kind]alerts.Alert{}
+			for range tc.wantKinds {
+				al := sink.next(t)
+				got[al.Kind] = al
+			}
+			for _, kind := range tc.wantKinds {
+				require.Contains(t, got, kind)
+			}
+			select {
+			case al := <-sink:
+				t.Fatalf("unexpected %s alert", al.Kind)
+			case <-time.After(50 * time.Millisecond):
+			}
+
+			orderNumber := base32.GenerateOrderNumber(100)
+			if al, ok := got[alerts.OrderPaid]; ok {
+				assert.Contains(t, al.Title, orderNumber)
+				require.NotNil(t, al.Order)
+				assert.Equal(t, models.OrderPaid, al.Order.Status)
+			}
+			if al, ok := got[alerts.DropSoldOut]; ok {
+				assert.Equal(t, "1", al.Key)
+				assert.Contains(t, al.Detail, "Summer drop")
+			}
+			if al, ok := got[alerts.RefundFailed]; ok {
+				assert.Contains(t, al.Title, orderNumber)
+				assert.Contains(t, al.Detail, "payos: 500")
+			}
+		})
+	}
+}
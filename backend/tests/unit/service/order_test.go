@@ -1,11 +1,16 @@
 package service_test
 
 import (
+	"context"
+	"database/sql"
 	"errors"
+	"strings"
 	"testing"
 	"time"
 
 	"ecommerce-backend/internal/models"
+	"ecommerce-backend/internal/service"
+	"ecommerce-backend/internal/utils/base32"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -50,7 +55,7 @@ func TestCreateOrder_TableDriven(t *testing.T) {
 				m.createOrderErr = tc.mockError
 			}
 
-			order, err := s.CreateOrder(tc.customerPhone, tc.shippingAddr, tc.items, tc.paymentMethod, tc.payOSOrderCode)
+			order, err := s.CreateOrder(context.Background(), tc.customerPhone, tc.shippingAddr, tc.items, tc.paymentMethod, tc.payOSOrderCode)
 
 			if tc.wantErr {
 				assert.Error(t, err)
@@ -95,7 +100,7 @@ func TestGetOrderByID_TableDriven(t *testing.T) {
 				m.orders[tc.orderID] = tc.mockReturn
 			}
 
-			order, err := s.GetOrderByID(tc.orderID)
+			order, err := s.GetOrderByID(context.Background(), tc.orderID)
 
 			if tc.wantErr {
 				assert.Error(t, err)
@@ -142,7 +147,7 @@ func TestGetOrdersByUserPhone_TableDriven(t *testing.T) {
 				m.ordersByPhone[tc.phone] = tc.mockReturn
 			}
 
-			orders, err := s.GetOrdersByUserPhone(tc.phone)
+			orders, err := s.GetOrdersByUserPhone(context.Background(), tc.phone)
 
 			if tc.wantErr {
 				assert.Error(t, err)
@@ -153,3 +158,86 @@ func TestGetOrdersByUserPhone_TableDriven(t *testing.T) {
 		})
 	}
 }
+
+func TestLookupOrder_TableDriven(t *testing.T) {
+	order := &models.Order{
+		ID:              7,
+		CustomerPhone:   "0901234567",
+		ShippingAddress: []byte(`{"name":"An","email":"An@Example.com","address":"1 Le Loi","province":"HCM"}`),
+		Items:           []byte(`[{"product_id":3,"drop_id":1,"name":"Watch","price":500000,"quantity":1}]`),
+		Status:          models.OrderPaid,
+		TotalAmount:     500000,
+	}
+	number := base32.GenerateOrderNumber(order.ID)
+
+	tests := []struct {
+		name    string
+		query   service.OrderLookup
+		wantErr error
+	}{
+		{name: "email", query: service.OrderLookup{OrderNumber: number, Email: " an@example.COM "}},
+		{name: "phone digits", query: service.OrderLookup{OrderNumber: number, PhoneLast4: "4567"}},
+		{name: "lowercase number", query: service.OrderLookup{OrderNumber: strings.ToLower(number), PhoneLast4: "4567"}},
+		{name: "wrong email", query: service.OrderLookup{OrderNumber: number, Email: "someone@example.com"}, wantErr: service.ErrOrderLookup},
+		{name: "wrong phone digits", query: service.OrderLookup{OrderNumber: number, PhoneLast4: "0000"}, wantErr: service.ErrOrderLookup},
+		{name: "too few phone digits", query: service.OrderLookup{OrderNumber: number, PhoneLast4: "67"}, wantErr: service.ErrOrderLookup},
+		{name: "sequential ID", query: service.OrderLookup{OrderNumber: "7", PhoneLast4: "4567"}, wantErr: service.ErrOrderLookup},
+		// The unkeyed legacy number of order 7, which is after the legacy cutover
+		{name: "sequential ID, legacy encoded", query: service.OrderLookup{OrderNumber: "DV-G4======", PhoneLast4: "4567"}, wantErr: service.ErrOrderLookup},
+		{name: "unknown order", query: service.OrderLookup{OrderNumber: base32.GenerateOrderNumber(8), PhoneLast4: "4567"}, wantErr: service.ErrOrderLookup},
+		{name: "no factor", query: service.OrderLookup{OrderNumber: number}, wantErr: service.ErrLookupFactor},
+		{name: "unknown link", query: service.OrderLookup{Token: "nope"}, wantErr: service.ErrInvalidLookupLink},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			s, m := setup()
+			m.orders[order.ID] = order
+			if tc.query.OrderNumber == base32.GenerateOrderNumber(8) {
+				m.getOrderErr = sql.ErrNoRows
+			}
+
+			view, err := s.LookupOrder(context.Background(), tc.query)
+			if tc.wantErr != nil {
+				assert.ErrorIs(t, err, tc.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, &service.OrderView{
+				OrderNumber: number,
+				Status:      "paid",
+				Phone:       "*******567",
+				Email:       "A***@Example.com",
+				Province:    "HCM",
+				Items:       []service.OrderViewItem{{Name: "Watch", Price: 500000, Quantity: 1}},
+				TotalAmount: 500000,
+			}, view)
+		})
+	}
+}
+
+func TestSendOrderLookupLink(t *testing.T) {
+	repo := newMockRepository()
+	email := newMockEmailSender()
+	s := service.NewService(repo, nil, email, nil, service.WithFrontendURL("https://shop.example/"))
+	repo.orders[7] = &models.Order{ID: 7, CustomerPhone: "0901234567", ShippingAddress: []byte(`{"email":"an@example.com"}`)}
+	repo.orders[8] = &models.Order{ID: 8, CustomerPhone: "0901234567", ShippingAddress: []byte(`{}`)}
+	ctx := context.Background()
+
+	// Unknown orders and orders without an email succeed without sending
+	assert.NoError(t, s.SendOrderLookupLink(ctx, "DV-nope"))
+	assert.NoError(t, s.SendOrderLookupLink(ctx, base32.GenerateOrderNumber(8)))
+	assert.Empty(t, email.sentEmails)
+
+	assert.NoError(t, s.SendOrderLookupLink(ctx, base32.GenerateOrderNumber(7)))
+	assert.Equal(t, []string{"an@example.com"}, email.sentEmails)
+	token, ok := strings.CutPrefix(email.lastLink, "https://shop.example/orders/view?t=")
+	assert.True(t, ok, email.lastLink)
+
+	view, err := s.LookupOrder(ctx, service.OrderLookup{Token: token})
+	assert.NoError(t, err)
+	assert.Equal(t, base32.GenerateOrderNumber(7), view.OrderNumber)
+
+	_, err = s.LookupOrder(ctx, service.OrderLookup{Token: token})
+	assert.ErrorIs(t, err, service.ErrInvalidLookupLink, "links are one-time")
+}
@@ -1,6 +1,7 @@
 package service_test
 
 import (
+	"context"
 	"errors"
 	"testing"
 
@@ -44,7 +45,7 @@ func TestGetProduct_TableDriven(t *testing.T) {
 				m.products[tc.productID] = tc.mockReturn
 			}
 
-			product, err := s.GetProduct(tc.productID)
+			product, err := s.GetProduct(context.Background(), tc.productID)
 
 			if tc.wantErr {
 				assert.Error(t, err)
@@ -88,7 +89,7 @@ func TestListProducts_TableDriven(t *testing.T) {
 			s, m := setup()
 			tc.setupMock(m)
 
-			products, err := s.ListProducts()
+			products, err := s.ListProducts(context.Background())
 
 			if tc.wantErr {
 				assert.Error(t, err)
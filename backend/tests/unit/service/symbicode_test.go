@@ -1,11 +1,19 @@
 package service_test
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"testing"
+	"time"
 
+	"ecommerce-backend/internal/geo"
+	"ecommerce-backend/internal/integrations"
 	"ecommerce-backend/internal/models"
+	"ecommerce-backend/internal/repository"
 	"ecommerce-backend/internal/service"
+	"ecommerce-backend/internal/utils/uuid"
 )
 
 // =============================================================================
@@ -51,7 +59,7 @@ func TestGenerateSymbicode_TableDriven(t *testing.T) {
 			tc.setup(repo)
 			srv := service.NewService(repo, nil, nil, nil)
 
-			sym, err := srv.GenerateSymbicode(tc.productID, tc.orderID)
+			sym, err := srv.GenerateSymbicode(context.Background(), tc.productID, tc.orderID)
 
 			if tc.wantErr {
 				if err == nil {
@@ -138,7 +146,7 @@ func TestVerifySymbicode_TableDriven(t *testing.T) {
 			tc.setup(repo)
 			srv := service.NewService(repo, nil, nil, nil)
 
-			sym, isFirst, err := srv.VerifySymbicode(tc.codeStr)
+			res, err := srv.VerifySymbicode(context.Background(), tc.codeStr, service.ScanInfo{IP: "203.0.113.7"})
 
 			if tc.wantErr {
 				if err == nil {
@@ -150,6 +158,7 @@ func TestVerifySymbicode_TableDriven(t *testing.T) {
 			if err != nil {
 				t.Fatalf("expected success, got error: %v", err)
 			}
+			sym, isFirst := res.Symbicode, res.IsFirstActivation
 			if sym == nil {
 				t.Fatal("expected symbicode, got nil")
 			}
@@ -159,3 +168,821 @@ func TestVerifySymbicode_TableDriven(t *testing.T) {
 		})
 	}
 }
+
+func TestVerifySymbicode_SignedTokens_TableDriven(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef")
+
+	// tamper flips the last character of a token's signature
+	tamper := func(token string) string {
+		last := token[len(token)-1]
+		if last == 'A' {
+			return token[:len(token)-1] + "B"
+		}
+		return token[:len(token)-1] + "A"
+	}
+
+	tests := []struct {
+		name  string
+		input func(sym *models.Symbicode, repo *mockRepository) string
+		// acceptUnsigned keeps verifying bare UUIDs
+		acceptUnsigned bool
+		wantErr        error
+	}{
+		{
+			name:  "valid token",
+			input: func(sym *models.Symbicode, _ *mockRepository) string { return sym.Token },
+		},
+		{
+			name: "scanned QR payload",
+			input: func(sym *models.Symbicode, _ *mockRepository) string {
+				return "https://donaldwatch.xyz" + service.GenerateQRCodeData(sym.Token)
+			},
+		},
+		{
+			name: "tampered signature",
+			input: func(sym *models.Symbicode, _ *mockRepository) string {
+				return tamper(sym.Token)
+			},
+			wantErr: service.ErrForgedSymbicode,
+		},
+		{
+			name: "token signed with another key",
+			input: func(sym *models.Symbicode, _ *mockRepository) string {
+				other, _ := service.NewService(newMockRepository(), nil, nil, nil,
+					service.WithSymbicodeSigning([]byte("another key, another key, another"), false),
+				).GenerateSymbicode(context.Background(), 1, nil)
+				return other.Token
+			},
+			wantErr: service.ErrForgedSymbicode,
+		},
+		{
+			name: "forged token rejected before the lookup",
+			input: func(sym *models.Symbicode, repo *mockRepository) string {
+				repo.getSymErr = errors.New("database is down")
+				return tamper(sym.Token)
+			},
+			wantErr: service.ErrForgedSymbicode,
+		},
+		{
+			name: "correctly signed but unknown",
+			input: func(sym *models.Symbicode, repo *mockRepository) string {
+				delete(repo.symbicodes, string(sym.Code))
+				return sym.Token
+			},
+			wantErr: service.ErrUnknownSymbicode,
+		},
+		{
+			name: "bare UUID rejected",
+			input: func(sym *models.Symbicode, _ *mockRepository) string {
+				return uuid.FormatUUIDToString(sym.Code)
+			},
+			wantErr: service.ErrUnsignedSymbicode,
+		},
+		{
+			name: "bare UUID accepted during migration",
+			input: func(sym *models.Symbicode, _ *mockRepository) string {
+				return uuid.FormatUUIDToString(sym.Code)
+			},
+			acceptUnsigned: true,
+		},
+		{
+			name:    "malformed",
+			input:   func(*models.Symbicode, *mockRepository) string { return "AQID" },
+			wantErr: service.ErrMalformedSymbicode,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := newMockRepository()
+			srv := service.NewService(repo, nil, nil, nil, service.WithSymbicodeSigning(key, tc.acceptUnsigned))
+
+			sym, err := srv.GenerateSymbicode(context.Background(), 10, nil)
+			if err != nil {
+				t.Fatalf("generate: %v", err)
+			}
+
+			res, err := srv.VerifySymbicode(context.Background(), tc.input(sym, repo), service.ScanInfo{IP: "203.0.113.7"})
+
+			if tc.wantErr != nil {
+				if !errors.Is(err, tc.wantErr) {
+					t.Fatalf("expected %v, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected success, got error: %v", err)
+			}
+			if !res.IsFirstActivation || res.Symbicode.ID != sym.ID {
+				t.Fatalf("expected first activation of %d, got %d (first=%v)", sym.ID, res.Symbicode.ID, res.IsFirstActivation)
+			}
+		})
+	}
+}
+
+func TestVerifySymbicode_KeyRotation(t *testing.T) {
+	oldKey := []byte("0123456789abcdef0123456789abcdef")
+	newKey := []byte("fedcba9876543210fedcba9876543210")
+	repo := newMockRepository()
+	old := service.NewService(repo, nil, nil, nil, service.WithSymbicodeSigning(oldKey, false))
+	sym, err := old.GenerateSymbicode(context.Background(), 10, nil)
+	if err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		opts    []service.Option
+		wantErr error
+	}{
+		{
+			name: "old key kept in the ring",
+			opts: []service.Option{service.WithSymbicodeSigning(newKey, false), service.WithPreviousSymbicodeKeys(oldKey)},
+		},
+		{
+			name:    "old key dropped",
+			opts:    []service.Option{service.WithSymbicodeSigning(newKey, false)},
+			wantErr: service.ErrForgedSymbicode,
+		},
+		{
+			name:    "restart without a key",
+			wantErr: service.ErrForgedSymbicode,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := service.NewService(repo, nil, nil, nil, tc.opts...)
+			res, err := srv.VerifySymbicode(context.Background(), sym.Token, service.ScanInfo{IP: "203.0.113.7"})
+			if tc.wantErr != nil {
+				if !errors.Is(err, tc.wantErr) {
+					t.Fatalf("expected %v, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("label printed before the key changed: %v", err)
+			}
+			if res.Symbicode.ID != sym.ID {
+				t.Fatalf("expected symbicode %d, got %d", sym.ID, res.Symbicode.ID)
+			}
+		})
+	}
+}
+
+// fakeLocator places documentation IPs (203.0.113.x) by their last octet
+type fakeLocator map[string]geo.Location
+
+func (f fakeLocator) Locate(ip string) geo.Location { return f[ip] }
+
+func TestVerifySymbicode_ScanHistory_TableDriven(t *testing.T) {
+	locator := fakeLocator{
+		"203.0.113.1": {Country: "VN", City: "Hanoi"},
+		"203.0.113.2": {Country: "VN", City: "Ho Chi Minh City"},
+		"203.0.113.3": {Country: "CN", City: "Shenzhen"},
+		"203.0.113.4": {Country: "TH", City: "Bangkok"},
+	}
+	scanner := func(ip string) service.ScanInfo { return service.ScanInfo{IP: ip, UserAgent: "Mozilla/5.0"} }
+
+	tests := []struct {
+		name        string
+		policy      service.ScanPolicy
+		scans       []service.ScanInfo
+		wantCount   int
+		wantReasons []string
+	}{
+		{
+			name:      "owner rescanning from home",
+			policy:    service.DefaultScanPolicy,
+			scans:     []service.ScanInfo{scanner("203.0.113.1"), scanner("203.0.113.1"), scanner("203.0.113.1")},
+			wantCount: 3,
+		},
+		{
+			name:        "many IPs",
+			policy:      service.ScanPolicy{MaxIPs: 3},
+			scans:       []service.ScanInfo{scanner("198.51.100.1"), scanner("198.51.100.2"), scanner("198.51.100.3")},
+			wantCount:   3,
+			wantReasons: []string{service.ReasonManyIPs},
+		},
+		{
+			name:        "many locations",
+			policy:      service.ScanPolicy{MaxLocations: 3},
+			scans:       []service.ScanInfo{scanner("203.0.113.1"), scanner("203.0.113.3"), scanner("203.0.113.4")},
+			wantCount:   3,
+			wantReasons: []string{service.ReasonManyLocations},
+		},
+		{
+			name:        "both rules",
+			policy:      service.ScanPolicy{MaxIPs: 4, MaxLocations: 4},
+			scans:       []service.ScanInfo{scanner("203.0.113.1"), scanner("203.0.113.2"), scanner("203.0.113.3"), scanner("203.0.113.4")},
+			wantCount:   4,
+			wantReasons: []string{service.ReasonManyIPs, service.ReasonManyLocations},
+		},
+		{
+			name:      "disabled rules",
+			policy:    service.ScanPolicy{},
+			scans:     []service.ScanInfo{scanner("203.0.113.1"), scanner("203.0.113.3"), scanner("203.0.113.4")},
+			wantCount: 3,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := newMockRepository()
+			srv := service.NewService(repo, nil, nil, nil, service.WithScanPolicy(tc.policy), service.WithGeoLocator(locator))
+			sym, err := srv.GenerateSymbicode(context.Background(), 10, nil)
+			if err != nil {
+				t.Fatalf("generate: %v", err)
+			}
+
+			var res *service.VerifyResult
+			for _, scan := range tc.scans {
+				if res, err = srv.VerifySymbicode(context.Background(), sym.Token, scan); err != nil {
+					t.Fatalf("verify: %v", err)
+				}
+			}
+
+			if res.Scans.Count != tc.wantCount {
+				t.Fatalf("expected %d scans, got %d", tc.wantCount, res.Scans.Count)
+			}
+			if fmt.Sprint(res.Scans.Reasons) != fmt.Sprint(tc.wantReasons) || res.Scans.Suspicious != (len(tc.wantReasons) > 0) {
+				t.Fatalf("expected reasons %v, got %v (suspicious=%v)", tc.wantReasons, res.Scans.Reasons, res.Scans.Suspicious)
+			}
+
+			// The first scan activates with the scanner's IP and every scan is kept
+			if got := repo.symbicodes[string(sym.Code)].ActivatedIP; got != tc.scans[0].IP {
+				t.Fatalf("expected activation IP %s, got %q", tc.scans[0].IP, got)
+			}
+			history, _ := srv.ListSymbicodeScans(context.Background(), sym.ID, 100)
+			if len(history) != len(tc.scans) || history[0].UserAgent != "Mozilla/5.0" {
+				t.Fatalf("unexpected scan history: %+v", history)
+			}
+			if loc := locator[tc.scans[0].IP]; history[len(history)-1].Country != loc.Country {
+				t.Fatalf("expected first scan located in %q, got %q", loc.Country, history[len(history)-1].Country)
+			}
+
+			report, err := srv.ListSuspiciousSymbicodes(context.Background(), 10)
+			if err != nil {
+				t.Fatalf("report: %v", err)
+			}
+			if (len(report) == 1) != (len(tc.wantReasons) > 0) {
+				t.Fatalf("expected the report to list suspicious codes only, got %+v", report)
+			}
+			if len(report) == 1 && report[0].Code != uuid.FormatUUIDToString(sym.Code) {
+				t.Fatalf("unexpected report entry %+v", report[0])
+			}
+		})
+	}
+}
+
+func TestAutoActivateExpiredSymbicodes_TableDriven(t *testing.T) {
+	old := time.Now().Add(-96 * time.Hour)
+	fresh := time.Now().Add(-time.Hour)
+
+	// addCodes stores n codes created at createdAt, activated or not
+	addCodes := func(m *mockRepository, n int, createdAt time.Time, activated uint8) {
+		for i := 0; i < n; i++ {
+			id := uint64(len(m.symbicodes) + 1)
+			m.symbicodes[fmt.Sprint(id)] = &models.Symbicode{ID: id, CreatedAt: createdAt, IsActivated: activated}
+		}
+	}
+
+	tests := []struct {
+		name       string
+		setup      func(*mockRepository)
+		wantCount  int
+		wantAudits []int // count recorded by each audit entry
+		wantErr    bool
+	}{
+		{
+			name:       "nothing expired - no audit entry",
+			setup:      func(m *mockRepository) { addCodes(m, 2, fresh, 0) },
+			wantCount:  0,
+			wantAudits: nil,
+		},
+		{
+			name: "activates only unscanned codes past the grace period",
+			setup: func(m *mockRepository) {
+				addCodes(m, 3, old, 0)
+				addCodes(m, 1, old, 1)
+				addCodes(m, 2, fresh, 0)
+			},
+			wantCount:  3,
+			wantAudits: []int{3},
+		},
+		{
+			name:       "one audit entry per batch",
+			setup:      func(m *mockRepository) { addCodes(m, 501, old, 0) },
+			wantCount:  501,
+			wantAudits: []int{500, 1},
+		},
+		{
+			name: "error - repository failure",
+			setup: func(m *mockRepository) {
+				addCodes(m, 1, old, 0)
+				m.autoActErr = errors.New("database is locked")
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := newMockRepository()
+			tc.setup(repo)
+			srv := service.NewService(repo, nil, nil, nil)
+
+			n, err := srv.AutoActivateExpiredSymbicodes(context.Background(), 72*time.Hour)
+
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected success, got error: %v", err)
+			}
+			if n != tc.wantCount {
+				t.Fatalf("expected %d activations, got %d", tc.wantCount, n)
+			}
+			if len(repo.audit) != len(tc.wantAudits) {
+				t.Fatalf("expected %d audit entries, got %d", len(tc.wantAudits), len(repo.audit))
+			}
+			for i, entry := range repo.audit {
+				var details struct {
+					Count int `json:"count"`
+				}
+				if err := json.Unmarshal(entry.Details, &details); err != nil {
+					t.Fatalf("audit details: %v", err)
+				}
+				if entry.Actor != "scheduler" || entry.Action != "symbicode.auto_activate" || details.Count != tc.wantAudits[i] {
+					t.Fatalf("unexpected audit entry %d: %+v", i, entry)
+				}
+			}
+			for _, s := range repo.symbicodes {
+				if s.ActivatedIP == models.AutoActivatedIP && s.CreatedAt.After(time.Now().Add(-72*time.Hour)) {
+					t.Fatalf("code %d activated before its grace period ended", s.ID)
+				}
+			}
+		})
+	}
+}
+
+func TestSymbicodeTransfer_TableDriven(t *testing.T) {
+	buyer := service.Contact{Phone: "0901 234 567"}
+	reseller := service.Contact{Phone: "0987654321"}
+	collector := service.Contact{Email: "collector@example.com"}
+
+	// notifier receives the transfer codes of the running case
+	var notifier recordingNotifier
+
+	// sendCode starts a transfer by owner and returns the code sent
+	sendCode := func(t *testing.T, srv service.Service, token string, owner service.Contact) integrations.Notification {
+		t.Helper()
+		if _, err := srv.InitiateSymbicodeTransfer(context.Background(), token, owner); err != nil {
+			t.Fatalf("transfer by %+v: %v", owner, err)
+		}
+		return notifier.next(t)
+	}
+	// offer transfers the code from owner and returns the claim token
+	offer := func(t *testing.T, srv service.Service, token string, owner service.Contact) string {
+		t.Helper()
+		sent := sendCode(t, srv, token, owner)
+		o, err := srv.ConfirmSymbicodeTransfer(context.Background(), token, owner, sent.Code)
+		if err != nil {
+			t.Fatalf("offer by %+v: %v", owner, err)
+		}
+		return o.Token
+	}
+	// wrong is a code other than code
+	wrong := func(code string) string {
+		if code[5] == '0' {
+			return code[:5] + "1"
+		}
+		return code[:5] + "0"
+	}
+
+	tests := []struct {
+		name       string
+		run        func(t *testing.T, srv service.Service, token string) error
+		wantErr    error
+		wantOwners int
+	}{
+		{
+			name: "buyer matches by phone without separators",
+			run: func(t *testing.T, srv service.Service, token string) error {
+				claim := offer(t, srv, token, service.Contact{Phone: "0901-234-567"})
+				_, err := srv.ClaimSymbicodeTransfer(context.Background(), claim, reseller)
+				return err
+			},
+			wantOwners: 2,
+		},
+		{
+			name: "buyer matches by email, case-insensitively",
+			run: func(t *testing.T, srv service.Service, token string) error {
+				claim := offer(t, srv, token, service.Contact{Email: "buyer@example.COM"})
+				_, err := srv.ClaimSymbicodeTransfer(context.Background(), claim, reseller)
+				return err
+			},
+			wantOwners: 2,
+		},
+		{
+			name: "chain of resales",
+			run: func(t *testing.T, srv service.Service, token string) error {
+				claim := offer(t, srv, token, buyer)
+				if _, err := srv.ClaimSymbicodeTransfer(context.Background(), claim, reseller); err != nil {
+					return err
+				}
+				claim = offer(t, srv, token, reseller)
+				_, err := srv.ClaimSymbicodeTransfer(context.Background(), claim, collector)
+				return err
+			},
+			wantOwners: 3,
+		},
+		{
+			name: "previous owner can no longer transfer",
+			run: func(t *testing.T, srv service.Service, token string) error {
+				claim := offer(t, srv, token, buyer)
+				if _, err := srv.ClaimSymbicodeTransfer(context.Background(), claim, reseller); err != nil {
+					return err
+				}
+				_, err := srv.InitiateSymbicodeTransfer(context.Background(), token, buyer)
+				return err
+			},
+			wantErr:    service.ErrTransferDenied,
+			wantOwners: 2,
+		},
+		{
+			name: "stranger cannot transfer",
+			run: func(t *testing.T, srv service.Service, token string) error {
+				_, err := srv.InitiateSymbicodeTransfer(context.Background(), token, collector)
+				return err
+			},
+			wantErr:    service.ErrTransferDenied,
+			wantOwners: 1,
+		},
+		{
+			name: "code goes only to the contact given",
+			run: func(t *testing.T, srv service.Service, token string) error {
+				sent := sendCode(t, srv, token, service.Contact{Email: "buyer@example.com"})
+				if sent.Event != integrations.EventTransferCode || sent.Email != "buyer@example.com" || sent.Phone != "" || len(sent.Code) != 6 {
+					t.Fatalf("unexpected code notification %+v", sent)
+				}
+				return nil
+			},
+			wantOwners: 1,
+		},
+		{
+			name: "label and contact alone create no offer",
+			run: func(t *testing.T, srv service.Service, token string) error {
+				sent := sendCode(t, srv, token, buyer)
+				_, err := srv.ConfirmSymbicodeTransfer(context.Background(), token, buyer, wrong(sent.Code))
+				return err
+			},
+			wantErr:    service.ErrInvalidTransferCode,
+			wantOwners: 1,
+		},
+		{
+			name: "code must be confirmed on the channel it went to",
+			run: func(t *testing.T, srv service.Service, token string) error {
+				sent := sendCode(t, srv, token, buyer)
+				_, err := srv.ConfirmSymbicodeTransfer(context.Background(), token, service.Contact{Email: "buyer@example.com"}, sent.Code)
+				return err
+			},
+			wantErr:    service.ErrInvalidTransferCode,
+			wantOwners: 1,
+		},
+		{
+			name: "wrong guesses kill the code",
+			run: func(t *testing.T, srv service.Service, token string) error {
+				sent := sendCode(t, srv, token, buyer)
+				for i := 0; i < repository.TransferCodeAttempts; i++ {
+					if _, err := srv.ConfirmSymbicodeTransfer(context.Background(), token, buyer, wrong(sent.Code)); !errors.Is(err, service.ErrInvalidTransferCode) {
+						t.Fatalf("guess %d: expected a wrong code to be rejected, got %v", i, err)
+					}
+				}
+				_, err := srv.ConfirmSymbicodeTransfer(context.Background(), token, buyer, sent.Code)
+				return err
+			},
+			wantErr:    service.ErrInvalidTransferCode,
+			wantOwners: 1,
+		},
+		{
+			name: "a new code supersedes the pending one",
+			run: func(t *testing.T, srv service.Service, token string) error {
+				first := sendCode(t, srv, token, buyer)
+				if second := sendCode(t, srv, token, buyer); second.Code == first.Code {
+					t.Skip("the same code was drawn twice")
+				}
+				_, err := srv.ConfirmSymbicodeTransfer(context.Background(), token, buyer, first.Code)
+				return err
+			},
+			wantErr:    service.ErrInvalidTransferCode,
+			wantOwners: 1,
+		},
+		{
+			name: "code is single-use",
+			run: func(t *testing.T, srv service.Service, token string) error {
+				sent := sendCode(t, srv, token, buyer)
+				if _, err := srv.ConfirmSymbicodeTransfer(context.Background(), token, buyer, sent.Code); err != nil {
+					return err
+				}
+				_, err := srv.ConfirmSymbicodeTransfer(context.Background(), token, buyer, sent.Code)
+				return err
+			},
+			wantErr:    service.ErrInvalidTransferCode,
+			wantOwners: 1,
+		},
+		{
+			name: "one contact at a time",
+			run: func(t *testing.T, srv service.Service, token string) error {
+				_, err := srv.InitiateSymbicodeTransfer(context.Background(), token, service.Contact{Phone: "0901234567", Email: "buyer@example.com"})
+				return err
+			},
+			wantErr:    service.ErrTransferChannel,
+			wantOwners: 1,
+		},
+		{
+			name: "contact is required",
+			run: func(t *testing.T, srv service.Service, token string) error {
+				_, err := srv.InitiateSymbicodeTransfer(context.Background(), token, service.Contact{Phone: " "})
+				return err
+			},
+			wantErr:    service.ErrTransferContact,
+			wantOwners: 1,
+		},
+		{
+			name: "claim token is single-use",
+			run: func(t *testing.T, srv service.Service, token string) error {
+				claim := offer(t, srv, token, buyer)
+				if _, err := srv.ClaimSymbicodeTransfer(context.Background(), claim, reseller); err != nil {
+					return err
+				}
+				_, err := srv.ClaimSymbicodeTransfer(context.Background(), claim, collector)
+				return err
+			},
+			wantErr:    service.ErrInvalidTransfer,
+			wantOwners: 2,
+		},
+		{
+			name: "a new offer supersedes the pending one",
+			run: func(t *testing.T, srv service.Service, token string) error {
+				first := offer(t, srv, token, buyer)
+				offer(t, srv, token, buyer)
+				_, err := srv.ClaimSymbicodeTransfer(context.Background(), first, reseller)
+				return err
+			},
+			wantErr:    service.ErrInvalidTransfer,
+			wantOwners: 1,
+		},
+		{
+			name: "forged label",
+			run: func(t *testing.T, srv service.Service, token string) error {
+				last := "A"
+				if token[len(token)-1] == 'A' {
+					last = "B"
+				}
+				_, err := srv.InitiateSymbicodeTransfer(context.Background(), token[:len(token)-1]+last, buyer)
+				return err
+			},
+			wantErr:    service.ErrTransferDenied,
+			wantOwners: 1,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := newMockRepository()
+			repo.orders[7] = &models.Order{ID: 7, CustomerPhone: "0901234567", ShippingAddress: []byte(`{"email":"buyer@example.com"}`)}
+			notifier = make(recordingNotifier, 8)
+			srv := service.NewService(repo, nil, nil, nil, service.WithNotifier(notifier))
+			orderID := uint64(7)
+			sym, err := srv.GenerateSymbicode(context.Background(), 10, &orderID)
+			if err != nil {
+				t.Fatalf("generate: %v", err)
+			}
+
+			err = tc.run(t, srv, sym.Token)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("expected error %v, got %v", tc.wantErr, err)
+			}
+
+			res, err := srv.VerifySymbicode(context.Background(), sym.Token, service.ScanInfo{IP: "203.0.113.7"})
+			if err != nil {
+				t.Fatalf("verify: %v", err)
+			}
+			if res.Owners != tc.wantOwners {
+				t.Fatalf("expected %d owners, got %d", tc.wantOwners, res.Owners)
+			}
+		})
+	}
+}
+
+func TestSymbicodeTransfer_Expired(t *testing.T) {
+	repo := newMockRepository()
+	repo.orders[7] = &models.Order{ID: 7, CustomerPhone: "0901234567"}
+	notifier := make(recordingNotifier, 1)
+	srv := service.NewService(repo, nil, nil, nil, service.WithTransferTTL(-time.Minute), service.WithNotifier(notifier))
+	orderID := uint64(7)
+	sym, _ := srv.GenerateSymbicode(context.Background(), 10, &orderID)
+
+	owner := service.Contact{Phone: "0901234567"}
+	if _, err := srv.InitiateSymbicodeTransfer(context.Background(), sym.Token, owner); err != nil {
+		t.Fatalf("send code: %v", err)
+	}
+	offer, err := srv.ConfirmSymbicodeTransfer(context.Background(), sym.Token, owner, notifier.next(t).Code)
+	if err != nil {
+		t.Fatalf("offer: %v", err)
+	}
+	if _, err := srv.ClaimSymbicodeTransfer(context.Background(), offer.Token, service.Contact{Phone: "0987654321"}); !errors.Is(err, service.ErrInvalidTransfer) {
+		t.Fatalf("expected an expired offer to be rejected, got %v", err)
+	}
+}
+
+func TestSymbicodeTransfer_CodeWithoutOrder(t *testing.T) {
+	srv, _ := setup()
+	sym, _ := srv.GenerateSymbicode(context.Background(), 10, nil)
+
+	if _, err := srv.InitiateSymbicodeTransfer(context.Background(), sym.Token, service.Contact{Phone: "0901234567"}); !errors.Is(err, service.ErrTransferDenied) {
+		t.Fatalf("expected a code with no owner to be untransferable, got %v", err)
+	}
+}
+
+func TestGenerateSymbicodeBatch_TableDriven(t *testing.T) {
+	tests := []struct {
+		name    string
+		count   int
+		wantErr error
+	}{
+		{"one code", 1, nil},
+		{"a sheet", 35, nil},
+		{"empty", 0, service.ErrBatchSize},
+		{"too many", service.MaxSymbicodeBatch + 1, service.ErrBatchSize},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := newMockRepository()
+			srv := service.NewService(repo, nil, nil, nil, service.WithFrontendURL("https://shop.example"))
+
+			batch, err := srv.GenerateSymbicodeBatch(context.Background(), 10, tc.count, "factory run 1")
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("expected error %v, got %v", tc.wantErr, err)
+			}
+			if tc.wantErr != nil {
+				if len(repo.symbicodes) != 0 {
+					t.Fatalf("expected no codes, got %d", len(repo.symbicodes))
+				}
+				return
+			}
+
+			_, tags, err := srv.SymbicodeLabels(context.Background(), batch.ID)
+			if err != nil {
+				t.Fatalf("labels: %v", err)
+			}
+			if len(tags) != tc.count || batch.Count != tc.count {
+				t.Fatalf("expected %d labels, got %d (batch count %d)", tc.count, len(tags), batch.Count)
+			}
+			if len(repo.audit) != 1 || repo.audit[0].Action != "symbicode.batch_generate" {
+				t.Fatalf("expected one audit entry, got %+v", repo.audit)
+			}
+
+			// Every label is an absolute QR URL that verifies its own code
+			for _, tag := range tags {
+				if want := "https://shop.example" + service.GenerateQRCodeData(tag.Token); tag.URL != want {
+					t.Fatalf("expected URL %s, got %s", want, tag.URL)
+				}
+				res, err := srv.VerifySymbicode(context.Background(), tag.URL, service.ScanInfo{IP: "203.0.113.7"})
+				if err != nil {
+					t.Fatalf("verify label %d: %v", tag.ID, err)
+				}
+				if res.Symbicode.ID != tag.ID || res.IsFirstActivation {
+					t.Fatalf("expected unsold code %d to verify without activating, got %+v", tag.ID, res)
+				}
+			}
+		})
+	}
+}
+
+func TestBindSymbicode_TableDriven(t *testing.T) {
+	oneUnit := []byte(`[{"drop_id":1,"product_id":10,"quantity":1}]`)
+	tests := []struct {
+		name      string
+		order     *models.Order
+		bindTwice bool
+		// product is the product the tag was printed for, default 10
+		product uint64
+		// generated gives the order the code generated when it was paid
+		generated bool
+		wantErr   error
+	}{
+		{name: "paid order", order: &models.Order{ID: 7, Status: models.OrderPaid, CustomerPhone: "0901234567", Items: oneUnit}},
+		{name: "confirmed order", order: &models.Order{ID: 7, Status: models.OrderConfirmed, CustomerPhone: "0901234567", Items: oneUnit}},
+		{name: "pending order", order: &models.Order{ID: 7, Status: models.OrderPending, Items: oneUnit}, wantErr: service.ErrOrderNotPaid},
+		{name: "already bound", order: &models.Order{ID: 7, Status: models.OrderPaid, CustomerPhone: "0901234567", Items: oneUnit}, bindTwice: true, wantErr: service.ErrSymbicodeBound},
+		{name: "every unit has a code", order: &models.Order{ID: 7, Status: models.OrderPaid, CustomerPhone: "0901234567", Items: oneUnit}, generated: true, wantErr: service.ErrOrderFullyBound},
+		{name: "another product's tag", order: &models.Order{ID: 7, Status: models.OrderPaid, CustomerPhone: "0901234567", Items: oneUnit}, product: 11, wantErr: service.ErrProductMismatch},
+		{name: "legacy item product ID", order: &models.Order{ID: 7, Status: models.OrderPaid, CustomerPhone: "0901234567", Items: []byte(`[{"drop_id":1,"product_id":1,"quantity":1}]`)}},
+		{name: "item without a drop", order: &models.Order{ID: 7, Status: models.OrderPaid, CustomerPhone: "0901234567", Items: []byte(`[{"product_id":10,"quantity":1}]`)}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := newMockRepository()
+			repo.orders[tc.order.ID] = tc.order
+			repo.drops[1] = &models.LimitedDrop{ID: 1, ProductID: 10}
+			srv := service.NewService(repo, nil, nil, nil, service.WithNotifier(make(recordingNotifier, 1)))
+
+			product := tc.product
+			if product == 0 {
+				product = 10
+			}
+			batch, err := srv.GenerateSymbicodeBatch(context.Background(), product, 1, "")
+			if err != nil {
+				t.Fatalf("generate: %v", err)
+			}
+			_, tags, _ := srv.SymbicodeLabels(context.Background(), batch.ID)
+			tag := tags[0]
+
+			// Unsold tags are never auto-activated
+			if n, _ := srv.AutoActivateExpiredSymbicodes(context.Background(), -time.Hour); n != 0 {
+				t.Fatalf("expected unsold codes to be skipped, activated %d", n)
+			}
+			if tc.generated {
+				if _, err := srv.GenerateSymbicode(context.Background(), 10, &tc.order.ID); err != nil {
+					t.Fatalf("generate: %v", err)
+				}
+			}
+
+			sym, err := srv.BindSymbicode(context.Background(), tag.URL, tc.order.ID)
+			if tc.bindTwice && err == nil {
+				_, err = srv.BindSymbicode(context.Background(), tag.Token, tc.order.ID)
+			}
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("expected error %v, got %v", tc.wantErr, err)
+			}
+			if tc.wantErr != nil && !tc.bindTwice {
+				return
+			}
+			if tc.wantErr == nil && (sym.OrderID != tc.order.ID || sym.BoundAt == nil || sym.Token != tag.Token) {
+				t.Fatalf("unexpected bound symbicode %+v", sym)
+			}
+
+			// Once sold, the first scan activates and the buyer owns the code
+			res, err := srv.VerifySymbicode(context.Background(), tag.Token, service.ScanInfo{IP: "203.0.113.7"})
+			if err != nil || !res.IsFirstActivation || res.Owners != 1 {
+				t.Fatalf("expected first activation with one owner, got %+v, %v", res, err)
+			}
+			if _, err := srv.InitiateSymbicodeTransfer(context.Background(), tag.Token, service.Contact{Phone: "0901234567"}); err != nil {
+				t.Fatalf("expected the buyer to own the bound code: %v", err)
+			}
+		})
+	}
+}
+
+func TestProcessSuccessfulDropPayment_PrintedTags(t *testing.T) {
+	for _, printed := range []bool{false, true} {
+		t.Run(fmt.Sprintf("printed=%v", printed), func(t *testing.T) {
+			// The drop and product IDs differ so a mix-up shows
+			repo := newMockRepository()
+			repo.drops[3] = &models.LimitedDrop{
+				ID: 3, Name: "Summer drop", ProductID: 10, TotalStock: 5, DropSize: 5,
+				StartTime: time.Now().Add(-time.Minute), IsActive: 1,
+			}
+			repo.products[10] = &models.Product{ID: 10, Name: "Tee", Price: 100000}
+			srv := service.NewService(repo, newMockPaymentGateway(), newMockEmailSender(), newMockSheetSubmitter(),
+				service.WithNotifier(make(recordingNotifier, 4)))
+			if printed {
+				if _, err := srv.GenerateSymbicodeBatch(context.Background(), 10, 1, ""); err != nil {
+					t.Fatalf("generate: %v", err)
+				}
+			}
+
+			res, err := srv.PurchaseDrop(context.Background(), 3, &service.PurchaseRequest{
+				Quantity: 1, Name: "Lan", Phone: "0912345678", Email: "lan@test.com",
+			})
+			if err != nil {
+				t.Fatalf("purchase: %v", err)
+			}
+			var order *models.Order
+			for _, o := range repo.orders {
+				order = o
+			}
+			repo.orderByPayOS[res.OrderCode] = order
+
+			if err := srv.ProcessSuccessfulDropPayment(context.Background(), res.OrderCode); err != nil {
+				t.Fatalf("payment: %v", err)
+			}
+			n, _ := repo.CountOrderSymbicodes(order.ID)
+			if want := map[bool]int{false: 1, true: 0}[printed]; n != want {
+				t.Fatalf("expected %d generated codes, got %d", want, n)
+			}
+			for _, sym := range repo.symbicodes {
+				if sym.ProductID != 10 {
+					t.Fatalf("expected codes for product 10, got %+v", sym)
+				}
+			}
+		})
+	}
+}
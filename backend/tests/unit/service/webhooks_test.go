@@ -0,0 +1,189 @@
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"ecommerce-backend/internal/models"
+	"ecommerce-backend/internal/service"
+	"ecommerce-backend/internal/webhooks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/datatypes"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// queuedEvent is a queued delivery's payload with its data left raw
+type queuedEvent struct {
+	ID    string          `json:"id"`
+	Event webhooks.Event  `json:"event"`
+	Data  json.RawMessage `json:"data"`
+}
+
+// webhookQueue returns a dispatcher with one subscription to every event,
+// not running, and a func reading the events it queued in order
+func webhookQueue(t *testing.T) (*webhooks.Dispatcher, func() []queuedEvent) {
+	t.Helper()
+	gdb, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "webhooks.db")), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, gdb.AutoMigrate(&models.WebhookSubscription{}, &models.WebhookDelivery{}, &models.WebhookAttempt{}))
+	db, err := gdb.DB()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	d := webhooks.New(webhooks.Options{Store: webhooks.NewSQLStore(db)})
+	_, err = d.Subscribe(context.Background(), "https://hooks.example.com/in", []string{"*"}, "")
+	require.NoError(t, err)
+
+	return d, func() []queuedEvent {
+		var rows []models.WebhookDelivery
+		require.NoError(t, gdb.Order("id").Find(&rows).Error)
+		out := make([]queuedEvent, len(rows))
+		for i, row := range rows {
+			require.NoError(t, json.Unmarshal([]byte(row.Payload), &out[i]))
+		}
+		return out
+	}
+}
+
+func eventTypes(events []queuedEvent) []webhooks.Event {
+	out := make([]webhooks.Event, len(events))
+	for i, ev := range events {
+		out[i] = ev.Event
+	}
+	return out
+}
+
+func TestProcessSuccessfulDropPayment_Webhooks(t *testing.T) {
+	tests := []struct {
+		name       string
+		soldOut    bool
+		wantEvents []webhooks.Event
+		wantStatus string
+	}{
+		{name: "winner takes the last unit", wantEvents: []webhooks.Event{webhooks.OrderPaid, webhooks.DropSoldOut}, wantStatus: "paid"},
+		{name: "loser", soldOut: true, wantEvents: []webhooks.Event{webhooks.DropSoldOut, webhooks.OrderCancelled}, wantStatus: "cancelled"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := newMockRepository()
+			repo.drops[1] = &models.LimitedDrop{ID: 1, Name: "Summer drop", ProductID: 10, TotalStock: 1}
+			if tc.soldOut {
+				repo.drops[1].Sold = 1
+			}
+			order := &models.Order{
+				ID:              100,
+				Status:          models.OrderPending,
+				Items:           datatypes.JSON(`[{"drop_id":1,"quantity":1,"product_id":10}]`),
+				ShippingAddress: datatypes.JSON(`{"name":"Lan","email":"lan@test.com","phone":"0912345678"}`),
+				CustomerPhone:   "0912345678",
+				TotalAmount:     1250000,
+			}
+			repo.orderByPayOS[12345] = order
+			repo.orders[100] = order
+
+			hooks, queued := webhookQueue(t)
+			srv := service.NewService(repo, newMockPaymentGateway(), newMockEmailSender(), newMockSheetSubmitter(),
+				service.WithNotifier(make(recordingNotifier, 4)), service.WithWebhooks(hooks))
+			require.NoError(t, srv.ProcessSuccessfulDropPayment(context.Background(), 12345))
+			// PayOS delivers the webhook again
+			require.NoError(t, srv.ProcessSuccessfulDropPayment(context.Background(), 12345))
+
+			events := queued()
+			require.Equal(t, tc.wantEvents, eventTypes(events), "each event is queued once")
+			for _, ev := range events {
+				switch ev.Event {
+				case webhooks.OrderPaid, webhooks.OrderCancelled:
+					assert.Equal(t, string(ev.Event)+":100", ev.ID)
+					var data webhooks.OrderData
+					require.NoError(t, json.Unmarshal(ev.Data, &data))
+					assert.Equal(t, uint64(100), data.OrderID)
+					assert.Equal(t, tc.wantStatus, data.Status)
+					assert.Equal(t, uint64(1250000), data.Amount)
+					assert.JSONEq(t, `{"name":"Lan","email":"lan@test.com","phone":"0912345678"}`, string(data.ShippingAddress))
+				case webhooks.DropSoldOut:
+					var data webhooks.DropData
+					require.NoError(t, json.Unmarshal(ev.Data, &data))
+					assert.Equal(t, uint64(1), data.DropID)
+					assert.Equal(t, "Summer drop", data.Name)
+				}
+			}
+		})
+	}
+}
+
+func TestAnnounceStartedDrops(t *testing.T) {
+	now := time.Now()
+	repo := newMockRepository()
+	repo.activeDrops = []models.LimitedDrop{
+		{ID: 1, Name: "Started", StartTime: now.Add(-10 * time.Minute)},
+		{ID: 2, Name: "Upcoming", StartTime: now.Add(time.Hour)},
+		{ID: 3, Name: "Long running", StartTime: now.Add(-3 * time.Hour)},
+	}
+	hooks, queued := webhookQueue(t)
+	srv := service.NewService(repo, nil, nil, nil, service.WithWebhooks(hooks))
+
+	for range 2 {
+		n, err := srv.AnnounceStartedDrops(context.Background(), time.Hour)
+		require.NoError(t, err)
+		assert.Equal(t, 1, n)
+	}
+
+	events := queued()
+	require.Len(t, events, 1, "announced once")
+	assert.Equal(t, webhooks.DropStarted, events[0].Event)
+	assert.Equal(t, "drop.started:1", events[0].ID)
+}
+
+func TestSymbicodeActivated_Webhooks(t *testing.T) {
+	t.Run("first scan", func(t *testing.T) {
+		repo := newMockRepository()
+		repo.orders[7] = &models.Order{ID: 7, CustomerPhone: "0901234567"}
+		hooks, queued := webhookQueue(t)
+		srv := service.NewService(repo, nil, nil, nil, service.WithWebhooks(hooks))
+		orderID := uint64(7)
+		sym, err := srv.GenerateSymbicode(context.Background(), 10, &orderID)
+		require.NoError(t, err)
+
+		for range 2 {
+			_, err := srv.VerifySymbicode(context.Background(), sym.Token, service.ScanInfo{IP: "203.0.113.7"})
+			require.NoError(t, err)
+		}
+
+		events := queued()
+		require.Len(t, events, 1, "only the first scan activates")
+		var data webhooks.SymbicodeData
+		require.NoError(t, json.Unmarshal(events[0].Data, &data))
+		assert.Equal(t, webhooks.SymbicodeActivated, events[0].Event)
+		assert.Equal(t, uint64(10), data.ProductID)
+		assert.Equal(t, uint64(7), data.OrderID)
+		assert.False(t, data.Auto)
+	})
+
+	t.Run("auto activation", func(t *testing.T) {
+		repo := newMockRepository()
+		old := time.Now().Add(-96 * time.Hour)
+		repo.symbicodes["1"] = &models.Symbicode{ID: 1, CreatedAt: old}
+		repo.symbicodes["2"] = &models.Symbicode{ID: 2, CreatedAt: old}
+		hooks, queued := webhookQueue(t)
+		srv := service.NewService(repo, nil, nil, nil, service.WithWebhooks(hooks))
+
+		n, err := srv.AutoActivateExpiredSymbicodes(context.Background(), 72*time.Hour)
+		require.NoError(t, err)
+		require.Equal(t, 2, n)
+
+		events := queued()
+		require.Len(t, events, 2)
+		assert.Equal(t, "symbicode.activated:1", events[0].ID)
+		var data webhooks.SymbicodeData
+		require.NoError(t, json.Unmarshal(events[1].Data, &data))
+		assert.Equal(t, uint64(2), data.SymbicodeID)
+		assert.True(t, data.Auto)
+	})
+}
@@ -0,0 +1,234 @@
+package sheetsync_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"ecommerce-backend/internal/integrations"
+	"ecommerce-backend/internal/integrations/sheetstest"
+	"ecommerce-backend/internal/jobs"
+	"ecommerce-backend/internal/models"
+	"ecommerce-backend/internal/sheetsync"
+	"ecommerce-backend/internal/utils/base32"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/datatypes"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// testDB returns a fresh database migrated like the server's
+func testDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	gdb, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "sheets.db")), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, gdb.AutoMigrate(&models.Order{}, &models.SheetRow{}))
+	db, err := gdb.DB()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return gdb
+}
+
+func store(t *testing.T, gdb *gorm.DB) *sheetsync.SQLStore {
+	t.Helper()
+	db, err := gdb.DB()
+	require.NoError(t, err)
+	return sheetsync.NewSQLStore(db)
+}
+
+// paidOrder inserts a paid order created at createdAt
+func paidOrder(t *testing.T, gdb *gorm.DB, id uint64, createdAt time.Time) {
+	t.Helper()
+	require.NoError(t, gdb.Create(&models.Order{
+		ID:              id,
+		CreatedAt:       createdAt,
+		CustomerPhone:   "0912345678",
+		ShippingAddress: datatypes.JSON(fmt.Sprintf(`{"name":"Buyer %d","email":"b%d@test.com","phone":"0912345678","address":"1 Le Loi","district":"Quan 1","province":"HCM"}`, id, id)),
+		Items:           datatypes.JSON(`[{"drop_id":1,"name":"Donald Watch","quantity":1,"price":1250000}]`),
+		TotalAmount:     1250000,
+		Status:          models.OrderPaid,
+	}).Error)
+}
+
+func TestParseColumns(t *testing.T) {
+	cols, err := sheetsync.ParseColumns([]string{"order_number", " amount", "-"})
+	require.NoError(t, err)
+	assert.Equal(t, []sheetsync.Column{sheetsync.OrderNumber, sheetsync.Amount, sheetsync.Blank}, cols)
+
+	_, err = sheetsync.ParseColumns([]string{"name", "shoe_size"})
+	assert.Error(t, err)
+}
+
+func TestSync_BatchesAndMarksExported(t *testing.T) {
+	gdb := testDB(t)
+	sheet := &sheetstest.Sheet{}
+	s := sheetsync.New(sheetsync.Options{API: sheet, Store: store(t, gdb), BatchSize: 2})
+	ctx := context.Background()
+
+	paidAt := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	for i := uint64(1); i <= 5; i++ {
+		paidOrder(t, gdb, i, paidAt)
+		require.NoError(t, s.QueueOrder(ctx, i, paidAt.Add(time.Duration(i)*time.Second)))
+	}
+	// Queuing twice keeps one row
+	require.NoError(t, s.QueueOrder(ctx, 1, paidAt))
+
+	n, err := s.Sync(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 5, n)
+	assert.Equal(t, 3, sheet.Calls(), "5 rows in batches of 2")
+
+	rows := sheet.Rows()
+	require.Len(t, rows, 5)
+	assert.Equal(t, []string{
+		paidAt.Add(time.Second).Local().Format(time.RFC3339),
+		"Buyer 1",
+		"0912345678",
+		"b1@test.com",
+		"1 Le Loi, Quan 1, HCM",
+		"Winner - Limited Drop " + base32.GenerateOrderNumber(1),
+		"1250000",
+	}, rows[0], "the default columns keep the old layout")
+	assert.Equal(t, "Buyer 5", rows[4][1], "oldest paid first")
+
+	st, err := s.Stats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, st.Pending)
+	assert.Equal(t, 5, st.Exported)
+	assert.NotNil(t, st.LastExportedAt)
+
+	// Nothing is exported twice
+	n, err = s.Sync(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+	assert.Len(t, sheet.Rows(), 5)
+}
+
+func TestSync_ColumnMapping(t *testing.T) {
+	gdb := testDB(t)
+	sheet := &sheetstest.Sheet{}
+	cols, err := sheetsync.ParseColumns([]string{"order_number", "order_id", "-", "items", "status", "amount"})
+	require.NoError(t, err)
+	s := sheetsync.New(sheetsync.Options{API: sheet, Store: store(t, gdb), Columns: cols})
+
+	paidOrder(t, gdb, 42, time.Now())
+	require.NoError(t, s.QueueOrder(context.Background(), 42, time.Now()))
+	_, err = s.Sync(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, [][]string{{base32.GenerateOrderNumber(42), "42", "", "Donald Watch x1", "paid", "1250000"}}, sheet.Rows())
+}
+
+func TestSync_RetriesWithBackoff(t *testing.T) {
+	gdb := testDB(t)
+	sheet := &sheetstest.Sheet{}
+	sheet.FailWith(2, errors.New("googleapi: Error 429: Quota exceeded"))
+	s := sheetsync.New(sheetsync.Options{API: sheet, Store: store(t, gdb), Attempts: 3, Backoff: time.Millisecond})
+
+	paidOrder(t, gdb, 1, time.Now())
+	require.NoError(t, s.QueueOrder(context.Background(), 1, time.Now()))
+
+	n, err := s.Sync(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, 3, sheet.Calls())
+	assert.Len(t, sheet.Rows(), 1)
+}
+
+func TestSync_FailedRowsStayPending(t *testing.T) {
+	gdb := testDB(t)
+	sheet := &sheetstest.Sheet{}
+	sheet.FailWith(-1, errors.New("googleapi: Error 503: backend unavailable"))
+	s := sheetsync.New(sheetsync.Options{API: sheet, Store: store(t, gdb), Attempts: 2, Backoff: time.Millisecond})
+	ctx := context.Background()
+
+	paidOrder(t, gdb, 1, time.Now())
+	require.NoError(t, s.QueueOrder(ctx, 1, time.Now()))
+
+	_, err := s.Sync(ctx)
+	require.Error(t, err)
+	assert.Equal(t, 2, sheet.Calls())
+
+	st, err := s.Stats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, st.Pending)
+	assert.NotNil(t, st.OldestPendingAt)
+	assert.Contains(t, st.LastError, "503")
+
+	var row models.SheetRow
+	require.NoError(t, gdb.First(&row, "order_id = ?", 1).Error)
+	assert.Equal(t, 1, row.Attempts)
+
+	// The next run exports it
+	sheet.FailWith(0, nil)
+	n, err := s.Sync(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	st, err = s.Stats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, st.Pending)
+	assert.Empty(t, st.LastError)
+}
+
+func TestSync_NotConfigured(t *testing.T) {
+	gdb := testDB(t)
+	sheet := &sheetstest.Sheet{}
+	sheet.FailWith(-1, integrations.ErrSheetsNotConfigured)
+	s := sheetsync.New(sheetsync.Options{API: sheet, Store: store(t, gdb), Backoff: time.Millisecond})
+
+	paidOrder(t, gdb, 1, time.Now())
+	require.NoError(t, s.QueueOrder(context.Background(), 1, time.Now()))
+
+	// The job reports it and keeps the rows for when a sheet is set
+	result, err := jobs.SheetSync(s, "@every 1m").Run(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "no spreadsheet configured", result)
+	assert.Equal(t, 1, sheet.Calls(), "not retried")
+
+	var row models.SheetRow
+	require.NoError(t, gdb.First(&row, "order_id = ?", 1).Error)
+	assert.Equal(t, 0, row.Attempts)
+	assert.Zero(t, row.ExportedAt)
+}
+
+func TestBackfill(t *testing.T) {
+	gdb := testDB(t)
+	sheet := &sheetstest.Sheet{}
+	s := sheetsync.New(sheetsync.Options{API: sheet, Store: store(t, gdb)})
+	ctx := context.Background()
+
+	day := time.Date(2026, 3, 1, 0, 0, 0, 0, time.Local)
+	paidOrder(t, gdb, 1, day.Add(-time.Hour)) // before the range
+	paidOrder(t, gdb, 2, day.Add(time.Hour))
+	paidOrder(t, gdb, 3, day.Add(2*time.Hour))
+	paidOrder(t, gdb, 4, day.Add(3*time.Hour))
+	require.NoError(t, gdb.Create(&models.Order{ID: 5, CreatedAt: day.Add(time.Hour), Status: models.OrderCancelled}).Error)
+	paidOrder(t, gdb, 6, day.Add(25*time.Hour)) // after the range
+
+	// Order 3 was already exported
+	require.NoError(t, s.QueueOrder(ctx, 3, day.Add(2*time.Hour)))
+	_, err := s.Sync(ctx)
+	require.NoError(t, err)
+
+	n, err := s.Backfill(ctx, day, day.Add(24*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 2, n, "orders 2 and 4")
+
+	// A second backfill of the same range queues nothing
+	n, err = s.Backfill(ctx, day, day.Add(24*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	_, err = s.Sync(ctx)
+	require.NoError(t, err)
+	rows := sheet.Rows()
+	require.Len(t, rows, 3)
+	assert.Equal(t, "Buyer 2", rows[1][1])
+	assert.Equal(t, day.Add(time.Hour).Format(time.RFC3339), rows[1][0], "paid at creation")
+	assert.Equal(t, "Buyer 4", rows[2][1])
+}
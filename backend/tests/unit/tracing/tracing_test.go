@@ -0,0 +1,222 @@
+package tracing_test
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"ecommerce-backend/internal/database"
+	"ecommerce-backend/internal/jobs"
+	"ecommerce-backend/internal/queue"
+	"ecommerce-backend/internal/repository"
+	"ecommerce-backend/internal/tracing"
+
+	"github.com/gofiber/fiber/v3"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	setupOnce sync.Once
+	exporter  = tracetest.NewInMemoryExporter()
+)
+
+// recordSpans installs an in-memory provider (once: tracers created before
+// the first provider keep delegating to it) and clears earlier spans
+func recordSpans(t *testing.T) *tracetest.InMemoryExporter {
+	t.Helper()
+	setupOnce.Do(func() {
+		otel.SetTextMapPropagator(propagation.TraceContext{})
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	})
+	exporter.Reset()
+	return exporter
+}
+
+func spanNamed(t *testing.T, spans tracetest.SpanStubs, name string) tracetest.SpanStub {
+	t.Helper()
+	for _, s := range spans {
+		if s.Name == name {
+			return s
+		}
+	}
+	require.Failf(t, "span not found", "no span %q in %v", name, spanNames(spans))
+	return tracetest.SpanStub{}
+}
+
+func spanNames(spans tracetest.SpanStubs) []string {
+	names := make([]string, len(spans))
+	for i, s := range spans {
+		names[i] = s.Name
+	}
+	return names
+}
+
+func attr(s tracetest.SpanStub, key string) string {
+	for _, a := range s.Attributes {
+		if string(a.Key) == key {
+			return a.Value.Emit()
+		}
+	}
+	return ""
+}
+
+func TestMiddleware_ServerSpan(t *testing.T) {
+	exp := recordSpans(t)
+
+	var handlerSpan trace.SpanContext
+	app := fiber.New()
+	app.Use(tracing.Middleware())
+	app.Get("/api/drops/:id/status", func(c fiber.Ctx) error {
+		handlerSpan = trace.SpanContextFromContext(c.Context())
+		return c.SendStatus(fiber.StatusServiceUnavailable)
+	})
+
+	const parent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	req := httptest.NewRequest("GET", "/api/drops/7/status", nil)
+	req.Header.Set("traceparent", parent)
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusServiceUnavailable, resp.StatusCode)
+
+	span := spanNamed(t, exp.GetSpans(), "GET /api/drops/:id/status")
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext.TraceID().String(), "continues the incoming trace")
+	assert.Equal(t, "00f067aa0ba902b7", span.Parent.SpanID().String())
+	assert.Equal(t, span.SpanContext.SpanID(), handlerSpan.SpanID(), "handlers see the server span")
+	assert.Equal(t, trace.SpanKindServer, span.SpanKind)
+	assert.Equal(t, "503", attr(span, "http.response.status_code"))
+	assert.Equal(t, "/api/drops/:id/status", attr(span, "http.route"))
+	assert.Equal(t, "Error", span.Status.Code.String())
+}
+
+func TestTransport_ClientSpanAndTraceparent(t *testing.T) {
+	exp := recordSpans(t)
+
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get("traceparent")
+	}))
+	defer srv.Close()
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "parent")
+	client := &http.Client{Transport: tracing.Transport(nil, "payos")}
+	req, err := http.NewRequestWithContext(ctx, "POST", srv.URL, nil)
+	require.NoError(t, err)
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	parent.End()
+
+	span := spanNamed(t, exp.GetSpans(), "payos POST")
+	assert.Equal(t, trace.SpanKindClient, span.SpanKind)
+	assert.Equal(t, parent.SpanContext().SpanID(), span.Parent.SpanID())
+	assert.Contains(t, got, span.SpanContext.TraceID().String())
+}
+
+func setupPools(t *testing.T) *database.SmartExecutor {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "trace.db")
+	open := func() *sql.DB {
+		db, err := sql.Open("sqlite3", path)
+		require.NoError(t, err)
+		t.Cleanup(func() { db.Close() })
+		return db
+	}
+	writer, reader := open(), open()
+	_, err := writer.Exec("CREATE TABLE kv (k TEXT PRIMARY KEY, v TEXT)")
+	require.NoError(t, err)
+	return database.NewSmartExecutor(writer, reader)
+}
+
+func TestSmartExecutor_StatementSpans(t *testing.T) {
+	se := setupPools(t)
+	exp := recordSpans(t)
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "parent")
+	db := se.WithContext(ctx)
+	_, err := db.Exec("INSERT INTO kv (k, v) VALUES ('a', '1')")
+	require.NoError(t, err)
+	var v string
+	require.NoError(t, db.QueryRow("SELECT v FROM kv WHERE k = 'a'").Scan(&v))
+	parent.End()
+
+	tests := []struct {
+		name string
+		pool string
+	}{
+		{"INSERT", "writer"},
+		{"SELECT", "reader"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			span := spanNamed(t, exp.GetSpans(), tt.name)
+			assert.Equal(t, tt.pool, attr(span, "db.sqlite.pool"))
+			assert.Equal(t, "sqlite", attr(span, "db.system.name"))
+			assert.Contains(t, attr(span, "db.query.text"), tt.name)
+			assert.Equal(t, parent.SpanContext().SpanID(), span.Parent.SpanID())
+		})
+	}
+}
+
+func TestRepository_TransactionSpans(t *testing.T) {
+	se := setupPools(t)
+	exp := recordSpans(t)
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "parent")
+	repo := repository.NewRepository(se).WithContext(ctx)
+	err := repo.WithTransaction(func(tx repository.Repository) error {
+		// IncrementSoldCount on a missing table fails inside the transaction
+		return tx.IncrementSoldCount(1, 1)
+	})
+	require.Error(t, err)
+	parent.End()
+
+	spans := exp.GetSpans()
+	txSpan := spanNamed(t, spans, "TRANSACTION")
+	assert.Equal(t, parent.SpanContext().SpanID(), txSpan.Parent.SpanID())
+
+	update := spanNamed(t, spans, "UPDATE")
+	assert.Equal(t, txSpan.SpanContext.SpanID(), update.Parent.SpanID(), "statements are children of the transaction")
+	assert.Equal(t, "writer", attr(update, "db.sqlite.pool"))
+	assert.Equal(t, "Error", update.Status.Code.String())
+}
+
+type recordingProcessor struct{ ctx context.Context }
+
+func (p *recordingProcessor) ProcessSuccessfulDropPayment(ctx context.Context, orderCode int64) error {
+	p.ctx = ctx
+	return nil
+}
+
+func TestPaymentJob_ContinuesWebhookTrace(t *testing.T) {
+	exp := recordSpans(t)
+
+	q := queue.NewMemoryQueue()
+	ctx, webhook := otel.Tracer("test").Start(context.Background(), "webhook")
+	require.NoError(t, jobs.EnqueuePayment(ctx, q, 42))
+	webhook.End()
+
+	msgs, err := q.Receive(context.Background(), 1, time.Minute)
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+
+	proc := &recordingProcessor{}
+	require.NoError(t, jobs.PaymentHandler(proc)(context.Background(), msgs[0].Body))
+
+	span := spanNamed(t, exp.GetSpans(), "PaymentJob")
+	assert.Equal(t, trace.SpanKindConsumer, span.SpanKind)
+	assert.Equal(t, webhook.SpanContext().TraceID(), span.SpanContext.TraceID())
+	assert.Equal(t, webhook.SpanContext().SpanID(), span.Parent.SpanID())
+	assert.Equal(t, span.SpanContext.SpanID(), trace.SpanContextFromContext(proc.ctx).SpanID())
+}
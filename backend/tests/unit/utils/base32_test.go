@@ -1,6 +1,8 @@
 package utils_test
 
 import (
+	"math"
+	"strings"
 	"testing"
 
 	"ecommerce-backend/internal/utils/base32"
@@ -46,11 +48,15 @@ func TestGenerateOrderNumber_TableDriven(t *testing.T) {
 
 			assert.True(t, len(result) > 3)
 			assert.Equal(t, tc.wantPrefix, result[:3])
+			assert.Len(t, result, 12, "DV- + 8 symbols + check")
 		})
 	}
 }
 
 func TestDecodeOrderNumber_TableDriven(t *testing.T) {
+	base32.SetLegacyMaxID(12345)
+	defer base32.SetLegacyMaxID(0)
+
 	tests := []struct {
 		name        string
 		orderNumber string
@@ -75,6 +81,36 @@ func TestDecodeOrderNumber_TableDriven(t *testing.T) {
 			wantID:      9999999999,
 			wantErr:     false,
 		},
+		{
+			name:        "valid - lowercase with hyphens",
+			orderNumber: " " + strings.ToLower(base32.GenerateOrderNumber(12345)[:7]) + "-" + strings.ToLower(base32.GenerateOrderNumber(12345)[7:]) + " ",
+			wantID:      12345,
+			wantErr:     false,
+		},
+		{
+			name:        "valid - legacy format",
+			orderNumber: "DV-GEZDGNBV",
+			wantID:      12345,
+			wantErr:     false,
+		},
+		{
+			name:        "valid - legacy format with padding",
+			orderNumber: "DV-GE======",
+			wantID:      1,
+			wantErr:     false,
+		},
+		{
+			name:        "error - legacy format after the cutover",
+			orderNumber: "DV-GEZDGNBW",
+			wantID:      0,
+			wantErr:     true,
+		},
+		{
+			name:        "error - sequential ID",
+			orderNumber: "DV-12345",
+			wantID:      0,
+			wantErr:     true,
+		},
 		{
 			name:        "error - invalid prefix",
 			orderNumber: "XX-ABCD",
@@ -116,12 +152,72 @@ func TestDecodeOrderNumber_TableDriven(t *testing.T) {
 	}
 }
 
+func TestDecodeOrderNumber_Checksum(t *testing.T) {
+	number := base32.GenerateOrderNumber(12345)
+	const alphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+	// Every single-symbol typo is caught
+	for i := len(base32.Prefix); i < len(number)-1; i++ {
+		for _, c := range alphabet {
+			if byte(c) == number[i] {
+				continue
+			}
+			typo := number[:i] + string(c) + number[i+1:]
+			_, err := base32.DecodeOrderNumber(typo)
+			assert.ErrorIs(t, err, base32.ErrInvalidOrderNumber, typo)
+		}
+	}
+
+	// Look-alikes read as the digits they resemble
+	aliased := strings.NewReplacer("0", "O", "1", "I").Replace(number[len(base32.Prefix):])
+	id, err := base32.DecodeOrderNumber(base32.Prefix + aliased)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(12345), id)
+}
+
+func TestGenerateOrderNumber_Keyed(t *testing.T) {
+	defer base32.SetKey(nil)
+	base32.SetLegacyMaxID(12345)
+	defer base32.SetLegacyMaxID(0)
+
+	// Consecutive IDs do not give neighbouring numbers
+	first, second := base32.GenerateOrderNumber(100), base32.GenerateOrderNumber(101)
+	assert.NotEqual(t, first[:len(first)-2], second[:len(second)-2])
+
+	base32.SetKey([]byte("0123456789abcdef0123456789abcdef"))
+	keyed := base32.GenerateOrderNumber(100)
+	assert.NotEqual(t, first, keyed)
+	id, err := base32.DecodeOrderNumber(keyed)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(100), id)
+
+	// The legacy format does not depend on the key
+	id, err = base32.DecodeOrderNumber("DV-GEZDGNBV")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(12345), id)
+}
+
+func TestDecodeOrderNumber_LegacyCutover(t *testing.T) {
+	defer base32.SetLegacyMaxID(0)
+
+	// Without a cutover no legacy number resolves
+	_, err := base32.DecodeOrderNumber("DV-GE======")
+	assert.ErrorIs(t, err, base32.ErrInvalidOrderNumber)
+
+	base32.SetLegacyMaxID(1)
+	id, err := base32.DecodeOrderNumber("DV-GE======")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), id)
+	_, err = base32.DecodeOrderNumber("DV-GI======")
+	assert.ErrorIs(t, err, base32.ErrInvalidOrderNumber, "order 2 was never emailed in the legacy format")
+}
+
 // =============================================================================
 // ROUNDTRIP TEST
 // =============================================================================
 
 func TestOrderNumber_RoundTrip(t *testing.T) {
-	testIDs := []uint64{0, 1, 100, 12345, 999999, 9999999999}
+	testIDs := []uint64{0, 1, 100, 12345, 999999, 9999999999, 1 << 40, math.MaxUint64}
 
 	for _, id := range testIDs {
 		t.Run("", func(t *testing.T) {
@@ -0,0 +1,311 @@
+package webhooks_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"ecommerce-backend/internal/models"
+	"ecommerce-backend/internal/webhooks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// testStore returns a store on a fresh database migrated like the server's
+func testStore(t *testing.T) *webhooks.SQLStore {
+	t.Helper()
+	gdb, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "webhooks.db")), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, gdb.AutoMigrate(&models.WebhookSubscription{}, &models.WebhookDelivery{}, &models.WebhookAttempt{}))
+	db, err := gdb.DB()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return webhooks.NewSQLStore(db)
+}
+
+// receiver is a subscriber endpoint answering with status
+type receiver struct {
+	*httptest.Server
+	status atomic.Int32
+
+	mu       sync.Mutex
+	requests []received
+}
+
+type received struct {
+	header http.Header
+	body   []byte
+}
+
+func newReceiver(t *testing.T, status int) *receiver {
+	r := &receiver{}
+	r.status.Store(int32(status))
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		r.mu.Lock()
+		r.requests = append(r.requests, received{header: req.Header.Clone(), body: body})
+		r.mu.Unlock()
+		w.WriteHeader(int(r.status.Load()))
+		io.WriteString(w, "ack")
+	}))
+	t.Cleanup(r.Close)
+	return r
+}
+
+func (r *receiver) received() []received {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]received(nil), r.requests...)
+}
+
+// clock is a settable time source
+type clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	c.mu.Unlock()
+}
+
+// run runs d until the test ends
+func run(t *testing.T, d *webhooks.Dispatcher) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		d.Run(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+}
+
+// waitStatus waits for a delivery to reach status and returns it
+func waitStatus(t *testing.T, d *webhooks.Dispatcher, id uint64, status string, attempts int) *webhooks.Delivery {
+	t.Helper()
+	var got *webhooks.Delivery
+	require.Eventually(t, func() bool {
+		var err error
+		got, err = d.Delivery(context.Background(), id)
+		require.NoError(t, err)
+		return got != nil && got.Status == status && got.Attempts == attempts
+	}, 2*time.Second, 5*time.Millisecond)
+	return got
+}
+
+func TestSubscribe_Validation(t *testing.T) {
+	d := webhooks.New(webhooks.Options{Store: testStore(t)})
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		url    string
+		events []string
+	}{
+		{name: "not a URL", url: "hooks.example.com", events: []string{"order.paid"}},
+		{name: "ftp", url: "ftp://hooks.example.com", events: []string{"order.paid"}},
+		{name: "unknown event", url: "https://hooks.example.com", events: []string{"order.shipped"}},
+		{name: "no events", url: "https://hooks.example.com"},
+		{name: "all events and more", url: "https://hooks.example.com", events: []string{"*", "order.paid"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := d.Subscribe(ctx, tc.url, tc.events, "")
+			assert.ErrorIs(t, err, webhooks.ErrInvalidSubscription)
+		})
+	}
+
+	sub, err := d.Subscribe(ctx, "https://hooks.example.com/in", []string{"order.paid", " drop.sold_out"}, "warehouse")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"order.paid", "drop.sold_out"}, sub.Events)
+	assert.Regexp(t, `^whsec_[0-9a-f]{48}$`, sub.Secret)
+
+	subs, err := d.Subscriptions(ctx)
+	require.NoError(t, err)
+	require.Len(t, subs, 1)
+	assert.Equal(t, "warehouse", subs[0].Description)
+	assert.Empty(t, subs[0].Secret, "the secret is only shown once")
+}
+
+func TestDeliver_Signed(t *testing.T) {
+	recv := newReceiver(t, http.StatusOK)
+	d := webhooks.New(webhooks.Options{Store: testStore(t), PollInterval: 10 * time.Millisecond})
+	ctx := context.Background()
+
+	sub, err := d.Subscribe(ctx, recv.URL, []string{"order.paid"}, "")
+	require.NoError(t, err)
+	other, err := d.Subscribe(ctx, recv.URL+"/drops", []string{"drop.started", "drop.sold_out"}, "")
+	require.NoError(t, err)
+	run(t, d)
+
+	d.Emit(ctx, webhooks.OrderPaid, "42", map[string]any{"order_id": 42})
+	// The same event again, as a redelivered payment webhook raises it
+	d.Emit(ctx, webhooks.OrderPaid, "42", map[string]any{"order_id": 42})
+
+	list, err := d.Deliveries(ctx, sub.ID, 10)
+	require.NoError(t, err)
+	require.Len(t, list, 1, "queued once")
+	got := waitStatus(t, d, list[0].ID, webhooks.StatusDelivered, 1)
+	assert.NotNil(t, got.DeliveredAt)
+	assert.Nil(t, got.NextAttemptAt)
+	require.Len(t, got.Log, 1)
+	assert.Equal(t, http.StatusOK, got.Log[0].StatusCode)
+	assert.Equal(t, "ack", got.Log[0].Response)
+	assert.JSONEq(t, `{"order_id":42}`, string(mustData(t, got.Payload)))
+
+	reqs := recv.received()
+	require.Len(t, reqs, 1)
+	h := reqs[0].header
+	assert.Equal(t, "order.paid", h.Get(webhooks.EventHeader))
+	assert.Equal(t, "order.paid:42", h.Get(webhooks.IDHeader))
+	assert.Equal(t, "application/json", h.Get("Content-Type"))
+	assert.NoError(t, webhooks.Verify(sub.Secret, h.Get(webhooks.SignatureHeader), reqs[0].body, 5*time.Minute, time.Now()))
+	assert.ErrorIs(t, webhooks.Verify(other.Secret, h.Get(webhooks.SignatureHeader), reqs[0].body, 5*time.Minute, time.Now()), webhooks.ErrBadSignature)
+
+	otherList, err := d.Deliveries(ctx, other.ID, 10)
+	require.NoError(t, err)
+	assert.Empty(t, otherList, "not subscribed to order.paid")
+}
+
+func TestDeliver_RetriesWithBackoff(t *testing.T) {
+	recv := newReceiver(t, http.StatusServiceUnavailable)
+	clk := &clock{now: time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)}
+	d := webhooks.New(webhooks.Options{
+		Store:        testStore(t),
+		MaxAttempts:  3,
+		Backoff:      time.Minute,
+		PollInterval: 5 * time.Millisecond,
+		Now:          clk.Now,
+	})
+	ctx := context.Background()
+	sub, err := d.Subscribe(ctx, recv.URL, []string{"*"}, "")
+	require.NoError(t, err)
+	run(t, d)
+
+	d.Emit(ctx, webhooks.DropSoldOut, "1", map[string]any{"drop_id": 1})
+	list, err := d.Deliveries(ctx, sub.ID, 10)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	id := list[0].ID
+
+	start := clk.Now()
+	got := waitStatus(t, d, id, webhooks.StatusPending, 1)
+	assert.Equal(t, start.Add(time.Minute), *got.NextAttemptAt, "first backoff")
+	assert.Equal(t, http.StatusServiceUnavailable, got.LastStatusCode)
+	assert.Contains(t, got.LastError, "503")
+
+	clk.Advance(time.Minute)
+	got = waitStatus(t, d, id, webhooks.StatusPending, 2)
+	assert.Equal(t, start.Add(3*time.Minute), *got.NextAttemptAt, "doubled")
+
+	clk.Advance(2 * time.Minute)
+	got = waitStatus(t, d, id, webhooks.StatusFailed, 3)
+	assert.Nil(t, got.NextAttemptAt)
+	assert.Len(t, got.Log, 3)
+	assert.Len(t, recv.received(), 3)
+
+	// Replay after the subscriber is fixed
+	recv.status.Store(http.StatusNoContent)
+	ok, err := d.Replay(ctx, id)
+	require.NoError(t, err)
+	require.True(t, ok)
+	got = waitStatus(t, d, id, webhooks.StatusDelivered, 1)
+	assert.Len(t, got.Log, 4, "the log keeps earlier attempts")
+	reqs := recv.received()
+	assert.Equal(t, reqs[0].body, reqs[3].body, "the same payload")
+	assert.Equal(t, "drop.sold_out:1", reqs[3].header.Get(webhooks.IDHeader))
+
+	// Delivered ones can be replayed too
+	ok, err = d.Replay(ctx, id)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	waitStatus(t, d, id, webhooks.StatusDelivered, 1)
+	assert.Len(t, recv.received(), 5)
+
+	ok, err = d.Replay(ctx, id+100)
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestUnsubscribe(t *testing.T) {
+	d := webhooks.New(webhooks.Options{Store: testStore(t)})
+	ctx := context.Background()
+	sub, err := d.Subscribe(ctx, "https://hooks.example.com/in", []string{"order.cancelled"}, "")
+	require.NoError(t, err)
+
+	// Not running: the delivery stays pending
+	d.Emit(ctx, webhooks.OrderCancelled, "7", map[string]any{"order_id": 7})
+	list, err := d.Deliveries(ctx, sub.ID, 10)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, webhooks.StatusPending, list[0].Status)
+
+	ok, err := d.Unsubscribe(ctx, sub.ID)
+	require.NoError(t, err)
+	require.True(t, ok)
+	ok, err = d.Unsubscribe(ctx, sub.ID)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	got, err := d.Delivery(ctx, list[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, webhooks.StatusFailed, got.Status)
+	assert.Equal(t, "subscription deleted", got.LastError)
+
+	ok, err = d.Replay(ctx, list[0].ID)
+	require.NoError(t, err)
+	assert.False(t, ok, "nowhere to send it")
+
+	// Events raised now go nowhere
+	d.Emit(ctx, webhooks.OrderCancelled, "8", map[string]any{"order_id": 8})
+	list, err = d.Deliveries(ctx, sub.ID, 10)
+	require.NoError(t, err)
+	assert.Len(t, list, 1)
+}
+
+func TestVerify(t *testing.T) {
+	body := []byte(`{"id":"order.paid:1"}`)
+	at := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	header := webhooks.Sign("whsec_test", at, body)
+	assert.Regexp(t, `^t=\d+,v1=[0-9a-f]{64}$`, header)
+
+	assert.NoError(t, webhooks.Verify("whsec_test", header, body, time.Minute, at.Add(30*time.Second)))
+	assert.ErrorIs(t, webhooks.Verify("whsec_test", header, []byte(`{"id":"order.paid:2"}`), time.Minute, at), webhooks.ErrBadSignature)
+	assert.ErrorIs(t, webhooks.Verify("whsec_test", header, body, time.Minute, at.Add(2*time.Minute)), webhooks.ErrBadSignature, "replayed later")
+	assert.ErrorIs(t, webhooks.Verify("whsec_test", "v1=abc", body, time.Minute, at), webhooks.ErrBadSignature)
+}
+
+func TestNilDispatcher(t *testing.T) {
+	var d *webhooks.Dispatcher
+	assert.NotPanics(t, func() { d.Emit(context.Background(), webhooks.OrderPaid, "1", nil) })
+}
+
+// mustData returns the data of a payload
+func mustData(t *testing.T, payload []byte) []byte {
+	t.Helper()
+	var p struct {
+		Data json.RawMessage `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(payload, &p))
+	return p.Data
+}
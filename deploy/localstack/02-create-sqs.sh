@@ -2,6 +2,7 @@
 echo "Initializing LocalStack SQS..."
 
 awslocal sqs create-queue --queue-name donald-orders
+awslocal sqs create-queue --queue-name donald-orders-dlq
 awslocal sqs create-queue --queue-name donald-emails
 awslocal sqs create-queue --queue-name donald-notifications
 
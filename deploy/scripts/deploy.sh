@@ -72,6 +72,12 @@ PAYOS_CLIENT_ID=your-client-id
 PAYOS_API_KEY=your-api-key
 PAYOS_CHECKSUM_KEY=your-checksum-key
 
+# Symbicode label signing (never change once labels are printed)
+SYMBICODE_SIGNING_KEY=$(openssl rand -hex 32)
+
+# Public order numbers (never change once orders are placed)
+ORDER_NUMBER_KEY=$(openssl rand -hex 32)
+
 # Email (add your credentials)
 RESEND_API_KEY=your-resend-key
 
@@ -55,6 +55,7 @@ awslocal s3 mb s3://donald-vibe --endpoint-url=http://localhost:4566 2>/dev/null
 # Create SQS queues
 echo "Creating SQS queues..."
 awslocal sqs create-queue --queue-name donald-orders --endpoint-url=http://localhost:4566 2>/dev/null || echo "Queue already exists"
+awslocal sqs create-queue --queue-name donald-orders-dlq --endpoint-url=http://localhost:4566 2>/dev/null || echo "Queue already exists"
 awslocal sqs create-queue --queue-name donald-emails --endpoint-url=http://localhost:4566 2>/dev/null || echo "Queue already exists"
 awslocal sqs create-queue --queue-name donald-notifications --endpoint-url=http://localhost:4566 2>/dev/null || echo "Queue already exists"
 
@@ -118,6 +119,11 @@ echo "test content $(date)" > /tmp/test-upload.txt
 awslocal s3 cp /tmp/test-upload.txt s3://donald-vibe/test-upload.txt --endpoint-url=http://localhost:4566
 awslocal s3 ls s3://donald-vibe/ --endpoint-url=http://localhost:4566
 
+# Test continuous replication (WAL shipping + point-in-time restore)
+echo ""
+echo -e "${YELLOW}=== Testing Database Replication ===${NC}"
+(cd backend && go test ./tests/integration/replication/... -run LocalStack -v 2>&1 | tail -5)
+
 # Test SQS messaging
 echo ""
 echo -e "${YELLOW}=== Testing SQS Messaging ===${NC}"
@@ -8,6 +8,12 @@ const MODE =
     : "e2e";
 const BASE_URL = "http://localhost:3030";
 const DROP_ID = 1; // assume drop id 1 exists for e2e smoke
+// Order verification lists orders by phone, an admin route (ADMIN_API_TOKEN)
+const ADMIN_TOKEN =
+  typeof __ENV !== "undefined" && __ENV.ADMIN_API_TOKEN
+    ? __ENV.ADMIN_API_TOKEN
+    : "";
+const ADMIN_PARAMS = { headers: { Authorization: `Bearer ${ADMIN_TOKEN}` } };
 
 // Options chosen based on mode
 export let options;
@@ -184,7 +190,8 @@ function runE2E() {
   let found = false;
   for (let i = 0; i < 10; i++) {
     const ordersResp = http.get(
-      `${BASE_URL}/api/orders?phone=${encodeURIComponent(phone)}`
+      `${BASE_URL}/api/admin/orders?phone=${encodeURIComponent(phone)}`,
+      ADMIN_PARAMS
     );
     if (ordersResp.status === 200) {
       try {
@@ -338,7 +345,8 @@ function runStressE2E() {
   while (Date.now() - start < 5000) {
     // 5s max wait
     const ordersResp = http.get(
-      `${BASE_URL}/api/orders?phone=${encodeURIComponent(phone)}`
+      `${BASE_URL}/api/admin/orders?phone=${encodeURIComponent(phone)}`,
+      ADMIN_PARAMS
     );
     if (ordersResp.status === 200) {
       try {